@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"nofx/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetTraderAIFailover 获取交易员的AI故障转移链及各提供商的熔断状态
+func (s *Server) handleGetTraderAIFailover(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	fullCfg, err := s.store.Trader().GetFullConfig(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	modelIDs, err := s.store.Trader().GetFailoverModelIDs(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI故障转移链失败: " + err.Error()})
+		return
+	}
+	if modelIDs == nil {
+		modelIDs = []string{}
+	}
+
+	response := gin.H{
+		"trader_id":         traderID,
+		"primary_model_id":  fullCfg.Trader.AIModelID,
+		"ai_model_ids":      modelIDs,
+		"provider_statuses": []any{},
+	}
+	if at, err := s.traderManager.GetTrader(traderID); err == nil {
+		if statuses := at.AIProviderStatus(); statuses != nil {
+			response["provider_statuses"] = statuses
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleSetTraderAIFailover 设置交易员的AI故障转移链（按顺序排列的备用AI模型ID，空数组表示关闭）
+func (s *Server) handleSetTraderAIFailover(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	fullCfg, err := s.store.Trader().GetFullConfig(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	var req struct {
+		AIModelIDs []string `json:"ai_model_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	// 校验备用模型：必须属于当前用户，且不能与主模型或彼此重复
+	seen := map[string]bool{fullCfg.Trader.AIModelID: true}
+	modelIDs := make([]string, 0, len(req.AIModelIDs))
+	for _, modelID := range req.AIModelIDs {
+		if seen[modelID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "备用AI模型不能与主模型或其他备用模型重复: " + modelID})
+			return
+		}
+		if _, err := s.store.AIModel().Get(userID, modelID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "AI模型不存在: " + modelID})
+			return
+		}
+		seen[modelID] = true
+		modelIDs = append(modelIDs, modelID)
+	}
+
+	if err := s.store.Trader().SetFailoverModelIDs(userID, traderID, modelIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存AI故障转移链失败: " + err.Error()})
+		return
+	}
+
+	// 未运行的交易员立即重新加载；运行中的交易员在下次启动后生效
+	message := "AI故障转移链已保存"
+	if at, err := s.traderManager.GetTrader(traderID); err == nil {
+		if isRunning, ok := at.GetStatus()["is_running"].(bool); ok && isRunning {
+			message = "AI故障转移链已保存，重启交易员后生效"
+		} else {
+			s.traderManager.RemoveTrader(traderID)
+		}
+	}
+	if err := s.traderManager.LoadUserTradersFromStore(s.store, userID); err != nil {
+		logger.Infof("⚠️ 重新加载用户交易员到内存失败: %v", err)
+	}
+
+	logger.Infof("✓ 交易员 %s 的AI故障转移链已更新: %v", traderID, modelIDs)
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":    traderID,
+		"ai_model_ids": modelIDs,
+		"message":      message,
+	})
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"net/http"
+	"nofx/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetAICosts 按天/按月汇总AI成本（按交易员和模型分组）
+// 查询参数: period=day|month（默认day）, trader_id, from/to (2006-01-02，to 不含当天)
+func (s *Server) handleGetAICosts(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	period := c.DefaultQuery("period", store.AICostPeriodDay)
+	if period != store.AICostPeriodDay && period != store.AICostPeriodMonth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period 必须为 day 或 month"})
+		return
+	}
+
+	var from, to time.Time
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始日期"})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束日期"})
+			return
+		}
+	}
+
+	costs, err := s.store.AIUsage().GetCostSummary(userID, period, c.Query("trader_id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI成本失败: " + err.Error()})
+		return
+	}
+	if costs == nil {
+		costs = []*store.AICostSummary{}
+	}
+
+	// 按交易员和模型汇总
+	byTrader := make(map[string]float64)
+	byModel := make(map[string]float64)
+	total := 0.0
+	for _, cost := range costs {
+		byTrader[cost.TraderID] += cost.CostUSD
+		byModel[cost.Model] += cost.CostUSD
+		total += cost.CostUSD
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"period":         period,
+		"costs":          costs,
+		"by_trader":      byTrader,
+		"by_model":       byModel,
+		"total_cost_usd": total,
+	})
+}
+
+// handleGetAIPrices 获取模型价格表（美元 / 百万 token）
+func (s *Server) handleGetAIPrices(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	prices, err := s.store.AIUsage().ListPrices(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取模型价格失败: " + err.Error()})
+		return
+	}
+	if prices == nil {
+		prices = []*store.AIModelPrice{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prices": prices})
+}
+
+// handleSetAIPrice 设置模型价格（覆盖系统默认价格）
+func (s *Server) handleSetAIPrice(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var price store.AIModelPrice
+	if err := c.ShouldBindJSON(&price); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if err := s.store.AIUsage().SetPrice(userID, &price); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "模型价格已保存"})
+}
+
+// handleDeleteAIPrice 删除自定义模型价格（?model=xxx，恢复系统默认价格）
+func (s *Server) handleDeleteAIPrice(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	if err := s.store.AIUsage().DeletePrice(userID, c.Query("model")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已恢复系统默认价格"})
+}
+
+// handleGetAIBudgets 获取交易员月度AI预算及本月已用成本
+func (s *Server) handleGetAIBudgets(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	budgets, err := s.store.AIUsage().ListBudgets(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI预算失败: " + err.Error()})
+		return
+	}
+
+	now := time.Now()
+	result := make([]gin.H, 0, len(budgets))
+	for _, budget := range budgets {
+		spent, _ := s.store.AIUsage().GetMonthCost(budget.TraderID, now)
+		result = append(result, gin.H{
+			"trader_id":          budget.TraderID,
+			"monthly_budget_usd": budget.MonthlyBudgetUSD,
+			"month_cost_usd":     spent,
+			"exceeded":           spent >= budget.MonthlyBudgetUSD,
+			"updated_at":         budget.UpdatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"budgets": result})
+}
+
+// handleSetTraderAIBudget 设置交易员月度AI预算（0 表示取消预算）
+func (s *Server) handleSetTraderAIBudget(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 校验交易员是否属于当前用户
+	if _, err := s.store.Trader().GetFullConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	var req struct {
+		MonthlyBudgetUSD float64 `json:"monthly_budget_usd"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if err := s.store.AIUsage().SetBudget(userID, traderID, req.MonthlyBudgetUSD); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	spent, _ := s.store.AIUsage().GetMonthCost(traderID, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":          traderID,
+		"monthly_budget_usd": req.MonthlyBudgetUSD,
+		"month_cost_usd":     spent,
+		"message":            "AI预算已更新",
+	})
+}
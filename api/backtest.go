@@ -13,6 +13,7 @@ import (
 
 	"nofx/backtest"
 	"nofx/decision"
+	"nofx/mcp"
 	"nofx/store"
 
 	"github.com/gin-gonic/gin"
@@ -529,6 +530,10 @@ func (s *Server) hydrateBacktestAIConfig(cfg *backtest.BacktestConfig) error {
 	}
 
 	cfg.UserID = normalizeUserID(cfg.UserID)
+	// 规则策略回测不调用AI
+	if cfg.UsesRuleProvider() {
+		return nil
+	}
 	modelID := strings.TrimSpace(cfg.AIModelID)
 
 	var (
@@ -554,7 +559,7 @@ func (s *Server) hydrateBacktestAIConfig(cfg *backtest.BacktestConfig) error {
 	}
 
 	apiKey := strings.TrimSpace(model.APIKey)
-	if apiKey == "" {
+	if apiKey == "" && !mcp.IsLocalProvider(model.Provider) {
 		return fmt.Errorf("AI模型 %s 缺少API Key，请先在系统中配置", model.Name)
 	}
 
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"net/http"
+	"nofx/evaluation"
+	"nofx/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// evaluationRequest 创建模型评估请求
+type evaluationRequest struct {
+	Name           string     `json:"name"`
+	TraderID       string     `json:"trader_id" binding:"required"`
+	ModelIDs       []string   `json:"model_ids" binding:"required"`
+	DecisionIDs    []int64    `json:"decision_ids"`
+	From           *time.Time `json:"from"`
+	To             *time.Time `json:"to"`
+	SampleLimit    int        `json:"sample_limit"`
+	HorizonMinutes int        `json:"horizon_minutes"`
+}
+
+// handleCreateEvaluation 创建模型评估：将交易员的历史决策 Prompt 回放给多个模型并在后台打分
+func (s *Server) handleCreateEvaluation(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req evaluationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	eval := &store.ModelEvaluation{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		Name:           req.Name,
+		TraderID:       req.TraderID,
+		ModelIDs:       req.ModelIDs,
+		DecisionIDs:    req.DecisionIDs,
+		From:           req.From,
+		To:             req.To,
+		SampleLimit:    req.SampleLimit,
+		HorizonMinutes: req.HorizonMinutes,
+		Status:         store.EvaluationStatusPending,
+	}
+
+	runner := evaluation.NewRunner(s.store)
+	if err := runner.Prepare(eval); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.store.Evaluation().Create(eval); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建评估任务失败: " + err.Error()})
+		return
+	}
+
+	go runner.Run(eval)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      eval.ID,
+		"message": "评估任务已开始",
+	})
+}
+
+// handleGetEvaluations 获取评估任务列表
+func (s *Server) handleGetEvaluations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	evals, err := s.store.Evaluation().List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评估列表失败: " + err.Error()})
+		return
+	}
+	if evals == nil {
+		evals = []*store.ModelEvaluation{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"evaluations": evals,
+	})
+}
+
+// handleGetEvaluation 获取评估任务详情及各模型得分
+func (s *Server) handleGetEvaluation(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	eval, err := s.store.Evaluation().Get(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评估任务不存在"})
+		return
+	}
+
+	scores, err := s.store.Evaluation().GetScores(eval.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评估得分失败: " + err.Error()})
+		return
+	}
+	if scores == nil {
+		scores = []*store.EvaluationScore{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"evaluation": eval,
+		"scores":     scores,
+	})
+}
+
+// handleGetEvaluationSamples 获取评估样本明细（可用 ?model_id=xxx 过滤）
+func (s *Server) handleGetEvaluationSamples(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	eval, err := s.store.Evaluation().Get(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评估任务不存在"})
+		return
+	}
+
+	samples, err := s.store.Evaluation().ListSamples(eval.ID, c.Query("model_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取评估样本失败: " + err.Error()})
+		return
+	}
+	if samples == nil {
+		samples = []*store.EvaluationSample{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"samples": samples,
+	})
+}
+
+// handleGetEvaluationLeaderboard 模型排行榜（汇总用户所有已完成评估的样本）
+func (s *Server) handleGetEvaluationLeaderboard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	scores, err := s.store.Evaluation().GetLeaderboard(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取模型排行榜失败: " + err.Error()})
+		return
+	}
+	if scores == nil {
+		scores = []*store.EvaluationScore{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"leaderboard": scores,
+	})
+}
+
+// handleDeleteEvaluation 删除评估任务（运行中的任务不可删除）
+func (s *Server) handleDeleteEvaluation(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	eval, err := s.store.Evaluation().Get(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评估任务不存在"})
+		return
+	}
+	if eval.Status == store.EvaluationStatusPending || eval.Status == store.EvaluationStatusRunning {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "评估任务运行中，无法删除"})
+		return
+	}
+
+	if err := s.store.Evaluation().Delete(userID, eval.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除评估任务失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "评估任务已删除"})
+}
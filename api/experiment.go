@@ -0,0 +1,206 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// experimentRequest 创建/更新实验请求
+type experimentRequest struct {
+	Name           string                    `json:"name" binding:"required"`
+	Description    string                    `json:"description"`
+	Status         string                    `json:"status"`
+	Scope          string                    `json:"scope"`
+	AssignmentMode string                    `json:"assignment_mode"`
+	Seed           int64                     `json:"seed"`
+	TraderIDs      []string                  `json:"trader_ids"`
+	Variants       []store.ExperimentVariant `json:"variants" binding:"required"`
+}
+
+// handleGetExperiments 获取实验列表
+func (s *Server) handleGetExperiments(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	experiments, err := s.store.Experiment().List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取实验列表失败: " + err.Error()})
+		return
+	}
+	if experiments == nil {
+		experiments = []*store.Experiment{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"experiments": experiments,
+	})
+}
+
+// handleGetExperiment 获取单个实验
+func (s *Server) handleGetExperiment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	exp, err := s.store.Experiment().Get(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "实验不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exp)
+}
+
+// handleCreateExperiment 创建实验
+func (s *Server) handleCreateExperiment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req experimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	exp := &store.Experiment{
+		ID:     uuid.New().String(),
+		UserID: userID,
+	}
+	req.applyTo(exp)
+	if err := s.validateExperiment(userID, exp); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.store.Experiment().Create(exp); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      exp.ID,
+		"message": "实验创建成功",
+	})
+}
+
+// handleUpdateExperiment 更新实验
+func (s *Server) handleUpdateExperiment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	exp, err := s.store.Experiment().Get(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "实验不存在"})
+		return
+	}
+
+	var req experimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	req.applyTo(exp)
+	if err := s.validateExperiment(userID, exp); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.store.Experiment().Update(exp); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "实验更新成功"})
+}
+
+// handleDeleteExperiment 删除实验
+func (s *Server) handleDeleteExperiment(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	if err := s.store.Experiment().Delete(userID, c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除实验失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "实验已删除"})
+}
+
+// handleExperimentStats 获取实验各变体的统计对比
+func (s *Server) handleExperimentStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	exp, err := s.store.Experiment().Get(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "实验不存在"})
+		return
+	}
+
+	stats, err := s.store.Experiment().GetStats(exp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取实验统计失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// applyTo 将请求内容写入实验
+func (req *experimentRequest) applyTo(exp *store.Experiment) {
+	exp.Name = req.Name
+	exp.Description = req.Description
+	exp.Status = req.Status
+	exp.Scope = req.Scope
+	exp.AssignmentMode = req.AssignmentMode
+	exp.Seed = req.Seed
+	exp.TraderIDs = req.TraderIDs
+	exp.Variants = req.Variants
+}
+
+// validateExperiment 校验实验配置，并确认参与的交易员属于当前用户
+func (s *Server) validateExperiment(userID string, exp *store.Experiment) error {
+	if err := exp.Validate(); err != nil {
+		return err
+	}
+	if len(exp.TraderIDs) == 0 {
+		return nil
+	}
+
+	traders, err := s.store.Trader().List(userID)
+	if err != nil {
+		return err
+	}
+	owned := make(map[string]bool, len(traders))
+	for _, t := range traders {
+		owned[t.ID] = true
+	}
+	for _, id := range exp.TraderIDs {
+		if !owned[id] {
+			return fmt.Errorf("交易员不存在: %s", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"nofx/mcp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// localModelDiscoveryTimeout 模型列表查询超时（无需等待推理，使用短超时）
+const localModelDiscoveryTimeout = 10 * time.Second
+
+// handleListLocalModels 列出本地模型服务（Ollama / llama.cpp）上已下载的模型
+// 使用AI模型配置中的自定义 API 地址，未配置时使用默认本地地址
+func (s *Server) handleListLocalModels(c *gin.Context) {
+	userID := c.GetString("user_id")
+	modelID := c.Param("id")
+
+	model, err := s.store.AIModel().Get(userID, modelID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "AI模型不存在"})
+		return
+	}
+	if !mcp.IsLocalProvider(model.Provider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "仅本地模型（ollama / openai-compatible-local）支持模型发现"})
+		return
+	}
+
+	client, ok := mcp.NewProviderClient(model.Provider, mcp.WithTimeout(localModelDiscoveryTimeout)).(*mcp.LocalClient)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建本地模型客户端失败"})
+		return
+	}
+	client.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
+
+	models, err := client.ListModels()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "获取本地模型列表失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"provider": model.Provider,
+		"base_url": client.BaseURL,
+		"models":   models,
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"nofx/market"
+	"nofx/store"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetMarketCacheStats 行情缓存命中率和各数据源的限流统计（进程级，所有交易员共享）
+func (s *Server) handleGetMarketCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, market.GetCacheStats())
+}
+
+// handleGetMarketAlerts 获取行情警报引擎触发的警报
+// 查询参数：symbol（可选，逗号分隔多个）、hours（默认 24，最多 168）、limit（默认 100）
+func (s *Server) handleGetMarketAlerts(c *gin.Context) {
+	var symbols []string
+	for _, symbol := range strings.Split(c.Query("symbol"), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, market.Normalize(symbol))
+		}
+	}
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours <= 0 || hours > 168 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hours 必须在 1-168 之间"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	alerts, err := s.store.MarketAlert().List(symbols, time.Now().Add(-time.Duration(hours)*time.Hour), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取行情警报失败: " + err.Error()})
+		return
+	}
+	if alerts == nil {
+		alerts = []*store.MarketAlert{}
+	}
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// handleGetCoinRanking 本地币种排名（Binance 全部 USDT 永续合约按动量、放量、持仓变化和波动率打分）
+// 查询参数：limit（默认 30）、min_quote_volume（24小时成交额下限，默认 2000 万 USDT）
+func (s *Server) handleGetCoinRanking(c *gin.Context) {
+	var params market.RankingParams
+	params.Limit, _ = strconv.Atoi(c.Query("limit"))
+	params.MinQuoteVolume, _ = strconv.ParseFloat(c.Query("min_quote_volume"), 64)
+	if err := params.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scores, err := market.RankCoins(market.DefaultProvider(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "币种排名失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coins": scores})
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/decision"
+	"nofx/store"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetUserPromptTemplates 获取用户可用的提示词模板（用户模板 + 系统模板）
+func (s *Server) handleGetUserPromptTemplates(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	templates, err := s.store.PromptTemplate().List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取提示词模板失败: " + err.Error()})
+		return
+	}
+	if templates == nil {
+		templates = []*store.PromptTemplate{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+	})
+}
+
+// handleGetUserPromptTemplate 获取模板及其当前版本内容
+func (s *Server) handleGetUserPromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	tmpl, err := s.store.PromptTemplate().Get(userID, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+		return
+	}
+	current, err := s.store.PromptTemplate().GetVersion(tmpl.ID, tmpl.CurrentVersion)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取模板当前版本失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"template": tmpl,
+		"current":  current,
+	})
+}
+
+// handleSaveUserPromptTemplate 保存模板新版本（模板不存在时创建，修改系统模板时创建用户副本）
+func (s *Server) handleSaveUserPromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req struct {
+		Content     string  `json:"content" binding:"required"`
+		Note        string  `json:"note"`
+		Description *string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	if err := decision.ValidatePromptTemplate("content", req.Content, store.RiskControlConfig{}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "模板校验失败: " + err.Error()})
+		return
+	}
+
+	name := c.Param("name")
+	version, err := s.store.PromptTemplate().SaveVersion(userID, name, req.Content, req.Note)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "保存模板失败: " + err.Error()})
+		return
+	}
+	if req.Description != nil {
+		if err := s.store.PromptTemplate().UpdateDescription(userID, name, *req.Description); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新模板描述失败: " + err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version": version,
+		"message": fmt.Sprintf("模板已保存为 v%d", version.Version),
+	})
+}
+
+// handleGetUserPromptTemplateVersions 获取模板的版本历史
+func (s *Server) handleGetUserPromptTemplateVersions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	tmpl, err := s.store.PromptTemplate().Get(userID, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+		return
+	}
+	versions, err := s.store.PromptTemplate().ListVersions(tmpl.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取版本历史失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"template": tmpl,
+		"versions": versions,
+	})
+}
+
+// handleGetUserPromptTemplateVersion 获取模板的指定版本
+func (s *Server) handleGetUserPromptTemplateVersion(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的版本号"})
+		return
+	}
+	tmpl, err := s.store.PromptTemplate().Get(userID, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+		return
+	}
+	v, err := s.store.PromptTemplate().GetVersion(tmpl.ID, version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("版本不存在: v%d", version)})
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// handleDiffUserPromptTemplate 比较模板的两个版本 (?from=1&to=2，to 默认为当前版本)
+func (s *Server) handleDiffUserPromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	tmpl, err := s.store.PromptTemplate().Get(userID, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+		return
+	}
+
+	fromVersion, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的起始版本号"})
+		return
+	}
+	toVersion := tmpl.CurrentVersion
+	if toStr := c.Query("to"); toStr != "" {
+		if toVersion, err = strconv.Atoi(toStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的目标版本号"})
+			return
+		}
+	}
+
+	from, err := s.store.PromptTemplate().GetVersion(tmpl.ID, fromVersion)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("版本不存在: v%d", fromVersion)})
+		return
+	}
+	to, err := s.store.PromptTemplate().GetVersion(tmpl.ID, toVersion)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("版本不存在: v%d", toVersion)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": from.Version,
+		"to":   to.Version,
+		"diff": store.DiffPromptVersions(from, to),
+	})
+}
+
+// handleRollbackUserPromptTemplate 回滚到指定版本（生成新版本，不修改历史）
+func (s *Server) handleRollbackUserPromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req struct {
+		Version int `json:"version" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+
+	version, err := s.store.PromptTemplate().Rollback(userID, c.Param("name"), req.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version": version,
+		"message": fmt.Sprintf("已回滚到 v%d（新版本 v%d）", req.Version, version.Version),
+	})
+}
+
+// applyPromptTemplate 若策略引用了提示词模板，加载用户可见的当前版本到策略引擎
+func (s *Server) applyPromptTemplate(userID string, engine *decision.StrategyEngine) (*decision.StrategyEngine, *store.PromptTemplateVersion, error) {
+	name := engine.GetConfig().PromptTemplate
+	if name == "" {
+		return engine, nil, nil
+	}
+	version, err := s.store.PromptTemplate().GetCurrentVersion(userID, name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("提示词模板不存在: %s", name)
+	}
+	return engine.WithBaseTemplate(version.Content), version, nil
+}
@@ -31,6 +31,7 @@ type Server struct {
 	backtestManager *backtest.Manager
 	httpServer      *http.Server
 	port            int
+	webhookReplay   webhookReplayGuard // 入站 Webhook 重放检测
 }
 
 // NewServer 创建API服务器
@@ -116,6 +117,9 @@ func (s *Server) setupRoutes() {
 		api.POST("/verify-otp", s.handleVerifyOTP)
 		api.POST("/complete-registration", s.handleCompleteRegistration)
 
+		// 入站 Webhook 信号（使用端点密钥/HMAC签名鉴权）
+		api.POST("/webhook/:id", s.handleWebhookIngest)
+
 		// 需要认证的路由
 		protected := api.Group("/", s.authMiddleware())
 		{
@@ -135,10 +139,15 @@ func (s *Server) setupRoutes() {
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
 			protected.POST("/traders/:id/sync-balance", s.handleSyncBalance)
+			protected.PUT("/traders/:id/ai-budget", s.handleSetTraderAIBudget)
+			protected.GET("/traders/:id/stream", s.handleTraderStream)
+			protected.GET("/traders/:id/ai-failover", s.handleGetTraderAIFailover)
+			protected.PUT("/traders/:id/ai-failover", s.handleSetTraderAIFailover)
 
 			// AI模型配置
 			protected.GET("/models", s.handleGetModelConfigs)
 			protected.PUT("/models", s.handleUpdateModelConfigs)
+			protected.GET("/models/:id/local-models", s.handleListLocalModels)
 
 			// 交易所配置
 			protected.GET("/exchanges", s.handleGetExchangeConfigs)
@@ -158,10 +167,60 @@ func (s *Server) setupRoutes() {
 			protected.POST("/strategies/:id/activate", s.handleActivateStrategy)
 			protected.POST("/strategies/:id/duplicate", s.handleDuplicateStrategy)
 
+			// Prompt A/B 实验
+			protected.GET("/experiments", s.handleGetExperiments)
+			protected.GET("/experiments/:id", s.handleGetExperiment)
+			protected.GET("/experiments/:id/stats", s.handleExperimentStats)
+			protected.POST("/experiments", s.handleCreateExperiment)
+			protected.PUT("/experiments/:id", s.handleUpdateExperiment)
+			protected.DELETE("/experiments/:id", s.handleDeleteExperiment)
+
+			// 模型评估（历史决策回放与排行榜）
+			protected.GET("/evaluations", s.handleGetEvaluations)
+			protected.GET("/evaluations/leaderboard", s.handleGetEvaluationLeaderboard)
+			protected.POST("/evaluations", s.handleCreateEvaluation)
+			protected.GET("/evaluations/:id", s.handleGetEvaluation)
+			protected.GET("/evaluations/:id/samples", s.handleGetEvaluationSamples)
+			protected.DELETE("/evaluations/:id", s.handleDeleteEvaluation)
+
+			// 入站 Webhook 端点管理
+			protected.GET("/webhooks", s.handleGetWebhooks)
+			protected.POST("/webhooks", s.handleCreateWebhook)
+			protected.PUT("/webhooks/:id", s.handleUpdateWebhook)
+			protected.DELETE("/webhooks/:id", s.handleDeleteWebhook)
+			protected.POST("/webhooks/:id/rotate", s.handleRotateWebhookSecret)
+			protected.GET("/webhooks/:id/signals", s.handleGetWebhookSignals)
+
+			// 行情数据（缓存与限流统计、行情警报）
+			protected.GET("/market/cache-stats", s.handleGetMarketCacheStats)
+			protected.GET("/market/alerts", s.handleGetMarketAlerts)
+			protected.GET("/market/coin-ranking", s.handleGetCoinRanking)
+
+			// AI用量与成本
+			protected.GET("/ai-usage/costs", s.handleGetAICosts)
+			protected.GET("/ai-usage/prices", s.handleGetAIPrices)
+			protected.PUT("/ai-usage/prices", s.handleSetAIPrice)
+			protected.DELETE("/ai-usage/prices", s.handleDeleteAIPrice)
+			protected.GET("/ai-usage/budgets", s.handleGetAIBudgets)
+
 			// 用户信号源配置
 			protected.GET("/user/signal-sources", s.handleGetUserSignalSource)
 			protected.POST("/user/signal-sources", s.handleSaveUserSignalSource)
 
+			// 用户币种允许/禁止列表（作用于该用户的所有交易员）
+			protected.GET("/user/symbol-lists", s.handleGetSymbolLists)
+			protected.POST("/user/symbol-lists", s.handleSetSymbolList)
+			protected.DELETE("/user/symbol-lists/:symbol", s.handleDeleteSymbolList)
+
+			// 用户提示词模板库（版本化）
+			protected.GET("/user/prompt-templates", s.handleGetUserPromptTemplates)
+			protected.GET("/user/prompt-templates/:name", s.handleGetUserPromptTemplate)
+			protected.PUT("/user/prompt-templates/:name", s.handleSaveUserPromptTemplate)
+			protected.GET("/user/prompt-templates/:name/versions", s.handleGetUserPromptTemplateVersions)
+			protected.GET("/user/prompt-templates/:name/versions/:version", s.handleGetUserPromptTemplateVersion)
+			protected.GET("/user/prompt-templates/:name/diff", s.handleDiffUserPromptTemplate)
+			protected.POST("/user/prompt-templates/:name/rollback", s.handleRollbackUserPromptTemplate)
+
 			// 指定trader的数据（使用query参数 ?trader_id=xxx）
 			protected.GET("/status", s.handleStatus)
 			protected.GET("/account", s.handleAccount)
@@ -2071,8 +2130,20 @@ func (s *Server) Start() error {
 	logger.Infof("  • DELETE /api/traders/:id    - 删除AI交易员")
 	logger.Infof("  • POST /api/traders/:id/start - 启动AI交易员")
 	logger.Infof("  • POST /api/traders/:id/stop  - 停止AI交易员")
+	logger.Infof("  • GET  /api/traders/:id/stream - 实时AI思维链（SSE）")
+	logger.Infof("  • GET  /api/traders/:id/ai-failover - AI故障转移链及熔断状态")
+	logger.Infof("  • PUT  /api/traders/:id/ai-failover - 设置AI故障转移链")
 	logger.Infof("  • GET  /api/models           - 获取AI模型配置")
 	logger.Infof("  • PUT  /api/models           - 更新AI模型配置")
+	logger.Infof("  • GET  /api/models/:id/local-models - 本地模型服务（Ollama/llama.cpp）已下载的模型")
+	logger.Infof("  • POST /api/evaluations      - 回放历史决策评估多个AI模型")
+	logger.Infof("  • GET  /api/evaluations/leaderboard - AI模型评估排行榜")
+	logger.Infof("  • POST /api/webhook/:id      - 接收外部告警信号（密钥/HMAC鉴权）")
+	logger.Infof("  • GET  /api/webhooks         - Webhook端点管理")
+	logger.Infof("  • GET  /api/user/symbol-lists - 币种允许/禁止列表及下架币种")
+	logger.Infof("  • GET  /api/market/cache-stats - 行情缓存命中率与限流统计")
+	logger.Infof("  • GET  /api/market/alerts    - 行情警报（放量、急涨急跌、RSI）")
+	logger.Infof("  • GET  /api/market/coin-ranking - 本地币种排名（动量、放量、持仓变化、波动率）")
 	logger.Infof("  • GET  /api/exchanges        - 获取交易所配置")
 	logger.Infof("  • PUT  /api/exchanges        - 更新交易所配置")
 	logger.Infof("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
@@ -101,6 +101,35 @@ func (s *Server) handleCreateStrategy(c *gin.Context) {
 		return
 	}
 
+	if err := decision.ValidateDecisionProviderConfig(req.Config.DecisionProvider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "决策提供者配置无效: " + err.Error()})
+		return
+	}
+	if err := decision.ValidateGuardConfig(req.Config.Guards); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "规则配置无效: " + err.Error()})
+		return
+	}
+	if err := decision.ValidateIndicatorConfig(req.Config.Indicators); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "指标配置无效: " + err.Error()})
+		return
+	}
+	if err := decision.ValidateExternalDataSources(req.Config.Indicators.ExternalDataSources); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "外部数据源配置无效: " + err.Error()})
+		return
+	}
+	if err := decision.ValidateTriggerConfig(req.Config.Triggers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "事件触发配置无效: " + err.Error()})
+		return
+	}
+	if err := market.ValidateProviderName(req.Config.MarketDataProvider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "行情数据源配置无效: " + err.Error()})
+		return
+	}
+	if err := req.Config.CoinSource.LocalRank.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "本地币种排名配置无效: " + err.Error()})
+		return
+	}
+
 	// 序列化配置
 	configJSON, err := json.Marshal(req.Config)
 	if err != nil {
@@ -161,6 +190,35 @@ func (s *Server) handleUpdateStrategy(c *gin.Context) {
 		return
 	}
 
+	if err := decision.ValidateDecisionProviderConfig(req.Config.DecisionProvider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "决策提供者配置无效: " + err.Error()})
+		return
+	}
+	if err := decision.ValidateGuardConfig(req.Config.Guards); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "规则配置无效: " + err.Error()})
+		return
+	}
+	if err := decision.ValidateIndicatorConfig(req.Config.Indicators); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "指标配置无效: " + err.Error()})
+		return
+	}
+	if err := decision.ValidateExternalDataSources(req.Config.Indicators.ExternalDataSources); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "外部数据源配置无效: " + err.Error()})
+		return
+	}
+	if err := decision.ValidateTriggerConfig(req.Config.Triggers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "事件触发配置无效: " + err.Error()})
+		return
+	}
+	if err := market.ValidateProviderName(req.Config.MarketDataProvider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "行情数据源配置无效: " + err.Error()})
+		return
+	}
+	if err := req.Config.CoinSource.LocalRank.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "本地币种排名配置无效: " + err.Error()})
+		return
+	}
+
 	// 序列化配置
 	configJSON, err := json.Marshal(req.Config)
 	if err != nil {
@@ -383,7 +441,21 @@ func (s *Server) handlePreviewPrompt(c *gin.Context) {
 	}
 
 	// 创建策略引擎来构建 prompt
-	engine := decision.NewStrategyEngine(&req.Config)
+	engine, templateVersion, err := s.applyPromptTemplate(userID, decision.NewStrategyEngine(&req.Config))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验模板语法（保存策略前前端通过此接口校验）
+	baseTemplate := ""
+	if templateVersion != nil {
+		baseTemplate = templateVersion.Content
+	}
+	if err := decision.ValidateStrategyPromptTemplates(&req.Config, baseTemplate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "提示词模板校验失败: " + err.Error()})
+		return
+	}
 
 	// 构建系统 prompt（使用策略引擎内置的方法）
 	systemPrompt := engine.BuildSystemPrompt(
@@ -398,6 +470,7 @@ func (s *Server) handlePreviewPrompt(c *gin.Context) {
 		"system_prompt":       systemPrompt,
 		"prompt_variant":      req.PromptVariant,
 		"available_templates": templateNames,
+		"prompt_template":     templateVersion,
 		"config_summary": gin.H{
 			"coin_source":      req.Config.CoinSource.SourceType,
 			"primary_tf":       req.Config.Indicators.Klines.PrimaryTimeframe,
@@ -433,7 +506,11 @@ func (s *Server) handleStrategyTestRun(c *gin.Context) {
 	}
 
 	// 创建策略引擎来构建 prompt
-	engine := decision.NewStrategyEngine(&req.Config)
+	engine, _, err := s.applyPromptTemplate(userID, decision.NewStrategyEngine(&req.Config))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 获取候选币种
 	candidates, err := engine.GetCandidateCoins()
@@ -471,10 +548,12 @@ func (s *Server) handleStrategyTestRun(c *gin.Context) {
 
 	fmt.Printf("📊 使用时间周期: %v, 主周期: %s, K线数量: %d\n", timeframes, primaryTimeframe, klineCount)
 
-	// 获取真实市场数据（使用多时间周期）
+	// 获取真实市场数据（使用多时间周期，策略指定了行情数据源时使用该数据源）
 	marketDataMap := make(map[string]*market.Data)
+	indicatorSettings := decision.MarketIndicatorSettings(req.Config.Indicators)
+	provider := engine.MarketProvider()
 	for _, coin := range candidates {
-		data, err := market.GetWithTimeframes(coin.Symbol, timeframes, primaryTimeframe, klineCount)
+		data, err := market.GetWithIndicatorsFrom(provider, coin.Symbol, timeframes, primaryTimeframe, klineCount, indicatorSettings)
 		if err != nil {
 			// 如果获取某个币种数据失败，记录日志但继续
 			fmt.Printf("⚠️  获取 %s 市场数据失败: %v\n", coin.Symbol, err)
@@ -502,17 +581,18 @@ func (s *Server) handleStrategyTestRun(c *gin.Context) {
 		CandidateCoins: candidates,
 		PromptVariant:  req.PromptVariant,
 		MarketDataMap:  marketDataMap,
+		Market:         provider,
 	}
 
 	// 构建 System Prompt
-	systemPrompt := engine.BuildSystemPrompt(1000.0, req.PromptVariant)
+	systemPrompt := engine.BuildSystemPromptWithContext(testContext, req.PromptVariant)
 
-	// 构建 User Prompt（使用真实市场数据）
-	userPrompt := engine.BuildUserPrompt(testContext)
+	// 构建 User Prompt（使用真实市场数据，按策略 token 预算裁剪）
+	userPrompt, tokenUsage := engine.BuildBudgetedUserPrompt(testContext, systemPrompt, s.tokenizerModelName(userID, req.AIModelID))
 
 	// 如果请求真实 AI 调用
 	if req.RunRealAI && req.AIModelID != "" {
-		aiResponse, aiErr := s.runRealAITest(userID, req.AIModelID, systemPrompt, userPrompt)
+		aiResult, aiErr := s.runRealAITest(userID, req.AIModelID, systemPrompt, userPrompt)
 		if aiErr != nil {
 			c.JSON(http.StatusOK, gin.H{
 				"system_prompt":   systemPrompt,
@@ -520,6 +600,7 @@ func (s *Server) handleStrategyTestRun(c *gin.Context) {
 				"candidate_count": len(candidates),
 				"candidates":      candidates,
 				"prompt_variant":  req.PromptVariant,
+				"token_usage":     tokenUsage,
 				"ai_response":     fmt.Sprintf("❌ AI 调用失败: %s", aiErr.Error()),
 				"ai_error":        aiErr.Error(),
 				"note":            "AI 调用出错",
@@ -533,7 +614,11 @@ func (s *Server) handleStrategyTestRun(c *gin.Context) {
 			"candidate_count": len(candidates),
 			"candidates":      candidates,
 			"prompt_variant":  req.PromptVariant,
-			"ai_response":     aiResponse,
+			"token_usage":     tokenUsage,
+			"ai_response":     aiResult.Content,
+			"ai_reasoning":    aiResult.Reasoning,
+			"finish_reason":   aiResult.FinishReason,
+			"ai_usage":        aiResult.Usage,
 			"note":            "✅ 真实 AI 测试运行成功",
 		})
 		return
@@ -551,45 +636,48 @@ func (s *Server) handleStrategyTestRun(c *gin.Context) {
 	})
 }
 
+// tokenizerModelName 用于 token 估算的模型名称（与交易员一致：优先自定义模型名，否则为提供商）
+// 未指定或找不到 AI 模型时返回空字符串（使用通用估算）
+func (s *Server) tokenizerModelName(userID, modelID string) string {
+	if modelID == "" {
+		return ""
+	}
+	model, err := s.store.AIModel().Get(userID, modelID)
+	if err != nil {
+		return ""
+	}
+	if model.CustomModelName != "" {
+		return model.CustomModelName
+	}
+	return model.Provider
+}
+
 // runRealAITest 执行真实的 AI 测试调用
-func (s *Server) runRealAITest(userID, modelID, systemPrompt, userPrompt string) (string, error) {
+func (s *Server) runRealAITest(userID, modelID, systemPrompt, userPrompt string) (*mcp.Result, error) {
 	// 获取 AI 模型配置
 	model, err := s.store.AIModel().Get(userID, modelID)
 	if err != nil {
-		return "", fmt.Errorf("获取 AI 模型失败: %w", err)
+		return nil, fmt.Errorf("获取 AI 模型失败: %w", err)
 	}
 
 	if !model.Enabled {
-		return "", fmt.Errorf("AI 模型 %s 尚未启用", model.Name)
+		return nil, fmt.Errorf("AI 模型 %s 尚未启用", model.Name)
 	}
 
-	if model.APIKey == "" {
-		return "", fmt.Errorf("AI 模型 %s 缺少 API Key", model.Name)
+	if model.APIKey == "" && !mcp.IsLocalProvider(model.Provider) {
+		return nil, fmt.Errorf("AI 模型 %s 缺少 API Key", model.Name)
 	}
 
-	// 创建 AI 客户端
-	var aiClient mcp.AIClient
-	provider := model.Provider
-
-	switch provider {
-	case "qwen":
-		aiClient = mcp.NewQwenClient()
-		aiClient.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
-	case "deepseek":
-		aiClient = mcp.NewDeepSeekClient()
-		aiClient.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
-	default:
-		// 使用通用客户端
-		aiClient = mcp.NewClient()
-		aiClient.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
-	}
+	// 创建 AI 客户端（按提供商选择，未知提供商使用通用客户端）
+	aiClient := mcp.NewProviderClient(model.Provider)
+	aiClient.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
 
-	// 调用 AI API
-	response, err := aiClient.CallWithMessages(systemPrompt, userPrompt)
+	// 调用 AI API（结构化结果：正文、思维链、结束原因和用量）
+	result, err := aiClient.CallWithMessagesResult(systemPrompt, userPrompt)
 	if err != nil {
-		return "", fmt.Errorf("AI API 调用失败: %w", err)
+		return nil, fmt.Errorf("AI API 调用失败: %w", err)
 	}
 
-	return response, nil
+	return result, nil
 }
 
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"nofx/market"
+	"nofx/store"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// symbolListRequest 添加币种到允许/禁止列表
+type symbolListRequest struct {
+	Symbol   string `json:"symbol" binding:"required"`
+	ListType string `json:"list_type" binding:"required"` // allow | deny
+	Reason   string `json:"reason"`
+}
+
+// handleGetSymbolLists 获取用户的币种允许/禁止列表，以及行情交易所已计划下架或暂停交易的币种
+func (s *Server) handleGetSymbolLists(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	entries, err := s.store.SymbolList().List(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取币种列表失败: " + err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []*store.SymbolListEntry{}
+	}
+
+	delisting := []market.SymbolStatus{}
+	if provider, ok := market.DefaultProvider().(market.SymbolStatusProvider); ok {
+		if statuses, err := provider.GetSymbolStatuses(); err == nil {
+			for _, status := range statuses {
+				if !status.Tradable() || status.Delisting() {
+					delisting = append(delisting, status)
+				}
+			}
+			sort.Slice(delisting, func(i, j int) bool { return delisting[i].Symbol < delisting[j].Symbol })
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":   entries,
+		"delisting": delisting,
+	})
+}
+
+// handleSetSymbolList 添加或更新币种（同一币种只能在一个列表中）
+func (s *Server) handleSetSymbolList(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req symbolListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if req.ListType != store.SymbolListAllow && req.ListType != store.SymbolListDeny {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "列表类型必须为 allow 或 deny"})
+		return
+	}
+
+	entry := &store.SymbolListEntry{
+		UserID:   userID,
+		Symbol:   market.Normalize(req.Symbol),
+		ListType: req.ListType,
+		Reason:   req.Reason,
+	}
+	if err := s.store.SymbolList().Set(entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存币种列表失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entry": entry, "message": "币种列表已更新，下个交易周期生效"})
+}
+
+// handleDeleteSymbolList 从允许/禁止列表中移除币种
+func (s *Server) handleDeleteSymbolList(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	err := s.store.SymbolList().Delete(userID, market.Normalize(c.Param("symbol")))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "币种不在列表中"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已从币种列表中移除"})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// traderStreamHeartbeat SSE 心跳间隔（防止代理断开空闲连接）
+const traderStreamHeartbeat = 15 * time.Second
+
+// handleTraderStream 通过 SSE 实时推送交易员AI决策周期的思维链和正文
+// 事件: cycle_start / reasoning / content / cycle_end / ping
+// 订阅后从下一个决策周期开始以流式方式调用AI
+func (s *Server) handleTraderStream(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 校验交易员是否属于当前用户
+	if _, err := s.store.Trader().GetFullConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员未加载"})
+		return
+	}
+
+	events, unsubscribe := trader.SubscribeCycleStream()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(traderStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.SSEvent("ping", gin.H{"trader_id": traderID, "is_running": trader.GetStatus()["is_running"]})
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"timestamp": time.Now().Unix()})
+			return true
+		}
+	})
+}
@@ -0,0 +1,573 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/store"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxWebhookBodySize 入站 Webhook 请求体上限
+	maxWebhookBodySize = 64 * 1024
+	// webhookTimestampSkew HMAC 签名时间戳允许的最大偏差
+	webhookTimestampSkew = 5 * time.Minute
+)
+
+// webhookAuthKeys payload 中可代替 X-Webhook-Token 请求头的鉴权字段（保存原始 payload 前移除）
+var webhookAuthKeys = []string{"passphrase", "token", "secret"}
+
+// webhookEndpointRequest 创建/更新 Webhook 端点请求
+type webhookEndpointRequest struct {
+	Name         string `json:"name"`
+	TraderID     string `json:"trader_id"`
+	StrategyID   string `json:"strategy_id"`
+	AuthMode     string `json:"auth_mode"`
+	TTLMinutes   int    `json:"ttl_minutes"`
+	TriggerCycle bool   `json:"trigger_cycle"`
+	Enabled      *bool  `json:"enabled"`
+}
+
+// generateWebhookSecret 生成随机密钥（32字节十六进制）
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validateWebhookTarget 校验端点绑定的交易员/策略属于当前用户
+func (s *Server) validateWebhookTarget(userID string, req *webhookEndpointRequest) error {
+	if (req.TraderID == "") == (req.StrategyID == "") {
+		return fmt.Errorf("必须且只能绑定一个交易员或策略")
+	}
+	switch req.AuthMode {
+	case "":
+		req.AuthMode = store.WebhookAuthToken
+	case store.WebhookAuthToken, store.WebhookAuthHMAC:
+	default:
+		return fmt.Errorf("不支持的鉴权方式: %s", req.AuthMode)
+	}
+	if req.TTLMinutes < 0 || req.TTLMinutes > 7*24*60 {
+		return fmt.Errorf("信号有效期必须在 0-10080 分钟之间")
+	}
+
+	if req.StrategyID != "" {
+		strategy, err := s.store.Strategy().Get(userID, req.StrategyID)
+		if err != nil {
+			return fmt.Errorf("策略不存在")
+		}
+		// 系统默认策略由所有用户共享，不允许绑定（否则信号会推送到其他用户的交易员）
+		if strategy.IsDefault {
+			return fmt.Errorf("不能绑定系统默认策略")
+		}
+		return nil
+	}
+	traders, err := s.store.Trader().List(userID)
+	if err != nil {
+		return fmt.Errorf("获取交易员列表失败: %w", err)
+	}
+	for _, t := range traders {
+		if t.ID == req.TraderID {
+			return nil
+		}
+	}
+	return fmt.Errorf("交易员不存在")
+}
+
+// handleGetWebhooks 获取 Webhook 端点列表
+func (s *Server) handleGetWebhooks(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	endpoints, err := s.store.Webhook().ListEndpoints(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取Webhook列表失败: " + err.Error()})
+		return
+	}
+	if endpoints == nil {
+		endpoints = []*store.WebhookEndpoint{}
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": endpoints})
+}
+
+// handleCreateWebhook 创建 Webhook 端点（密钥仅在此时返回一次）
+func (s *Server) handleCreateWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	var req webhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if err := s.validateWebhookTarget(userID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+		return
+	}
+	ep := &store.WebhookEndpoint{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		Name:         req.Name,
+		TraderID:     req.TraderID,
+		StrategyID:   req.StrategyID,
+		AuthMode:     req.AuthMode,
+		Secret:       secret,
+		TTLMinutes:   req.TTLMinutes,
+		TriggerCycle: req.TriggerCycle,
+		Enabled:      req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.store.Webhook().CreateEndpoint(ep); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhook": ep,
+		"url":     "/api/webhook/" + ep.ID,
+		"message": "Webhook已创建，请妥善保存密钥（之后不再显示）",
+	})
+}
+
+// handleUpdateWebhook 更新 Webhook 端点设置
+func (s *Server) handleUpdateWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	existing, err := s.store.Webhook().GetEndpoint(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook不存在"})
+		return
+	}
+
+	var req webhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数: " + err.Error()})
+		return
+	}
+	if err := s.validateWebhookTarget(userID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing.Name = req.Name
+	existing.TraderID = req.TraderID
+	existing.StrategyID = req.StrategyID
+	existing.AuthMode = req.AuthMode
+	existing.TTLMinutes = req.TTLMinutes
+	existing.TriggerCycle = req.TriggerCycle
+	if req.Enabled != nil {
+		existing.Enabled = *req.Enabled
+	}
+	if err := s.store.Webhook().UpdateEndpoint(existing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"webhook": existing})
+}
+
+// handleRotateWebhookSecret 重置 Webhook 密钥
+func (s *Server) handleRotateWebhookSecret(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+		return
+	}
+	if err := s.store.Webhook().RotateSecret(userID, c.Param("id"), secret); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"secret":  secret,
+		"message": "密钥已重置，旧密钥立即失效",
+	})
+}
+
+// handleDeleteWebhook 删除 Webhook 端点及其信号
+func (s *Server) handleDeleteWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	if err := s.store.Webhook().DeleteEndpoint(userID, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook已删除"})
+}
+
+// handleGetWebhookSignals 获取端点最近收到的信号
+func (s *Server) handleGetWebhookSignals(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	ep, err := s.store.Webhook().GetEndpoint(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook不存在"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	signals, err := s.store.Webhook().ListSignals(ep.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取信号失败: " + err.Error()})
+		return
+	}
+	if signals == nil {
+		signals = []*store.WebhookSignal{}
+	}
+	for _, sig := range signals {
+		sig.Raw = redactWebhookBody([]byte(sig.Raw)) // 兼容旧版本保存的未脱敏 payload
+	}
+	c.JSON(http.StatusOK, gin.H{"signals": signals})
+}
+
+// handleWebhookIngest 接收入站告警（无需登录，使用端点密钥鉴权）
+// token 模式：X-Webhook-Token 请求头或 payload 中的 passphrase/token 字段（不接受查询参数，避免密钥写入访问日志）
+// hmac 模式：X-Webhook-Timestamp 为 Unix 秒级时间戳（与服务器时间偏差不超过 5 分钟），
+// X-Signature（或 X-Hub-Signature-256: sha256=...）为 HMAC-SHA256(密钥, "时间戳.请求体") 的十六进制
+// 时间窗口内重复的签名（或 token 模式下重复的 X-Webhook-Nonce / payload nonce）视为重放并拒绝
+// 注意：token 模式只有在发送方提供 nonce 时才能防重放，未提供时截获的请求可被重复提交；能签名的发送方应使用 hmac 模式
+func (s *Server) handleWebhookIngest(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize+1))
+	if err != nil || len(body) > maxWebhookBodySize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "请求体过大或读取失败"})
+		return
+	}
+
+	ep, err := s.store.Webhook().GetEndpointWithSecret(c.Param("id"))
+	if err != nil || !ep.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook不存在或已停用"})
+		return
+	}
+
+	now := time.Now().UTC()
+	payload := parseWebhookPayload(body)
+	if !verifyWebhookRequest(ep, c.Request, body, payload, now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "签名、时间戳或密钥无效"})
+		return
+	}
+	if key := webhookReplayKey(ep, c.Request, payload); key != "" && !s.webhookReplay.accept(ep.ID+"|"+key, now) {
+		c.JSON(http.StatusConflict, gin.H{"error": "重复的请求（疑似重放）"})
+		return
+	}
+
+	sig, err := normalizeWebhookPayload(payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sig.EndpointID = ep.ID
+	sig.UserID = ep.UserID
+	sig.TraderID = ep.TraderID
+	sig.StrategyID = ep.StrategyID
+	if sig.Source == "" {
+		sig.Source = ep.Name
+	}
+	sig.Raw = redactWebhookBody(body)
+	sig.ReceivedAt = now
+	sig.ExpiresAt = now.Add(time.Duration(ep.TTLMinutes) * time.Minute)
+
+	if err := s.store.Webhook().AddSignal(sig); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.Infof("📨 Webhook [%s] 收到信号: %s %s", ep.Name, sig.Symbol, sig.Action)
+
+	triggered := s.triggerWebhookTraders(ep, sig)
+
+	c.JSON(http.StatusOK, gin.H{
+		"signal_id": sig.ID,
+		"symbol":    sig.Symbol,
+		"action":    sig.Action,
+		"triggered": triggered,
+	})
+}
+
+// triggerWebhookTraders 触发端点绑定的交易员（或使用该策略的所有运行中交易员）提前执行周期
+// 端点开启了 TriggerCycle，或交易员策略的事件触发配置了 Webhook 信号时触发
+func (s *Server) triggerWebhookTraders(ep *store.WebhookEndpoint, sig *store.WebhookSignal) int {
+	reason := fmt.Sprintf("Webhook %s: %s %s", ep.Name, sig.Symbol, sig.Action)
+	triggered := 0
+	for id, at := range s.traderManager.GetAllTraders() {
+		if at.GetUserID() != ep.UserID {
+			continue
+		}
+		if ep.TraderID != "" && id != ep.TraderID {
+			continue
+		}
+		if ep.StrategyID != "" && at.GetStrategyID() != ep.StrategyID {
+			continue
+		}
+		if !ep.TriggerCycle && !at.TriggersOnWebhookSignal() {
+			continue
+		}
+		if at.TriggerCycle(reason) {
+			triggered++
+		}
+	}
+	return triggered
+}
+
+// verifyWebhookRequest 按端点鉴权方式校验请求（常量时间比较）
+func verifyWebhookRequest(ep *store.WebhookEndpoint, r *http.Request, body []byte, payload map[string]any, now time.Time) bool {
+	if ep.Secret == "" {
+		return false
+	}
+
+	if ep.AuthMode == store.WebhookAuthHMAC {
+		timestamp := strings.TrimSpace(r.Header.Get("X-Webhook-Timestamp"))
+		sec, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return false
+		}
+		if skew := now.Sub(time.Unix(sec, 0)); skew > webhookTimestampSkew || skew < -webhookTimestampSkew {
+			return false
+		}
+		provided, err := hex.DecodeString(webhookSignature(r))
+		if err != nil || len(provided) == 0 {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(ep.Secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		return hmac.Equal(provided, mac.Sum(nil))
+	}
+
+	token := r.Header.Get("X-Webhook-Token")
+	if token == "" {
+		for _, key := range webhookAuthKeys {
+			if v, ok := payload[key].(string); ok && v != "" {
+				token = v
+				break
+			}
+		}
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(ep.Secret)) == 1
+}
+
+// webhookSignature 请求头中的 HMAC 签名（十六进制）
+func webhookSignature(r *http.Request) string {
+	signature := r.Header.Get("X-Signature")
+	if signature == "" {
+		signature = strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+	}
+	return strings.ToLower(strings.TrimSpace(signature))
+}
+
+// webhookReplayKey 请求的去重键：HMAC 模式为签名，token 模式为可选的 nonce（未提供时不去重）
+func webhookReplayKey(ep *store.WebhookEndpoint, r *http.Request, payload map[string]any) string {
+	if ep.AuthMode == store.WebhookAuthHMAC {
+		return "sig:" + webhookSignature(r)
+	}
+	nonce := r.Header.Get("X-Webhook-Nonce")
+	if nonce == "" {
+		nonce = webhookString(payload, "nonce")
+	}
+	if nonce == "" {
+		return ""
+	}
+	return "nonce:" + nonce
+}
+
+// webhookReplayGuard 记录时间窗口内已接受的签名/nonce，拒绝重放请求
+type webhookReplayGuard struct {
+	mu   sync.Mutex
+	seen map[string]time.Time // 去重键 → 过期时间
+}
+
+// accept 记录去重键；窗口内已出现过时返回 false
+func (g *webhookReplayGuard) accept(key string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seen == nil {
+		g.seen = make(map[string]time.Time)
+	}
+	for k, expires := range g.seen {
+		if now.After(expires) {
+			delete(g.seen, k)
+		}
+	}
+	if _, dup := g.seen[key]; dup {
+		return false
+	}
+	// 时间戳在 ±skew 内均有效，保留两倍窗口
+	g.seen[key] = now.Add(2 * webhookTimestampSkew)
+	return true
+}
+
+// redactWebhookBody 移除 JSON payload 中的鉴权字段后返回（非 JSON 请求体原样返回）
+func redactWebhookBody(body []byte) string {
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return string(body)
+	}
+	redacted := false
+	for _, key := range webhookAuthKeys {
+		if _, ok := payload[key]; ok {
+			delete(payload, key)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return string(body)
+	}
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// parseWebhookPayload 解析 JSON 请求体；纯文本（如 "BTCUSDT buy 65000"）按空格拆分为 symbol/action/price
+func parseWebhookPayload(body []byte) map[string]any {
+	payload := map[string]any{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		return payload
+	}
+
+	payload = map[string]any{}
+	fields := strings.Fields(string(body))
+	if len(fields) > 0 {
+		payload["symbol"] = fields[0]
+	}
+	if len(fields) > 1 {
+		payload["action"] = fields[1]
+	}
+	if len(fields) > 2 {
+		payload["price"] = fields[2]
+	}
+	if len(fields) > 3 {
+		payload["message"] = strings.Join(fields[3:], " ")
+	}
+	return payload
+}
+
+// webhookActionAliases 常见告警动作到信号动作的映射
+var webhookActionAliases = map[string]string{
+	"long":        store.SignalActionLong,
+	"buy":         store.SignalActionLong,
+	"open_long":   store.SignalActionLong,
+	"bullish":     store.SignalActionLong,
+	"short":       store.SignalActionShort,
+	"sell":        store.SignalActionShort,
+	"open_short":  store.SignalActionShort,
+	"bearish":     store.SignalActionShort,
+	"close":       store.SignalActionClose,
+	"exit":        store.SignalActionClose,
+	"flat":        store.SignalActionClose,
+	"close_long":  store.SignalActionCloseLong,
+	"exit_long":   store.SignalActionCloseLong,
+	"close_short": store.SignalActionCloseShort,
+	"exit_short":  store.SignalActionCloseShort,
+}
+
+// normalizeWebhookPayload 将告警 payload 归一化为信号（兼容 TradingView 等常见字段名）
+func normalizeWebhookPayload(payload map[string]any) (*store.WebhookSignal, error) {
+	rawAction := strings.ToLower(strings.TrimSpace(webhookString(payload, "action", "side", "signal", "order_action")))
+	rawAction = strings.ReplaceAll(strings.ReplaceAll(rawAction, " ", "_"), "-", "_")
+	action, ok := webhookActionAliases[rawAction]
+	if !ok {
+		return nil, fmt.Errorf("无法识别的信号动作: %q", rawAction)
+	}
+
+	sig := &store.WebhookSignal{
+		Symbol:    normalizeWebhookSymbol(webhookString(payload, "symbol", "ticker")),
+		Action:    action,
+		Price:     webhookFloat(payload, "price", "close"),
+		Timeframe: webhookString(payload, "timeframe", "interval"),
+		Message:   webhookString(payload, "message", "comment", "text"),
+		Source:    webhookString(payload, "source", "strategy"),
+	}
+	confidence := int(webhookFloat(payload, "confidence"))
+	sig.Confidence = max(0, min(100, confidence))
+	if message := []rune(sig.Message); len(message) > 500 {
+		sig.Message = string(message[:500]) // 按字符截断，避免截断多字节字符
+	}
+	return sig, nil
+}
+
+// normalizeWebhookSymbol 去掉交易所前缀（BINANCE:）和永续后缀（.P），统一为 USDT 交易对
+func normalizeWebhookSymbol(symbol string) string {
+	symbol = strings.TrimSpace(symbol)
+	if symbol == "" {
+		return ""
+	}
+	if i := strings.LastIndex(symbol, ":"); i >= 0 {
+		symbol = symbol[i+1:]
+	}
+	symbol = strings.TrimSuffix(strings.ToUpper(symbol), ".P")
+	symbol = strings.TrimSuffix(symbol, "PERP")
+	return market.Normalize(symbol)
+}
+
+// webhookString 按候选字段名取第一个非空字符串
+func webhookString(payload map[string]any, keys ...string) string {
+	for _, key := range keys {
+		switch v := payload[key].(type) {
+		case string:
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// webhookFloat 按候选字段名取第一个可解析的数值
+func webhookFloat(payload map[string]any, keys ...string) float64 {
+	for _, key := range keys {
+		switch v := payload[key].(type) {
+		case float64:
+			return v
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f
+			}
+		}
+	}
+	return 0
+}
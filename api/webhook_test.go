@@ -0,0 +1,186 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"nofx/store"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestNormalizeWebhookPayload(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantSymbol string
+		wantAction string
+		wantPrice  float64
+		wantErr    bool
+	}{
+		{
+			name:       "TradingView 字段",
+			body:       `{"ticker":"BINANCE:BTCUSDT.P","order_action":"buy","close":"65000.5","interval":"15"}`,
+			wantSymbol: "BTCUSDT",
+			wantAction: store.SignalActionLong,
+			wantPrice:  65000.5,
+		},
+		{
+			name:       "标准字段",
+			body:       `{"symbol":"eth","action":"close short","price":3200}`,
+			wantSymbol: "ETHUSDT",
+			wantAction: store.SignalActionCloseShort,
+			wantPrice:  3200,
+		},
+		{
+			name:       "纯文本",
+			body:       "SOLUSDT sell 150 breakdown below support",
+			wantSymbol: "SOLUSDT",
+			wantAction: store.SignalActionShort,
+			wantPrice:  150,
+		},
+		{
+			name:    "未知动作",
+			body:    `{"symbol":"BTCUSDT","action":"moon"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sig, err := normalizeWebhookPayload(parseWebhookPayload([]byte(tt.body)))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sig.Symbol != tt.wantSymbol || sig.Action != tt.wantAction || sig.Price != tt.wantPrice {
+				t.Errorf("got %s %s %.2f, want %s %s %.2f",
+					sig.Symbol, sig.Action, sig.Price, tt.wantSymbol, tt.wantAction, tt.wantPrice)
+			}
+		})
+	}
+
+	// 超长消息按字符截断，不截断多字节字符
+	sig, err := normalizeWebhookPayload(map[string]any{"action": "buy", "message": "a" + strings.Repeat("突破", 300)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := utf8.RuneCountInString(sig.Message); n != 500 || !utf8.ValidString(sig.Message) {
+		t.Errorf("message runes = %d, valid = %v", n, utf8.ValidString(sig.Message))
+	}
+}
+
+func TestVerifyWebhookRequest(t *testing.T) {
+	body := []byte(`{"symbol":"BTCUSDT","action":"buy","passphrase":"s3cret"}`)
+	payload := parseWebhookPayload(body)
+	now := time.Unix(1_700_000_000, 0)
+
+	tokenEp := &store.WebhookEndpoint{AuthMode: store.WebhookAuthToken, Secret: "s3cret"}
+	req := httptest.NewRequest("POST", "/api/webhook/x", strings.NewReader(string(body)))
+	if !verifyWebhookRequest(tokenEp, req, body, payload, now) {
+		t.Error("passphrase in payload should be accepted")
+	}
+	req = httptest.NewRequest("POST", "/api/webhook/x", nil)
+	req.Header.Set("X-Webhook-Token", "wrong")
+	if verifyWebhookRequest(tokenEp, req, nil, map[string]any{}, now) {
+		t.Error("wrong token should be rejected")
+	}
+	req.Header.Set("X-Webhook-Token", "s3cret")
+	if !verifyWebhookRequest(tokenEp, req, nil, map[string]any{}, now) {
+		t.Error("token header should be accepted")
+	}
+	// 查询参数中的密钥会写入访问日志，不接受
+	req = httptest.NewRequest("POST", "/api/webhook/x?token=s3cret", nil)
+	if verifyWebhookRequest(tokenEp, req, nil, map[string]any{}, now) {
+		t.Error("token query parameter should be rejected")
+	}
+
+	hmacEp := &store.WebhookEndpoint{AuthMode: store.WebhookAuthHMAC, Secret: "s3cret"}
+	signed := func(ts int64, body []byte) *http.Request {
+		timestamp := strconv.FormatInt(ts, 10)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		req := httptest.NewRequest("POST", "/api/webhook/x", nil)
+		req.Header.Set("X-Webhook-Timestamp", timestamp)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		return req
+	}
+	if !verifyWebhookRequest(hmacEp, signed(now.Unix()-60, body), body, payload, now) {
+		t.Error("valid HMAC signature should be accepted")
+	}
+	// 超出时间窗口或篡改时间戳
+	if verifyWebhookRequest(hmacEp, signed(now.Unix()-600, body), body, payload, now) {
+		t.Error("stale timestamp should be rejected")
+	}
+	req = signed(now.Unix(), body)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(now.Unix()+1, 10))
+	if verifyWebhookRequest(hmacEp, req, body, payload, now) {
+		t.Error("timestamp must be covered by the signature")
+	}
+	// 只签名请求体（不含时间戳）的旧格式不再接受
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	req = httptest.NewRequest("POST", "/api/webhook/x", nil)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+	if verifyWebhookRequest(hmacEp, req, body, payload, now) {
+		t.Error("body-only signature should be rejected")
+	}
+	// HMAC 模式下 payload 中的密钥不能代替签名
+	req = httptest.NewRequest("POST", "/api/webhook/x", nil)
+	if verifyWebhookRequest(hmacEp, req, body, payload, now) {
+		t.Error("HMAC endpoint should require a signature")
+	}
+}
+
+func TestWebhookReplayGuard(t *testing.T) {
+	hmacEp := &store.WebhookEndpoint{AuthMode: store.WebhookAuthHMAC}
+	req := httptest.NewRequest("POST", "/api/webhook/x", nil)
+	req.Header.Set("X-Signature", "ABCD")
+	key := webhookReplayKey(hmacEp, req, nil)
+	if key != "sig:abcd" {
+		t.Fatalf("key = %q", key)
+	}
+
+	var guard webhookReplayGuard
+	now := time.Unix(1_700_000_000, 0)
+	if !guard.accept(key, now) {
+		t.Fatal("first request should be accepted")
+	}
+	if guard.accept(key, now.Add(time.Minute)) {
+		t.Error("replayed signature should be rejected")
+	}
+	if !guard.accept(key, now.Add(2*webhookTimestampSkew+time.Second)) {
+		t.Error("key should expire after the replay window")
+	}
+
+	// token 模式只在提供 nonce 时去重
+	tokenEp := &store.WebhookEndpoint{AuthMode: store.WebhookAuthToken}
+	if key := webhookReplayKey(tokenEp, httptest.NewRequest("POST", "/", nil), map[string]any{}); key != "" {
+		t.Errorf("token mode without nonce: %q", key)
+	}
+	if key := webhookReplayKey(tokenEp, httptest.NewRequest("POST", "/", nil), map[string]any{"nonce": "n1"}); key != "nonce:n1" {
+		t.Errorf("token mode nonce: %q", key)
+	}
+}
+
+func TestRedactWebhookBody(t *testing.T) {
+	raw := redactWebhookBody([]byte(`{"symbol":"BTCUSDT","passphrase":"s3cret","token":"s3cret"}`))
+	if strings.Contains(raw, "s3cret") || !strings.Contains(raw, "BTCUSDT") {
+		t.Errorf("raw = %s", raw)
+	}
+	if raw := redactWebhookBody([]byte("BTCUSDT buy 65000")); raw != "BTCUSDT buy 65000" {
+		t.Errorf("plain text raw = %s", raw)
+	}
+}
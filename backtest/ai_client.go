@@ -14,7 +14,10 @@ func configureMCPClient(cfg BacktestConfig, base mcp.AIClient) (mcp.AIClient, er
 
 	// DeepSeek
 	if provider == "" || provider == "inherit" || provider == "default" {
-		client := cloneBaseClient(base)
+		client, err := cloneBaseClient(base)
+		if err != nil {
+			return nil, err
+		}
 		if cfg.AICfg.APIKey != "" || cfg.AICfg.BaseURL != "" || cfg.AICfg.Model != "" {
 			client.SetAPIKey(cfg.AICfg.APIKey, cfg.AICfg.BaseURL, cfg.AICfg.Model)
 		}
@@ -36,11 +39,24 @@ func configureMCPClient(cfg BacktestConfig, base mcp.AIClient) (mcp.AIClient, er
 		qc := mcp.NewQwenClientWithOptions()
 		qc.(*mcp.QwenClient).SetAPIKey(cfg.AICfg.APIKey, cfg.AICfg.BaseURL, cfg.AICfg.Model)
 		return qc, nil
+	case mcp.ProviderAnthropic, mcp.ProviderGemini:
+		if cfg.AICfg.APIKey == "" {
+			return nil, fmt.Errorf("%s provider requires api key", provider)
+		}
+		client := mcp.NewProviderClient(provider)
+		client.SetAPIKey(cfg.AICfg.APIKey, cfg.AICfg.BaseURL, cfg.AICfg.Model)
+		return client, nil
+	case mcp.ProviderOllama, mcp.ProviderLocalOpenAI:
+		// 本地模型无需 API Key
+		client := mcp.NewProviderClient(provider)
+		client.SetAPIKey(cfg.AICfg.APIKey, cfg.AICfg.BaseURL, cfg.AICfg.Model)
+		return client, nil
 	case "custom":
 		if cfg.AICfg.BaseURL == "" || cfg.AICfg.APIKey == "" || cfg.AICfg.Model == "" {
 			return nil, fmt.Errorf("custom provider requires base_url, api key and model")
 		}
-		client := cloneBaseClient(base)
+		// 自定义服务使用 OpenAI 兼容接口，不沿用基础客户端的提供商格式
+		client := mcp.NewClient()
 		client.SetAPIKey(cfg.AICfg.APIKey, cfg.AICfg.BaseURL, cfg.AICfg.Model)
 		return client, nil
 	default:
@@ -48,24 +64,15 @@ func configureMCPClient(cfg BacktestConfig, base mcp.AIClient) (mcp.AIClient, er
 	}
 }
 
-// cloneBaseClient 复制基础客户端以避免共享可变状态。
-func cloneBaseClient(base mcp.AIClient) *mcp.Client {
-	// 优先尝试复用传入的基础客户端（深拷贝）
-	switch c := base.(type) {
-	case *mcp.Client:
-		cp := *c
-		return &cp
-	case *mcp.DeepSeekClient:
-		if c != nil && c.Client != nil {
-			cp := *c.Client
-			return &cp
-		}
-	case *mcp.QwenClient:
-		if c != nil && c.Client != nil {
-			cp := *c.Client
-			return &cp
-		}
+// cloneBaseClient 复制基础客户端以避免共享可变状态（保留 Anthropic/Gemini/本地模型等提供商的请求格式）。
+// 未提供基础客户端时使用默认客户端；无法复制的类型返回错误，避免误用其他提供商的接口。
+func cloneBaseClient(base mcp.AIClient) (mcp.AIClient, error) {
+	if base == nil {
+		return mcp.NewClient(), nil
+	}
+	client, err := mcp.CloneClient(base)
+	if err != nil {
+		return nil, fmt.Errorf("无法复用当前 AI 客户端进行回测，请在回测配置中指定 AI 提供商: %w", err)
 	}
-	// 回退到新的默认客户端
-	return mcp.NewClient().(*mcp.Client)
+	return client, nil
 }
@@ -5,7 +5,9 @@ import (
 	"strings"
 	"time"
 
+	"nofx/decision"
 	"nofx/market"
+	"nofx/store"
 )
 
 // AIConfig 定义回测中使用的 AI 客户端配置。
@@ -52,6 +54,16 @@ type BacktestConfig struct {
 	CheckpointIntervalBars    int    `json:"checkpoint_interval_bars,omitempty"`
 	CheckpointIntervalSeconds int    `json:"checkpoint_interval_seconds,omitempty"`
 	ReplayDecisionDir         string `json:"replay_decision_dir,omitempty"`
+
+	// DecisionProvider 决策提供者（为空或 llm 时调用AI，也可使用内置规则策略作为基准）
+	DecisionProvider *store.DecisionProviderConfig `json:"decision_provider,omitempty"`
+
+	// MarketDataProvider 历史K线数据源（binance / bybit / hyperliquid，默认 binance）
+	MarketDataProvider string `json:"market_data_provider,omitempty"`
+
+	// Derivatives 历史持仓情绪数据（多空比、主动买卖量；强平没有历史数据，忽略）
+	// 仅 Binance 支持，且只能获取最近 30 天
+	Derivatives *market.DerivativesOptions `json:"derivatives,omitempty"`
 }
 
 // Validate 对配置进行合法性检查并填充默认值。
@@ -68,6 +80,10 @@ func (cfg *BacktestConfig) Validate() error {
 		cfg.UserID = "default"
 	}
 	cfg.AIModelID = strings.TrimSpace(cfg.AIModelID)
+	cfg.MarketDataProvider = strings.ToLower(strings.TrimSpace(cfg.MarketDataProvider))
+	if err := market.ValidateProviderName(cfg.MarketDataProvider); err != nil {
+		return err
+	}
 
 	if len(cfg.Symbols) == 0 {
 		return fmt.Errorf("at least one symbol is required")
@@ -98,6 +114,12 @@ func (cfg *BacktestConfig) Validate() error {
 	}
 	cfg.DecisionTimeframe = normalizedDecision
 
+	if cfg.Derivatives != nil {
+		if err := cfg.Derivatives.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if cfg.DecisionCadenceNBars <= 0 {
 		cfg.DecisionCadenceNBars = 20
 	}
@@ -148,9 +170,37 @@ func (cfg *BacktestConfig) Validate() error {
 		cfg.Leverage.AltcoinLeverage = 5
 	}
 
+	if cfg.DecisionProvider != nil {
+		if err := decision.ValidateDecisionProviderConfig(*cfg.DecisionProvider); err != nil {
+			return err
+		}
+		if tf := cfg.DecisionProvider.Timeframe; tf != "" {
+			normalized, err := market.NormalizeTimeframe(tf)
+			if err != nil {
+				return fmt.Errorf("invalid decision_provider.timeframe: %w", err)
+			}
+			found := false
+			for _, loaded := range cfg.Timeframes {
+				if loaded == normalized {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("decision_provider.timeframe %s must be one of timeframes", normalized)
+			}
+			cfg.DecisionProvider.Timeframe = normalized
+		}
+	}
+
 	return nil
 }
 
+// UsesRuleProvider 是否使用内置规则策略（无需AI模型）。
+func (cfg *BacktestConfig) UsesRuleProvider() bool {
+	return cfg != nil && cfg.DecisionProvider != nil && decision.IsRuleProvider(cfg.DecisionProvider.Type)
+}
+
 // Duration 返回回测区间时长。
 func (cfg *BacktestConfig) Duration() time.Duration {
 	if cfg == nil {
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"time"
 
+	"nofx/logger"
 	"nofx/market"
 )
 
@@ -14,7 +15,8 @@ type timeframeSeries struct {
 }
 
 type symbolSeries struct {
-	byTF map[string]*timeframeSeries
+	byTF        map[string]*timeframeSeries
+	derivatives []market.DerivativesPoint
 }
 
 // DataFeed 管理历史K线数据，为回测提供按时间推进的快照。
@@ -49,6 +51,15 @@ func (df *DataFeed) loadAll() error {
 	start := time.Unix(df.cfg.StartTS, 0)
 	end := time.Unix(df.cfg.EndTS, 0)
 
+	provider := market.DefaultProvider()
+	if df.cfg.MarketDataProvider != "" {
+		p, err := market.ProviderFor(df.cfg.MarketDataProvider)
+		if err != nil {
+			return err
+		}
+		provider = p
+	}
+
 	// longest timeframe用于辅助指标
 	var longestDur time.Duration
 	for _, tf := range df.timeframes {
@@ -65,34 +76,35 @@ func (df *DataFeed) loadAll() error {
 	for _, symbol := range df.symbols {
 		ss := &symbolSeries{byTF: make(map[string]*timeframeSeries)}
 		for _, tf := range df.timeframes {
-			dur, _ := market.TFDuration(tf)
-			buffer := dur * 200
-			fetchStart := start.Add(-buffer)
-			if fetchStart.Before(time.Unix(0, 0)) {
-				fetchStart = time.Unix(0, 0)
-			}
-			fetchEnd := end.Add(dur)
-
-			klines, err := market.GetKlinesRange(symbol, tf, fetchStart, fetchEnd)
+			series, err := loadTimeframeSeries(provider, symbol, tf, start, end)
 			if err != nil {
-				return fmt.Errorf("fetch klines for %s %s: %w", symbol, tf, err)
-			}
-			if len(klines) == 0 {
-				return fmt.Errorf("no klines for %s %s", symbol, tf)
-			}
-
-			series := &timeframeSeries{
-				klines:     klines,
-				closeTimes: make([]int64, len(klines)),
-			}
-			for i, k := range klines {
-				series.closeTimes[i] = k.CloseTime
+				return err
 			}
 			ss.byTF[tf] = series
 		}
+		if df.cfg.Derivatives != nil && df.cfg.Derivatives.Enabled() {
+			points, err := loadDerivativesHistory(provider, symbol, *df.cfg.Derivatives, start, end)
+			if err != nil {
+				return fmt.Errorf("fetch derivatives for %s: %w", symbol, err)
+			}
+			ss.derivatives = points
+		}
 		df.symbolSeries[symbol] = ss
 	}
 
+	// BTC/ETH 基准只加载决策周期，用于计算市场概览（加载失败不影响回测）
+	for _, symbol := range []string{market.BenchmarkBTC, market.BenchmarkETH} {
+		if _, ok := df.symbolSeries[symbol]; ok {
+			continue
+		}
+		series, err := loadTimeframeSeries(provider, symbol, df.primaryTF, start, end)
+		if err != nil {
+			logger.Infof("⚠️ 加载市场概览基准 %s 失败: %v", symbol, err)
+			continue
+		}
+		df.symbolSeries[symbol] = &symbolSeries{byTF: map[string]*timeframeSeries{df.primaryTF: series}}
+	}
+
 	// 以第一个符号的主周期生成回测进度时间轴
 	firstSymbol := df.symbols[0]
 	primarySeries := df.symbolSeries[firstSymbol].byTF[df.primaryTF]
@@ -119,6 +131,46 @@ func (df *DataFeed) loadAll() error {
 	return nil
 }
 
+// loadTimeframeSeries 加载回测区间（含 200 根预热K线）的单周期K线
+func loadTimeframeSeries(provider market.MarketDataProvider, symbol, tf string, start, end time.Time) (*timeframeSeries, error) {
+	dur, err := market.TFDuration(tf)
+	if err != nil {
+		return nil, err
+	}
+	buffer := dur * 200
+	fetchStart := start.Add(-buffer)
+	if fetchStart.Before(time.Unix(0, 0)) {
+		fetchStart = time.Unix(0, 0)
+	}
+	fetchEnd := end.Add(dur)
+
+	klines, err := provider.GetKlinesRange(symbol, tf, fetchStart, fetchEnd)
+	if err != nil {
+		return nil, fmt.Errorf("fetch klines for %s %s: %w", symbol, tf, err)
+	}
+	if len(klines) == 0 {
+		return nil, fmt.Errorf("no klines for %s %s", symbol, tf)
+	}
+
+	series := &timeframeSeries{
+		klines:     klines,
+		closeTimes: make([]int64, len(klines)),
+	}
+	for i, k := range klines {
+		series.closeTimes[i] = k.CloseTime
+	}
+	return series, nil
+}
+
+// loadDerivativesHistory 加载回测区间（含聚合窗口）的持仓情绪数据；数据源不支持时返回空
+func loadDerivativesHistory(provider market.MarketDataProvider, symbol string, opts market.DerivativesOptions, start, end time.Time) ([]market.DerivativesPoint, error) {
+	dp, ok := provider.(market.DerivativesProvider)
+	if !ok {
+		return nil, nil
+	}
+	return dp.GetDerivativesHistory(symbol, opts, start.Add(-opts.Window()), end)
+}
+
 func (df *DataFeed) DecisionBarCount() int {
 	return len(df.decisionTimes)
 }
@@ -138,6 +190,18 @@ func (df *DataFeed) sliceUpTo(symbol, tf string, ts int64) []market.Kline {
 	return series.klines[:idx]
 }
 
+// KlinesUpTo 返回截至 ts 已收盘的K线（供规则策略计算信号）。
+func (df *DataFeed) KlinesUpTo(symbol, tf string, ts int64) ([]market.Kline, error) {
+	ss, ok := df.symbolSeries[symbol]
+	if !ok {
+		return nil, fmt.Errorf("symbol %s not loaded", symbol)
+	}
+	if _, ok := ss.byTF[tf]; !ok {
+		return nil, fmt.Errorf("timeframe %s not loaded for %s", tf, symbol)
+	}
+	return df.sliceUpTo(symbol, tf, ts), nil
+}
+
 func (df *DataFeed) BuildMarketData(ts int64) (map[string]*market.Data, map[string]map[string]*market.Data, error) {
 	result := make(map[string]*market.Data, len(df.symbols))
 	multi := make(map[string]map[string]*market.Data, len(df.symbols))
@@ -157,6 +221,9 @@ func (df *DataFeed) BuildMarketData(ts int64) (map[string]*market.Data, map[stri
 			if err != nil {
 				return nil, nil, err
 			}
+			if df.cfg.Derivatives != nil {
+				data.Derivatives = market.DerivativesAt(df.symbolSeries[symbol].derivatives, *df.cfg.Derivatives, ts)
+			}
 			perTF[tf] = data
 			if tf == df.primaryTF {
 				result[symbol] = data
@@ -37,6 +37,7 @@ type Runner struct {
 
 	decisionLogDir string
 	mcpClient      mcp.AIClient
+	provider       decision.DecisionProvider
 
 	statusMu sync.RWMutex
 	status   RunState
@@ -74,6 +75,11 @@ func NewRunner(cfg BacktestConfig, mcpClient mcp.AIClient) (*Runner, error) {
 		return nil, err
 	}
 
+	provider, err := newDecisionProvider(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+
 	feed, err := NewDataFeed(cfg)
 	if err != nil {
 		return nil, err
@@ -121,6 +127,7 @@ func NewRunner(cfg BacktestConfig, mcpClient mcp.AIClient) (*Runner, error) {
 		account:        account,
 		decisionLogDir: dLogDir,
 		mcpClient:      client,
+		provider:       provider,
 		status:         RunStateCreated,
 		state:          state,
 		pauseCh:        make(chan struct{}, 1),
@@ -303,7 +310,7 @@ func (r *Runner) stepOnce() error {
 			fromCache    bool
 			cacheKey     string
 		)
-		if r.aiCache != nil {
+		if r.aiCache != nil && !r.cfg.UsesRuleProvider() {
 			if key, err := computeCacheKey(ctx, r.cfg.PromptVariant, ts); err == nil {
 				cacheKey = key
 				if cached, ok := r.aiCache.Get(cacheKey); ok {
@@ -503,9 +510,20 @@ func (r *Runner) buildDecisionContext(ts int64, marketData map[string]*market.Da
 		MultiTFMarket:   multiTF,
 		BTCETHLeverage:  r.cfg.Leverage.BTCETHLeverage,
 		AltcoinLeverage: r.cfg.Leverage.AltcoinLeverage,
+		Klines: func(symbol, timeframe string) ([]market.Kline, error) {
+			return r.feed.KlinesUpTo(symbol, timeframe, ts)
+		},
+	}
+
+	// 市场概览基于决策周期K线（需加载 BTC 基准数据）
+	symbols := make([]string, 0, len(marketData))
+	for symbol := range marketData {
+		symbols = append(symbols, symbol)
 	}
+	ctx.MarketOverview = decision.LoadMarketOverview(ctx.Klines, r.cfg.DecisionTimeframe, symbols)
 
 	record := &store.DecisionRecord{
+		MarketRegime: ctx.MarketRegime(),
 		AccountState: store.AccountSnapshot{
 			TotalBalance:          accountInfo.TotalEquity,
 			AvailableBalance:      accountInfo.AvailableBalance,
@@ -534,16 +552,27 @@ func (r *Runner) fillDecisionRecord(record *store.DecisionRecord, full *decision
 	}
 }
 
+// newDecisionProvider 根据回测配置创建决策提供者（默认使用AI + 提示词模板）
+func newDecisionProvider(cfg BacktestConfig, client mcp.AIClient) (decision.DecisionProvider, error) {
+	if cfg.UsesRuleProvider() {
+		risk := store.RiskControlConfig{
+			BTCETHMaxLeverage:  cfg.Leverage.BTCETHLeverage,
+			AltcoinMaxLeverage: cfg.Leverage.AltcoinLeverage,
+		}
+		return decision.NewRuleProvider(*cfg.DecisionProvider, risk, cfg.DecisionTimeframe)
+	}
+	return decision.NewCustomPromptLLMProvider(client, cfg.CustomPrompt, cfg.OverrideBasePrompt, cfg.PromptTemplate), nil
+}
+
 func (r *Runner) invokeAIWithRetry(ctx *decision.Context) (*decision.FullDecision, error) {
+	// 规则策略结果是确定的，无需重试
+	if r.cfg.UsesRuleProvider() {
+		return r.provider.Decide(ctx)
+	}
+
 	var lastErr error
 	for attempt := 0; attempt < aiDecisionMaxRetries; attempt++ {
-		fd, err := decision.GetFullDecisionWithCustomPrompt(
-			ctx,
-			r.mcpClient,
-			r.cfg.CustomPrompt,
-			r.cfg.OverrideBasePrompt,
-			r.cfg.PromptTemplate,
-		)
+		fd, err := r.provider.Decide(ctx)
 		if err == nil {
 			return fd, nil
 		}
@@ -8,6 +8,7 @@ import (
 	"nofx/market"
 	"nofx/mcp"
 	"nofx/pool"
+	"nofx/store"
 	"regexp"
 	"strings"
 	"time"
@@ -59,7 +60,7 @@ type AccountInfo struct {
 // CandidateCoin 候选币种（来自币种池）
 type CandidateCoin struct {
 	Symbol  string   `json:"symbol"`
-	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"
+	Sources []string `json:"sources"` // 来源: "ai500"、"oi_top"、"local_rank"、"static"
 }
 
 // OITopData 持仓量增长Top数据（用于AI决策参考）
@@ -106,12 +107,27 @@ type Context struct {
 	PromptVariant   string                             `json:"prompt_variant,omitempty"`
 	TradingStats    *TradingStats                      `json:"trading_stats,omitempty"`  // 交易统计指标
 	RecentOrders    []RecentOrder                      `json:"recent_orders,omitempty"`  // 最近完成的订单（10条）
+	Signals         []*store.WebhookSignal             `json:"signals,omitempty"`        // 未过期的外部 Webhook 信号
+	MarketAlerts    []*store.MarketAlert               `json:"market_alerts,omitempty"`  // 近期行情警报（策略启用时）
+	MarketOverview  *market.MarketOverview             `json:"market_overview,omitempty"` // 市场概览（市场状态、BTC/ETH 相关性与 Beta）
+	DelistWarnings  []DelistingWarning                 `json:"delist_warnings,omitempty"` // 持仓币种下架预警
+	ExternalData    []*ExternalDataResult              `json:"external_data,omitempty"`  // 外部数据源缓存结果
 	MarketDataMap   map[string]*market.Data            `json:"-"`                        // 不序列化，但内部使用
 	MultiTFMarket   map[string]map[string]*market.Data `json:"-"`
 	OITopDataMap    map[string]*OITopData              `json:"-"` // OI Top数据映射
 	QuantDataMap    map[string]*QuantData              `json:"-"` // 量化数据映射（资金流向、持仓变化）
 	BTCETHLeverage  int                                `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
 	AltcoinLeverage int                                `json:"-"` // 山寨币杠杆倍数（从配置读取）
+	AIModel         string                             `json:"-"` // AI模型名称（用于 token 估算）
+	OnStreamChunk   mcp.StreamHandler                  `json:"-"` // 设置后以流式方式调用AI，实时推送正文/思维链片段
+	Klines          KlineSource                        `json:"-"` // 规则策略获取K线（为空时使用实时行情）
+	Market          market.MarketDataProvider          `json:"-"` // 行情数据源（为空时使用默认数据源 Binance）
+	Universe        *SymbolUniverse                    `json:"-"` // 可交易币种范围（为空时不限制开仓币种）
+}
+
+// marketProvider 本次决策使用的行情数据源
+func (ctx *Context) marketProvider() market.MarketDataProvider {
+	return market.OrDefault(ctx.Market)
 }
 
 // Decision AI的交易决策
@@ -140,6 +156,16 @@ type FullDecision struct {
 	Timestamp    time.Time  `json:"timestamp"`
 	// AIRequestDurationMs 记录 AI API 调用耗时（毫秒）方便排查延迟问题
 	AIRequestDurationMs int64 `json:"ai_request_duration_ms,omitempty"`
+	// TokenUsage Prompt token 估算与预算裁剪结果
+	TokenUsage *PromptTokenUsage `json:"token_usage,omitempty"`
+	// AIUsage AI返回的实际 token 用量（用于成本统计）
+	AIUsage *mcp.Usage `json:"ai_usage,omitempty"`
+	// FinishReason AI输出的结束原因（stop / length / content_filter 等）
+	FinishReason string `json:"finish_reason,omitempty"`
+	// AIProvider 实际应答的提供商（启用故障转移时可能是备用提供商）
+	AIProvider string `json:"ai_provider,omitempty"`
+	// AITarget 故障转移链中实际应答的目标名称（未启用故障转移时为空）
+	AITarget string `json:"ai_target,omitempty"`
 }
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
@@ -147,6 +173,21 @@ func GetFullDecision(ctx *Context, mcpClient mcp.AIClient) (*FullDecision, error
 	return GetFullDecisionWithCustomPrompt(ctx, mcpClient, "", false, "")
 }
 
+// callAI 调用AI API并返回结构化结果（上下文中设置了流式回调时使用流式调用）
+func callAI(ctx *Context, mcpClient mcp.AIClient, systemPrompt, userPrompt string) (*mcp.Result, error) {
+	if ctx.OnStreamChunk == nil {
+		return mcpClient.CallWithMessagesResult(systemPrompt, userPrompt)
+	}
+	content, err := mcpClient.CallWithMessagesStream(systemPrompt, userPrompt, ctx.OnStreamChunk)
+	if err != nil {
+		return nil, err
+	}
+	if result := mcp.LastResultOf(mcpClient); result != nil {
+		return result, nil
+	}
+	return mcp.NewResult(content, "", "", nil), nil
+}
+
 // GetFullDecisionWithStrategy 使用 StrategyEngine 获取AI决策（新版：策略驱动）
 // 关键：使用策略配置的时间周期来获取市场数据，与 api/strategy.go 的测试运行逻辑保持一致
 func GetFullDecisionWithStrategy(ctx *Context, mcpClient mcp.AIClient, engine *StrategyEngine, variant string) (*FullDecision, error) {
@@ -186,22 +227,22 @@ func GetFullDecisionWithStrategy(ctx *Context, mcpClient mcp.AIClient, engine *S
 
 	// 2. 使用策略引擎构建 System Prompt
 	riskConfig := engine.GetRiskControlConfig()
-	systemPrompt := engine.BuildSystemPrompt(ctx.Account.TotalEquity, variant)
+	systemPrompt := engine.BuildSystemPromptWithContext(ctx, variant)
 
-	// 3. 使用策略引擎构建 User Prompt（包含多周期数据）
-	userPrompt := engine.BuildUserPrompt(ctx)
+	// 3. 使用策略引擎构建 User Prompt（包含多周期数据，超出 token 预算时裁剪）
+	userPrompt, tokenUsage := engine.BuildBudgetedUserPrompt(ctx, systemPrompt, ctx.AIModel)
 
 	// 4. 调用AI API
 	aiCallStart := time.Now()
-	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
+	aiResult, err := callAI(ctx, mcpClient, systemPrompt, userPrompt)
 	aiCallDuration := time.Since(aiCallStart)
 	if err != nil {
 		return nil, fmt.Errorf("调用AI API失败: %w", err)
 	}
 
 	// 5. 解析AI响应
-	decision, err := parseFullDecisionResponse(
-		aiResponse,
+	decision, err := parseAIResult(
+		aiResult,
 		ctx.Account.TotalEquity,
 		riskConfig.BTCETHMaxLeverage,
 		riskConfig.AltcoinMaxLeverage,
@@ -212,6 +253,7 @@ func GetFullDecisionWithStrategy(ctx *Context, mcpClient mcp.AIClient, engine *S
 		decision.SystemPrompt = systemPrompt
 		decision.UserPrompt = userPrompt
 		decision.AIRequestDurationMs = aiCallDuration.Milliseconds()
+		decision.TokenUsage = tokenUsage
 	}
 
 	if err != nil {
@@ -249,12 +291,14 @@ func fetchMarketDataWithStrategy(ctx *Context, engine *StrategyEngine) error {
 	if klineCount <= 0 {
 		klineCount = 30
 	}
+	indicators := engine.indicatorSettings()
+	provider := ctx.marketProvider()
 
 	logger.Infof("📊 策略时间周期: %v, 主周期: %s, K线数量: %d", timeframes, primaryTimeframe, klineCount)
 
 	// 1. 先获取持仓币种的数据（必须获取）
 	for _, pos := range ctx.Positions {
-		data, err := market.GetWithTimeframes(pos.Symbol, timeframes, primaryTimeframe, klineCount)
+		data, err := market.GetWithIndicatorsFrom(provider, pos.Symbol, timeframes, primaryTimeframe, klineCount, indicators)
 		if err != nil {
 			logger.Infof("⚠️  获取持仓 %s 市场数据失败: %v", pos.Symbol, err)
 			continue
@@ -278,7 +322,7 @@ func fetchMarketDataWithStrategy(ctx *Context, engine *StrategyEngine) error {
 			continue
 		}
 
-		data, err := market.GetWithTimeframes(coin.Symbol, timeframes, primaryTimeframe, klineCount)
+		data, err := market.GetWithIndicatorsFrom(provider, coin.Symbol, timeframes, primaryTimeframe, klineCount, indicators)
 		if err != nil {
 			logger.Infof("⚠️  获取 %s 市场数据失败: %v", coin.Symbol, err)
 			continue
@@ -302,6 +346,9 @@ func fetchMarketDataWithStrategy(ctx *Context, engine *StrategyEngine) error {
 	}
 
 	logger.Infof("📊 成功获取 %d 个币种的多时间周期市场数据（已过滤低流动性币种）", len(ctx.MarketDataMap))
+
+	// 3. 基于主周期K线计算市场概览
+	ctx.loadMarketOverview(primaryTimeframe)
 	return nil
 }
 
@@ -335,14 +382,14 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, custo
 
 	// 3. 调用AI API（使用 system + user prompt）
 	aiCallStart := time.Now()
-	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
+	aiResult, err := callAI(ctx, mcpClient, systemPrompt, userPrompt)
 	aiCallDuration := time.Since(aiCallStart)
 	if err != nil {
 		return nil, fmt.Errorf("调用AI API失败: %w", err)
 	}
 
 	// 4. 解析AI响应
-	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
+	decision, err := parseAIResult(aiResult, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
 
 	// 无论是否有错误，都要保存 SystemPrompt 和 UserPrompt（用于调试和决策未执行后的问题定位）
 	if decision != nil {
@@ -392,7 +439,7 @@ func fetchMarketDataForContext(ctx *Context) error {
 	}
 
 	for symbol := range symbolSet {
-		data, err := market.Get(symbol)
+		data, err := market.GetFrom(ctx.marketProvider(), symbol)
 		if err != nil {
 			// 单个币种失败不影响整体，只记录错误
 			continue
@@ -436,6 +483,9 @@ func fetchMarketDataForContext(ctx *Context) error {
 		}
 	}
 
+	// 基于3分钟K线计算市场概览（与 market.Get 的短周期一致）
+	ctx.loadMarketOverview("3m")
+
 	return nil
 }
 
@@ -513,12 +563,15 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 			// 如果连 default 都不存在，使用内置的简化版本
 			logger.Infof("❌ 无法加载任何提示词模板，使用内置简化版本")
 			sb.WriteString("你是专业的加密货币交易AI。请根据市场数据做出交易决策。\n\n")
-		} else {
-			sb.WriteString(template.Content)
-			sb.WriteString("\n\n")
 		}
-	} else {
-		sb.WriteString(template.Content)
+	}
+	if template != nil {
+		data := NewPromptData(nil, store.RiskControlConfig{
+			BTCETHMaxLeverage:  btcEthLeverage,
+			AltcoinMaxLeverage: altcoinLeverage,
+		}, variant)
+		data.Account.TotalEquity = accountEquity
+		sb.WriteString(renderPromptTextOrRaw(template.Name, template.Content, data))
 		sb.WriteString("\n\n")
 	}
 
@@ -646,6 +699,9 @@ func buildUserPrompt(ctx *Context) string {
 		sb.WriteString("当前持仓: 无\n\n")
 	}
 
+	// 持仓下架预警（如果有）
+	sb.WriteString(formatDelistingWarnings(ctx.DelistWarnings, time.Now()))
+
 	// 交易统计（如果有）
 	if ctx.TradingStats != nil && ctx.TradingStats.TotalTrades > 0 {
 		sb.WriteString("## 历史交易统计\n")
@@ -678,6 +734,15 @@ func buildUserPrompt(ctx *Context) string {
 		sb.WriteString("\n")
 	}
 
+	// 外部 Webhook 信号（如果有）
+	sb.WriteString(formatWebhookSignals(ctx.Signals, time.Now()))
+
+	// 近期行情警报（如果有）
+	sb.WriteString(formatMarketAlerts(ctx.MarketAlerts, time.Now()))
+
+	// 市场概览（如果有）
+	sb.WriteString(formatMarketOverview(ctx.MarketOverview))
+
 	// 候选币种（完整市场数据）
 	sb.WriteString(fmt.Sprintf("## 候选币种 (%d个)\n\n", len(ctx.MarketDataMap)))
 	displayedCount := 0
@@ -708,10 +773,42 @@ func buildUserPrompt(ctx *Context) string {
 	return sb.String()
 }
 
+// parseAIResult 解析结构化的AI响应：优先使用提供商返回的原生思维链，并记录结束原因和用量
+func parseAIResult(result *mcp.Result, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
+	decision, err := parseFullDecisionResponse(result.Content, accountEquity, btcEthLeverage, altcoinLeverage)
+	if decision != nil {
+		if result.Reasoning != "" {
+			decision.CoTTrace = result.Reasoning
+		}
+		decision.FinishReason = result.FinishReason
+		decision.AIUsage = result.Usage
+		decision.AIProvider = result.Provider
+		decision.AITarget = result.Target
+	}
+	if err != nil && result.FinishReason == mcp.FinishReasonLength {
+		err = fmt.Errorf("%w（AI输出达到 max_tokens 上限被截断）", err)
+	}
+	return decision, err
+}
+
+// ParseAIResult 解析并验证AI响应（用于离线评估等不经过决策周期的场景）
+// parsed 表示是否成功提取出决策JSON：err 不为 nil 而 parsed 为 true 时，说明决策未通过验证
+func ParseAIResult(result *mcp.Result, accountEquity float64, btcEthLeverage, altcoinLeverage int) (decision *FullDecision, parsed bool, err error) {
+	decision, err = parseAIResult(result, accountEquity, btcEthLeverage, altcoinLeverage)
+	parsed = err == nil || (decision != nil && len(decision.Decisions) > 0)
+	return decision, parsed, err
+}
+
 // parseFullDecisionResponse 解析AI的完整决策响应
 func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
-	// 1. 提取思维链
-	cotTrace := extractCoTTrace(aiResponse)
+	// 0. 移除推理模型的 <think> 思考块，避免其中的示例JSON干扰决策提取
+	aiResponse, thinking := mcp.SplitThinking(aiResponse)
+
+	// 1. 提取思维链（有思考块时以思考块为准）
+	cotTrace := thinking
+	if cotTrace == "" {
+		cotTrace = extractCoTTrace(aiResponse)
+	}
 
 	// 2. 提取JSON决策列表
 	decisions, err := extractDecisions(aiResponse)
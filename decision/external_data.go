@@ -0,0 +1,413 @@
+package decision
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"nofx/logger"
+	"nofx/store"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultExternalRefresh   = 5 * time.Minute  // 默认刷新间隔
+	minExternalRefresh       = 10 * time.Second // 最小刷新间隔
+	defaultExternalTimeout   = 10 * time.Second // 默认请求超时
+	externalBackoffBase      = 15 * time.Second // 失败后首次重试间隔
+	externalBackoffMax       = 30 * time.Minute // 失败重试间隔上限
+	externalIdleTimeout      = 30 * time.Minute // 超过该时间无人使用的数据源停止刷新
+	externalMaxResponseBytes = 1 << 20
+	externalPromptValueChars = 1000 // Prompt 中单个数据源的最大字符数
+)
+
+// ExternalDataResult 外部数据源的缓存结果
+type ExternalDataResult struct {
+	Name        string      `json:"name"`
+	Value       interface{} `json:"value,omitempty"`
+	FetchedAt   time.Time   `json:"fetched_at"`         // 最近一次成功获取时间（零值表示尚未成功）
+	RefreshSecs int         `json:"refresh_secs"`       // 刷新间隔
+	Stale       bool        `json:"stale"`              // 超过 2 个刷新间隔未成功刷新
+	Failures    int         `json:"failures,omitempty"` // 连续失败次数
+	LastError   string      `json:"last_error,omitempty"`
+}
+
+// ExternalDataScheduler 共享外部数据调度器
+// 相同配置（URL/方法/请求头/路径/转换）的数据源在所有交易员间只拉取一次，按各自刷新间隔后台更新，
+// 失败时指数退避重试；长时间无人使用的数据源自动移除
+type ExternalDataScheduler struct {
+	mu      sync.Mutex
+	entries map[string]*externalDataEntry
+	fetch   func(source store.ExternalDataSource) (interface{}, error)
+	now     func() time.Time
+	once    sync.Once
+}
+
+type externalDataEntry struct {
+	source    store.ExternalDataSource
+	value     interface{}
+	fetchedAt time.Time
+	nextFetch time.Time
+	lastUsed  time.Time
+	failures  int
+	lastError string
+	fetching  bool
+	done      chan struct{} // 当前拉取完成时关闭
+}
+
+// NewExternalDataScheduler 创建外部数据调度器
+func NewExternalDataScheduler() *ExternalDataScheduler {
+	return &ExternalDataScheduler{
+		entries: make(map[string]*externalDataEntry),
+		fetch:   fetchExternalSource,
+		now:     time.Now,
+	}
+}
+
+// defaultExternalData 进程内共享的外部数据调度器
+var defaultExternalData = NewExternalDataScheduler()
+
+// FetchExternalData 从共享调度器获取策略配置的外部数据（首次请求时同步拉取）
+func (e *StrategyEngine) FetchExternalData() []*ExternalDataResult {
+	return defaultExternalData.Get(e.config.Indicators.ExternalDataSources)
+}
+
+// Get 返回数据源的缓存结果；尚未获取过的数据源同步拉取一次，之后由后台按刷新间隔更新
+// "webhook" 类型由入站 Webhook 推送，这里跳过
+func (s *ExternalDataScheduler) Get(sources []store.ExternalDataSource) []*ExternalDataResult {
+	s.once.Do(func() { go s.loop() })
+
+	now := s.now()
+	keys := make([]string, 0, len(sources))
+	var pending []chan struct{}
+
+	s.mu.Lock()
+	for _, source := range sources {
+		if source.Type == "webhook" {
+			continue
+		}
+		key := externalSourceKey(source)
+		keys = append(keys, key)
+		entry, ok := s.entries[key]
+		if !ok {
+			entry = &externalDataEntry{source: source, nextFetch: now}
+			s.entries[key] = entry
+		}
+		entry.lastUsed = now
+		if entry.fetchedAt.IsZero() && entry.failures == 0 {
+			if done := s.startFetchLocked(entry); done != nil {
+				pending = append(pending, done)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, done := range pending {
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now = s.now()
+	results := make([]*ExternalDataResult, 0, len(keys))
+	i := 0
+	for _, source := range sources {
+		if source.Type == "webhook" {
+			continue
+		}
+		entry := s.entries[keys[i]]
+		i++
+		if entry == nil {
+			continue
+		}
+		refresh := externalRefreshInterval(source)
+		results = append(results, &ExternalDataResult{
+			Name:        source.Name,
+			Value:       entry.value,
+			FetchedAt:   entry.fetchedAt,
+			RefreshSecs: int(refresh.Seconds()),
+			Stale:       entry.fetchedAt.IsZero() || now.Sub(entry.fetchedAt) > 2*refresh,
+			Failures:    entry.failures,
+			LastError:   entry.lastError,
+		})
+	}
+	return results
+}
+
+// startFetchLocked 开始拉取（调用方持有锁）；已在拉取中时返回当前拉取的完成通道
+func (s *ExternalDataScheduler) startFetchLocked(entry *externalDataEntry) chan struct{} {
+	if entry.fetching {
+		return entry.done
+	}
+	entry.fetching = true
+	entry.done = make(chan struct{})
+	done := entry.done
+	source := entry.source
+
+	go func() {
+		value, err := s.fetch(source)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := s.now()
+		entry.fetching = false
+		close(done)
+		if err != nil {
+			entry.failures++
+			entry.lastError = err.Error()
+			entry.nextFetch = now.Add(externalBackoff(entry.failures))
+			logger.Infof("⚠️  外部数据源 [%s] 获取失败（连续 %d 次），%v 后重试: %v",
+				source.Name, entry.failures, externalBackoff(entry.failures), err)
+			return
+		}
+		entry.value = value
+		entry.fetchedAt = now
+		entry.failures = 0
+		entry.lastError = ""
+		entry.nextFetch = now.Add(externalRefreshInterval(source))
+	}()
+	return done
+}
+
+// loop 后台刷新到期的数据源
+func (s *ExternalDataScheduler) loop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.refreshDue()
+	}
+}
+
+// refreshDue 移除闲置的数据源，并对到期的数据源发起拉取
+func (s *ExternalDataScheduler) refreshDue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for key, entry := range s.entries {
+		if now.Sub(entry.lastUsed) > externalIdleTimeout && !entry.fetching {
+			delete(s.entries, key)
+			continue
+		}
+		if !now.Before(entry.nextFetch) {
+			s.startFetchLocked(entry)
+		}
+	}
+}
+
+// externalSourceKey 数据源去重键（名称不参与）
+func externalSourceKey(source store.ExternalDataSource) string {
+	source.Name = ""
+	data, _ := json.Marshal(source)
+	return string(data)
+}
+
+func externalRefreshInterval(source store.ExternalDataSource) time.Duration {
+	if source.RefreshSecs <= 0 {
+		return defaultExternalRefresh
+	}
+	return max(time.Duration(source.RefreshSecs)*time.Second, minExternalRefresh)
+}
+
+// externalBackoff 连续失败 n 次后的重试间隔
+func externalBackoff(failures int) time.Duration {
+	backoff := externalBackoffBase
+	for i := 1; i < failures && backoff < externalBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > externalBackoffMax {
+		return externalBackoffMax
+	}
+	return backoff
+}
+
+// fetchExternalSource 请求数据源，按 JSONPath 提取并执行值转换
+func fetchExternalSource(source store.ExternalDataSource) (interface{}, error) {
+	timeout := defaultExternalTimeout
+	if source.TimeoutSecs > 0 {
+		timeout = time.Duration(source.TimeoutSecs) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	method := strings.ToUpper(source.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if source.Body != "" {
+		body = bytes.NewBufferString(source.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, source.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range source.Headers {
+		req.Header.Set(k, v)
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, externalMaxResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %w", err)
+	}
+
+	if source.DataPath != "" {
+		path, err := compileJSONPath(source.DataPath)
+		if err != nil {
+			return nil, err
+		}
+		result = path.evaluate(result)
+	}
+	return applyExternalTransform(result, source.Transform), nil
+}
+
+// applyExternalTransform 对提取结果中的每个标量执行 scale → round → map
+func applyExternalTransform(value interface{}, t *store.ExternalDataTransform) interface{} {
+	if t == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = applyExternalTransform(item, t)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = applyExternalTransform(item, t)
+		}
+		return out
+	case string:
+		// 数值字符串（API 常见）按数值处理
+		if f, err := strconv.ParseFloat(v, 64); err == nil && (t.Scale != 0 || t.Round != nil) {
+			return applyExternalTransform(f, t)
+		}
+	case float64:
+		if t.Scale != 0 {
+			v *= t.Scale
+		}
+		if t.Round != nil {
+			pow := math.Pow(10, float64(*t.Round))
+			v = math.Round(v*pow) / pow
+		}
+		value = v
+	}
+
+	if len(t.Map) > 0 {
+		if mapped, ok := t.Map[formatJSONScalar(value)]; ok {
+			return mapped
+		}
+	}
+	return value
+}
+
+// formatJSONScalar 标量的字符串形式（用于值映射）
+func formatJSONScalar(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(value)
+}
+
+// ValidateExternalDataSources 校验外部数据源配置
+func ValidateExternalDataSources(sources []store.ExternalDataSource) error {
+	names := make(map[string]bool)
+	for i, source := range sources {
+		if source.Name == "" {
+			return fmt.Errorf("外部数据源 #%d 缺少名称", i+1)
+		}
+		if names[source.Name] {
+			return fmt.Errorf("外部数据源名称重复: %s", source.Name)
+		}
+		names[source.Name] = true
+
+		switch source.Type {
+		case "webhook":
+			continue
+		case "", "api":
+		default:
+			return fmt.Errorf("外部数据源 [%s] 类型无效: %s", source.Name, source.Type)
+		}
+		if !strings.HasPrefix(source.URL, "http://") && !strings.HasPrefix(source.URL, "https://") {
+			return fmt.Errorf("外部数据源 [%s] URL 无效", source.Name)
+		}
+		switch strings.ToUpper(source.Method) {
+		case "", http.MethodGet, http.MethodPost:
+		default:
+			return fmt.Errorf("外部数据源 [%s] 不支持的请求方法: %s", source.Name, source.Method)
+		}
+		if source.RefreshSecs < 0 || source.RefreshSecs > 86400 {
+			return fmt.Errorf("外部数据源 [%s] 刷新间隔必须在 0-86400 秒之间", source.Name)
+		}
+		if source.TimeoutSecs < 0 || source.TimeoutSecs > 60 {
+			return fmt.Errorf("外部数据源 [%s] 超时必须在 0-60 秒之间", source.Name)
+		}
+		if source.DataPath != "" {
+			if _, err := compileJSONPath(source.DataPath); err != nil {
+				return fmt.Errorf("外部数据源 [%s] 数据路径无效: %w", source.Name, err)
+			}
+		}
+		if t := source.Transform; t != nil && t.Round != nil && (*t.Round < 0 || *t.Round > 12) {
+			return fmt.Errorf("外部数据源 [%s] 保留小数位必须在 0-12 之间", source.Name)
+		}
+	}
+	return nil
+}
+
+// formatExternalData 格式化外部数据（含缓存时效）
+func formatExternalData(results []*ExternalDataResult, now time.Time) string {
+	if len(results) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 外部数据\n")
+	for _, r := range results {
+		if r.FetchedAt.IsZero() {
+			sb.WriteString(fmt.Sprintf("- %s: 暂无数据（获取失败: %s）\n", r.Name, r.LastError))
+			continue
+		}
+		status := fmt.Sprintf("%s前更新", formatPromptDuration(now.Sub(r.FetchedAt)))
+		if r.Stale {
+			status += "，数据已过期"
+		}
+		value, _ := json.Marshal(r.Value)
+		text := string(value)
+		if runes := []rune(text); len(runes) > externalPromptValueChars {
+			text = string(runes[:externalPromptValueChars]) + "...(已截断)"
+		}
+		sb.WriteString(fmt.Sprintf("- %s（%s）: %s\n", r.Name, status, text))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,228 @@
+package decision
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nofx/store"
+)
+
+const testExternalJSON = `{
+	"data": {
+		"items": [
+			{"symbol": "BTC", "rate": 0.0001, "tags": ["major"]},
+			{"symbol": "ETH", "rate": -0.0002},
+			{"symbol": "SOL", "rate": 0.0005, "meta": {"rate": 9}}
+		],
+		"count": 3
+	}
+}`
+
+// TestExtractJSONPath 测试 JSONPath 下标、切片、通配、递归和过滤
+func TestExtractJSONPath(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(testExternalJSON), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"data.count", 3.0},
+		{"$.data.count", 3.0},
+		{"$['data']['count']", 3.0},
+		{"$.data.items[0].symbol", "BTC"},
+		{"$.data.items[-1].symbol", "SOL"},
+		{"$.data.items[5]", nil},
+		{"$.data.items[*].symbol", []interface{}{"BTC", "ETH", "SOL"}},
+		{"$.data.items[0:2].symbol", []interface{}{"BTC", "ETH"}},
+		{"$.data.items[0,2].rate", []interface{}{0.0001, 0.0005}},
+		{"$..rate", []interface{}{0.0001, -0.0002, 0.0005, 9.0}},
+		{"$.data.items[?(@.rate < 0)].symbol", []interface{}{"ETH"}},
+		{"$.data.items[?(@.symbol == 'SOL' || @.symbol == \"BTC\")].rate", []interface{}{0.0001, 0.0005}},
+		{"$.data.items[?(@.rate > 0 && @.tags)].symbol", []interface{}{"BTC"}},
+		{"$.data.items[?(@.symbol == 'XRP')]", []interface{}{}},
+	}
+
+	for _, tt := range tests {
+		got := extractJSONPath(data, tt.path)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"$.data[", "$.data[?(@.a >)]", "$.data[1:2:3]", "$..", "$.data[?(a == 1)]"} {
+		if _, err := compileJSONPath(invalid); err == nil {
+			t.Errorf("%s should be rejected", invalid)
+		}
+	}
+}
+
+// TestApplyExternalTransform 测试缩放、取整和值映射
+func TestApplyExternalTransform(t *testing.T) {
+	round := 2
+	got := applyExternalTransform([]interface{}{0.00012345, "0.0005", "n/a"}, &store.ExternalDataTransform{Scale: 10000, Round: &round})
+	want := []interface{}{1.23, 5.0, "n/a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scale/round = %#v, want %#v", got, want)
+	}
+
+	mapped := applyExternalTransform(map[string]interface{}{"signal": 1.0, "other": 0.0},
+		&store.ExternalDataTransform{Map: map[string]string{"1": "bullish", "-1": "bearish"}})
+	if m := mapped.(map[string]interface{}); m["signal"] != "bullish" || m["other"] != 0.0 {
+		t.Errorf("map = %#v", mapped)
+	}
+}
+
+// TestExternalDataScheduler 测试共享缓存、刷新间隔和失败退避
+func TestExternalDataScheduler(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fail := false
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := NewExternalDataScheduler()
+	s.once.Do(func() {}) // 测试中手动驱动 refreshDue
+	s.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	s.fetch = func(source store.ExternalDataSource) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if fail {
+			return nil, errors.New("boom")
+		}
+		return float64(calls), nil
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	// 等待后台拉取完成
+	settle := func() {
+		for i := 0; i < 100; i++ {
+			s.mu.Lock()
+			busy := false
+			for _, e := range s.entries {
+				busy = busy || e.fetching
+			}
+			s.mu.Unlock()
+			if !busy {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	source := store.ExternalDataSource{Name: "a", URL: "http://example.com", RefreshSecs: 60}
+	renamed := source
+	renamed.Name = "b"
+
+	// 首次同步拉取，同配置不同名称共享缓存
+	results := s.Get([]store.ExternalDataSource{source, renamed, {Name: "hook", Type: "webhook"}})
+	if len(results) != 2 || results[0].Value != 1.0 || results[1].Value != 1.0 || results[1].Name != "b" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+
+	// 未到刷新间隔不拉取
+	advance(30 * time.Second)
+	s.refreshDue()
+	settle()
+	if calls != 1 {
+		t.Errorf("calls = %d before refresh interval", calls)
+	}
+
+	// 到期后台刷新
+	advance(31 * time.Second)
+	s.refreshDue()
+	settle()
+	if r := s.Get([]store.ExternalDataSource{source}); r[0].Value != 2.0 || r[0].Stale {
+		t.Errorf("after refresh: %+v", r[0])
+	}
+
+	// 失败后保留旧值并退避
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	advance(61 * time.Second)
+	s.refreshDue()
+	settle()
+	r := s.Get([]store.ExternalDataSource{source})[0]
+	if r.Value != 2.0 || r.Failures != 1 || r.LastError != "boom" {
+		t.Errorf("after failure: %+v", r)
+	}
+	advance(externalBackoffBase - time.Second)
+	s.refreshDue()
+	settle()
+	if calls != 3 {
+		t.Errorf("calls = %d, retry should wait for backoff", calls)
+	}
+	advance(2 * time.Second)
+	s.refreshDue()
+	settle()
+	if r := s.Get([]store.ExternalDataSource{source})[0]; r.Failures != 2 || calls != 4 {
+		t.Errorf("after second failure: calls=%d %+v", calls, r)
+	}
+
+	// 超过 2 个刷新间隔未成功刷新标记为过期
+	advance(2 * time.Minute)
+	if r := s.Get([]store.ExternalDataSource{source})[0]; !r.Stale {
+		t.Errorf("result should be stale: %+v", r)
+	}
+
+	if got := externalBackoff(20); got != externalBackoffMax {
+		t.Errorf("backoff cap = %v", got)
+	}
+}
+
+// TestFormatExternalData 测试外部数据在 Prompt 中的时效标注
+func TestFormatExternalData(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	out := formatExternalData([]*ExternalDataResult{
+		{Name: "funding", Value: []interface{}{1.5}, FetchedAt: now.Add(-3 * time.Minute)},
+		{Name: "fear_greed", Value: 20.0, FetchedAt: now.Add(-2 * time.Hour), Stale: true},
+		{Name: "broken", LastError: "HTTP 500"},
+	}, now)
+
+	for _, want := range []string{"## 外部数据", "funding（3分钟前更新）: [1.5]", "数据已过期", "broken: 暂无数据（获取失败: HTTP 500）"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+// TestValidateExternalDataSources 测试外部数据源配置校验
+func TestValidateExternalDataSources(t *testing.T) {
+	valid := []store.ExternalDataSource{
+		{Name: "a", Type: "api", URL: "https://example.com", DataPath: "$.data[?(@.x > 1)]"},
+		{Name: "b", Type: "webhook"},
+	}
+	if err := ValidateExternalDataSources(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := [][]store.ExternalDataSource{
+		{{Name: "a", URL: "ftp://example.com"}},
+		{{Name: "a", URL: "https://example.com", DataPath: "$.data["}},
+		{{Name: "a", URL: "https://example.com"}, {Name: "a", URL: "https://example.com"}},
+		{{Name: "a", URL: "https://example.com", Method: "DELETE"}},
+	}
+	for i, sources := range invalid {
+		if err := ValidateExternalDataSources(sources); err == nil {
+			t.Errorf("case %d should be rejected", i)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// compiledGuardRule 已解析的规则
+type compiledGuardRule struct {
+	name    string
+	expr    *GuardExpr
+	actions map[string]bool // 为空表示所有开仓动作
+	onError string          // 无法评估时的处理（store.GuardOnErrorBlock/Skip，为空时使用调用方的默认值）
+}
+
+// blockOnError 规则无法评估时是否否决决策/排除候选币
+func (r *compiledGuardRule) blockOnError(def string) bool {
+	if r.onError == "" {
+		return def == store.GuardOnErrorBlock
+	}
+	return r.onError == store.GuardOnErrorBlock
+}
+
+func (r *compiledGuardRule) label() string {
+	if r.name != "" {
+		return fmt.Sprintf("%s (%s)", r.name, r.expr)
+	}
+	return r.expr.String()
+}
+
+// VetoedDecision 被规则否决的决策
+type VetoedDecision struct {
+	Decision Decision
+	Rule     string
+	Reason   string
+}
+
+// compileGuardRules 解析规则列表（跳过已暂停的规则）
+func compileGuardRules(rules []store.GuardRule) ([]*compiledGuardRule, error) {
+	compiled := make([]*compiledGuardRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule.Disabled {
+			continue
+		}
+		expr, err := ParseGuardExpr(rule.Expr)
+		if err != nil {
+			name := rule.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("规则 %s 表达式错误: %w", name, err)
+		}
+		c := &compiledGuardRule{name: rule.Name, expr: expr, onError: strings.ToLower(strings.TrimSpace(rule.OnError))}
+		if len(rule.Actions) > 0 {
+			c.actions = make(map[string]bool, len(rule.Actions))
+			for _, action := range rule.Actions {
+				c.actions[strings.ToLower(strings.TrimSpace(action))] = true
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// ValidateGuardConfig 校验规则表达式语法（保存策略时调用）
+func ValidateGuardConfig(config store.GuardConfig) error {
+	filters, err := compileGuardRules(config.CandidateFilters)
+	if err != nil {
+		return fmt.Errorf("候选币过滤%w", err)
+	}
+	vetoes, err := compileGuardRules(config.Vetoes)
+	if err != nil {
+		return fmt.Errorf("决策否决%w", err)
+	}
+	for _, rule := range append(filters, vetoes...) {
+		switch rule.onError {
+		case "", store.GuardOnErrorBlock, store.GuardOnErrorSkip:
+		default:
+			return fmt.Errorf("规则 %s 的 on_error 无效: %s（可选: %s/%s）", rule.label(), rule.onError, store.GuardOnErrorBlock, store.GuardOnErrorSkip)
+		}
+	}
+	validActions := map[string]bool{"open_long": true, "open_short": true, "close_long": true, "close_short": true, "hold": true, "wait": true}
+	for _, rule := range vetoes {
+		for action := range rule.actions {
+			if !validActions[action] {
+				return fmt.Errorf("决策否决规则 %s 的动作无效: %s", rule.label(), action)
+			}
+		}
+	}
+	return nil
+}
+
+// guardEnv 规则表达式的变量环境：币种行情、账户、持仓和待执行的决策
+// 行情数据按需获取（只有表达式用到时才请求）
+type guardEnv struct {
+	symbol  string
+	sources []string
+
+	data       *market.Data
+	fetchData  func(symbol string) (*market.Data, error)
+	dataErr    error
+	fetched    bool
+	tfData     map[string]*market.TimeframeSeriesData
+	primaryTF  string
+	klineCount int
+	indicators *market.IndicatorSettings
+	ticker     *market.Ticker24hr
+	provider   market.MarketDataProvider
+
+	account   *AccountInfo
+	positions []PositionInfo
+	decision  *Decision
+}
+
+func (env *guardEnv) marketData() (*market.Data, error) {
+	if env.data == nil && !env.fetched && env.fetchData != nil {
+		env.fetched = true
+		env.data, env.dataErr = env.fetchData(env.symbol)
+	}
+	if env.data == nil {
+		if env.dataErr != nil {
+			return nil, fmt.Errorf("获取 %s 行情失败: %w", env.symbol, env.dataErr)
+		}
+		return nil, fmt.Errorf("缺少 %s 行情数据", env.symbol)
+	}
+	return env.data, nil
+}
+
+// timeframeSeries 指定周期的序列数据（策略未加载该周期时单独获取）
+func (env *guardEnv) timeframeSeries(timeframe string) (*market.TimeframeSeriesData, error) {
+	if series, ok := env.tfData[timeframe]; ok {
+		return series, nil
+	}
+	if data, err := env.marketData(); err == nil && data.TimeframeData[timeframe] != nil {
+		return data.TimeframeData[timeframe], nil
+	}
+	if _, err := market.NormalizeTimeframe(timeframe); err != nil {
+		return nil, err
+	}
+	count := env.klineCount
+	if count <= 0 {
+		count = 30
+	}
+	data, err := market.GetWithIndicatorsFrom(env.provider, env.symbol, []string{timeframe}, timeframe, count, env.indicators)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s %s K线失败: %w", env.symbol, timeframe, err)
+	}
+	if env.tfData == nil {
+		env.tfData = make(map[string]*market.TimeframeSeriesData)
+	}
+	env.tfData[timeframe] = data.TimeframeData[timeframe]
+	if env.tfData[timeframe] == nil {
+		return nil, fmt.Errorf("缺少 %s %s 序列数据", env.symbol, timeframe)
+	}
+	return env.tfData[timeframe], nil
+}
+
+func (env *guardEnv) ticker24h() (*market.Ticker24hr, error) {
+	if env.ticker == nil {
+		ticker, err := market.OrDefault(env.provider).GetTicker(env.symbol)
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 24小时行情失败: %w", env.symbol, err)
+		}
+		env.ticker = ticker
+	}
+	return env.ticker, nil
+}
+
+func (env *guardEnv) lookup(name, timeframe string) (any, error) {
+	if timeframe != "" {
+		series, err := env.timeframeSeries(timeframe)
+		if err != nil {
+			return nil, err
+		}
+		return seriesValue(series, name)
+	}
+
+	switch {
+	case strings.HasPrefix(name, "account."):
+		return env.accountValue(strings.TrimPrefix(name, "account."))
+	case strings.HasPrefix(name, "position."):
+		return env.positionValue(strings.TrimPrefix(name, "position."))
+	case strings.HasPrefix(name, "decision."):
+		return env.decisionValue(strings.TrimPrefix(name, "decision."))
+	}
+
+	switch name {
+	case "symbol":
+		return env.symbol, nil
+	case "sources":
+		return env.sources, nil
+	case "quote_volume_24h", "volume_24h", "price_change_24h":
+		ticker, err := env.ticker24h()
+		if err != nil {
+			return nil, err
+		}
+		raw := map[string]string{
+			"quote_volume_24h": ticker.QuoteVolume,
+			"volume_24h":       ticker.Volume,
+			"price_change_24h": ticker.PriceChangePercent,
+		}[name]
+		return strconv.ParseFloat(raw, 64)
+	}
+
+	data, err := env.marketData()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "price":
+		return data.CurrentPrice, nil
+	case "price_change_1h":
+		return data.PriceChange1h, nil
+	case "price_change_4h":
+		return data.PriceChange4h, nil
+	case "ema20":
+		return data.CurrentEMA20, nil
+	case "macd":
+		return data.CurrentMACD, nil
+	case "rsi7":
+		return data.CurrentRSI7, nil
+	case "funding_rate":
+		return data.FundingRate, nil
+	case "oi", "oi_value":
+		if data.OpenInterest == nil {
+			return nil, fmt.Errorf("缺少 %s 持仓量数据", env.symbol)
+		}
+		if name == "oi" {
+			return data.OpenInterest.Latest, nil
+		}
+		return data.OpenInterest.Latest * data.CurrentPrice, nil
+	}
+
+	// 其他名称视为主周期序列
+	if series := data.TimeframeData[env.primaryTF]; series != nil {
+		return seriesValue(series, name)
+	}
+	return nil, fmt.Errorf("未知变量: %s", name)
+}
+
+// seriesValue 周期序列的最新值
+func seriesValue(series *market.TimeframeSeriesData, name string) (any, error) {
+	var values []float64
+	switch name {
+	case "price", "close":
+		values = series.MidPrices
+	case "ema20":
+		values = series.EMA20Values
+	case "ema50":
+		values = series.EMA50Values
+	case "macd":
+		values = series.MACDValues
+	case "rsi7":
+		values = series.RSI7Values
+	case "rsi14":
+		values = series.RSI14Values
+	case "volume":
+		values = series.Volume
+	case "atr14":
+		return series.ATR14, nil
+	default:
+		// 按策略配置计算的命名序列（如 ema9、adx、bb_upper）
+		named, ok := series.Series[name]
+		if !ok {
+			return nil, fmt.Errorf("未知变量: %s@%s", name, series.Timeframe)
+		}
+		values = named
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%s@%s 没有数据", name, series.Timeframe)
+	}
+	return values[len(values)-1], nil
+}
+
+func (env *guardEnv) accountValue(field string) (any, error) {
+	if env.account == nil {
+		return nil, fmt.Errorf("当前阶段没有账户数据: account.%s", field)
+	}
+	switch field {
+	case "equity":
+		return env.account.TotalEquity, nil
+	case "available":
+		return env.account.AvailableBalance, nil
+	case "unrealized_pnl":
+		return env.account.UnrealizedPnL, nil
+	case "total_pnl":
+		return env.account.TotalPnL, nil
+	case "total_pnl_pct":
+		return env.account.TotalPnLPct, nil
+	case "margin_used":
+		return env.account.MarginUsed, nil
+	case "margin_used_pct":
+		return env.account.MarginUsedPct, nil
+	case "position_count":
+		return env.account.PositionCount, nil
+	}
+	return nil, fmt.Errorf("未知变量: account.%s", field)
+}
+
+// positionValue 当前币种的持仓（平仓决策按方向匹配）
+func (env *guardEnv) positionValue(field string) (any, error) {
+	if env.account == nil {
+		return nil, fmt.Errorf("当前阶段没有持仓数据: position.%s", field)
+	}
+	var pos *PositionInfo
+	for i := range env.positions {
+		p := &env.positions[i]
+		if p.Symbol != env.symbol {
+			continue
+		}
+		if env.decision != nil && strings.HasPrefix(env.decision.Action, "close_") &&
+			p.Side != strings.TrimPrefix(env.decision.Action, "close_") {
+			continue
+		}
+		pos = p
+		break
+	}
+	if field == "exists" {
+		return pos != nil, nil
+	}
+	if pos == nil {
+		return nil, fmt.Errorf("%s 没有持仓: position.%s", env.symbol, field)
+	}
+	switch field {
+	case "side":
+		return pos.Side, nil
+	case "entry_price":
+		return pos.EntryPrice, nil
+	case "mark_price":
+		return pos.MarkPrice, nil
+	case "quantity":
+		return pos.Quantity, nil
+	case "leverage":
+		return pos.Leverage, nil
+	case "unrealized_pnl":
+		return pos.UnrealizedPnL, nil
+	case "pnl_pct":
+		return pos.UnrealizedPnLPct, nil
+	case "peak_pnl_pct":
+		return pos.PeakPnLPct, nil
+	case "liquidation_price":
+		return pos.LiquidationPrice, nil
+	case "margin_used":
+		return pos.MarginUsed, nil
+	case "holding_minutes":
+		if pos.UpdateTime <= 0 {
+			return 0.0, nil
+		}
+		return time.Since(time.UnixMilli(pos.UpdateTime)).Minutes(), nil
+	}
+	return nil, fmt.Errorf("未知变量: position.%s", field)
+}
+
+func (env *guardEnv) decisionValue(field string) (any, error) {
+	if env.decision == nil {
+		return nil, fmt.Errorf("当前阶段没有决策数据: decision.%s", field)
+	}
+	d := env.decision
+	switch field {
+	case "action":
+		return d.Action, nil
+	case "side":
+		switch d.Action {
+		case "open_long", "close_long":
+			return "long", nil
+		case "open_short", "close_short":
+			return "short", nil
+		}
+		return "", nil
+	case "leverage":
+		return d.Leverage, nil
+	case "position_size_usd":
+		return d.PositionSizeUSD, nil
+	case "stop_loss":
+		return d.StopLoss, nil
+	case "take_profit":
+		return d.TakeProfit, nil
+	case "confidence":
+		return d.Confidence, nil
+	case "risk_usd":
+		return d.RiskUSD, nil
+	}
+	return nil, fmt.Errorf("未知变量: decision.%s", field)
+}
+
+// klineSettings 策略的主周期与K线数量（表达式按需获取行情时使用）
+func (e *StrategyEngine) klineSettings() (string, []string, int) {
+	klines := e.config.Indicators.Klines
+	timeframes := klines.SelectedTimeframes
+	primary := klines.PrimaryTimeframe
+	if len(timeframes) == 0 {
+		if primary != "" {
+			timeframes = []string{primary}
+		} else {
+			timeframes = []string{"3m"}
+		}
+	}
+	if primary == "" {
+		primary = timeframes[0]
+	}
+	count := klines.PrimaryCount
+	if count <= 0 {
+		count = 30
+	}
+	return primary, timeframes, count
+}
+
+// filterCandidates 候选币预过滤：不满足任一条件的候选币被排除
+// 表达式无法求值（如数据缺失）时保留候选币并记录警告
+func (e *StrategyEngine) filterCandidates(candidates []CandidateCoin) ([]CandidateCoin, error) {
+	rules, err := compileGuardRules(e.config.Guards.CandidateFilters)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return candidates, nil
+	}
+
+	primary, timeframes, count := e.klineSettings()
+	indicators := e.indicatorSettings()
+	provider := e.MarketProvider()
+	fetch := func(symbol string) (*market.Data, error) {
+		return market.GetWithIndicatorsFrom(provider, symbol, timeframes, primary, count, indicators)
+	}
+
+	kept := make([]CandidateCoin, 0, len(candidates))
+	for _, coin := range candidates {
+		env := &guardEnv{symbol: coin.Symbol, sources: coin.Sources, fetchData: fetch, primaryTF: primary, klineCount: count, indicators: indicators, provider: provider}
+		passed := true
+		for _, rule := range rules {
+			ok, err := rule.expr.evalBool(env)
+			if err != nil {
+				if !rule.blockOnError(store.GuardOnErrorSkip) {
+					logger.Infof("⚠️  候选币过滤规则 %s 无法评估 %s，跳过该规则: %v", rule.label(), coin.Symbol, err)
+					continue
+				}
+				logger.Infof("🚫 候选币过滤规则 %s 无法评估 %s，排除该币种: %v", rule.label(), coin.Symbol, err)
+				ok = false
+			}
+			if !ok {
+				logger.Infof("🚫 %s 未通过候选币过滤规则: %s", coin.Symbol, rule.label())
+				passed = false
+				break
+			}
+		}
+		if passed {
+			kept = append(kept, coin)
+		}
+	}
+	if len(kept) < len(candidates) {
+		logger.Infof("📋 候选币过滤: %d → %d", len(candidates), len(kept))
+	}
+	return kept, nil
+}
+
+// ApplyVetoes 决策否决：规则条件成立时拒绝执行该决策
+// 未指定动作的规则只作用于开仓决策；表达式无法求值时按规则的 on_error 处理（默认 block 否决，skip 跳过该规则）
+// 规则编译失败时否决全部开仓决策（平仓不受影响）并返回错误
+// 上下文设置了可交易币种范围时，范围外币种的开仓决策先被否决
+func (e *StrategyEngine) ApplyVetoes(ctx *Context, decisions []Decision) ([]Decision, []VetoedDecision, error) {
+	// 不在可交易范围内（禁止列表、暂停交易、计划下架）的币种禁止开仓
+	decisions, vetoed := ctx.Universe.vetoOpens(decisions)
+	if e == nil || e.config == nil {
+		return decisions, vetoed, nil
+	}
+	rules, err := compileGuardRules(e.config.Guards.Vetoes)
+	if err != nil {
+		kept := make([]Decision, 0, len(decisions))
+		for _, d := range decisions {
+			if d.Action == "open_long" || d.Action == "open_short" {
+				vetoed = append(vetoed, VetoedDecision{
+					Decision: d,
+					Rule:     "guards",
+					Reason:   fmt.Sprintf("%s %s 被否决: 否决规则配置错误（%v）", d.Symbol, d.Action, err),
+				})
+				continue
+			}
+			kept = append(kept, d)
+		}
+		return kept, vetoed, err
+	}
+	if len(rules) == 0 {
+		return decisions, vetoed, nil
+	}
+
+	primary, timeframes, count := e.klineSettings()
+	indicators := e.indicatorSettings()
+	provider := e.MarketProvider()
+	if ctx.Market != nil {
+		provider = ctx.Market
+	}
+	kept := make([]Decision, 0, len(decisions))
+	for i := range decisions {
+		d := decisions[i]
+		env := &guardEnv{
+			symbol:     d.Symbol,
+			primaryTF:  primary,
+			klineCount: count,
+			indicators: indicators,
+			provider:   provider,
+			account:    &ctx.Account,
+			positions:  ctx.Positions,
+			decision:   &d,
+		}
+		if data, ok := ctx.MarketDataMap[d.Symbol]; ok {
+			env.data = data
+		} else {
+			env.fetchData = func(symbol string) (*market.Data, error) {
+				return market.GetWithIndicatorsFrom(provider, symbol, timeframes, primary, count, indicators)
+			}
+		}
+
+		var veto *VetoedDecision
+		for _, rule := range rules {
+			if !rule.appliesTo(d.Action) {
+				continue
+			}
+			hit, err := rule.expr.evalBool(env)
+			if err != nil {
+				if !rule.blockOnError(store.GuardOnErrorBlock) {
+					logger.Infof("⚠️  否决规则 %s 无法评估 %s %s，跳过该规则: %v", rule.label(), d.Symbol, d.Action, err)
+					continue
+				}
+				veto = &VetoedDecision{
+					Decision: d,
+					Rule:     rule.label(),
+					Reason:   fmt.Sprintf("%s %s 被规则否决: %s 无法评估（%v）", d.Symbol, d.Action, rule.label(), err),
+				}
+				break
+			}
+			if hit {
+				veto = &VetoedDecision{
+					Decision: d,
+					Rule:     rule.label(),
+					Reason:   fmt.Sprintf("%s %s 被规则否决: %s", d.Symbol, d.Action, rule.label()),
+				}
+				break
+			}
+		}
+		if veto != nil {
+			vetoed = append(vetoed, *veto)
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept, vetoed, nil
+}
+
+func (r *compiledGuardRule) appliesTo(action string) bool {
+	if len(r.actions) == 0 {
+		return action == "open_long" || action == "open_short"
+	}
+	return r.actions[action]
+}
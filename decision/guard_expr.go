@@ -0,0 +1,666 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ============================================================
+// 规则表达式：策略中的确定性过滤/否决条件
+//
+// 语法示例：
+//   rsi14@4h > 80 and funding_rate > 0.0005
+//   quote_volume_24h >= 50M or symbol in ["BTCUSDT", "ETHUSDT"]
+//   not position.exists and account.margin_used_pct < 60
+//
+// 支持：数字（可带 K/M/B 后缀）、字符串、true/false、列表 [..]
+// 运算符：or/||、and/&&、not/!、== != < <= > >=、in、+ - * /、括号
+// 函数：abs(x)、min(a, b)、max(a, b)
+// 变量名可带 @周期 后缀（如 ema20@1h），表示该周期序列的最新值
+// ============================================================
+
+// GuardExpr 已解析的规则表达式
+type GuardExpr struct {
+	source string
+	root   exprNode
+}
+
+// exprEnv 表达式变量解析
+type exprEnv interface {
+	lookup(name, timeframe string) (any, error)
+}
+
+type exprNode interface {
+	eval(env exprEnv) (any, error)
+}
+
+// ParseGuardExpr 解析规则表达式
+func ParseGuardExpr(source string) (*GuardExpr, error) {
+	tokens, err := tokenizeExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("位置 %d 处有多余的内容: %q", tok.pos, tok.text)
+	}
+	return &GuardExpr{source: source, root: root}, nil
+}
+
+// String 返回表达式原文
+func (e *GuardExpr) String() string {
+	return e.source
+}
+
+// Timeframes 表达式中通过 @周期 引用的时间周期
+func (e *GuardExpr) Timeframes() []string {
+	seen := make(map[string]bool)
+	var timeframes []string
+	walkExpr(e.root, func(n exprNode) {
+		if v, ok := n.(*varNode); ok && v.timeframe != "" && !seen[v.timeframe] {
+			seen[v.timeframe] = true
+			timeframes = append(timeframes, v.timeframe)
+		}
+	})
+	return timeframes
+}
+
+// evalBool 求值并要求结果为布尔值
+func (e *GuardExpr) evalBool(env exprEnv) (bool, error) {
+	value, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("表达式结果不是布尔值: %v", value)
+	}
+	return b, nil
+}
+
+// ------------------------------------------------------------
+// 词法分析
+// ------------------------------------------------------------
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+var numberSuffixes = map[rune]float64{'k': 1e3, 'K': 1e3, 'm': 1e6, 'M': 1e6, 'b': 1e9, 'B': 1e9}
+
+func tokenizeExpr(source string) ([]exprToken, error) {
+	runes := []rune(source)
+	var tokens []exprToken
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			num, err := strconv.ParseFloat(string(runes[start:i]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("位置 %d 处数字格式错误: %s", start, string(runes[start:i]))
+			}
+			if i < len(runes) {
+				if mult, ok := numberSuffixes[runes[i]]; ok && (i+1 == len(runes) || !isIdentRune(runes[i+1])) {
+					num *= mult
+					i++
+				}
+			}
+			tokens = append(tokens, exprToken{kind: tokNumber, text: string(runes[start:i]), num: num, pos: start})
+
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(runes) && runes[i] != r {
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("位置 %d 处字符串未闭合", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: tokString, text: sb.String(), pos: start})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (isIdentRune(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// 周期后缀：name@4h
+			if i < len(runes) && runes[i] == '@' {
+				i++
+				for i < len(runes) && isIdentRune(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, exprToken{kind: tokIdent, text: string(runes[start:i]), pos: start})
+
+		default:
+			start := i
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				tokens = append(tokens, exprToken{kind: tokOp, text: two, pos: start})
+				i += 2
+				continue
+			}
+			if !strings.ContainsRune("<>!+-*/(),[]", r) {
+				return nil, fmt.Errorf("位置 %d 处无法识别的字符: %q", start, r)
+			}
+			tokens = append(tokens, exprToken{kind: tokOp, text: string(r), pos: start})
+			i++
+		}
+	}
+	return append(tokens, exprToken{kind: tokEOF, pos: len(runes)}), nil
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// ------------------------------------------------------------
+// 语法分析（递归下降）
+// ------------------------------------------------------------
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// match 当前 token 为指定运算符或关键字时消费它
+func (p *exprParser) match(texts ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp && tok.kind != tokIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if tok.kind == tokOp && tok.text == text || tok.kind == tokIdent && strings.EqualFold(tok.text, text) {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(text string) error {
+	if _, ok := p.match(text); !ok {
+		tok := p.peek()
+		return fmt.Errorf("位置 %d 处缺少 %q", tok.pos, text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.match("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{and: false, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.match("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{and: true, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if _, ok := p.match("!", "not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.match("==", "!=", "<=", ">=", "<", ">", "in"); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.match("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.match("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.match("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithNode{op: "-", left: &literalNode{value: 0.0}, right: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &literalNode{value: tok.num}, nil
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+	case tokIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		if _, ok := p.match("("); ok {
+			return p.parseCall(tok)
+		}
+		name, timeframe, _ := strings.Cut(tok.text, "@")
+		return &varNode{name: strings.ToLower(name), timeframe: strings.ToLower(timeframe)}, nil
+	case tokOp:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			list := &listNode{}
+			if _, ok := p.match("]"); ok {
+				return list, nil
+			}
+			for {
+				item, err := p.parseAdditive()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if _, ok := p.match(","); !ok {
+					break
+				}
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			return list, nil
+		}
+	}
+	if tok.kind == tokEOF {
+		return nil, fmt.Errorf("表达式不完整")
+	}
+	return nil, fmt.Errorf("位置 %d 处语法错误: %q", tok.pos, tok.text)
+}
+
+func (p *exprParser) parseCall(name exprToken) (exprNode, error) {
+	fn := strings.ToLower(name.text)
+	arity, ok := map[string]int{"abs": 1, "min": 2, "max": 2}[fn]
+	if !ok {
+		return nil, fmt.Errorf("位置 %d 处未知函数: %s", name.pos, name.text)
+	}
+	call := &callNode{fn: fn}
+	if _, ok := p.match(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, ok := p.match(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(call.args) != arity {
+		return nil, fmt.Errorf("函数 %s 需要 %d 个参数", fn, arity)
+	}
+	return call, nil
+}
+
+// ------------------------------------------------------------
+// 求值
+// ------------------------------------------------------------
+
+type literalNode struct{ value any }
+
+func (n *literalNode) eval(exprEnv) (any, error) { return n.value, nil }
+
+type varNode struct{ name, timeframe string }
+
+func (n *varNode) eval(env exprEnv) (any, error) {
+	value, err := env.lookup(n.name, n.timeframe)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case []string:
+		list := make([]any, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list, nil
+	}
+	return value, nil
+}
+
+type listNode struct{ items []exprNode }
+
+func (n *listNode) eval(env exprEnv) (any, error) {
+	values := make([]any, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+type logicNode struct {
+	and         bool
+	left, right exprNode
+}
+
+func (n *logicNode) eval(env exprEnv) (any, error) {
+	left, err := evalBoolNode(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+	// 短路求值
+	if n.and != left {
+		return left, nil
+	}
+	return evalBoolNode(n.right, env)
+}
+
+type notNode struct{ operand exprNode }
+
+func (n *notNode) eval(env exprEnv) (any, error) {
+	value, err := evalBoolNode(n.operand, env)
+	if err != nil {
+		return nil, err
+	}
+	return !value, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *compareNode) eval(env exprEnv) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "in" {
+		list, ok := right.([]any)
+		if !ok {
+			return nil, fmt.Errorf("in 右侧必须是列表")
+		}
+		for _, item := range list {
+			if valuesEqual(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%s 只能比较数字: %v %s %v", n.op, left, n.op, right)
+	}
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	default:
+		return l >= r, nil
+	}
+}
+
+type arithNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *arithNode) eval(env exprEnv) (any, error) {
+	l, err := evalNumberNode(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := evalNumberNode(n.right, env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	default:
+		if r == 0 {
+			return nil, fmt.Errorf("除数为 0")
+		}
+		return l / r, nil
+	}
+}
+
+type callNode struct {
+	fn   string
+	args []exprNode
+}
+
+func (n *callNode) eval(env exprEnv) (any, error) {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		value, err := evalNumberNode(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	switch n.fn {
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "min":
+		return math.Min(args[0], args[1]), nil
+	default:
+		return math.Max(args[0], args[1]), nil
+	}
+}
+
+func evalBoolNode(n exprNode, env exprEnv) (bool, error) {
+	value, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("需要布尔值: %v", value)
+	}
+	return b, nil
+}
+
+func evalNumberNode(n exprNode, env exprEnv) (float64, error) {
+	value, err := n.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("需要数字: %v", value)
+	}
+	return f, nil
+}
+
+func valuesEqual(a, b any) bool {
+	if _, ok := a.([]any); ok {
+		return false
+	}
+	if _, ok := b.([]any); ok {
+		return false
+	}
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			return strings.EqualFold(as, bs)
+		}
+		return false
+	}
+	return a == b
+}
+
+func walkExpr(n exprNode, visit func(exprNode)) {
+	visit(n)
+	switch node := n.(type) {
+	case *logicNode:
+		walkExpr(node.left, visit)
+		walkExpr(node.right, visit)
+	case *notNode:
+		walkExpr(node.operand, visit)
+	case *compareNode:
+		walkExpr(node.left, visit)
+		walkExpr(node.right, visit)
+	case *arithNode:
+		walkExpr(node.left, visit)
+		walkExpr(node.right, visit)
+	case *callNode:
+		for _, arg := range node.args {
+			walkExpr(arg, visit)
+		}
+	case *listNode:
+		for _, item := range node.items {
+			walkExpr(item, visit)
+		}
+	}
+}
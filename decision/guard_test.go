@@ -0,0 +1,198 @@
+package decision
+
+import (
+	"nofx/market"
+	"nofx/store"
+	"strings"
+	"testing"
+)
+
+// mapEnv 测试用变量环境
+type mapEnv map[string]any
+
+func (m mapEnv) lookup(name, timeframe string) (any, error) {
+	if timeframe != "" {
+		name += "@" + timeframe
+	}
+	value, ok := m[name]
+	if !ok {
+		return nil, errUnknownVar(name)
+	}
+	return value, nil
+}
+
+type errUnknownVar string
+
+func (e errUnknownVar) Error() string { return "unknown " + string(e) }
+
+// TestGuardExpr_Eval 测试表达式解析与求值
+func TestGuardExpr_Eval(t *testing.T) {
+	env := mapEnv{
+		"rsi14@4h":            82.5,
+		"quote_volume_24h":    75e6,
+		"symbol":              "SOLUSDT",
+		"sources":             []string{"ai500", "oi_top"},
+		"position.exists":     false,
+		"account.equity":      1000.0,
+		"decision.leverage":   10,
+		"decision.action":     "open_long",
+		"funding_rate":        -0.0002,
+		"price":               101.0,
+		"ema20":               100.0,
+		"account.margin_used": 250.0,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"rsi14@4h > 80", true},
+		{"RSI14@4H > 80 AND quote_volume_24h >= 50M", true},
+		{"quote_volume_24h > 100m", false},
+		{"symbol in ['BTCUSDT', 'ETHUSDT']", false},
+		{"\"oi_top\" in sources", true},
+		{"not position.exists && account.equity > 500", true},
+		{"decision.leverage >= 10 and decision.action == \"open_long\"", true},
+		{"abs(funding_rate) > 0.0001", true},
+		{"(price - ema20) / ema20 * 100 > 0.5", true},
+		{"account.margin_used / account.equity * 100 < 20 || false", false},
+		{"!(1 + 2 * 3 == 7)", false},
+		{"max(1, 2) == 2 and min(1, 2) == 1 and -1 < 0", true},
+		// 短路：左侧为 false 时不求值右侧的未知变量
+		{"false and missing > 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseGuardExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			got, err := expr.evalBool(env)
+			if err != nil {
+				t.Fatalf("eval error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGuardExpr_Errors 测试语法错误与类型错误
+func TestGuardExpr_Errors(t *testing.T) {
+	for _, src := range []string{"", "rsi14 >", "(price > 1", "price > 1 1", "foo(1)", "price $ 2", "'abc"} {
+		if _, err := ParseGuardExpr(src); err == nil {
+			t.Errorf("ParseGuardExpr(%q) should fail", src)
+		}
+	}
+
+	expr, _ := ParseGuardExpr("symbol > 1")
+	if _, err := expr.evalBool(mapEnv{"symbol": "BTCUSDT"}); err == nil {
+		t.Error("comparing string with number should fail")
+	}
+	expr, _ = ParseGuardExpr("price + 1")
+	if _, err := expr.evalBool(mapEnv{"price": 1.0}); err == nil {
+		t.Error("non-boolean result should fail")
+	}
+
+	expr, _ = ParseGuardExpr("rsi14@4h > 70 or ema20@1h > price")
+	if got := strings.Join(expr.Timeframes(), ","); got != "4h,1h" {
+		t.Errorf("Timeframes() = %s, want 4h,1h", got)
+	}
+}
+
+// TestStrategyEngine_Guards 测试候选币预过滤和决策否决
+func TestStrategyEngine_Guards(t *testing.T) {
+	config := &store.StrategyConfig{
+		Indicators: store.IndicatorConfig{Klines: store.KlineConfig{PrimaryTimeframe: "15m"}},
+		Guards: store.GuardConfig{
+			CandidateFilters: []store.GuardRule{
+				{Name: "排除 PEPE", Expr: "symbol != '1000PEPEUSDT'"},
+				{Expr: "'ai500' in sources", Disabled: true},
+			},
+			Vetoes: []store.GuardRule{
+				{Name: "4h 过热不追多", Expr: "rsi14@4h > 80", Actions: []string{"open_long"}},
+				{Name: "保证金过高", Expr: "account.margin_used_pct > 60"},
+				{Name: "无法评估", Expr: "unknown_var > 1", OnError: store.GuardOnErrorSkip},
+			},
+		},
+	}
+	if err := ValidateGuardConfig(config.Guards); err != nil {
+		t.Fatalf("ValidateGuardConfig: %v", err)
+	}
+	engine := NewStrategyEngine(config)
+
+	candidates, err := engine.filterCandidates([]CandidateCoin{
+		{Symbol: "BTCUSDT", Sources: []string{"oi_top"}},
+		{Symbol: "1000PEPEUSDT", Sources: []string{"ai500"}},
+	})
+	if err != nil {
+		t.Fatalf("filterCandidates: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Symbol != "BTCUSDT" {
+		t.Errorf("candidates = %+v, want only BTCUSDT", candidates)
+	}
+
+	ctx := &Context{
+		Account: AccountInfo{TotalEquity: 1000, MarginUsedPct: 30},
+		MarketDataMap: map[string]*market.Data{
+			"BTCUSDT": {Symbol: "BTCUSDT", TimeframeData: map[string]*market.TimeframeSeriesData{
+				"4h": {Timeframe: "4h", RSI14Values: []float64{70, 85}},
+			}},
+			"ETHUSDT": {Symbol: "ETHUSDT", TimeframeData: map[string]*market.TimeframeSeriesData{
+				"4h": {Timeframe: "4h", RSI14Values: []float64{50}},
+			}},
+		},
+	}
+	decisions := []Decision{
+		{Symbol: "BTCUSDT", Action: "open_long"},
+		{Symbol: "BTCUSDT", Action: "open_short"},
+		{Symbol: "ETHUSDT", Action: "open_long"},
+		{Symbol: "SOLUSDT", Action: "close_long"},
+	}
+	kept, vetoed, err := engine.ApplyVetoes(ctx, decisions)
+	if err != nil {
+		t.Fatalf("ApplyVetoes: %v", err)
+	}
+	if len(kept) != 3 || len(vetoed) != 1 {
+		t.Fatalf("kept=%d vetoed=%d, want 3/1", len(kept), len(vetoed))
+	}
+	if vetoed[0].Decision.Symbol != "BTCUSDT" || vetoed[0].Decision.Action != "open_long" ||
+		!strings.Contains(vetoed[0].Reason, "4h 过热不追多") {
+		t.Errorf("unexpected veto: %+v", vetoed[0])
+	}
+
+	// 保证金使用率过高时否决所有开仓，但不影响平仓
+	ctx.Account.MarginUsedPct = 75
+	kept, vetoed, _ = engine.ApplyVetoes(ctx, decisions)
+	if len(vetoed) != 3 || len(kept) != 1 || kept[0].Action != "close_long" {
+		t.Errorf("kept=%+v vetoed=%d, want only close_long kept", kept, len(vetoed))
+	}
+
+	// 未设置 on_error 时无法评估的否决规则默认否决（失败即否决）
+	ctx.Account.MarginUsedPct = 30
+	config.Guards.Vetoes[2].OnError = ""
+	kept, vetoed, _ = NewStrategyEngine(config).ApplyVetoes(ctx, decisions)
+	if len(vetoed) != 3 || len(kept) != 1 || !strings.Contains(vetoed[1].Reason, "无法评估") {
+		t.Errorf("kept=%+v vetoed=%+v, want opens vetoed when a rule cannot be evaluated", kept, vetoed)
+	}
+	config.Guards.Vetoes[2].OnError = "ignore"
+	if err := ValidateGuardConfig(config.Guards); err == nil {
+		t.Error("expected invalid on_error error")
+	}
+	config.Guards.Vetoes[2].OnError = store.GuardOnErrorSkip
+
+	config.Guards.Vetoes = append(config.Guards.Vetoes, store.GuardRule{Expr: "price >", Actions: []string{"open_long"}})
+	if err := ValidateGuardConfig(config.Guards); err == nil {
+		t.Error("expected syntax error")
+	}
+	// 规则编译失败时否决全部开仓，平仓照常执行
+	kept, vetoed, err = NewStrategyEngine(config).ApplyVetoes(ctx, decisions)
+	if err == nil || len(vetoed) != 3 || len(kept) != 1 || kept[0].Action != "close_long" {
+		t.Errorf("err=%v kept=%+v vetoed=%d, want opens vetoed on compile error", err, kept, len(vetoed))
+	}
+	config.Guards.Vetoes[len(config.Guards.Vetoes)-1] = store.GuardRule{Expr: "price > 1", Actions: []string{"buy"}}
+	if err := ValidateGuardConfig(config.Guards); err == nil {
+		t.Error("expected invalid action error")
+	}
+}
@@ -0,0 +1,120 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"nofx/store"
+	"strings"
+)
+
+// MarketIndicatorSettings 将策略指标配置转换为行情模块的指标计算配置
+// 只计算已启用的指标；EMA/RSI/ATR 按配置的周期计算
+func MarketIndicatorSettings(config store.IndicatorConfig) *market.IndicatorSettings {
+	settings := &market.IndicatorSettings{
+		VWAP:      config.EnableVWAP,
+		OBV:       config.EnableOBV,
+		OrderBook: config.EnableOrderBook,
+	}
+	if config.EnableEMA {
+		settings.EMAPeriods = config.EMAPeriods
+	}
+	if config.EnableRSI {
+		settings.RSIPeriods = config.RSIPeriods
+	}
+	if config.EnableATR {
+		settings.ATRPeriods = config.ATRPeriods
+	}
+	if config.EnableBollinger {
+		params := config.Bollinger
+		settings.Bollinger = &params
+	}
+	if config.EnableStochRSI {
+		params := config.StochRSI
+		settings.StochRSI = &params
+	}
+	if config.EnableADX {
+		params := config.ADX
+		settings.ADX = &params
+	}
+	if config.EnableSupertrend {
+		params := config.Supertrend
+		settings.Supertrend = &params
+	}
+	if config.EnableIchimoku {
+		params := config.Ichimoku
+		settings.Ichimoku = &params
+	}
+	if config.EnableDonchian {
+		params := config.Donchian
+		settings.Donchian = &params
+	}
+	if config.EnableLongShortRatio || config.EnableTakerFlow || config.EnableLiquidations {
+		settings.Derivatives = &market.DerivativesOptions{
+			DerivativesParams: config.Derivatives,
+			LongShortRatio:    config.EnableLongShortRatio,
+			TakerFlow:         config.EnableTakerFlow,
+			Liquidations:      config.EnableLiquidations,
+		}
+	}
+	return settings
+}
+
+// ValidateIndicatorConfig 校验指标周期和扩展指标参数
+func ValidateIndicatorConfig(config store.IndicatorConfig) error {
+	return MarketIndicatorSettings(config).Validate()
+}
+
+// indicatorSettings 当前策略的指标计算配置
+func (e *StrategyEngine) indicatorSettings() *market.IndicatorSettings {
+	return MarketIndicatorSettings(e.config.Indicators)
+}
+
+// formatNamedSeries 格式化扩展指标序列（周期序列中缺少的指标跳过）
+func formatNamedSeries(sb *strings.Builder, data *market.TimeframeSeriesData, indicators store.IndicatorConfig, series func([]float64) string) {
+	if len(data.Series) == 0 {
+		return
+	}
+	settings := MarketIndicatorSettings(indicators).WithDefaults()
+	write := func(label, key string) {
+		if values := data.Series[key]; len(values) > 0 {
+			sb.WriteString(fmt.Sprintf("%s: %s\n\n", label, series(values)))
+		}
+	}
+
+	if p := settings.Bollinger; p != nil {
+		write(fmt.Sprintf("Bollinger upper (%d, %.1fσ)", p.Period, p.StdDev), market.SeriesKeyBollingerUpper)
+		write("Bollinger middle", market.SeriesKeyBollingerMiddle)
+		write("Bollinger lower", market.SeriesKeyBollingerLower)
+		write("Bollinger width (%)", market.SeriesKeyBollingerWidth)
+	}
+	if settings.VWAP {
+		write("VWAP (daily session)", market.SeriesKeyVWAP)
+	}
+	if p := settings.StochRSI; p != nil {
+		write(fmt.Sprintf("StochRSI %%K (%d,%d,%d,%d)", p.RSIPeriod, p.StochPeriod, p.KPeriod, p.DPeriod), market.SeriesKeyStochRSIK)
+		write("StochRSI %D", market.SeriesKeyStochRSID)
+	}
+	if p := settings.ADX; p != nil {
+		write(fmt.Sprintf("ADX (%d-period)", p.Period), market.SeriesKeyADX)
+		write("+DI", market.SeriesKeyPlusDI)
+		write("-DI", market.SeriesKeyMinusDI)
+	}
+	if settings.OBV {
+		write("OBV", market.SeriesKeyOBV)
+	}
+	if p := settings.Supertrend; p != nil {
+		write(fmt.Sprintf("Supertrend (%d, %.1f×ATR)", p.Period, p.Multiplier), market.SeriesKeySupertrend)
+		write("Supertrend direction (1=up, -1=down)", market.SeriesKeySupertrendDir)
+	}
+	if p := settings.Ichimoku; p != nil {
+		write(fmt.Sprintf("Ichimoku Tenkan (%d)", p.TenkanPeriod), market.SeriesKeyTenkan)
+		write(fmt.Sprintf("Ichimoku Kijun (%d)", p.KijunPeriod), market.SeriesKeyKijun)
+		write("Ichimoku Senkou A (current cloud)", market.SeriesKeySenkouA)
+		write(fmt.Sprintf("Ichimoku Senkou B (%d, current cloud)", p.SenkouBPeriod), market.SeriesKeySenkouB)
+	}
+	if p := settings.Donchian; p != nil {
+		write(fmt.Sprintf("Donchian upper (%d)", p.Period), market.SeriesKeyDonchianUpper)
+		write("Donchian middle", market.SeriesKeyDonchianMiddle)
+		write("Donchian lower", market.SeriesKeyDonchianLower)
+	}
+}
@@ -0,0 +1,571 @@
+package decision
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath 编译后的 JSONPath 表达式
+// 支持：$ 根节点、.key / ['key'] 子节点、[n] 下标（负数从末尾计）、[a,b] 并集、[start:end] 切片、
+// * 通配、.. 递归下降、[?(@.field op value)] 过滤（op: == != > >= < <=，可用 && / || 组合，省略 op 表示字段存在）
+type jsonPath struct {
+	steps    []jsonPathStep
+	definite bool // 不含通配/过滤/切片/递归/并集时只返回单个值
+}
+
+type jsonPathStepKind int
+
+const (
+	stepNames jsonPathStepKind = iota
+	stepIndexes
+	stepWildcard
+	stepSlice
+	stepFilter
+)
+
+type jsonPathStep struct {
+	kind      jsonPathStepKind
+	recursive bool // 前面是 ..
+	names     []string
+	indexes   []int
+	start     *int
+	end       *int
+	filter    jsonPathExpr
+}
+
+// jsonPathExpr 过滤表达式
+type jsonPathExpr interface {
+	match(node interface{}) bool
+}
+
+type jsonPathOr []jsonPathExpr
+type jsonPathAnd []jsonPathExpr
+
+type jsonPathCompare struct {
+	path    *jsonPath // 相对 @ 的路径
+	op      string    // 为空表示存在性检查
+	literal interface{}
+}
+
+func (e jsonPathOr) match(node interface{}) bool {
+	for _, sub := range e {
+		if sub.match(node) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e jsonPathAnd) match(node interface{}) bool {
+	for _, sub := range e {
+		if !sub.match(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *jsonPathCompare) match(node interface{}) bool {
+	values := e.path.selectNodes(node)
+	if len(values) == 0 {
+		return false
+	}
+	if e.op == "" {
+		return true
+	}
+	for _, v := range values {
+		if compareJSONValues(v, e.op, e.literal) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareJSONValues 比较 JSON 值：数值按大小，字符串按字典序，其他类型仅支持 == / !=
+func compareJSONValues(left interface{}, op string, right interface{}) bool {
+	if l, ok := left.(float64); ok {
+		if r, ok := right.(float64); ok {
+			switch op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			}
+			return false
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			}
+			return false
+		}
+	}
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+	return false
+}
+
+// extractJSONPath 提取 JSON 路径数据（表达式无效时返回 nil）
+// 兼容旧的点分路径（如 data.items），等价于 $.data.items
+func extractJSONPath(data interface{}, path string) interface{} {
+	p, err := compileJSONPath(path)
+	if err != nil {
+		return nil
+	}
+	return p.evaluate(data)
+}
+
+// compileJSONPath 解析 JSONPath 表达式
+func compileJSONPath(path string) (*jsonPath, error) {
+	path = strings.TrimSpace(path)
+	switch {
+	case path == "" || path == "$":
+		return &jsonPath{definite: true}, nil
+	case strings.HasPrefix(path, "$"):
+		path = path[1:]
+	case strings.HasPrefix(path, "["):
+	default:
+		path = "." + path
+	}
+
+	p := &jsonPath{definite: true}
+	for i := 0; i < len(path); {
+		step := jsonPathStep{}
+		switch {
+		case strings.HasPrefix(path[i:], ".."):
+			step.recursive = true
+			i += 2
+			if i < len(path) && path[i] == '[' {
+				break
+			}
+			name, n := scanJSONPathName(path[i:])
+			if name == "" {
+				return nil, fmt.Errorf("JSONPath 位置 %d: .. 后缺少字段名", i)
+			}
+			i += n
+			if name == "*" {
+				step.kind = stepWildcard
+			} else {
+				step.kind = stepNames
+				step.names = []string{name}
+			}
+			p.addStep(step)
+			continue
+		case path[i] == '.':
+			i++
+			name, n := scanJSONPathName(path[i:])
+			if name == "" {
+				return nil, fmt.Errorf("JSONPath 位置 %d: . 后缺少字段名", i)
+			}
+			i += n
+			if name == "*" {
+				step.kind = stepWildcard
+			} else {
+				step.kind = stepNames
+				step.names = []string{name}
+			}
+			p.addStep(step)
+			continue
+		case path[i] != '[':
+			return nil, fmt.Errorf("JSONPath 位置 %d: 意外的字符 %q", i, path[i])
+		}
+
+		// 方括号
+		end, err := findJSONPathBracketEnd(path, i)
+		if err != nil {
+			return nil, err
+		}
+		if err := parseJSONPathBracket(strings.TrimSpace(path[i+1:end]), &step); err != nil {
+			return nil, err
+		}
+		i = end + 1
+		p.addStep(step)
+	}
+	return p, nil
+}
+
+func (p *jsonPath) addStep(step jsonPathStep) {
+	if step.recursive || step.kind == stepWildcard || step.kind == stepSlice || step.kind == stepFilter ||
+		len(step.names) > 1 || len(step.indexes) > 1 {
+		p.definite = false
+	}
+	p.steps = append(p.steps, step)
+}
+
+// scanJSONPathName 读取点号后的字段名（到下一个 . 或 [ 为止）
+func scanJSONPathName(s string) (string, int) {
+	n := 0
+	for n < len(s) && s[n] != '.' && s[n] != '[' {
+		n++
+	}
+	return strings.TrimSpace(s[:n]), n
+}
+
+// findJSONPathBracketEnd 找到与 start 处 [ 匹配的 ]（跳过引号和嵌套括号）
+func findJSONPathBracketEnd(path string, start int) (int, error) {
+	depth := 0
+	var quote byte
+	for i := start; i < len(path); i++ {
+		c := path[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+			if depth == 0 && c == ']' {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("JSONPath 位置 %d: 缺少匹配的 ]", start)
+}
+
+func parseJSONPathBracket(content string, step *jsonPathStep) error {
+	switch {
+	case content == "*":
+		step.kind = stepWildcard
+		return nil
+	case strings.HasPrefix(content, "?"):
+		expr := strings.TrimSpace(content[1:])
+		if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+			return fmt.Errorf("JSONPath 过滤表达式需要括号: %s", content)
+		}
+		filter, err := parseJSONPathFilter(expr[1 : len(expr)-1])
+		if err != nil {
+			return err
+		}
+		step.kind = stepFilter
+		step.filter = filter
+		return nil
+	case strings.HasPrefix(content, "'") || strings.HasPrefix(content, "\""):
+		step.kind = stepNames
+		for _, part := range splitJSONPathOutsideQuotes(content, ",") {
+			name, err := unquoteJSONPathString(strings.TrimSpace(part))
+			if err != nil {
+				return err
+			}
+			step.names = append(step.names, name)
+		}
+		return nil
+	case strings.Contains(content, ":"):
+		parts := strings.Split(content, ":")
+		if len(parts) > 2 {
+			return fmt.Errorf("JSONPath 切片不支持步长: [%s]", content)
+		}
+		step.kind = stepSlice
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("JSONPath 切片下标无效: [%s]", content)
+			}
+			if i == 0 {
+				step.start = &n
+			} else {
+				step.end = &n
+			}
+		}
+		return nil
+	}
+
+	step.kind = stepIndexes
+	for _, part := range strings.Split(content, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return fmt.Errorf("JSONPath 下标无效: [%s]", content)
+		}
+		step.indexes = append(step.indexes, n)
+	}
+	return nil
+}
+
+// parseJSONPathFilter 解析过滤表达式（&& 优先级高于 ||）
+func parseJSONPathFilter(expr string) (jsonPathExpr, error) {
+	var or jsonPathOr
+	for _, orPart := range splitJSONPathOutsideQuotes(expr, "||") {
+		var and jsonPathAnd
+		for _, andPart := range splitJSONPathOutsideQuotes(orPart, "&&") {
+			cmp, err := parseJSONPathCompare(strings.TrimSpace(andPart))
+			if err != nil {
+				return nil, err
+			}
+			and = append(and, cmp)
+		}
+		or = append(or, and)
+	}
+	return or, nil
+}
+
+func parseJSONPathCompare(expr string) (*jsonPathCompare, error) {
+	if !strings.HasPrefix(expr, "@") {
+		return nil, fmt.Errorf("JSONPath 过滤条件必须以 @ 开头: %s", expr)
+	}
+
+	// 查找引号外的比较运算符
+	opIndex, op := -1, ""
+	var quote byte
+	for i := 1; i < len(expr) && opIndex < 0; i++ {
+		c := expr[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '\'' || c == '"' {
+			quote = c
+			continue
+		}
+		for _, candidate := range []string{"==", "!=", ">=", "<=", ">", "<"} {
+			if strings.HasPrefix(expr[i:], candidate) {
+				opIndex, op = i, candidate
+				break
+			}
+		}
+	}
+
+	left := expr
+	if opIndex >= 0 {
+		left = expr[:opIndex]
+	}
+	path, err := compileJSONPath("$" + strings.TrimSpace(left[1:]))
+	if err != nil {
+		return nil, err
+	}
+	cmp := &jsonPathCompare{path: path, op: op}
+	if opIndex < 0 {
+		return cmp, nil
+	}
+
+	literal := strings.TrimSpace(expr[opIndex+len(op):])
+	switch {
+	case literal == "true":
+		cmp.literal = true
+	case literal == "false":
+		cmp.literal = false
+	case literal == "null":
+		cmp.literal = nil
+	case strings.HasPrefix(literal, "'") || strings.HasPrefix(literal, "\""):
+		s, err := unquoteJSONPathString(literal)
+		if err != nil {
+			return nil, err
+		}
+		cmp.literal = s
+	default:
+		f, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, fmt.Errorf("JSONPath 过滤值无效: %s", literal)
+		}
+		cmp.literal = f
+	}
+	return cmp, nil
+}
+
+// splitJSONPathOutsideQuotes 按分隔符拆分（忽略引号内的分隔符）
+func splitJSONPathOutsideQuotes(s, sep string) []string {
+	var parts []string
+	var quote byte
+	last := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '\'' || c == '"' {
+			quote = c
+			continue
+		}
+		if strings.HasPrefix(s[i:], sep) {
+			parts = append(parts, s[last:i])
+			i += len(sep) - 1
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
+
+func unquoteJSONPathString(s string) (string, error) {
+	if len(s) < 2 || s[0] != s[len(s)-1] || (s[0] != '\'' && s[0] != '"') {
+		return "", fmt.Errorf("JSONPath 字符串缺少引号: %s", s)
+	}
+	inner := s[1 : len(s)-1]
+	inner = strings.ReplaceAll(inner, `\`+string(s[0]), string(s[0]))
+	return strings.ReplaceAll(inner, `\\`, `\`), nil
+}
+
+// evaluate 执行表达式：确定路径返回单个值（不存在为 nil），否则返回匹配值数组
+func (p *jsonPath) evaluate(data interface{}) interface{} {
+	nodes := p.selectNodes(data)
+	if p.definite {
+		if len(nodes) == 0 {
+			return nil
+		}
+		return nodes[0]
+	}
+	if nodes == nil {
+		nodes = []interface{}{}
+	}
+	return nodes
+}
+
+func (p *jsonPath) selectNodes(data interface{}) []interface{} {
+	nodes := []interface{}{data}
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, node := range nodes {
+			if step.recursive {
+				for _, descendant := range jsonDescendants(node) {
+					next = append(next, step.apply(descendant)...)
+				}
+			} else {
+				next = append(next, step.apply(node)...)
+			}
+		}
+		nodes = next
+		if len(nodes) == 0 {
+			return nil
+		}
+	}
+	return nodes
+}
+
+// apply 对单个节点执行一步选择
+func (s *jsonPathStep) apply(node interface{}) []interface{} {
+	switch s.kind {
+	case stepNames:
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		var out []interface{}
+		for _, name := range s.names {
+			if v, ok := m[name]; ok {
+				out = append(out, v)
+			}
+		}
+		return out
+	case stepIndexes:
+		arr, ok := node.([]interface{})
+		if !ok {
+			return nil
+		}
+		var out []interface{}
+		for _, idx := range s.indexes {
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx >= 0 && idx < len(arr) {
+				out = append(out, arr[idx])
+			}
+		}
+		return out
+	case stepSlice:
+		arr, ok := node.([]interface{})
+		if !ok {
+			return nil
+		}
+		start, end := 0, len(arr)
+		if s.start != nil {
+			start = clampSliceIndex(*s.start, len(arr))
+		}
+		if s.end != nil {
+			end = clampSliceIndex(*s.end, len(arr))
+		}
+		if start >= end {
+			return nil
+		}
+		return append([]interface{}(nil), arr[start:end]...)
+	case stepWildcard:
+		return jsonChildren(node)
+	case stepFilter:
+		var out []interface{}
+		for _, child := range jsonChildren(node) {
+			if s.filter.match(child) {
+				out = append(out, child)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func clampSliceIndex(idx, length int) int {
+	if idx < 0 {
+		idx += length
+	}
+	return max(0, min(length, idx))
+}
+
+// jsonChildren 返回对象的值（按键排序）或数组元素
+func jsonChildren(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, v[k])
+		}
+		return out
+	}
+	return nil
+}
+
+// jsonDescendants 返回节点自身及所有后代（先序）
+func jsonDescendants(node interface{}) []interface{} {
+	out := []interface{}{node}
+	for _, child := range jsonChildren(node) {
+		out = append(out, jsonDescendants(child)...)
+	}
+	return out
+}
@@ -0,0 +1,24 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/store"
+	"strings"
+	"time"
+)
+
+// formatMarketAlerts 格式化近期行情警报（按触发时间倒序）
+func formatMarketAlerts(alerts []*store.MarketAlert, now time.Time) string {
+	if len(alerts) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 近期行情警报\n")
+	sb.WriteString("以下为行情监控自动触发的异动提示（3分钟K线），仅作市场背景参考\n")
+	for i, alert := range alerts {
+		sb.WriteString(fmt.Sprintf("%d. %s | %s前\n", i+1, alert.Message, formatPromptDuration(now.Sub(alert.CreatedAt))))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// regimeLabels 市场状态的中文名称
+var regimeLabels = map[string]string{
+	market.RegimeTrendingUp:     "普涨趋势",
+	market.RegimeTrendingDown:   "普跌趋势",
+	market.RegimeRanging:        "震荡",
+	market.RegimeHighVolatility: "高波动",
+}
+
+// LoadMarketOverview 从K线缓存计算市场概览（BTC/ETH 相关性、Beta 与市场状态）
+// 市场状态基于固定参考币种 market.RegimeBasket；symbols 为需要计算联动的币种。BTC K线不可用时返回 nil
+// 只使用已收盘K线（实盘K线缓存的最后一根可能尚未收盘）
+func LoadMarketOverview(klines KlineSource, timeframe string, symbols []string) *market.MarketOverview {
+	now := time.Now()
+	series := make(map[string][]market.Kline, len(market.RegimeBasket)+len(symbols))
+	for _, symbol := range append(append([]string{}, market.RegimeBasket...), symbols...) {
+		if _, loaded := series[symbol]; loaded {
+			continue
+		}
+		data, err := klines(symbol, timeframe)
+		if err != nil {
+			if symbol == market.BenchmarkBTC {
+				logger.Infof("⚠️  获取 BTC %s K线失败，跳过市场概览: %v", timeframe, err)
+				return nil
+			}
+			continue
+		}
+		series[symbol] = market.ClosedKlines(data, now)
+	}
+	return market.BuildMarketOverview(timeframe, series, symbols)
+}
+
+// loadMarketOverview 为上下文中已获取市场数据的币种计算市场概览
+func (ctx *Context) loadMarketOverview(timeframe string) {
+	klines := ctx.Klines
+	if klines == nil {
+		klines = ctx.marketProvider().Stream().GetCurrentKlines
+	}
+	symbols := make([]string, 0, len(ctx.MarketDataMap))
+	for symbol := range ctx.MarketDataMap {
+		symbols = append(symbols, symbol)
+	}
+	ctx.MarketOverview = LoadMarketOverview(klines, timeframe, symbols)
+}
+
+// MarketRegime 本次决策的市场状态（未计算市场概览时为空）
+func (ctx *Context) MarketRegime() string {
+	if ctx == nil || ctx.MarketOverview == nil {
+		return ""
+	}
+	return ctx.MarketOverview.Regime
+}
+
+// formatMarketOverview 格式化市场概览（市场状态、宽度、波动率及候选币与 BTC/ETH 的联动）
+func formatMarketOverview(o *market.MarketOverview) string {
+	if o == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## 市场概览（%s K线，近%d根）\n", o.Timeframe, o.TrendBars))
+	label := regimeLabels[o.Regime]
+	if label == "" {
+		label = o.Regime
+	}
+	sb.WriteString(fmt.Sprintf("市场状态: %s | 上涨币种占比 %.0f%% | 站上SMA%d %.0f%% | 波动率 %.2f%%/根（近期/基准 %.2fx）| 统计币种 %d个\n",
+		label, o.Breadth, o.TrendBars, o.AboveSMA, o.Volatility, o.VolatilityRatio, o.Symbols))
+	sb.WriteString(fmt.Sprintf("BTC: %+.2f%% | ETH: %+.2f%%\n", o.BTCChange, o.ETHChange))
+	if len(o.Assets) > 0 {
+		sb.WriteString("与 BTC/ETH 的联动（收益率相关系数 / Beta）:\n")
+		for _, a := range o.Assets {
+			sb.WriteString(fmt.Sprintf("- %s: BTC 相关 %.2f β %.2f | ETH 相关 %.2f β %.2f（%d根）\n",
+				a.Symbol, a.CorrBTC, a.BetaBTC, a.CorrETH, a.BetaETH, a.Samples))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -3,6 +3,7 @@ package decision
 import (
 	"fmt"
 	"log"
+	"nofx/store"
 	"os"
 	"path/filepath"
 	"strings"
@@ -160,3 +161,16 @@ func GetAllPromptTemplates() []*PromptTemplate {
 func ReloadPromptTemplates() error {
 	return globalPromptManager.ReloadTemplates(promptsDir)
 }
+
+// SeedPromptTemplates 将 prompts 目录中的模板导入数据库作为系统模板
+// 文件内容变化时会产生新版本，已有版本保持不变
+func SeedPromptTemplates(st *store.Store) error {
+	for _, tmpl := range GetAllPromptTemplates() {
+		version, err := st.PromptTemplate().SeedSystemTemplate(tmpl.Name, tmpl.Content)
+		if err != nil {
+			return fmt.Errorf("导入提示词模板 %s 失败: %w", tmpl.Name, err)
+		}
+		log.Printf("  📄 系统提示词模板 %s 当前版本: v%d", tmpl.Name, version.Version)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package decision
+
+import (
+	"bytes"
+	"fmt"
+	"nofx/logger"
+	"nofx/store"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PromptData 提示词模板（text/template）可使用的数据模型
+//
+// 提示词模板、PromptSectionsConfig 各部分和自定义 Prompt 中均可使用，例如：
+//
+//	当前净值 {{usd .Account.TotalEquity}}，持仓 {{len .Positions}} 个
+//	{{if eq .Regime "trending_down"}}市场偏空，优先考虑做空或观望{{end}}
+//	{{range .Positions}}- {{.Symbol}} {{.Side}} 浮盈 {{pct .UnrealizedPnLPct}}{{end}}
+//	{{if .Time.IsWeekend}}周末流动性较差，降低仓位{{end}}
+//
+// 不包含 "{{" 的文本按原样输出，兼容旧配置。
+type PromptData struct {
+	Account    AccountInfo             // 账户信息（净值、可用余额、保证金使用率等）
+	Positions  []PositionInfo          // 当前持仓
+	Risk       store.RiskControlConfig // 风险控制配置（杠杆上限、最大持仓数、最小信心度等）
+	Candidates []CandidateCoin         // 候选币种
+	Regime     string                  // 市场状态: trending_up/trending_down/ranging/high_volatility/unknown（同市场概览）
+	Variant    string                  // 交易模式变体: balanced/aggressive/conservative/scalping
+	Time       PromptTime              // 时间信息
+}
+
+// PromptTime 模板中的时间信息（UTC）
+type PromptTime struct {
+	Now       time.Time
+	Hour      int    // 0-23
+	Weekday   string // Monday..Sunday
+	IsWeekend bool
+	Session   string // asia(00-08) / europe(08-13) / us(13-21) / off_hours(21-24)
+}
+
+// promptFuncs 模板辅助函数
+//
+//	fixed x n     保留n位小数          {{fixed .Account.TotalEquity 2}}
+//	pct x         百分数（x已是百分比）  {{pct .Account.MarginUsedPct}} → 12.34%
+//	ratioPct x    比例转百分数          {{ratioPct .Risk.MaxMarginUsage}} → 90.00%
+//	usd x         金额                 {{usd .Account.AvailableBalance}} → 123.45 USDT
+//	add/sub/mul/div a b  四则运算（支持整数和浮点数混用）
+//	upper/lower s, join list sep, contains s sub
+var promptFuncs = template.FuncMap{
+	"fixed": func(v interface{}, digits int) string {
+		return strconv.FormatFloat(toFloat(v), 'f', digits, 64)
+	},
+	"pct": func(v interface{}) string {
+		return fmt.Sprintf("%.2f%%", toFloat(v))
+	},
+	"ratioPct": func(v interface{}) string {
+		return fmt.Sprintf("%.2f%%", toFloat(v)*100)
+	},
+	"usd": func(v interface{}) string {
+		return fmt.Sprintf("%.2f USDT", toFloat(v))
+	},
+	"add": func(a, b interface{}) float64 { return toFloat(a) + toFloat(b) },
+	"sub": func(a, b interface{}) float64 { return toFloat(a) - toFloat(b) },
+	"mul": func(a, b interface{}) float64 { return toFloat(a) * toFloat(b) },
+	"div": func(a, b interface{}) float64 {
+		d := toFloat(b)
+		if d == 0 {
+			return 0
+		}
+		return toFloat(a) / d
+	},
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"join":     strings.Join,
+	"contains": strings.Contains,
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	default:
+		return 0
+	}
+}
+
+// NewPromptData 根据交易上下文构建模板数据（ctx 可以为 nil）
+func NewPromptData(ctx *Context, risk store.RiskControlConfig, variant string) *PromptData {
+	data := &PromptData{
+		Risk:    risk,
+		Regime:  "unknown",
+		Variant: variant,
+		Time:    newPromptTime(time.Now().UTC()),
+	}
+	if ctx != nil {
+		data.Account = ctx.Account
+		data.Positions = ctx.Positions
+		data.Candidates = ctx.CandidateCoins
+		if regime := ctx.MarketRegime(); regime != "" {
+			data.Regime = regime
+		}
+	}
+	return data
+}
+
+// SamplePromptData 用于校验模板的示例数据（覆盖持仓、候选币等分支）
+func SamplePromptData(risk store.RiskControlConfig) *PromptData {
+	return &PromptData{
+		Account: AccountInfo{
+			TotalEquity:      1000,
+			AvailableBalance: 800,
+			UnrealizedPnL:    12.5,
+			TotalPnL:         50,
+			TotalPnLPct:      5,
+			MarginUsed:       200,
+			MarginUsedPct:    20,
+			PositionCount:    1,
+		},
+		Positions: []PositionInfo{{
+			Symbol:           "BTCUSDT",
+			Side:             "long",
+			EntryPrice:       95000,
+			MarkPrice:        96000,
+			Quantity:         0.01,
+			Leverage:         5,
+			UnrealizedPnL:    10,
+			UnrealizedPnLPct: 5.26,
+			LiquidationPrice: 80000,
+			MarginUsed:       190,
+		}},
+		Risk:       risk,
+		Candidates: []CandidateCoin{{Symbol: "ETHUSDT", Sources: []string{"ai500"}}},
+		Regime:     "ranging",
+		Variant:    "balanced",
+		Time:       newPromptTime(time.Now().UTC()),
+	}
+}
+
+func newPromptTime(now time.Time) PromptTime {
+	hour := now.Hour()
+	session := "off_hours"
+	switch {
+	case hour < 8:
+		session = "asia"
+	case hour < 13:
+		session = "europe"
+	case hour < 21:
+		session = "us"
+	}
+	return PromptTime{
+		Now:       now,
+		Hour:      hour,
+		Weekday:   now.Weekday().String(),
+		IsWeekend: now.Weekday() == time.Saturday || now.Weekday() == time.Sunday,
+		Session:   session,
+	}
+}
+
+// renderPromptText 使用 text/template 渲染提示词文本，不含模板语法时原样返回
+func renderPromptText(name, text string, data *PromptData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s 模板语法错误: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%s 模板渲染失败: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// renderPromptTextOrRaw 渲染失败时记录日志并回退到原始文本，避免影响交易周期
+func renderPromptTextOrRaw(name, text string, data *PromptData) string {
+	rendered, err := renderPromptText(name, text, data)
+	if err != nil {
+		logger.Warnf("⚠️ %v，使用原始文本", err)
+		return text
+	}
+	return rendered
+}
+
+// ValidatePromptTemplate 使用示例数据校验单个模板文本
+func ValidatePromptTemplate(name, text string, risk store.RiskControlConfig) error {
+	_, err := renderPromptText(name, text, SamplePromptData(risk))
+	return err
+}
+
+// ValidateStrategyPromptTemplates 校验策略中所有可使用模板语法的文本
+func ValidateStrategyPromptTemplates(config *store.StrategyConfig, baseTemplate string) error {
+	sections := []struct {
+		name string
+		text string
+	}{
+		{"base_template", baseTemplate},
+		{"role_definition", config.PromptSections.RoleDefinition},
+		{"trading_frequency", config.PromptSections.TradingFrequency},
+		{"entry_standards", config.PromptSections.EntryStandards},
+		{"decision_process", config.PromptSections.DecisionProcess},
+		{"custom_prompt", config.CustomPrompt},
+	}
+	var errs []string
+	for _, section := range sections {
+		if err := ValidatePromptTemplate(section.name, section.text, config.RiskControl); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
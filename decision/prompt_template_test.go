@@ -0,0 +1,91 @@
+package decision
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/market"
+	"nofx/store"
+)
+
+// TestRenderPromptText 测试模板变量、条件和辅助函数
+func TestRenderPromptText(t *testing.T) {
+	data := SamplePromptData(store.RiskControlConfig{MaxMarginUsage: 0.9, MaxPositions: 3})
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"纯文本原样输出", "# 角色 100%", "# 角色 100%"},
+		{"金额格式化", "净值 {{usd .Account.TotalEquity}}", "净值 1000.00 USDT"},
+		{"百分比", "{{pct .Account.MarginUsedPct}} / {{ratioPct .Risk.MaxMarginUsage}}", "20.00% / 90.00%"},
+		{"小数位数", "{{fixed .Account.UnrealizedPnL 1}}", "12.5"},
+		{"整数与浮点运算", "{{fixed (mul .Account.TotalEquity 2) 0}}", "2000"},
+		{"条件", `{{if lt (len .Positions) .Risk.MaxPositions}}可开仓{{else}}已满仓{{end}}`, "可开仓"},
+		{"遍历持仓", "{{range .Positions}}{{.Symbol}} {{upper .Side}}{{end}}", "BTCUSDT LONG"},
+		{"市场状态", `{{if eq .Regime "ranging"}}震荡{{end}}`, "震荡"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderPromptText(tt.name, tt.text, data)
+			if err != nil {
+				t.Fatalf("渲染失败: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateStrategyPromptTemplates 测试保存前的模板校验
+func TestValidateStrategyPromptTemplates(t *testing.T) {
+	config := &store.StrategyConfig{
+		PromptSections: store.PromptSectionsConfig{
+			RoleDefinition: "{{if .Account.TotalEquity}}ok{{end}}",
+		},
+	}
+	if err := ValidateStrategyPromptTemplates(config, ""); err != nil {
+		t.Fatalf("合法模板不应报错: %v", err)
+	}
+
+	config.PromptSections.EntryStandards = "{{if .Account.TotalEquity}}缺少end"
+	config.CustomPrompt = "{{.Account.NoSuchField}}"
+	err := ValidateStrategyPromptTemplates(config, "")
+	if err == nil {
+		t.Fatal("非法模板应报错")
+	}
+	if !strings.Contains(err.Error(), "entry_standards") || !strings.Contains(err.Error(), "custom_prompt") {
+		t.Errorf("错误信息应包含出错的部分: %v", err)
+	}
+}
+
+// TestBuildSystemPromptWithContext 测试使用交易上下文渲染 System Prompt
+func TestBuildSystemPromptWithContext(t *testing.T) {
+	engine := NewStrategyEngine(&store.StrategyConfig{
+		PromptSections: store.PromptSectionsConfig{
+			RoleDefinition: `持仓{{len .Positions}}个，市场{{.Regime}}`,
+		},
+		CustomPrompt: "{{.Account.NoSuchField}}",
+	})
+	ctx := &Context{
+		Account:        AccountInfo{TotalEquity: 500},
+		Positions:      []PositionInfo{{Symbol: "ETHUSDT"}},
+		MarketOverview: &market.MarketOverview{Regime: market.RegimeTrendingDown},
+	}
+
+	prompt := engine.BuildSystemPromptWithContext(ctx, "balanced")
+	if !strings.Contains(prompt, "持仓1个，市场trending_down") {
+		t.Errorf("角色定义未按上下文渲染")
+	}
+	// 未计算市场概览时为 unknown
+	if data := NewPromptData(&Context{}, store.RiskControlConfig{}, "balanced"); data.Regime != "unknown" {
+		t.Errorf("regime = %s, want unknown", data.Regime)
+	}
+	// 渲染失败时回退到原始文本
+	if !strings.Contains(prompt, "{{.Account.NoSuchField}}") {
+		t.Errorf("渲染失败的部分应保留原始文本")
+	}
+}
@@ -0,0 +1,132 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/mcp"
+	"nofx/store"
+	"strings"
+)
+
+// 决策提供者类型
+const (
+	DecisionProviderLLM          = "llm"
+	DecisionProviderEMACross     = "ema_cross"
+	DecisionProviderRSIReversion = "rsi_reversion"
+	DecisionProviderATRBreakout  = "atr_breakout"
+)
+
+// DecisionProvider 决策提供者：根据交易上下文给出完整决策
+// LLM 和内置规则策略都实现该接口，实盘 AutoTrader 与回测 Runner 共用
+type DecisionProvider interface {
+	// Name 提供者名称（用于日志和决策记录）
+	Name() string
+	// Decide 生成决策；出错时可能同时返回部分结果（用于调试记录）
+	Decide(ctx *Context) (*FullDecision, error)
+}
+
+// IsRuleProvider 是否为不依赖AI的内置规则策略
+func IsRuleProvider(providerType string) bool {
+	switch normalizeProviderType(providerType) {
+	case DecisionProviderEMACross, DecisionProviderRSIReversion, DecisionProviderATRBreakout:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateDecisionProviderConfig 校验决策提供者配置
+func ValidateDecisionProviderConfig(config store.DecisionProviderConfig) error {
+	providerType := normalizeProviderType(config.Type)
+	if providerType != DecisionProviderLLM && !IsRuleProvider(providerType) {
+		return fmt.Errorf("不支持的决策提供者: %s", config.Type)
+	}
+	if !IsRuleProvider(providerType) {
+		return nil
+	}
+	rules := withRuleDefaults(config)
+	if rules.EMAFast >= rules.EMASlow {
+		return fmt.Errorf("EMA 快线周期必须小于慢线周期")
+	}
+	if rules.RSIOversold >= rules.RSIOverbought {
+		return fmt.Errorf("RSI 超卖阈值必须小于超买阈值")
+	}
+	if rules.PositionSizePct > 100 {
+		return fmt.Errorf("单笔仓位比例不能超过 100%%")
+	}
+	if rules.TakeProfitATR <= rules.StopLossATR {
+		return fmt.Errorf("止盈距离必须大于止损距离")
+	}
+	return nil
+}
+
+// NewDecisionProvider 根据策略配置创建决策提供者
+// engine 为 nil 或未配置规则策略时使用 LLM（variant 为 Prompt 变体）
+func NewDecisionProvider(engine *StrategyEngine, client mcp.AIClient, variant string) (DecisionProvider, error) {
+	if engine == nil {
+		return NewLLMProvider(client, nil, variant), nil
+	}
+	config := engine.GetConfig()
+	if !IsRuleProvider(config.DecisionProvider.Type) {
+		if err := ValidateDecisionProviderConfig(config.DecisionProvider); err != nil {
+			return nil, err
+		}
+		return NewLLMProvider(client, engine, variant), nil
+	}
+
+	primaryTimeframe := config.Indicators.Klines.PrimaryTimeframe
+	if primaryTimeframe == "" && len(config.Indicators.Klines.SelectedTimeframes) > 0 {
+		primaryTimeframe = config.Indicators.Klines.SelectedTimeframes[0]
+	}
+	return NewRuleProvider(config.DecisionProvider, config.RiskControl, primaryTimeframe)
+}
+
+func normalizeProviderType(providerType string) string {
+	providerType = strings.ToLower(strings.TrimSpace(providerType))
+	if providerType == "" {
+		return DecisionProviderLLM
+	}
+	return providerType
+}
+
+// LLMProvider 使用AI模型生成决策
+type LLMProvider struct {
+	client  mcp.AIClient
+	engine  *StrategyEngine
+	variant string
+
+	// 未使用策略引擎时的 Prompt 配置（回测）
+	customPrompt string
+	overrideBase bool
+	templateName string
+}
+
+// NewLLMProvider 创建使用策略引擎构建 Prompt 的 LLM 决策提供者
+func NewLLMProvider(client mcp.AIClient, engine *StrategyEngine, variant string) *LLMProvider {
+	return &LLMProvider{client: client, engine: engine, variant: variant}
+}
+
+// NewCustomPromptLLMProvider 创建使用提示词模板 + 自定义 Prompt 的 LLM 决策提供者
+func NewCustomPromptLLMProvider(client mcp.AIClient, customPrompt string, overrideBase bool, templateName string) *LLMProvider {
+	return &LLMProvider{
+		client:       client,
+		customPrompt: customPrompt,
+		overrideBase: overrideBase,
+		templateName: templateName,
+	}
+}
+
+// Name 提供者名称
+func (p *LLMProvider) Name() string {
+	return DecisionProviderLLM
+}
+
+// Decide 调用AI生成决策
+func (p *LLMProvider) Decide(ctx *Context) (*FullDecision, error) {
+	if p.engine != nil {
+		return GetFullDecisionWithStrategy(ctx, p.client, p.engine, p.variant)
+	}
+	if ctx != nil && p.variant != "" {
+		ctx.PromptVariant = p.variant
+	}
+	return GetFullDecisionWithCustomPrompt(ctx, p.client, p.customPrompt, p.overrideBase, p.templateName)
+}
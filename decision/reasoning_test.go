@@ -0,0 +1,57 @@
+package decision
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/mcp"
+)
+
+// TestParseFullDecisionResponse_StripThinking 思考块中的示例JSON不应被当作决策
+func TestParseFullDecisionResponse_StripThinking(t *testing.T) {
+	response := `<think>也许可以这样输出: [{"symbol":"ETHUSDT","action":"open_long"}]，不对，应该观望</think>
+<reasoning>BTC 震荡，观望</reasoning>
+<decision>
+[{"symbol":"BTCUSDT","action":"wait","reasoning":"震荡"}]
+</decision>`
+
+	decision, err := parseFullDecisionResponse(response, 1000, 10, 5)
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if len(decision.Decisions) != 1 || decision.Decisions[0].Symbol != "BTCUSDT" {
+		t.Errorf("unexpected decisions: %+v", decision.Decisions)
+	}
+	if !strings.HasPrefix(decision.CoTTrace, "也许可以这样输出") {
+		t.Errorf("think block should be used as CoT, got %q", decision.CoTTrace)
+	}
+}
+
+// TestParseAIResult_NativeReasoning 优先使用提供商返回的原生思维链
+func TestParseAIResult_NativeReasoning(t *testing.T) {
+	result := mcp.NewResult(`<reasoning>正文分析</reasoning>[{"symbol":"BTCUSDT","action":"wait","reasoning":"x"}]`, "原生推理", "stop", &mcp.Usage{TotalTokens: 10})
+	result.Provider, result.Target = "deepseek", "backup-model"
+
+	decision, err := parseAIResult(result, 1000, 10, 5)
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if decision.CoTTrace != "原生推理" || decision.FinishReason != mcp.FinishReasonStop || decision.AIUsage == nil ||
+		decision.AIProvider != "deepseek" || decision.AITarget != "backup-model" {
+		t.Errorf("unexpected decision: %+v", decision)
+	}
+
+	// 没有原生思维链时使用正文中的分析
+	decision, _ = parseAIResult(mcp.NewResult(`<reasoning>正文分析</reasoning>[{"symbol":"BTCUSDT","action":"wait","reasoning":"x"}]`, "", "", nil), 1000, 10, 5)
+	if decision.CoTTrace != "正文分析" {
+		t.Errorf("should fall back to text CoT, got %q", decision.CoTTrace)
+	}
+}
+
+// TestParseAIResult_Truncated 输出被截断时在错误中说明原因
+func TestParseAIResult_Truncated(t *testing.T) {
+	result := mcp.NewResult(`[{"symbol":"BTCUSDT","action":"open_long","leverage":100,"position_size_usd":1}]`, "", "length", nil)
+	if _, err := parseAIResult(result, 1000, 10, 5); err == nil || !strings.Contains(err.Error(), "max_tokens") {
+		t.Errorf("expected truncation hint, got %v", err)
+	}
+}
@@ -0,0 +1,398 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"nofx/market"
+	"nofx/store"
+	"strings"
+	"time"
+)
+
+// KlineSource 按币种和周期获取K线（最后一根为最新K线）
+// 实盘默认从 WebSocket 缓存读取，回测按当前回放时间截取历史K线
+type KlineSource func(symbol, timeframe string) ([]market.Kline, error)
+
+// ruleSignal 单个币种的规则信号
+type ruleSignal struct {
+	entry     int    // 1: 开多, -1: 开空, 0: 不开仓
+	exitLong  bool   // 平多条件成立
+	exitShort bool   // 平空条件成立
+	summary   string // 指标摘要（写入思维链）
+}
+
+// RuleProvider 内置规则策略决策提供者（不调用AI）
+type RuleProvider struct {
+	kind      string
+	config    store.DecisionProviderConfig
+	risk      store.RiskControlConfig
+	timeframe string
+	minBars   int
+	signal    func(klines []market.Kline) ruleSignal
+}
+
+// NewRuleProvider 创建规则策略决策提供者
+func NewRuleProvider(config store.DecisionProviderConfig, risk store.RiskControlConfig, primaryTimeframe string) (*RuleProvider, error) {
+	if err := ValidateDecisionProviderConfig(config); err != nil {
+		return nil, err
+	}
+	config = withRuleDefaults(config)
+
+	p := &RuleProvider{
+		kind:      normalizeProviderType(config.Type),
+		config:    config,
+		risk:      risk,
+		timeframe: config.Timeframe,
+	}
+	if p.timeframe == "" {
+		p.timeframe = primaryTimeframe
+	}
+	if p.timeframe == "" {
+		p.timeframe = "15m"
+	}
+
+	switch p.kind {
+	case DecisionProviderEMACross:
+		p.minBars = max(config.EMASlow, config.ATRPeriod) + 2
+		p.signal = p.emaCrossSignal
+	case DecisionProviderRSIReversion:
+		p.minBars = max(config.RSIPeriod, config.ATRPeriod) + 2
+		p.signal = p.rsiReversionSignal
+	case DecisionProviderATRBreakout:
+		p.minBars = max(config.BreakoutLookback, config.ATRPeriod) + 2
+		p.signal = p.atrBreakoutSignal
+	default:
+		return nil, fmt.Errorf("%s 不是规则策略", config.Type)
+	}
+	return p, nil
+}
+
+// withRuleDefaults 填充规则策略的默认参数
+func withRuleDefaults(config store.DecisionProviderConfig) store.DecisionProviderConfig {
+	if config.PositionSizePct <= 0 {
+		config.PositionSizePct = 20
+	}
+	if config.StopLossATR <= 0 {
+		config.StopLossATR = 2
+	}
+	if config.TakeProfitATR <= 0 {
+		config.TakeProfitATR = 6
+	}
+	if config.ATRPeriod <= 0 {
+		config.ATRPeriod = 14
+	}
+	if config.EMAFast <= 0 {
+		config.EMAFast = 9
+	}
+	if config.EMASlow <= 0 {
+		config.EMASlow = 21
+	}
+	if config.RSIPeriod <= 0 {
+		config.RSIPeriod = 14
+	}
+	if config.RSIOversold <= 0 {
+		config.RSIOversold = 30
+	}
+	if config.RSIOverbought <= 0 {
+		config.RSIOverbought = 70
+	}
+	if config.RSIExit <= 0 {
+		config.RSIExit = 50
+	}
+	if config.BreakoutLookback <= 0 {
+		config.BreakoutLookback = 20
+	}
+	if config.BreakoutATRMult <= 0 {
+		config.BreakoutATRMult = 0.5
+	}
+	return config
+}
+
+// Name 提供者名称
+func (p *RuleProvider) Name() string {
+	return p.kind
+}
+
+// Decide 对持仓和候选币种逐一计算信号：持仓满足平仓条件则平仓，空仓币种出现入场信号则开仓
+// 有下架预警的持仓直接平仓
+func (p *RuleProvider) Decide(ctx *Context) (*FullDecision, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("context is nil")
+	}
+	klines := ctx.Klines
+	if klines == nil {
+		klines = ctx.marketProvider().Stream().GetCurrentKlines
+	}
+
+	btcEthLeverage, altcoinLeverage := p.leverageLimits(ctx)
+	var cot strings.Builder
+	fmt.Fprintf(&cot, "规则策略 %s（周期 %s）\n", p.kind, p.timeframe)
+
+	// 持仓方向（同一币种只考虑首个持仓）
+	positionSide := make(map[string]string)
+	symbols := make([]string, 0, len(ctx.Positions)+len(ctx.CandidateCoins))
+	for _, pos := range ctx.Positions {
+		if _, exists := positionSide[pos.Symbol]; !exists {
+			positionSide[pos.Symbol] = pos.Side
+			symbols = append(symbols, pos.Symbol)
+		}
+	}
+	for _, coin := range ctx.CandidateCoins {
+		if _, exists := positionSide[coin.Symbol]; !exists {
+			positionSide[coin.Symbol] = ""
+			symbols = append(symbols, coin.Symbol)
+		}
+	}
+
+	// 记录市场状态（实盘规则策略不经过 LLM 的行情获取流程，需要在此计算市场概览）
+	if ctx.MarketOverview == nil {
+		ctx.MarketOverview = LoadMarketOverview(klines, p.timeframe, symbols)
+	}
+
+	openSlots := len(symbols)
+	if p.risk.MaxPositions > 0 {
+		openSlots = p.risk.MaxPositions - len(ctx.Positions)
+	}
+	// 可用于新开仓的保证金（策略配置了最大保证金使用率时）
+	marginBudget := math.Inf(1)
+	if p.risk.MaxMarginUsage > 0 {
+		marginBudget = ctx.Account.TotalEquity*p.risk.MaxMarginUsage - ctx.Account.MarginUsed
+	}
+
+	now := time.Now()
+	decisions := make([]Decision, 0)
+
+	// 下架预警的持仓直接平仓（不依赖K线信号，K线可能已停止更新）
+	delisting := make(map[string]bool)
+	for _, w := range ctx.DelistWarnings {
+		side := strings.ToLower(w.Side)
+		if (side != "long" && side != "short") || delisting[w.Symbol+"_"+side] {
+			continue
+		}
+		delisting[w.Symbol+"_"+side] = true
+		reason := "下架预警: " + w.Reason
+		decisions = append(decisions, Decision{Symbol: w.Symbol, Action: "close_" + side, Reasoning: reason})
+		fmt.Fprintf(&cot, "- %s %s: %s，平仓\n", w.Symbol, side, reason)
+	}
+
+	for _, symbol := range symbols {
+		if delisting[symbol+"_"+positionSide[symbol]] {
+			continue
+		}
+		series, err := klines(symbol, p.timeframe)
+		if err != nil {
+			fmt.Fprintf(&cot, "- %s: 获取K线失败: %v\n", symbol, err)
+			continue
+		}
+		// 只使用已收盘K线（与回测一致，避免未收盘K线内信号反复出现又消失）
+		series = market.ClosedKlines(series, now)
+		if len(series) < p.minBars {
+			fmt.Fprintf(&cot, "- %s: K线不足（%d < %d）\n", symbol, len(series), p.minBars)
+			continue
+		}
+
+		signal := p.signal(series)
+		fmt.Fprintf(&cot, "- %s: %s\n", symbol, signal.summary)
+
+		switch positionSide[symbol] {
+		case "long":
+			if signal.exitLong {
+				decisions = append(decisions, Decision{Symbol: symbol, Action: "close_long", Reasoning: signal.summary})
+			} else {
+				decisions = append(decisions, Decision{Symbol: symbol, Action: "hold", Reasoning: "未触发平多条件"})
+			}
+		case "short":
+			if signal.exitShort {
+				decisions = append(decisions, Decision{Symbol: symbol, Action: "close_short", Reasoning: signal.summary})
+			} else {
+				decisions = append(decisions, Decision{Symbol: symbol, Action: "hold", Reasoning: "未触发平空条件"})
+			}
+		default:
+			if signal.entry == 0 {
+				continue
+			}
+			if openSlots <= 0 {
+				fmt.Fprintf(&cot, "  已达最大持仓数，跳过开仓\n")
+				continue
+			}
+			d := p.openDecision(symbol, signal, series, ctx.Account.TotalEquity, marginBudget, btcEthLeverage, altcoinLeverage)
+			if p.risk.MinPositionSize > 0 && d.PositionSizeUSD < p.risk.MinPositionSize {
+				fmt.Fprintf(&cot, "  开仓金额 %.2f USDT 低于策略最小开仓金额 %.2f USDT，跳过开仓\n", d.PositionSizeUSD, p.risk.MinPositionSize)
+				continue
+			}
+			if err := validateDecision(&d, ctx.Account.TotalEquity, btcEthLeverage, altcoinLeverage); err != nil {
+				fmt.Fprintf(&cot, "  开仓决策未通过验证: %v\n", err)
+				continue
+			}
+			decisions = append(decisions, d)
+			openSlots--
+			marginBudget -= d.PositionSizeUSD / float64(d.Leverage)
+		}
+	}
+
+	if len(decisions) == 0 {
+		decisions = append(decisions, Decision{Symbol: "ALL", Action: "wait", Reasoning: "无入场或平仓信号"})
+	}
+
+	return &FullDecision{
+		CoTTrace:  strings.TrimSpace(cot.String()),
+		Decisions: decisions,
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// openDecision 构建开仓决策：仓位按净值比例，止损止盈按 ATR 倍数
+// 仓位不超过策略的单币种仓位上限，且占用保证金不超过 marginBudget
+func (p *RuleProvider) openDecision(symbol string, signal ruleSignal, klines []market.Kline, equity, marginBudget float64, btcEthLeverage, altcoinLeverage int) Decision {
+	price := klines[len(klines)-1].Close
+	atr := lastValue(market.ATRSeries(klines, p.config.ATRPeriod), 0)
+
+	leverage := altcoinLeverage
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		leverage = btcEthLeverage
+	}
+	if p.config.Leverage > 0 && p.config.Leverage < leverage {
+		leverage = p.config.Leverage
+	}
+
+	// 仓位价值 = 保证金 × 杠杆，不超过单币种上限和剩余保证金额度
+	positionSize := equity * p.config.PositionSizePct / 100 * float64(leverage)
+	positionSize = math.Min(positionSize, p.maxPositionValue(symbol, equity))
+	positionSize = math.Max(math.Min(positionSize, marginBudget*float64(leverage)), 0)
+
+	d := Decision{
+		Symbol:          symbol,
+		Leverage:        leverage,
+		PositionSizeUSD: math.Round(positionSize*100) / 100,
+		Confidence:      70,
+		Reasoning:       signal.summary,
+	}
+	if signal.entry > 0 {
+		d.Action = "open_long"
+		d.StopLoss = price - p.config.StopLossATR*atr
+		d.TakeProfit = price + p.config.TakeProfitATR*atr
+	} else {
+		d.Action = "open_short"
+		d.StopLoss = price + p.config.StopLossATR*atr
+		d.TakeProfit = price - p.config.TakeProfitATR*atr
+	}
+	d.RiskUSD = math.Round(d.PositionSizeUSD*math.Abs(price-d.StopLoss)/price*100) / 100
+	return d
+}
+
+// maxPositionValue 单币种仓位价值上限：山寨币按策略的单币种最大仓位比例（未配置时 1.5 倍净值），
+// BTC/ETH 为 10 倍净值（与提示词中的硬约束一致）
+func (p *RuleProvider) maxPositionValue(symbol string, equity float64) float64 {
+	if symbol == "BTCUSDT" || symbol == "ETHUSDT" {
+		return equity * 10
+	}
+	ratio := p.risk.MaxPositionRatio
+	if ratio <= 0 {
+		ratio = 1.5
+	}
+	return equity * ratio
+}
+
+// leverageLimits 杠杆上限：优先使用上下文中的配置，其次使用风控配置，默认 5x
+func (p *RuleProvider) leverageLimits(ctx *Context) (int, int) {
+	btcEthLeverage, altcoinLeverage := ctx.BTCETHLeverage, ctx.AltcoinLeverage
+	if btcEthLeverage <= 0 {
+		btcEthLeverage = p.risk.BTCETHMaxLeverage
+	}
+	if altcoinLeverage <= 0 {
+		altcoinLeverage = p.risk.AltcoinMaxLeverage
+	}
+	if btcEthLeverage <= 0 {
+		btcEthLeverage = 5
+	}
+	if altcoinLeverage <= 0 {
+		altcoinLeverage = 5
+	}
+	return btcEthLeverage, altcoinLeverage
+}
+
+// emaCrossSignal 快线上穿慢线开多、下穿开空；快线位于慢线另一侧时平仓
+func (p *RuleProvider) emaCrossSignal(klines []market.Kline) ruleSignal {
+	closes := market.ClosePrices(klines)
+	fast := market.EMASeries(closes, p.config.EMAFast)
+	slow := market.EMASeries(closes, p.config.EMASlow)
+	n := len(closes)
+	fastNow, slowNow := fast[n-1], slow[n-1]
+	fastPrev, slowPrev := fast[n-2], slow[n-2]
+
+	signal := ruleSignal{
+		exitLong:  fastNow < slowNow,
+		exitShort: fastNow > slowNow,
+		summary: fmt.Sprintf("EMA%d=%.4f EMA%d=%.4f（前值 %.4f / %.4f）",
+			p.config.EMAFast, fastNow, p.config.EMASlow, slowNow, fastPrev, slowPrev),
+	}
+	if fastPrev <= slowPrev && fastNow > slowNow {
+		signal.entry = 1
+		signal.summary += "，金叉"
+	} else if fastPrev >= slowPrev && fastNow < slowNow {
+		signal.entry = -1
+		signal.summary += "，死叉"
+	}
+	return signal
+}
+
+// rsiReversionSignal RSI 超卖开多、超买开空；回到中轴时平仓
+func (p *RuleProvider) rsiReversionSignal(klines []market.Kline) ruleSignal {
+	rsi := lastValue(market.RSISeries(market.ClosePrices(klines), p.config.RSIPeriod), 50)
+
+	signal := ruleSignal{
+		exitLong:  rsi >= p.config.RSIExit,
+		exitShort: rsi <= p.config.RSIExit,
+		summary:   fmt.Sprintf("RSI%d=%.2f（超卖 %.0f / 超买 %.0f / 平仓 %.0f）", p.config.RSIPeriod, rsi, p.config.RSIOversold, p.config.RSIOverbought, p.config.RSIExit),
+	}
+	if rsi < p.config.RSIOversold {
+		signal.entry = 1
+		signal.summary += "，超卖"
+	} else if rsi > p.config.RSIOverbought {
+		signal.entry = -1
+		signal.summary += "，超买"
+	}
+	return signal
+}
+
+// atrBreakoutSignal 收盘价突破前 N 根K线区间 ± k×ATR 时顺势开仓，反向突破 N/2 区间时平仓
+func (p *RuleProvider) atrBreakoutSignal(klines []market.Kline) ruleSignal {
+	n := len(klines)
+	last := klines[n-1].Close
+	atr := lastValue(market.ATRSeries(klines, p.config.ATRPeriod), 0)
+	high, low := rangeOf(klines[n-1-p.config.BreakoutLookback : n-1])
+	exitHigh, exitLow := rangeOf(klines[n-1-max(p.config.BreakoutLookback/2, 1) : n-1])
+
+	upper := high + p.config.BreakoutATRMult*atr
+	lower := low - p.config.BreakoutATRMult*atr
+	signal := ruleSignal{
+		exitLong:  last < exitLow,
+		exitShort: last > exitHigh,
+		summary:   fmt.Sprintf("收盘 %.4f，上轨 %.4f，下轨 %.4f，ATR%d=%.4f", last, upper, lower, p.config.ATRPeriod, atr),
+	}
+	if last > upper {
+		signal.entry = 1
+		signal.summary += "，向上突破"
+	} else if last < lower {
+		signal.entry = -1
+		signal.summary += "，向下突破"
+	}
+	return signal
+}
+
+func rangeOf(klines []market.Kline) (high, low float64) {
+	high, low = klines[0].High, klines[0].Low
+	for _, k := range klines[1:] {
+		high = math.Max(high, k.High)
+		low = math.Min(low, k.Low)
+	}
+	return high, low
+}
+
+// lastValue 序列的最新值（预热阶段为 NaN 时返回 def）
+func lastValue(series []float64, def float64) float64 {
+	if len(series) == 0 || math.IsNaN(series[len(series)-1]) {
+		return def
+	}
+	return series[len(series)-1]
+}
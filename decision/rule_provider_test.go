@@ -0,0 +1,269 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"nofx/store"
+	"testing"
+	"time"
+)
+
+// klinesFromCloses 用收盘价序列构造K线（高低点为收盘价 ±1%）
+func klinesFromCloses(closes []float64) []market.Kline {
+	klines := make([]market.Kline, len(closes))
+	for i, c := range closes {
+		open := c
+		if i > 0 {
+			open = closes[i-1]
+		}
+		klines[i] = market.Kline{Open: open, High: c * 1.01, Low: c * 0.99, Close: c}
+	}
+	return klines
+}
+
+func ruleContext(klines map[string][]market.Kline, positions []PositionInfo) *Context {
+	coins := make([]CandidateCoin, 0, len(klines))
+	for symbol := range klines {
+		coins = append(coins, CandidateCoin{Symbol: symbol})
+	}
+	return &Context{
+		Account:         AccountInfo{TotalEquity: 1000},
+		Positions:       positions,
+		CandidateCoins:  coins,
+		BTCETHLeverage:  5,
+		AltcoinLeverage: 3,
+		Klines: func(symbol, timeframe string) ([]market.Kline, error) {
+			series, ok := klines[symbol]
+			if !ok {
+				return nil, fmt.Errorf("no klines for %s", symbol)
+			}
+			return series, nil
+		},
+	}
+}
+
+func findAction(decisions []Decision, symbol string) string {
+	for _, d := range decisions {
+		if d.Symbol == symbol {
+			return d.Action
+		}
+	}
+	return ""
+}
+
+// TestRuleProvider_EMACross 测试EMA金叉开多、死叉平多
+func TestRuleProvider_EMACross(t *testing.T) {
+	provider, err := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderEMACross, EMAFast: 3, EMASlow: 8}, store.RiskControlConfig{}, "15m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 长时间下跌后最后一根大涨，快线上穿慢线
+	closes := make([]float64, 0, 40)
+	for i := 0; i < 39; i++ {
+		closes = append(closes, 100-float64(i)*0.5)
+	}
+	closes = append(closes, 110)
+	series := klinesFromCloses(closes)
+
+	fd, err := provider.Decide(ruleContext(map[string][]market.Kline{"SOLUSDT": series}, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fd.Decisions) != 1 || fd.Decisions[0].Action != "open_long" {
+		t.Fatalf("decisions = %+v, want open_long", fd.Decisions)
+	}
+	d := fd.Decisions[0]
+	if d.Leverage != 3 || d.StopLoss >= 110 || d.TakeProfit <= 110 {
+		t.Errorf("unexpected open parameters: %+v", d)
+	}
+	if d.PositionSizeUSD != 600 { // 1000 × 20% × 3x
+		t.Errorf("position size = %.2f, want 600", d.PositionSizeUSD)
+	}
+
+	// 持有空仓时金叉触发平空
+	fd, _ = provider.Decide(ruleContext(map[string][]market.Kline{"SOLUSDT": series},
+		[]PositionInfo{{Symbol: "SOLUSDT", Side: "short"}}))
+	if got := findAction(fd.Decisions, "SOLUSDT"); got != "close_short" {
+		t.Errorf("action = %s, want close_short", got)
+	}
+
+	// 规则策略同样记录市场状态（基于参考币种）
+	ctx := ruleContext(map[string][]market.Kline{"SOLUSDT": series}, nil)
+	candidates := ctx.Klines
+	ctx.Klines = func(symbol, timeframe string) ([]market.Kline, error) {
+		if symbol == market.BenchmarkBTC {
+			return series, nil
+		}
+		return candidates(symbol, timeframe)
+	}
+	if _, err := provider.Decide(ctx); err != nil || ctx.MarketRegime() == "" {
+		t.Errorf("market regime not recorded: %v %+v", err, ctx.MarketOverview)
+	}
+}
+
+// TestRuleProvider_RSIReversion 测试RSI超买开空，持多仓时平仓
+func TestRuleProvider_RSIReversion(t *testing.T) {
+	provider, err := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderRSIReversion}, store.RiskControlConfig{}, "15m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	closes := make([]float64, 30)
+	for i := range closes {
+		closes[i] = 100 + float64(i)
+	}
+	series := klinesFromCloses(closes)
+
+	fd, _ := provider.Decide(ruleContext(map[string][]market.Kline{"ETHUSDT": series}, nil))
+	if got := findAction(fd.Decisions, "ETHUSDT"); got != "open_short" {
+		t.Errorf("action = %s, want open_short (cot: %s)", got, fd.CoTTrace)
+	}
+
+	fd, _ = provider.Decide(ruleContext(map[string][]market.Kline{"ETHUSDT": series},
+		[]PositionInfo{{Symbol: "ETHUSDT", Side: "long"}}))
+	if got := findAction(fd.Decisions, "ETHUSDT"); got != "close_long" {
+		t.Errorf("action = %s, want close_long", got)
+	}
+}
+
+// TestRuleProvider_ATRBreakout 测试突破开仓、无信号时 wait 以及最大持仓限制
+func TestRuleProvider_ATRBreakout(t *testing.T) {
+	flat := make([]float64, 30)
+	for i := range flat {
+		flat[i] = 100
+	}
+	breakout := append(append([]float64{}, flat...), 120)
+
+	provider, err := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderATRBreakout}, store.RiskControlConfig{}, "1h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fd, _ := provider.Decide(ruleContext(map[string][]market.Kline{
+		"BTCUSDT": klinesFromCloses(breakout),
+		"SOLUSDT": klinesFromCloses(flat),
+	}, nil))
+	if got := findAction(fd.Decisions, "BTCUSDT"); got != "open_long" {
+		t.Errorf("BTCUSDT action = %s, want open_long", got)
+	}
+	if got := findAction(fd.Decisions, "SOLUSDT"); got != "" {
+		t.Errorf("SOLUSDT action = %s, want no decision", got)
+	}
+
+	// 已达最大持仓数时不再开仓
+	limited, _ := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderATRBreakout}, store.RiskControlConfig{MaxPositions: 1}, "1h")
+	fd, _ = limited.Decide(ruleContext(map[string][]market.Kline{
+		"BTCUSDT": klinesFromCloses(breakout),
+		"SOLUSDT": klinesFromCloses(flat),
+	}, []PositionInfo{{Symbol: "SOLUSDT", Side: "long"}}))
+	if got := findAction(fd.Decisions, "BTCUSDT"); got != "" {
+		t.Errorf("BTCUSDT action = %s, want no open when max positions reached", got)
+	}
+	if got := findAction(fd.Decisions, "SOLUSDT"); got != "hold" {
+		t.Errorf("SOLUSDT action = %s, want hold", got)
+	}
+}
+
+// TestRuleProvider_ClosedKlinesAndLimits 测试忽略未收盘K线，以及按策略风控配置限制仓位
+func TestRuleProvider_ClosedKlinesAndLimits(t *testing.T) {
+	flat := make([]float64, 30)
+	for i := range flat {
+		flat[i] = 100
+	}
+	series := klinesFromCloses(append(flat, 120))
+	provider, _ := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderATRBreakout}, store.RiskControlConfig{}, "1h")
+
+	// 突破K线尚未收盘时不开仓
+	open := append([]market.Kline{}, series...)
+	open[len(open)-1].CloseTime = time.Now().Add(time.Hour).UnixMilli()
+	fd, _ := provider.Decide(ruleContext(map[string][]market.Kline{"SOLUSDT": open}, nil))
+	if got := findAction(fd.Decisions, "SOLUSDT"); got != "" {
+		t.Errorf("unclosed breakout: action = %s, want none", got)
+	}
+
+	// 单币种仓位比例上限：1000 × 0.5
+	limited, _ := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderATRBreakout}, store.RiskControlConfig{MaxPositionRatio: 0.5}, "1h")
+	fd, _ = limited.Decide(ruleContext(map[string][]market.Kline{"SOLUSDT": series}, nil))
+	if len(fd.Decisions) != 1 || fd.Decisions[0].PositionSizeUSD != 500 {
+		t.Errorf("max position ratio: %+v", fd.Decisions)
+	}
+
+	// 保证金使用率上限：剩余保证金 1000 × 0.3 - 200 = 100，3x 杠杆最多 300
+	margin, _ := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderATRBreakout}, store.RiskControlConfig{MaxMarginUsage: 0.3}, "1h")
+	ctx := ruleContext(map[string][]market.Kline{"SOLUSDT": series}, nil)
+	ctx.Account.MarginUsed = 200
+	fd, _ = margin.Decide(ctx)
+	if len(fd.Decisions) != 1 || fd.Decisions[0].PositionSizeUSD != 300 {
+		t.Errorf("max margin usage: %+v", fd.Decisions)
+	}
+
+	// 低于策略最小开仓金额时跳过
+	minSize, _ := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderATRBreakout}, store.RiskControlConfig{MaxMarginUsage: 0.3, MinPositionSize: 400}, "1h")
+	fd, _ = minSize.Decide(ctx)
+	if got := findAction(fd.Decisions, "SOLUSDT"); got != "" {
+		t.Errorf("min position size: action = %s, want none", got)
+	}
+}
+
+// TestRuleProvider_DelistWarnings 测试有下架预警的持仓直接平仓，不依赖K线信号
+func TestRuleProvider_DelistWarnings(t *testing.T) {
+	provider, _ := NewRuleProvider(store.DecisionProviderConfig{Type: DecisionProviderRSIReversion}, store.RiskControlConfig{}, "15m")
+	// 持续下跌，RSI 低于平仓线，多仓本应 hold
+	closes := make([]float64, 30)
+	for i := range closes {
+		closes[i] = 100 - float64(i)*0.5
+	}
+	positions := []PositionInfo{
+		{Symbol: "OLDUSDT", Side: "short"}, // 已停止交易，没有K线
+		{Symbol: "SOLUSDT", Side: "long"},
+	}
+	ctx := ruleContext(map[string][]market.Kline{"SOLUSDT": klinesFromCloses(closes)}, positions)
+	if fd, _ := provider.Decide(ctx); findAction(fd.Decisions, "SOLUSDT") != "hold" {
+		t.Fatalf("without warnings: %+v", fd.Decisions)
+	}
+
+	ctx = ruleContext(map[string][]market.Kline{"SOLUSDT": klinesFromCloses(closes)}, positions)
+	ctx.DelistWarnings = []DelistingWarning{
+		{Symbol: "OLDUSDT", Side: "short", Reason: "交易所状态为 SETTLING"},
+		{Symbol: "SOLUSDT", Side: "long", Reason: "计划于 2026-01-02 08:00 UTC 下架"},
+	}
+
+	fd, err := provider.Decide(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fd.Decisions) != 2 {
+		t.Fatalf("decisions = %+v, want one close per delisting position", fd.Decisions)
+	}
+	if got := findAction(fd.Decisions, "OLDUSDT"); got != "close_short" {
+		t.Errorf("OLDUSDT action = %s, want close_short", got)
+	}
+	if got := findAction(fd.Decisions, "SOLUSDT"); got != "close_long" {
+		t.Errorf("SOLUSDT action = %s, want close_long", got)
+	}
+}
+
+// TestNewDecisionProvider 测试根据策略配置选择决策提供者
+func TestNewDecisionProvider(t *testing.T) {
+	config := &store.StrategyConfig{}
+	provider, err := NewDecisionProvider(NewStrategyEngine(config), nil, "balanced")
+	if err != nil || provider.Name() != DecisionProviderLLM {
+		t.Errorf("default provider = %v (%v), want llm", provider, err)
+	}
+
+	config.DecisionProvider.Type = "RSI_Reversion"
+	provider, err = NewDecisionProvider(NewStrategyEngine(config), nil, "balanced")
+	if err != nil || provider.Name() != DecisionProviderRSIReversion {
+		t.Errorf("provider = %v (%v), want rsi_reversion", provider, err)
+	}
+
+	config.DecisionProvider.Type = "magic"
+	if _, err := NewDecisionProvider(NewStrategyEngine(config), nil, "balanced"); err == nil {
+		t.Error("expected error for unknown provider type")
+	}
+
+	invalid := store.DecisionProviderConfig{Type: DecisionProviderEMACross, EMAFast: 30, EMASlow: 10}
+	if err := ValidateDecisionProviderConfig(invalid); err == nil {
+		t.Error("expected error when fast EMA >= slow EMA")
+	}
+}
@@ -0,0 +1,56 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/store"
+	"strings"
+	"time"
+)
+
+// formatWebhookSignals 格式化未过期的外部 Webhook 信号（按接收时间倒序）
+func formatWebhookSignals(signals []*store.WebhookSignal, now time.Time) string {
+	if len(signals) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## 外部信号（Webhook）\n")
+	sb.WriteString("以下信号来自外部告警（如 TradingView），仅作参考，需结合行情数据独立判断\n")
+	for i, sig := range signals {
+		symbol := sig.Symbol
+		if symbol == "" {
+			symbol = "全市场"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s %s", i+1, symbol, sig.Action))
+		if sig.Price > 0 {
+			sb.WriteString(fmt.Sprintf(" @ %.4f", sig.Price))
+		}
+		if sig.Timeframe != "" {
+			sb.WriteString(fmt.Sprintf(" | 周期: %s", sig.Timeframe))
+		}
+		if sig.Confidence > 0 {
+			sb.WriteString(fmt.Sprintf(" | 信心: %d", sig.Confidence))
+		}
+		if sig.Source != "" {
+			sb.WriteString(fmt.Sprintf(" | 来源: %s", sig.Source))
+		}
+		sb.WriteString(fmt.Sprintf(" | %s前收到，剩余有效 %s\n",
+			formatPromptDuration(now.Sub(sig.ReceivedAt)), formatPromptDuration(sig.ExpiresAt.Sub(now))))
+		if sig.Message != "" {
+			sb.WriteString(fmt.Sprintf("   说明: %s\n", sig.Message))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// formatPromptDuration 以分钟/小时显示时长（用于信号、缓存数据的时效）
+func formatPromptDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "不到1分钟"
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%d分钟", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%.1f小时", d.Hours())
+}
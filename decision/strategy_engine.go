@@ -16,16 +16,47 @@ import (
 // StrategyEngine 策略执行引擎
 // 负责基于策略配置动态获取数据和组装 Prompt
 type StrategyEngine struct {
-	config *store.StrategyConfig
+	config       *store.StrategyConfig
+	baseTemplate string                    // 基础提示词模板内容（来自用户模板库的具体版本）
+	provider     market.MarketDataProvider // 行情数据源（为空时使用默认数据源）
 }
 
 // NewStrategyEngine 创建策略执行引擎
+// 策略显式指定了行情数据源时使用该数据源，否则由调用方通过 SetMarketProvider 按交易所设置
 func NewStrategyEngine(config *store.StrategyConfig) *StrategyEngine {
-	return &StrategyEngine{config: config}
+	e := &StrategyEngine{config: config}
+	if config != nil && config.MarketDataProvider != "" {
+		if p, err := market.ProviderFor(config.MarketDataProvider); err == nil {
+			e.provider = p
+		}
+	}
+	return e
+}
+
+// SetMarketProvider 设置行情数据源
+func (e *StrategyEngine) SetMarketProvider(p market.MarketDataProvider) {
+	e.provider = p
+}
+
+// MarketProvider 当前使用的行情数据源
+func (e *StrategyEngine) MarketProvider() market.MarketDataProvider {
+	return market.OrDefault(e.provider)
 }
 
 // GetCandidateCoins 根据策略配置获取候选币种
 func (e *StrategyEngine) GetCandidateCoins() ([]CandidateCoin, error) {
+	candidates, err := e.collectCandidateCoins()
+	if err != nil {
+		return nil, err
+	}
+	// 应用策略的允许/禁止币种列表
+	candidates = e.SymbolUniverse().FilterCandidates(candidates)
+	// 应用策略中的候选币过滤规则
+	return e.filterCandidates(candidates)
+}
+
+// collectCandidateCoins 按币种来源配置收集候选币
+func (e *StrategyEngine) collectCandidateCoins() ([]CandidateCoin, error) {
 	var candidates []CandidateCoin
 	symbolSources := make(map[string][]string)
 
@@ -61,8 +92,12 @@ func (e *StrategyEngine) GetCandidateCoins() ([]CandidateCoin, error) {
 		// 仅使用 OI Top
 		return e.getOITopCoins(coinSource.OITopLimit)
 
+	case "local_rank":
+		// 本地排名（不依赖外部币种池服务）
+		return e.getLocalRankCoins(coinSource.LocalRank)
+
 	case "mixed":
-		// 混合模式：AI500 + OI Top
+		// 混合模式：AI500 + OI Top + 本地排名
 		if coinSource.UseCoinPool {
 			poolCoins, err := e.getCoinPoolCoins(coinSource.CoinPoolLimit)
 			if err != nil {
@@ -85,6 +120,17 @@ func (e *StrategyEngine) GetCandidateCoins() ([]CandidateCoin, error) {
 			}
 		}
 
+		if coinSource.UseLocalRank {
+			rankCoins, err := e.getLocalRankCoins(coinSource.LocalRank)
+			if err != nil {
+				logger.Infof("⚠️  本地币种排名失败: %v", err)
+			} else {
+				for _, coin := range rankCoins {
+					symbolSources[coin.Symbol] = append(symbolSources[coin.Symbol], "local_rank")
+				}
+			}
+		}
+
 		// 添加静态币种（如果有）
 		for _, symbol := range coinSource.StaticCoins {
 			symbol = market.Normalize(symbol)
@@ -155,26 +201,31 @@ func (e *StrategyEngine) getOITopCoins(limit int) ([]CandidateCoin, error) {
 	return candidates, nil
 }
 
-// FetchMarketData 根据策略配置获取市场数据
-func (e *StrategyEngine) FetchMarketData(symbol string) (*market.Data, error) {
-	// 目前使用现有的 market.Get，后续可以根据策略配置自定义
-	return market.Get(symbol)
-}
-
-// FetchExternalData 获取外部数据源
-func (e *StrategyEngine) FetchExternalData() (map[string]interface{}, error) {
-	externalData := make(map[string]interface{})
+// getLocalRankCoins 按本地排名获取候选币（使用策略的行情数据源）
+// 数据源不支持排名（如 Bybit、Hyperliquid）时使用 Binance 数据
+func (e *StrategyEngine) getLocalRankCoins(params market.RankingParams) ([]CandidateCoin, error) {
+	provider := e.MarketProvider()
+	if _, ok := provider.(market.CoinRanker); !ok {
+		provider = market.DefaultProvider()
+	}
+	scores, err := market.RankCoins(provider, params)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, source := range e.config.Indicators.ExternalDataSources {
-		data, err := e.fetchSingleExternalSource(source)
-		if err != nil {
-			logger.Infof("⚠️  获取外部数据源 [%s] 失败: %v", source.Name, err)
-			continue
-		}
-		externalData[source.Name] = data
+	candidates := make([]CandidateCoin, 0, len(scores))
+	for _, score := range scores {
+		candidates = append(candidates, CandidateCoin{
+			Symbol:  score.Symbol,
+			Sources: []string{"local_rank"},
+		})
 	}
+	return candidates, nil
+}
 
-	return externalData, nil
+// FetchMarketData 根据策略配置获取市场数据
+func (e *StrategyEngine) FetchMarketData(symbol string) (*market.Data, error) {
+	return market.GetFrom(e.MarketProvider(), symbol)
 }
 
 // QuantData 量化数据结构（资金流向、持仓变化、价格变化）
@@ -364,83 +415,44 @@ func (e *StrategyEngine) formatQuantData(data *QuantData) string {
 	return sb.String()
 }
 
-// fetchSingleExternalSource 获取单个外部数据源
-func (e *StrategyEngine) fetchSingleExternalSource(source store.ExternalDataSource) (interface{}, error) {
-	client := &http.Client{
-		Timeout: time.Duration(source.RefreshSecs) * time.Second,
-	}
-
-	if client.Timeout == 0 {
-		client.Timeout = 30 * time.Second
-	}
-
-	req, err := http.NewRequest(source.Method, source.URL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// 添加请求头
-	for k, v := range source.Headers {
-		req.Header.Set(k, v)
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var result interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
-	// 如果指定了数据路径，提取指定路径的数据
-	if source.DataPath != "" {
-		result = extractJSONPath(result, source.DataPath)
-	}
-
-	return result, nil
+// BuildUserPrompt 根据策略配置构建 User Prompt（不做 Token 预算裁剪）
+func (e *StrategyEngine) BuildUserPrompt(ctx *Context) string {
+	w := newPromptSectionWriter(defaultTokenizerProfile)
+	e.buildUserPrompt(ctx, userPromptOptions{maxCandidates: -1, candidateExternalData: true}, w)
+	return w.String()
 }
 
-// extractJSONPath 提取 JSON 路径数据（简单实现）
-func extractJSONPath(data interface{}, path string) interface{} {
-	parts := strings.Split(path, ".")
-	current := data
-
-	for _, part := range parts {
-		if m, ok := current.(map[string]interface{}); ok {
-			current = m[part]
-		} else {
-			return nil
-		}
-	}
+// userPromptOptions User Prompt 裁剪选项
+type userPromptOptions struct {
+	seriesLimit           int  // 每个K线序列保留的最新数据点数（0表示不限制）
+	maxCandidates         int  // 最多展示的候选币数量（-1表示不限制）
+	candidateExternalData bool // 是否输出候选币的外部（量化）数据
+}
 
-	return current
+// userPromptStats User Prompt 构建统计（用于决定下一步裁剪）
+type userPromptStats struct {
+	maxSeriesLen          int  // 输出的最长K线序列长度
+	candidates            int  // 实际展示的候选币数量
+	candidateExternalData bool // 是否输出了候选币外部数据
 }
 
-// BuildUserPrompt 根据策略配置构建 User Prompt
-func (e *StrategyEngine) BuildUserPrompt(ctx *Context) string {
-	var sb strings.Builder
+// buildUserPrompt 按裁剪选项构建 User Prompt，并按部分统计 token
+func (e *StrategyEngine) buildUserPrompt(ctx *Context, opts userPromptOptions, w *promptSectionWriter) userPromptStats {
+	var stats userPromptStats
 
 	// 系统状态
-	sb.WriteString(fmt.Sprintf("时间: %s | 周期: #%d | 运行: %d分钟\n\n",
+	w.Write(promptSectionBase, fmt.Sprintf("时间: %s | 周期: #%d | 运行: %d分钟\n\n",
 		ctx.CurrentTime, ctx.CallCount, ctx.RuntimeMinutes))
 
 	// BTC 市场（如果配置了）
 	if btcData, hasBTC := ctx.MarketDataMap["BTCUSDT"]; hasBTC {
-		sb.WriteString(fmt.Sprintf("BTC: %.2f (1h: %+.2f%%, 4h: %+.2f%%) | MACD: %.4f | RSI: %.2f\n\n",
+		w.Write(promptSectionBase, fmt.Sprintf("BTC: %.2f (1h: %+.2f%%, 4h: %+.2f%%) | MACD: %.4f | RSI: %.2f\n\n",
 			btcData.CurrentPrice, btcData.PriceChange1h, btcData.PriceChange4h,
 			btcData.CurrentMACD, btcData.CurrentRSI7))
 	}
 
 	// 账户信息
-	sb.WriteString(fmt.Sprintf("账户: 净值%.2f | 余额%.2f (%.1f%%) | 盈亏%+.2f%% | 保证金%.1f%% | 持仓%d个\n\n",
+	w.Write(promptSectionBase, fmt.Sprintf("账户: 净值%.2f | 余额%.2f (%.1f%%) | 盈亏%+.2f%% | 保证金%.1f%% | 持仓%d个\n\n",
 		ctx.Account.TotalEquity,
 		ctx.Account.AvailableBalance,
 		(ctx.Account.AvailableBalance/ctx.Account.TotalEquity)*100,
@@ -450,23 +462,40 @@ func (e *StrategyEngine) BuildUserPrompt(ctx *Context) string {
 
 	// 持仓信息
 	if len(ctx.Positions) > 0 {
-		sb.WriteString("## 当前持仓\n")
+		w.Write(promptSectionPositions, "## 当前持仓\n")
 		for i, pos := range ctx.Positions {
-			sb.WriteString(e.formatPositionInfo(i+1, pos, ctx))
+			w.Write(promptSectionPositions, e.formatPositionInfo(i+1, pos))
+
+			// 使用策略配置的指标输出市场数据
+			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
+				stats.maxSeriesLen = max(stats.maxSeriesLen, seriesLen(marketData, opts.seriesLimit))
+				w.Write(promptSectionKlines, e.formatMarketData(marketData, opts.seriesLimit))
+
+				// 添加量化数据（如果有）
+				if ctx.QuantDataMap != nil {
+					if quantData, hasQuant := ctx.QuantDataMap[pos.Symbol]; hasQuant {
+						w.Write(promptSectionExternal, e.formatQuantData(quantData))
+					}
+				}
+				w.Write(promptSectionPositions, "\n")
+			}
 		}
 	} else {
-		sb.WriteString("当前持仓: 无\n\n")
+		w.Write(promptSectionBase, "当前持仓: 无\n\n")
 	}
 
+	// 持仓下架预警
+	w.Write(promptSectionBase, formatDelistingWarnings(ctx.DelistWarnings, time.Now()))
+
 	// 交易统计
 	if ctx.TradingStats != nil && ctx.TradingStats.TotalTrades > 0 {
-		sb.WriteString("## 历史交易统计\n")
-		sb.WriteString(fmt.Sprintf("总交易数: %d | 胜率: %.1f%% | 盈亏比: %.2f | 夏普比: %.2f\n",
+		w.Write(promptSectionBase, "## 历史交易统计\n")
+		w.Write(promptSectionBase, fmt.Sprintf("总交易数: %d | 胜率: %.1f%% | 盈亏比: %.2f | 夏普比: %.2f\n",
 			ctx.TradingStats.TotalTrades,
 			ctx.TradingStats.WinRate,
 			ctx.TradingStats.ProfitFactor,
 			ctx.TradingStats.SharpeRatio))
-		sb.WriteString(fmt.Sprintf("总盈亏: %.2f USDT | 平均盈利: %.2f | 平均亏损: %.2f | 最大回撤: %.1f%%\n\n",
+		w.Write(promptSectionBase, fmt.Sprintf("总盈亏: %.2f USDT | 平均盈利: %.2f | 平均亏损: %.2f | 最大回撤: %.1f%%\n\n",
 			ctx.TradingStats.TotalPnL,
 			ctx.TradingStats.AvgWin,
 			ctx.TradingStats.AvgLoss,
@@ -475,53 +504,74 @@ func (e *StrategyEngine) BuildUserPrompt(ctx *Context) string {
 
 	// 最近完成的订单
 	if len(ctx.RecentOrders) > 0 {
-		sb.WriteString("## 最近完成的交易\n")
+		w.Write(promptSectionBase, "## 最近完成的交易\n")
 		for i, order := range ctx.RecentOrders {
 			resultStr := "盈利"
 			if order.RealizedPnL < 0 {
 				resultStr = "亏损"
 			}
-			sb.WriteString(fmt.Sprintf("%d. %s %s | 入场%.4f 出场%.4f | %s: %+.2f USDT (%+.2f%%) | %s\n",
+			w.Write(promptSectionBase, fmt.Sprintf("%d. %s %s | 入场%.4f 出场%.4f | %s: %+.2f USDT (%+.2f%%) | %s\n",
 				i+1, order.Symbol, order.Side,
 				order.EntryPrice, order.ExitPrice,
 				resultStr, order.RealizedPnL, order.PnLPct,
 				order.FilledAt))
 		}
-		sb.WriteString("\n")
+		w.Write(promptSectionBase, "\n")
 	}
 
-	// 候选币种
-	sb.WriteString(fmt.Sprintf("## 候选币种 (%d个)\n\n", len(ctx.MarketDataMap)))
-	displayedCount := 0
+	// 市场概览（市场状态、BTC/ETH 相关性与 Beta）
+	w.Write(promptSectionBase, formatMarketOverview(ctx.MarketOverview))
+
+	// 外部 Webhook 信号
+	w.Write(promptSectionExternal, formatWebhookSignals(ctx.Signals, time.Now()))
+
+	// 近期行情警报
+	w.Write(promptSectionExternal, formatMarketAlerts(ctx.MarketAlerts, time.Now()))
+
+	// 外部数据源（共享缓存，标注数据时效）
+	w.Write(promptSectionExternal, formatExternalData(ctx.ExternalData, time.Now()))
+
+	// 候选币种（按排名顺序，裁剪时从末尾移除）
+	w.Write(promptSectionCandidates, fmt.Sprintf("## 候选币种 (%d个)\n\n", len(ctx.MarketDataMap)))
+	omitted := 0
 	for _, coin := range ctx.CandidateCoins {
 		marketData, hasData := ctx.MarketDataMap[coin.Symbol]
 		if !hasData {
 			continue
 		}
-		displayedCount++
+		if opts.maxCandidates >= 0 && stats.candidates >= opts.maxCandidates {
+			omitted++
+			continue
+		}
+		stats.candidates++
+		stats.maxSeriesLen = max(stats.maxSeriesLen, seriesLen(marketData, opts.seriesLimit))
 
 		sourceTags := e.formatCoinSourceTag(coin.Sources)
-		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
-		sb.WriteString(e.formatMarketData(marketData))
+		w.Write(promptSectionCandidates, fmt.Sprintf("### %d. %s%s\n\n", stats.candidates, coin.Symbol, sourceTags))
+		w.Write(promptSectionKlines, e.formatMarketData(marketData, opts.seriesLimit))
 
 		// 添加量化数据（如果有）
-		if ctx.QuantDataMap != nil {
+		if opts.candidateExternalData && ctx.QuantDataMap != nil {
 			if quantData, hasQuant := ctx.QuantDataMap[coin.Symbol]; hasQuant {
-				sb.WriteString(e.formatQuantData(quantData))
+				stats.candidateExternalData = true
+				w.Write(promptSectionExternal, e.formatQuantData(quantData))
 			}
 		}
-		sb.WriteString("\n")
+		w.Write(promptSectionCandidates, "\n")
 	}
-	sb.WriteString("\n")
+	if omitted > 0 {
+		w.Write(promptSectionCandidates, fmt.Sprintf("（受上下文长度限制，另有 %d 个排名靠后的候选币未展示）\n", omitted))
+	}
+	w.Write(promptSectionCandidates, "\n")
 
-	sb.WriteString("---\n\n")
-	sb.WriteString("现在请分析并输出决策（思维链 + JSON）\n")
+	w.Write(promptSectionBase, "---\n\n")
+	w.Write(promptSectionBase, "现在请分析并输出决策（思维链 + JSON）\n")
 
-	return sb.String()
+	return stats
 }
 
 // formatPositionInfo 格式化持仓信息
-func (e *StrategyEngine) formatPositionInfo(index int, pos PositionInfo, ctx *Context) string {
+func (e *StrategyEngine) formatPositionInfo(index int, pos PositionInfo) string {
 	var sb strings.Builder
 
 	// 计算持仓时长
@@ -549,19 +599,6 @@ func (e *StrategyEngine) formatPositionInfo(index int, pos PositionInfo, ctx *Co
 		pos.EntryPrice, pos.MarkPrice, pos.Quantity, positionValue, pos.UnrealizedPnLPct, pos.UnrealizedPnL, pos.PeakPnLPct,
 		pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
 
-	// 使用策略配置的指标输出市场数据
-	if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
-		sb.WriteString(e.formatMarketData(marketData))
-
-		// 添加量化数据（如果有）
-		if ctx.QuantDataMap != nil {
-			if quantData, hasQuant := ctx.QuantDataMap[pos.Symbol]; hasQuant {
-				sb.WriteString(e.formatQuantData(quantData))
-			}
-		}
-		sb.WriteString("\n")
-	}
-
 	return sb.String()
 }
 
@@ -577,15 +614,20 @@ func (e *StrategyEngine) formatCoinSourceTag(sources []string) string {
 			return " (OI_Top持仓增长)"
 		case "static":
 			return " (手动选择)"
+		case "local_rank":
+			return " (本地排名)"
 		}
 	}
 	return ""
 }
 
-// formatMarketData 根据策略配置格式化市场数据
-func (e *StrategyEngine) formatMarketData(data *market.Data) string {
+// formatMarketData 根据策略配置格式化市场数据（seriesLimit > 0 时每个序列只保留最新的 seriesLimit 个数据点）
+func (e *StrategyEngine) formatMarketData(data *market.Data, seriesLimit int) string {
 	var sb strings.Builder
 	indicators := e.config.Indicators
+	series := func(values []float64) string {
+		return formatFloatSlice(tailSeries(values, seriesLimit))
+	}
 
 	// 当前价格（总是显示）
 	sb.WriteString(fmt.Sprintf("current_price = %.4f", data.CurrentPrice))
@@ -621,6 +663,17 @@ func (e *StrategyEngine) formatMarketData(data *market.Data) string {
 		}
 	}
 
+	// 持仓情绪（多空比、主动买卖量、强平）
+	sb.WriteString(market.FormatDerivatives(data.Derivatives))
+
+	// 盘口流动性
+	if indicators.EnableOrderBook && data.Liquidity != nil {
+		l := data.Liquidity
+		sb.WriteString(fmt.Sprintf("Order book: best_bid = %.4f, best_ask = %.4f, spread = %.2f bps\n", l.BestBid, l.BestAsk, l.SpreadBps))
+		sb.WriteString(fmt.Sprintf("Depth ±0.5%%: bid %.0f USDT / ask %.0f USDT (imbalance %+.2f)\n", l.BidDepth05, l.AskDepth05, l.Imbalance05))
+		sb.WriteString(fmt.Sprintf("Depth ±1%%: bid %.0f USDT / ask %.0f USDT (imbalance %+.2f)\n\n", l.BidDepth1, l.AskDepth1, l.Imbalance1))
+	}
+
 	// 优先使用多时间周期数据（新增）
 	if len(data.TimeframeData) > 0 {
 		// 按时间周期排序输出
@@ -628,7 +681,7 @@ func (e *StrategyEngine) formatMarketData(data *market.Data) string {
 		for _, tf := range timeframeOrder {
 			if tfData, ok := data.TimeframeData[tf]; ok {
 				sb.WriteString(fmt.Sprintf("=== %s Timeframe (oldest → latest) ===\n\n", strings.ToUpper(tf)))
-				e.formatTimeframeSeriesData(&sb, tfData, indicators)
+				e.formatTimeframeSeriesData(&sb, tfData, indicators, seriesLimit)
 			}
 		}
 	} else {
@@ -639,28 +692,28 @@ func (e *StrategyEngine) formatMarketData(data *market.Data) string {
 			sb.WriteString(fmt.Sprintf("Intraday series (%s intervals, oldest → latest):\n\n", klineConfig.PrimaryTimeframe))
 
 			if len(data.IntradaySeries.MidPrices) > 0 {
-				sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", formatFloatSlice(data.IntradaySeries.MidPrices)))
+				sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", series(data.IntradaySeries.MidPrices)))
 			}
 
 			if indicators.EnableEMA && len(data.IntradaySeries.EMA20Values) > 0 {
-				sb.WriteString(fmt.Sprintf("EMA indicators (20-period): %s\n\n", formatFloatSlice(data.IntradaySeries.EMA20Values)))
+				sb.WriteString(fmt.Sprintf("EMA indicators (20-period): %s\n\n", series(data.IntradaySeries.EMA20Values)))
 			}
 
 			if indicators.EnableMACD && len(data.IntradaySeries.MACDValues) > 0 {
-				sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(data.IntradaySeries.MACDValues)))
+				sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", series(data.IntradaySeries.MACDValues)))
 			}
 
 			if indicators.EnableRSI {
 				if len(data.IntradaySeries.RSI7Values) > 0 {
-					sb.WriteString(fmt.Sprintf("RSI indicators (7-Period): %s\n\n", formatFloatSlice(data.IntradaySeries.RSI7Values)))
+					sb.WriteString(fmt.Sprintf("RSI indicators (7-Period): %s\n\n", series(data.IntradaySeries.RSI7Values)))
 				}
 				if len(data.IntradaySeries.RSI14Values) > 0 {
-					sb.WriteString(fmt.Sprintf("RSI indicators (14-Period): %s\n\n", formatFloatSlice(data.IntradaySeries.RSI14Values)))
+					sb.WriteString(fmt.Sprintf("RSI indicators (14-Period): %s\n\n", series(data.IntradaySeries.RSI14Values)))
 				}
 			}
 
 			if indicators.EnableVolume && len(data.IntradaySeries.Volume) > 0 {
-				sb.WriteString(fmt.Sprintf("Volume: %s\n\n", formatFloatSlice(data.IntradaySeries.Volume)))
+				sb.WriteString(fmt.Sprintf("Volume: %s\n\n", series(data.IntradaySeries.Volume)))
 			}
 
 			if indicators.EnableATR {
@@ -688,11 +741,11 @@ func (e *StrategyEngine) formatMarketData(data *market.Data) string {
 			}
 
 			if indicators.EnableMACD && len(data.LongerTermContext.MACDValues) > 0 {
-				sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(data.LongerTermContext.MACDValues)))
+				sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", series(data.LongerTermContext.MACDValues)))
 			}
 
 			if indicators.EnableRSI && len(data.LongerTermContext.RSI14Values) > 0 {
-				sb.WriteString(fmt.Sprintf("RSI indicators (14-Period): %s\n\n", formatFloatSlice(data.LongerTermContext.RSI14Values)))
+				sb.WriteString(fmt.Sprintf("RSI indicators (14-Period): %s\n\n", series(data.LongerTermContext.RSI14Values)))
 			}
 		}
 	}
@@ -701,40 +754,80 @@ func (e *StrategyEngine) formatMarketData(data *market.Data) string {
 }
 
 // formatTimeframeSeriesData 格式化单个时间周期的序列数据
-func (e *StrategyEngine) formatTimeframeSeriesData(sb *strings.Builder, data *market.TimeframeSeriesData, indicators store.IndicatorConfig) {
+func (e *StrategyEngine) formatTimeframeSeriesData(sb *strings.Builder, data *market.TimeframeSeriesData, indicators store.IndicatorConfig, seriesLimit int) {
+	series := func(values []float64) string {
+		return formatFloatSlice(tailSeries(values, seriesLimit))
+	}
 	if len(data.MidPrices) > 0 {
-		sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", formatFloatSlice(data.MidPrices)))
+		sb.WriteString(fmt.Sprintf("Mid prices: %s\n\n", series(data.MidPrices)))
 	}
 
-	if indicators.EnableEMA {
-		if len(data.EMA20Values) > 0 {
-			sb.WriteString(fmt.Sprintf("EMA indicators (20-period): %s\n\n", formatFloatSlice(data.EMA20Values)))
+	// 命名序列中有配置周期的数据时按配置周期输出，否则使用默认周期字段
+	periodSeries := func(key func(int) string, periods []int) map[int][]float64 {
+		values := make(map[int][]float64)
+		for _, p := range periods {
+			if v := data.Series[key(p)]; len(v) > 0 {
+				values[p] = v
+			}
 		}
-		if len(data.EMA50Values) > 0 {
-			sb.WriteString(fmt.Sprintf("EMA indicators (50-period): %s\n\n", formatFloatSlice(data.EMA50Values)))
+		return values
+	}
+
+	if indicators.EnableEMA {
+		if emas := periodSeries(market.EMAKey, indicators.EMAPeriods); len(emas) > 0 {
+			for _, p := range indicators.EMAPeriods {
+				if v, ok := emas[p]; ok {
+					sb.WriteString(fmt.Sprintf("EMA indicators (%d-period): %s\n\n", p, series(v)))
+				}
+			}
+		} else {
+			if len(data.EMA20Values) > 0 {
+				sb.WriteString(fmt.Sprintf("EMA indicators (20-period): %s\n\n", series(data.EMA20Values)))
+			}
+			if len(data.EMA50Values) > 0 {
+				sb.WriteString(fmt.Sprintf("EMA indicators (50-period): %s\n\n", series(data.EMA50Values)))
+			}
 		}
 	}
 
 	if indicators.EnableMACD && len(data.MACDValues) > 0 {
-		sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", formatFloatSlice(data.MACDValues)))
+		sb.WriteString(fmt.Sprintf("MACD indicators: %s\n\n", series(data.MACDValues)))
 	}
 
 	if indicators.EnableRSI {
-		if len(data.RSI7Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (7-Period): %s\n\n", formatFloatSlice(data.RSI7Values)))
-		}
-		if len(data.RSI14Values) > 0 {
-			sb.WriteString(fmt.Sprintf("RSI indicators (14-Period): %s\n\n", formatFloatSlice(data.RSI14Values)))
+		if rsis := periodSeries(market.RSIKey, indicators.RSIPeriods); len(rsis) > 0 {
+			for _, p := range indicators.RSIPeriods {
+				if v, ok := rsis[p]; ok {
+					sb.WriteString(fmt.Sprintf("RSI indicators (%d-Period): %s\n\n", p, series(v)))
+				}
+			}
+		} else {
+			if len(data.RSI7Values) > 0 {
+				sb.WriteString(fmt.Sprintf("RSI indicators (7-Period): %s\n\n", series(data.RSI7Values)))
+			}
+			if len(data.RSI14Values) > 0 {
+				sb.WriteString(fmt.Sprintf("RSI indicators (14-Period): %s\n\n", series(data.RSI14Values)))
+			}
 		}
 	}
 
 	if indicators.EnableVolume && len(data.Volume) > 0 {
-		sb.WriteString(fmt.Sprintf("Volume: %s\n\n", formatFloatSlice(data.Volume)))
+		sb.WriteString(fmt.Sprintf("Volume: %s\n\n", series(data.Volume)))
 	}
 
 	if indicators.EnableATR {
-		sb.WriteString(fmt.Sprintf("ATR (14-period): %.3f\n\n", data.ATR14))
+		if atrs := periodSeries(market.ATRKey, indicators.ATRPeriods); len(atrs) > 0 {
+			for _, p := range indicators.ATRPeriods {
+				if v, ok := atrs[p]; ok {
+					sb.WriteString(fmt.Sprintf("ATR (%d-period): %.3f\n\n", p, v[len(v)-1]))
+				}
+			}
+		} else {
+			sb.WriteString(fmt.Sprintf("ATR (14-period): %.3f\n\n", data.ATR14))
+		}
 	}
+
+	formatNamedSeries(sb, data, indicators, series)
 }
 
 // formatFloatSlice 格式化浮点数切片
@@ -746,21 +839,46 @@ func formatFloatSlice(values []float64) string {
 	return "[" + strings.Join(strValues, ", ") + "]"
 }
 
-// BuildSystemPrompt 根据策略配置构建 System Prompt
+// BuildSystemPrompt 根据策略配置构建 System Prompt（无交易上下文时，模板仅能获取净值和风控配置）
 func (e *StrategyEngine) BuildSystemPrompt(accountEquity float64, variant string) string {
+	data := NewPromptData(nil, e.config.RiskControl, variant)
+	data.Account.TotalEquity = accountEquity
+	return e.buildSystemPrompt(accountEquity, variant, data)
+}
+
+// BuildSystemPromptWithContext 根据策略配置和交易上下文构建 System Prompt
+// 可编辑部分、基础模板和自定义 Prompt 均按 text/template 渲染（见 PromptData）
+func (e *StrategyEngine) BuildSystemPromptWithContext(ctx *Context, variant string) string {
+	data := NewPromptData(ctx, e.config.RiskControl, variant)
+	return e.buildSystemPrompt(ctx.Account.TotalEquity, variant, data)
+}
+
+func (e *StrategyEngine) buildSystemPrompt(accountEquity float64, variant string, data *PromptData) string {
 	var sb strings.Builder
 	riskControl := e.config.RiskControl
 	promptSections := e.config.PromptSections
+	promptSections.RoleDefinition = renderPromptTextOrRaw("role_definition", promptSections.RoleDefinition, data)
+	promptSections.TradingFrequency = renderPromptTextOrRaw("trading_frequency", promptSections.TradingFrequency, data)
+	promptSections.EntryStandards = renderPromptTextOrRaw("entry_standards", promptSections.EntryStandards, data)
+	promptSections.DecisionProcess = renderPromptTextOrRaw("decision_process", promptSections.DecisionProcess, data)
+	baseTemplate := renderPromptTextOrRaw("base_template", e.baseTemplate, data)
+	customPrompt := renderPromptTextOrRaw("custom_prompt", e.config.CustomPrompt, data)
 
 	// 1. 角色定义（可编辑）
 	if promptSections.RoleDefinition != "" {
 		sb.WriteString(promptSections.RoleDefinition)
 		sb.WriteString("\n\n")
-	} else {
+	} else if baseTemplate == "" {
 		sb.WriteString("# 你是专业的加密货币交易AI\n\n")
 		sb.WriteString("你的任务是根据提供的市场数据做出交易决策。\n\n")
 	}
 
+	// 1.1 基础提示词模板（核心交易策略）
+	if baseTemplate != "" {
+		sb.WriteString(baseTemplate)
+		sb.WriteString("\n\n")
+	}
+
 	// 2. 交易模式变体
 	switch strings.ToLower(strings.TrimSpace(variant)) {
 	case "aggressive":
@@ -842,9 +960,9 @@ func (e *StrategyEngine) BuildSystemPrompt(accountEquity float64, variant string
 	sb.WriteString("- 开仓时必填: leverage, position_size_usd, stop_loss, take_profit, confidence, risk_usd\n\n")
 
 	// 8. 自定义 Prompt
-	if e.config.CustomPrompt != "" {
+	if customPrompt != "" {
 		sb.WriteString("# 📌 个性化交易策略\n\n")
-		sb.WriteString(e.config.CustomPrompt)
+		sb.WriteString(customPrompt)
 		sb.WriteString("\n\n")
 		sb.WriteString("注意: 以上个性化策略是对基础规则的补充，不能违背基础风险控制原则。\n")
 	}
@@ -904,7 +1022,33 @@ func (e *StrategyEngine) writeAvailableIndicators(sb *strings.Builder) {
 		sb.WriteString("- 资金费率\n")
 	}
 
-	if len(e.config.CoinSource.StaticCoins) > 0 || e.config.CoinSource.UseCoinPool || e.config.CoinSource.UseOITop {
+	extended := []struct {
+		enabled bool
+		label   string
+	}{
+		{indicators.EnableBollinger, "布林带指标"},
+		{indicators.EnableVWAP, "VWAP 指标"},
+		{indicators.EnableStochRSI, "StochRSI 指标"},
+		{indicators.EnableADX, "ADX/DMI 指标"},
+		{indicators.EnableOBV, "OBV 指标"},
+		{indicators.EnableSupertrend, "Supertrend 指标"},
+		{indicators.EnableIchimoku, "一目均衡表指标"},
+		{indicators.EnableDonchian, "唐奇安通道指标"},
+		{indicators.EnableOrderBook, "订单簿数据（价差、±0.5%/±1% 深度、买卖盘失衡）"},
+		{indicators.EnableLongShortRatio, "多空比（大户账户/持仓、全市场账户）"},
+		{indicators.EnableTakerFlow, "主动买卖量（买卖比、净主动买入量）"},
+		{indicators.EnableLiquidations, "强平统计（多/空强平笔数与金额）"},
+	}
+	for _, ind := range extended {
+		if ind.enabled {
+			sb.WriteString(fmt.Sprintf("- %s\n", ind.label))
+		}
+	}
+
+	coinSource := e.config.CoinSource
+	if coinSource.SourceType == "local_rank" || coinSource.UseLocalRank {
+		sb.WriteString("- AI500 / OI_Top / 本地排名 筛选标签（若有）\n")
+	} else if len(coinSource.StaticCoins) > 0 || coinSource.UseCoinPool || coinSource.UseOITop {
 		sb.WriteString("- AI500 / OI_Top 筛选标签（若有）\n")
 	}
 
@@ -922,3 +1066,31 @@ func (e *StrategyEngine) GetRiskControlConfig() store.RiskControlConfig {
 func (e *StrategyEngine) GetConfig() *store.StrategyConfig {
 	return e.config
 }
+
+// WithPromptSections 返回使用覆盖后 Prompt 可编辑部分的引擎副本（用于 A/B 实验）
+// overrides 中为空的字段沿用原策略配置，原引擎不受影响
+func (e *StrategyEngine) WithPromptSections(overrides store.PromptSectionsConfig) *StrategyEngine {
+	cfg := *e.config
+	if overrides.RoleDefinition != "" {
+		cfg.PromptSections.RoleDefinition = overrides.RoleDefinition
+	}
+	if overrides.TradingFrequency != "" {
+		cfg.PromptSections.TradingFrequency = overrides.TradingFrequency
+	}
+	if overrides.EntryStandards != "" {
+		cfg.PromptSections.EntryStandards = overrides.EntryStandards
+	}
+	if overrides.DecisionProcess != "" {
+		cfg.PromptSections.DecisionProcess = overrides.DecisionProcess
+	}
+	cp := *e
+	cp.config = &cfg
+	return &cp
+}
+
+// WithBaseTemplate 返回使用指定基础提示词模板内容的引擎副本
+func (e *StrategyEngine) WithBaseTemplate(content string) *StrategyEngine {
+	cp := *e
+	cp.baseTemplate = content
+	return &cp
+}
@@ -0,0 +1,255 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/market"
+	"nofx/store"
+)
+
+// TestWithPromptSections_OverridesOnlyNonEmptyFields 测试实验变体只覆盖非空的 Prompt 部分
+func TestWithPromptSections_OverridesOnlyNonEmptyFields(t *testing.T) {
+	base := &store.StrategyConfig{
+		PromptSections: store.PromptSectionsConfig{
+			RoleDefinition:  "# 原始角色",
+			EntryStandards:  "# 原始开仓标准",
+			DecisionProcess: "# 原始决策流程",
+		},
+	}
+	engine := NewStrategyEngine(base)
+
+	variant := engine.WithPromptSections(store.PromptSectionsConfig{
+		EntryStandards: "# 变体开仓标准",
+	})
+
+	prompt := variant.BuildSystemPrompt(1000, "balanced")
+	if !strings.Contains(prompt, "# 变体开仓标准") {
+		t.Errorf("变体 prompt 应包含覆盖后的开仓标准")
+	}
+	if strings.Contains(prompt, "# 原始开仓标准") {
+		t.Errorf("变体 prompt 不应包含原始开仓标准")
+	}
+	if !strings.Contains(prompt, "# 原始角色") || !strings.Contains(prompt, "# 原始决策流程") {
+		t.Errorf("变体 prompt 应沿用未覆盖的部分")
+	}
+
+	// 原引擎不受影响
+	if base.PromptSections.EntryStandards != "# 原始开仓标准" {
+		t.Errorf("原策略配置被修改: %q", base.PromptSections.EntryStandards)
+	}
+}
+
+// TestEngineCopies_KeepMarketProvider 测试应用实验变体或模板后的引擎副本沿用行情数据源
+func TestEngineCopies_KeepMarketProvider(t *testing.T) {
+	engine := NewStrategyEngine(&store.StrategyConfig{})
+	engine.SetMarketProvider(market.NewBybitProvider())
+
+	copies := map[string]*StrategyEngine{
+		"WithPromptSections": engine.WithPromptSections(store.PromptSectionsConfig{RoleDefinition: "# 变体"}),
+		"WithBaseTemplate":   engine.WithBaseTemplate("模板内容"),
+	}
+	for name, cp := range copies {
+		if got := cp.MarketProvider().Name(); got != market.ProviderBybit {
+			t.Errorf("%s: provider = %s, want %s", name, got, market.ProviderBybit)
+		}
+	}
+	if engine.baseTemplate != "" || engine.config.PromptSections.RoleDefinition != "" {
+		t.Error("原引擎不应被修改")
+	}
+}
+
+// TestFormatTimeframeSeriesData_ConfiguredIndicators 测试按配置周期和扩展指标格式化周期序列
+func TestFormatTimeframeSeriesData_ConfiguredIndicators(t *testing.T) {
+	indicators := store.IndicatorConfig{
+		EnableEMA:       true,
+		EnableATR:       true,
+		EnableADX:       true,
+		EnableBollinger: true,
+		EMAPeriods:      []int{9, 21},
+		ATRPeriods:      []int{7},
+	}
+	data := &market.TimeframeSeriesData{
+		Timeframe:   "1h",
+		EMA20Values: []float64{1, 2},
+		ATR14:       3,
+		Series: map[string][]float64{
+			"ema9":  {10, 11},
+			"ema21": {12, 13},
+			"atr7":  {0.5, 0.75},
+			"adx":   {25, 30},
+		},
+	}
+
+	var sb strings.Builder
+	NewStrategyEngine(&store.StrategyConfig{Indicators: indicators}).formatTimeframeSeriesData(&sb, data, indicators, 0)
+	out := sb.String()
+
+	for _, want := range []string{"EMA indicators (9-period)", "EMA indicators (21-period)", "ATR (7-period): 0.750", "ADX (14-period)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"20-period", "ATR (14-period)", "Bollinger"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output should not contain %q:\n%s", unwanted, out)
+		}
+	}
+}
+
+// TestFormatWebhookSignals 测试外部信号在 Prompt 中的格式
+func TestFormatWebhookSignals(t *testing.T) {
+	if formatWebhookSignals(nil, time.Now()) != "" {
+		t.Error("no signals should produce empty section")
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	out := formatWebhookSignals([]*store.WebhookSignal{{
+		Symbol:     "BTCUSDT",
+		Action:     store.SignalActionLong,
+		Price:      65000,
+		Timeframe:  "15",
+		Source:     "tv-breakout",
+		Message:    "range breakout",
+		ReceivedAt: now.Add(-5 * time.Minute),
+		ExpiresAt:  now.Add(55 * time.Minute),
+	}}, now)
+
+	for _, want := range []string{"## 外部信号（Webhook）", "BTCUSDT long @ 65000.0000", "来源: tv-breakout", "5分钟前收到", "剩余有效 55分钟", "range breakout"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+// TestFormatMarketAlerts 测试行情警报在 Prompt 中的格式
+func TestFormatMarketAlerts(t *testing.T) {
+	if formatMarketAlerts(nil, time.Now()) != "" {
+		t.Error("no alerts should produce empty section")
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	out := formatMarketAlerts([]*store.MarketAlert{{
+		Type:      "volume_spike",
+		Symbol:    "SOLUSDT",
+		Message:   "SOLUSDT 成交量放大至近20根均量的 4.0 倍",
+		CreatedAt: now.Add(-12 * time.Minute),
+	}}, now)
+
+	for _, want := range []string{"## 近期行情警报", "1. SOLUSDT 成交量放大至近20根均量的 4.0 倍 | 12分钟前"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+// TestMarketOverview 测试从K线源计算市场概览、记录市场状态并输出到 Prompt
+func TestMarketOverview(t *testing.T) {
+	// 基准币和候选币同步上涨，候选币波动为 BTC 的 2 倍
+	series := func(k float64) []market.Kline {
+		klines := make([]market.Kline, 100)
+		price := 100.0
+		for i := range klines {
+			r := 0.004 * float64(1+i%3)
+			if i%2 == 1 {
+				r = -r * 0.5
+			}
+			price *= 1 + r*k
+			klines[i] = market.Kline{OpenTime: int64(i) * 60000, Close: price}
+		}
+		return klines
+	}
+	source := func(symbol, timeframe string) ([]market.Kline, error) {
+		switch symbol {
+		case market.BenchmarkBTC:
+			return series(1), nil
+		case market.BenchmarkETH:
+			return series(1.5), nil
+		case "SOLUSDT":
+			return series(2), nil
+		}
+		return nil, fmt.Errorf("unknown symbol %s", symbol)
+	}
+
+	failing := func(symbol, timeframe string) ([]market.Kline, error) { return nil, fmt.Errorf("offline") }
+	if LoadMarketOverview(failing, "1m", []string{"SOLUSDT"}) != nil {
+		t.Error("BTC K线不可用时不应计算市场概览")
+	}
+
+	ctx := &Context{
+		Klines:        source,
+		MarketDataMap: map[string]*market.Data{"SOLUSDT": {Symbol: "SOLUSDT"}, "DOGEUSDT": {Symbol: "DOGEUSDT"}},
+	}
+	if ctx.MarketRegime() != "" {
+		t.Error("未计算市场概览时市场状态应为空")
+	}
+	ctx.loadMarketOverview("1m")
+	if ctx.MarketOverview == nil || ctx.MarketRegime() != market.RegimeTrendingUp {
+		t.Fatalf("overview: %+v", ctx.MarketOverview)
+	}
+	if len(ctx.MarketOverview.Assets) != 1 {
+		t.Fatalf("K线不可用的币种应被跳过: %+v", ctx.MarketOverview.Assets)
+	}
+
+	out := formatMarketOverview(ctx.MarketOverview)
+	for _, want := range []string{"## 市场概览（1m K线，近20根）", "市场状态: 普涨趋势", "上涨币种占比 100%", "- SOLUSDT: BTC 相关 1.00 β 2.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if formatMarketOverview(nil) != "" {
+		t.Error("nil overview should produce empty section")
+	}
+
+	// 未收盘的当前K线不参与计算
+	forming := func(symbol, timeframe string) ([]market.Kline, error) {
+		klines, err := source(symbol, timeframe)
+		if err != nil {
+			return nil, err
+		}
+		last := klines[len(klines)-1]
+		return append(klines, market.Kline{
+			OpenTime:  last.OpenTime + 60000,
+			Close:     last.Close * 0.5,
+			CloseTime: time.Now().Add(time.Minute).UnixMilli(),
+		}), nil
+	}
+	if o := LoadMarketOverview(forming, "1m", []string{"SOLUSDT"}); o == nil || o.BTCChange != ctx.MarketOverview.BTCChange || o.Regime != market.RegimeTrendingUp {
+		t.Errorf("unclosed kline should be ignored: %+v", o)
+	}
+}
+
+// TestMarketIndicatorSettings_Derivatives 测试持仓情绪开关映射到行情获取配置并出现在市场数据中
+func TestMarketIndicatorSettings_Derivatives(t *testing.T) {
+	if MarketIndicatorSettings(store.IndicatorConfig{}).Derivatives != nil {
+		t.Error("derivatives should be nil when disabled")
+	}
+
+	indicators := store.IndicatorConfig{
+		EnableTakerFlow:    true,
+		EnableLiquidations: true,
+		Derivatives:        market.DerivativesParams{Period: "1h", Lookback: 6},
+	}
+	opts := MarketIndicatorSettings(indicators).Derivatives
+	if opts == nil || opts.LongShortRatio || !opts.TakerFlow || !opts.Liquidations || opts.Period != "1h" || opts.Lookback != 6 {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	if err := ValidateIndicatorConfig(store.IndicatorConfig{EnableTakerFlow: true, Derivatives: market.DerivativesParams{Period: "3m"}}); err == nil {
+		t.Error("unsupported period should be rejected")
+	}
+
+	data := &market.Data{
+		Symbol:       "BTCUSDT",
+		CurrentPrice: 100,
+		Derivatives: &market.DerivativesData{
+			Period: "1h", Lookback: 6,
+			TakerFlow: &market.TakerFlow{BuySellRatio: 1.25, BuyVolume: 125, SellVolume: 100, NetVolume: 25, Series: []float64{1.25}},
+		},
+	}
+	out := NewStrategyEngine(&store.StrategyConfig{Indicators: indicators}).formatMarketData(data, 0)
+	if !strings.Contains(out, "Taker buy/sell: ratio 1.250") {
+		t.Errorf("market data missing taker flow:\n%s", out)
+	}
+}
@@ -0,0 +1,198 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/logger"
+	"nofx/market"
+	"strings"
+	"time"
+)
+
+// SymbolUniverse 可交易币种范围：允许/禁止列表、行情交易所的交易对状态和执行交易所的市场列表
+// 零值可用，未设置的项不做对应检查
+type SymbolUniverse struct {
+	allow     []allowList
+	deny      map[string]string
+	statuses  map[string]market.SymbolStatus
+	venue     map[string]bool
+	venueName string
+}
+
+type allowList struct {
+	scope   string
+	symbols map[string]bool
+}
+
+// DelistingWarning 持仓币种的下架预警（提示 AI 尽快平仓）
+type DelistingWarning struct {
+	Symbol   string    `json:"symbol"`
+	Side     string    `json:"side"`
+	Reason   string    `json:"reason"`
+	DelistAt time.Time `json:"delist_at,omitempty"` // 计划下架时间（已停止交易或交易所已移除时为零值）
+}
+
+// SymbolUniverse 由策略的允许/禁止币种列表创建可交易币种范围（交易员可继续添加用户列表和交易所状态）
+func (e *StrategyEngine) SymbolUniverse() *SymbolUniverse {
+	u := &SymbolUniverse{}
+	if e == nil || e.config == nil {
+		return u
+	}
+	u.Allow("策略", e.config.CoinSource.AllowSymbols)
+	for _, symbol := range e.config.CoinSource.DenySymbols {
+		u.Deny(symbol, "策略配置")
+	}
+	return u
+}
+
+// Allow 添加允许列表（scope 如 "策略"、"用户"，用于排除原因）；多个列表同时生效，空列表忽略
+func (u *SymbolUniverse) Allow(scope string, symbols []string) {
+	if len(symbols) == 0 {
+		return
+	}
+	list := allowList{scope: scope, symbols: make(map[string]bool, len(symbols))}
+	for _, symbol := range symbols {
+		list.symbols[market.Normalize(symbol)] = true
+	}
+	u.allow = append(u.allow, list)
+}
+
+// Deny 添加禁止交易的币种
+func (u *SymbolUniverse) Deny(symbol, reason string) {
+	if u.deny == nil {
+		u.deny = make(map[string]string)
+	}
+	u.deny[market.Normalize(symbol)] = reason
+}
+
+// SetStatuses 设置行情交易所的交易对状态（用于排除暂停交易和计划下架的币种）
+func (u *SymbolUniverse) SetStatuses(statuses map[string]market.SymbolStatus) {
+	u.statuses = statuses
+}
+
+// SetVenue 设置执行交易所可交易的币种（不在其中的币种视为未上线或已下架）
+func (u *SymbolUniverse) SetVenue(name string, symbols map[string]bool) {
+	u.venueName = name
+	u.venue = symbols
+}
+
+// Check 返回币种不可开仓的原因，可交易时返回空字符串
+func (u *SymbolUniverse) Check(symbol string) string {
+	if u == nil {
+		return ""
+	}
+	symbol = market.Normalize(symbol)
+	if reason, denied := u.deny[symbol]; denied {
+		if reason == "" {
+			return "在禁止交易列表中"
+		}
+		return "在禁止交易列表中: " + reason
+	}
+	for _, list := range u.allow {
+		if !list.symbols[symbol] {
+			return fmt.Sprintf("不在%s允许交易列表中", list.scope)
+		}
+	}
+	return u.delistReason(symbol)
+}
+
+// delistReason 返回因交易所状态或下架导致不可交易的原因（禁止/允许列表不计入）
+func (u *SymbolUniverse) delistReason(symbol string) string {
+	if status, ok := u.statuses[symbol]; ok {
+		if !status.Tradable() {
+			return fmt.Sprintf("交易所状态为 %s", status.Status)
+		}
+		if status.Delisting() {
+			return fmt.Sprintf("计划于 %s 下架", status.DelistAt.UTC().Format("2006-01-02 15:04 UTC"))
+		}
+	}
+	if u.venue != nil && !u.venue[symbol] {
+		return fmt.Sprintf("%s 市场列表中没有该币种", u.venueName)
+	}
+	return ""
+}
+
+// FilterCandidates 排除不在可交易范围内的候选币
+func (u *SymbolUniverse) FilterCandidates(candidates []CandidateCoin) []CandidateCoin {
+	if u == nil {
+		return candidates
+	}
+	kept := make([]CandidateCoin, 0, len(candidates))
+	for _, coin := range candidates {
+		if reason := u.Check(coin.Symbol); reason != "" {
+			logger.Infof("🚫 %s 不在可交易范围内: %s", coin.Symbol, reason)
+			continue
+		}
+		kept = append(kept, coin)
+	}
+	if len(kept) < len(candidates) {
+		logger.Infof("📋 币种范围过滤: %d → %d", len(candidates), len(kept))
+	}
+	return kept
+}
+
+// DelistingWarnings 找出已停止交易、计划下架或执行交易所已移除的持仓币种
+func (u *SymbolUniverse) DelistingWarnings(positions []PositionInfo) []DelistingWarning {
+	if u == nil {
+		return nil
+	}
+	var warnings []DelistingWarning
+	for _, pos := range positions {
+		symbol := market.Normalize(pos.Symbol)
+		reason := u.delistReason(symbol)
+		if reason == "" {
+			continue
+		}
+		warnings = append(warnings, DelistingWarning{
+			Symbol:   pos.Symbol,
+			Side:     pos.Side,
+			Reason:   reason,
+			DelistAt: u.statuses[symbol].DelistAt,
+		})
+	}
+	return warnings
+}
+
+// vetoOpens 否决不在可交易范围内的开仓决策（平仓等其他动作不受影响）
+func (u *SymbolUniverse) vetoOpens(decisions []Decision) ([]Decision, []VetoedDecision) {
+	if u == nil {
+		return decisions, nil
+	}
+	kept := make([]Decision, 0, len(decisions))
+	var vetoed []VetoedDecision
+	for _, d := range decisions {
+		if d.Action == "open_long" || d.Action == "open_short" {
+			if reason := u.Check(d.Symbol); reason != "" {
+				vetoed = append(vetoed, VetoedDecision{
+					Decision: d,
+					Rule:     "symbol_universe",
+					Reason:   fmt.Sprintf("%s %s 被否决: %s", d.Symbol, d.Action, reason),
+				})
+				continue
+			}
+		}
+		kept = append(kept, d)
+	}
+	return kept, vetoed
+}
+
+// formatDelistingWarnings 格式化持仓下架预警
+func formatDelistingWarnings(warnings []DelistingWarning, now time.Time) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## ⚠️ 下架预警\n")
+	sb.WriteString("以下持仓币种已停止交易、即将下架或执行交易所已移除，请优先平仓，不要加仓或反向开仓：\n")
+	for i, w := range warnings {
+		line := fmt.Sprintf("%d. %s %s | %s", i+1, w.Symbol, strings.ToUpper(w.Side), w.Reason)
+		if !w.DelistAt.IsZero() {
+			if remaining := w.DelistAt.Sub(now); remaining > 0 {
+				line += fmt.Sprintf("（剩余%s）", formatPromptDuration(remaining))
+			}
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
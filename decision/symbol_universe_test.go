@@ -0,0 +1,93 @@
+package decision
+
+import (
+	"nofx/market"
+	"nofx/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSymbolUniverse 测试允许/禁止列表、交易所状态和执行交易所市场列表的组合过滤
+func TestSymbolUniverse(t *testing.T) {
+	engine := NewStrategyEngine(&store.StrategyConfig{CoinSource: store.CoinSourceConfig{
+		AllowSymbols: []string{"btc", "ETHUSDT", "SOLUSDT", "OLDUSDT", "HALTUSDT"},
+		DenySymbols:  []string{"SOL"},
+	}})
+	delistAt := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+
+	u := engine.SymbolUniverse()
+	u.Allow("用户", []string{"BTCUSDT", "ETHUSDT", "OLDUSDT", "HALTUSDT", "DOGEUSDT"})
+	u.SetStatuses(map[string]market.SymbolStatus{
+		"BTCUSDT":  {Symbol: "BTCUSDT", Status: market.SymbolStatusTrading},
+		"OLDUSDT":  {Symbol: "OLDUSDT", Status: market.SymbolStatusTrading, DelistAt: delistAt},
+		"HALTUSDT": {Symbol: "HALTUSDT", Status: "SETTLING"},
+	})
+
+	cases := map[string]string{
+		"BTCUSDT":  "",
+		"ETHUSDT":  "", // 无状态数据时不排除
+		"SOLUSDT":  "禁止交易列表",
+		"DOGEUSDT": "不在策略允许交易列表",
+		"OLDUSDT":  "计划于 2026-01-02 08:00 UTC 下架",
+		"HALTUSDT": "SETTLING",
+	}
+	for symbol, want := range cases {
+		got := u.Check(symbol)
+		if (want == "") != (got == "") || !strings.Contains(got, want) {
+			t.Errorf("Check(%s) = %q, want %q", symbol, got, want)
+		}
+	}
+
+	// 执行交易所市场列表
+	u.SetVenue("lighter", map[string]bool{"BTCUSDT": true, "SOLUSDT": true, "OLDUSDT": true})
+	if reason := u.Check("ETHUSDT"); !strings.Contains(reason, "lighter 市场列表中没有该币种") {
+		t.Errorf("venue: %q", reason)
+	}
+
+	kept := u.FilterCandidates([]CandidateCoin{{Symbol: "BTCUSDT"}, {Symbol: "ETHUSDT"}, {Symbol: "SOLUSDT"}})
+	if len(kept) != 1 || kept[0].Symbol != "BTCUSDT" {
+		t.Errorf("candidates: %+v", kept)
+	}
+
+	// 下架预警只针对交易所原因，禁止列表中的持仓不预警
+	warnings := u.DelistingWarnings([]PositionInfo{
+		{Symbol: "BTCUSDT", Side: "long"},
+		{Symbol: "OLDUSDT", Side: "short"},
+		{Symbol: "ETHUSDT", Side: "long"},
+		{Symbol: "SOLUSDT", Side: "long"},
+	})
+	if len(warnings) != 2 || warnings[0].Symbol != "OLDUSDT" || !warnings[0].DelistAt.Equal(delistAt) || warnings[1].Symbol != "ETHUSDT" {
+		t.Fatalf("warnings: %+v", warnings)
+	}
+	prompt := formatDelistingWarnings(warnings, delistAt.Add(-2*time.Hour))
+	if !strings.Contains(prompt, "OLDUSDT SHORT") || !strings.Contains(prompt, "剩余2.0小时") {
+		t.Errorf("prompt:\n%s", prompt)
+	}
+}
+
+// TestApplyVetoesSymbolUniverse 测试范围外币种的开仓决策被否决，平仓不受影响
+func TestApplyVetoesSymbolUniverse(t *testing.T) {
+	engine := NewStrategyEngine(&store.StrategyConfig{})
+	universe := &SymbolUniverse{}
+	universe.Deny("PEPEUSDT", "风险过高")
+	ctx := &Context{Universe: universe}
+
+	kept, vetoed, err := engine.ApplyVetoes(ctx, []Decision{
+		{Symbol: "PEPEUSDT", Action: "open_long"},
+		{Symbol: "PEPEUSDT", Action: "close_short"},
+		{Symbol: "BTCUSDT", Action: "open_short"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || len(vetoed) != 1 || vetoed[0].Rule != "symbol_universe" ||
+		!strings.Contains(vetoed[0].Reason, "风险过高") {
+		t.Errorf("kept=%+v vetoed=%+v", kept, vetoed)
+	}
+
+	// 未设置范围时不否决
+	if kept, _, _ := engine.ApplyVetoes(&Context{}, []Decision{{Symbol: "PEPEUSDT", Action: "open_long"}}); len(kept) != 1 {
+		t.Errorf("kept=%+v", kept)
+	}
+}
@@ -0,0 +1,249 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/store"
+	"strings"
+)
+
+// User Prompt 各部分名称（用于 token 统计和预算）
+const (
+	promptSectionBase       = "base"       // 系统状态、账户、交易统计等固定部分
+	promptSectionPositions  = "positions"  // 持仓信息
+	promptSectionCandidates = "candidates" // 候选币标题与来源
+	promptSectionKlines     = "klines"     // 指标与K线序列
+	promptSectionExternal   = "external"   // 外部（量化）数据
+)
+
+const (
+	// defaultReserveOutputTokens 默认为AI输出（思维链 + JSON）预留的 token 数
+	defaultReserveOutputTokens = 4000
+	// minSeriesLimit 缩短K线序列时保留的最少数据点
+	minSeriesLimit = 10
+)
+
+// TokenizerProfile 模型的分词近似参数
+// 无法在本地运行各家分词器，使用"字符数/token"近似估算，中文等非 ASCII 字符单独计算
+type TokenizerProfile struct {
+	Name             string  `json:"name"`
+	ContextWindow    int     `json:"context_window"`      // 上下文窗口（token）
+	CharsPerToken    float64 `json:"chars_per_token"`     // 每个 token 对应的 ASCII 字符数
+	CJKCharsPerToken float64 `json:"cjk_chars_per_token"` // 每个 token 对应的非 ASCII 字符数
+}
+
+// tokenizerProfiles 内置模型分词近似参数（按模型名称关键字匹配）
+var tokenizerProfiles = []TokenizerProfile{
+	{Name: "deepseek", ContextWindow: 64000, CharsPerToken: 3.3, CJKCharsPerToken: 1.5},
+	{Name: "qwen", ContextWindow: 32000, CharsPerToken: 3.3, CJKCharsPerToken: 1.4},
+	{Name: "gpt", ContextWindow: 128000, CharsPerToken: 4.0, CJKCharsPerToken: 1.1},
+	{Name: "claude", ContextWindow: 200000, CharsPerToken: 3.5, CJKCharsPerToken: 1.0},
+	{Name: "anthropic", ContextWindow: 200000, CharsPerToken: 3.5, CJKCharsPerToken: 1.0}, // 提供商名（未指定模型名时）
+	{Name: "gemini", ContextWindow: 1000000, CharsPerToken: 4.0, CJKCharsPerToken: 1.3},
+}
+
+// defaultTokenizerProfile 未匹配到模型时使用的保守参数
+var defaultTokenizerProfile = TokenizerProfile{Name: "default", ContextWindow: 32000, CharsPerToken: 3.0, CJKCharsPerToken: 1.0}
+
+// ResolveTokenizerProfile 根据模型名称和策略配置确定分词近似参数（配置中的非零值覆盖默认值）
+func ResolveTokenizerProfile(model string, cfg store.TokenBudgetConfig) TokenizerProfile {
+	if cfg.Model != "" {
+		model = cfg.Model
+	}
+	profile := defaultTokenizerProfile
+	lower := strings.ToLower(model)
+	for _, p := range tokenizerProfiles {
+		if strings.Contains(lower, p.Name) {
+			profile = p
+			break
+		}
+	}
+	if cfg.ContextWindow > 0 {
+		profile.ContextWindow = cfg.ContextWindow
+	}
+	if cfg.CharsPerToken > 0 {
+		profile.CharsPerToken = cfg.CharsPerToken
+	}
+	if cfg.CJKCharsPerToken > 0 {
+		profile.CJKCharsPerToken = cfg.CJKCharsPerToken
+	}
+	return profile
+}
+
+// EstimateTokens 估算文本的 token 数
+func (p TokenizerProfile) EstimateTokens(text string) int {
+	return int(math.Ceil(p.estimate(text)))
+}
+
+func (p TokenizerProfile) estimate(text string) float64 {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < 128 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return float64(ascii)/p.CharsPerToken + float64(other)/p.CJKCharsPerToken
+}
+
+// PromptTokenUsage 本次决策 Prompt 的 token 估算与裁剪结果
+type PromptTokenUsage struct {
+	Model        string         `json:"model"`         // 使用的分词近似参数
+	SystemTokens int            `json:"system_tokens"` // System Prompt token 数
+	UserTokens   int            `json:"user_tokens"`   // User Prompt token 数（裁剪后）
+	Limit        int            `json:"limit"`         // 输入可用上限（上下文窗口 - 输出预留），0 表示未启用预算
+	Sections     map[string]int `json:"sections"`      // User Prompt 各部分 token 数
+	Trimmed      []string       `json:"trimmed,omitempty"`
+}
+
+// promptSectionWriter 写入 Prompt 并按部分累计 token 估算值
+type promptSectionWriter struct {
+	sb       strings.Builder
+	profile  TokenizerProfile
+	sections map[string]float64
+}
+
+func newPromptSectionWriter(profile TokenizerProfile) *promptSectionWriter {
+	return &promptSectionWriter{profile: profile, sections: make(map[string]float64)}
+}
+
+// Write 写入属于指定部分的文本
+func (w *promptSectionWriter) Write(section, text string) {
+	w.sb.WriteString(text)
+	w.sections[section] += w.profile.estimate(text)
+}
+
+func (w *promptSectionWriter) String() string {
+	return w.sb.String()
+}
+
+// Sections 各部分 token 数
+func (w *promptSectionWriter) Sections() map[string]int {
+	result := make(map[string]int, len(w.sections))
+	for name, tokens := range w.sections {
+		result[name] = int(math.Ceil(tokens))
+	}
+	return result
+}
+
+// BuildBudgetedUserPrompt 在 Token 预算内构建 User Prompt
+// 未启用预算时不裁剪，仅返回 token 估算值；启用后按以下优先级裁剪，直到总量和各部分预算均满足：
+//  1. 缩短K线序列（每次减半，最少保留 minSeriesLimit 个数据点）
+//  2. 移除候选币的外部（量化）数据（持仓币种保留）
+//  3. 按排名从低到高移除候选币
+func (e *StrategyEngine) BuildBudgetedUserPrompt(ctx *Context, systemPrompt string, model string) (string, *PromptTokenUsage) {
+	cfg := e.config.TokenBudget
+	profile := ResolveTokenizerProfile(model, cfg)
+	usage := &PromptTokenUsage{
+		Model:        profile.Name,
+		SystemTokens: profile.EstimateTokens(systemPrompt),
+	}
+
+	opts := userPromptOptions{maxCandidates: -1, candidateExternalData: true}
+	if !cfg.Enabled {
+		w := newPromptSectionWriter(profile)
+		e.buildUserPrompt(ctx, opts, w)
+		usage.Sections = w.Sections()
+		usage.UserTokens = profile.EstimateTokens(w.String())
+		return w.String(), usage
+	}
+
+	reserve := cfg.ReserveOutputTokens
+	if reserve <= 0 {
+		reserve = defaultReserveOutputTokens
+	}
+	usage.Limit = profile.ContextWindow - reserve
+	userLimit := usage.Limit - usage.SystemTokens
+
+	sectionBudgets := map[string]int{
+		promptSectionPositions:  cfg.PositionsTokens,
+		promptSectionCandidates: cfg.CandidatesTokens,
+		promptSectionKlines:     cfg.KlinesTokens,
+		promptSectionExternal:   cfg.ExternalDataTokens,
+	}
+
+	var (
+		prompt     string
+		stats      userPromptStats
+		candidates = -1
+	)
+	for {
+		w := newPromptSectionWriter(profile)
+		stats = e.buildUserPrompt(ctx, opts, w)
+		if candidates < 0 {
+			candidates = stats.candidates
+		}
+		prompt = w.String()
+		usage.Sections = w.Sections()
+		usage.UserTokens = profile.EstimateTokens(prompt)
+
+		overTotal := usage.UserTokens > userLimit
+		over := func(section string) bool {
+			budget := sectionBudgets[section]
+			return overTotal || (budget > 0 && usage.Sections[section] > budget)
+		}
+
+		switch {
+		case (over(promptSectionKlines) || over(promptSectionPositions)) && stats.maxSeriesLen > minSeriesLimit:
+			opts.seriesLimit = max(minSeriesLimit, stats.maxSeriesLen/2)
+			continue
+		case over(promptSectionExternal) && stats.candidateExternalData:
+			opts.candidateExternalData = false
+			continue
+		case (over(promptSectionCandidates) || over(promptSectionKlines) || over(promptSectionExternal)) && stats.candidates > 0:
+			opts.maxCandidates = stats.candidates - 1
+			continue
+		}
+
+		if overTotal {
+			logger.Warnf("⚠️ Prompt 裁剪后仍超出预算: %d + %d > %d tokens (%s)",
+				usage.SystemTokens, usage.UserTokens, usage.Limit, profile.Name)
+		}
+		break
+	}
+
+	if opts.seriesLimit > 0 {
+		usage.Trimmed = append(usage.Trimmed, fmt.Sprintf("klines:%d", opts.seriesLimit))
+	}
+	if !opts.candidateExternalData {
+		usage.Trimmed = append(usage.Trimmed, "external:candidates")
+	}
+	if opts.maxCandidates >= 0 {
+		usage.Trimmed = append(usage.Trimmed, fmt.Sprintf("candidates:%d->%d", candidates, stats.candidates))
+	}
+	if len(usage.Trimmed) > 0 {
+		logger.Infof("✂️ Prompt 超出 token 预算，已裁剪: %s（User Prompt %d tokens）",
+			strings.Join(usage.Trimmed, ", "), usage.UserTokens)
+	}
+
+	return prompt, usage
+}
+
+// tailSeries 保留序列最新的 limit 个数据点（limit <= 0 时不截断）
+func tailSeries(values []float64, limit int) []float64 {
+	if limit <= 0 || len(values) <= limit {
+		return values
+	}
+	return values[len(values)-limit:]
+}
+
+// seriesLen 按 limit 截断后，市场数据中最长的序列长度
+func seriesLen(data *market.Data, limit int) int {
+	n := 0
+	for _, tf := range data.TimeframeData {
+		n = max(n, len(tf.MidPrices), len(tf.Volume))
+	}
+	if data.IntradaySeries != nil {
+		n = max(n, len(data.IntradaySeries.MidPrices), len(data.IntradaySeries.Volume))
+	}
+	if data.LongerTermContext != nil {
+		n = max(n, len(data.LongerTermContext.MACDValues), len(data.LongerTermContext.RSI14Values))
+	}
+	if limit > 0 {
+		return min(n, limit)
+	}
+	return n
+}
@@ -0,0 +1,128 @@
+package decision
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"nofx/market"
+	"nofx/store"
+)
+
+func newBudgetTestContext(candidates, seriesLen int) *Context {
+	ctx := &Context{
+		Account:       AccountInfo{TotalEquity: 1000, AvailableBalance: 1000},
+		MarketDataMap: make(map[string]*market.Data),
+		QuantDataMap:  make(map[string]*QuantData),
+	}
+	for i := 0; i < candidates; i++ {
+		symbol := fmt.Sprintf("COIN%dUSDT", i+1)
+		prices := make([]float64, seriesLen)
+		for j := range prices {
+			prices[j] = float64(100 + j)
+		}
+		ctx.CandidateCoins = append(ctx.CandidateCoins, CandidateCoin{Symbol: symbol, Sources: []string{"ai500"}})
+		ctx.MarketDataMap[symbol] = &market.Data{
+			Symbol:       symbol,
+			CurrentPrice: 100,
+			TimeframeData: map[string]*market.TimeframeSeriesData{
+				"5m": {MidPrices: prices, Volume: prices},
+			},
+		}
+		ctx.QuantDataMap[symbol] = &QuantData{Symbol: symbol, Price: 100}
+	}
+	return ctx
+}
+
+// TestEstimateTokens 测试中英文混合文本的 token 估算
+func TestEstimateTokens(t *testing.T) {
+	profile := TokenizerProfile{CharsPerToken: 4, CJKCharsPerToken: 1}
+	if got := profile.EstimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("ASCII: got %d, want 2", got)
+	}
+	if got := profile.EstimateTokens("中文ab"); got != 3 {
+		t.Errorf("混合: got %d, want 3", got)
+	}
+}
+
+// TestResolveTokenizerProfile 测试模型匹配和配置覆盖
+func TestResolveTokenizerProfile(t *testing.T) {
+	if p := ResolveTokenizerProfile("deepseek-chat", store.TokenBudgetConfig{}); p.Name != "deepseek" {
+		t.Errorf("应匹配 deepseek, got %s", p.Name)
+	}
+	if p := ResolveTokenizerProfile("unknown-model", store.TokenBudgetConfig{}); p.Name != "default" {
+		t.Errorf("未知模型应使用默认参数, got %s", p.Name)
+	}
+	p := ResolveTokenizerProfile("deepseek", store.TokenBudgetConfig{Model: "gpt-4o", ContextWindow: 8000})
+	if p.Name != "gpt" || p.ContextWindow != 8000 {
+		t.Errorf("配置应覆盖模型和上下文窗口, got %s %d", p.Name, p.ContextWindow)
+	}
+}
+
+// TestBuildBudgetedUserPrompt_Disabled 未启用预算时不裁剪
+func TestBuildBudgetedUserPrompt_Disabled(t *testing.T) {
+	engine := NewStrategyEngine(&store.StrategyConfig{})
+	ctx := newBudgetTestContext(5, 50)
+
+	prompt, usage := engine.BuildBudgetedUserPrompt(ctx, "system", "deepseek")
+	if prompt != engine.BuildUserPrompt(ctx) {
+		t.Errorf("未启用预算时应与 BuildUserPrompt 一致")
+	}
+	if usage.Limit != 0 || len(usage.Trimmed) != 0 || usage.UserTokens == 0 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+// TestBuildBudgetedUserPrompt_TrimOrder 测试裁剪顺序：先缩短K线，再移除外部数据，最后移除排名靠后的候选币
+func TestBuildBudgetedUserPrompt_TrimOrder(t *testing.T) {
+	ctx := newBudgetTestContext(10, 200)
+	full := NewStrategyEngine(&store.StrategyConfig{}).BuildUserPrompt(ctx)
+	profile := ResolveTokenizerProfile("deepseek", store.TokenBudgetConfig{})
+	fullTokens := profile.EstimateTokens(full)
+
+	// 只需缩短K线序列
+	engine := NewStrategyEngine(&store.StrategyConfig{
+		TokenBudget: store.TokenBudgetConfig{Enabled: true, ContextWindow: fullTokens*3/4 + 100, ReserveOutputTokens: 100},
+	})
+	prompt, usage := engine.BuildBudgetedUserPrompt(ctx, "", "deepseek")
+	if usage.UserTokens > usage.Limit {
+		t.Errorf("裁剪后仍超出预算: %d > %d", usage.UserTokens, usage.Limit)
+	}
+	if len(usage.Trimmed) != 1 || !strings.HasPrefix(usage.Trimmed[0], "klines:") {
+		t.Errorf("应只缩短K线序列, got %v", usage.Trimmed)
+	}
+	if !strings.Contains(prompt, "COIN10USDT") {
+		t.Errorf("不应移除候选币")
+	}
+
+	// 预算很小时移除排名靠后的候选币
+	engine = NewStrategyEngine(&store.StrategyConfig{
+		TokenBudget: store.TokenBudgetConfig{Enabled: true, ContextWindow: 600, ReserveOutputTokens: 100},
+	})
+	prompt, usage = engine.BuildBudgetedUserPrompt(ctx, "", "deepseek")
+	if usage.UserTokens > usage.Limit {
+		t.Errorf("裁剪后仍超出预算: %d > %d", usage.UserTokens, usage.Limit)
+	}
+	trimmed := strings.Join(usage.Trimmed, ",")
+	if !strings.Contains(trimmed, "klines:10") || !strings.Contains(trimmed, "external:candidates") || !strings.Contains(trimmed, "candidates:10->") {
+		t.Errorf("unexpected trimmed: %v", usage.Trimmed)
+	}
+	if !strings.Contains(prompt, "COIN1USDT") || strings.Contains(prompt, "COIN10USDT") {
+		t.Errorf("应保留排名靠前的候选币，移除排名靠后的")
+	}
+}
+
+// TestBuildBudgetedUserPrompt_SectionBudget 测试单独的部分预算
+func TestBuildBudgetedUserPrompt_SectionBudget(t *testing.T) {
+	ctx := newBudgetTestContext(3, 20)
+	engine := NewStrategyEngine(&store.StrategyConfig{
+		TokenBudget: store.TokenBudgetConfig{Enabled: true, ContextWindow: 1000000, ExternalDataTokens: 1},
+	})
+	_, usage := engine.BuildBudgetedUserPrompt(ctx, "", "deepseek")
+	if usage.Sections[promptSectionExternal] != 0 {
+		t.Errorf("外部数据应被移除, got %d tokens", usage.Sections[promptSectionExternal])
+	}
+	if len(usage.Trimmed) != 1 || usage.Trimmed[0] != "external:candidates" {
+		t.Errorf("unexpected trimmed: %v", usage.Trimmed)
+	}
+}
@@ -0,0 +1,283 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"nofx/market"
+	"nofx/store"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTriggerDebounce = 5 * time.Second
+	defaultTriggerMinGap   = 60 * time.Second
+	triggerATRPeriod       = 14
+	triggerVolumeLookback  = 20
+)
+
+// 触发条件名称
+const (
+	TriggerPriceMove     = "price_move"
+	TriggerATRSpike      = "atr_spike"
+	TriggerVolumeSpike   = "volume_spike"
+	TriggerStopProximity = "stop_proximity"
+	TriggerWebhookSignal = "webhook_signal"
+	TriggerCandleClose   = "candle_close"
+)
+
+// ValidateTriggerConfig 校验事件触发配置
+func ValidateTriggerConfig(config store.TriggerConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+	if config.Timeframe != "" {
+		if _, err := market.NormalizeTimeframe(config.Timeframe); err != nil {
+			return err
+		}
+	}
+	if config.PriceMovePct < 0 || config.ATRSpikeMultiplier < 0 || config.VolumeSpikeMultiplier < 0 || config.StopProximityPct < 0 {
+		return fmt.Errorf("触发阈值不能为负数")
+	}
+	if config.PriceMovePct == 0 && config.ATRSpikeMultiplier == 0 && config.VolumeSpikeMultiplier == 0 &&
+		config.StopProximityPct == 0 && !config.OnWebhookSignal && !config.OnCandleClose {
+		return fmt.Errorf("启用事件触发时至少需要配置一个触发条件")
+	}
+	if config.DebounceSecs < 0 || config.DebounceSecs > 600 {
+		return fmt.Errorf("防抖窗口必须在 0-600 秒之间")
+	}
+	if config.MinGapSecs < 0 || config.MinGapSecs > 86400 {
+		return fmt.Errorf("最小间隔必须在 0-86400 秒之间")
+	}
+	return nil
+}
+
+// TriggerTiming 返回外部触发（行情事件、Webhook 信号）的防抖窗口和两次 AI 调用的最小间隔
+// 未启用事件触发时使用默认值，Webhook 触发的周期同样受最小间隔限制
+func TriggerTiming(config store.TriggerConfig) (debounce, minGap time.Duration) {
+	debounce, minGap = defaultTriggerDebounce, defaultTriggerMinGap
+	if !config.Enabled {
+		return debounce, minGap
+	}
+	if config.DebounceSecs > 0 {
+		debounce = time.Duration(config.DebounceSecs) * time.Second
+	}
+	if config.MinGapSecs > 0 {
+		minGap = time.Duration(config.MinGapSecs) * time.Second
+	}
+	return debounce, minGap
+}
+
+// TriggerEvaluator 基于实时K线判断事件触发条件
+// 关注上次周期的候选币和持仓；同一币种的同一条件在一根K线内只触发一次（价格变动触发后以新价格为基准）
+type TriggerEvaluator struct {
+	config    store.TriggerConfig
+	timeframe string
+	klines    KlineSource
+
+	mu      sync.Mutex
+	symbols map[string]*triggerSymbolState
+	stops   map[string]float64 // symbol_side -> 止损价
+}
+
+type triggerSymbolState struct {
+	refPrice   float64          // 价格变动基准（上次周期后的首个价格）
+	side       string           // 持仓方向（无持仓为空）
+	liqPrice   float64          // 强平价
+	atr        float64          // 已收盘K线的 ATR
+	avgVolume  float64          // 已收盘K线的平均成交量
+	statsOpen  int64            // 统计对应的当前K线开盘时间
+	firedOpens map[string]int64 // 条件 -> 已触发的K线开盘时间
+}
+
+// NewTriggerEvaluator 创建触发条件判断器；timeframe 为空时使用 primaryTimeframe
+func NewTriggerEvaluator(config store.TriggerConfig, primaryTimeframe string, klines KlineSource) *TriggerEvaluator {
+	timeframe := config.Timeframe
+	if timeframe == "" {
+		timeframe = primaryTimeframe
+	}
+	if timeframe == "" {
+		timeframe = "3m"
+	}
+	return &TriggerEvaluator{
+		config:    config,
+		timeframe: strings.ToLower(timeframe),
+		klines:    klines,
+		symbols:   make(map[string]*triggerSymbolState),
+		stops:     make(map[string]float64),
+	}
+}
+
+// Timeframe 监听的K线周期
+func (t *TriggerEvaluator) Timeframe() string {
+	return t.timeframe
+}
+
+// Reset 周期执行后重置关注的币种和价格基准，返回新加入关注的币种
+func (t *TriggerEvaluator) Reset(ctx *Context) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := t.symbols
+	t.symbols = make(map[string]*triggerSymbolState)
+	var added []string
+	watch := func(symbol string) *triggerSymbolState {
+		if st, ok := t.symbols[symbol]; ok {
+			return st
+		}
+		st := &triggerSymbolState{firedOpens: make(map[string]int64)}
+		if old, ok := previous[symbol]; ok {
+			st.atr, st.avgVolume, st.statsOpen = old.atr, old.avgVolume, old.statsOpen
+			st.firedOpens = old.firedOpens
+		} else {
+			added = append(added, symbol)
+		}
+		t.symbols[symbol] = st
+		return st
+	}
+
+	for _, pos := range ctx.Positions {
+		st := watch(pos.Symbol)
+		st.side = pos.Side
+		st.liqPrice = pos.LiquidationPrice
+	}
+	for _, coin := range ctx.CandidateCoins {
+		watch(coin.Symbol)
+	}
+
+	// 已平仓的止损价不再跟踪
+	for key := range t.stops {
+		symbol, side, _ := strings.Cut(key, "_")
+		if st, ok := t.symbols[symbol]; !ok || st.side != side {
+			delete(t.stops, key)
+		}
+	}
+	return added
+}
+
+// SetStopLoss 记录持仓止损价（开仓设置止损后调用，新持仓在下个周期前也开始跟踪）
+func (t *TriggerEvaluator) SetStopLoss(symbol, side string, price float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	side = strings.ToLower(side)
+	t.stops[symbol+"_"+side] = price
+	if st, ok := t.symbols[symbol]; ok && st.side == "" {
+		st.side = side
+	}
+}
+
+// Evaluate 处理一条K线更新，返回满足的触发原因
+func (t *TriggerEvaluator) Evaluate(update market.KlineUpdate) []string {
+	k := update.Kline
+	if update.Interval != t.timeframe || k.Close <= 0 {
+		return nil
+	}
+	if t.config.ATRSpikeMultiplier > 0 || t.config.VolumeSpikeMultiplier > 0 {
+		t.refreshStats(update.Symbol, k.OpenTime)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st, ok := t.symbols[update.Symbol]
+	if !ok {
+		return nil
+	}
+
+	var reasons []string
+	once := func(name, reason string) {
+		if st.firedOpens[name] == k.OpenTime {
+			return
+		}
+		st.firedOpens[name] = k.OpenTime
+		reasons = append(reasons, reason)
+	}
+
+	if pct := t.config.PriceMovePct; pct > 0 {
+		if st.refPrice <= 0 {
+			st.refPrice = k.Close
+		} else if move := (k.Close/st.refPrice - 1) * 100; math.Abs(move) >= pct {
+			reasons = append(reasons, fmt.Sprintf("%s 价格较上次周期变动 %+.2f%%", update.Symbol, move))
+			st.refPrice = k.Close
+		}
+	}
+
+	if m := t.config.ATRSpikeMultiplier; m > 0 && st.atr > 0 && k.High-k.Low >= m*st.atr {
+		once(TriggerATRSpike, fmt.Sprintf("%s %s K线振幅 %.4g 达到 ATR 的 %.1f 倍", update.Symbol, t.timeframe, k.High-k.Low, (k.High-k.Low)/st.atr))
+	}
+	if m := t.config.VolumeSpikeMultiplier; m > 0 && st.avgVolume > 0 && k.Volume >= m*st.avgVolume {
+		once(TriggerVolumeSpike, fmt.Sprintf("%s %s 成交量达到均量的 %.1f 倍", update.Symbol, t.timeframe, k.Volume/st.avgVolume))
+	}
+
+	if pct := t.config.StopProximityPct; pct > 0 && st.side != "" {
+		if dist, ok := stopDistancePct(st.side, k.Close, st.liqPrice); ok && dist <= pct {
+			once(TriggerStopProximity+"_liq", fmt.Sprintf("%s %s 持仓距强平价仅 %.2f%%", update.Symbol, st.side, dist))
+		}
+		if dist, ok := stopDistancePct(st.side, k.Close, t.stops[update.Symbol+"_"+st.side]); ok && dist <= pct {
+			once(TriggerStopProximity, fmt.Sprintf("%s %s 持仓距止损价仅 %.2f%%", update.Symbol, st.side, dist))
+		}
+	}
+
+	if t.config.OnCandleClose && update.Closed {
+		once(TriggerCandleClose, fmt.Sprintf("%s K线收盘", t.timeframe))
+	}
+	return reasons
+}
+
+// refreshStats 新K线开始时重新计算 ATR 和均量（只使用已收盘K线）
+// K线在锁外获取，数据源较慢时不阻塞其他币种的判断和 Reset/SetStopLoss
+func (t *TriggerEvaluator) refreshStats(symbol string, openTime int64) {
+	if t.klines == nil {
+		return
+	}
+	t.mu.Lock()
+	st, ok := t.symbols[symbol]
+	stale := ok && st.statsOpen != openTime
+	if stale {
+		st.statsOpen = openTime
+	}
+	t.mu.Unlock()
+	if !stale {
+		return
+	}
+
+	klines, err := t.klines(symbol, t.timeframe)
+	if err != nil {
+		return
+	}
+	closed := klines[:0:0]
+	for _, k := range klines {
+		if k.OpenTime < openTime {
+			closed = append(closed, k)
+		}
+	}
+	atr := lastValue(market.ATRSeries(closed, triggerATRPeriod), 0)
+	var avgVolume float64
+	if n := min(len(closed), triggerVolumeLookback); n > 0 {
+		for _, k := range closed[len(closed)-n:] {
+			avgVolume += k.Volume
+		}
+		avgVolume /= float64(n)
+	}
+
+	// 获取期间 Reset 可能已替换状态，写入当前状态
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if st, ok := t.symbols[symbol]; ok && st.statsOpen == openTime {
+		st.atr, st.avgVolume = atr, avgVolume
+	}
+}
+
+// stopDistancePct 当前价距止损/强平价的百分比（价格已越过时为 0）
+func stopDistancePct(side string, price, stop float64) (float64, bool) {
+	if stop <= 0 || price <= 0 {
+		return 0, false
+	}
+	var dist float64
+	if side == "long" {
+		dist = (price - stop) / price * 100
+	} else {
+		dist = (stop - price) / price * 100
+	}
+	return math.Max(dist, 0), true
+}
@@ -0,0 +1,109 @@
+package decision
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/market"
+	"nofx/store"
+)
+
+// triggerHistory 20 根已收盘K线：振幅 1、成交量 100
+func triggerHistory(symbol, timeframe string) ([]market.Kline, error) {
+	klines := make([]market.Kline, 20)
+	for i := range klines {
+		klines[i] = market.Kline{OpenTime: int64(i), Open: 100, High: 100.5, Low: 99.5, Close: 100, Volume: 100}
+	}
+	return klines, nil
+}
+
+func triggerUpdate(symbol string, openTime int64, high, low, close, volume float64, closed bool) market.KlineUpdate {
+	return market.KlineUpdate{
+		Symbol:   symbol,
+		Interval: "3m",
+		Kline:    market.Kline{OpenTime: openTime, Open: 100, High: high, Low: low, Close: close, Volume: volume},
+		Closed:   closed,
+	}
+}
+
+// TestTriggerEvaluator 测试价格变动、振幅/成交量异动、止损接近和K线收盘触发
+func TestTriggerEvaluator(t *testing.T) {
+	eval := NewTriggerEvaluator(store.TriggerConfig{
+		Enabled:               true,
+		PriceMovePct:          2,
+		ATRSpikeMultiplier:    3,
+		VolumeSpikeMultiplier: 4,
+		StopProximityPct:      0.5,
+		OnCandleClose:         true,
+	}, "3m", triggerHistory)
+	eval.Reset(&Context{
+		CandidateCoins: []CandidateCoin{{Symbol: "BTCUSDT"}},
+		Positions:      []PositionInfo{{Symbol: "ETHUSDT", Side: "long", LiquidationPrice: 50}},
+	})
+
+	// 未关注的币种和其他周期忽略
+	if r := eval.Evaluate(triggerUpdate("SOLUSDT", 20, 200, 50, 150, 1000, true)); len(r) != 0 {
+		t.Errorf("unwatched symbol triggered: %v", r)
+	}
+	other := triggerUpdate("BTCUSDT", 20, 200, 50, 150, 1000, true)
+	other.Interval = "4h"
+	if r := eval.Evaluate(other); len(r) != 0 {
+		t.Errorf("other timeframe triggered: %v", r)
+	}
+
+	// 首个价格作为基准，平静行情不触发
+	if r := eval.Evaluate(triggerUpdate("BTCUSDT", 20, 100.2, 99.9, 100, 50, false)); len(r) != 0 {
+		t.Errorf("quiet market triggered: %v", r)
+	}
+
+	// 价格 +2.5%、振幅 4（ATR≈1）、成交量 500
+	r := eval.Evaluate(triggerUpdate("BTCUSDT", 20, 102.5, 98.5, 102.5, 500, false))
+	if len(r) != 3 {
+		t.Fatalf("expected price/atr/volume triggers, got %v", r)
+	}
+
+	// 同一根K线内振幅/成交量不重复触发，价格以新基准计算
+	if r := eval.Evaluate(triggerUpdate("BTCUSDT", 20, 102.6, 98.5, 102.6, 600, false)); len(r) != 0 {
+		t.Errorf("repeated triggers within candle: %v", r)
+	}
+
+	// K线收盘
+	if r := eval.Evaluate(triggerUpdate("BTCUSDT", 20, 102.6, 98.5, 102.6, 600, true)); len(r) != 1 || !strings.Contains(r[0], "收盘") {
+		t.Errorf("expected candle close trigger, got %v", r)
+	}
+
+	// 持仓接近止损价
+	eval.SetStopLoss("ETHUSDT", "LONG", 99.7)
+	r = eval.Evaluate(triggerUpdate("ETHUSDT", 20, 100.1, 99.9, 100, 50, false))
+	if len(r) != 1 || !strings.Contains(r[0], "止损") {
+		t.Errorf("expected stop proximity trigger, got %v", r)
+	}
+
+	// 新周期后已平仓的止损不再跟踪
+	eval.Reset(&Context{CandidateCoins: []CandidateCoin{{Symbol: "ETHUSDT"}}})
+	if r := eval.Evaluate(triggerUpdate("ETHUSDT", 21, 100.1, 99.9, 99.8, 50, false)); len(r) != 0 {
+		t.Errorf("closed position still triggered: %v", r)
+	}
+}
+
+// TestValidateTriggerConfig 测试事件触发配置校验和默认时间参数
+func TestValidateTriggerConfig(t *testing.T) {
+	if err := ValidateTriggerConfig(store.TriggerConfig{}); err != nil {
+		t.Errorf("disabled config should be valid: %v", err)
+	}
+	if err := ValidateTriggerConfig(store.TriggerConfig{Enabled: true}); err == nil {
+		t.Error("enabled config without conditions should be rejected")
+	}
+	if err := ValidateTriggerConfig(store.TriggerConfig{Enabled: true, PriceMovePct: 1, Timeframe: "7m"}); err == nil {
+		t.Error("unsupported timeframe should be rejected")
+	}
+
+	debounce, minGap := TriggerTiming(store.TriggerConfig{Enabled: true, MinGapSecs: 120})
+	if debounce != defaultTriggerDebounce || minGap.Seconds() != 120 {
+		t.Errorf("timing = %v/%v", debounce, minGap)
+	}
+	// 未启用事件触发时 Webhook 触发的周期仍使用默认防抖和最小间隔
+	if debounce, minGap := TriggerTiming(store.TriggerConfig{MinGapSecs: 1}); debounce != defaultTriggerDebounce || minGap != defaultTriggerMinGap {
+		t.Errorf("disabled timing = %v/%v", debounce, minGap)
+	}
+}
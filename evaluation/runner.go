@@ -0,0 +1,332 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/mcp"
+	"nofx/store"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHorizonMinutes 默认前瞻观察窗口
+	DefaultHorizonMinutes = 60
+	// DefaultSampleLimit 未指定决策记录时默认回放的最近记录数
+	DefaultSampleLimit = 50
+	// MaxSamples 单次评估最多回放的记录数（控制成本）
+	MaxSamples = 500
+
+	defaultLeverage = 5
+)
+
+// Runner 模型评估执行器：将历史决策的 Prompt 重新发送给多个AI模型并打分
+type Runner struct {
+	store *store.Store
+
+	// 以下依赖可替换（便于测试）
+	newClient func(model *store.AIModel) mcp.AIClient
+	getKlines func(symbol, timeframe string, start, end time.Time) ([]market.Kline, error)
+	now       func() time.Time
+}
+
+// NewRunner 创建评估执行器
+func NewRunner(st *store.Store) *Runner {
+	return &Runner{
+		store: st,
+		newClient: func(model *store.AIModel) mcp.AIClient {
+			client := mcp.NewProviderClient(model.Provider)
+			client.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
+			return client
+		},
+		getKlines: market.GetKlinesRange,
+		now:       time.Now,
+	}
+}
+
+// evalModel 参与评估的模型
+type evalModel struct {
+	config *store.AIModel
+	client mcp.AIClient
+}
+
+// Prepare 校验评估配置并补全默认值（在创建任务前调用，尽早返回配置错误）
+func (r *Runner) Prepare(eval *store.ModelEvaluation) error {
+	if eval.TraderID == "" {
+		return fmt.Errorf("必须指定交易员")
+	}
+	if _, err := r.store.Trader().GetFullConfig(eval.UserID, eval.TraderID); err != nil {
+		return fmt.Errorf("交易员不存在或无访问权限")
+	}
+	if len(eval.ModelIDs) == 0 {
+		return fmt.Errorf("至少选择一个AI模型")
+	}
+	if _, err := r.loadModels(eval); err != nil {
+		return err
+	}
+	if len(eval.DecisionIDs) > MaxSamples {
+		return fmt.Errorf("单次评估最多回放 %d 条决策记录", MaxSamples)
+	}
+	if eval.SampleLimit <= 0 {
+		eval.SampleLimit = DefaultSampleLimit
+	}
+	if eval.SampleLimit > MaxSamples {
+		eval.SampleLimit = MaxSamples
+	}
+	if eval.HorizonMinutes <= 0 {
+		eval.HorizonMinutes = DefaultHorizonMinutes
+	}
+	if strings.TrimSpace(eval.Name) == "" {
+		eval.Name = fmt.Sprintf("评估 %s", r.now().Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// Run 执行评估（阻塞直到完成，调用方通常在 goroutine 中运行）
+func (r *Runner) Run(eval *store.ModelEvaluation) error {
+	err := r.run(eval)
+	if err != nil {
+		logger.Infof("❌ [评估 %s] 失败: %v", eval.ID, err)
+		r.store.Evaluation().UpdateProgress(eval.ID, store.EvaluationStatusFailed, eval.Progress, eval.Total, err.Error())
+	}
+	return err
+}
+
+func (r *Runner) run(eval *store.ModelEvaluation) error {
+	models, err := r.loadModels(eval)
+	if err != nil {
+		return err
+	}
+	records, err := r.loadRecords(eval)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("没有可回放的决策记录（需要包含 System Prompt 和输入 Prompt）")
+	}
+
+	btcEthLeverage, altcoinLeverage := r.loadLeverage(eval)
+	horizon := time.Duration(eval.HorizonMinutes) * time.Minute
+	prices := newPriceCache(r.getKlines, horizon, r.now)
+
+	eval.Total = len(records) * len(models)
+	eval.Progress = 0
+	r.store.Evaluation().UpdateProgress(eval.ID, store.EvaluationStatusRunning, 0, eval.Total, "")
+	logger.Infof("🧪 [评估 %s] 开始回放 %d 条决策 × %d 个模型", eval.ID, len(records), len(models))
+
+	for _, record := range records {
+		baseline, hasBaseline := ParseDecisionJSON(record.DecisionJSON)
+		priceMove := func(symbol string) (float64, bool) {
+			return prices.move(symbol, record.Timestamp)
+		}
+
+		// 同一条记录并发发送给所有模型
+		samples := make([]*store.EvaluationSample, len(models))
+		var wg sync.WaitGroup
+		for i, model := range models {
+			wg.Add(1)
+			go func(i int, model *evalModel) {
+				defer wg.Done()
+				samples[i] = r.replay(eval, record, model, btcEthLeverage, altcoinLeverage)
+			}(i, model)
+		}
+		wg.Wait()
+
+		for _, sample := range samples {
+			var candidate []decision.Decision
+			json.Unmarshal([]byte(sample.DecisionJSON), &candidate)
+
+			sample.HasBaseline = hasBaseline
+			if hasBaseline {
+				sample.Agreement = Agreement(baseline, candidate)
+			}
+			forward := ScoreForward(candidate, priceMove)
+			sample.ActionableCount = forward.Actionable
+			sample.HitCount = forward.Hits
+			sample.ForwardReturnPct = forward.ReturnPct
+
+			if err := r.store.Evaluation().AddSample(sample); err != nil {
+				return err
+			}
+			eval.Progress++
+		}
+		r.store.Evaluation().UpdateProgress(eval.ID, store.EvaluationStatusRunning, eval.Progress, eval.Total, "")
+	}
+
+	scores, err := r.store.Evaluation().ComputeScores(eval.ID)
+	if err != nil {
+		return err
+	}
+	r.store.Evaluation().UpdateProgress(eval.ID, store.EvaluationStatusCompleted, eval.Progress, eval.Total, "")
+	if len(scores) > 0 {
+		logger.Infof("✓ [评估 %s] 完成，第一名: %s (得分 %.1f)", eval.ID, scores[0].ModelID, scores[0].Score)
+	}
+	return nil
+}
+
+// replay 将一条决策记录的 Prompt 发送给模型，记录解析/验证结果、耗时和成本
+func (r *Runner) replay(eval *store.ModelEvaluation, record *store.DecisionRecord, model *evalModel, btcEthLeverage, altcoinLeverage int) *store.EvaluationSample {
+	sample := &store.EvaluationSample{
+		EvaluationID: eval.ID,
+		ModelID:      model.config.ID,
+		DecisionID:   record.ID,
+		DecisionJSON: "[]",
+	}
+
+	start := time.Now()
+	result, err := model.client.CallWithMessagesResult(record.SystemPrompt, record.InputPrompt)
+	sample.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+
+	if usage := result.Usage; usage != nil {
+		sample.PromptTokens = usage.PromptTokens
+		sample.CompletionTokens = usage.CompletionTokens
+		sample.CostUSD = r.store.AIUsage().CalculateCost(eval.UserID, usage.Model,
+			usage.PromptTokens, usage.CachedTokens, usage.CompletionTokens)
+	}
+
+	fullDecision, parsed, err := decision.ParseAIResult(result, record.AccountState.TotalBalance, btcEthLeverage, altcoinLeverage)
+	sample.Parsed = parsed
+	sample.Valid = err == nil
+	if err != nil {
+		sample.Error = err.Error()
+	}
+	if fullDecision != nil && len(fullDecision.Decisions) > 0 {
+		decisionJSON, _ := json.Marshal(fullDecision.Decisions)
+		sample.DecisionJSON = string(decisionJSON)
+	}
+	return sample
+}
+
+// loadModels 加载评估模型（必须已启用，云端模型需要 API Key）
+func (r *Runner) loadModels(eval *store.ModelEvaluation) ([]*evalModel, error) {
+	seen := make(map[string]bool)
+	models := make([]*evalModel, 0, len(eval.ModelIDs))
+	for _, modelID := range eval.ModelIDs {
+		if seen[modelID] {
+			continue
+		}
+		seen[modelID] = true
+
+		config, err := r.store.AIModel().Get(eval.UserID, modelID)
+		if err != nil {
+			return nil, fmt.Errorf("AI模型 %s 不存在", modelID)
+		}
+		if !config.Enabled {
+			return nil, fmt.Errorf("AI模型 %s 尚未启用", config.Name)
+		}
+		if config.APIKey == "" && !mcp.IsLocalProvider(config.Provider) {
+			return nil, fmt.Errorf("AI模型 %s 缺少API Key", config.Name)
+		}
+		models = append(models, &evalModel{config: config, client: r.newClient(config)})
+	}
+	return models, nil
+}
+
+// loadRecords 加载要回放的决策记录（跳过没有 Prompt 的记录）
+func (r *Runner) loadRecords(eval *store.ModelEvaluation) ([]*store.DecisionRecord, error) {
+	var records []*store.DecisionRecord
+	var err error
+	if len(eval.DecisionIDs) > 0 {
+		records, err = r.store.Decision().GetRecordsByIDs(eval.TraderID, eval.DecisionIDs)
+	} else {
+		var from, to time.Time
+		if eval.From != nil {
+			from = *eval.From
+		}
+		if eval.To != nil {
+			to = *eval.To
+		}
+		// 按时间范围选取时只取最近的 SampleLimit 条
+		records, err = r.store.Decision().GetRecordsInRange(eval.TraderID, from, to, eval.SampleLimit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	replayable := make([]*store.DecisionRecord, 0, len(records))
+	for _, record := range records {
+		if record.SystemPrompt != "" && record.InputPrompt != "" {
+			replayable = append(replayable, record)
+		}
+	}
+	return replayable, nil
+}
+
+// loadLeverage 使用交易员当前策略的杠杆上限验证决策
+func (r *Runner) loadLeverage(eval *store.ModelEvaluation) (int, int) {
+	btcEthLeverage, altcoinLeverage := defaultLeverage, defaultLeverage
+	fullCfg, err := r.store.Trader().GetFullConfig(eval.UserID, eval.TraderID)
+	if err != nil || fullCfg.Strategy == nil {
+		return btcEthLeverage, altcoinLeverage
+	}
+	config, err := fullCfg.Strategy.ParseConfig()
+	if err != nil {
+		return btcEthLeverage, altcoinLeverage
+	}
+	if config.RiskControl.BTCETHMaxLeverage > 0 {
+		btcEthLeverage = config.RiskControl.BTCETHMaxLeverage
+	}
+	if config.RiskControl.AltcoinMaxLeverage > 0 {
+		altcoinLeverage = config.RiskControl.AltcoinMaxLeverage
+	}
+	return btcEthLeverage, altcoinLeverage
+}
+
+// priceCache 缓存各币种在决策时刻之后的价格变化（同一记录的多个模型共享）
+type priceCache struct {
+	getKlines func(symbol, timeframe string, start, end time.Time) ([]market.Kline, error)
+	horizon   time.Duration
+	now       func() time.Time
+	moves     map[string]*cachedMove
+}
+
+type cachedMove struct {
+	pct float64
+	ok  bool
+}
+
+func newPriceCache(getKlines func(symbol, timeframe string, start, end time.Time) ([]market.Kline, error), horizon time.Duration, now func() time.Time) *priceCache {
+	return &priceCache{getKlines: getKlines, horizon: horizon, now: now, moves: make(map[string]*cachedMove)}
+}
+
+// move 决策时刻起观察窗口内的价格变化；窗口尚未结束或拉取失败时 ok 为 false
+func (c *priceCache) move(symbol string, at time.Time) (float64, bool) {
+	symbol = market.Normalize(symbol)
+	key := fmt.Sprintf("%s|%d", symbol, at.Unix())
+	if cached, exists := c.moves[key]; exists {
+		return cached.pct, cached.ok
+	}
+
+	end := at.Add(c.horizon)
+	cached := &cachedMove{}
+	if end.Before(c.now()) {
+		klines, err := c.getKlines(symbol, horizonTimeframe(c.horizon), at, end)
+		if err != nil {
+			logger.Infof("⚠️  [评估] 获取 %s K线失败: %v", symbol, err)
+		} else {
+			cached.pct, cached.ok = PriceMove(klines)
+		}
+	}
+	c.moves[key] = cached
+	return cached.pct, cached.ok
+}
+
+// horizonTimeframe 根据观察窗口选择K线周期（单次请求不超过 1500 根）
+func horizonTimeframe(horizon time.Duration) string {
+	switch {
+	case horizon <= 24*time.Hour:
+		return "1m"
+	case horizon <= 5*24*time.Hour:
+		return "5m"
+	default:
+		return "1h"
+	}
+}
@@ -0,0 +1,107 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"nofx/decision"
+	"nofx/market"
+	"strings"
+)
+
+// actionDirection 决策对价格的方向判断：看涨为 1，看跌为 -1，hold/wait 等无方向为 0
+// 平多意味着预期下跌，平空意味着预期上涨
+func actionDirection(action string) float64 {
+	switch action {
+	case "open_long", "close_short":
+		return 1
+	case "open_short", "close_long":
+		return -1
+	default:
+		return 0
+	}
+}
+
+// actionKeys 提取有方向的决策（symbol + action），hold/wait 视为不操作
+func actionKeys(decisions []decision.Decision) map[string]bool {
+	keys := make(map[string]bool)
+	for _, d := range decisions {
+		if actionDirection(d.Action) == 0 {
+			continue
+		}
+		keys[market.Normalize(d.Symbol)+"|"+d.Action] = true
+	}
+	return keys
+}
+
+// Agreement 两组决策的一致度（开平仓动作集合的 Jaccard 相似度，0-1）
+// 双方都不操作时视为完全一致
+func Agreement(original, candidate []decision.Decision) float64 {
+	a, b := actionKeys(original), actionKeys(candidate)
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	intersection := 0
+	for key := range a {
+		if b[key] {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	return float64(intersection) / float64(union)
+}
+
+// ParseDecisionJSON 解析决策记录中保存的决策列表
+func ParseDecisionJSON(decisionJSON string) ([]decision.Decision, bool) {
+	if strings.TrimSpace(decisionJSON) == "" {
+		return nil, false
+	}
+	var decisions []decision.Decision
+	if err := json.Unmarshal([]byte(decisionJSON), &decisions); err != nil {
+		return nil, false
+	}
+	return decisions, true
+}
+
+// PriceMove 观察窗口内的价格变化 (%)，K 线为空时 ok 为 false
+func PriceMove(klines []market.Kline) (pct float64, ok bool) {
+	if len(klines) == 0 || klines[0].Open <= 0 {
+		return 0, false
+	}
+	start := klines[0].Open
+	end := klines[len(klines)-1].Close
+	return (end - start) / start * 100, true
+}
+
+// ForwardResult 一组决策的前瞻表现
+type ForwardResult struct {
+	Actionable int     // 有价格数据的开平仓决策数
+	Hits       int     // 方向正确的决策数
+	ReturnPct  float64 // 按决策方向计算的平均收益 (%)
+}
+
+// ScoreForward 按决策方向计算前瞻收益：看涨决策收益为价格涨幅，看跌决策为跌幅
+// priceMove 返回 symbol 在观察窗口内的价格变化，ok 为 false 的决策不计入
+func ScoreForward(decisions []decision.Decision, priceMove func(symbol string) (float64, bool)) ForwardResult {
+	var result ForwardResult
+	total := 0.0
+	for _, d := range decisions {
+		direction := actionDirection(d.Action)
+		if direction == 0 {
+			continue
+		}
+		move, ok := priceMove(d.Symbol)
+		if !ok {
+			continue
+		}
+		ret := direction * move
+		result.Actionable++
+		total += ret
+		if ret > 0 {
+			result.Hits++
+		}
+	}
+	if result.Actionable > 0 {
+		result.ReturnPct = total / float64(result.Actionable)
+	}
+	return result
+}
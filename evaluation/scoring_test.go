@@ -0,0 +1,66 @@
+package evaluation
+
+import (
+	"math"
+	"nofx/decision"
+	"nofx/market"
+	"testing"
+)
+
+func TestAgreement(t *testing.T) {
+	tests := []struct {
+		name      string
+		original  []decision.Decision
+		candidate []decision.Decision
+		want      float64
+	}{
+		{"both idle", []decision.Decision{{Symbol: "BTCUSDT", Action: "hold"}}, nil, 1},
+		{"identical", []decision.Decision{{Symbol: "BTCUSDT", Action: "open_long"}}, []decision.Decision{{Symbol: "btc", Action: "open_long"}}, 1},
+		{"opposite", []decision.Decision{{Symbol: "BTCUSDT", Action: "open_long"}}, []decision.Decision{{Symbol: "BTCUSDT", Action: "open_short"}}, 0},
+		{"partial", []decision.Decision{
+			{Symbol: "BTCUSDT", Action: "open_long"},
+			{Symbol: "ETHUSDT", Action: "close_short"},
+		}, []decision.Decision{{Symbol: "BTCUSDT", Action: "open_long"}}, 0.5},
+		{"one idle", nil, []decision.Decision{{Symbol: "BTCUSDT", Action: "open_long"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Agreement(tt.original, tt.candidate); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Agreement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceMove(t *testing.T) {
+	if _, ok := PriceMove(nil); ok {
+		t.Error("empty klines should not be ok")
+	}
+	pct, ok := PriceMove([]market.Kline{{Open: 100, Close: 101}, {Open: 101, Close: 102}})
+	if !ok || math.Abs(pct-2) > 1e-9 {
+		t.Errorf("PriceMove() = (%v, %v), want (2, true)", pct, ok)
+	}
+}
+
+func TestScoreForward(t *testing.T) {
+	moves := map[string]float64{"BTCUSDT": 2, "ETHUSDT": -1}
+	priceMove := func(symbol string) (float64, bool) {
+		move, ok := moves[symbol]
+		return move, ok
+	}
+
+	decisions := []decision.Decision{
+		{Symbol: "BTCUSDT", Action: "open_long"},  // +2 命中
+		{Symbol: "ETHUSDT", Action: "open_long"},  // -1 未命中
+		{Symbol: "ETHUSDT", Action: "close_long"}, // +1 命中
+		{Symbol: "SOLUSDT", Action: "open_short"}, // 无价格数据
+		{Symbol: "BTCUSDT", Action: "hold"},       // 无方向
+	}
+	got := ScoreForward(decisions, priceMove)
+	if got.Actionable != 3 || got.Hits != 2 {
+		t.Errorf("ScoreForward() = %+v, want 3 actionable, 2 hits", got)
+	}
+	if math.Abs(got.ReturnPct-2.0/3) > 1e-9 {
+		t.Errorf("ReturnPct = %v, want %v", got.ReturnPct, 2.0/3)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"nofx/backtest"
 	"nofx/config"
 	"nofx/crypto"
+	"nofx/decision"
 	"nofx/logger"
 	"nofx/manager"
 	"nofx/market"
@@ -120,6 +121,36 @@ func syncConfigToDatabase(st *store.Store, configFile *ConfigFile) error {
 	return nil
 }
 
+// marketAlertRetention 行情警报保留时长
+const marketAlertRetention = 7 * 24 * time.Hour
+
+// startMarketAlertEngine 创建并启动行情警报引擎，触发的警报写入数据库（每小时清理过期警报）
+func startMarketAlertEngine(st *store.Store, monitor *market.WSMonitor) *market.AlertEngine {
+	engine := market.NewAlertEngine(monitor)
+	var lastCleanup time.Time
+	engine.OnAlert(func(alert market.Alert) {
+		err := st.MarketAlert().Create(&store.MarketAlert{
+			Type:      alert.Type,
+			Symbol:    alert.Symbol,
+			Value:     alert.Value,
+			Threshold: alert.Threshold,
+			Message:   alert.Message,
+			CreatedAt: alert.Timestamp,
+		})
+		if err != nil {
+			logger.Warnf("⚠️  保存行情警报失败: %v", err)
+		}
+		if time.Since(lastCleanup) > time.Hour {
+			lastCleanup = time.Now()
+			if _, err := st.MarketAlert().DeleteBefore(time.Now().Add(-marketAlertRetention)); err != nil {
+				logger.Warnf("⚠️  清理过期行情警报失败: %v", err)
+			}
+		}
+	})
+	engine.Start()
+	return engine
+}
+
 // loadBetaCodesToDatabase 加载内测码文件到数据库
 func loadBetaCodesToDatabase(st *store.Store) error {
 	betaCodeFile := "beta_codes.txt"
@@ -186,6 +217,9 @@ func main() {
 	}
 	defer st.Close()
 	backtest.UseDatabase(st.DB())
+	if err := st.Evaluation().MarkInterrupted(); err != nil {
+		logger.Warnf("⚠️  标记中断的模型评估失败: %v", err)
+	}
 
 	// 初始化加密服务
 	logger.Info("🔐 初始化加密服务...")
@@ -232,6 +266,11 @@ func main() {
 		logger.Warnf("⚠️  加载内测码到数据库失败: %v", err)
 	}
 
+	// 导入系统提示词模板（prompts/*.txt）到模板库
+	if err := decision.SeedPromptTemplates(st); err != nil {
+		logger.Warnf("⚠️  导入系统提示词模板失败: %v", err)
+	}
+
 	// 获取系统配置
 	useDefaultCoinsStr, _ := st.SystemConfig().Get("use_default_coins")
 	useDefaultCoins := useDefaultCoinsStr == "true"
@@ -295,6 +334,15 @@ func main() {
 		logger.Info("✓ 已配置OI Top API")
 	}
 
+	// 创建 Binance 行情监控器并注册为 Binance 数据源（需在加载交易员前完成，交易员创建时按交易所选择数据源）
+	// NOFX_ORDERBOOK_STREAM=true 时同时订阅增量深度流，在本地维护订单簿（否则按需请求 REST 快照）
+	wsMonitor := market.NewWSMonitor(150)
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("NOFX_ORDERBOOK_STREAM")), "true") {
+		wsMonitor.EnableOrderBookStream()
+		logger.Infof("📚 已启用订单簿深度流")
+	}
+	market.RegisterProvider(market.NewBinanceProvider(wsMonitor))
+
 	// 创建TraderManager 与 BacktestManager
 	cfgForAI, cfgErr := config.LoadConfig("config.json")
 	if cfgErr != nil {
@@ -383,8 +431,12 @@ func main() {
 	}()
 
 	// 启动流行情数据 - 默认使用所有交易员设置的币种 如果没有设置币种 则优先使用系统默认
-	go market.NewWSMonitor(150).Start(st.Trader().GetCustomCoins())
+	go wsMonitor.Start(st.Trader().GetCustomCoins())
 	//go market.NewWSMonitor(150).Start([]string{}) //这里是一个使用方式 传入空的话 则使用market市场的所有币种
+
+	// 启动行情警报引擎：基于实时K线检测放量、急涨急跌、RSI 超买超卖，警报持久化供 API 和 AI Prompt 使用
+	alertEngine := startMarketAlertEngine(st, wsMonitor)
+
 	// 设置优雅退出
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -401,6 +453,8 @@ func main() {
 	traderManager.StopAll()
 	logger.Info("✅ 所有交易员已停止")
 
+	alertEngine.Stop()
+
 	// 步骤 2: 停止订单同步管理器和仓位同步管理器
 	logger.Info("📦 停止订单同步管理器...")
 	orderSyncManager.Stop()
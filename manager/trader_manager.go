@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"nofx/logger"
+	"nofx/mcp"
 	"nofx/store"
 	"nofx/trader"
 	"sort"
@@ -619,6 +620,7 @@ func (tm *TraderManager) addTraderFromStore(traderCfg *store.Trader, aiModelCfg
 		MaxDrawdown:           maxDrawdown,
 		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
 		IsCrossMargin:         traderCfg.IsCrossMargin,
+		StrategyID:            traderCfg.StrategyID,
 		StrategyConfig:        strategyConfig,
 	}
 
@@ -648,6 +650,33 @@ func (tm *TraderManager) addTraderFromStore(traderCfg *store.Trader, aiModelCfg
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else if aiModelCfg.Provider == "anthropic" {
+		traderConfig.AnthropicKey = aiModelCfg.APIKey
+	} else if aiModelCfg.Provider == "gemini" {
+		traderConfig.GeminiKey = aiModelCfg.APIKey
+	} else if mcp.IsLocalProvider(aiModelCfg.Provider) {
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
+	}
+
+	// 加载AI故障转移链（跳过未启用、未配置API Key或与主模型重复的模型）
+	fallbackIDs, err := st.Trader().GetFailoverModelIDs(traderCfg.UserID, traderCfg.ID)
+	if err != nil {
+		logger.Infof("⚠️  交易员 %s 的AI故障转移链加载失败: %v", traderCfg.Name, err)
+	}
+	for _, modelID := range fallbackIDs {
+		if modelID == aiModelCfg.ID {
+			continue
+		}
+		model, err := st.AIModel().Get(traderCfg.UserID, modelID)
+		if err != nil {
+			logger.Infof("⚠️  交易员 %s 的备用AI模型 %s 不存在，跳过", traderCfg.Name, modelID)
+			continue
+		}
+		if !model.Enabled || (model.APIKey == "" && !mcp.IsLocalProvider(model.Provider)) {
+			logger.Infof("⚠️  交易员 %s 的备用AI模型 %s 未启用或未配置API Key，跳过", traderCfg.Name, modelID)
+			continue
+		}
+		traderConfig.FallbackAIModels = append(traderConfig.FallbackAIModels, model)
 	}
 
 	// 创建trader实例
@@ -0,0 +1,339 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// 警报类型
+const (
+	AlertTypeVolumeSpike   = "volume_spike"       // 当前K线成交量超过近 20 根均量的倍数
+	AlertTypePriceChange   = "price_change_15min" // 15 分钟涨跌幅超过阈值
+	AlertTypeVolumeTrend   = "volume_trend"       // 近 5 根均量相对此前 20 根均量放大
+	AlertTypeRSIOverbought = "rsi_overbought"
+	AlertTypeRSIOversold   = "rsi_oversold"
+)
+
+const (
+	alertKlineInterval = "3m"
+	alertCooldown      = 15 * time.Minute // 同一币种同一类型警报的最小间隔
+	alertMinKlines     = 21               // 计算特征所需的最少K线数量（20 根均量 + 当前K线）
+	alertRecentLimit   = 200              // 内存中保留的最近警报数量
+)
+
+// AlertEngine 行情警报引擎
+// 定期基于 WSMonitor 的 3m K线为所有监控币种计算 SymbolFeatures，按 AlertThresholds 触发警报；
+// 同一币种同一类型的警报在冷却时间内只触发一次，触发的警报交给 OnAlert 注册的处理函数（如持久化）
+type AlertEngine struct {
+	monitor    *WSMonitor
+	thresholds AlertThresholds
+	interval   time.Duration
+	cooldown   time.Duration
+
+	mu        sync.Mutex
+	lastFired map[string]time.Time // symbol|type -> 上次触发时间
+	recent    []Alert
+	handlers  []func(Alert)
+	stopCh    chan struct{}
+}
+
+// NewAlertEngine 创建警报引擎（使用默认阈值，扫描间隔为 config.UpdateInterval）
+func NewAlertEngine(monitor *WSMonitor) *AlertEngine {
+	return &AlertEngine{
+		monitor:    monitor,
+		thresholds: config.AlertThresholds,
+		interval:   time.Duration(config.UpdateInterval) * time.Second,
+		cooldown:   alertCooldown,
+		lastFired:  make(map[string]time.Time),
+	}
+}
+
+// Thresholds 返回当前警报阈值
+func (e *AlertEngine) Thresholds() AlertThresholds {
+	return e.thresholds
+}
+
+// OnAlert 注册警报处理函数（在扫描协程中同步调用）
+func (e *AlertEngine) OnAlert(fn func(Alert)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers = append(e.handlers, fn)
+}
+
+// Start 启动定期扫描
+func (e *AlertEngine) Start() {
+	e.mu.Lock()
+	if e.stopCh != nil {
+		e.mu.Unlock()
+		return
+	}
+	e.stopCh = make(chan struct{})
+	stopCh := e.stopCh
+	e.mu.Unlock()
+
+	log.Printf("🚨 行情警报引擎已启动（扫描间隔 %v，冷却 %v）", e.interval, e.cooldown)
+	go func() {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.Scan(time.Now())
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止扫描
+func (e *AlertEngine) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopCh != nil {
+		close(e.stopCh)
+		e.stopCh = nil
+	}
+}
+
+// Scan 扫描所有已加载 3m K线的币种，返回本次触发的警报
+func (e *AlertEngine) Scan(now time.Time) []Alert {
+	var fired []Alert
+	e.monitor.getKlineDataMap(alertKlineInterval).Range(func(key, value interface{}) bool {
+		symbol := key.(string)
+		features, ok := ComputeSymbolFeatures(symbol, value.([]Kline), now)
+		if !ok {
+			return true
+		}
+		e.monitor.featuresMap.Store(symbol, features)
+		fired = append(fired, e.Evaluate(features)...)
+		return true
+	})
+	return fired
+}
+
+// Evaluate 按阈值检查特征并触发警报（冷却中的警报被忽略）
+func (e *AlertEngine) Evaluate(f SymbolFeatures) []Alert {
+	candidates := checkAlertThresholds(f, e.thresholds)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	e.mu.Lock()
+	var fired []Alert
+	for _, alert := range candidates {
+		key := alert.Symbol + "|" + alert.Type
+		if last, ok := e.lastFired[key]; ok && alert.Timestamp.Sub(last) < e.cooldown {
+			continue
+		}
+		e.lastFired[key] = alert.Timestamp
+		fired = append(fired, alert)
+	}
+	e.recent = append(e.recent, fired...)
+	if len(e.recent) > alertRecentLimit {
+		e.recent = append([]Alert(nil), e.recent[len(e.recent)-alertRecentLimit:]...)
+	}
+	handlers := e.handlers
+	e.mu.Unlock()
+
+	for _, alert := range fired {
+		e.recordStats(alert)
+		for _, fn := range handlers {
+			fn(alert)
+		}
+	}
+	return fired
+}
+
+// recordStats 更新币种统计（警报次数、最近警报时间）
+func (e *AlertEngine) recordStats(alert Alert) {
+	value, _ := e.monitor.symbolStats.LoadOrStore(alert.Symbol, &SymbolStats{})
+	stats := value.(*SymbolStats)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats.LastActiveTime = alert.Timestamp
+	stats.LastAlertTime = alert.Timestamp
+	stats.AlertCount++
+	if alert.Type == AlertTypeVolumeSpike {
+		stats.VolumeSpikeCount++
+	}
+}
+
+// RecentAlerts 返回内存中最近的警报（按时间倒序，symbol 为空时返回全部币种）
+func (e *AlertEngine) RecentAlerts(symbol string, limit int) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var alerts []Alert
+	for i := len(e.recent) - 1; i >= 0 && (limit <= 0 || len(alerts) < limit); i-- {
+		if symbol == "" || e.recent[i].Symbol == symbol {
+			alerts = append(alerts, e.recent[i])
+		}
+	}
+	return alerts
+}
+
+// GetSymbolFeatures 返回最近一次扫描计算的币种特征
+func (m *WSMonitor) GetSymbolFeatures(symbol string) (SymbolFeatures, bool) {
+	value, ok := m.featuresMap.Load(Normalize(symbol))
+	if !ok {
+		return SymbolFeatures{}, false
+	}
+	return value.(SymbolFeatures), true
+}
+
+// checkAlertThresholds 返回特征超过阈值的警报（不考虑冷却）
+func checkAlertThresholds(f SymbolFeatures, t AlertThresholds) []Alert {
+	var alerts []Alert
+	add := func(alertType string, value, threshold float64, message string) {
+		alerts = append(alerts, Alert{
+			Type:      alertType,
+			Symbol:    f.Symbol,
+			Value:     value,
+			Threshold: threshold,
+			Message:   message,
+			Timestamp: f.Timestamp,
+		})
+	}
+
+	if t.VolumeSpike > 0 && f.VolumeRatio20 >= t.VolumeSpike {
+		add(AlertTypeVolumeSpike, f.VolumeRatio20, t.VolumeSpike,
+			fmt.Sprintf("%s 成交量放大至近20根均量的 %.1f 倍", f.Symbol, f.VolumeRatio20))
+	}
+	if t.PriceChange15Min > 0 && math.Abs(f.PriceChange15Min) >= t.PriceChange15Min {
+		direction := "上涨"
+		if f.PriceChange15Min < 0 {
+			direction = "下跌"
+		}
+		add(AlertTypePriceChange, f.PriceChange15Min, t.PriceChange15Min,
+			fmt.Sprintf("%s 15分钟%s %.2f%%", f.Symbol, direction, math.Abs(f.PriceChange15Min)*100))
+	}
+	if t.VolumeTrend > 0 && f.VolumeTrend >= t.VolumeTrend {
+		add(AlertTypeVolumeTrend, f.VolumeTrend, t.VolumeTrend,
+			fmt.Sprintf("%s 近15分钟均量为此前1小时均量的 %.1f 倍", f.Symbol, f.VolumeTrend))
+	}
+	if t.RSIOverbought > 0 && f.RSI14 >= t.RSIOverbought {
+		add(AlertTypeRSIOverbought, f.RSI14, t.RSIOverbought, fmt.Sprintf("%s RSI(14)=%.1f 超买", f.Symbol, f.RSI14))
+	} else if t.RSIOversold > 0 && f.RSI14 > 0 && f.RSI14 <= t.RSIOversold {
+		add(AlertTypeRSIOversold, f.RSI14, t.RSIOversold, fmt.Sprintf("%s RSI(14)=%.1f 超卖", f.Symbol, f.RSI14))
+	}
+	return alerts
+}
+
+// ComputeSymbolFeatures 基于 3m K线（升序）计算币种特征，只使用已收盘K线（最后一根为最近收盘的K线）
+// 涨跌幅和波动率为小数（0.05 = 5%）；已收盘K线不足 alertMinKlines 时返回 false
+func ComputeSymbolFeatures(symbol string, klines []Kline, now time.Time) (SymbolFeatures, bool) {
+	klines = ClosedKlines(klines, now)
+	n := len(klines)
+	if n < alertMinKlines {
+		return SymbolFeatures{}, false
+	}
+	last := klines[n-1]
+	f := SymbolFeatures{
+		Symbol:    Normalize(symbol),
+		Timestamp: now,
+		Price:     last.Close,
+		Volume:    last.Volume,
+		SMA5:      smaClose(klines, 5),
+		SMA10:     smaClose(klines, 10),
+		SMA20:     smaClose(klines, 20),
+	}
+
+	// calculateRSI 在没有下跌时返回 100，价格完全不变时视为中性，避免冷门币误报超买
+	if f.RSI14 = calculateRSI(klines, 14); f.RSI14 == 100 && !hasPriceChange(klines[max(0, n-15):]) {
+		f.RSI14 = 50
+	}
+
+	// 3m K线：15 分钟 = 5 根，1 小时 = 20 根，4 小时 = 80 根
+	f.PriceChange15Min = changeOverBars(klines, 5)
+	f.PriceChange1H = changeOverBars(klines, 20)
+	f.PriceChange4H = changeOverBars(klines, 80)
+
+	// 最近收盘K线成交量相对此前 N 根均量
+	if avg := avgVolume(klines[n-6 : n-1]); avg > 0 {
+		f.VolumeRatio5 = last.Volume / avg
+	}
+	if avg := avgVolume(klines[n-21 : n-1]); avg > 0 {
+		f.VolumeRatio20 = last.Volume / avg
+	}
+	// 近 5 根均量相对此前 20 根均量（K线不足时使用全部可用K线）
+	if prev := avgVolume(klines[max(0, n-25) : n-5]); prev > 0 {
+		f.VolumeTrend = avgVolume(klines[n-5:]) / prev
+	}
+
+	window := klines[n-20:]
+	high, low := window[0].High, window[0].Low
+	returns := make([]float64, 0, len(window)-1)
+	for i, k := range window {
+		high, low = max(high, k.High), min(low, k.Low)
+		if i > 0 && window[i-1].Close > 0 {
+			returns = append(returns, k.Close/window[i-1].Close-1)
+		}
+	}
+	if low > 0 {
+		f.HighLowRatio = high / low
+	}
+	if high > low {
+		f.PositionInRange = (last.Close - low) / (high - low)
+	}
+	f.Volatility20 = stdDev(returns)
+	return f, true
+}
+
+// changeOverBars 最新收盘价相对 bars 根之前收盘价的变化（K线不足时返回 0）
+func changeOverBars(klines []Kline, bars int) float64 {
+	n := len(klines)
+	if n <= bars || klines[n-1-bars].Close <= 0 {
+		return 0
+	}
+	return klines[n-1].Close/klines[n-1-bars].Close - 1
+}
+
+func hasPriceChange(klines []Kline) bool {
+	for i := 1; i < len(klines); i++ {
+		if klines[i].Close != klines[0].Close {
+			return true
+		}
+	}
+	return false
+}
+
+func smaClose(klines []Kline, period int) float64 {
+	if len(klines) < period {
+		return 0
+	}
+	sum := 0.0
+	for _, k := range klines[len(klines)-period:] {
+		sum += k.Close
+	}
+	return sum / float64(period)
+}
+
+func avgVolume(klines []Kline) float64 {
+	if len(klines) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, k := range klines {
+		sum += k.Volume
+	}
+	return sum / float64(len(klines))
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)-1))
+}
@@ -0,0 +1,137 @@
+package market
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// flatKlines 生成价格和成交量不变的 3m K线
+func flatKlines(n int, price, volume float64) []Kline {
+	klines := make([]Kline, n)
+	for i := range klines {
+		klines[i] = Kline{
+			OpenTime: int64(i) * 180000,
+			Open:     price, High: price * 1.001, Low: price * 0.999, Close: price,
+			Volume: volume,
+		}
+	}
+	return klines
+}
+
+// TestComputeSymbolFeatures 测试由K线计算币种特征
+func TestComputeSymbolFeatures(t *testing.T) {
+	now := time.Now()
+	if _, ok := ComputeSymbolFeatures("BTCUSDT", flatKlines(10, 100, 1), now); ok {
+		t.Fatal("K线不足时不应计算特征")
+	}
+
+	klines := flatKlines(30, 100, 10)
+	klines[len(klines)-1].Close = 110
+	klines[len(klines)-1].High = 110
+	klines[len(klines)-1].Volume = 50
+
+	f, ok := ComputeSymbolFeatures("btc", klines, now)
+	if !ok {
+		t.Fatal("expected features")
+	}
+	if f.Symbol != "BTCUSDT" || f.Price != 110 {
+		t.Errorf("symbol/price: %+v", f)
+	}
+	if math.Abs(f.PriceChange15Min-0.10) > 1e-9 || math.Abs(f.PriceChange1H-0.10) > 1e-9 {
+		t.Errorf("price change 15m=%v 1h=%v", f.PriceChange15Min, f.PriceChange1H)
+	}
+	if f.PriceChange4H != 0 {
+		t.Errorf("K线不足 4 小时时涨跌幅应为 0, got %v", f.PriceChange4H)
+	}
+	if f.VolumeRatio5 != 5 || f.VolumeRatio20 != 5 {
+		t.Errorf("volume ratio 5=%v 20=%v", f.VolumeRatio5, f.VolumeRatio20)
+	}
+	if math.Abs(f.VolumeTrend-1.8) > 1e-9 { // (4*10+50)/5 / 10
+		t.Errorf("volume trend %v", f.VolumeTrend)
+	}
+	if f.PositionInRange != 1 || f.RSI14 != 100 {
+		t.Errorf("position=%v rsi=%v", f.PositionInRange, f.RSI14)
+	}
+}
+
+// TestComputeSymbolFeaturesClosedKlines 测试未收盘的当前K线不参与特征计算
+func TestComputeSymbolFeaturesClosedKlines(t *testing.T) {
+	klines := flatKlines(31, 100, 10)
+	for i := range klines {
+		klines[i].CloseTime = klines[i].OpenTime + 179999
+	}
+	forming := &klines[len(klines)-1]
+	forming.Close, forming.High, forming.Volume = 150, 150, 1
+
+	now := time.UnixMilli(forming.OpenTime + 60000)
+	f, ok := ComputeSymbolFeatures("BTCUSDT", klines, now)
+	if !ok {
+		t.Fatal("expected features")
+	}
+	if f.Price != 100 || f.PriceChange15Min != 0 || f.VolumeRatio20 != 1 {
+		t.Errorf("当前K线未收盘时应使用上一根已收盘K线: %+v", f)
+	}
+
+	// 收盘后纳入计算
+	if f, _ := ComputeSymbolFeatures("BTCUSDT", klines, time.UnixMilli(forming.CloseTime)); f.Price != 150 {
+		t.Errorf("已收盘K线应参与计算: %+v", f)
+	}
+}
+
+// TestAlertEngineCooldown 测试警报阈值和冷却
+func TestAlertEngineCooldown(t *testing.T) {
+	e := NewAlertEngine(NewWSMonitor(10))
+	var handled []Alert
+	e.OnAlert(func(a Alert) { handled = append(handled, a) })
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	f := SymbolFeatures{Symbol: "ETHUSDT", Timestamp: now, VolumeRatio20: 4, PriceChange15Min: -0.06, RSI14: 25}
+
+	fired := e.Evaluate(f)
+	types := map[string]bool{}
+	for _, a := range fired {
+		types[a.Type] = true
+	}
+	if len(fired) != 3 || !types[AlertTypeVolumeSpike] || !types[AlertTypePriceChange] || !types[AlertTypeRSIOversold] {
+		t.Fatalf("unexpected alerts: %+v", fired)
+	}
+	if len(handled) != 3 {
+		t.Errorf("handlers should receive fired alerts, got %d", len(handled))
+	}
+
+	f.Timestamp = now.Add(5 * time.Minute)
+	if fired := e.Evaluate(f); len(fired) != 0 {
+		t.Errorf("冷却期内不应重复触发: %+v", fired)
+	}
+
+	f.Timestamp = now.Add(alertCooldown)
+	if fired := e.Evaluate(f); len(fired) != 3 {
+		t.Errorf("冷却结束后应再次触发, got %d", len(fired))
+	}
+
+	if recent := e.RecentAlerts("ETHUSDT", 2); len(recent) != 2 || !recent[0].Timestamp.Equal(f.Timestamp) {
+		t.Errorf("recent alerts: %+v", recent)
+	}
+	value, _ := e.monitor.symbolStats.Load("ETHUSDT")
+	if stats := value.(*SymbolStats); stats.AlertCount != 6 || stats.VolumeSpikeCount != 2 {
+		t.Errorf("symbol stats: %+v", stats)
+	}
+}
+
+// TestAlertEngineScan 测试扫描监控器中的K线
+func TestAlertEngineScan(t *testing.T) {
+	m := NewWSMonitor(10)
+	klines := flatKlines(30, 100, 10)
+	klines[len(klines)-1].Volume = 40
+	m.klineDataMap3m.Store("SOLUSDT", klines)
+	m.klineDataMap3m.Store("XRPUSDT", flatKlines(30, 1, 10))
+
+	fired := NewAlertEngine(m).Scan(time.Now())
+	if len(fired) != 1 || fired[0].Symbol != "SOLUSDT" || fired[0].Type != AlertTypeVolumeSpike {
+		t.Fatalf("unexpected alerts: %+v", fired)
+	}
+	if f, ok := m.GetSymbolFeatures("XRPUSDT"); !ok || f.Price != 1 {
+		t.Errorf("features should be stored for scanned symbols: %+v", f)
+	}
+}
@@ -36,6 +36,7 @@ func NewAPIClient() *APIClient {
 }
 
 func (c *APIClient) GetExchangeInfo() (*ExchangeInfo, error) {
+	throttle(ProviderBinance, 1)
 	url := fmt.Sprintf("%s/fapi/v1/exchangeInfo", baseURL)
 	resp, err := c.client.Get(url)
 	if err != nil {
@@ -57,6 +58,7 @@ func (c *APIClient) GetExchangeInfo() (*ExchangeInfo, error) {
 }
 
 func (c *APIClient) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	throttle(ProviderBinance, binanceKlinesWeight(limit))
 	url := fmt.Sprintf("%s/fapi/v1/klines", baseURL)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -124,6 +126,7 @@ func parseKline(kr KlineResponse) (Kline, error) {
 }
 
 func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
+	throttle(ProviderBinance, 1)
 	url := fmt.Sprintf("%s/fapi/v1/ticker/price", baseURL)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -158,3 +161,61 @@ func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
 
 	return price, nil
 }
+
+// Get24hrTicker 获取24小时行情统计（成交量、成交额、涨跌幅）
+func (c *APIClient) Get24hrTicker(symbol string) (*Ticker24hr, error) {
+	throttle(ProviderBinance, 1)
+	url := fmt.Sprintf("%s/fapi/v1/ticker/24hr", baseURL)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("symbol", symbol)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取24小时行情失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var ticker Ticker24hr
+	if err := json.Unmarshal(body, &ticker); err != nil {
+		return nil, err
+	}
+	return &ticker, nil
+}
+
+// GetAll24hrTickers 获取全部合约的24小时行情统计
+func (c *APIClient) GetAll24hrTickers() ([]*Ticker24hr, error) {
+	throttle(ProviderBinance, binanceAllTickersWeight)
+	resp, err := c.client.Get(fmt.Sprintf("%s/fapi/v1/ticker/24hr", baseURL))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取24小时行情失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var tickers []*Ticker24hr
+	if err := json.Unmarshal(body, &tickers); err != nil {
+		return nil, err
+	}
+	return tickers, nil
+}
@@ -0,0 +1,168 @@
+package market
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// klineCacheMaxAge 当前未收盘K线的最长缓存时间（无论周期长短，收盘价最多滞后该时长）
+	klineCacheMaxAge = 15 * time.Second
+	tickerCacheTTL   = 10 * time.Second
+	oiCacheTTL       = 30 * time.Second
+	symbolsCacheTTL  = 10 * time.Minute
+	cacheSweepEvery  = time.Minute
+)
+
+// dataCache 进程级行情数据缓存：多个交易员请求相同数据时共享结果
+// 同一键的并发请求只发起一次（其余等待并复用结果），结果按键的 TTL 缓存
+type dataCache struct {
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	inflight  map[string]*cacheCall
+	lastSweep time.Time
+	now       func() time.Time
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	coalesced atomic.Int64
+}
+
+type cacheEntry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+type cacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newDataCache() *dataCache {
+	return &dataCache{
+		entries:  make(map[string]*cacheEntry),
+		inflight: make(map[string]*cacheCall),
+		now:      time.Now,
+	}
+}
+
+// marketCache 所有行情数据源共享的缓存
+var marketCache = newDataCache()
+
+// do 返回键对应的缓存结果；未命中时调用 fetch（并发的相同请求合并为一次），成功结果缓存 ttl（ttl<=0 不缓存）
+func (c *dataCache) do(key string, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	now := c.now()
+	if entry, ok := c.entries[key]; ok && now.Before(entry.expiresAt) {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return entry.value, nil
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		c.coalesced.Add(1)
+		<-call.done
+		return call.value, call.err
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.sweepLocked(now)
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	call.value, call.err = fetch()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if call.err == nil && ttl > 0 {
+		c.entries[key] = &cacheEntry{value: call.value, expiresAt: c.now().Add(ttl)}
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.value, call.err
+}
+
+// sweepLocked 定期清理过期条目（调用方持有锁）
+func (c *dataCache) sweepLocked(now time.Time) {
+	if now.Sub(c.lastSweep) < cacheSweepEvery {
+		return
+	}
+	c.lastSweep = now
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// cachedKlines 缓存K线请求（返回副本，调用方可以修改）
+func cachedKlines(key, interval string, fetch func() ([]Kline, error)) ([]Kline, error) {
+	value, err := marketCache.do(key, klineCacheTTL(interval, marketCache.now()), func() (interface{}, error) {
+		return fetch()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]Kline(nil), value.([]Kline)...), nil
+}
+
+// cachedValue 缓存任意请求结果；结果为指针或切片时在多个调用方间共享，需复制后再返回给外部
+func cachedValue[T any](key string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	value, err := marketCache.do(key, ttl, func() (interface{}, error) {
+		return fetch()
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+// klineCacheTTL K线缓存在下一根K线开盘时失效，保证新K线立即可见；当前K线最多缓存 klineCacheMaxAge
+func klineCacheTTL(interval string, now time.Time) time.Duration {
+	d, err := TFDuration(interval)
+	if err != nil {
+		return 0
+	}
+	untilBoundary := d - time.Duration(now.UnixMilli()%d.Milliseconds())*time.Millisecond
+	return min(untilBoundary, klineCacheMaxAge)
+}
+
+// fundingCacheTTL 资金费率缓存到下一个整点（Hyperliquid 每小时结算，Binance/Bybit 每 8 小时）
+func fundingCacheTTL(now time.Time) time.Duration {
+	return min(now.Truncate(time.Hour).Add(time.Hour).Sub(now), frCacheTTL)
+}
+
+// CacheStats 行情缓存与限流统计
+type CacheStats struct {
+	Hits       int64                     `json:"hits"`
+	Misses     int64                     `json:"misses"`
+	Coalesced  int64                     `json:"coalesced"` // 合并到进行中请求的次数
+	HitRatio   float64                   `json:"hit_ratio"` // (命中 + 合并) / 总请求
+	Entries    int                       `json:"entries"`
+	RateLimits map[string]RateLimitStats `json:"rate_limits"`
+}
+
+// GetCacheStats 返回行情缓存命中率和各数据源的限流统计
+func GetCacheStats() CacheStats {
+	marketCache.mu.Lock()
+	entries := len(marketCache.entries)
+	marketCache.mu.Unlock()
+
+	stats := CacheStats{
+		Hits:       marketCache.hits.Load(),
+		Misses:     marketCache.misses.Load(),
+		Coalesced:  marketCache.coalesced.Load(),
+		Entries:    entries,
+		RateLimits: make(map[string]RateLimitStats, len(rateLimiters)),
+	}
+	if total := stats.Hits + stats.Misses + stats.Coalesced; total > 0 {
+		stats.HitRatio = float64(stats.Hits+stats.Coalesced) / float64(total)
+	}
+	for name, limiter := range rateLimiters {
+		stats.RateLimits[name] = limiter.stats()
+	}
+	return stats
+}
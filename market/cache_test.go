@@ -0,0 +1,133 @@
+package market
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestKlineCacheTTL 测试K线缓存按周期边界失效
+func TestKlineCacheTTL(t *testing.T) {
+	boundary := time.Date(2025, 1, 1, 12, 3, 0, 0, time.UTC)
+
+	if got := klineCacheTTL("3m", boundary.Add(-10*time.Second)); got != 10*time.Second {
+		t.Errorf("临近收盘应缓存到下一根K线开盘, got %v", got)
+	}
+	if got := klineCacheTTL("3m", boundary.Add(-90*time.Second)); got != klineCacheMaxAge {
+		t.Errorf("K线中途应缓存 %v, got %v", klineCacheMaxAge, got)
+	}
+	if got := klineCacheTTL("bad", boundary); got != 0 {
+		t.Errorf("无效周期不应缓存, got %v", got)
+	}
+}
+
+// TestFundingCacheTTL 测试资金费率缓存到整点
+func TestFundingCacheTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 50, 0, 0, time.UTC)
+	if got := fundingCacheTTL(now); got != 10*time.Minute {
+		t.Errorf("资金费率应缓存到下一个整点, got %v", got)
+	}
+}
+
+// TestDataCacheCoalescesConcurrentRequests 测试相同键的并发请求合并
+func TestDataCacheCoalescesConcurrentRequests(t *testing.T) {
+	c := newDataCache()
+	var fetches atomic.Int32
+	release := make(chan struct{})
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([]interface{}, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = c.do("k", time.Minute, func() (interface{}, error) {
+				fetches.Add(1)
+				<-release
+				return 42, nil
+			})
+		}(i)
+	}
+
+	// 等待其余请求进入等待状态后再放行
+	deadline := time.Now().Add(2 * time.Second)
+	for c.coalesced.Load()+c.misses.Load() < callers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if fetches.Load() != 1 {
+		t.Fatalf("并发请求应合并为一次, fetches=%d", fetches.Load())
+	}
+	for i, r := range results {
+		if r != 42 {
+			t.Errorf("caller %d got %v", i, r)
+		}
+	}
+
+	if v, _ := c.do("k", time.Minute, func() (interface{}, error) { return 0, nil }); v != 42 {
+		t.Errorf("应命中缓存, got %v", v)
+	}
+	if c.hits.Load() != 1 || c.misses.Load() != 1 || c.coalesced.Load() != callers-1 {
+		t.Errorf("统计错误: hits=%d misses=%d coalesced=%d", c.hits.Load(), c.misses.Load(), c.coalesced.Load())
+	}
+}
+
+// TestDataCacheExpiry 测试缓存过期后重新请求
+func TestDataCacheExpiry(t *testing.T) {
+	c := newDataCache()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	calls := 0
+	fetch := func() (interface{}, error) { calls++; return calls, nil }
+
+	c.do("k", 10*time.Second, fetch)
+	now = now.Add(5 * time.Second)
+	c.do("k", 10*time.Second, fetch)
+	if calls != 1 {
+		t.Fatalf("TTL 内不应重新请求, calls=%d", calls)
+	}
+	now = now.Add(6 * time.Second)
+	if v, _ := c.do("k", 10*time.Second, fetch); v != 2 || calls != 2 {
+		t.Errorf("过期后应重新请求, v=%v calls=%d", v, calls)
+	}
+}
+
+// TestWeightLimiter 测试按权重限流
+func TestWeightLimiter(t *testing.T) {
+	l := newWeightLimiter(60) // 每秒补充 1
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	l.now = func() time.Time { return now }
+	l.sleep = func(d time.Duration) { slept += d }
+
+	if d := l.wait(50); d != 0 {
+		t.Fatalf("令牌充足不应等待, got %v", d)
+	}
+	if d := l.wait(15); d != 5*time.Second {
+		t.Fatalf("缺少 5 个令牌应等待 5s, got %v", d)
+	}
+	now = now.Add(10 * time.Second)
+	if d := l.wait(5); d != 0 {
+		t.Fatalf("补充后不应等待, got %v", d)
+	}
+
+	stats := l.stats()
+	if stats.Requests != 3 || stats.Weight != 70 || stats.Throttled != 1 || stats.WaitedMs != 5000 || slept != 5*time.Second {
+		t.Errorf("统计错误: %+v slept=%v", stats, slept)
+	}
+}
+
+// TestBinanceWeights 测试 Binance 接口权重
+func TestBinanceWeights(t *testing.T) {
+	if binanceKlinesWeight(100) != 2 || binanceKlinesWeight(1500) != 10 {
+		t.Error("K线权重错误")
+	}
+	if binanceDepthWeight(20) != 2 || binanceDepthWeight(1000) != 20 {
+		t.Error("深度权重错误")
+	}
+}
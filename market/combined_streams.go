@@ -76,6 +76,24 @@ func (c *CombinedStreamsClient) BatchSubscribeKlines(symbols []string, interval
 	return nil
 }
 
+// BatchSubscribeStreams 分批订阅任意流（如 btcusdt@depth@100ms）
+func (c *CombinedStreamsClient) BatchSubscribeStreams(streams []string) error {
+	batches := c.splitIntoBatches(streams, c.batchSize)
+
+	for i, batch := range batches {
+		if err := c.subscribeStreams(batch); err != nil {
+			return fmt.Errorf("第 %d 批订阅失败: %v", i+1, err)
+		}
+
+		// 批次间延迟，避免被限制
+		if i < len(batches)-1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	return nil
+}
+
 // splitIntoBatches 将切片分成指定大小的批次
 func (c *CombinedStreamsClient) splitIntoBatches(symbols []string, batchSize int) [][]string {
 	var batches [][]string
@@ -1,37 +1,33 @@
 package market
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"nofx/logger"
 	"math"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
-// FundingRateCache 资金费率缓存结构
+// frCacheTTL 资金费率最长缓存时间
 // Binance Funding Rate 每 8 小时才更新一次，使用 1 小时缓存可显著减少 API 调用
-type FundingRateCache struct {
-	Rate      float64
-	UpdatedAt time.Time
-}
-
-var (
-	fundingRateMap sync.Map // map[string]*FundingRateCache
-	frCacheTTL     = 1 * time.Hour
-)
+var frCacheTTL = 1 * time.Hour
 
-// Get 获取指定代币的市场数据
+// Get 使用默认数据源（Binance）获取指定代币的市场数据
 func Get(symbol string) (*Data, error) {
+	return GetFrom(DefaultProvider(), symbol)
+}
+
+// GetFrom 从指定数据源获取代币的市场数据（3分钟 + 4小时K线）
+func GetFrom(p MarketDataProvider, symbol string) (*Data, error) {
 	var klines3m, klines4h []Kline
 	var err error
+	p = OrDefault(p)
+	stream := p.Stream()
 	// 标准化symbol
 	symbol = Normalize(symbol)
 	// 获取3分钟K线数据 (最近10个)
-	klines3m, err = WSMonitorCli.GetCurrentKlines(symbol, "3m") // 多获取一些用于计算
+	klines3m, err = stream.GetCurrentKlines(symbol, "3m") // 多获取一些用于计算
 	if err != nil {
 		return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
 	}
@@ -43,7 +39,7 @@ func Get(symbol string) (*Data, error) {
 	}
 
 	// 获取4小时K线数据 (最近10个)
-	klines4h, err = WSMonitorCli.GetCurrentKlines(symbol, "4h") // 多获取用于计算指标
+	klines4h, err = stream.GetCurrentKlines(symbol, "4h") // 多获取用于计算指标
 	if err != nil {
 		return nil, fmt.Errorf("获取4小时K线失败: %v", err)
 	}
@@ -82,14 +78,14 @@ func Get(symbol string) (*Data, error) {
 	}
 
 	// 获取OI数据
-	oiData, err := getOpenInterestData(symbol)
+	oiData, err := p.GetOpenInterest(symbol)
 	if err != nil {
 		// OI失败不影响整体,使用默认值
 		oiData = &OIData{Latest: 0, Average: 0}
 	}
 
 	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
+	fundingRate, _ := p.GetFundingRate(symbol)
 
 	// 计算日内系列数据
 	intradayData := calculateIntradaySeries(klines3m)
@@ -112,11 +108,23 @@ func Get(symbol string) (*Data, error) {
 	}, nil
 }
 
-// GetWithTimeframes 获取指定多个时间周期的市场数据
+// GetWithTimeframes 使用默认数据源获取指定多个时间周期的市场数据
 // timeframes: 时间周期列表，如 ["5m", "15m", "1h", "4h"]
 // primaryTimeframe: 主时间周期（用于计算当前指标），默认使用 timeframes[0]
 // count: 每个时间周期的 K 线数量
 func GetWithTimeframes(symbol string, timeframes []string, primaryTimeframe string, count int) (*Data, error) {
+	return GetWithIndicators(symbol, timeframes, primaryTimeframe, count, nil)
+}
+
+// GetWithIndicators 使用默认数据源获取多时间周期市场数据，见 GetWithIndicatorsFrom
+func GetWithIndicators(symbol string, timeframes []string, primaryTimeframe string, count int, settings *IndicatorSettings) (*Data, error) {
+	return GetWithIndicatorsFrom(DefaultProvider(), symbol, timeframes, primaryTimeframe, count, settings)
+}
+
+// GetWithIndicatorsFrom 从指定数据源获取多时间周期市场数据，并按 settings 计算每个周期的命名指标序列
+// settings 为 nil 时与 GetWithTimeframes 相同
+func GetWithIndicatorsFrom(p MarketDataProvider, symbol string, timeframes []string, primaryTimeframe string, count int, settings *IndicatorSettings) (*Data, error) {
+	p = OrDefault(p)
 	symbol = Normalize(symbol)
 
 	if len(timeframes) == 0 {
@@ -146,7 +154,7 @@ func GetWithTimeframes(symbol string, timeframes []string, primaryTimeframe stri
 
 	// 获取每个时间周期的 K 线数据
 	for _, tf := range timeframes {
-		klines, err := WSMonitorCli.GetCurrentKlines(symbol, tf)
+		klines, err := p.Stream().GetCurrentKlines(symbol, tf)
 		if err != nil {
 			logger.Infof("⚠️ 获取 %s %s K线失败: %v", symbol, tf, err)
 			continue
@@ -164,6 +172,7 @@ func GetWithTimeframes(symbol string, timeframes []string, primaryTimeframe stri
 
 		// 计算该时间周期的系列数据
 		seriesData := calculateTimeframeSeries(klines, tf)
+		seriesData.Series = calculateNamedSeries(klines, settings)
 		timeframeData[tf] = seriesData
 	}
 
@@ -189,13 +198,31 @@ func GetWithTimeframes(symbol string, timeframes []string, primaryTimeframe stri
 	priceChange4h := calculatePriceChangeByBars(primaryKlines, primaryTimeframe, 240) // 4小时
 
 	// 获取OI数据
-	oiData, err := getOpenInterestData(symbol)
+	oiData, err := p.GetOpenInterest(symbol)
 	if err != nil {
 		oiData = &OIData{Latest: 0, Average: 0}
 	}
 
 	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
+	fundingRate, _ := p.GetFundingRate(symbol)
+
+	// 获取盘口流动性
+	var liquidity *LiquidityMetrics
+	if settings != nil && settings.OrderBook {
+		if liquidity, err = GetLiquidity(symbol); err != nil {
+			logger.Infof("⚠️ 获取 %s 订单簿失败: %v", symbol, err)
+		}
+	}
+
+	// 获取持仓情绪数据
+	var derivatives *DerivativesData
+	if settings != nil && settings.Derivatives != nil && settings.Derivatives.Enabled() {
+		if dp, ok := p.(DerivativesProvider); ok {
+			if derivatives, err = dp.GetDerivatives(symbol, *settings.Derivatives); err != nil {
+				logger.Infof("⚠️ 获取 %s 持仓情绪数据失败: %v", symbol, err)
+			}
+		}
+	}
 
 	return &Data{
 		Symbol:        symbol,
@@ -208,6 +235,8 @@ func GetWithTimeframes(symbol string, timeframes []string, primaryTimeframe stri
 		OpenInterest:  oiData,
 		FundingRate:   fundingRate,
 		TimeframeData: timeframeData,
+		Liquidity:     liquidity,
+		Derivatives:   derivatives,
 	}, nil
 }
 
@@ -546,92 +575,6 @@ func calculateLongerTermData(klines []Kline) *LongerTermData {
 	return data
 }
 
-// getOpenInterestData 获取OI数据
-func getOpenInterestData(symbol string) (*OIData, error) {
-	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/openInterest?symbol=%s", symbol)
-
-	apiClient := NewAPIClient()
-	resp, err := apiClient.client.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	var result struct {
-		OpenInterest string `json:"openInterest"`
-		Symbol       string `json:"symbol"`
-		Time         int64  `json:"time"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
-	}
-
-	oi, _ := strconv.ParseFloat(result.OpenInterest, 64)
-
-	return &OIData{
-		Latest:  oi,
-		Average: oi * 0.999, // 近似平均值
-	}, nil
-}
-
-// getFundingRate 获取资金费率（优化：使用 1 小时缓存）
-func getFundingRate(symbol string) (float64, error) {
-	// 检查缓存（有效期 1 小时）
-	// Funding Rate 每 8 小时才更新，1 小时缓存非常合理
-	if cached, ok := fundingRateMap.Load(symbol); ok {
-		cache := cached.(*FundingRateCache)
-		if time.Since(cache.UpdatedAt) < frCacheTTL {
-			// 缓存命中，直接返回
-			return cache.Rate, nil
-		}
-	}
-
-	// 缓存过期或不存在，调用 API
-	url := fmt.Sprintf("https://fapi.binance.com/fapi/v1/premiumIndex?symbol=%s", symbol)
-
-	apiClient := NewAPIClient()
-	resp, err := apiClient.client.Get(url)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
-
-	var result struct {
-		Symbol          string `json:"symbol"`
-		MarkPrice       string `json:"markPrice"`
-		IndexPrice      string `json:"indexPrice"`
-		LastFundingRate string `json:"lastFundingRate"`
-		NextFundingTime int64  `json:"nextFundingTime"`
-		InterestRate    string `json:"interestRate"`
-		Time            int64  `json:"time"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, err
-	}
-
-	rate, _ := strconv.ParseFloat(result.LastFundingRate, 64)
-
-	// 更新缓存
-	fundingRateMap.Store(symbol, &FundingRateCache{
-		Rate:      rate,
-		UpdatedAt: time.Now(),
-	})
-
-	return rate, nil
-}
-
 // Format 格式化输出市场数据
 func Format(data *Data) string {
 	var sb strings.Builder
@@ -654,6 +597,8 @@ func Format(data *Data) string {
 
 	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
 
+	sb.WriteString(FormatDerivatives(data.Derivatives))
+
 	if data.IntradaySeries != nil {
 		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
 
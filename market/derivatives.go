@@ -0,0 +1,241 @@
+package market
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDerivativesPeriod   = "5m"
+	defaultDerivativesLookback = 12
+	maxDerivativesLookback     = 500
+)
+
+// derivativesPeriods 持仓情绪数据支持的统计周期（Binance futures/data 接口）
+var derivativesPeriods = map[string]bool{
+	"5m": true, "15m": true, "30m": true, "1h": true, "2h": true, "4h": true, "6h": true, "12h": true, "1d": true,
+}
+
+// DerivativesParams 持仓情绪数据参数
+type DerivativesParams struct {
+	Period   string `json:"period,omitempty"`   // 统计周期：5m/15m/30m/1h/2h/4h/6h/12h/1d，默认 5m
+	Lookback int    `json:"lookback,omitempty"` // 聚合的周期数，默认 12（5m × 12 = 1 小时）
+}
+
+// WithDefaults 填充默认值
+func (p DerivativesParams) WithDefaults() DerivativesParams {
+	if p.Period == "" {
+		p.Period = defaultDerivativesPeriod
+	}
+	if p.Lookback <= 0 {
+		p.Lookback = defaultDerivativesLookback
+	}
+	p.Lookback = min(p.Lookback, maxDerivativesLookback)
+	return p
+}
+
+// Window 聚合窗口时长
+func (p DerivativesParams) Window() time.Duration {
+	p = p.WithDefaults()
+	d, _ := TFDuration(p.Period)
+	return d * time.Duration(p.Lookback)
+}
+
+// Validate 检查统计周期
+func (p DerivativesParams) Validate() error {
+	if p.Period != "" && !derivativesPeriods[p.Period] {
+		return fmt.Errorf("不支持的持仓情绪统计周期: %s", p.Period)
+	}
+	if p.Lookback < 0 || p.Lookback > maxDerivativesLookback {
+		return fmt.Errorf("持仓情绪聚合周期数必须在 0-%d 之间", maxDerivativesLookback)
+	}
+	return nil
+}
+
+// DerivativesOptions 需要获取的持仓情绪数据
+type DerivativesOptions struct {
+	DerivativesParams
+	LongShortRatio bool `json:"long_short_ratio"` // 大户账户/持仓多空比、全市场账户多空比
+	TakerFlow      bool `json:"taker_flow"`       // 主动买卖量
+	Liquidations   bool `json:"liquidations"`     // 强平订单（仅实时，来自 forceOrder 流）
+}
+
+// Enabled 是否启用任一数据
+func (o DerivativesOptions) Enabled() bool {
+	return o.LongShortRatio || o.TakerFlow || o.Liquidations
+}
+
+// DerivativesProvider 支持持仓情绪数据的行情数据源（目前仅 Binance）
+type DerivativesProvider interface {
+	// GetDerivatives 获取最近 Lookback 个周期的聚合数据
+	GetDerivatives(symbol string, opts DerivativesOptions) (*DerivativesData, error)
+	// GetDerivativesHistory 获取 [start, end] 区间内每个统计周期的数据（用于回测，不含强平）
+	GetDerivativesHistory(symbol string, opts DerivativesOptions, start, end time.Time) ([]DerivativesPoint, error)
+}
+
+// DerivativesPoint 单个统计周期的持仓情绪数据（字段为 0 表示未获取）
+type DerivativesPoint struct {
+	Timestamp          int64   `json:"timestamp"` // 周期时间戳（毫秒）
+	TopAccountRatio    float64 `json:"top_account_ratio,omitempty"`
+	TopAccountLong     float64 `json:"top_account_long,omitempty"` // 多头账户占比（0-1）
+	TopPositionRatio   float64 `json:"top_position_ratio,omitempty"`
+	TopPositionLong    float64 `json:"top_position_long,omitempty"`
+	GlobalAccountRatio float64 `json:"global_account_ratio,omitempty"`
+	GlobalAccountLong  float64 `json:"global_account_long,omitempty"`
+	TakerBuyVolume     float64 `json:"taker_buy_volume,omitempty"`
+	TakerSellVolume    float64 `json:"taker_sell_volume,omitempty"`
+}
+
+// DerivativesData 按窗口聚合的持仓情绪数据
+type DerivativesData struct {
+	Period             string            `json:"period"`
+	Lookback           int               `json:"lookback"`
+	TopAccountRatio    *LongShortRatio   `json:"top_account_ratio,omitempty"`    // 大户账户多空比
+	TopPositionRatio   *LongShortRatio   `json:"top_position_ratio,omitempty"`   // 大户持仓多空比
+	GlobalAccountRatio *LongShortRatio   `json:"global_account_ratio,omitempty"` // 全市场账户多空比
+	TakerFlow          *TakerFlow        `json:"taker_flow,omitempty"`
+	Liquidations       *LiquidationStats `json:"liquidations,omitempty"`
+}
+
+// LongShortRatio 多空比在窗口内的统计
+type LongShortRatio struct {
+	Latest  float64   `json:"latest"`
+	Average float64   `json:"average"`
+	Change  float64   `json:"change"`   // 最新值相对窗口起点的变化
+	LongPct float64   `json:"long_pct"` // 最新多头占比（%）
+	Series  []float64 `json:"series"`   // 窗口内序列（旧 → 新）
+}
+
+// TakerFlow 主动买卖量在窗口内的统计
+type TakerFlow struct {
+	BuyVolume    float64   `json:"buy_volume"`
+	SellVolume   float64   `json:"sell_volume"`
+	BuySellRatio float64   `json:"buy_sell_ratio"` // 窗口内总买量 / 总卖量
+	NetVolume    float64   `json:"net_volume"`     // 买量 - 卖量
+	Series       []float64 `json:"series"`         // 每个周期的买卖比（旧 → 新）
+}
+
+// AggregateDerivatives 聚合最近 lookback 个周期的数据（points 按时间升序）；没有数据时返回 nil
+func AggregateDerivatives(points []DerivativesPoint, opts DerivativesOptions) *DerivativesData {
+	params := opts.WithDefaults()
+	if len(points) > params.Lookback {
+		points = points[len(points)-params.Lookback:]
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	data := &DerivativesData{Period: params.Period, Lookback: params.Lookback}
+	if opts.LongShortRatio {
+		data.TopAccountRatio = aggregateRatio(points, func(p DerivativesPoint) (float64, float64) { return p.TopAccountRatio, p.TopAccountLong })
+		data.TopPositionRatio = aggregateRatio(points, func(p DerivativesPoint) (float64, float64) { return p.TopPositionRatio, p.TopPositionLong })
+		data.GlobalAccountRatio = aggregateRatio(points, func(p DerivativesPoint) (float64, float64) { return p.GlobalAccountRatio, p.GlobalAccountLong })
+	}
+	if opts.TakerFlow {
+		flow := &TakerFlow{}
+		for _, p := range points {
+			if p.TakerBuyVolume == 0 && p.TakerSellVolume == 0 {
+				continue
+			}
+			flow.BuyVolume += p.TakerBuyVolume
+			flow.SellVolume += p.TakerSellVolume
+			ratio := 0.0
+			if p.TakerSellVolume > 0 {
+				ratio = p.TakerBuyVolume / p.TakerSellVolume
+			}
+			flow.Series = append(flow.Series, ratio)
+		}
+		if len(flow.Series) > 0 {
+			if flow.SellVolume > 0 {
+				flow.BuySellRatio = flow.BuyVolume / flow.SellVolume
+			}
+			flow.NetVolume = flow.BuyVolume - flow.SellVolume
+			data.TakerFlow = flow
+		}
+	}
+	if data.TopAccountRatio == nil && data.TopPositionRatio == nil && data.GlobalAccountRatio == nil && data.TakerFlow == nil {
+		return nil
+	}
+	return data
+}
+
+func aggregateRatio(points []DerivativesPoint, field func(DerivativesPoint) (ratio, long float64)) *LongShortRatio {
+	var series []float64
+	var latestLong float64
+	for _, p := range points {
+		if ratio, long := field(p); ratio > 0 {
+			series = append(series, ratio)
+			latestLong = long
+		}
+	}
+	if len(series) == 0 {
+		return nil
+	}
+	sum := 0.0
+	for _, v := range series {
+		sum += v
+	}
+	latest := series[len(series)-1]
+	return &LongShortRatio{
+		Latest:  latest,
+		Average: sum / float64(len(series)),
+		Change:  latest - series[0],
+		LongPct: latestLong * 100,
+		Series:  series,
+	}
+}
+
+// DerivativesAt 返回截至 ts（毫秒）已结束周期的聚合数据（用于回测，points 按时间升序）
+func DerivativesAt(points []DerivativesPoint, opts DerivativesOptions, ts int64) *DerivativesData {
+	period, _ := TFDuration(opts.WithDefaults().Period)
+	// Binance 的周期时间戳为周期起点，周期结束后数据才完整
+	idx := sort.Search(len(points), func(i int) bool {
+		return points[i].Timestamp+period.Milliseconds() > ts
+	})
+	return AggregateDerivatives(points[:idx], opts)
+}
+
+// mergeDerivativesPoints 按时间戳合并多个接口的数据
+func mergeDerivativesPoints(byTime map[int64]*DerivativesPoint) []DerivativesPoint {
+	points := make([]DerivativesPoint, 0, len(byTime))
+	for _, p := range byTime {
+		points = append(points, *p)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp < points[j].Timestamp })
+	return points
+}
+
+// FormatDerivatives 格式化持仓情绪数据（用于 AI Prompt）
+func FormatDerivatives(d *DerivativesData) string {
+	if d == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Positioning & flow (%s periods, last %d):\n\n", d.Period, d.Lookback))
+	writeRatio := func(name string, r *LongShortRatio) {
+		if r == nil {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("%s: latest %.3f (long %.1f%%), avg %.3f, change %+.3f, series %s\n",
+			name, r.Latest, r.LongPct, r.Average, r.Change, formatFloatSlice(r.Series)))
+	}
+	writeRatio("Top trader long/short (accounts)", d.TopAccountRatio)
+	writeRatio("Top trader long/short (positions)", d.TopPositionRatio)
+	writeRatio("Global long/short (accounts)", d.GlobalAccountRatio)
+	if f := d.TakerFlow; f != nil {
+		sb.WriteString(fmt.Sprintf("Taker buy/sell: ratio %.3f, buy %.2f / sell %.2f (net %+.2f), per-period ratio %s\n",
+			f.BuySellRatio, f.BuyVolume, f.SellVolume, f.NetVolume, formatFloatSlice(f.Series)))
+	}
+	if l := d.Liquidations; l != nil {
+		sb.WriteString(fmt.Sprintf("Liquidations (last %d min): longs %d / %.0f USDT, shorts %d / %.0f USDT",
+			l.WindowMinutes, l.LongCount, l.LongUSD, l.ShortCount, l.ShortUSD))
+		if l.LargestUSD > 0 {
+			sb.WriteString(fmt.Sprintf(", largest %.0f USDT", l.LargestUSD))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
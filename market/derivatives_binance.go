@@ -0,0 +1,188 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// binanceFuturesDataLimit futures/data 接口单次最多返回的周期数
+	binanceFuturesDataLimit = 500
+	// binanceFuturesDataHistory futures/data 接口只提供最近 30 天的数据
+	binanceFuturesDataHistory = 30 * 24 * time.Hour
+)
+
+// binanceFuturesDataEndpoint 持仓情绪接口及其写入 DerivativesPoint 的方式
+type binanceFuturesDataEndpoint struct {
+	path  string
+	apply func(p *DerivativesPoint, row map[string]interface{})
+}
+
+var (
+	binanceLongShortEndpoints = []binanceFuturesDataEndpoint{
+		{"/futures/data/topLongShortAccountRatio", func(p *DerivativesPoint, row map[string]interface{}) {
+			p.TopAccountRatio, p.TopAccountLong = rowFloat(row, "longShortRatio"), rowFloat(row, "longAccount")
+		}},
+		{"/futures/data/topLongShortPositionRatio", func(p *DerivativesPoint, row map[string]interface{}) {
+			// 持仓多空比接口的多头占比字段在不同版本中为 longAccount 或 longPosition
+			p.TopPositionRatio, p.TopPositionLong = rowFloat(row, "longShortRatio"), rowFloat(row, "longPosition")
+			if p.TopPositionLong == 0 {
+				p.TopPositionLong = rowFloat(row, "longAccount")
+			}
+		}},
+		{"/futures/data/globalLongShortAccountRatio", func(p *DerivativesPoint, row map[string]interface{}) {
+			p.GlobalAccountRatio, p.GlobalAccountLong = rowFloat(row, "longShortRatio"), rowFloat(row, "longAccount")
+		}},
+	}
+	binanceTakerFlowEndpoint = binanceFuturesDataEndpoint{
+		"/futures/data/takerlongshortRatio", func(p *DerivativesPoint, row map[string]interface{}) {
+			p.TakerBuyVolume, p.TakerSellVolume = rowFloat(row, "buyVol"), rowFloat(row, "sellVol")
+		},
+	}
+)
+
+func rowFloat(row map[string]interface{}, key string) float64 {
+	v, _ := parseFloat(row[key])
+	return v
+}
+
+// derivativesEndpoints 按选项返回需要请求的接口
+func derivativesEndpoints(opts DerivativesOptions) []binanceFuturesDataEndpoint {
+	var endpoints []binanceFuturesDataEndpoint
+	if opts.LongShortRatio {
+		endpoints = append(endpoints, binanceLongShortEndpoints...)
+	}
+	if opts.TakerFlow {
+		endpoints = append(endpoints, binanceTakerFlowEndpoint)
+	}
+	return endpoints
+}
+
+// GetDerivatives 获取最近 Lookback 个周期的多空比、主动买卖量（按周期边界缓存）和强平统计
+func (p *BinanceProvider) GetDerivatives(symbol string, opts DerivativesOptions) (*DerivativesData, error) {
+	symbol = Normalize(symbol)
+	opts.DerivativesParams = opts.WithDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	var data *DerivativesData
+	if endpoints := derivativesEndpoints(opts); len(endpoints) > 0 {
+		key := fmt.Sprintf("%s|derivatives|%s|%s|%d|%t|%t", ProviderBinance, symbol, opts.Period, opts.Lookback, opts.LongShortRatio, opts.TakerFlow)
+		points, err := cachedValue(key, klineCacheTTL(opts.Period, time.Now()), func() ([]DerivativesPoint, error) {
+			byTime := make(map[int64]*DerivativesPoint)
+			for _, endpoint := range endpoints {
+				if err := p.fetchFuturesData(endpoint, symbol, opts.Period, opts.Lookback, 0, 0, byTime); err != nil {
+					return nil, err
+				}
+			}
+			return mergeDerivativesPoints(byTime), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		data = AggregateDerivatives(points, opts)
+	}
+
+	if opts.Liquidations && p.monitor != nil && p.monitor.liquidations != nil {
+		if data == nil {
+			data = &DerivativesData{Period: opts.Period, Lookback: opts.Lookback}
+		}
+		data.Liquidations = p.monitor.liquidations.Stats(symbol, opts.Window())
+	}
+	return data, nil
+}
+
+// GetDerivativesHistory 分页获取区间内每个周期的多空比和主动买卖量
+// Binance 只保留最近 30 天，更早的区间没有数据
+func (p *BinanceProvider) GetDerivativesHistory(symbol string, opts DerivativesOptions, start, end time.Time) ([]DerivativesPoint, error) {
+	symbol = Normalize(symbol)
+	opts.DerivativesParams = opts.WithDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if earliest := time.Now().Add(-binanceFuturesDataHistory); start.Before(earliest) {
+		start = earliest
+	}
+	if !end.After(start) {
+		return nil, nil
+	}
+
+	byTime := make(map[int64]*DerivativesPoint)
+	for _, endpoint := range derivativesEndpoints(opts) {
+		for cursor := start.UnixMilli(); cursor < end.UnixMilli(); {
+			last, n, err := p.fetchFuturesDataPage(endpoint, symbol, opts.Period, binanceFuturesDataLimit, cursor, end.UnixMilli(), byTime)
+			if err != nil {
+				return nil, err
+			}
+			if n < binanceFuturesDataLimit || last < cursor {
+				break
+			}
+			cursor = last + 1
+		}
+	}
+	return mergeDerivativesPoints(byTime), nil
+}
+
+func (p *BinanceProvider) fetchFuturesData(endpoint binanceFuturesDataEndpoint, symbol, period string, limit int, start, end int64, byTime map[int64]*DerivativesPoint) error {
+	_, _, err := p.fetchFuturesDataPage(endpoint, symbol, period, limit, start, end, byTime)
+	return err
+}
+
+// fetchFuturesDataPage 请求一页数据并写入 byTime，返回最后一条的时间戳和条数
+func (p *BinanceProvider) fetchFuturesDataPage(endpoint binanceFuturesDataEndpoint, symbol, period string, limit int, start, end int64, byTime map[int64]*DerivativesPoint) (int64, int, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("period", period)
+	params.Set("limit", strconv.Itoa(limit))
+	if start > 0 {
+		params.Set("startTime", strconv.FormatInt(start, 10))
+	}
+	if end > 0 {
+		params.Set("endTime", strconv.FormatInt(end, 10))
+	}
+
+	throttle(ProviderBinance, 1)
+	resp, err := p.client.client.Get(baseURL + endpoint.path + "?" + params.Encode())
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("binance %s returned status %d: %s", endpoint.path, resp.StatusCode, string(body))
+	}
+	return applyFuturesDataRows(body, endpoint, byTime)
+}
+
+// applyFuturesDataRows 解析 futures/data 接口返回的数组，按时间戳写入 byTime
+func applyFuturesDataRows(body []byte, endpoint binanceFuturesDataEndpoint, byTime map[int64]*DerivativesPoint) (int64, int, error) {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return 0, 0, err
+	}
+	var last int64
+	for _, row := range rows {
+		ts := int64(rowFloat(row, "timestamp"))
+		if ts == 0 {
+			continue
+		}
+		point, ok := byTime[ts]
+		if !ok {
+			point = &DerivativesPoint{Timestamp: ts}
+			byTime[ts] = point
+		}
+		endpoint.apply(point, row)
+		last = max(last, ts)
+	}
+	return last, len(rows), nil
+}
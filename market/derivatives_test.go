@@ -0,0 +1,139 @@
+package market
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestApplyFuturesDataRows 测试解析 futures/data 接口（数值可能为字符串或数字）并按时间戳合并
+func TestApplyFuturesDataRows(t *testing.T) {
+	byTime := make(map[int64]*DerivativesPoint)
+	accounts := `[{"symbol":"BTCUSDT","longShortRatio":"1.5","longAccount":"0.6","shortAccount":"0.4","timestamp":1000},
+		{"symbol":"BTCUSDT","longShortRatio":"2.0","longAccount":"0.6667","shortAccount":"0.3333","timestamp":"2000"}]`
+	taker := `[{"buySellRatio":"1.2","buyVol":"120","sellVol":"100","timestamp":2000}]`
+
+	last, n, err := applyFuturesDataRows([]byte(accounts), binanceLongShortEndpoints[0], byTime)
+	if err != nil || last != 2000 || n != 2 {
+		t.Fatalf("last=%d n=%d err=%v", last, n, err)
+	}
+	if _, _, err := applyFuturesDataRows([]byte(taker), binanceTakerFlowEndpoint, byTime); err != nil {
+		t.Fatal(err)
+	}
+
+	points := mergeDerivativesPoints(byTime)
+	if len(points) != 2 || points[0].Timestamp != 1000 {
+		t.Fatalf("points: %+v", points)
+	}
+	if p := points[1]; p.TopAccountRatio != 2.0 || p.TopAccountLong != 0.6667 || p.TakerBuyVolume != 120 || p.TakerSellVolume != 100 {
+		t.Errorf("merged point: %+v", p)
+	}
+}
+
+// TestAggregateDerivatives 测试按窗口聚合多空比和主动买卖量
+func TestAggregateDerivatives(t *testing.T) {
+	points := []DerivativesPoint{
+		{Timestamp: 1, TopAccountRatio: 1.0, TopAccountLong: 0.5, TakerBuyVolume: 50, TakerSellVolume: 50},
+		{Timestamp: 2, TopAccountRatio: 1.2, TopAccountLong: 0.55, TakerBuyVolume: 100, TakerSellVolume: 50},
+		{Timestamp: 3, TopAccountRatio: 1.5, TopAccountLong: 0.6, TakerBuyVolume: 30, TakerSellVolume: 60},
+	}
+	opts := DerivativesOptions{DerivativesParams: DerivativesParams{Lookback: 2}, LongShortRatio: true, TakerFlow: true}
+
+	d := AggregateDerivatives(points, opts)
+	if d == nil || d.Period != "5m" || d.Lookback != 2 {
+		t.Fatalf("unexpected data: %+v", d)
+	}
+	r := d.TopAccountRatio
+	if r.Latest != 1.5 || r.Average != 1.35 || r.LongPct != 60 || len(r.Series) != 2 {
+		t.Errorf("top account ratio: %+v", r)
+	}
+	if diff := r.Change - 0.3; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("change: %v", r.Change)
+	}
+	if d.TopPositionRatio != nil || d.GlobalAccountRatio != nil {
+		t.Error("ratios without data should be nil")
+	}
+	if f := d.TakerFlow; f.BuyVolume != 130 || f.SellVolume != 110 || f.NetVolume != 20 || len(f.Series) != 2 || f.Series[1] != 0.5 {
+		t.Errorf("taker flow: %+v", f)
+	}
+
+	if AggregateDerivatives(nil, opts) != nil {
+		t.Error("no points should produce nil")
+	}
+}
+
+// TestDerivativesAt 测试回测快照只使用已结束周期的数据
+func TestDerivativesAt(t *testing.T) {
+	period := 5 * time.Minute.Milliseconds()
+	points := []DerivativesPoint{
+		{Timestamp: 0, GlobalAccountRatio: 1.0},
+		{Timestamp: period, GlobalAccountRatio: 2.0},
+	}
+	opts := DerivativesOptions{LongShortRatio: true}
+
+	if d := DerivativesAt(points, opts, period); d == nil || d.GlobalAccountRatio.Latest != 1.0 {
+		t.Errorf("second period has not closed yet: %+v", d)
+	}
+	if d := DerivativesAt(points, opts, 2*period); d == nil || d.GlobalAccountRatio.Latest != 2.0 {
+		t.Errorf("expected latest closed period: %+v", d)
+	}
+	if d := DerivativesAt(points, opts, period-1); d != nil {
+		t.Errorf("no closed periods should produce nil: %+v", d)
+	}
+}
+
+// TestLiquidationTracker 测试强平订单统计和窗口过滤
+func TestLiquidationTracker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newLiquidationTracker()
+	tracker.now = func() time.Time { return now }
+
+	record := func(side, price, qty string, ago time.Duration) {
+		var e forceOrderEvent
+		e.Order.Symbol, e.Order.Side, e.Order.AveragePrice, e.Order.FilledQty = "BTCUSDT", side, price, qty
+		e.Order.TradeTime = now.Add(-ago).UnixMilli()
+		tracker.record(e)
+	}
+	record("SELL", "100", "10", time.Minute)    // 多头强平 1000
+	record("SELL", "100", "30", 10*time.Minute) // 多头强平 3000
+	record("BUY", "100", "5", 2*time.Minute)    // 空头强平 500
+	record("BUY", "100", "50", 2*time.Hour)     // 窗口外
+	record("BUY", "0", "50", time.Minute)       // 无效
+
+	stats := tracker.Stats("btc", time.Hour)
+	if stats.WindowMinutes != 60 || stats.LongCount != 2 || stats.LongUSD != 4000 || stats.ShortCount != 1 || stats.ShortUSD != 500 || stats.LargestUSD != 3000 {
+		t.Errorf("stats: %+v", stats)
+	}
+}
+
+// TestFormatDerivatives 测试持仓情绪数据格式
+func TestFormatDerivatives(t *testing.T) {
+	if FormatDerivatives(nil) != "" {
+		t.Error("nil data should produce empty string")
+	}
+	out := FormatDerivatives(&DerivativesData{
+		Period:          "15m",
+		Lookback:        4,
+		TopAccountRatio: &LongShortRatio{Latest: 1.8, LongPct: 64.3, Average: 1.7, Change: 0.2, Series: []float64{1.6, 1.8}},
+		TakerFlow:       &TakerFlow{BuySellRatio: 1.1, BuyVolume: 110, SellVolume: 100, NetVolume: 10, Series: []float64{1.1}},
+		Liquidations:    &LiquidationStats{WindowMinutes: 60, LongCount: 2, LongUSD: 4000},
+	})
+	for _, want := range []string{"15m periods, last 4", "Top trader long/short (accounts): latest 1.800 (long 64.3%)", "Taker buy/sell: ratio 1.100", "Liquidations (last 60 min): longs 2 / 4000 USDT"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+// TestDerivativesParamsValidate 测试统计周期校验
+func TestDerivativesParamsValidate(t *testing.T) {
+	if err := (DerivativesParams{Period: "1h", Lookback: 24}).Validate(); err != nil {
+		t.Errorf("valid params rejected: %v", err)
+	}
+	if err := (DerivativesParams{Period: "3m"}).Validate(); err == nil {
+		t.Error("3m period should be rejected")
+	}
+	if w := (DerivativesParams{}).Window(); w != time.Hour {
+		t.Errorf("default window = %v, want 1h", w)
+	}
+}
@@ -13,8 +13,13 @@ const (
 	binanceMaxKlineLimit    = 1500
 )
 
-// GetKlinesRange 拉取指定时间范围内的 K 线序列（闭区间），返回按时间升序排列的数据。
+// GetKlinesRange 使用默认数据源（Binance）拉取指定时间范围内的 K 线序列，返回按时间升序排列的数据。
 func GetKlinesRange(symbol string, timeframe string, start, end time.Time) ([]Kline, error) {
+	return DefaultProvider().GetKlinesRange(symbol, timeframe, start, end)
+}
+
+// GetKlinesRange 拉取指定时间范围内的 Binance 合约 K 线序列（闭区间），返回按时间升序排列的数据。
+func (p *BinanceProvider) GetKlinesRange(symbol string, timeframe string, start, end time.Time) ([]Kline, error) {
 	symbol = Normalize(symbol)
 	normTF, err := NormalizeTimeframe(timeframe)
 	if err != nil {
@@ -33,6 +38,7 @@ func GetKlinesRange(symbol string, timeframe string, start, end time.Time) ([]Kl
 	client := &http.Client{Timeout: 15 * time.Second}
 
 	for cursor < endMs {
+		throttle(ProviderBinance, binanceKlinesWeight(binanceMaxKlineLimit))
 		req, err := http.NewRequest("GET", binanceFuturesKlinesURL, nil)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,691 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// timeframeSeriesPoints 每个周期序列保留的最新数据点数
+const timeframeSeriesPoints = 10
+
+// maxIndicatorPeriod 指标周期上限：K线缓存每个周期只保留 streamKlineLimit 根，
+// 至少需要 周期+1 根K线（RSI/ATR 使用相邻收盘价），更长的周期无法得到任何数值
+const maxIndicatorPeriod = streamKlineLimit - 1
+
+// BollingerParams 布林带参数
+type BollingerParams struct {
+	Period int     `json:"period,omitempty"`  // 默认 20
+	StdDev float64 `json:"std_dev,omitempty"` // 标准差倍数，默认 2
+}
+
+// StochRSIParams 随机RSI参数
+type StochRSIParams struct {
+	RSIPeriod   int `json:"rsi_period,omitempty"`   // 默认 14
+	StochPeriod int `json:"stoch_period,omitempty"` // 默认 14
+	KPeriod     int `json:"k_period,omitempty"`     // %K 平滑，默认 3
+	DPeriod     int `json:"d_period,omitempty"`     // %D 平滑，默认 3
+}
+
+// ADXParams ADX/DMI 参数
+type ADXParams struct {
+	Period int `json:"period,omitempty"` // 默认 14
+}
+
+// SupertrendParams 超级趋势参数
+type SupertrendParams struct {
+	Period     int     `json:"period,omitempty"`     // ATR 周期，默认 10
+	Multiplier float64 `json:"multiplier,omitempty"` // ATR 倍数，默认 3
+}
+
+// IchimokuParams 一目均衡表参数
+type IchimokuParams struct {
+	TenkanPeriod  int `json:"tenkan_period,omitempty"`   // 转换线，默认 9
+	KijunPeriod   int `json:"kijun_period,omitempty"`    // 基准线，默认 26
+	SenkouBPeriod int `json:"senkou_b_period,omitempty"` // 先行带B，默认 52
+}
+
+// DonchianParams 唐奇安通道参数
+type DonchianParams struct {
+	Period int `json:"period,omitempty"` // 默认 20
+}
+
+// IndicatorSettings 周期序列的指标计算配置
+// 周期列表非空时按配置周期计算 EMA/RSI/ATR，扩展指标为 nil（或 false）时不计算
+// 计算结果写入 TimeframeSeriesData.Series，键名见 SeriesKey* 及 EMAKey/RSIKey/ATRKey
+type IndicatorSettings struct {
+	EMAPeriods []int
+	RSIPeriods []int
+	ATRPeriods []int
+
+	Bollinger  *BollingerParams
+	VWAP       bool
+	StochRSI   *StochRSIParams
+	ADX        *ADXParams
+	OBV        bool
+	Supertrend *SupertrendParams
+	Ichimoku   *IchimokuParams
+	Donchian   *DonchianParams
+
+	// OrderBook 是否获取订单簿流动性指标（写入 Data.Liquidity）
+	OrderBook bool
+
+	// Derivatives 获取的持仓情绪数据（写入 Data.Derivatives，数据源不支持时忽略）
+	Derivatives *DerivativesOptions
+}
+
+// 命名序列键
+const (
+	SeriesKeyBollingerUpper  = "bb_upper"
+	SeriesKeyBollingerMiddle = "bb_middle"
+	SeriesKeyBollingerLower  = "bb_lower"
+	SeriesKeyBollingerWidth  = "bb_width" // 带宽（%）
+	SeriesKeyVWAP            = "vwap"
+	SeriesKeyStochRSIK       = "stoch_rsi_k"
+	SeriesKeyStochRSID       = "stoch_rsi_d"
+	SeriesKeyADX             = "adx"
+	SeriesKeyPlusDI          = "plus_di"
+	SeriesKeyMinusDI         = "minus_di"
+	SeriesKeyOBV             = "obv"
+	SeriesKeySupertrend      = "supertrend"
+	SeriesKeySupertrendDir   = "supertrend_dir" // 1 多头 / -1 空头
+	SeriesKeyTenkan          = "ichimoku_tenkan"
+	SeriesKeyKijun           = "ichimoku_kijun"
+	SeriesKeySenkouA         = "ichimoku_span_a" // 当前K线对应的云层（26根前计算的先行带）
+	SeriesKeySenkouB         = "ichimoku_span_b"
+	SeriesKeyDonchianUpper   = "donchian_upper"
+	SeriesKeyDonchianMiddle  = "donchian_middle"
+	SeriesKeyDonchianLower   = "donchian_lower"
+)
+
+// EMAKey 指定周期 EMA 序列的键名，如 ema20
+func EMAKey(period int) string { return fmt.Sprintf("ema%d", period) }
+
+// RSIKey 指定周期 RSI 序列的键名，如 rsi14
+func RSIKey(period int) string { return fmt.Sprintf("rsi%d", period) }
+
+// ATRKey 指定周期 ATR 序列的键名，如 atr14
+func ATRKey(period int) string { return fmt.Sprintf("atr%d", period) }
+
+// WithDefaults 返回补全默认参数后的配置副本
+func (s IndicatorSettings) WithDefaults() IndicatorSettings {
+	if s.Bollinger != nil {
+		p := *s.Bollinger
+		p.Period = defaultInt(p.Period, 20)
+		if p.StdDev <= 0 {
+			p.StdDev = 2
+		}
+		s.Bollinger = &p
+	}
+	if s.StochRSI != nil {
+		p := *s.StochRSI
+		p.RSIPeriod = defaultInt(p.RSIPeriod, 14)
+		p.StochPeriod = defaultInt(p.StochPeriod, 14)
+		p.KPeriod = defaultInt(p.KPeriod, 3)
+		p.DPeriod = defaultInt(p.DPeriod, 3)
+		s.StochRSI = &p
+	}
+	if s.ADX != nil {
+		p := *s.ADX
+		p.Period = defaultInt(p.Period, 14)
+		s.ADX = &p
+	}
+	if s.Supertrend != nil {
+		p := *s.Supertrend
+		p.Period = defaultInt(p.Period, 10)
+		if p.Multiplier <= 0 {
+			p.Multiplier = 3
+		}
+		s.Supertrend = &p
+	}
+	if s.Ichimoku != nil {
+		p := *s.Ichimoku
+		p.TenkanPeriod = defaultInt(p.TenkanPeriod, 9)
+		p.KijunPeriod = defaultInt(p.KijunPeriod, 26)
+		p.SenkouBPeriod = defaultInt(p.SenkouBPeriod, 52)
+		s.Ichimoku = &p
+	}
+	if s.Donchian != nil {
+		p := *s.Donchian
+		p.Period = defaultInt(p.Period, 20)
+		s.Donchian = &p
+	}
+	return s
+}
+
+// Validate 校验指标参数（未设置的参数使用默认值，不视为错误）
+func (s IndicatorSettings) Validate() error {
+	for name, periods := range map[string][]int{"EMA": s.EMAPeriods, "RSI": s.RSIPeriods, "ATR": s.ATRPeriods} {
+		for _, p := range periods {
+			if err := checkPeriod(name, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	s = s.WithDefaults()
+	if s.Bollinger != nil {
+		if err := checkPeriod("布林带", s.Bollinger.Period); err != nil {
+			return err
+		}
+		if s.Bollinger.StdDev > 10 {
+			return fmt.Errorf("布林带标准差倍数不能超过 10")
+		}
+	}
+	if s.StochRSI != nil {
+		for _, p := range []int{s.StochRSI.RSIPeriod, s.StochRSI.StochPeriod, s.StochRSI.KPeriod, s.StochRSI.DPeriod} {
+			if err := checkPeriod("StochRSI", p); err != nil {
+				return err
+			}
+		}
+	}
+	if s.ADX != nil {
+		if err := checkPeriod("ADX", s.ADX.Period); err != nil {
+			return err
+		}
+	}
+	if s.Supertrend != nil {
+		if err := checkPeriod("Supertrend", s.Supertrend.Period); err != nil {
+			return err
+		}
+		if s.Supertrend.Multiplier > 20 {
+			return fmt.Errorf("Supertrend ATR 倍数不能超过 20")
+		}
+	}
+	if s.Ichimoku != nil {
+		p := s.Ichimoku
+		for _, period := range []int{p.TenkanPeriod, p.KijunPeriod, p.SenkouBPeriod} {
+			if err := checkPeriod("一目均衡表", period); err != nil {
+				return err
+			}
+		}
+		if p.TenkanPeriod >= p.KijunPeriod || p.KijunPeriod >= p.SenkouBPeriod {
+			return fmt.Errorf("一目均衡表周期必须满足 转换线 < 基准线 < 先行带B")
+		}
+	}
+	if s.Donchian != nil {
+		if err := checkPeriod("唐奇安通道", s.Donchian.Period); err != nil {
+			return err
+		}
+	}
+	if s.Derivatives != nil {
+		if err := s.Derivatives.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkPeriod(name string, period int) error {
+	if period <= 0 || period > maxIndicatorPeriod {
+		return fmt.Errorf("%s 周期必须在 1-%d 之间: %d", name, maxIndicatorPeriod, period)
+	}
+	return nil
+}
+
+func defaultInt(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// calculateNamedSeries 按配置计算命名指标序列（每个序列保留最新 timeframeSeriesPoints 个有效值）
+func calculateNamedSeries(klines []Kline, settings *IndicatorSettings) map[string][]float64 {
+	if settings == nil || len(klines) == 0 {
+		return nil
+	}
+	s := settings.WithDefaults()
+	closes := ClosePrices(klines)
+	out := make(map[string][]float64)
+	put := func(key string, values []float64) {
+		if tail := tailValid(values, timeframeSeriesPoints); len(tail) > 0 {
+			out[key] = tail
+		}
+	}
+
+	for _, p := range s.EMAPeriods {
+		put(EMAKey(p), EMASeries(closes, p))
+	}
+	for _, p := range s.RSIPeriods {
+		put(RSIKey(p), RSISeries(closes, p))
+	}
+	for _, p := range s.ATRPeriods {
+		put(ATRKey(p), ATRSeries(klines, p))
+	}
+	if s.Bollinger != nil {
+		upper, middle, lower, width := bollingerSeries(closes, s.Bollinger.Period, s.Bollinger.StdDev)
+		put(SeriesKeyBollingerUpper, upper)
+		put(SeriesKeyBollingerMiddle, middle)
+		put(SeriesKeyBollingerLower, lower)
+		put(SeriesKeyBollingerWidth, width)
+	}
+	if s.VWAP {
+		put(SeriesKeyVWAP, vwapSeries(klines))
+	}
+	if s.StochRSI != nil {
+		k, d := stochRSISeries(closes, *s.StochRSI)
+		put(SeriesKeyStochRSIK, k)
+		put(SeriesKeyStochRSID, d)
+	}
+	if s.ADX != nil {
+		adx, plusDI, minusDI := adxSeries(klines, s.ADX.Period)
+		put(SeriesKeyADX, adx)
+		put(SeriesKeyPlusDI, plusDI)
+		put(SeriesKeyMinusDI, minusDI)
+	}
+	if s.OBV {
+		put(SeriesKeyOBV, obvSeries(klines))
+	}
+	if s.Supertrend != nil {
+		line, dir := supertrendSeries(klines, s.Supertrend.Period, s.Supertrend.Multiplier)
+		put(SeriesKeySupertrend, line)
+		put(SeriesKeySupertrendDir, dir)
+	}
+	if s.Ichimoku != nil {
+		tenkan, kijun, spanA, spanB := ichimokuSeries(klines, *s.Ichimoku)
+		put(SeriesKeyTenkan, tenkan)
+		put(SeriesKeyKijun, kijun)
+		put(SeriesKeySenkouA, spanA)
+		put(SeriesKeySenkouB, spanB)
+	}
+	if s.Donchian != nil {
+		upper, middle, lower := donchianSeries(klines, s.Donchian.Period)
+		put(SeriesKeyDonchianUpper, upper)
+		put(SeriesKeyDonchianMiddle, middle)
+		put(SeriesKeyDonchianLower, lower)
+	}
+	return out
+}
+
+// ClosedKlines 去掉尚未收盘的最后一根K线（收盘时间晚于 now），使实盘与回测使用相同的已收盘数据
+// 未设置收盘时间的K线视为已收盘
+func ClosedKlines(klines []Kline, now time.Time) []Kline {
+	if n := len(klines); n > 0 && klines[n-1].CloseTime > now.UnixMilli() {
+		return klines[:n-1]
+	}
+	return klines
+}
+
+// 以下序列函数返回与 K 线等长的序列，预热阶段为 NaN
+
+// ClosePrices 收盘价序列
+func ClosePrices(klines []Kline) []float64 {
+	closes := make([]float64, len(klines))
+	for i, k := range klines {
+		closes[i] = k.Close
+	}
+	return closes
+}
+
+func nanSeries(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	return values
+}
+
+// tailValid 取最后 n 个位置中的有效值
+func tailValid(values []float64, n int) []float64 {
+	start := len(values) - n
+	if start < 0 {
+		start = 0
+	}
+	out := make([]float64, 0, len(values)-start)
+	for _, v := range values[start:] {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// EMASeries EMA 序列（SMA 作为初始值，与 calculateEMA 一致），NaN 输入视为预热
+func EMASeries(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	first := 0
+	for first < len(values) && math.IsNaN(values[first]) {
+		first++
+	}
+	if len(values)-first < period {
+		return out
+	}
+	sum := 0.0
+	for i := first; i < first+period; i++ {
+		sum += values[i]
+	}
+	ema := sum / float64(period)
+	out[first+period-1] = ema
+	multiplier := 2.0 / float64(period+1)
+	for i := first + period; i < len(values); i++ {
+		ema = (values[i]-ema)*multiplier + ema
+		out[i] = ema
+	}
+	return out
+}
+
+// smaSeries 简单移动平均序列，NaN 输入视为预热
+func smaSeries(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	for i := period - 1; i < len(values); i++ {
+		sum := 0.0
+		valid := true
+		for j := i - period + 1; j <= i; j++ {
+			if math.IsNaN(values[j]) {
+				valid = false
+				break
+			}
+			sum += values[j]
+		}
+		if valid {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// RSISeries RSI 序列（Wilder 平滑，与 calculateRSI 一致），NaN 输入视为预热
+func RSISeries(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	first := 0
+	for first < len(values) && math.IsNaN(values[first]) {
+		first++
+	}
+	if len(values)-first <= period {
+		return out
+	}
+	rsi := func(avgGain, avgLoss float64) float64 {
+		if avgLoss == 0 {
+			return 100
+		}
+		return 100 - 100/(1+avgGain/avgLoss)
+	}
+
+	gains, losses := 0.0, 0.0
+	for i := first + 1; i <= first+period; i++ {
+		change := values[i] - values[i-1]
+		if change > 0 {
+			gains += change
+		} else {
+			losses -= change
+		}
+	}
+	avgGain := gains / float64(period)
+	avgLoss := losses / float64(period)
+	out[first+period] = rsi(avgGain, avgLoss)
+	for i := first + period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		gain, loss := math.Max(change, 0), math.Max(-change, 0)
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		out[i] = rsi(avgGain, avgLoss)
+	}
+	return out
+}
+
+// trueRanges 真实波幅序列（首根K线为 NaN）
+func trueRanges(klines []Kline) []float64 {
+	trs := nanSeries(len(klines))
+	for i := 1; i < len(klines); i++ {
+		prevClose := klines[i-1].Close
+		trs[i] = math.Max(klines[i].High-klines[i].Low,
+			math.Max(math.Abs(klines[i].High-prevClose), math.Abs(klines[i].Low-prevClose)))
+	}
+	return trs
+}
+
+// wilderSeries Wilder 平滑（首个值为前 period 个有效值的均值）
+func wilderSeries(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	if period <= 0 {
+		return out
+	}
+	first := 0
+	for first < len(values) && math.IsNaN(values[first]) {
+		first++
+	}
+	if len(values)-first < period {
+		return out
+	}
+	sum := 0.0
+	for i := first; i < first+period; i++ {
+		sum += values[i]
+	}
+	avg := sum / float64(period)
+	out[first+period-1] = avg
+	for i := first + period; i < len(values); i++ {
+		avg = (avg*float64(period-1) + values[i]) / float64(period)
+		out[i] = avg
+	}
+	return out
+}
+
+// ATRSeries ATR 序列（与 calculateATR 一致）
+func ATRSeries(klines []Kline, period int) []float64 {
+	return wilderSeries(trueRanges(klines), period)
+}
+
+// bollingerSeries 布林带：中轨为 SMA，上下轨为 ±k 倍总体标准差，带宽为 (上轨-下轨)/中轨×100
+func bollingerSeries(closes []float64, period int, k float64) (upper, middle, lower, width []float64) {
+	middle = smaSeries(closes, period)
+	upper, lower, width = nanSeries(len(closes)), nanSeries(len(closes)), nanSeries(len(closes))
+	for i := range closes {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		variance := 0.0
+		for j := i - period + 1; j <= i; j++ {
+			d := closes[j] - middle[i]
+			variance += d * d
+		}
+		sd := math.Sqrt(variance / float64(period))
+		upper[i] = middle[i] + k*sd
+		lower[i] = middle[i] - k*sd
+		if middle[i] != 0 {
+			width[i] = (upper[i] - lower[i]) / middle[i] * 100
+		}
+	}
+	return upper, middle, lower, width
+}
+
+// vwapSeries 成交量加权均价（典型价格加权，按 UTC 自然日重置）
+func vwapSeries(klines []Kline) []float64 {
+	out := nanSeries(len(klines))
+	var pv, vol float64
+	var day int64 = -1
+	for i, k := range klines {
+		d := time.UnixMilli(k.OpenTime).UTC().Unix() / 86400
+		if d != day {
+			day = d
+			pv, vol = 0, 0
+		}
+		typical := (k.High + k.Low + k.Close) / 3
+		pv += typical * k.Volume
+		vol += k.Volume
+		if vol > 0 {
+			out[i] = pv / vol
+		}
+	}
+	return out
+}
+
+// stochRSISeries 随机RSI：RSI 在 StochPeriod 窗口内的位置（0-100），%K 为其 SMA，%D 为 %K 的 SMA
+func stochRSISeries(closes []float64, p StochRSIParams) (k, d []float64) {
+	rsi := RSISeries(closes, p.RSIPeriod)
+	raw := nanSeries(len(closes))
+	for i := range rsi {
+		if i < p.StochPeriod-1 {
+			continue
+		}
+		lo, hi := math.Inf(1), math.Inf(-1)
+		valid := true
+		for j := i - p.StochPeriod + 1; j <= i; j++ {
+			if math.IsNaN(rsi[j]) {
+				valid = false
+				break
+			}
+			lo = math.Min(lo, rsi[j])
+			hi = math.Max(hi, rsi[j])
+		}
+		if !valid {
+			continue
+		}
+		if hi == lo {
+			raw[i] = 50
+		} else {
+			raw[i] = (rsi[i] - lo) / (hi - lo) * 100
+		}
+	}
+	k = smaSeries(raw, p.KPeriod)
+	d = smaSeries(k, p.DPeriod)
+	return k, d
+}
+
+// adxSeries ADX/DMI（Wilder）：返回 ADX、+DI、-DI
+func adxSeries(klines []Kline, period int) (adx, plusDI, minusDI []float64) {
+	n := len(klines)
+	plusDM, minusDM := nanSeries(n), nanSeries(n)
+	for i := 1; i < n; i++ {
+		up := klines[i].High - klines[i-1].High
+		down := klines[i-1].Low - klines[i].Low
+		plusDM[i], minusDM[i] = 0, 0
+		if up > down && up > 0 {
+			plusDM[i] = up
+		}
+		if down > up && down > 0 {
+			minusDM[i] = down
+		}
+	}
+	atr := ATRSeries(klines, period)
+	smoothPlus := wilderSeries(plusDM, period)
+	smoothMinus := wilderSeries(minusDM, period)
+
+	plusDI, minusDI = nanSeries(n), nanSeries(n)
+	dx := nanSeries(n)
+	for i := 0; i < n; i++ {
+		if math.IsNaN(atr[i]) || atr[i] == 0 {
+			continue
+		}
+		plusDI[i] = smoothPlus[i] / atr[i] * 100
+		minusDI[i] = smoothMinus[i] / atr[i] * 100
+		if sum := plusDI[i] + minusDI[i]; sum > 0 {
+			dx[i] = math.Abs(plusDI[i]-minusDI[i]) / sum * 100
+		} else {
+			dx[i] = 0
+		}
+	}
+	adx = wilderSeries(dx, period)
+	return adx, plusDI, minusDI
+}
+
+// obvSeries 能量潮（首根K线为 0）
+func obvSeries(klines []Kline) []float64 {
+	out := make([]float64, len(klines))
+	for i := 1; i < len(klines); i++ {
+		out[i] = out[i-1]
+		switch {
+		case klines[i].Close > klines[i-1].Close:
+			out[i] += klines[i].Volume
+		case klines[i].Close < klines[i-1].Close:
+			out[i] -= klines[i].Volume
+		}
+	}
+	return out
+}
+
+// supertrendSeries 超级趋势：返回趋势线与方向（1 多头 / -1 空头）
+func supertrendSeries(klines []Kline, period int, multiplier float64) (line, dir []float64) {
+	n := len(klines)
+	atr := ATRSeries(klines, period)
+	line, dir = nanSeries(n), nanSeries(n)
+	var finalUpper, finalLower float64
+	trend := 0.0
+	for i := 0; i < n; i++ {
+		if math.IsNaN(atr[i]) {
+			continue
+		}
+		mid := (klines[i].High + klines[i].Low) / 2
+		basicUpper := mid + multiplier*atr[i]
+		basicLower := mid - multiplier*atr[i]
+		if trend == 0 {
+			finalUpper, finalLower = basicUpper, basicLower
+			trend = 1
+			if klines[i].Close < mid {
+				trend = -1
+			}
+		} else {
+			prevClose := klines[i-1].Close
+			if basicUpper < finalUpper || prevClose > finalUpper {
+				finalUpper = basicUpper
+			}
+			if basicLower > finalLower || prevClose < finalLower {
+				finalLower = basicLower
+			}
+			if trend < 0 && klines[i].Close > finalUpper {
+				trend = 1
+			} else if trend > 0 && klines[i].Close < finalLower {
+				trend = -1
+			}
+		}
+		dir[i] = trend
+		if trend > 0 {
+			line[i] = finalLower
+		} else {
+			line[i] = finalUpper
+		}
+	}
+	return line, dir
+}
+
+// midpointSeries 窗口内 (最高价+最低价)/2
+func midpointSeries(klines []Kline, period int) []float64 {
+	_, middle, _ := donchianSeries(klines, period)
+	return middle
+}
+
+// ichimokuSeries 一目均衡表：先行带不做前移，返回当前K线对应的云层（即 KijunPeriod 根之前计算的先行带）
+func ichimokuSeries(klines []Kline, p IchimokuParams) (tenkan, kijun, spanA, spanB []float64) {
+	n := len(klines)
+	tenkan = midpointSeries(klines, p.TenkanPeriod)
+	kijun = midpointSeries(klines, p.KijunPeriod)
+	senkouB := midpointSeries(klines, p.SenkouBPeriod)
+	spanA, spanB = nanSeries(n), nanSeries(n)
+	shift := p.KijunPeriod
+	for i := shift; i < n; i++ {
+		j := i - shift
+		if !math.IsNaN(tenkan[j]) && !math.IsNaN(kijun[j]) {
+			spanA[i] = (tenkan[j] + kijun[j]) / 2
+		}
+		spanB[i] = senkouB[j]
+	}
+	return tenkan, kijun, spanA, spanB
+}
+
+// donchianSeries 唐奇安通道：窗口最高价、中轨、最低价
+func donchianSeries(klines []Kline, period int) (upper, middle, lower []float64) {
+	n := len(klines)
+	upper, middle, lower = nanSeries(n), nanSeries(n), nanSeries(n)
+	if period <= 0 {
+		return upper, middle, lower
+	}
+	for i := period - 1; i < n; i++ {
+		hi, lo := math.Inf(-1), math.Inf(1)
+		for j := i - period + 1; j <= i; j++ {
+			hi = math.Max(hi, klines[j].High)
+			lo = math.Min(lo, klines[j].Low)
+		}
+		upper[i], lower[i] = hi, lo
+		middle[i] = (hi + lo) / 2
+	}
+	return upper, middle, lower
+}
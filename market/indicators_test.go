@@ -0,0 +1,164 @@
+package market
+
+import (
+	"math"
+	"testing"
+)
+
+// trendKlines 生成单边上涨（step > 0）或下跌的 K 线
+func trendKlines(count int, start, step float64) []Kline {
+	klines := make([]Kline, count)
+	for i := range klines {
+		c := start + float64(i)*step
+		klines[i] = Kline{
+			OpenTime: int64(i) * 3600_000,
+			Open:     c - step/2,
+			High:     c + 0.5,
+			Low:      c - 0.5,
+			Close:    c,
+			Volume:   100,
+		}
+	}
+	return klines
+}
+
+func lastValue(t *testing.T, series map[string][]float64, key string) float64 {
+	t.Helper()
+	values := series[key]
+	if len(values) == 0 {
+		t.Fatalf("series %s missing", key)
+	}
+	return values[len(values)-1]
+}
+
+// TestSeriesFunctions_MatchScalarIndicators 测试序列版 EMA/RSI/ATR 与原有单值计算一致
+func TestSeriesFunctions_MatchScalarIndicators(t *testing.T) {
+	klines := generateTestKlines(60)
+	closes := ClosePrices(klines)
+
+	checks := []struct {
+		name   string
+		series []float64
+		scalar float64
+	}{
+		{"ema20", EMASeries(closes, 20), calculateEMA(klines, 20)},
+		{"rsi14", RSISeries(closes, 14), calculateRSI(klines, 14)},
+		{"atr14", ATRSeries(klines, 14), calculateATR(klines, 14)},
+	}
+	for _, c := range checks {
+		got := c.series[len(c.series)-1]
+		if math.Abs(got-c.scalar) > 1e-9 {
+			t.Errorf("%s = %.6f, want %.6f", c.name, got, c.scalar)
+		}
+	}
+
+	// 预热阶段为 NaN
+	if ema := EMASeries(closes, 20); !math.IsNaN(ema[18]) || math.IsNaN(ema[19]) {
+		t.Errorf("ema warmup boundary wrong: %v %v", ema[18], ema[19])
+	}
+}
+
+// TestCalculateNamedSeries_ConfiguredPeriods 测试按配置周期生成命名序列
+func TestCalculateNamedSeries_ConfiguredPeriods(t *testing.T) {
+	klines := generateTestKlines(60)
+	series := calculateNamedSeries(klines, &IndicatorSettings{
+		EMAPeriods: []int{9, 21},
+		RSIPeriods: []int{21},
+		ATRPeriods: []int{7},
+	})
+
+	for _, key := range []string{"ema9", "ema21", "rsi21", "atr7"} {
+		if n := len(series[key]); n != timeframeSeriesPoints {
+			t.Errorf("len(%s) = %d, want %d", key, n, timeframeSeriesPoints)
+		}
+	}
+	if _, ok := series["bb_upper"]; ok {
+		t.Error("disabled indicator should not be computed")
+	}
+	if got := calculateNamedSeries(klines, nil); got != nil {
+		t.Errorf("nil settings should produce no series, got %v", got)
+	}
+}
+
+// TestCalculateNamedSeries_TrendIndicators 测试单边上涨时各趋势指标的方向
+func TestCalculateNamedSeries_TrendIndicators(t *testing.T) {
+	klines := trendKlines(120, 100, 1)
+	series := calculateNamedSeries(klines, &IndicatorSettings{
+		Bollinger:  &BollingerParams{},
+		VWAP:       true,
+		StochRSI:   &StochRSIParams{},
+		ADX:        &ADXParams{},
+		OBV:        true,
+		Supertrend: &SupertrendParams{},
+		Ichimoku:   &IchimokuParams{},
+		Donchian:   &DonchianParams{},
+	})
+	price := klines[len(klines)-1].Close
+
+	if upper, lower := lastValue(t, series, SeriesKeyBollingerUpper), lastValue(t, series, SeriesKeyBollingerLower); !(upper > price-10 && lower < price-10) {
+		t.Errorf("bollinger bands should wrap the 20-bar mean: upper=%.2f lower=%.2f", upper, lower)
+	}
+	if mid := lastValue(t, series, SeriesKeyBollingerMiddle); math.Abs(mid-(price-9.5)) > 1e-9 {
+		t.Errorf("bollinger middle = %.4f, want %.4f", mid, price-9.5)
+	}
+	if vwap := lastValue(t, series, SeriesKeyVWAP); vwap >= price {
+		t.Errorf("vwap = %.2f, want below price %.2f in an uptrend", vwap, price)
+	}
+	if adx, plus, minus := lastValue(t, series, SeriesKeyADX), lastValue(t, series, SeriesKeyPlusDI), lastValue(t, series, SeriesKeyMinusDI); adx < 50 || plus <= minus {
+		t.Errorf("adx=%.2f +di=%.2f -di=%.2f, want strong uptrend", adx, plus, minus)
+	}
+	if obv := lastValue(t, series, SeriesKeyOBV); obv != 119*100 {
+		t.Errorf("obv = %.0f, want %d", obv, 119*100)
+	}
+	if dir := lastValue(t, series, SeriesKeySupertrendDir); dir != 1 {
+		t.Errorf("supertrend direction = %.0f, want 1", dir)
+	}
+	if line := lastValue(t, series, SeriesKeySupertrend); line >= price {
+		t.Errorf("supertrend line = %.2f, want below price in an uptrend", line)
+	}
+	if tenkan, kijun := lastValue(t, series, SeriesKeyTenkan), lastValue(t, series, SeriesKeyKijun); tenkan <= kijun {
+		t.Errorf("tenkan=%.2f should be above kijun=%.2f in an uptrend", tenkan, kijun)
+	}
+	if spanA, spanB := lastValue(t, series, SeriesKeySenkouA), lastValue(t, series, SeriesKeySenkouB); spanA <= spanB || spanA >= price {
+		t.Errorf("span A=%.2f span B=%.2f price=%.2f, want bullish cloud below price", spanA, spanB, price)
+	}
+	if upper, lower := lastValue(t, series, SeriesKeyDonchianUpper), lastValue(t, series, SeriesKeyDonchianLower); upper != price+0.5 || lower != price-19-0.5 {
+		t.Errorf("donchian = [%.2f, %.2f]", lower, upper)
+	}
+	// 持续上涨时 RSI 恒为 100，随机RSI 取中值
+	if k := lastValue(t, series, SeriesKeyStochRSIK); k != 50 {
+		t.Errorf("stoch rsi %%K = %.2f, want 50 for flat RSI", k)
+	}
+}
+
+// TestIndicatorSettings_Validate 测试指标参数校验
+func TestIndicatorSettings_Validate(t *testing.T) {
+	valid := IndicatorSettings{EMAPeriods: []int{9, 21}, Bollinger: &BollingerParams{}, Ichimoku: &IchimokuParams{}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := []IndicatorSettings{
+		{EMAPeriods: []int{0}},
+		{RSIPeriods: []int{maxIndicatorPeriod + 1}},
+		{Bollinger: &BollingerParams{StdDev: 50}},
+		{Ichimoku: &IchimokuParams{TenkanPeriod: 30, KijunPeriod: 26}},
+	}
+	for i, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+
+	// 允许的最大周期在K线缓存长度内仍能得到数值
+	klines := make([]Kline, streamKlineLimit)
+	for i := range klines {
+		price := 100 + float64(i%7)
+		klines[i] = Kline{High: price + 1, Low: price - 1, Close: price}
+	}
+	rsi := RSISeries(ClosePrices(klines), maxIndicatorPeriod)
+	atr := ATRSeries(klines, maxIndicatorPeriod)
+	if math.IsNaN(rsi[len(rsi)-1]) || math.IsNaN(atr[len(atr)-1]) {
+		t.Errorf("max period %d should produce values from %d cached klines", maxIndicatorPeriod, streamKlineLimit)
+	}
+}
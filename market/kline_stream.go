@@ -0,0 +1,268 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamKlineLimit 每个币种/周期缓存的K线数量
+	streamKlineLimit = 100
+	// streamRefreshAfter 超过该时间没有实时推送时重新通过 REST 加载
+	streamRefreshAfter   = 15 * time.Second
+	streamPingInterval   = 20 * time.Second
+	streamReconnectAfter = 5 * time.Second
+)
+
+// klineBroadcaster K线实时更新的订阅者管理
+// 消费过慢时新事件会被丢弃，不阻塞行情处理
+type klineBroadcaster struct {
+	listenersMu    sync.RWMutex
+	listeners      map[int]chan KlineUpdate
+	nextListenerID int
+}
+
+// SubscribeKlineUpdates 订阅所有已订阅币种/周期的K线实时更新，返回事件通道和取消函数
+func (b *klineBroadcaster) SubscribeKlineUpdates(buffer int) (<-chan KlineUpdate, func()) {
+	ch := make(chan KlineUpdate, buffer)
+
+	b.listenersMu.Lock()
+	if b.listeners == nil {
+		b.listeners = make(map[int]chan KlineUpdate)
+	}
+	id := b.nextListenerID
+	b.nextListenerID++
+	b.listeners[id] = ch
+	b.listenersMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.listenersMu.Lock()
+			delete(b.listeners, id)
+			b.listenersMu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (b *klineBroadcaster) publishKlineUpdate(update KlineUpdate) {
+	b.listenersMu.RLock()
+	defer b.listenersMu.RUnlock()
+	for _, ch := range b.listeners {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// streamCodec 交易所 WebSocket K线协议
+type streamCodec interface {
+	URL() string
+	SubscribeMessage(symbol, interval string) interface{}
+	PingMessage() interface{}
+	// Decode 解析推送消息（symbol 为 BTCUSDT 形式，interval 为规范化周期），非K线消息返回空
+	Decode(msg []byte) []KlineUpdate
+}
+
+type klineSeries struct {
+	klines     []Kline
+	updatedAt  time.Time // 最近一次 REST 加载或实时推送的时间
+	closedOpen int64     // 已推送收盘事件的K线开盘时间
+}
+
+// klineStream 通用实时K线流：按需通过 REST 加载最近K线，再由交易所 WebSocket 推送增量更新
+// 未提供 codec 时仅按 streamRefreshAfter 定期通过 REST 刷新
+// 交易所不推送收盘标记时，新K线出现即为上一根补发收盘事件
+type klineStream struct {
+	klineBroadcaster
+	name  string
+	fetch func(symbol, interval string, limit int) ([]Kline, error)
+	codec streamCodec
+
+	mu      sync.Mutex
+	series  map[string]*klineSeries
+	conn    *websocket.Conn
+	started bool
+	writeMu sync.Mutex
+}
+
+func newKlineStream(name string, fetch func(symbol, interval string, limit int) ([]Kline, error), codec streamCodec) *klineStream {
+	return &klineStream{
+		name:   name,
+		fetch:  fetch,
+		codec:  codec,
+		series: make(map[string]*klineSeries),
+	}
+}
+
+func streamKey(symbol, interval string) string {
+	return symbol + "|" + interval
+}
+
+// GetCurrentKlines 获取缓存的最近K线（返回副本）
+func (s *klineStream) GetCurrentKlines(symbol, interval string) ([]Kline, error) {
+	symbol = Normalize(symbol)
+	interval, err := NormalizeTimeframe(interval)
+	if err != nil {
+		return nil, err
+	}
+	key := streamKey(symbol, interval)
+
+	s.mu.Lock()
+	if ser, ok := s.series[key]; ok && time.Since(ser.updatedAt) < streamRefreshAfter {
+		result := append([]Kline(nil), ser.klines...)
+		s.mu.Unlock()
+		return result, nil
+	}
+	s.mu.Unlock()
+
+	klines, err := s.fetch(symbol, interval, streamKlineLimit)
+	if err != nil {
+		return nil, fmt.Errorf("获取%v K线失败: %v", interval, err)
+	}
+
+	s.mu.Lock()
+	ser, subscribed := s.series[key]
+	if !subscribed {
+		ser = &klineSeries{}
+		s.series[key] = ser
+	}
+	ser.klines = append([]Kline(nil), klines...)
+	ser.updatedAt = time.Now()
+	conn := s.conn
+	start := s.codec != nil && !s.started
+	if start {
+		s.started = true
+	}
+	s.mu.Unlock()
+
+	if start {
+		go s.run()
+	} else if !subscribed && conn != nil {
+		if err := s.writeJSON(conn, s.codec.SubscribeMessage(symbol, interval)); err != nil {
+			log.Printf("⚠️ %s 动态订阅 %s %s K线失败: %v (使用API数据)", s.name, symbol, interval, err)
+		}
+	}
+	return klines, nil
+}
+
+// run 维持 WebSocket 连接，断线后重连并重新订阅
+func (s *klineStream) run() {
+	for {
+		if err := s.serve(); err != nil {
+			log.Printf("⚠️ %s 行情WebSocket断开: %v，%v 后重连", s.name, err, streamReconnectAfter)
+		}
+		time.Sleep(streamReconnectAfter)
+	}
+}
+
+func (s *klineStream) serve() error {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+	conn, _, err := dialer.Dial(s.codec.URL(), nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.conn = conn
+	keys := make([]string, 0, len(s.series))
+	for key := range s.series {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+	}()
+
+	for _, key := range keys {
+		symbol, interval, _ := strings.Cut(key, "|")
+		if err := s.writeJSON(conn, s.codec.SubscribeMessage(symbol, interval)); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(streamPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.writeJSON(conn, s.codec.PingMessage()); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		for _, update := range s.codec.Decode(msg) {
+			s.apply(update)
+		}
+	}
+}
+
+func (s *klineStream) writeJSON(conn *websocket.Conn, v interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return conn.WriteJSON(v)
+}
+
+// apply 合并一条实时K线到缓存并推送给订阅者
+func (s *klineStream) apply(update KlineUpdate) {
+	s.mu.Lock()
+	ser, ok := s.series[streamKey(update.Symbol, update.Interval)]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+
+	var closedPrev *KlineUpdate
+	n := len(ser.klines)
+	switch {
+	case n > 0 && ser.klines[n-1].OpenTime == update.Kline.OpenTime:
+		ser.klines[n-1] = update.Kline
+	case n == 0 || update.Kline.OpenTime > ser.klines[n-1].OpenTime:
+		if prev := ser.klines; n > 0 && ser.closedOpen != prev[n-1].OpenTime {
+			closedPrev = &KlineUpdate{Symbol: update.Symbol, Interval: update.Interval, Kline: prev[n-1], Closed: true}
+			ser.closedOpen = prev[n-1].OpenTime
+		}
+		ser.klines = append(ser.klines, update.Kline)
+		if len(ser.klines) > streamKlineLimit {
+			ser.klines = ser.klines[len(ser.klines)-streamKlineLimit:]
+		}
+	default:
+		// 早于缓存最新K线的推送直接忽略
+		s.mu.Unlock()
+		return
+	}
+	if update.Closed {
+		ser.closedOpen = update.Kline.OpenTime
+	}
+	ser.updatedAt = time.Now()
+	s.mu.Unlock()
+
+	if closedPrev != nil {
+		s.publishKlineUpdate(*closedPrev)
+	}
+	s.publishKlineUpdate(update)
+}
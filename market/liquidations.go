@@ -0,0 +1,122 @@
+package market
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	liquidationStream    = "!forceOrder@arr"
+	liquidationRetention = 24 * time.Hour
+)
+
+// LiquidationStats 窗口内的强平统计
+// 多头强平 = 强平卖单，空头强平 = 强平买单
+type LiquidationStats struct {
+	WindowMinutes int     `json:"window_minutes"`
+	LongCount     int     `json:"long_count"`
+	LongUSD       float64 `json:"long_usd"`
+	ShortCount    int     `json:"short_count"`
+	ShortUSD      float64 `json:"short_usd"`
+	LargestUSD    float64 `json:"largest_usd"`
+}
+
+type liquidationEvent struct {
+	time     time.Time
+	longSide bool // 被强平的是多头
+	notional float64
+}
+
+// forceOrderEvent Binance 强平订单推送
+type forceOrderEvent struct {
+	Order struct {
+		Symbol       string `json:"s"`
+		Side         string `json:"S"`
+		AveragePrice string `json:"ap"`
+		Price        string `json:"p"`
+		FilledQty    string `json:"z"`
+		TradeTime    int64  `json:"T"`
+	} `json:"o"`
+}
+
+// LiquidationTracker 订阅全市场强平订单流并按币种保留最近 24 小时的强平记录
+// 注意：Binance 每个币种每秒最多推送一条强平订单，统计值是实际强平量的下限
+type LiquidationTracker struct {
+	mu     sync.Mutex
+	events map[string][]liquidationEvent
+	now    func() time.Time
+}
+
+func newLiquidationTracker() *LiquidationTracker {
+	return &LiquidationTracker{events: make(map[string][]liquidationEvent), now: time.Now}
+}
+
+// subscribe 在组合流上订阅全市场强平订单
+func (t *LiquidationTracker) subscribe(client *CombinedStreamsClient) error {
+	ch := client.AddSubscriber(liquidationStream, 1000)
+	go func() {
+		for data := range ch {
+			var event forceOrderEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				log.Printf("解析强平订单失败: %v", err)
+				continue
+			}
+			t.record(event)
+		}
+	}()
+	return client.BatchSubscribeStreams([]string{liquidationStream})
+}
+
+func (t *LiquidationTracker) record(event forceOrderEvent) {
+	o := event.Order
+	price, _ := strconv.ParseFloat(o.AveragePrice, 64)
+	if price == 0 {
+		price, _ = strconv.ParseFloat(o.Price, 64)
+	}
+	qty, _ := strconv.ParseFloat(o.FilledQty, 64)
+	if o.Symbol == "" || price <= 0 || qty <= 0 {
+		return
+	}
+	at := time.UnixMilli(o.TradeTime)
+	if o.TradeTime == 0 {
+		at = t.now()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	events := append(t.events[o.Symbol], liquidationEvent{time: at, longSide: o.Side == "SELL", notional: price * qty})
+	// 清理超过保留时长的记录
+	cutoff := t.now().Add(-liquidationRetention)
+	drop := 0
+	for drop < len(events) && events[drop].time.Before(cutoff) {
+		drop++
+	}
+	t.events[o.Symbol] = events[drop:]
+}
+
+// Stats 返回币种在最近 window 内的强平统计
+func (t *LiquidationTracker) Stats(symbol string, window time.Duration) *LiquidationStats {
+	window = min(window, liquidationRetention)
+	stats := &LiquidationStats{WindowMinutes: int(window.Minutes())}
+	cutoff := t.now().Add(-window)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range t.events[Normalize(symbol)] {
+		if e.time.Before(cutoff) {
+			continue
+		}
+		if e.longSide {
+			stats.LongCount++
+			stats.LongUSD += e.notional
+		} else {
+			stats.ShortCount++
+			stats.ShortUSD += e.notional
+		}
+		stats.LargestUSD = max(stats.LargestUSD, e.notional)
+	}
+	return stats
+}
@@ -10,18 +10,30 @@ import (
 )
 
 type WSMonitor struct {
-	wsClient       *WSClient
-	combinedClient *CombinedStreamsClient
-	symbols        []string
-	featuresMap    sync.Map
-	alertsChan     chan Alert
-	klineDataMap3m sync.Map // 存储每个交易对的K线历史数据
-	klineDataMap4h sync.Map // 存储每个交易对的K线历史数据
-	tickerDataMap  sync.Map // 存储每个交易对的ticker数据
-	batchSize      int
-	filterSymbols  sync.Map // 使用sync.Map来存储需要监控的币种和其状态
-	symbolStats    sync.Map // 存储币种统计信息
-	FilterSymbol   []string //经过筛选的币种
+	wsClient         *WSClient
+	combinedClient   *CombinedStreamsClient
+	symbols          []string
+	featuresMap      sync.Map
+	alertsChan       chan Alert
+	klineDataMap3m   sync.Map // 存储每个交易对的K线历史数据
+	klineDataMap4h   sync.Map // 存储每个交易对的K线历史数据
+	klineDataMaps    sync.Map // 其他动态订阅周期的K线数据 interval -> *sync.Map
+	tickerDataMap    sync.Map // 存储每个交易对的ticker数据
+	batchSize        int
+	filterSymbols    sync.Map            // 使用sync.Map来存储需要监控的币种和其状态
+	symbolStats      sync.Map            // 存储币种统计信息
+	FilterSymbol     []string            //经过筛选的币种
+	orderBooks       *OrderBookStream    // 本地订单簿（未启用深度流时为 nil）
+	liquidations     *LiquidationTracker // 全市场强平订单
+	klineBroadcaster                     // K线实时更新订阅者
+}
+
+// KlineUpdate K线实时更新事件
+type KlineUpdate struct {
+	Symbol   string
+	Interval string
+	Kline    Kline
+	Closed   bool // K线已收盘
 }
 type SymbolStats struct {
 	LastActiveTime   time.Time
@@ -31,17 +43,23 @@ type SymbolStats struct {
 	Score            float64 // 综合评分
 }
 
-var WSMonitorCli *WSMonitor
 var subKlineTime = []string{"3m", "4h"} // 管理订阅流的K线周期
 
+// NewWSMonitor 创建 Binance 合约 WebSocket 监控器，通过 NewBinanceProvider 注册后供行情数据源使用
 func NewWSMonitor(batchSize int) *WSMonitor {
-	WSMonitorCli = &WSMonitor{
+	return &WSMonitor{
 		wsClient:       NewWSClient(),
 		combinedClient: NewCombinedStreamsClient(batchSize),
 		alertsChan:     make(chan Alert, 1000),
+		liquidations:   newLiquidationTracker(),
 		batchSize:      batchSize,
 	}
-	return WSMonitorCli
+}
+
+// EnableOrderBookStream 启动时同时订阅增量深度流，GetOrderBook 优先使用本地订单簿
+func (m *WSMonitor) EnableOrderBookStream() *WSMonitor {
+	m.orderBooks = NewOrderBookStream(m.combinedClient)
+	return m
 }
 
 func (m *WSMonitor) Initialize(coins []string) error {
@@ -163,6 +181,14 @@ func (m *WSMonitor) subscribeAll() error {
 			return err
 		}
 	}
+	if err := m.liquidations.subscribe(m.combinedClient); err != nil {
+		log.Printf("❌ 订阅强平订单流失败: %v", err)
+	}
+	if m.orderBooks != nil {
+		if err := m.orderBooks.Subscribe(m.symbols); err != nil {
+			log.Printf("❌ 订阅深度流失败: %v", err)
+		}
+	}
 	log.Println("所有交易对订阅完成")
 	return nil
 }
@@ -185,7 +211,8 @@ func (m *WSMonitor) getKlineDataMap(_time string) *sync.Map {
 	} else if _time == "4h" {
 		klineDataMap = &m.klineDataMap4h
 	} else {
-		klineDataMap = &sync.Map{}
+		value, _ := m.klineDataMaps.LoadOrStore(_time, &sync.Map{})
+		klineDataMap = value.(*sync.Map)
 	}
 	return klineDataMap
 }
@@ -230,6 +257,8 @@ func (m *WSMonitor) processKlineUpdate(symbol string, wsData KlineWSData, _time
 	}
 
 	klineDataMap.Store(symbol, klines)
+
+	m.publishKlineUpdate(KlineUpdate{Symbol: symbol, Interval: _time, Kline: kline, Closed: wsData.Kline.IsFinal})
 }
 
 func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, error) {
@@ -237,8 +266,7 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 	value, exists := m.getKlineDataMap(duration).Load(symbol)
 	if !exists {
 		// 如果Ws数据未初始化完成时,单独使用api获取 - 兼容性代码 (防止在未初始化完成是,已经有交易员运行)
-		apiClient := NewAPIClient()
-		klines, err := apiClient.GetKlines(symbol, duration, 100)
+		klines, err := binanceKlines(NewAPIClient(), strings.ToUpper(symbol), duration, 100)
 		if err != nil {
 			return nil, fmt.Errorf("获取%v分钟K线失败: %v", duration, err)
 		}
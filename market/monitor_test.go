@@ -0,0 +1,29 @@
+package market
+
+import "testing"
+
+// TestWSMonitor_SubscribeKlineUpdates 测试K线更新广播、收盘标记和取消订阅
+func TestWSMonitor_SubscribeKlineUpdates(t *testing.T) {
+	m := &WSMonitor{}
+	updates, cancel := m.SubscribeKlineUpdates(1)
+
+	var data KlineWSData
+	data.Kline.StartTime = 1000
+	data.Kline.ClosePrice = "101.5"
+	data.Kline.IsFinal = true
+	m.processKlineUpdate("BTCUSDT", data, "3m")
+	// 缓冲已满时丢弃，不阻塞
+	m.processKlineUpdate("BTCUSDT", data, "3m")
+
+	update := <-updates
+	if update.Symbol != "BTCUSDT" || update.Interval != "3m" || update.Kline.Close != 101.5 || !update.Closed {
+		t.Errorf("unexpected update: %+v", update)
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-updates; ok {
+		t.Error("channel should be closed after cancel")
+	}
+	m.processKlineUpdate("BTCUSDT", data, "3m")
+}
@@ -0,0 +1,469 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// orderBookSnapshotLimit REST 深度快照档位数
+	orderBookSnapshotLimit = 100
+	// orderBookStreamSnapshotLimit 本地订单簿初始化时的快照档位数
+	orderBookStreamSnapshotLimit = 1000
+	// orderBookMaxLevels 本地订单簿每侧保留的最大档位数
+	orderBookMaxLevels = 1000
+	// orderBookStaleAfter 本地订单簿超过该时间未更新时改用 REST 快照
+	orderBookStaleAfter = 10 * time.Second
+)
+
+// OrderBookLevel 订单簿档位
+type OrderBookLevel struct {
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+}
+
+// OrderBook 订单簿快照（买盘价格从高到低，卖盘价格从低到高）
+type OrderBook struct {
+	Symbol       string           `json:"symbol"`
+	LastUpdateID int64            `json:"last_update_id"`
+	Bids         []OrderBookLevel `json:"bids"`
+	Asks         []OrderBookLevel `json:"asks"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// LiquidityMetrics 盘口流动性指标（深度以 USDT 名义价值计）
+type LiquidityMetrics struct {
+	BestBid       float64 `json:"best_bid"`
+	BestAsk       float64 `json:"best_ask"`
+	MidPrice      float64 `json:"mid_price"`
+	SpreadBps     float64 `json:"spread_bps"`      // 买卖价差（基点）
+	BidDepth05    float64 `json:"bid_depth_0_5"`   // 中间价下方 0.5% 以内买盘深度
+	AskDepth05    float64 `json:"ask_depth_0_5"`   // 中间价上方 0.5% 以内卖盘深度
+	BidDepth1     float64 `json:"bid_depth_1"`     // 中间价下方 1% 以内买盘深度
+	AskDepth1     float64 `json:"ask_depth_1"`     // 中间价上方 1% 以内卖盘深度
+	Imbalance05   float64 `json:"imbalance_0_5"`   // (买-卖)/(买+卖)，范围 [-1, 1]
+	Imbalance1    float64 `json:"imbalance_1"`     // 同上，±1% 范围
+	FromStream    bool    `json:"from_stream"`     // 是否来自 WebSocket 本地订单簿
+	SnapshotAgeMs int64   `json:"snapshot_age_ms"` // 快照距今时长
+}
+
+// MarketImpact 市价单冲击成本估算
+type MarketImpact struct {
+	Side        string  `json:"side"`         // "buy" | "sell"
+	NotionalUSD float64 `json:"notional_usd"` // 计划成交名义价值
+	FilledUSD   float64 `json:"filled_usd"`   // 可见深度内可成交的名义价值
+	AvgPrice    float64 `json:"avg_price"`    // 预计成交均价
+	ImpactBps   float64 `json:"impact_bps"`   // 成交均价相对中间价的偏离（基点，含半个价差）
+	CostUSD     float64 `json:"cost_usd"`     // 冲击成本 = 名义价值 × 偏离
+	Complete    bool    `json:"complete"`     // 可见深度是否足以完全成交
+}
+
+// GetDepth 获取订单簿深度快照（limit 可选 5/10/20/50/100/500/1000）
+func (c *APIClient) GetDepth(symbol string, limit int) (*OrderBook, error) {
+	throttle(ProviderBinance, binanceDepthWeight(limit))
+	url := fmt.Sprintf("%s/fapi/v1/depth", baseURL)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("symbol", symbol)
+	q.Add("limit", strconv.Itoa(limit))
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取订单簿失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var raw struct {
+		LastUpdateID int64      `json:"lastUpdateId"`
+		Bids         [][]string `json:"bids"`
+		Asks         [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+	bids, err := parseDepthLevels(raw.Bids)
+	if err != nil {
+		return nil, err
+	}
+	asks, err := parseDepthLevels(raw.Asks)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderBook{
+		Symbol:       symbol,
+		LastUpdateID: raw.LastUpdateID,
+		Bids:         bids,
+		Asks:         asks,
+		UpdatedAt:    time.Now(),
+	}, nil
+}
+
+func parseDepthLevels(raw [][]string) ([]OrderBookLevel, error) {
+	levels := make([]OrderBookLevel, 0, len(raw))
+	for _, entry := range raw {
+		if len(entry) < 2 {
+			continue
+		}
+		price, err := strconv.ParseFloat(entry[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("解析档位价格失败: %w", err)
+		}
+		qty, err := strconv.ParseFloat(entry[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("解析档位数量失败: %w", err)
+		}
+		levels = append(levels, OrderBookLevel{Price: price, Quantity: qty})
+	}
+	return levels, nil
+}
+
+// GetOrderBook 获取 Binance 合约订单簿：优先使用已注册监控器维护的本地订单簿，不可用或过期时请求 REST 快照
+func GetOrderBook(symbol string) (*OrderBook, error) {
+	symbol = Normalize(symbol)
+	if p, ok := DefaultProvider().(*BinanceProvider); ok && p.monitor != nil && p.monitor.orderBooks != nil {
+		if book := p.monitor.orderBooks.Get(symbol); book != nil && time.Since(book.UpdatedAt) < orderBookStaleAfter {
+			return book, nil
+		}
+	}
+	return NewAPIClient().GetDepth(symbol, orderBookSnapshotLimit)
+}
+
+// GetLiquidity 获取指定币种的盘口流动性指标
+func GetLiquidity(symbol string) (*LiquidityMetrics, error) {
+	book, err := GetOrderBook(symbol)
+	if err != nil {
+		return nil, err
+	}
+	metrics := book.Metrics()
+	if metrics == nil {
+		return nil, fmt.Errorf("%s 订单簿为空", symbol)
+	}
+	return metrics, nil
+}
+
+// Metrics 计算价差、±0.5%/±1% 深度和买卖盘失衡；任一侧为空时返回 nil
+func (ob *OrderBook) Metrics() *LiquidityMetrics {
+	if ob == nil || len(ob.Bids) == 0 || len(ob.Asks) == 0 {
+		return nil
+	}
+	bestBid, bestAsk := ob.Bids[0].Price, ob.Asks[0].Price
+	mid := (bestBid + bestAsk) / 2
+	if mid <= 0 {
+		return nil
+	}
+	m := &LiquidityMetrics{
+		BestBid:       bestBid,
+		BestAsk:       bestAsk,
+		MidPrice:      mid,
+		SpreadBps:     (bestAsk - bestBid) / mid * 10000,
+		BidDepth05:    depthWithin(ob.Bids, mid*(1-0.005), true),
+		AskDepth05:    depthWithin(ob.Asks, mid*(1+0.005), false),
+		BidDepth1:     depthWithin(ob.Bids, mid*(1-0.01), true),
+		AskDepth1:     depthWithin(ob.Asks, mid*(1+0.01), false),
+		SnapshotAgeMs: time.Since(ob.UpdatedAt).Milliseconds(),
+	}
+	m.Imbalance05 = imbalance(m.BidDepth05, m.AskDepth05)
+	m.Imbalance1 = imbalance(m.BidDepth1, m.AskDepth1)
+	return m
+}
+
+// depthWithin 累计价格不劣于 bound 的档位名义价值
+func depthWithin(levels []OrderBookLevel, bound float64, bids bool) float64 {
+	total := 0.0
+	for _, l := range levels {
+		if (bids && l.Price < bound) || (!bids && l.Price > bound) {
+			break
+		}
+		total += l.Price * l.Quantity
+	}
+	return total
+}
+
+func imbalance(bid, ask float64) float64 {
+	if bid+ask == 0 {
+		return 0
+	}
+	return (bid - ask) / (bid + ask)
+}
+
+// EstimateImpact 估算以市价单成交 notionalUSD 的冲击成本
+// side 为 "buy"（开多/平空）或 "sell"（开空/平多）；可见深度不足时按已成交部分计算并标记 Complete=false
+func (ob *OrderBook) EstimateImpact(side string, notionalUSD float64) (*MarketImpact, error) {
+	levels, err := ob.sideLevels(side)
+	if err != nil {
+		return nil, err
+	}
+	mid := (ob.Bids[0].Price + ob.Asks[0].Price) / 2
+	impact := &MarketImpact{Side: side, NotionalUSD: notionalUSD}
+	if notionalUSD <= 0 {
+		impact.AvgPrice = levels[0].Price
+		impact.Complete = true
+		return impact, nil
+	}
+
+	remaining := notionalUSD
+	quantity := 0.0
+	for _, l := range levels {
+		levelNotional := l.Price * l.Quantity
+		take := math.Min(remaining, levelNotional)
+		quantity += take / l.Price
+		impact.FilledUSD += take
+		remaining -= take
+		if remaining <= 1e-9 {
+			break
+		}
+	}
+	impact.Complete = remaining <= 1e-9
+	if quantity > 0 {
+		impact.AvgPrice = impact.FilledUSD / quantity
+		impact.ImpactBps = math.Abs(impact.AvgPrice-mid) / mid * 10000
+		impact.CostUSD = notionalUSD * impact.ImpactBps / 10000
+	}
+	return impact, nil
+}
+
+// MaxNotionalWithinImpact 冲击成本不超过 maxImpactBps 时可成交的最大名义价值
+func (ob *OrderBook) MaxNotionalWithinImpact(side string, maxImpactBps float64) (float64, error) {
+	levels, err := ob.sideLevels(side)
+	if err != nil {
+		return 0, err
+	}
+	mid := (ob.Bids[0].Price + ob.Asks[0].Price) / 2
+	target := mid * maxImpactBps / 10000
+
+	// 成交均价偏离随成交量单调递增，逐档累加找到越过上限的档位后在该档内求解
+	notional, quantity := 0.0, 0.0
+	for _, l := range levels {
+		levelDev := math.Abs(l.Price - mid)
+		if levelDev <= target {
+			notional += l.Price * l.Quantity
+			quantity += l.Quantity
+			continue
+		}
+		// 在该档成交 q 后：|notional + q·p - (quantity + q)·mid| = target·(quantity + q)
+		// 买入: notional - quantity·mid + q(p - mid) = target(quantity + q)
+		// 卖出: quantity·mid - notional + q(mid - p) = target(quantity + q)
+		currentDev := math.Abs(notional - quantity*mid)
+		q := (target*quantity - currentDev) / (levelDev - target)
+		if q > 0 {
+			q = math.Min(q, l.Quantity)
+			notional += q * l.Price
+		}
+		return notional, nil
+	}
+	return notional, nil
+}
+
+func (ob *OrderBook) sideLevels(side string) ([]OrderBookLevel, error) {
+	if ob == nil || len(ob.Bids) == 0 || len(ob.Asks) == 0 {
+		return nil, fmt.Errorf("订单簿为空")
+	}
+	switch strings.ToLower(side) {
+	case "buy":
+		return ob.Asks, nil
+	case "sell":
+		return ob.Bids, nil
+	default:
+		return nil, fmt.Errorf("无效的成交方向: %s", side)
+	}
+}
+
+// OrderBookStream 通过组合流的增量深度推送维护本地订单簿
+// 按 Binance 合约规则同步：先取 REST 快照，丢弃过期事件，pu 不连续时重新同步
+type OrderBookStream struct {
+	client *CombinedStreamsClient
+	api    *APIClient
+
+	mu    sync.RWMutex
+	books map[string]*localOrderBook
+}
+
+type localOrderBook struct {
+	bids, asks   map[float64]float64
+	lastUpdateID int64
+	prevFinalID  int64
+	synced       bool
+	updatedAt    time.Time
+}
+
+// depthUpdateEvent 增量深度事件
+type depthUpdateEvent struct {
+	Symbol        string     `json:"s"`
+	FirstUpdateID int64      `json:"U"`
+	FinalUpdateID int64      `json:"u"`
+	PrevFinalID   int64      `json:"pu"`
+	Bids          [][]string `json:"b"`
+	Asks          [][]string `json:"a"`
+}
+
+// NewOrderBookStream 创建本地订单簿维护器
+func NewOrderBookStream(client *CombinedStreamsClient) *OrderBookStream {
+	return &OrderBookStream{
+		client: client,
+		api:    NewAPIClient(),
+		books:  make(map[string]*localOrderBook),
+	}
+}
+
+// Subscribe 订阅币种的增量深度流（100ms）
+func (s *OrderBookStream) Subscribe(symbols []string) error {
+	streams := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = Normalize(symbol)
+		stream := fmt.Sprintf("%s@depth@100ms", strings.ToLower(symbol))
+		ch := s.client.AddSubscriber(stream, 500)
+		go s.handleUpdates(symbol, ch)
+		streams = append(streams, stream)
+	}
+	return s.client.BatchSubscribeStreams(streams)
+}
+
+func (s *OrderBookStream) handleUpdates(symbol string, ch <-chan []byte) {
+	for data := range ch {
+		var event depthUpdateEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("解析深度数据失败: %v", err)
+			continue
+		}
+		if err := s.apply(symbol, &event); err != nil {
+			log.Printf("⚠️ %s 本地订单簿同步失败: %v", symbol, err)
+		}
+	}
+}
+
+// apply 应用一条增量事件；未同步时先获取快照
+func (s *OrderBookStream) apply(symbol string, event *depthUpdateEvent) error {
+	s.mu.RLock()
+	book := s.books[symbol]
+	s.mu.RUnlock()
+
+	if book == nil || !book.synced {
+		snapshot, err := s.api.GetDepth(symbol, orderBookStreamSnapshotLimit)
+		if err != nil {
+			return err
+		}
+		book = newLocalOrderBook(snapshot)
+		s.mu.Lock()
+		s.books[symbol] = book
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return book.apply(event)
+}
+
+func newLocalOrderBook(snapshot *OrderBook) *localOrderBook {
+	book := &localOrderBook{
+		bids:         make(map[float64]float64, len(snapshot.Bids)),
+		asks:         make(map[float64]float64, len(snapshot.Asks)),
+		lastUpdateID: snapshot.LastUpdateID,
+		synced:       true,
+		updatedAt:    snapshot.UpdatedAt,
+	}
+	for _, l := range snapshot.Bids {
+		book.bids[l.Price] = l.Quantity
+	}
+	for _, l := range snapshot.Asks {
+		book.asks[l.Price] = l.Quantity
+	}
+	return book
+}
+
+// apply 应用增量事件；事件不连续时标记为未同步（下一条事件触发重新获取快照）
+func (b *localOrderBook) apply(event *depthUpdateEvent) error {
+	if event.FinalUpdateID < b.lastUpdateID {
+		return nil // 快照之前的事件
+	}
+	if b.prevFinalID == 0 {
+		// 快照后的第一条事件必须覆盖快照的 lastUpdateId
+		if event.FirstUpdateID > b.lastUpdateID {
+			b.synced = false
+			return fmt.Errorf("快照与增量事件不连续 (U=%d > lastUpdateId=%d)", event.FirstUpdateID, b.lastUpdateID)
+		}
+	} else if event.PrevFinalID != b.prevFinalID {
+		b.synced = false
+		return fmt.Errorf("增量事件不连续 (pu=%d, 期望 %d)", event.PrevFinalID, b.prevFinalID)
+	}
+
+	if err := applyDepthLevels(b.bids, event.Bids); err != nil {
+		return err
+	}
+	if err := applyDepthLevels(b.asks, event.Asks); err != nil {
+		return err
+	}
+	b.prevFinalID = event.FinalUpdateID
+	b.updatedAt = time.Now()
+	return nil
+}
+
+func applyDepthLevels(side map[float64]float64, updates [][]string) error {
+	levels, err := parseDepthLevels(updates)
+	if err != nil {
+		return err
+	}
+	for _, l := range levels {
+		if l.Quantity == 0 {
+			delete(side, l.Price)
+		} else {
+			side[l.Price] = l.Quantity
+		}
+	}
+	return nil
+}
+
+// Get 返回本地订单簿快照；未同步时返回 nil
+func (s *OrderBookStream) Get(symbol string) *OrderBook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	book := s.books[Normalize(symbol)]
+	if book == nil || !book.synced {
+		return nil
+	}
+	return &OrderBook{
+		Symbol:       Normalize(symbol),
+		LastUpdateID: book.prevFinalID,
+		Bids:         sortedLevels(book.bids, true),
+		Asks:         sortedLevels(book.asks, false),
+		UpdatedAt:    book.updatedAt,
+	}
+}
+
+func sortedLevels(side map[float64]float64, desc bool) []OrderBookLevel {
+	levels := make([]OrderBookLevel, 0, len(side))
+	for price, qty := range side {
+		levels = append(levels, OrderBookLevel{Price: price, Quantity: qty})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if desc {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	if len(levels) > orderBookMaxLevels {
+		levels = levels[:orderBookMaxLevels]
+	}
+	return levels
+}
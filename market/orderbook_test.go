@@ -0,0 +1,123 @@
+package market
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// testOrderBook 中间价 100，价差 0.2（20 bps），档位间隔 0.3，买盘每档 10 个、卖盘每档 5 个
+func testOrderBook() *OrderBook {
+	book := &OrderBook{Symbol: "BTCUSDT", UpdatedAt: time.Now()}
+	for i := 0; i < 5; i++ {
+		book.Bids = append(book.Bids, OrderBookLevel{Price: 99.9 - 0.3*float64(i), Quantity: 10})
+		book.Asks = append(book.Asks, OrderBookLevel{Price: 100.1 + 0.3*float64(i), Quantity: 5})
+	}
+	return book
+}
+
+// TestOrderBook_Metrics 测试价差、深度和买卖盘失衡计算
+func TestOrderBook_Metrics(t *testing.T) {
+	m := testOrderBook().Metrics()
+	if m == nil {
+		t.Fatal("metrics should not be nil")
+	}
+	if math.Abs(m.SpreadBps-20) > 1e-6 {
+		t.Errorf("spread = %.4f bps, want 20", m.SpreadBps)
+	}
+	// ±0.5%: 买盘 99.9/99.6 两档，卖盘 100.1/100.4 两档
+	wantBid := 99.9*10 + 99.6*10
+	wantAsk := 100.1*5 + 100.4*5
+	if math.Abs(m.BidDepth05-wantBid) > 1e-6 || math.Abs(m.AskDepth05-wantAsk) > 1e-6 {
+		t.Errorf("depth ±0.5%% = %.2f/%.2f, want %.2f/%.2f", m.BidDepth05, m.AskDepth05, wantBid, wantAsk)
+	}
+	if m.BidDepth1 <= m.BidDepth05 || m.Imbalance1 <= 0 {
+		t.Errorf("unexpected ±1%% metrics: %+v", m)
+	}
+
+	if (&OrderBook{Bids: []OrderBookLevel{{Price: 1, Quantity: 1}}}).Metrics() != nil {
+		t.Error("one-sided book should produce nil metrics")
+	}
+}
+
+// TestOrderBook_EstimateImpact 测试逐档成交的冲击成本估算
+func TestOrderBook_EstimateImpact(t *testing.T) {
+	book := testOrderBook()
+
+	// 买入 1000 USDT：第一档可成交约 500.5，剩余在第二档
+	impact, err := book.EstimateImpact("buy", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !impact.Complete || impact.AvgPrice <= 100.1 || impact.AvgPrice >= 100.4 {
+		t.Errorf("unexpected impact: %+v", impact)
+	}
+	if impact.ImpactBps <= 10 || impact.CostUSD <= 0 {
+		t.Errorf("impact should exceed half spread: %+v", impact)
+	}
+
+	// 超过可见深度
+	impact, _ = book.EstimateImpact("sell", 1_000_000)
+	if impact.Complete {
+		t.Error("impact should be incomplete when depth is exhausted")
+	}
+
+	if _, err := book.EstimateImpact("hold", 100); err == nil {
+		t.Error("expected error for invalid side")
+	}
+}
+
+// TestOrderBook_MaxNotionalWithinImpact 测试冲击上限内的最大可成交金额
+func TestOrderBook_MaxNotionalWithinImpact(t *testing.T) {
+	book := testOrderBook()
+
+	for _, maxBps := range []float64{15, 25, 40} {
+		allowed, err := book.MaxNotionalWithinImpact("buy", maxBps)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		impact, _ := book.EstimateImpact("buy", allowed)
+		if impact.ImpactBps > maxBps+1e-6 {
+			t.Errorf("max %.0f bps: allowed %.2f USDT has impact %.4f bps", maxBps, allowed, impact.ImpactBps)
+		}
+		// 略多于上限金额时冲击应超过上限（深度未耗尽时）
+		if more, _ := book.EstimateImpact("buy", allowed+1); more.Complete && more.ImpactBps <= maxBps {
+			t.Errorf("max %.0f bps: allowed %.2f USDT is not the maximum", maxBps, allowed)
+		}
+	}
+
+	// 上限小于半个价差时无法成交
+	if allowed, _ := book.MaxNotionalWithinImpact("sell", 5); allowed != 0 {
+		t.Errorf("allowed = %.2f, want 0 when spread exceeds limit", allowed)
+	}
+}
+
+// TestLocalOrderBook_Apply 测试增量事件与快照的衔接和连续性检查
+func TestLocalOrderBook_Apply(t *testing.T) {
+	book := newLocalOrderBook(&OrderBook{
+		LastUpdateID: 100,
+		Bids:         []OrderBookLevel{{Price: 99, Quantity: 1}},
+		Asks:         []OrderBookLevel{{Price: 101, Quantity: 1}},
+	})
+
+	// 快照前的事件被忽略
+	if err := book.apply(&depthUpdateEvent{FirstUpdateID: 90, FinalUpdateID: 95, Bids: [][]string{{"98", "5"}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := book.bids[98]; ok {
+		t.Error("stale event should be ignored")
+	}
+
+	// 第一条覆盖快照的事件：新增买盘、删除卖盘
+	if err := book.apply(&depthUpdateEvent{FirstUpdateID: 99, FinalUpdateID: 105, Bids: [][]string{{"98", "5"}}, Asks: [][]string{{"101", "0"}, {"102", "2"}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if book.bids[98] != 5 || book.asks[101] != 0 || book.asks[102] != 2 {
+		t.Errorf("unexpected book: bids=%v asks=%v", book.bids, book.asks)
+	}
+
+	// pu 不连续时标记为未同步
+	if err := book.apply(&depthUpdateEvent{FirstUpdateID: 110, FinalUpdateID: 112, PrevFinalID: 108}); err == nil || book.synced {
+		t.Error("gap should mark book as unsynced")
+	}
+}
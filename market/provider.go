@@ -0,0 +1,126 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 行情数据源名称
+const (
+	ProviderBinance     = "binance"
+	ProviderBybit       = "bybit"
+	ProviderHyperliquid = "hyperliquid"
+)
+
+// MarketDataProvider 交易所行情数据源：K线、24小时行情、资金费率、持仓量、交易对列表和实时K线流
+// symbol 统一使用 BTCUSDT 形式，由各实现转换为交易所自己的标识
+type MarketDataProvider interface {
+	// Name 数据源名称（binance / bybit / hyperliquid）
+	Name() string
+	// GetKlines 获取最近 limit 根K线（按时间升序）
+	GetKlines(symbol, interval string, limit int) ([]Kline, error)
+	// GetKlinesRange 获取指定时间范围内的K线（闭区间，按时间升序）
+	GetKlinesRange(symbol, interval string, start, end time.Time) ([]Kline, error)
+	// GetTicker 获取24小时行情统计
+	GetTicker(symbol string) (*Ticker24hr, error)
+	// GetFundingRate 获取最新资金费率
+	GetFundingRate(symbol string) (float64, error)
+	// GetOpenInterest 获取持仓量
+	GetOpenInterest(symbol string) (*OIData, error)
+	// GetSymbols 获取可交易的 USDT 永续合约列表
+	GetSymbols() ([]string, error)
+	// Stream 实时K线流（缓存最近K线并推送更新）
+	Stream() KlineStream
+}
+
+// KlineStream 实时K线流
+type KlineStream interface {
+	// GetCurrentKlines 获取缓存的最近K线，未订阅的币种/周期会先通过 REST 加载并开始订阅
+	GetCurrentKlines(symbol, interval string) ([]Kline, error)
+	// SubscribeKlineUpdates 订阅K线实时更新，返回事件通道和取消函数
+	SubscribeKlineUpdates(buffer int) (<-chan KlineUpdate, func())
+}
+
+var (
+	providersMu sync.Mutex
+	providers   = make(map[string]MarketDataProvider)
+
+	providerFactories = map[string]func() MarketDataProvider{
+		ProviderBinance:     func() MarketDataProvider { return NewBinanceProvider(nil) },
+		ProviderBybit:       func() MarketDataProvider { return NewBybitProvider() },
+		ProviderHyperliquid: func() MarketDataProvider { return NewHyperliquidProvider() },
+	}
+)
+
+// RegisterProvider 注册（或替换）指定名称的共享数据源实例，如启动时注册带 WebSocket 监控的 Binance 数据源
+func RegisterProvider(p MarketDataProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+// ProviderFor 按名称获取共享数据源实例（首次使用时创建）
+func ProviderFor(name string) (MarketDataProvider, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if p, ok := providers[name]; ok {
+		return p, nil
+	}
+	factory, ok := providerFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的行情数据源: %s", name)
+	}
+	p := factory()
+	providers[name] = p
+	return p, nil
+}
+
+// ValidateProviderName 校验行情数据源名称（空表示跟随交易所）
+func ValidateProviderName(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	if _, ok := providerFactories[name]; !ok {
+		return fmt.Errorf("不支持的行情数据源: %s（可选 binance、bybit、hyperliquid）", name)
+	}
+	return nil
+}
+
+// ProviderForExchange 返回与交易所匹配的行情数据源；没有独立行情实现的交易所（aster、lighter 等）使用 Binance
+func ProviderForExchange(exchange string) MarketDataProvider {
+	name := ProviderBinance
+	switch strings.ToLower(exchange) {
+	case ProviderBybit:
+		name = ProviderBybit
+	case ProviderHyperliquid:
+		name = ProviderHyperliquid
+	}
+	p, _ := ProviderFor(name)
+	return p
+}
+
+// ResolveProvider 优先使用显式指定的数据源，否则按交易所选择
+func ResolveProvider(override, exchange string) (MarketDataProvider, error) {
+	if strings.TrimSpace(override) != "" {
+		return ProviderFor(override)
+	}
+	return ProviderForExchange(exchange), nil
+}
+
+// DefaultProvider 默认数据源（Binance），供未指定交易所的调用方使用
+func DefaultProvider() MarketDataProvider {
+	p, _ := ProviderFor(ProviderBinance)
+	return p
+}
+
+// OrDefault 数据源为 nil 时返回默认数据源
+func OrDefault(p MarketDataProvider) MarketDataProvider {
+	if p == nil {
+		return DefaultProvider()
+	}
+	return p
+}
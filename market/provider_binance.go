@@ -0,0 +1,165 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BinanceProvider Binance USDT 永续合约行情数据源
+// 实时K线使用启动时创建的 WSMonitor；未提供监控器时按需通过 REST 加载并定期刷新
+type BinanceProvider struct {
+	client  *APIClient
+	monitor *WSMonitor
+	stream  KlineStream
+}
+
+// NewBinanceProvider 创建 Binance 数据源；monitor 为 nil 时不使用 WebSocket
+func NewBinanceProvider(monitor *WSMonitor) *BinanceProvider {
+	p := &BinanceProvider{client: NewAPIClient(), monitor: monitor}
+	if monitor != nil {
+		p.stream = monitor
+	} else {
+		p.stream = newKlineStream(ProviderBinance, p.GetKlines, nil)
+	}
+	return p
+}
+
+func (p *BinanceProvider) Name() string { return ProviderBinance }
+
+func (p *BinanceProvider) Stream() KlineStream { return p.stream }
+
+// Monitor 返回 WebSocket 监控器（未启用时为 nil）
+func (p *BinanceProvider) Monitor() *WSMonitor { return p.monitor }
+
+func (p *BinanceProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	return binanceKlines(p.client, Normalize(symbol), interval, limit)
+}
+
+// binanceKlines 通过共享缓存获取 Binance 合约K线（WebSocket 监控器的 REST 回退也使用）
+func binanceKlines(client *APIClient, symbol, interval string, limit int) ([]Kline, error) {
+	key := fmt.Sprintf("%s|klines|%s|%s|%d", ProviderBinance, symbol, interval, limit)
+	return cachedKlines(key, interval, func() ([]Kline, error) {
+		return client.GetKlines(symbol, interval, limit)
+	})
+}
+
+func (p *BinanceProvider) GetTicker(symbol string) (*Ticker24hr, error) {
+	symbol = Normalize(symbol)
+	ticker, err := cachedValue(ProviderBinance+"|ticker|"+symbol, tickerCacheTTL, func() (*Ticker24hr, error) {
+		return p.client.Get24hrTicker(symbol)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := *ticker
+	return &result, nil
+}
+
+func (p *BinanceProvider) GetSymbols() ([]string, error) {
+	symbols, err := cachedValue(ProviderBinance+"|symbols", symbolsCacheTTL, func() ([]string, error) {
+		info, err := p.client.GetExchangeInfo()
+		if err != nil {
+			return nil, err
+		}
+		var symbols []string
+		for _, s := range info.Symbols {
+			if s.Status == "TRADING" && s.ContractType == "PERPETUAL" && strings.HasSuffix(strings.ToUpper(s.Symbol), "USDT") {
+				symbols = append(symbols, s.Symbol)
+			}
+		}
+		return symbols, nil
+	})
+	return append([]string(nil), symbols...), err
+}
+
+// GetOpenInterest 获取OI数据（缓存 oiCacheTTL）
+func (p *BinanceProvider) GetOpenInterest(symbol string) (*OIData, error) {
+	symbol = Normalize(symbol)
+	oi, err := cachedValue(ProviderBinance+"|oi|"+symbol, oiCacheTTL, func() (*OIData, error) {
+		return p.fetchOpenInterest(symbol)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := *oi
+	return &result, nil
+}
+
+func (p *BinanceProvider) fetchOpenInterest(symbol string) (*OIData, error) {
+	throttle(ProviderBinance, 1)
+	url := fmt.Sprintf("%s/fapi/v1/openInterest?symbol=%s", baseURL, symbol)
+
+	resp, err := p.client.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		OpenInterest string `json:"openInterest"`
+		Symbol       string `json:"symbol"`
+		Time         int64  `json:"time"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	oi, _ := strconv.ParseFloat(result.OpenInterest, 64)
+
+	return &OIData{
+		Latest:  oi,
+		Average: oi * 0.999, // 近似平均值
+	}, nil
+}
+
+// GetFundingRate 获取资金费率（缓存到下一个整点，最长 1 小时）
+// Funding Rate 每 8 小时才更新，1 小时缓存非常合理
+func (p *BinanceProvider) GetFundingRate(symbol string) (float64, error) {
+	symbol = Normalize(symbol)
+	return cachedValue(ProviderBinance+"|funding|"+symbol, fundingCacheTTL(time.Now()), func() (float64, error) {
+		return p.fetchFundingRate(symbol)
+	})
+}
+
+func (p *BinanceProvider) fetchFundingRate(symbol string) (float64, error) {
+	throttle(ProviderBinance, 1)
+	url := fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", baseURL, symbol)
+
+	resp, err := p.client.client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Symbol          string `json:"symbol"`
+		MarkPrice       string `json:"markPrice"`
+		IndexPrice      string `json:"indexPrice"`
+		LastFundingRate string `json:"lastFundingRate"`
+		NextFundingTime int64  `json:"nextFundingTime"`
+		InterestRate    string `json:"interestRate"`
+		Time            int64  `json:"time"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	rate, _ := strconv.ParseFloat(result.LastFundingRate, 64)
+	return rate, nil
+}
@@ -0,0 +1,329 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	bybitBaseURL       = "https://api.bybit.com"
+	bybitWSURL         = "wss://stream.bybit.com/v5/public/linear"
+	bybitMaxKlineLimit = 1000
+)
+
+// bybitIntervals 时间周期 -> Bybit K线周期
+var bybitIntervals = map[string]string{
+	"1m": "1", "3m": "3", "5m": "5", "15m": "15", "30m": "30",
+	"1h": "60", "2h": "120", "4h": "240", "6h": "360", "12h": "720", "1d": "D",
+}
+
+// BybitProvider Bybit USDT 永续合约（linear）行情数据源，使用 v5 公共接口
+type BybitProvider struct {
+	baseURL string
+	client  *http.Client
+	stream  *klineStream
+}
+
+// NewBybitProvider 创建 Bybit 数据源
+func NewBybitProvider() *BybitProvider {
+	p := &BybitProvider{baseURL: bybitBaseURL, client: NewAPIClient().client}
+	p.stream = newKlineStream(ProviderBybit, p.GetKlines, bybitCodec{})
+	return p
+}
+
+func (p *BybitProvider) Name() string { return ProviderBybit }
+
+func (p *BybitProvider) Stream() KlineStream { return p.stream }
+
+// get 请求 v5 公共接口并解析 result 字段
+func (p *BybitProvider) get(path string, params url.Values, result interface{}) error {
+	throttle(ProviderBybit, 1)
+	resp, err := p.client.Get(p.baseURL + path + "?" + params.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bybit api returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var envelope struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return err
+	}
+	if envelope.RetCode != 0 {
+		return fmt.Errorf("bybit api error %d: %s", envelope.RetCode, envelope.RetMsg)
+	}
+	return json.Unmarshal(envelope.Result, result)
+}
+
+// fetchKlines 请求一页K线（Bybit 按时间倒序返回，这里转为升序）
+func (p *BybitProvider) fetchKlines(symbol, interval string, limit int, start, end int64) ([]Kline, error) {
+	code, ok := bybitIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("bybit 不支持的时间周期: %s", interval)
+	}
+	duration, _ := TFDuration(interval)
+
+	params := url.Values{}
+	params.Set("category", "linear")
+	params.Set("symbol", symbol)
+	params.Set("interval", code)
+	params.Set("limit", strconv.Itoa(limit))
+	if start > 0 {
+		params.Set("start", strconv.FormatInt(start, 10))
+	}
+	if end > 0 {
+		params.Set("end", strconv.FormatInt(end, 10))
+	}
+
+	var result struct {
+		List [][]string `json:"list"`
+	}
+	if err := p.get("/v5/market/kline", params, &result); err != nil {
+		return nil, err
+	}
+
+	klines := make([]Kline, 0, len(result.List))
+	for _, item := range result.List {
+		if len(item) < 7 {
+			continue
+		}
+		openTime, _ := strconv.ParseInt(item[0], 10, 64)
+		k := Kline{OpenTime: openTime, CloseTime: openTime + duration.Milliseconds() - 1}
+		k.Open, _ = strconv.ParseFloat(item[1], 64)
+		k.High, _ = strconv.ParseFloat(item[2], 64)
+		k.Low, _ = strconv.ParseFloat(item[3], 64)
+		k.Close, _ = strconv.ParseFloat(item[4], 64)
+		k.Volume, _ = strconv.ParseFloat(item[5], 64)
+		k.QuoteVolume, _ = strconv.ParseFloat(item[6], 64)
+		klines = append(klines, k)
+	}
+	sort.Slice(klines, func(i, j int) bool { return klines[i].OpenTime < klines[j].OpenTime })
+	return klines, nil
+}
+
+func (p *BybitProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	interval, err := NormalizeTimeframe(interval)
+	if err != nil {
+		return nil, err
+	}
+	symbol, limit = Normalize(symbol), min(limit, bybitMaxKlineLimit)
+	key := fmt.Sprintf("%s|klines|%s|%s|%d", ProviderBybit, symbol, interval, limit)
+	return cachedKlines(key, interval, func() ([]Kline, error) {
+		return p.fetchKlines(symbol, interval, limit, 0, 0)
+	})
+}
+
+func (p *BybitProvider) GetKlinesRange(symbol, interval string, start, end time.Time) ([]Kline, error) {
+	symbol = Normalize(symbol)
+	interval, err := NormalizeTimeframe(interval)
+	if err != nil {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+
+	var all []Kline
+	endMs := end.UnixMilli()
+	for cursor := start.UnixMilli(); cursor < endMs; {
+		batch, err := p.fetchKlines(symbol, interval, bybitMaxKlineLimit, cursor, endMs)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		all = append(all, batch...)
+		cursor = batch[len(batch)-1].CloseTime + 1
+		if len(batch) < bybitMaxKlineLimit {
+			break
+		}
+	}
+	return all, nil
+}
+
+type bybitTicker struct {
+	Symbol       string `json:"symbol"`
+	LastPrice    string `json:"lastPrice"`
+	PrevPrice24h string `json:"prevPrice24h"`
+	Price24hPcnt string `json:"price24hPcnt"`
+	Volume24h    string `json:"volume24h"`
+	Turnover24h  string `json:"turnover24h"`
+	FundingRate  string `json:"fundingRate"`
+	OpenInterest string `json:"openInterest"`
+}
+
+// ticker 获取行情（含资金费率和持仓量，短时间缓存供多个字段共用；返回值只读）
+func (p *BybitProvider) ticker(symbol string) (*bybitTicker, error) {
+	symbol = Normalize(symbol)
+	return cachedValue(ProviderBybit+"|ticker|"+symbol, tickerCacheTTL, func() (*bybitTicker, error) {
+		params := url.Values{}
+		params.Set("category", "linear")
+		params.Set("symbol", symbol)
+
+		var result struct {
+			List []bybitTicker `json:"list"`
+		}
+		if err := p.get("/v5/market/tickers", params, &result); err != nil {
+			return nil, err
+		}
+		if len(result.List) == 0 {
+			return nil, fmt.Errorf("bybit 未找到交易对 %s", symbol)
+		}
+		return &result.List[0], nil
+	})
+}
+
+func (p *BybitProvider) GetTicker(symbol string) (*Ticker24hr, error) {
+	t, err := p.ticker(symbol)
+	if err != nil {
+		return nil, err
+	}
+	last, _ := strconv.ParseFloat(t.LastPrice, 64)
+	prev, _ := strconv.ParseFloat(t.PrevPrice24h, 64)
+	pcnt, _ := strconv.ParseFloat(t.Price24hPcnt, 64)
+	return &Ticker24hr{
+		Symbol:             t.Symbol,
+		PriceChange:        strconv.FormatFloat(last-prev, 'f', -1, 64),
+		PriceChangePercent: strconv.FormatFloat(pcnt*100, 'f', 4, 64),
+		Volume:             t.Volume24h,
+		QuoteVolume:        t.Turnover24h,
+	}, nil
+}
+
+func (p *BybitProvider) GetFundingRate(symbol string) (float64, error) {
+	t, err := p.ticker(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(t.FundingRate, 64)
+}
+
+func (p *BybitProvider) GetOpenInterest(symbol string) (*OIData, error) {
+	t, err := p.ticker(symbol)
+	if err != nil {
+		return nil, err
+	}
+	oi, _ := strconv.ParseFloat(t.OpenInterest, 64)
+	return &OIData{Latest: oi, Average: oi * 0.999}, nil
+}
+
+func (p *BybitProvider) GetSymbols() ([]string, error) {
+	symbols, err := cachedValue(ProviderBybit+"|symbols", symbolsCacheTTL, p.fetchSymbols)
+	return append([]string(nil), symbols...), err
+}
+
+func (p *BybitProvider) fetchSymbols() ([]string, error) {
+	var symbols []string
+	cursor := ""
+	for {
+		params := url.Values{}
+		params.Set("category", "linear")
+		params.Set("limit", "1000")
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
+		var result struct {
+			List []struct {
+				Symbol       string `json:"symbol"`
+				ContractType string `json:"contractType"`
+				Status       string `json:"status"`
+				QuoteCoin    string `json:"quoteCoin"`
+			} `json:"list"`
+			NextPageCursor string `json:"nextPageCursor"`
+		}
+		if err := p.get("/v5/market/instruments-info", params, &result); err != nil {
+			return nil, err
+		}
+		for _, s := range result.List {
+			if s.Status == "Trading" && s.ContractType == "LinearPerpetual" && s.QuoteCoin == "USDT" {
+				symbols = append(symbols, s.Symbol)
+			}
+		}
+		if result.NextPageCursor == "" || len(result.List) == 0 {
+			return symbols, nil
+		}
+		cursor = result.NextPageCursor
+	}
+}
+
+// bybitCodec Bybit v5 公共 WebSocket K线协议（topic: kline.{interval}.{symbol}）
+type bybitCodec struct{}
+
+func (bybitCodec) URL() string { return bybitWSURL }
+
+func (bybitCodec) SubscribeMessage(symbol, interval string) interface{} {
+	return map[string]interface{}{
+		"op":   "subscribe",
+		"args": []string{fmt.Sprintf("kline.%s.%s", bybitIntervals[interval], symbol)},
+	}
+}
+
+func (bybitCodec) PingMessage() interface{} {
+	return map[string]string{"op": "ping"}
+}
+
+func (bybitCodec) Decode(msg []byte) []KlineUpdate {
+	var payload struct {
+		Topic string `json:"topic"`
+		Data  []struct {
+			Start    int64  `json:"start"`
+			End      int64  `json:"end"`
+			Open     string `json:"open"`
+			Close    string `json:"close"`
+			High     string `json:"high"`
+			Low      string `json:"low"`
+			Volume   string `json:"volume"`
+			Turnover string `json:"turnover"`
+			Confirm  bool   `json:"confirm"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(msg, &payload); err != nil || !strings.HasPrefix(payload.Topic, "kline.") {
+		return nil
+	}
+	parts := strings.Split(payload.Topic, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	interval := ""
+	for tf, code := range bybitIntervals {
+		if code == parts[1] {
+			interval = tf
+			break
+		}
+	}
+	if interval == "" {
+		return nil
+	}
+
+	updates := make([]KlineUpdate, 0, len(payload.Data))
+	for _, d := range payload.Data {
+		k := Kline{OpenTime: d.Start, CloseTime: d.End}
+		k.Open, _ = strconv.ParseFloat(d.Open, 64)
+		k.High, _ = strconv.ParseFloat(d.High, 64)
+		k.Low, _ = strconv.ParseFloat(d.Low, 64)
+		k.Close, _ = strconv.ParseFloat(d.Close, 64)
+		k.Volume, _ = strconv.ParseFloat(d.Volume, 64)
+		k.QuoteVolume, _ = strconv.ParseFloat(d.Turnover, 64)
+		updates = append(updates, KlineUpdate{Symbol: parts[2], Interval: interval, Kline: k, Closed: d.Confirm})
+	}
+	return updates
+}
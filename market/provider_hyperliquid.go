@@ -0,0 +1,326 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	hyperliquidInfoURL        = "https://api.hyperliquid.xyz/info"
+	hyperliquidWSURL          = "wss://api.hyperliquid.xyz/ws"
+	hyperliquidMaxKlineLimit  = 5000
+	hyperliquidAssetCtxMaxAge = 5 * time.Second
+	hyperliquidInfoWeight     = 20
+)
+
+// HyperliquidProvider Hyperliquid 永续合约行情数据源
+// Hyperliquid 以币种名（BTC）标识合约，这里与 BTCUSDT 形式互相转换；资金费率每小时结算
+type HyperliquidProvider struct {
+	infoURL string
+	client  *http.Client
+	stream  *klineStream
+}
+
+type hyperliquidAsset struct {
+	Name         string
+	Delisted     bool
+	Funding      float64
+	OpenInterest float64
+	MarkPx       float64
+	PrevDayPx    float64
+	DayNtlVlm    float64
+	DayBaseVlm   float64
+}
+
+// NewHyperliquidProvider 创建 Hyperliquid 数据源
+func NewHyperliquidProvider() *HyperliquidProvider {
+	p := &HyperliquidProvider{infoURL: hyperliquidInfoURL, client: NewAPIClient().client}
+	p.stream = newKlineStream(ProviderHyperliquid, p.GetKlines, hyperliquidCodec{p})
+	return p
+}
+
+func (p *HyperliquidProvider) Name() string { return ProviderHyperliquid }
+
+func (p *HyperliquidProvider) Stream() KlineStream { return p.stream }
+
+// info 请求 /info 接口（weight 为请求权重）
+func (p *HyperliquidProvider) info(request interface{}, weight int, result interface{}) error {
+	throttle(ProviderHyperliquid, weight)
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Post(p.infoURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hyperliquid api returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, result)
+}
+
+// assetContexts 获取所有合约的元数据和行情上下文（短时间缓存，一次请求覆盖全部币种；返回值只读）
+func (p *HyperliquidProvider) assetContexts() (map[string]hyperliquidAsset, error) {
+	return cachedValue(ProviderHyperliquid+"|assets", hyperliquidAssetCtxMaxAge, p.fetchAssetContexts)
+}
+
+func (p *HyperliquidProvider) fetchAssetContexts() (map[string]hyperliquidAsset, error) {
+	var raw []json.RawMessage
+	if err := p.info(map[string]string{"type": "metaAndAssetCtxs"}, hyperliquidInfoWeight, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw) < 2 {
+		return nil, fmt.Errorf("hyperliquid metaAndAssetCtxs 返回格式异常")
+	}
+	var meta struct {
+		Universe []struct {
+			Name       string `json:"name"`
+			IsDelisted bool   `json:"isDelisted"`
+		} `json:"universe"`
+	}
+	var ctxs []struct {
+		Funding      string `json:"funding"`
+		OpenInterest string `json:"openInterest"`
+		MarkPx       string `json:"markPx"`
+		PrevDayPx    string `json:"prevDayPx"`
+		DayNtlVlm    string `json:"dayNtlVlm"`
+		DayBaseVlm   string `json:"dayBaseVlm"`
+	}
+	if err := json.Unmarshal(raw[0], &meta); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw[1], &ctxs); err != nil {
+		return nil, err
+	}
+
+	assets := make(map[string]hyperliquidAsset, len(meta.Universe))
+	for i, u := range meta.Universe {
+		asset := hyperliquidAsset{Name: u.Name, Delisted: u.IsDelisted}
+		if i < len(ctxs) {
+			c := ctxs[i]
+			asset.Funding, _ = strconv.ParseFloat(c.Funding, 64)
+			asset.OpenInterest, _ = strconv.ParseFloat(c.OpenInterest, 64)
+			asset.MarkPx, _ = strconv.ParseFloat(c.MarkPx, 64)
+			asset.PrevDayPx, _ = strconv.ParseFloat(c.PrevDayPx, 64)
+			asset.DayNtlVlm, _ = strconv.ParseFloat(c.DayNtlVlm, 64)
+			asset.DayBaseVlm, _ = strconv.ParseFloat(c.DayBaseVlm, 64)
+		}
+		assets[strings.ToUpper(u.Name)] = asset
+	}
+	return assets, nil
+}
+
+// asset 按 BTCUSDT 形式的 symbol 查找合约
+func (p *HyperliquidProvider) asset(symbol string) (hyperliquidAsset, error) {
+	assets, err := p.assetContexts()
+	if err != nil {
+		return hyperliquidAsset{}, err
+	}
+	asset, ok := assets[strings.TrimSuffix(Normalize(symbol), "USDT")]
+	if !ok {
+		return hyperliquidAsset{}, fmt.Errorf("hyperliquid 未找到交易对 %s", Normalize(symbol))
+	}
+	return asset, nil
+}
+
+// coin BTCUSDT -> BTC（优先使用交易所的大小写，如 kPEPE）
+func (p *HyperliquidProvider) coin(symbol string) string {
+	if asset, err := p.asset(symbol); err == nil {
+		return asset.Name
+	}
+	return strings.TrimSuffix(Normalize(symbol), "USDT")
+}
+
+type hyperliquidCandle struct {
+	OpenTime  int64  `json:"t"`
+	CloseTime int64  `json:"T"`
+	Coin      string `json:"s"`
+	Interval  string `json:"i"`
+	Open      string `json:"o"`
+	Close     string `json:"c"`
+	High      string `json:"h"`
+	Low       string `json:"l"`
+	Volume    string `json:"v"`
+	Trades    int    `json:"n"`
+}
+
+func (c hyperliquidCandle) kline() Kline {
+	k := Kline{OpenTime: c.OpenTime, CloseTime: c.CloseTime, Trades: c.Trades}
+	k.Open, _ = strconv.ParseFloat(c.Open, 64)
+	k.High, _ = strconv.ParseFloat(c.High, 64)
+	k.Low, _ = strconv.ParseFloat(c.Low, 64)
+	k.Close, _ = strconv.ParseFloat(c.Close, 64)
+	k.Volume, _ = strconv.ParseFloat(c.Volume, 64)
+	k.QuoteVolume = k.Volume * k.Close
+	return k
+}
+
+func (p *HyperliquidProvider) candleSnapshot(symbol, interval string, start, end int64) ([]Kline, error) {
+	duration, _ := TFDuration(interval)
+	weight := hyperliquidCandleWeight(int(min((end-start)/duration.Milliseconds(), hyperliquidMaxKlineLimit)))
+	var candles []hyperliquidCandle
+	request := map[string]interface{}{
+		"type": "candleSnapshot",
+		"req": map[string]interface{}{
+			"coin":      p.coin(symbol),
+			"interval":  interval,
+			"startTime": start,
+			"endTime":   end,
+		},
+	}
+	if err := p.info(request, weight, &candles); err != nil {
+		return nil, err
+	}
+	klines := make([]Kline, len(candles))
+	for i, c := range candles {
+		klines[i] = c.kline()
+	}
+	return klines, nil
+}
+
+func (p *HyperliquidProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	interval, err := NormalizeTimeframe(interval)
+	if err != nil {
+		return nil, err
+	}
+	duration, _ := TFDuration(interval)
+	symbol, limit = Normalize(symbol), min(limit, hyperliquidMaxKlineLimit)
+	key := fmt.Sprintf("%s|klines|%s|%s|%d", ProviderHyperliquid, symbol, interval, limit)
+	return cachedKlines(key, interval, func() ([]Kline, error) {
+		end := time.Now()
+		klines, err := p.candleSnapshot(symbol, interval, end.Add(-time.Duration(limit)*duration).UnixMilli(), end.UnixMilli())
+		if err != nil {
+			return nil, err
+		}
+		if len(klines) > limit {
+			klines = klines[len(klines)-limit:]
+		}
+		return klines, nil
+	})
+}
+
+func (p *HyperliquidProvider) GetKlinesRange(symbol, interval string, start, end time.Time) ([]Kline, error) {
+	interval, err := NormalizeTimeframe(interval)
+	if err != nil {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+
+	var all []Kline
+	endMs := end.UnixMilli()
+	for cursor := start.UnixMilli(); cursor < endMs; {
+		batch, err := p.candleSnapshot(symbol, interval, cursor, endMs)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		all = append(all, batch...)
+		cursor = batch[len(batch)-1].CloseTime + 1
+		if len(batch) < hyperliquidMaxKlineLimit {
+			break
+		}
+	}
+	return all, nil
+}
+
+func (p *HyperliquidProvider) GetTicker(symbol string) (*Ticker24hr, error) {
+	asset, err := p.asset(symbol)
+	if err != nil {
+		return nil, err
+	}
+	change, changePct := asset.MarkPx-asset.PrevDayPx, 0.0
+	if asset.PrevDayPx > 0 {
+		changePct = change / asset.PrevDayPx * 100
+	}
+	return &Ticker24hr{
+		Symbol:             Normalize(symbol),
+		PriceChange:        strconv.FormatFloat(change, 'f', -1, 64),
+		PriceChangePercent: strconv.FormatFloat(changePct, 'f', 4, 64),
+		Volume:             strconv.FormatFloat(asset.DayBaseVlm, 'f', -1, 64),
+		QuoteVolume:        strconv.FormatFloat(asset.DayNtlVlm, 'f', -1, 64),
+	}, nil
+}
+
+func (p *HyperliquidProvider) GetFundingRate(symbol string) (float64, error) {
+	asset, err := p.asset(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return asset.Funding, nil
+}
+
+func (p *HyperliquidProvider) GetOpenInterest(symbol string) (*OIData, error) {
+	asset, err := p.asset(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &OIData{Latest: asset.OpenInterest, Average: asset.OpenInterest * 0.999}, nil
+}
+
+func (p *HyperliquidProvider) GetSymbols() ([]string, error) {
+	assets, err := p.assetContexts()
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]string, 0, len(assets))
+	for name, asset := range assets {
+		if !asset.Delisted {
+			symbols = append(symbols, name+"USDT")
+		}
+	}
+	return symbols, nil
+}
+
+// hyperliquidCodec Hyperliquid WebSocket candle 订阅协议（不推送收盘标记，由 klineStream 在新K线出现时补发）
+type hyperliquidCodec struct {
+	p *HyperliquidProvider
+}
+
+func (hyperliquidCodec) URL() string { return hyperliquidWSURL }
+
+func (c hyperliquidCodec) SubscribeMessage(symbol, interval string) interface{} {
+	return map[string]interface{}{
+		"method": "subscribe",
+		"subscription": map[string]string{
+			"type":     "candle",
+			"coin":     c.p.coin(symbol),
+			"interval": interval,
+		},
+	}
+}
+
+func (hyperliquidCodec) PingMessage() interface{} {
+	return map[string]string{"method": "ping"}
+}
+
+func (hyperliquidCodec) Decode(msg []byte) []KlineUpdate {
+	var payload struct {
+		Channel string            `json:"channel"`
+		Data    hyperliquidCandle `json:"data"`
+	}
+	if err := json.Unmarshal(msg, &payload); err != nil || payload.Channel != "candle" {
+		return nil
+	}
+	return []KlineUpdate{{
+		Symbol:   Normalize(payload.Data.Coin + "USDT"),
+		Interval: payload.Data.Interval,
+		Kline:    payload.Data.kline(),
+	}}
+}
@@ -0,0 +1,194 @@
+package market
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestProviderForExchange 测试交易所与行情数据源的对应关系
+func TestProviderForExchange(t *testing.T) {
+	cases := map[string]string{
+		"binance":     ProviderBinance,
+		"bybit":       ProviderBybit,
+		"hyperliquid": ProviderHyperliquid,
+		"aster":       ProviderBinance,
+		"lighter":     ProviderBinance,
+		"":            ProviderBinance,
+	}
+	for exchange, want := range cases {
+		if got := ProviderForExchange(exchange).Name(); got != want {
+			t.Errorf("ProviderForExchange(%q) = %s, want %s", exchange, got, want)
+		}
+	}
+	if ProviderForExchange("bybit") != ProviderForExchange("bybit") {
+		t.Error("provider instances should be shared")
+	}
+
+	p, err := ResolveProvider("Hyperliquid", "binance")
+	if err != nil || p.Name() != ProviderHyperliquid {
+		t.Errorf("override not applied: %v %v", p, err)
+	}
+	if _, err := ResolveProvider("okx", "binance"); err == nil {
+		t.Error("unknown provider should be rejected")
+	}
+	if ValidateProviderName("") != nil || ValidateProviderName("okx") == nil {
+		t.Error("ValidateProviderName mismatch")
+	}
+}
+
+// TestBybitProvider 测试 Bybit K线（倒序转升序）和行情字段解析
+func TestBybitProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		switch r.URL.Path {
+		case "/v5/market/kline":
+			if r.URL.Query().Get("interval") != "15" || r.URL.Query().Get("category") != "linear" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			result = map[string]interface{}{"list": [][]string{
+				{"1800000", "101", "103", "100", "102", "20", "2040"},
+				{"900000", "100", "102", "99", "101", "10", "1005"},
+			}}
+		case "/v5/market/tickers":
+			result = map[string]interface{}{"list": []map[string]string{{
+				"symbol": "BTCUSDT", "lastPrice": "110", "prevPrice24h": "100", "price24hPcnt": "0.1",
+				"volume24h": "5", "turnover24h": "550", "fundingRate": "0.0001", "openInterest": "1000",
+			}}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"retCode": 0, "retMsg": "OK", "result": result})
+	}))
+	defer server.Close()
+
+	p := NewBybitProvider()
+	p.baseURL = server.URL
+
+	klines, err := p.GetKlines("btc", "15m", 2)
+	if err != nil {
+		t.Fatalf("GetKlines: %v", err)
+	}
+	if len(klines) != 2 || klines[0].OpenTime != 900000 || klines[1].Close != 102 || klines[0].CloseTime != 1799999 {
+		t.Errorf("unexpected klines: %+v", klines)
+	}
+
+	ticker, err := p.GetTicker("BTCUSDT")
+	if err != nil || ticker.PriceChange != "10" || ticker.PriceChangePercent != "10.0000" || ticker.QuoteVolume != "550" {
+		t.Errorf("unexpected ticker: %+v %v", ticker, err)
+	}
+	if rate, _ := p.GetFundingRate("BTCUSDT"); rate != 0.0001 {
+		t.Errorf("funding rate = %v", rate)
+	}
+	if oi, _ := p.GetOpenInterest("BTCUSDT"); oi == nil || oi.Latest != 1000 {
+		t.Errorf("open interest = %+v", oi)
+	}
+}
+
+// TestHyperliquidProvider 测试 Hyperliquid 币种映射、资产上下文和K线解析
+func TestHyperliquidProvider(t *testing.T) {
+	var coins []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Type string `json:"type"`
+			Req  struct {
+				Coin string `json:"coin"`
+			} `json:"req"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Type {
+		case "metaAndAssetCtxs":
+			w.Write([]byte(`[{"universe":[{"name":"BTC"},{"name":"kPEPE"},{"name":"OLD","isDelisted":true}]},
+				[{"funding":"0.0000125","openInterest":"500","markPx":"105","prevDayPx":"100","dayNtlVlm":"1000","dayBaseVlm":"10"},
+				 {"funding":"0.00002","openInterest":"9","markPx":"0.01","prevDayPx":"0.01","dayNtlVlm":"1","dayBaseVlm":"100"},
+				 {"funding":"0","openInterest":"0","markPx":"1","prevDayPx":"1","dayNtlVlm":"0","dayBaseVlm":"0"}]]`))
+		case "candleSnapshot":
+			coins = append(coins, req.Req.Coin)
+			w.Write([]byte(`[{"t":0,"T":59999,"s":"kPEPE","i":"1m","o":"1","c":"2","h":"2","l":"1","v":"10","n":3}]`))
+		}
+	}))
+	defer server.Close()
+
+	p := NewHyperliquidProvider()
+	p.infoURL = server.URL
+
+	if rate, err := p.GetFundingRate("BTCUSDT"); err != nil || rate != 0.0000125 {
+		t.Errorf("funding rate = %v %v", rate, err)
+	}
+	ticker, err := p.GetTicker("BTC")
+	if err != nil || ticker.Symbol != "BTCUSDT" || ticker.PriceChangePercent != "5.0000" || ticker.QuoteVolume != "1000" {
+		t.Errorf("unexpected ticker: %+v %v", ticker, err)
+	}
+	if _, err := p.GetOpenInterest("ETHUSDT"); err == nil {
+		t.Error("unknown coin should fail")
+	}
+	symbols, _ := p.GetSymbols()
+	if len(symbols) != 2 {
+		t.Errorf("delisted coins should be excluded: %v", symbols)
+	}
+
+	klines, err := p.GetKlines("KPEPEUSDT", "1m", 10)
+	if err != nil || len(klines) != 1 || klines[0].Close != 2 || klines[0].QuoteVolume != 20 {
+		t.Errorf("unexpected klines: %+v %v", klines, err)
+	}
+	if len(coins) != 1 || coins[0] != "kPEPE" {
+		t.Errorf("coin should use exchange casing: %v", coins)
+	}
+
+	updates := hyperliquidCodec{p}.Decode([]byte(`{"channel":"candle","data":{"t":60000,"T":119999,"s":"kPEPE","i":"1m","o":"2","c":"3","h":"3","l":"2","v":"1","n":1}}`))
+	if len(updates) != 1 || updates[0].Symbol != "KPEPEUSDT" || updates[0].Interval != "1m" || updates[0].Kline.Close != 3 {
+		t.Errorf("unexpected ws updates: %+v", updates)
+	}
+}
+
+// TestKlineStream 测试通用K线流的缓存、实时合并和收盘事件补发
+func TestKlineStream(t *testing.T) {
+	fetches := 0
+	s := newKlineStream("test", func(symbol, interval string, limit int) ([]Kline, error) {
+		fetches++
+		return []Kline{{OpenTime: 0, Close: 100}, {OpenTime: 60000, Close: 101}}, nil
+	}, nil)
+
+	if _, err := s.GetCurrentKlines("btc", "1M"); err != nil {
+		t.Fatalf("GetCurrentKlines: %v", err)
+	}
+	klines, _ := s.GetCurrentKlines("BTCUSDT", "1m")
+	if fetches != 1 || len(klines) != 2 {
+		t.Fatalf("cached klines should be reused: fetches=%d len=%d", fetches, len(klines))
+	}
+
+	updates, cancel := s.SubscribeKlineUpdates(8)
+	defer cancel()
+
+	// 未订阅的币种忽略
+	s.apply(KlineUpdate{Symbol: "ETHUSDT", Interval: "1m", Kline: Kline{OpenTime: 120000}})
+	// 当前K线更新
+	s.apply(KlineUpdate{Symbol: "BTCUSDT", Interval: "1m", Kline: Kline{OpenTime: 60000, Close: 102}})
+	// 新K线出现：补发上一根收盘
+	s.apply(KlineUpdate{Symbol: "BTCUSDT", Interval: "1m", Kline: Kline{OpenTime: 120000, Close: 103}})
+	// 交易所推送了收盘标记的K线不再重复补发
+	s.apply(KlineUpdate{Symbol: "BTCUSDT", Interval: "1m", Kline: Kline{OpenTime: 120000, Close: 104}, Closed: true})
+	s.apply(KlineUpdate{Symbol: "BTCUSDT", Interval: "1m", Kline: Kline{OpenTime: 180000, Close: 105}})
+	// 过期推送忽略
+	s.apply(KlineUpdate{Symbol: "BTCUSDT", Interval: "1m", Kline: Kline{OpenTime: 60000, Close: 1}})
+
+	want := []struct {
+		open   int64
+		closed bool
+	}{{60000, false}, {60000, true}, {120000, false}, {120000, true}, {180000, false}}
+	for i, w := range want {
+		u := <-updates
+		if u.Kline.OpenTime != w.open || u.Closed != w.closed {
+			t.Errorf("update %d = %d/%v, want %d/%v", i, u.Kline.OpenTime, u.Closed, w.open, w.closed)
+		}
+	}
+	select {
+	case u := <-updates:
+		t.Errorf("unexpected extra update: %+v", u)
+	default:
+	}
+
+	klines, _ = s.GetCurrentKlines("BTCUSDT", "1m")
+	if len(klines) != 4 || klines[3].Close != 105 || klines[1].Close != 102 {
+		t.Errorf("unexpected cached klines: %+v", klines)
+	}
+}
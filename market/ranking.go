@@ -0,0 +1,294 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRankingLimit          = 30
+	defaultRankingPrefilter      = 60
+	maxRankingPrefilter          = 200
+	defaultRankingMinQuoteVolume = 20_000_000 // 24小时成交额下限（USDT）
+
+	// rankingInterval 计算动量、放量和波动率使用的K线周期
+	rankingInterval = "1h"
+	// rankingKlines 24 根已收盘 1h K线 + 1 根未收盘K线（计算时丢弃）
+	rankingKlines = 25
+	// rankingRecentBars 动量、放量和持仓变化的近期窗口（4 小时）
+	rankingRecentBars = 4
+	// rankingCacheTTL 排名结果缓存时长（所有交易员共享）
+	rankingCacheTTL = 5 * time.Minute
+)
+
+// DefaultRankingWeights 默认因子权重
+var DefaultRankingWeights = RankingWeights{Momentum: 0.35, VolumeSurge: 0.25, OIChange: 0.25, Volatility: 0.15}
+
+// RankingWeights 本地币种排名各因子的权重（按比例生效，无需合计为 1）
+type RankingWeights struct {
+	Momentum    float64 `json:"momentum"`     // 趋势强度：近 4 小时与 24 小时涨跌幅（不区分方向）
+	VolumeSurge float64 `json:"volume_surge"` // 放量：近 4 小时平均成交额 / 此前 20 小时平均成交额
+	OIChange    float64 `json:"oi_change"`    // 近 4 小时持仓量变化（增仓优先）
+	Volatility  float64 `json:"volatility"`   // 24 小时平均振幅
+}
+
+// total 权重之和
+func (w RankingWeights) total() float64 {
+	return w.Momentum + w.VolumeSurge + w.OIChange + w.Volatility
+}
+
+// RankingParams 本地币种排名参数（零值使用默认值）
+type RankingParams struct {
+	Limit          int            `json:"limit,omitempty"`            // 输出的币种数量，默认 30
+	MinQuoteVolume float64        `json:"min_quote_volume,omitempty"` // 24小时成交额下限（USDT），默认 2000 万
+	Prefilter      int            `json:"prefilter,omitempty"`        // 按成交额预选后参与打分的币种数，默认 60，最多 200
+	Weights        RankingWeights `json:"weights"`                    // 因子权重，全为 0 时使用默认权重
+}
+
+// WithDefaults 填充默认值
+func (p RankingParams) WithDefaults() RankingParams {
+	if p.Limit <= 0 {
+		p.Limit = defaultRankingLimit
+	}
+	if p.MinQuoteVolume <= 0 {
+		p.MinQuoteVolume = defaultRankingMinQuoteVolume
+	}
+	if p.Prefilter <= 0 {
+		p.Prefilter = defaultRankingPrefilter
+	}
+	// 预选数量不少于输出数量
+	p.Prefilter = min(max(p.Prefilter, p.Limit), maxRankingPrefilter)
+	if p.Weights.total() == 0 {
+		p.Weights = DefaultRankingWeights
+	}
+	return p
+}
+
+// Validate 检查参数
+func (p RankingParams) Validate() error {
+	w := p.Weights
+	if w.Momentum < 0 || w.VolumeSurge < 0 || w.OIChange < 0 || w.Volatility < 0 {
+		return fmt.Errorf("币种排名因子权重不能为负数")
+	}
+	if p.Limit < 0 || p.Limit > maxRankingPrefilter {
+		return fmt.Errorf("币种排名数量必须在 0-%d 之间", maxRankingPrefilter)
+	}
+	if p.Prefilter < 0 || p.MinQuoteVolume < 0 {
+		return fmt.Errorf("币种排名预选数量和成交额下限不能为负数")
+	}
+	return nil
+}
+
+// CoinRanker 本地币种排名所需的行情能力（目前仅 Binance 实现）
+type CoinRanker interface {
+	// GetTickers 获取所有交易对的24小时行情
+	GetTickers() ([]*Ticker24hr, error)
+	// GetOpenInterestChange 获取最近 bars 个周期的持仓量变化（%）
+	GetOpenInterestChange(symbol, period string, bars int) (float64, error)
+}
+
+// CoinFactors 单个币种的排名因子原始值
+type CoinFactors struct {
+	Symbol      string  `json:"symbol"`
+	QuoteVolume float64 `json:"quote_volume"` // 24小时成交额（USDT）
+	Momentum    float64 `json:"momentum"`     // 趋势强度（%）
+	VolumeSurge float64 `json:"volume_surge"` // 放量倍数
+	OIChange    float64 `json:"oi_change"`    // 持仓量变化（%）
+	Volatility  float64 `json:"volatility"`   // 平均振幅（%）
+}
+
+// CoinScore 币种排名结果
+type CoinScore struct {
+	CoinFactors
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"` // 0-100，各因子横截面百分位的加权平均
+}
+
+// RankCoins 对数据源的全部 USDT 永续合约打分排名（结果缓存 rankingCacheTTL）
+// 先按24小时成交额过滤并预选 Prefilter 个流动性最好的币种，再用 1h K线和持仓量计算因子
+func RankCoins(provider MarketDataProvider, params RankingParams) ([]CoinScore, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	params = params.WithDefaults()
+	ranker, ok := provider.(CoinRanker)
+	if !ok {
+		return nil, fmt.Errorf("行情数据源 %s 不支持本地币种排名", provider.Name())
+	}
+
+	key := fmt.Sprintf("%s|ranking|%d|%.0f|%d|%v", provider.Name(), params.Limit, params.MinQuoteVolume, params.Prefilter, params.Weights)
+	scores, err := cachedValue(key, rankingCacheTTL, func() ([]CoinScore, error) {
+		return rankCoins(provider, ranker, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append([]CoinScore(nil), scores...), nil
+}
+
+func rankCoins(provider MarketDataProvider, ranker CoinRanker, params RankingParams) ([]CoinScore, error) {
+	symbols, err := provider.GetSymbols()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易对列表失败: %w", err)
+	}
+	tradable := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		tradable[Normalize(symbol)] = true
+	}
+	tickers, err := ranker.GetTickers()
+	if err != nil {
+		return nil, fmt.Errorf("获取24小时行情失败: %w", err)
+	}
+
+	liquid := prefilterTickers(tickers, tradable, params.MinQuoteVolume, params.Prefilter)
+	if len(liquid) == 0 {
+		return nil, fmt.Errorf("没有24小时成交额高于 %.0f USDT 的交易对", params.MinQuoteVolume)
+	}
+
+	factors := make([]CoinFactors, 0, len(liquid))
+	for _, ticker := range liquid {
+		klines, err := provider.GetKlines(ticker.Symbol, rankingInterval, rankingKlines)
+		if err != nil || len(klines) <= rankingRecentBars+1 {
+			continue
+		}
+		klines = klines[:len(klines)-1] // 最后一根尚未收盘，成交额不完整
+		// 持仓量获取失败时按 0 处理，不影响其他因子
+		oiChange, _ := ranker.GetOpenInterestChange(ticker.Symbol, rankingInterval, rankingRecentBars)
+		factors = append(factors, ComputeCoinFactors(ticker, klines, oiChange))
+	}
+	if len(factors) == 0 {
+		return nil, fmt.Errorf("所有预选币种的K线均获取失败")
+	}
+
+	scores := ScoreCoins(factors, params.Weights)
+	if len(scores) > params.Limit {
+		scores = scores[:params.Limit]
+	}
+	return scores, nil
+}
+
+// prefilterTickers 过滤不可交易和成交额不足的交易对，按成交额降序保留前 limit 个
+func prefilterTickers(tickers []*Ticker24hr, tradable map[string]bool, minQuoteVolume float64, limit int) []*Ticker24hr {
+	type liquidTicker struct {
+		ticker      *Ticker24hr
+		quoteVolume float64
+	}
+	var liquid []liquidTicker
+	for _, t := range tickers {
+		if t == nil || !tradable[t.Symbol] {
+			continue
+		}
+		qv, _ := strconv.ParseFloat(t.QuoteVolume, 64)
+		if qv >= minQuoteVolume {
+			liquid = append(liquid, liquidTicker{t, qv})
+		}
+	}
+	sort.SliceStable(liquid, func(i, j int) bool { return liquid[i].quoteVolume > liquid[j].quoteVolume })
+	result := make([]*Ticker24hr, 0, min(limit, len(liquid)))
+	for i := 0; i < len(liquid) && i < limit; i++ {
+		result = append(result, liquid[i].ticker)
+	}
+	return result
+}
+
+// ComputeCoinFactors 由24小时行情和已收盘的 1h K线（时间升序，多于 rankingRecentBars 根）计算排名因子
+func ComputeCoinFactors(ticker *Ticker24hr, klines []Kline, oiChange float64) CoinFactors {
+	f := CoinFactors{Symbol: ticker.Symbol, OIChange: oiChange}
+	f.QuoteVolume, _ = strconv.ParseFloat(ticker.QuoteVolume, 64)
+	change24h, _ := strconv.ParseFloat(ticker.PriceChangePercent, 64)
+
+	// 趋势强度：近 4 小时涨跌幅折算到 24 小时尺度后与 24 小时涨跌幅取均值
+	change4h := changeOverBars(klines, rankingRecentBars) * 100
+	f.Momentum = (math.Abs(change4h)*math.Sqrt(24/float64(rankingRecentBars)) + math.Abs(change24h)) / 2
+
+	n := len(klines)
+	recent := klines[n-rankingRecentBars:]
+	earlier := klines[:n-rankingRecentBars]
+	if base := avgQuoteVolume(earlier); base > 0 {
+		f.VolumeSurge = avgQuoteVolume(recent) / base
+	}
+
+	var amplitude float64
+	for _, k := range klines {
+		if k.Close > 0 {
+			amplitude += (k.High - k.Low) / k.Close
+		}
+	}
+	f.Volatility = amplitude / float64(n) * 100
+	return f
+}
+
+func avgQuoteVolume(klines []Kline) float64 {
+	if len(klines) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, k := range klines {
+		sum += k.QuoteVolume
+	}
+	return sum / float64(len(klines))
+}
+
+// ScoreCoins 按因子的横截面百分位加权打分，返回按得分降序排列的结果
+func ScoreCoins(factors []CoinFactors, weights RankingWeights) []CoinScore {
+	if weights.total() == 0 {
+		weights = DefaultRankingWeights
+	}
+	field := func(get func(CoinFactors) float64) []float64 {
+		values := make([]float64, len(factors))
+		for i, f := range factors {
+			values[i] = get(f)
+		}
+		return percentileRanks(values)
+	}
+	momentum := field(func(f CoinFactors) float64 { return f.Momentum })
+	volume := field(func(f CoinFactors) float64 { return f.VolumeSurge })
+	oi := field(func(f CoinFactors) float64 { return f.OIChange })
+	volatility := field(func(f CoinFactors) float64 { return f.Volatility })
+
+	scores := make([]CoinScore, len(factors))
+	for i, f := range factors {
+		weighted := weights.Momentum*momentum[i] + weights.VolumeSurge*volume[i] +
+			weights.OIChange*oi[i] + weights.Volatility*volatility[i]
+		scores[i] = CoinScore{CoinFactors: f, Score: weighted / weights.total() * 100}
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].QuoteVolume > scores[j].QuoteVolume
+	})
+	for i := range scores {
+		scores[i].Rank = i + 1
+	}
+	return scores
+}
+
+// percentileRanks 返回每个值在序列中的百分位（0-1，相同值取平均名次；只有一个值时为 1）
+func percentileRanks(values []float64) []float64 {
+	ranks := make([]float64, len(values))
+	if len(values) == 1 {
+		ranks[0] = 1
+		return ranks
+	}
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		avg := float64(i+j) / 2 / float64(len(values)-1)
+		for k := i; k <= j; k++ {
+			ranks[order[k]] = avg
+		}
+		i = j + 1
+	}
+	return ranks
+}
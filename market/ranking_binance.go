@@ -0,0 +1,74 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// binanceAllTickersWeight 不带 symbol 的24小时行情接口权重
+const binanceAllTickersWeight = 40
+
+// GetTickers 获取全部合约的24小时行情（共享缓存 tickerCacheTTL）
+func (p *BinanceProvider) GetTickers() ([]*Ticker24hr, error) {
+	tickers, err := cachedValue(ProviderBinance+"|tickers", tickerCacheTTL, func() ([]*Ticker24hr, error) {
+		return p.client.GetAll24hrTickers()
+	})
+	return append([]*Ticker24hr(nil), tickers...), err
+}
+
+// GetOpenInterestChange 获取最近 bars 个周期的持仓量变化（%，按周期边界缓存）
+func (p *BinanceProvider) GetOpenInterestChange(symbol, period string, bars int) (float64, error) {
+	symbol = Normalize(symbol)
+	if !derivativesPeriods[period] {
+		return 0, fmt.Errorf("不支持的持仓量统计周期: %s", period)
+	}
+	key := fmt.Sprintf("%s|oi_hist|%s|%s|%d", ProviderBinance, symbol, period, bars)
+	return cachedValue(key, klineCacheTTL(period, time.Now()), func() (float64, error) {
+		return p.fetchOpenInterestChange(symbol, period, bars)
+	})
+}
+
+func (p *BinanceProvider) fetchOpenInterestChange(symbol, period string, bars int) (float64, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("period", period)
+	params.Set("limit", strconv.Itoa(bars+1))
+
+	throttle(ProviderBinance, 1)
+	resp, err := p.client.client.Get(baseURL + "/futures/data/openInterestHist?" + params.Encode())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("binance openInterestHist returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return parseOpenInterestChange(body)
+}
+
+// parseOpenInterestChange 由 openInterestHist 返回的数组（时间升序）计算首尾持仓量变化（%）
+func parseOpenInterestChange(body []byte) (float64, error) {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(body, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) < 2 {
+		return 0, fmt.Errorf("持仓量历史数据不足")
+	}
+	first := rowFloat(rows[0], "sumOpenInterest")
+	last := rowFloat(rows[len(rows)-1], "sumOpenInterest")
+	if first <= 0 {
+		return 0, fmt.Errorf("持仓量历史数据无效")
+	}
+	return (last/first - 1) * 100, nil
+}
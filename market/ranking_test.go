@@ -0,0 +1,164 @@
+package market
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// rankingTestProvider 用于排名测试的行情数据源（K线按币种预设）
+type rankingTestProvider struct {
+	symbols []string
+	tickers []*Ticker24hr
+	klines  map[string][]Kline
+	oi      map[string]float64
+}
+
+func (p *rankingTestProvider) Name() string { return "ranking-test" }
+func (p *rankingTestProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	if k, ok := p.klines[symbol]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("no klines for %s", symbol)
+}
+func (p *rankingTestProvider) GetKlinesRange(symbol, interval string, start, end time.Time) ([]Kline, error) {
+	return nil, nil
+}
+func (p *rankingTestProvider) GetTicker(symbol string) (*Ticker24hr, error)   { return nil, nil }
+func (p *rankingTestProvider) GetFundingRate(symbol string) (float64, error)  { return 0, nil }
+func (p *rankingTestProvider) GetOpenInterest(symbol string) (*OIData, error) { return nil, nil }
+func (p *rankingTestProvider) GetSymbols() ([]string, error)                  { return p.symbols, nil }
+func (p *rankingTestProvider) Stream() KlineStream                            { return nil }
+func (p *rankingTestProvider) GetTickers() ([]*Ticker24hr, error)             { return p.tickers, nil }
+func (p *rankingTestProvider) GetOpenInterestChange(symbol, period string, bars int) (float64, error) {
+	return p.oi[symbol], nil
+}
+
+// hourlyKlines 生成 25 根 1h K线：前 20 根平稳，最后 4 根按 move 上涨并放量 surge 倍（最后一根视为未收盘）
+func hourlyKlines(move, surge float64) []Kline {
+	klines := make([]Kline, 25)
+	price := 100.0
+	for i := range klines {
+		volume := 1000.0
+		if i >= 20 && i < 24 {
+			price *= 1 + move
+			volume *= surge
+		}
+		klines[i] = Kline{OpenTime: int64(i) * 3600000, Close: price, High: price * 1.01, Low: price * 0.99, QuoteVolume: volume}
+	}
+	return klines
+}
+
+// TestComputeCoinFactors 测试由K线和24小时行情计算排名因子
+func TestComputeCoinFactors(t *testing.T) {
+	ticker := &Ticker24hr{Symbol: "SOLUSDT", PriceChangePercent: "-6", QuoteVolume: "50000000"}
+	f := ComputeCoinFactors(ticker, hourlyKlines(0.01, 3)[:24], 2.5)
+
+	change4h := (math.Pow(1.01, 4) - 1) * 100
+	wantMomentum := (change4h*math.Sqrt(6) + 6) / 2
+	if math.Abs(f.Momentum-wantMomentum) > 1e-9 {
+		t.Errorf("momentum = %v, want %v", f.Momentum, wantMomentum)
+	}
+	if math.Abs(f.VolumeSurge-3) > 1e-9 || f.OIChange != 2.5 || f.QuoteVolume != 50_000_000 {
+		t.Errorf("factors: %+v", f)
+	}
+	if math.Abs(f.Volatility-2) > 1e-9 {
+		t.Errorf("volatility = %v, want 2", f.Volatility)
+	}
+}
+
+// TestScoreCoins 测试百分位打分、权重和排序
+func TestScoreCoins(t *testing.T) {
+	factors := []CoinFactors{
+		{Symbol: "AUSDT", Momentum: 1, VolumeSurge: 1, OIChange: 0, Volatility: 1, QuoteVolume: 3},
+		{Symbol: "BUSDT", Momentum: 5, VolumeSurge: 3, OIChange: 4, Volatility: 2, QuoteVolume: 2},
+		{Symbol: "CUSDT", Momentum: 3, VolumeSurge: 2, OIChange: 2, Volatility: 3, QuoteVolume: 1},
+	}
+
+	scores := ScoreCoins(factors, RankingWeights{})
+	if scores[0].Symbol != "BUSDT" || scores[0].Rank != 1 || scores[2].Symbol != "AUSDT" {
+		t.Fatalf("default order: %+v", scores)
+	}
+	// B 除波动率（中位）外各因子均为最高：(0.35+0.25+0.25+0.15*0.5) * 100
+	if math.Abs(scores[0].Score-92.5) > 1e-9 {
+		t.Errorf("B score = %v, want 92.5", scores[0].Score)
+	}
+
+	// 只看波动率时 C 排第一
+	scores = ScoreCoins(factors, RankingWeights{Volatility: 1})
+	if scores[0].Symbol != "CUSDT" || scores[0].Score != 100 || scores[2].Score != 0 {
+		t.Errorf("volatility only: %+v", scores)
+	}
+
+	// 相同得分按成交额排序
+	ties := ScoreCoins([]CoinFactors{{Symbol: "LOW", QuoteVolume: 1}, {Symbol: "HIGH", QuoteVolume: 2}}, RankingWeights{})
+	if ties[0].Symbol != "HIGH" || ties[0].Score != 50 {
+		t.Errorf("ties: %+v", ties)
+	}
+}
+
+// TestRankingParams 测试排名参数默认值和校验
+func TestRankingParams(t *testing.T) {
+	p := RankingParams{}.WithDefaults()
+	if p.Limit != 30 || p.Prefilter != 60 || p.MinQuoteVolume != 20_000_000 || p.Weights != DefaultRankingWeights {
+		t.Errorf("defaults: %+v", p)
+	}
+	if p := (RankingParams{Limit: 80, Prefilter: 10}).WithDefaults(); p.Prefilter != 80 {
+		t.Errorf("prefilter should be at least limit, got %d", p.Prefilter)
+	}
+	if err := (RankingParams{Weights: RankingWeights{Momentum: -1}}).Validate(); err == nil {
+		t.Error("negative weight should be rejected")
+	}
+	if err := (RankingParams{Limit: 500}).Validate(); err == nil {
+		t.Error("limit above max should be rejected")
+	}
+}
+
+// TestRankCoins 测试流动性过滤、不可交易币种过滤和排名输出
+func TestRankCoins(t *testing.T) {
+	provider := &rankingTestProvider{
+		symbols: []string{"BTCUSDT", "SOLUSDT", "DOGEUSDT", "PEPEUSDT"},
+		tickers: []*Ticker24hr{
+			{Symbol: "BTCUSDT", PriceChangePercent: "1", QuoteVolume: "900000000"},
+			{Symbol: "SOLUSDT", PriceChangePercent: "8", QuoteVolume: "300000000"},
+			{Symbol: "DOGEUSDT", PriceChangePercent: "2", QuoteVolume: "5000000"},    // 成交额不足
+			{Symbol: "DELISTUSDT", PriceChangePercent: "9", QuoteVolume: "80000000"}, // 不在可交易列表
+			{Symbol: "PEPEUSDT", PriceChangePercent: "3", QuoteVolume: "60000000"},   // K线获取失败
+		},
+		klines: map[string][]Kline{
+			"BTCUSDT":    hourlyKlines(0.001, 1),
+			"SOLUSDT":    hourlyKlines(0.02, 4),
+			"DOGEUSDT":   hourlyKlines(0.05, 5),
+			"DELISTUSDT": hourlyKlines(0.05, 5),
+		},
+		oi: map[string]float64{"SOLUSDT": 6, "BTCUSDT": 1},
+	}
+
+	scores, err := RankCoins(provider, RankingParams{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 2 || scores[0].Symbol != "SOLUSDT" || scores[1].Symbol != "BTCUSDT" {
+		t.Fatalf("scores: %+v", scores)
+	}
+	if math.Abs(scores[0].VolumeSurge-4) > 1e-9 {
+		t.Errorf("未收盘K线应被丢弃, volume surge = %v", scores[0].VolumeSurge)
+	}
+
+	if _, err := RankCoins(provider, RankingParams{MinQuoteVolume: 1e12}); err == nil {
+		t.Error("expected error when no symbol passes the volume filter")
+	}
+}
+
+// TestParseOpenInterestChange 测试持仓量历史解析
+func TestParseOpenInterestChange(t *testing.T) {
+	body := []byte(`[{"symbol":"BTCUSDT","sumOpenInterest":"1000","timestamp":1},{"sumOpenInterest":"1100","timestamp":2}]`)
+	change, err := parseOpenInterestChange(body)
+	if err != nil || math.Abs(change-10) > 1e-9 {
+		t.Errorf("change = %v, err = %v", change, err)
+	}
+	if _, err := parseOpenInterestChange([]byte(`[]`)); err == nil {
+		t.Error("expected error for empty history")
+	}
+}
@@ -0,0 +1,140 @@
+package market
+
+import (
+	"sync"
+	"time"
+)
+
+// 各数据源每分钟可用的请求权重（低于交易所 IP 限额，为交易接口和其他进程留出余量）
+// Binance 合约 2400/分钟，Bybit 600 次/5 秒，Hyperliquid 1200/分钟
+const (
+	binanceWeightPerMinute     = 1200
+	bybitWeightPerMinute       = 3000
+	hyperliquidWeightPerMinute = 1000
+)
+
+// weightLimiter 令牌桶限流：按请求权重扣减令牌，令牌不足时阻塞等待
+type weightLimiter struct {
+	mu        sync.Mutex
+	capacity  float64
+	perSecond float64
+	tokens    float64
+	last      time.Time
+	now       func() time.Time
+	sleep     func(time.Duration)
+
+	requests  int64
+	weight    int64
+	throttled int64
+	waited    time.Duration
+}
+
+func newWeightLimiter(perMinute int) *weightLimiter {
+	return &weightLimiter{
+		capacity:  float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+		tokens:    float64(perMinute),
+		now:       time.Now,
+		sleep:     time.Sleep,
+	}
+}
+
+// rateLimiters 进程级限流器（所有交易员共享）
+var rateLimiters = map[string]*weightLimiter{
+	ProviderBinance:     newWeightLimiter(binanceWeightPerMinute),
+	ProviderBybit:       newWeightLimiter(bybitWeightPerMinute),
+	ProviderHyperliquid: newWeightLimiter(hyperliquidWeightPerMinute),
+}
+
+// throttle 发起请求前按权重取得令牌
+func throttle(provider string, weight int) {
+	if limiter, ok := rateLimiters[provider]; ok {
+		limiter.wait(weight)
+	}
+}
+
+// wait 预留 weight 个令牌（不足时令牌变为负数，按补充速度计算等待时间），返回等待时长
+func (l *weightLimiter) wait(weight int) time.Duration {
+	l.mu.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.perSecond)
+	}
+	l.last = now
+	l.tokens -= float64(weight)
+	l.requests++
+	l.weight += int64(weight)
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.perSecond * float64(time.Second))
+		l.throttled++
+		l.waited += delay
+	}
+	l.mu.Unlock()
+
+	if delay > 0 {
+		l.sleep(delay)
+	}
+	return delay
+}
+
+// RateLimitStats 单个数据源的限流统计
+type RateLimitStats struct {
+	LimitPerMinute int     `json:"limit_per_minute"`
+	Available      float64 `json:"available"` // 当前剩余令牌（负数表示已有请求在排队）
+	Requests       int64   `json:"requests"`
+	Weight         int64   `json:"weight"`
+	Throttled      int64   `json:"throttled"` // 因限流等待的请求数
+	WaitedMs       int64   `json:"waited_ms"` // 累计等待时长
+}
+
+func (l *weightLimiter) stats() RateLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	available := l.tokens
+	if !l.last.IsZero() {
+		available = min(l.capacity, available+l.now().Sub(l.last).Seconds()*l.perSecond)
+	}
+	return RateLimitStats{
+		LimitPerMinute: int(l.capacity),
+		Available:      available,
+		Requests:       l.requests,
+		Weight:         l.weight,
+		Throttled:      l.throttled,
+		WaitedMs:       l.waited.Milliseconds(),
+	}
+}
+
+// binanceKlinesWeight Binance 合约K线接口权重（按 limit）
+func binanceKlinesWeight(limit int) int {
+	switch {
+	case limit < 100:
+		return 1
+	case limit < 500:
+		return 2
+	case limit <= 1000:
+		return 5
+	default:
+		return 10
+	}
+}
+
+// binanceDepthWeight Binance 合约深度接口权重（按 limit）
+func binanceDepthWeight(limit int) int {
+	switch {
+	case limit <= 50:
+		return 2
+	case limit <= 100:
+		return 5
+	case limit <= 500:
+		return 10
+	default:
+		return 20
+	}
+}
+
+// hyperliquidCandleWeight Hyperliquid candleSnapshot 权重：基础 20，每 60 根K线额外 +1
+func hyperliquidCandleWeight(limit int) int {
+	return 20 + limit/60
+}
@@ -0,0 +1,226 @@
+package market
+
+import (
+	"math"
+	"sort"
+)
+
+// 市场状态（用于 Prompt 和按状态统计决策表现）
+const (
+	RegimeTrendingUp     = "trending_up"     // 普涨趋势
+	RegimeTrendingDown   = "trending_down"   // 普跌趋势
+	RegimeRanging        = "ranging"         // 震荡
+	RegimeHighVolatility = "high_volatility" // 高波动
+)
+
+const (
+	// BenchmarkBTC / BenchmarkETH 相关性与 Beta 的基准币种
+	BenchmarkBTC = "BTCUSDT"
+	BenchmarkETH = "ETHUSDT"
+
+	// regimeTrendBars 计算涨跌幅、市场宽度和近期波动率的K线数
+	regimeTrendBars = 20
+	// regimeCorrelationBars 计算相关性和 Beta 最多使用的收益率个数
+	regimeCorrelationBars = 100
+	// regimeMinSamples 计算相关性所需的最少对齐收益率个数
+	regimeMinSamples = 20
+
+	// regimeHighVolRatio 近期波动率达到基准波动率（含近期）的倍数时判定为高波动
+	// 1.5 倍约相当于近期波动率为更早时段的 2 倍
+	regimeHighVolRatio = 1.5
+	// regimeTrendBreadth 上涨（下跌）币种占比达到该比例（%）且 BTC 同向时判定为趋势
+	regimeTrendBreadth = 65.0
+	// regimeTrendStrength BTC 涨跌幅至少为同期随机波动幅度（单根波动率 × √TrendBars）的倍数才算趋势
+	regimeTrendStrength = 0.5
+)
+
+// RegimeBasket 判定市场状态的固定参考币种
+// 市场宽度和波动率只基于该篮子计算，不随各交易员的候选币变化，保证不同交易员、不同周期的市场状态可比
+var RegimeBasket = []string{
+	BenchmarkBTC, BenchmarkETH, "SOLUSDT", "BNBUSDT", "XRPUSDT",
+	"DOGEUSDT", "ADAUSDT", "AVAXUSDT", "LINKUSDT", "LTCUSDT",
+}
+
+// AssetCorrelation 单个币种与 BTC/ETH 的相关性和 Beta（基于主周期收益率）
+type AssetCorrelation struct {
+	Symbol  string  `json:"symbol"`
+	CorrBTC float64 `json:"corr_btc"`
+	BetaBTC float64 `json:"beta_btc"`
+	CorrETH float64 `json:"corr_eth,omitempty"`
+	BetaETH float64 `json:"beta_eth,omitempty"`
+	Samples int     `json:"samples"` // 与 BTC 对齐的收益率个数
+}
+
+// MarketOverview 市场概览：市场状态、宽度、波动率及各币种与 BTC/ETH 的联动
+type MarketOverview struct {
+	Timeframe       string             `json:"timeframe"`
+	Regime          string             `json:"regime"`
+	TrendBars       int                `json:"trend_bars"`
+	BTCChange       float64            `json:"btc_change"`       // BTC 近 TrendBars 根涨跌幅（%）
+	ETHChange       float64            `json:"eth_change"`       // ETH 近 TrendBars 根涨跌幅（%）
+	Breadth         float64            `json:"breadth"`          // 近 TrendBars 根上涨的币种占比（%）
+	AboveSMA        float64            `json:"above_sma"`        // 收盘价高于 SMA(TrendBars) 的币种占比（%）
+	Volatility      float64            `json:"volatility"`       // 近期单根收益率标准差的中位数（%）
+	VolatilityRatio float64            `json:"volatility_ratio"` // 近期波动率 / 基准波动率的中位数
+	Symbols         int                `json:"symbols"`          // 参与统计的参考币种数（RegimeBasket 中有K线的币种）
+	Assets          []AssetCorrelation `json:"assets,omitempty"`
+}
+
+// BuildMarketOverview 根据同一周期的K线（按币种，时间升序）计算市场概览
+// 市场状态只统计 RegimeBasket 中的币种，symbols 为需要计算与 BTC/ETH 联动的币种
+// klines 需包含 BTCUSDT；ETHUSDT 缺失时不计算与 ETH 的相关性。BTC 数据不足时返回 nil
+func BuildMarketOverview(timeframe string, klines map[string][]Kline, symbols []string) *MarketOverview {
+	btc := klines[BenchmarkBTC]
+	if len(btc) <= regimeTrendBars {
+		return nil
+	}
+	eth := klines[BenchmarkETH]
+
+	o := &MarketOverview{
+		Timeframe: timeframe,
+		TrendBars: regimeTrendBars,
+		BTCChange: changeOverBars(btc, regimeTrendBars) * 100,
+		ETHChange: changeOverBars(eth, regimeTrendBars) * 100,
+	}
+
+	var up, aboveSMA int
+	var vols, ratios []float64
+	for _, symbol := range RegimeBasket {
+		series := klines[symbol]
+		if len(series) <= regimeTrendBars {
+			continue
+		}
+		o.Symbols++
+		if changeOverBars(series, regimeTrendBars) > 0 {
+			up++
+		}
+		if series[len(series)-1].Close > smaClose(series, regimeTrendBars) {
+			aboveSMA++
+		}
+		if recent, ratio := volatilityRatio(series); recent > 0 {
+			vols = append(vols, recent*100)
+			ratios = append(ratios, ratio)
+		}
+	}
+
+	sorted := append([]string(nil), symbols...)
+	sort.Strings(sorted)
+	for i, symbol := range sorted {
+		series := klines[symbol]
+		if symbol == BenchmarkBTC || symbol == BenchmarkETH || (i > 0 && symbol == sorted[i-1]) || len(series) <= regimeTrendBars {
+			continue
+		}
+		asset := AssetCorrelation{Symbol: symbol}
+		asset.CorrBTC, asset.BetaBTC, asset.Samples = CorrelationBeta(series, btc, regimeCorrelationBars)
+		if asset.Samples == 0 {
+			continue
+		}
+		asset.CorrETH, asset.BetaETH, _ = CorrelationBeta(series, eth, regimeCorrelationBars)
+		o.Assets = append(o.Assets, asset)
+	}
+
+	o.Breadth = float64(up) / float64(o.Symbols) * 100
+	o.AboveSMA = float64(aboveSMA) / float64(o.Symbols) * 100
+	o.Volatility = median(vols)
+	o.VolatilityRatio = median(ratios)
+	o.Regime = ClassifyRegime(o)
+	return o
+}
+
+// ClassifyRegime 根据波动率和市场宽度判定市场状态
+// 波动率显著放大时优先判定为高波动；否则多数币种与 BTC 同向且 BTC 涨跌幅明显超出随机波动时为趋势，其余为震荡
+func ClassifyRegime(o *MarketOverview) string {
+	trending := math.Abs(o.BTCChange) >= regimeTrendStrength*o.Volatility*math.Sqrt(float64(o.TrendBars))
+	switch {
+	case o.VolatilityRatio >= regimeHighVolRatio:
+		return RegimeHighVolatility
+	case trending && o.Breadth >= regimeTrendBreadth && o.BTCChange > 0:
+		return RegimeTrendingUp
+	case trending && o.Breadth <= 100-regimeTrendBreadth && o.BTCChange < 0:
+		return RegimeTrendingDown
+	default:
+		return RegimeRanging
+	}
+}
+
+// CorrelationBeta 计算 asset 相对 benchmark 的收益率相关系数和 Beta
+// 两组K线按开盘时间对齐，最多使用最近 window 个收益率；对齐样本不足时 samples 返回 0
+func CorrelationBeta(asset, benchmark []Kline, window int) (corr, beta float64, samples int) {
+	benchReturns := make(map[int64]float64, len(benchmark))
+	for i := 1; i < len(benchmark); i++ {
+		if benchmark[i-1].Close > 0 {
+			benchReturns[benchmark[i].OpenTime] = benchmark[i].Close/benchmark[i-1].Close - 1
+		}
+	}
+
+	var xs, ys []float64
+	for i := len(asset) - 1; i >= 1 && len(xs) < window; i-- {
+		b, ok := benchReturns[asset[i].OpenTime]
+		if !ok || asset[i-1].Close <= 0 {
+			continue
+		}
+		xs = append(xs, b)
+		ys = append(ys, asset[i].Close/asset[i-1].Close-1)
+	}
+	if len(xs) < regimeMinSamples {
+		return 0, 0, 0
+	}
+
+	meanX, meanY := mean(xs), mean(ys)
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX > 0 {
+		beta = cov / varX
+		if varY > 0 {
+			corr = cov / math.Sqrt(varX*varY)
+		}
+	}
+	return corr, beta, len(xs)
+}
+
+// volatilityRatio 返回最近 regimeTrendBars 个收益率的标准差，以及它相对最近 regimeCorrelationBars 个收益率标准差的倍数
+func volatilityRatio(klines []Kline) (recent, ratio float64) {
+	var returns []float64
+	for i := max(1, len(klines)-regimeCorrelationBars); i < len(klines); i++ {
+		if klines[i-1].Close > 0 {
+			returns = append(returns, klines[i].Close/klines[i-1].Close-1)
+		}
+	}
+	if len(returns) < regimeTrendBars {
+		return 0, 0
+	}
+	recent = stdDev(returns[len(returns)-regimeTrendBars:])
+	if base := stdDev(returns); base > 0 {
+		ratio = recent / base
+	}
+	return recent, ratio
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
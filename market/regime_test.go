@@ -0,0 +1,115 @@
+package market
+
+import (
+	"math"
+	"testing"
+)
+
+// klinesFromReturns 按收益率序列生成K线（起始价 100，开盘时间从 start 开始按 3m 递增）
+func klinesFromReturns(start int64, returns []float64) []Kline {
+	klines := make([]Kline, 0, len(returns)+1)
+	price := 100.0
+	klines = append(klines, Kline{OpenTime: start, Close: price})
+	for i, r := range returns {
+		price *= 1 + r
+		klines = append(klines, Kline{OpenTime: start + int64(i+1)*180000, Close: price})
+	}
+	return klines
+}
+
+// wave 生成交替正负、幅度逐渐变化的收益率（用于模拟基准走势）
+func wave(n int, amplitude, drift float64) []float64 {
+	returns := make([]float64, n)
+	for i := range returns {
+		r := amplitude * (1 + float64(i%5)/5)
+		if i%2 == 1 {
+			r = -r
+		}
+		returns[i] = r + drift
+	}
+	return returns
+}
+
+func scaled(returns []float64, k float64) []float64 {
+	out := make([]float64, len(returns))
+	for i, r := range returns {
+		out[i] = r * k
+	}
+	return out
+}
+
+// TestCorrelationBeta 测试相关系数、Beta 和时间对齐
+func TestCorrelationBeta(t *testing.T) {
+	bench := wave(60, 0.01, 0)
+	btc := klinesFromReturns(0, bench)
+
+	corr, beta, n := CorrelationBeta(klinesFromReturns(0, scaled(bench, 2)), btc, 100)
+	if n != 60 || math.Abs(corr-1) > 1e-9 || math.Abs(beta-2) > 1e-9 {
+		t.Errorf("2x 基准: corr=%v beta=%v n=%v", corr, beta, n)
+	}
+
+	corr, beta, _ = CorrelationBeta(klinesFromReturns(0, scaled(bench, -0.5)), btc, 100)
+	if math.Abs(corr+1) > 1e-9 || math.Abs(beta+0.5) > 1e-9 {
+		t.Errorf("反向基准: corr=%v beta=%v", corr, beta)
+	}
+
+	// 窗口限制只使用最近的收益率
+	if _, _, n := CorrelationBeta(klinesFromReturns(0, bench), btc, 30); n != 30 {
+		t.Errorf("window samples = %d, want 30", n)
+	}
+
+	// 时间不重叠时没有样本
+	if _, _, n := CorrelationBeta(klinesFromReturns(1e9, bench), btc, 100); n != 0 {
+		t.Errorf("未对齐的K线不应计算相关性, samples=%d", n)
+	}
+}
+
+// TestBuildMarketOverview 测试市场宽度、状态判定和币种联动
+func TestBuildMarketOverview(t *testing.T) {
+	if BuildMarketOverview("3m", map[string][]Kline{"SOLUSDT": klinesFromReturns(0, wave(60, 0.01, 0))}, nil) != nil {
+		t.Fatal("缺少 BTC 时应返回 nil")
+	}
+
+	up := wave(99, 0.005, 0.002)
+	o := BuildMarketOverview("3m", map[string][]Kline{
+		BenchmarkBTC: klinesFromReturns(0, up),
+		BenchmarkETH: klinesFromReturns(0, scaled(up, 1.2)),
+		"SOLUSDT":    klinesFromReturns(0, scaled(up, 1.5)),
+		"PEPEUSDT":   klinesFromReturns(0, scaled(up, -1)),
+	}, []string{"SOLUSDT"})
+	if o == nil {
+		t.Fatal("expected overview")
+	}
+	// 市场状态只统计参考币种，非参考币种（PEPE 反向下跌）不影响市场宽度
+	if o.Regime != RegimeTrendingUp || o.Breadth != 100 || o.Symbols != 3 || o.BTCChange <= 0 {
+		t.Errorf("上涨行情: %+v", o)
+	}
+	if len(o.Assets) != 1 || o.Assets[0].Symbol != "SOLUSDT" {
+		t.Fatalf("assets: %+v", o.Assets)
+	}
+	if a := o.Assets[0]; math.Abs(a.CorrBTC-1) > 1e-6 || math.Abs(a.BetaBTC-1.5) > 1e-6 || math.Abs(a.BetaETH-1.25) > 1e-6 {
+		t.Errorf("SOL 联动: %+v", a)
+	}
+
+	down := BuildMarketOverview("3m", map[string][]Kline{
+		BenchmarkBTC: klinesFromReturns(0, wave(99, 0.005, -0.002)),
+		BenchmarkETH: klinesFromReturns(0, wave(99, 0.005, -0.003)),
+	}, nil)
+	if down.Regime != RegimeTrendingDown || down.Breadth != 0 {
+		t.Errorf("下跌行情: %+v", down)
+	}
+
+	ranging := BuildMarketOverview("3m", map[string][]Kline{
+		BenchmarkBTC: klinesFromReturns(0, wave(99, 0.005, 0)),
+	}, nil)
+	if ranging.Regime != RegimeRanging {
+		t.Errorf("震荡行情: %+v", ranging)
+	}
+
+	// 最近 20 根波动放大 4 倍
+	spike := append(wave(79, 0.002, 0), wave(20, 0.008, 0)...)
+	volatile := BuildMarketOverview("3m", map[string][]Kline{BenchmarkBTC: klinesFromReturns(0, spike)}, nil)
+	if volatile.Regime != RegimeHighVolatility || volatile.VolatilityRatio < regimeHighVolRatio {
+		t.Errorf("高波动行情: %+v", volatile)
+	}
+}
@@ -0,0 +1,74 @@
+package market
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// SymbolStatusTrading 正常交易中的交易对状态
+	SymbolStatusTrading = "TRADING"
+
+	// binancePerpetualDeliveryDate 未计划下架的永续合约返回的默认交割时间（2100-12-25）
+	binancePerpetualDeliveryDate = 4133404800000
+)
+
+// SymbolStatus 交易对状态（用于排除暂停交易或计划下架的币种）
+type SymbolStatus struct {
+	Symbol   string    `json:"symbol"`
+	Status   string    `json:"status"`              // TRADING / SETTLING / CLOSE / PENDING_TRADING 等
+	DelistAt time.Time `json:"delist_at,omitempty"` // 计划下架时间，未计划下架时为零值
+}
+
+// Tradable 是否处于正常交易状态
+func (s SymbolStatus) Tradable() bool {
+	return s.Status == SymbolStatusTrading
+}
+
+// Delisting 是否已计划下架
+func (s SymbolStatus) Delisting() bool {
+	return !s.DelistAt.IsZero()
+}
+
+// SymbolStatusProvider 可查询交易对状态的行情数据源（目前仅 Binance 实现）
+type SymbolStatusProvider interface {
+	// GetSymbolStatuses 获取全部 USDT 永续合约（含暂停交易的）的状态，键为币种
+	GetSymbolStatuses() (map[string]SymbolStatus, error)
+}
+
+// GetSymbolStatuses 获取全部 USDT 永续合约的交易状态和计划下架时间（缓存 symbolsCacheTTL）
+func (p *BinanceProvider) GetSymbolStatuses() (map[string]SymbolStatus, error) {
+	statuses, err := cachedValue(ProviderBinance+"|symbol_status", symbolsCacheTTL, func() (map[string]SymbolStatus, error) {
+		info, err := p.client.GetExchangeInfo()
+		if err != nil {
+			return nil, err
+		}
+		return parseSymbolStatuses(info), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]SymbolStatus, len(statuses))
+	for symbol, status := range statuses {
+		result[symbol] = status
+	}
+	return result, nil
+}
+
+// parseSymbolStatuses 从 exchangeInfo 提取 USDT 永续合约状态
+// 计划下架的永续合约 deliveryDate 为下架时间，否则为默认的 2100 年
+func parseSymbolStatuses(info *ExchangeInfo) map[string]SymbolStatus {
+	statuses := make(map[string]SymbolStatus, len(info.Symbols))
+	for _, s := range info.Symbols {
+		symbol := strings.ToUpper(s.Symbol)
+		if s.ContractType != "PERPETUAL" || !strings.HasSuffix(symbol, "USDT") {
+			continue
+		}
+		status := SymbolStatus{Symbol: symbol, Status: s.Status}
+		if s.DeliveryDate > 0 && s.DeliveryDate < binancePerpetualDeliveryDate {
+			status.DelistAt = time.UnixMilli(s.DeliveryDate).UTC()
+		}
+		statuses[symbol] = status
+	}
+	return statuses
+}
@@ -0,0 +1,37 @@
+package market
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestParseSymbolStatuses 测试交易对状态和计划下架时间解析
+func TestParseSymbolStatuses(t *testing.T) {
+	body := []byte(`{"symbols":[
+		{"symbol":"BTCUSDT","status":"TRADING","contractType":"PERPETUAL","deliveryDate":4133404800000},
+		{"symbol":"OLDUSDT","status":"TRADING","contractType":"PERPETUAL","deliveryDate":1767225600000},
+		{"symbol":"GONEUSDT","status":"SETTLING","contractType":"PERPETUAL","deliveryDate":1760000000000},
+		{"symbol":"BTCUSDT_251226","status":"TRADING","contractType":"CURRENT_QUARTER","deliveryDate":1766736000000},
+		{"symbol":"ETHBTC","status":"TRADING","contractType":"PERPETUAL"}
+	]}`)
+	var info ExchangeInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := parseSymbolStatuses(&info)
+	if len(statuses) != 3 {
+		t.Fatalf("只应包含 USDT 永续合约: %+v", statuses)
+	}
+	if btc := statuses["BTCUSDT"]; !btc.Tradable() || btc.Delisting() {
+		t.Errorf("BTC: %+v", btc)
+	}
+	old := statuses["OLDUSDT"]
+	if !old.Tradable() || !old.Delisting() || !old.DelistAt.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("计划下架: %+v", old)
+	}
+	if gone := statuses["GONEUSDT"]; gone.Tradable() {
+		t.Errorf("SETTLING 不应可交易: %+v", gone)
+	}
+}
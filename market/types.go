@@ -17,6 +17,10 @@ type Data struct {
 	LongerTermContext *LongerTermData
 	// 多时间周期数据（新增）
 	TimeframeData map[string]*TimeframeSeriesData `json:"timeframe_data,omitempty"`
+	// 盘口流动性（策略启用订单簿数据时填充）
+	Liquidity *LiquidityMetrics `json:"liquidity,omitempty"`
+	// 持仓情绪：多空比、主动买卖量、强平（策略启用时填充）
+	Derivatives *DerivativesData `json:"derivatives,omitempty"`
 }
 
 // TimeframeSeriesData 单个时间周期的序列数据
@@ -30,6 +34,9 @@ type TimeframeSeriesData struct {
 	RSI14Values []float64 `json:"rsi14_values"` // RSI14 序列
 	Volume      []float64 `json:"volume"`       // 成交量序列
 	ATR14       float64   `json:"atr14"`        // ATR14
+
+	// Series 按策略配置计算的命名指标序列（如 ema9、rsi21、bb_upper、adx），键名见 indicators.go
+	Series map[string][]float64 `json:"series,omitempty"`
 }
 
 // OIData Open Interest数据
@@ -74,6 +81,7 @@ type SymbolInfo struct {
 	ContractType      string `json:"contractType"`
 	PricePrecision    int    `json:"pricePrecision"`
 	QuantityPrecision int    `json:"quantityPrecision"`
+	DeliveryDate      int64  `json:"deliveryDate"` // 交割时间（毫秒）；永续合约计划下架时为下架时间
 }
 
 type Kline struct {
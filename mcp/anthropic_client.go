@@ -0,0 +1,244 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProviderAnthropic       = "anthropic"
+	DefaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	DefaultAnthropicModel   = "claude-sonnet-4-5"
+
+	// AnthropicAPIVersion Messages API 版本（anthropic-version 请求头）
+	AnthropicAPIVersion = "2023-06-01"
+)
+
+// anthropicRetryableStatus Anthropic 可重试的错误码
+// 429 rate_limit_error / 500 api_error / 529 overloaded_error
+var anthropicRetryableStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	529:                            true,
+}
+
+// AnthropicClient Anthropic Messages API 客户端
+// 与 OpenAI 兼容接口的区别：系统提示词放在顶层 system 字段，响应内容为 content 块数组
+type AnthropicClient struct {
+	*Client
+}
+
+// NewAnthropicClient 创建 Anthropic 客户端（向前兼容）
+//
+// Deprecated: 推荐使用 NewAnthropicClientWithOptions 以获得更好的灵活性
+func NewAnthropicClient() AIClient {
+	return NewAnthropicClientWithOptions()
+}
+
+// NewAnthropicClientWithOptions 创建 Anthropic 客户端（支持选项模式）
+//
+// 使用示例：
+//   client := mcp.NewAnthropicClientWithOptions(
+//       mcp.WithAPIKey("sk-ant-xxx"),
+//       mcp.WithModel("claude-haiku-4-5"),
+//   )
+func NewAnthropicClientWithOptions(opts ...ClientOption) AIClient {
+	// 1. 创建 Anthropic 预设选项
+	anthropicOpts := []ClientOption{
+		WithProvider(ProviderAnthropic),
+		WithModel(DefaultAnthropicModel),
+		WithBaseURL(DefaultAnthropicBaseURL),
+	}
+
+	// 2. 合并用户选项（用户选项优先级更高）
+	allOpts := append(anthropicOpts, opts...)
+
+	// 3. 创建基础客户端
+	baseClient := NewClient(allOpts...).(*Client)
+
+	// 4. 创建 Anthropic 客户端
+	anthropicClient := &AnthropicClient{
+		Client: baseClient,
+	}
+
+	// 5. 设置 hooks 指向 AnthropicClient（实现动态分派）
+	baseClient.hooks = anthropicClient
+
+	return anthropicClient
+}
+
+func (anthropicClient *AnthropicClient) SetAPIKey(apiKey string, customURL string, customModel string) {
+	anthropicClient.APIKey = apiKey
+
+	if len(apiKey) > 8 {
+		anthropicClient.logger.Infof("🔧 [MCP] Anthropic API Key: %s...%s", apiKey[:4], apiKey[len(apiKey)-4:])
+	}
+	if customURL != "" {
+		anthropicClient.BaseURL = strings.TrimSuffix(customURL, "/")
+		anthropicClient.logger.Infof("🔧 [MCP] Anthropic 使用自定义 BaseURL: %s", customURL)
+	} else {
+		anthropicClient.logger.Infof("🔧 [MCP] Anthropic 使用默认 BaseURL: %s", anthropicClient.BaseURL)
+	}
+	if customModel != "" {
+		anthropicClient.Model = customModel
+		anthropicClient.logger.Infof("🔧 [MCP] Anthropic 使用自定义 Model: %s", customModel)
+	} else {
+		anthropicClient.logger.Infof("🔧 [MCP] Anthropic 使用默认 Model: %s", anthropicClient.Model)
+	}
+}
+
+func (anthropicClient *AnthropicClient) setAuthHeader(reqHeaders http.Header) {
+	reqHeaders.Set("x-api-key", anthropicClient.APIKey)
+	reqHeaders.Set("anthropic-version", AnthropicAPIVersion)
+}
+
+func (anthropicClient *AnthropicClient) buildUrl() string {
+	if anthropicClient.UseFullURL {
+		return anthropicClient.BaseURL
+	}
+	return fmt.Sprintf("%s/messages", anthropicClient.BaseURL)
+}
+
+func (anthropicClient *AnthropicClient) buildMCPRequestBody(systemPrompt, userPrompt string) map[string]any {
+	requestBody := map[string]any{
+		"model": anthropicClient.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": userPrompt},
+		},
+		"temperature": anthropicClient.config.Temperature,
+		"max_tokens":  anthropicClient.MaxTokens,
+	}
+	if systemPrompt != "" {
+		requestBody["system"] = systemPrompt
+	}
+	return requestBody
+}
+
+func (anthropicClient *AnthropicClient) buildRequestBodyFromRequest(req *Request) map[string]any {
+	system, conversation := splitSystemMessages(req.Messages)
+
+	messages := make([]map[string]string, 0, len(conversation))
+	for _, msg := range conversation {
+		messages = append(messages, map[string]string{
+			"role":    msg.Role,
+			"content": msg.Content,
+		})
+	}
+
+	requestBody := map[string]any{
+		"model":       req.Model,
+		"messages":    messages,
+		"temperature": anthropicClient.config.Temperature,
+		"max_tokens":  anthropicClient.MaxTokens, // Anthropic 要求必须设置 max_tokens
+	}
+	if system != "" {
+		requestBody["system"] = system
+	}
+	if req.Temperature != nil {
+		requestBody["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		requestBody["max_tokens"] = *req.MaxTokens
+	}
+	if req.TopP != nil {
+		requestBody["top_p"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		requestBody["stop_sequences"] = req.Stop
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			tools = append(tools, map[string]any{
+				"name":         tool.Function.Name,
+				"description":  tool.Function.Description,
+				"input_schema": tool.Function.Parameters,
+			})
+		}
+		requestBody["tools"] = tools
+	}
+	// frequency/presence penalty 不被 Messages API 支持，忽略
+
+	return requestBody
+}
+
+func (anthropicClient *AnthropicClient) parseMCPResponse(body []byte) (string, error) {
+	var result struct {
+		Type  string `json:"type"`
+		Model string `json:"model"`
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			Thinking string `json:"thinking"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Usage      *struct {
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		} `json:"usage"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.Type == "error" && result.Error != nil {
+		return "", fmt.Errorf("Anthropic返回错误 (%s): %s", result.Error.Type, result.Error.Message)
+	}
+
+	// 拼接文本块，thinking 块作为思维链（忽略 tool_use 等其他块）
+	var text, thinking strings.Builder
+	for _, block := range result.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("API返回空响应 (stop_reason: %s)", result.StopReason)
+	}
+
+	// 记录 token 用量（input_tokens 不含缓存部分，这里统一折算为总输入）
+	var usage *Usage
+	if u := result.Usage; u != nil {
+		model := result.Model
+		if model == "" {
+			model = anthropicClient.Model
+		}
+		prompt := u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+		usage = &Usage{
+			Model:            model,
+			PromptTokens:     prompt,
+			CompletionTokens: u.OutputTokens,
+			CachedTokens:     u.CacheReadInputTokens,
+			TotalTokens:      prompt + u.OutputTokens,
+		}
+	}
+	anthropicClient.setLastResult(NewResult(text.String(), thinking.String(), result.StopReason, usage))
+
+	return text.String(), nil
+}
+
+// callStream Anthropic 的流式格式与 OpenAI 不兼容，完整调用后作为单个片段推送
+func (anthropicClient *AnthropicClient) callStream(req *Request, onChunk StreamHandler) (string, error) {
+	return anthropicClient.callStreamFallback(req, onChunk)
+}
+
+// isRetryableError 限流、过载和服务端错误可重试，其余按通用规则判断
+func (anthropicClient *AnthropicClient) isRetryableError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return anthropicRetryableStatus[apiErr.StatusCode]
+	}
+	return anthropicClient.Client.isRetryableError(err)
+}
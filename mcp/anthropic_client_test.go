@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ============================================================
+// 测试 AnthropicClient（使用本地 httptest 模拟 Messages API）
+// ============================================================
+
+func newAnthropicTestServer(t *testing.T, handler func(w http.ResponseWriter, body map[string]any)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "sk-ant-test-key" {
+			t.Errorf("x-api-key header not set, got %q", r.Header.Get("x-api-key"))
+		}
+		if r.Header.Get("anthropic-version") != AnthropicAPIVersion {
+			t.Errorf("anthropic-version header not set")
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Authorization header should not be set")
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request body: %v", err)
+		}
+		handler(w, body)
+	}))
+}
+
+func TestNewAnthropicClient_Default(t *testing.T) {
+	client, ok := NewAnthropicClient().(*AnthropicClient)
+	if !ok {
+		t.Fatal("client should be *AnthropicClient")
+	}
+	if client.Provider != ProviderAnthropic || client.BaseURL != DefaultAnthropicBaseURL || client.Model != DefaultAnthropicModel {
+		t.Errorf("unexpected defaults: %s %s %s", client.Provider, client.BaseURL, client.Model)
+	}
+	if client.hooks != client {
+		t.Error("hooks should point to AnthropicClient")
+	}
+}
+
+func TestAnthropicClient_CallWithMessages(t *testing.T) {
+	server := newAnthropicTestServer(t, func(w http.ResponseWriter, body map[string]any) {
+		if body["system"] != "system prompt" {
+			t.Errorf("system prompt should be top-level, got %v", body["system"])
+		}
+		messages := body["messages"].([]any)
+		if len(messages) != 1 || messages[0].(map[string]any)["role"] != "user" {
+			t.Errorf("messages should only contain user message, got %v", messages)
+		}
+		if body["max_tokens"] == nil {
+			t.Error("max_tokens is required")
+		}
+		w.Write([]byte(`{"type":"message","model":"claude-sonnet-4-5-20250929","content":[{"type":"thinking","thinking":"..."},{"type":"text","text":"hello "},{"type":"text","text":"world"}],"stop_reason":"end_turn","usage":{"input_tokens":100,"output_tokens":20,"cache_read_input_tokens":50}}`))
+	})
+	defer server.Close()
+
+	client := NewAnthropicClientWithOptions(
+		WithBaseURL(server.URL),
+		WithAPIKey("sk-ant-test-key"),
+		WithLogger(NewMockLogger()),
+	)
+
+	result, err := client.CallWithMessages("system prompt", "user prompt")
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if result != "hello world" {
+		t.Errorf("unexpected result: %q", result)
+	}
+
+	usage := LastUsageOf(client)
+	want := Usage{Model: "claude-sonnet-4-5-20250929", PromptTokens: 150, CompletionTokens: 20, CachedTokens: 50, TotalTokens: 170}
+	if usage == nil || *usage != want {
+		t.Errorf("got usage %+v, want %+v", usage, want)
+	}
+}
+
+func TestAnthropicClient_CallWithRequest(t *testing.T) {
+	server := newAnthropicTestServer(t, func(w http.ResponseWriter, body map[string]any) {
+		if body["system"] != "s1\n\ns2" {
+			t.Errorf("system messages should be merged, got %v", body["system"])
+		}
+		if len(body["messages"].([]any)) != 3 {
+			t.Errorf("expected 3 conversation messages, got %v", body["messages"])
+		}
+		if body["stop_sequences"] == nil || body["frequency_penalty"] != nil {
+			t.Errorf("unexpected params: %v", body)
+		}
+		w.Write([]byte(`{"type":"message","content":[{"type":"text","text":"ok"}]}`))
+	})
+	defer server.Close()
+
+	client := NewAnthropicClientWithOptions(
+		WithBaseURL(server.URL),
+		WithAPIKey("sk-ant-test-key"),
+		WithLogger(NewMockLogger()),
+	)
+
+	req := NewRequestBuilder().
+		AddSystemMessage("s1").
+		AddSystemMessage("s2").
+		AddUserMessage("q1").
+		AddAssistantMessage("a1").
+		AddUserMessage("q2").
+		WithStopSequences([]string{"END"}).
+		WithFrequencyPenalty(0.5).
+		MustBuild()
+
+	if result, err := client.CallWithRequest(req); err != nil || result != "ok" {
+		t.Fatalf("unexpected result: %q, %v", result, err)
+	}
+}
+
+func TestAnthropicClient_ErrorCodes(t *testing.T) {
+	var calls int32
+	status := int32(http.StatusBadRequest)
+	server := newAnthropicTestServer(t, func(w http.ResponseWriter, body map[string]any) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`))
+	})
+	defer server.Close()
+
+	client := NewAnthropicClientWithOptions(
+		WithBaseURL(server.URL),
+		WithAPIKey("sk-ant-test-key"),
+		WithLogger(NewMockLogger()),
+		WithMaxRetries(3),
+		WithRetryWaitBase(time.Millisecond),
+	)
+
+	// 400 不可重试
+	if _, err := client.CallWithMessages("", "user"); err == nil {
+		t.Fatal("should error")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("400 should not retry, got %d calls", calls)
+	}
+
+	// 529 overloaded 可重试
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&status, 529)
+	if _, err := client.CallWithMessages("", "user"); err == nil {
+		t.Fatal("should error")
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("529 should retry, got %d calls", calls)
+	}
+}
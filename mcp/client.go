@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -46,6 +47,9 @@ type Client struct {
 	logger     Logger // 日志器（可替换）
 	config     *Config // 配置对象（保存所有配置）
 
+	resultMu   sync.Mutex
+	lastResult *Result // 最近一次成功调用的结构化结果（含 token 用量）
+
 	// hooks 用于实现动态分派（多态）
 	// 当 DeepSeekClient 嵌入 Client 时，hooks 指向 DeepSeekClient
 	// 这样 call() 中调用的方法会自动分派到子类重写的版本
@@ -133,11 +137,34 @@ func (client *Client) SetTimeout(timeout time.Duration) {
 	client.httpClient.Timeout = timeout
 }
 
+// Timeout 请求超时（故障转移客户端据此推算响应耗时阈值）
+func (client *Client) Timeout() time.Duration {
+	return client.httpClient.Timeout
+}
+
+// Clone 复制客户端配置（不复制用量记录），返回的客户端使用 OpenAI 兼容的默认流程
+func (client *Client) Clone() *Client {
+	cp := &Client{
+		Provider:   client.Provider,
+		APIKey:     client.APIKey,
+		BaseURL:    client.BaseURL,
+		Model:      client.Model,
+		UseFullURL: client.UseFullURL,
+		MaxTokens:  client.MaxTokens,
+		httpClient: client.httpClient,
+		logger:     client.logger,
+		config:     client.config,
+	}
+	cp.hooks = cp
+	return cp
+}
+
 // CallWithMessages 模板方法 - 固定的重试流程（不可重写）
 func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
-	if client.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
+	if err := client.checkAPIKey(); err != nil {
+		return "", err
 	}
+	client.setLastResult(nil)
 
 	// 固定的重试流程
 	var lastErr error
@@ -154,7 +181,7 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 			if attempt > 1 {
 				client.logger.Infof("✓ AI API重试成功")
 			}
-			return result, nil
+			return stripThinking(result), nil
 		}
 
 		lastErr = err
@@ -174,6 +201,14 @@ func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string,
 	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
 }
 
+// checkAPIKey 检查是否已设置 API Key（无需认证的本地模型服务跳过）
+func (client *Client) checkAPIKey() error {
+	if client.APIKey == "" && !client.config.NoAuth {
+		return fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
+	}
+	return nil
+}
+
 func (client *Client) setAuthHeader(reqHeader http.Header) {
 	reqHeader.Set("Authorization", fmt.Sprintf("Bearer %s", client.APIKey))
 }
@@ -218,8 +253,11 @@ func (client *Client) parseMCPResponse(body []byte) (string, error) {
 	var result struct {
 		Choices []struct {
 			Message struct {
-				Content string `json:"content"`
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"` // DeepSeek / Qwen 等推理模型
+				Reasoning        string `json:"reasoning"`         // 部分聚合平台使用的字段名
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 	}
 
@@ -231,7 +269,15 @@ func (client *Client) parseMCPResponse(body []byte) (string, error) {
 		return "", fmt.Errorf("API返回空响应")
 	}
 
-	return result.Choices[0].Message.Content, nil
+	// 记录结构化结果和 token 用量（用于思维链和成本统计）
+	choice := result.Choices[0]
+	reasoning := choice.Message.ReasoningContent
+	if reasoning == "" {
+		reasoning = choice.Message.Reasoning
+	}
+	client.setLastResult(NewResult(choice.Message.Content, reasoning, choice.FinishReason, parseUsage(body, client.Model)))
+
+	return choice.Message.Content, nil
 }
 
 func (client *Client) buildUrl() string {
@@ -299,7 +345,7 @@ func (client *Client) call(systemPrompt, userPrompt string) (string, error) {
 
 	// Step 7: 检查 HTTP 状态码（固定逻辑）
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	// Step 8: 解析响应（通过 hooks 实现动态分派）
@@ -311,6 +357,21 @@ func (client *Client) call(systemPrompt, userPrompt string) (string, error) {
 	return result, nil
 }
 
+// APIError AI 服务返回的非 200 响应（保留状态码，便于各提供商按错误码判断是否重试）
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API返回错误 (status %d): %s", e.StatusCode, e.Body)
+}
+
+// ProviderName 客户端的提供商（deepseek/qwen/anthropic/custom 等）
+func (client *Client) ProviderName() string {
+	return client.Provider
+}
+
 func (client *Client) String() string {
 	return fmt.Sprintf("[Provider: %s, Model: %s]",
 		client.Provider, client.Model)
@@ -348,9 +409,10 @@ func (client *Client) isRetryableError(err error) bool {
 //       Build()
 //   result, err := client.CallWithRequest(request)
 func (client *Client) CallWithRequest(req *Request) (string, error) {
-	if client.APIKey == "" {
-		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
+	if err := client.checkAPIKey(); err != nil {
+		return "", err
 	}
+	client.setLastResult(nil)
 
 	// 如果 Request 中没有设置 Model，使用 Client 的 Model
 	if req.Model == "" {
@@ -372,7 +434,7 @@ func (client *Client) CallWithRequest(req *Request) (string, error) {
 			if attempt > 1 {
 				client.logger.Infof("✓ AI API重试成功")
 			}
-			return result, nil
+			return stripThinking(result), nil
 		}
 
 		lastErr = err
@@ -398,8 +460,8 @@ func (client *Client) callWithRequest(req *Request) (string, error) {
 	client.logger.Infof("📡 [%s] Request AI Server with Builder: BaseURL: %s", client.String(), client.BaseURL)
 	client.logger.Debugf("[%s] Messages count: %d", client.String(), len(req.Messages))
 
-	// 构建请求体（从 Request 对象）
-	requestBody := client.buildRequestBodyFromRequest(req)
+	// 构建请求体（从 Request 对象，通过 hooks 实现动态分派）
+	requestBody := client.hooks.buildRequestBodyFromRequest(req)
 
 	// 序列化请求体
 	jsonData, err := client.hooks.marshalRequestBody(requestBody)
@@ -432,7 +494,7 @@ func (client *Client) callWithRequest(req *Request) (string, error) {
 
 	// 检查 HTTP 状态码
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	// 解析响应
@@ -2,7 +2,9 @@ package mcp
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -114,6 +116,29 @@ func TestClient_CallWithMessages_Success(t *testing.T) {
 	}
 }
 
+func TestClient_CallWithMessages_StripsThinking(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.SetSuccessResponse("<think>先看BTC走势</think> 观望")
+
+	client := NewClient(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("test-key"),
+		WithBaseURL("https://api.test.com"),
+	)
+
+	result, err := client.CallWithMessages("system prompt", "user prompt")
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if result != "观望" {
+		t.Errorf("think block should be stripped, got %q", result)
+	}
+	if last := client.(*Client).LastResult(); last == nil || last.Reasoning != "先看BTC走势" {
+		t.Errorf("reasoning should be kept in the structured result: %+v", last)
+	}
+}
+
 func TestClient_CallWithMessages_NoAPIKey(t *testing.T) {
 	client := NewClient()
 
@@ -404,6 +429,52 @@ func TestClient_String(t *testing.T) {
 	}
 }
 
+// ============================================================
+// 测试 CloneClient
+// ============================================================
+
+func TestCloneClient(t *testing.T) {
+	tests := []struct {
+		name   string
+		client AIClient
+	}{
+		{"generic", NewClient()},
+		{"deepseek", NewDeepSeekClientWithOptions()},
+		{"qwen", NewQwenClientWithOptions()},
+		{"anthropic", NewAnthropicClientWithOptions()},
+		{"gemini", NewGeminiClientWithOptions()},
+		{"ollama", NewOllamaClientWithOptions()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cp, err := CloneClient(tt.client)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if reflect.TypeOf(cp) != reflect.TypeOf(tt.client) {
+				t.Fatalf("clone type = %T, want %T", cp, tt.client)
+			}
+
+			// 副本的 hooks 指向副本自身，修改副本不影响原客户端
+			cp.SetAPIKey("sk-clone-key", "http://clone.local", "clone-model")
+			base, ok := cp.(*Client)
+			if !ok {
+				base = reflect.ValueOf(cp).Elem().FieldByName("Client").Interface().(*Client)
+			}
+			if base.hooks != cp.(clientHooks) {
+				t.Errorf("clone hooks = %T, want the clone itself", base.hooks)
+			}
+			if orig := tt.client.(fmt.Stringer).String(); contains(orig, "clone-model") {
+				t.Errorf("original client modified: %s", orig)
+			}
+		})
+	}
+
+	if _, err := CloneClient(NewFailoverClient(nil, FailoverConfig{})); err == nil {
+		t.Error("failover client should not be clonable")
+	}
+}
+
 // 辅助函数
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) && findSubstring(s, substr))
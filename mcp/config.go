@@ -21,6 +21,7 @@ type Config struct {
 	MaxTokens   int
 	Temperature float64
 	UseFullURL  bool
+	NoAuth      bool // 无需 API Key（本地模型服务），不发送认证头
 
 	// 重试配置
 	MaxRetries     int
@@ -0,0 +1,354 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 熔断器状态
+const (
+	CircuitClosed   = "closed"    // 正常
+	CircuitOpen     = "open"      // 熔断中，跳过该提供商
+	CircuitHalfOpen = "half_open" // 冷却结束，允许一次试探请求
+)
+
+const (
+	DefaultFailureThreshold = 3
+	DefaultLatencyThreshold = 90 * time.Second // 无法获取客户端超时时使用
+	DefaultCircuitCooldown  = 5 * time.Minute
+
+	// latencyThresholdRatio 未配置耗时阈值时取客户端超时的比例（默认 120s 超时对应 90s）
+	latencyThresholdRatio = 0.75
+)
+
+// FailoverConfig 故障转移与熔断参数（零值使用默认值）
+type FailoverConfig struct {
+	FailureThreshold int           // 连续失败多少次后熔断
+	LatencyThreshold time.Duration // 单次调用超过该耗时记为失败（结果仍然返回），0 时按各提供商客户端的超时推算，小于 0 表示不检查
+	Cooldown         time.Duration // 熔断后经过多久进入半开状态
+	Logger           Logger
+}
+
+// FailoverTarget 故障转移链中的一个提供商
+type FailoverTarget struct {
+	Name     string // 显示名称（如 AI 模型 ID），记录在结果的 Target 中
+	Provider string // 提供商，记录在结果的 Provider 中（为空时取客户端的提供商，仍为空时使用 Name）
+	Client   AIClient
+}
+
+// ProviderStatus 提供商的熔断状态
+type ProviderStatus struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalCalls          int        `json:"total_calls"`
+	TotalFailures       int        `json:"total_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastLatencyMs       int64      `json:"last_latency_ms"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+type failoverMember struct {
+	FailoverTarget
+	state            string
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight bool
+	totalCalls       int
+	totalFailures    int
+	lastError        string
+	lastLatency      time.Duration
+}
+
+// provider 成员的提供商（未指定时取客户端的提供商，仍为空时使用 Name）
+func (member *failoverMember) provider() string {
+	if member.Provider != "" {
+		return member.Provider
+	}
+	if provider := ProviderOf(member.Client); provider != "" {
+		return provider
+	}
+	return member.Name
+}
+
+// FailoverClient 按顺序调用多个提供商的客户端
+// 每个提供商有独立的熔断器：连续失败或响应过慢达到阈值后熔断，冷却后半开试探，成功则恢复
+type FailoverClient struct {
+	members []*failoverMember
+	config  FailoverConfig
+	logger  Logger
+	now     func() time.Time
+
+	mu         sync.Mutex
+	lastResult *Result
+}
+
+// NewFailoverClient 创建故障转移客户端（targets 按优先级排序）
+//
+// 使用示例：
+//   client := mcp.NewFailoverClient([]mcp.FailoverTarget{
+//       {Name: "deepseek", Client: deepseekClient},
+//       {Name: "qwen", Client: qwenClient},
+//   }, mcp.FailoverConfig{FailureThreshold: 2})
+func NewFailoverClient(targets []FailoverTarget, cfg FailoverConfig) *FailoverClient {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultCircuitCooldown
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = DefaultConfig().Logger
+	}
+
+	client := &FailoverClient{
+		config: cfg,
+		logger: cfg.Logger,
+		now:    time.Now,
+	}
+	for _, target := range targets {
+		client.members = append(client.members, &failoverMember{FailoverTarget: target, state: CircuitClosed})
+	}
+	return client
+}
+
+// SetAPIKey 设置首选提供商的 API Key（备用提供商在创建时已配置）
+func (client *FailoverClient) SetAPIKey(apiKey string, customURL string, customModel string) {
+	if len(client.members) > 0 {
+		client.members[0].Client.SetAPIKey(apiKey, customURL, customModel)
+	}
+}
+
+// ProviderName 首选提供商
+func (client *FailoverClient) ProviderName() string {
+	if len(client.members) == 0 {
+		return ""
+	}
+	return client.members[0].provider()
+}
+
+func (client *FailoverClient) SetTimeout(timeout time.Duration) {
+	for _, member := range client.members {
+		member.Client.SetTimeout(timeout)
+	}
+}
+
+func (client *FailoverClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return client.do(func(c AIClient) (string, error) {
+		return c.CallWithMessages(systemPrompt, userPrompt)
+	}, nil)
+}
+
+func (client *FailoverClient) CallWithRequest(req *Request) (string, error) {
+	return client.do(func(c AIClient) (string, error) {
+		// 各提供商使用自己的模型
+		memberReq := *req
+		memberReq.Model = ""
+		return c.CallWithRequest(&memberReq)
+	}, nil)
+}
+
+func (client *FailoverClient) CallWithMessagesResult(systemPrompt, userPrompt string) (*Result, error) {
+	content, err := client.CallWithMessages(systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+	if result := client.LastResult(); result != nil {
+		return result, nil
+	}
+	return NewResult(content, "", "", nil), nil
+}
+
+// CallWithMessagesStream 流式调用；已经推送过片段后失败不再切换提供商（避免重复内容）
+func (client *FailoverClient) CallWithMessagesStream(systemPrompt, userPrompt string, onChunk StreamHandler) (string, error) {
+	emitted := false
+	return client.do(func(c AIClient) (string, error) {
+		return c.CallWithMessagesStream(systemPrompt, userPrompt, func(chunk StreamChunk) {
+			emitted = true
+			if onChunk != nil {
+				onChunk(chunk)
+			}
+		})
+	}, func() bool { return !emitted })
+}
+
+func (client *FailoverClient) CallWithRequestStream(req *Request, onChunk StreamHandler) (string, error) {
+	emitted := false
+	return client.do(func(c AIClient) (string, error) {
+		memberReq := *req
+		memberReq.Model = ""
+		return c.CallWithRequestStream(&memberReq, func(chunk StreamChunk) {
+			emitted = true
+			if onChunk != nil {
+				onChunk(chunk)
+			}
+		})
+	}, func() bool { return !emitted })
+}
+
+// do 按顺序尝试可用的提供商，canFailover 返回 false 时不再尝试下一个
+func (client *FailoverClient) do(call func(AIClient) (string, error), canFailover func() bool) (string, error) {
+	client.setLastResult(nil)
+
+	var errs []string
+	for i, member := range client.members {
+		if !client.allow(member) {
+			errs = append(errs, fmt.Sprintf("%s: 熔断中", member.Name))
+			continue
+		}
+		if i > 0 && len(errs) > 0 {
+			client.logger.Warnf("🔀 [MCP Failover] 切换到备用提供商 %s", member.Name)
+		}
+
+		start := client.now()
+		content, err := call(member.Client)
+		latency := client.now().Sub(start)
+		if err == nil {
+			client.recordSuccess(member, latency)
+			client.recordResult(member, content)
+			return content, nil
+		}
+
+		client.recordFailure(member, err.Error(), latency)
+		errs = append(errs, fmt.Sprintf("%s: %v", member.Name, err))
+		if canFailover != nil && !canFailover() {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("所有AI提供商均不可用: %s", strings.Join(errs, "; "))
+}
+
+// allow 判断是否可以调用该提供商（冷却结束的熔断器进入半开状态，只放行一次试探请求）
+func (client *FailoverClient) allow(member *failoverMember) bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	switch member.state {
+	case CircuitOpen:
+		if client.now().Sub(member.openedAt) < client.config.Cooldown {
+			return false
+		}
+		member.state = CircuitHalfOpen
+		member.halfOpenInFlight = true
+		client.logger.Infof("🔌 [MCP Failover] %s 冷却结束，半开试探", member.Name)
+		return true
+	case CircuitHalfOpen:
+		if member.halfOpenInFlight {
+			return false
+		}
+		member.halfOpenInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// latencyThreshold 成员的响应耗时阈值：未配置时取客户端超时的 75%（本地模型超时较长，阈值随之放宽）
+func (client *FailoverClient) latencyThreshold(member *failoverMember) time.Duration {
+	if client.config.LatencyThreshold != 0 {
+		return client.config.LatencyThreshold
+	}
+	if timed, ok := member.Client.(interface{ Timeout() time.Duration }); ok && timed.Timeout() > 0 {
+		return time.Duration(float64(timed.Timeout()) * latencyThresholdRatio)
+	}
+	return DefaultLatencyThreshold
+}
+
+func (client *FailoverClient) recordSuccess(member *failoverMember, latency time.Duration) {
+	threshold := client.latencyThreshold(member)
+	if threshold > 0 && latency > threshold {
+		client.recordFailure(member, fmt.Sprintf("响应耗时 %v 超过阈值 %v", latency.Round(time.Millisecond), threshold), latency)
+		return
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if member.state != CircuitClosed {
+		client.logger.Infof("✅ [MCP Failover] %s 已恢复", member.Name)
+	}
+	member.state = CircuitClosed
+	member.failures = 0
+	member.halfOpenInFlight = false
+	member.totalCalls++
+	member.lastLatency = latency
+}
+
+func (client *FailoverClient) recordFailure(member *failoverMember, errMsg string, latency time.Duration) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	member.failures++
+	member.totalCalls++
+	member.totalFailures++
+	member.lastError = errMsg
+	member.lastLatency = latency
+	member.halfOpenInFlight = false
+
+	if member.state == CircuitHalfOpen || member.failures >= client.config.FailureThreshold {
+		member.state = CircuitOpen
+		member.openedAt = client.now()
+		client.logger.Warnf("⛔ [MCP Failover] %s 熔断 %v（连续失败 %d 次: %s）",
+			member.Name, client.config.Cooldown, member.failures, errMsg)
+	}
+}
+
+// recordResult 记录实际应答的提供商
+func (client *FailoverClient) recordResult(member *failoverMember, content string) {
+	result := NewResult(content, "", "", nil)
+	if memberResult := LastResultOf(member.Client); memberResult != nil {
+		copied := *memberResult
+		result = &copied
+	}
+	result.Provider = member.provider()
+	result.Target = member.Name
+	client.setLastResult(result)
+}
+
+// Status 各提供商的熔断状态（按优先级排序）
+func (client *FailoverClient) Status() []ProviderStatus {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	statuses := make([]ProviderStatus, 0, len(client.members))
+	for _, member := range client.members {
+		status := ProviderStatus{
+			Name:                member.Name,
+			State:               member.state,
+			ConsecutiveFailures: member.failures,
+			TotalCalls:          member.totalCalls,
+			TotalFailures:       member.totalFailures,
+			LastError:           member.lastError,
+			LastLatencyMs:       member.lastLatency.Milliseconds(),
+		}
+		if member.state != CircuitClosed {
+			openedAt := member.openedAt
+			status.OpenedAt = &openedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// LastResult 最近一次成功调用的结构化结果（Provider 为实际应答的提供商）
+func (client *FailoverClient) LastResult() *Result {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.lastResult
+}
+
+// LastUsage 最近一次成功调用的用量
+func (client *FailoverClient) LastUsage() *Usage {
+	if result := client.LastResult(); result != nil {
+		return result.Usage
+	}
+	return nil
+}
+
+func (client *FailoverClient) setLastResult(result *Result) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.lastResult = result
+}
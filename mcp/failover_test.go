@@ -0,0 +1,273 @@
+package mcp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAIClient 按预设结果应答的 AIClient
+type fakeAIClient struct {
+	err     error
+	content string
+	calls   int
+	onCall  func()
+	timeout time.Duration
+}
+
+func (f *fakeAIClient) SetAPIKey(apiKey string, customURL string, customModel string) {}
+func (f *fakeAIClient) SetTimeout(timeout time.Duration)                              { f.timeout = timeout }
+func (f *fakeAIClient) Timeout() time.Duration                                        { return f.timeout }
+
+func (f *fakeAIClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	f.calls++
+	if f.onCall != nil {
+		f.onCall()
+	}
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.content, nil
+}
+
+func (f *fakeAIClient) CallWithRequest(req *Request) (string, error) {
+	return f.CallWithMessages("", "")
+}
+
+func (f *fakeAIClient) CallWithMessagesResult(systemPrompt, userPrompt string) (*Result, error) {
+	content, err := f.CallWithMessages(systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+	return NewResult(content, "", "", nil), nil
+}
+
+func (f *fakeAIClient) CallWithMessagesStream(systemPrompt, userPrompt string, onChunk StreamHandler) (string, error) {
+	f.calls++
+	if f.err != nil {
+		onChunk(StreamChunk{Content: "partial"})
+		return "", f.err
+	}
+	onChunk(StreamChunk{Content: f.content})
+	return f.content, nil
+}
+
+func (f *fakeAIClient) CallWithRequestStream(req *Request, onChunk StreamHandler) (string, error) {
+	return f.CallWithMessagesStream("", "", onChunk)
+}
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestFailoverClient(cfg FailoverConfig, clients ...*fakeAIClient) (*FailoverClient, *fakeClock) {
+	targets := make([]FailoverTarget, 0, len(clients))
+	for i, c := range clients {
+		targets = append(targets, FailoverTarget{Name: []string{"primary", "backup", "third"}[i], Client: c})
+	}
+	cfg.Logger = NewMockLogger()
+	client := NewFailoverClient(targets, cfg)
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	client.now = clock.now
+	return client, clock
+}
+
+func TestFailoverClient_FallsBackToNextProvider(t *testing.T) {
+	primary := &fakeAIClient{err: errors.New("connection refused")}
+	backup := &fakeAIClient{content: "from backup"}
+	client, _ := newTestFailoverClient(FailoverConfig{}, primary, backup)
+
+	result, err := client.CallWithMessagesResult("system", "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Content != "from backup" {
+		t.Errorf("content = %q, want from backup", result.Content)
+	}
+	if result.Provider != "backup" {
+		t.Errorf("provider = %q, want backup", result.Provider)
+	}
+
+	status := client.Status()
+	if status[0].State != CircuitClosed || status[0].ConsecutiveFailures != 1 {
+		t.Errorf("primary status = %+v, want closed with 1 failure", status[0])
+	}
+}
+
+func TestFailoverClient_RecordsProviderAndTarget(t *testing.T) {
+	primary := &fakeAIClient{err: errors.New("connection refused")}
+	backup := NewQwenClientWithOptions(WithLogger(NewMockLogger()))
+	client := NewFailoverClient([]FailoverTarget{
+		{Name: "deepseek", Provider: ProviderDeepSeek, Client: primary},
+		{Name: "user1_qwen_backup", Client: backup},
+	}, FailoverConfig{Logger: NewMockLogger()})
+
+	if got := ProviderOf(client); got != ProviderDeepSeek {
+		t.Errorf("ProviderOf(failover) = %q, want %s", got, ProviderDeepSeek)
+	}
+	// 未指定提供商时取客户端的提供商
+	if got := client.members[1].provider(); got != ProviderQwen {
+		t.Errorf("backup provider = %q, want %s", got, ProviderQwen)
+	}
+	// 客户端也不支持时使用名称
+	if got := (&failoverMember{FailoverTarget: FailoverTarget{Name: "fake", Client: primary}}).provider(); got != "fake" {
+		t.Errorf("fallback provider = %q, want fake", got)
+	}
+}
+
+func TestFailoverClient_OpensCircuitAfterThreshold(t *testing.T) {
+	primary := &fakeAIClient{err: errors.New("503 service unavailable")}
+	backup := &fakeAIClient{content: "ok"}
+	client, _ := newTestFailoverClient(FailoverConfig{FailureThreshold: 2}, primary, backup)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.CallWithMessages("s", "u"); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+	}
+	if state := client.Status()[0].State; state != CircuitOpen {
+		t.Fatalf("primary state = %s, want open", state)
+	}
+
+	// 熔断期间不再调用主提供商
+	if _, err := client.CallWithMessages("s", "u"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if primary.calls != 2 {
+		t.Errorf("primary calls = %d, want 2", primary.calls)
+	}
+	if backup.calls != 3 {
+		t.Errorf("backup calls = %d, want 3", backup.calls)
+	}
+}
+
+func TestFailoverClient_HalfOpenAfterCooldown(t *testing.T) {
+	primary := &fakeAIClient{err: errors.New("timeout")}
+	backup := &fakeAIClient{content: "ok"}
+	client, clock := newTestFailoverClient(FailoverConfig{FailureThreshold: 1, Cooldown: time.Minute}, primary, backup)
+
+	client.CallWithMessages("s", "u")
+	if state := client.Status()[0].State; state != CircuitOpen {
+		t.Fatalf("primary state = %s, want open", state)
+	}
+
+	// 半开试探失败：重新熔断
+	clock.advance(2 * time.Minute)
+	client.CallWithMessages("s", "u")
+	if primary.calls != 2 {
+		t.Fatalf("primary calls = %d, want 2 (half-open trial)", primary.calls)
+	}
+	if state := client.Status()[0].State; state != CircuitOpen {
+		t.Fatalf("primary state = %s, want open after failed trial", state)
+	}
+
+	// 半开试探成功：恢复
+	clock.advance(2 * time.Minute)
+	primary.err = nil
+	primary.content = "recovered"
+	result, err := client.CallWithMessagesResult("s", "u")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Provider != "primary" || result.Content != "recovered" {
+		t.Errorf("result = %+v, want recovered from primary", result)
+	}
+	if status := client.Status()[0]; status.State != CircuitClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("primary status = %+v, want closed", status)
+	}
+}
+
+func TestFailoverClient_LatencySpikeCountsAsFailure(t *testing.T) {
+	var clock *fakeClock
+	primary := &fakeAIClient{content: "slow answer"}
+	primary.onCall = func() { clock.advance(2 * time.Minute) }
+	backup := &fakeAIClient{content: "ok"}
+	client, c := newTestFailoverClient(FailoverConfig{FailureThreshold: 1, LatencyThreshold: time.Minute}, primary, backup)
+	clock = c
+
+	// 慢响应的结果仍然返回，但会触发熔断
+	content, err := client.CallWithMessages("s", "u")
+	if err != nil || content != "slow answer" {
+		t.Fatalf("got (%q, %v), want slow answer", content, err)
+	}
+	status := client.Status()[0]
+	if status.State != CircuitOpen {
+		t.Fatalf("primary state = %s, want open", status.State)
+	}
+	if !strings.Contains(status.LastError, "超过阈值") {
+		t.Errorf("last error = %q, want latency message", status.LastError)
+	}
+
+	result, err := client.CallWithMessagesResult("s", "u")
+	if err != nil || result.Provider != "backup" {
+		t.Errorf("got (%+v, %v), want answer from backup", result, err)
+	}
+}
+
+// TestFailoverClient_LatencyThresholdFromTimeout 测试未配置阈值时按客户端超时推算（本地模型超时较长）
+func TestFailoverClient_LatencyThresholdFromTimeout(t *testing.T) {
+	var clock *fakeClock
+	local := &fakeAIClient{content: "slow but healthy", timeout: 600 * time.Second}
+	local.onCall = func() { clock.advance(5 * time.Minute) }
+	remote := &fakeAIClient{content: "ok"}
+	client, c := newTestFailoverClient(FailoverConfig{FailureThreshold: 1}, local, remote)
+	clock = c
+
+	if _, err := client.CallWithMessages("s", "u"); err != nil {
+		t.Fatal(err)
+	}
+	if status := client.Status()[0]; status.State != CircuitClosed || status.TotalFailures != 0 {
+		t.Errorf("5 分钟内应答的本地模型不应记为失败: %+v", status)
+	}
+
+	// 无法获取超时的客户端使用默认阈值
+	if got := client.latencyThreshold(client.members[1]); got != DefaultLatencyThreshold {
+		t.Errorf("threshold = %v, want %v", got, DefaultLatencyThreshold)
+	}
+	if got := client.latencyThreshold(client.members[0]); got != 450*time.Second {
+		t.Errorf("threshold = %v, want 450s", got)
+	}
+}
+
+func TestFailoverClient_AllProvidersUnavailable(t *testing.T) {
+	primary := &fakeAIClient{err: errors.New("down")}
+	backup := &fakeAIClient{err: errors.New("also down")}
+	client, _ := newTestFailoverClient(FailoverConfig{}, primary, backup)
+
+	_, err := client.CallWithMessages("s", "u")
+	if err == nil {
+		t.Fatal("expected error when every provider fails")
+	}
+	if !strings.Contains(err.Error(), "所有AI提供商均不可用") ||
+		!strings.Contains(err.Error(), "primary: down") || !strings.Contains(err.Error(), "backup: also down") {
+		t.Errorf("error = %v, want summary of every provider", err)
+	}
+	if client.LastResult() != nil {
+		t.Error("LastResult should be nil after failure")
+	}
+}
+
+func TestFailoverClient_StreamDoesNotFailoverAfterChunks(t *testing.T) {
+	primary := &fakeAIClient{err: errors.New("stream broken")}
+	backup := &fakeAIClient{content: "ok"}
+	client, _ := newTestFailoverClient(FailoverConfig{}, primary, backup)
+
+	var chunks []string
+	_, err := client.CallWithMessagesStream("s", "u", func(chunk StreamChunk) {
+		chunks = append(chunks, chunk.Content)
+	})
+	if err == nil || err.Error() != "stream broken" {
+		t.Fatalf("err = %v, want stream broken", err)
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup calls = %d, want 0 after chunks were emitted", backup.calls)
+	}
+	if len(chunks) != 1 {
+		t.Errorf("chunks = %v, want only the partial chunk", chunks)
+	}
+}
@@ -0,0 +1,255 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProviderGemini       = "gemini"
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultGeminiModel   = "gemini-2.5-flash"
+)
+
+// geminiRetryableStatus Gemini 可重试的错误码
+// 429 RESOURCE_EXHAUSTED / 500 INTERNAL / 503 UNAVAILABLE / 504 DEADLINE_EXCEEDED
+var geminiRetryableStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// GeminiClient Google Gemini generateContent 客户端
+// 与 OpenAI 兼容接口的区别：模型名在 URL 中，系统提示词放在 systemInstruction，助手角色为 model
+type GeminiClient struct {
+	*Client
+}
+
+// NewGeminiClient 创建 Gemini 客户端（向前兼容）
+//
+// Deprecated: 推荐使用 NewGeminiClientWithOptions 以获得更好的灵活性
+func NewGeminiClient() AIClient {
+	return NewGeminiClientWithOptions()
+}
+
+// NewGeminiClientWithOptions 创建 Gemini 客户端（支持选项模式）
+//
+// 使用示例：
+//   client := mcp.NewGeminiClientWithOptions(
+//       mcp.WithAPIKey("AIza-xxx"),
+//       mcp.WithModel("gemini-2.5-pro"),
+//   )
+func NewGeminiClientWithOptions(opts ...ClientOption) AIClient {
+	// 1. 创建 Gemini 预设选项
+	geminiOpts := []ClientOption{
+		WithProvider(ProviderGemini),
+		WithModel(DefaultGeminiModel),
+		WithBaseURL(DefaultGeminiBaseURL),
+	}
+
+	// 2. 合并用户选项（用户选项优先级更高）
+	allOpts := append(geminiOpts, opts...)
+
+	// 3. 创建基础客户端
+	baseClient := NewClient(allOpts...).(*Client)
+
+	// 4. 创建 Gemini 客户端
+	geminiClient := &GeminiClient{
+		Client: baseClient,
+	}
+
+	// 5. 设置 hooks 指向 GeminiClient（实现动态分派）
+	baseClient.hooks = geminiClient
+
+	return geminiClient
+}
+
+func (geminiClient *GeminiClient) SetAPIKey(apiKey string, customURL string, customModel string) {
+	geminiClient.APIKey = apiKey
+
+	if len(apiKey) > 8 {
+		geminiClient.logger.Infof("🔧 [MCP] Gemini API Key: %s...%s", apiKey[:4], apiKey[len(apiKey)-4:])
+	}
+	if customURL != "" {
+		geminiClient.BaseURL = strings.TrimSuffix(customURL, "/")
+		geminiClient.logger.Infof("🔧 [MCP] Gemini 使用自定义 BaseURL: %s", customURL)
+	} else {
+		geminiClient.logger.Infof("🔧 [MCP] Gemini 使用默认 BaseURL: %s", geminiClient.BaseURL)
+	}
+	if customModel != "" {
+		geminiClient.Model = customModel
+		geminiClient.logger.Infof("🔧 [MCP] Gemini 使用自定义 Model: %s", customModel)
+	} else {
+		geminiClient.logger.Infof("🔧 [MCP] Gemini 使用默认 Model: %s", geminiClient.Model)
+	}
+}
+
+// setAuthHeader 使用 x-goog-api-key 请求头（避免 API Key 出现在 URL 和日志中）
+func (geminiClient *GeminiClient) setAuthHeader(reqHeaders http.Header) {
+	reqHeaders.Set("x-goog-api-key", geminiClient.APIKey)
+}
+
+func (geminiClient *GeminiClient) buildUrl() string {
+	if geminiClient.UseFullURL {
+		return geminiClient.BaseURL
+	}
+	return fmt.Sprintf("%s/models/%s:generateContent", geminiClient.BaseURL, geminiClient.Model)
+}
+
+func (geminiClient *GeminiClient) buildMCPRequestBody(systemPrompt, userPrompt string) map[string]any {
+	messages := []Message{NewUserMessage(userPrompt)}
+	if systemPrompt != "" {
+		messages = append([]Message{NewSystemMessage(systemPrompt)}, messages...)
+	}
+	return geminiClient.buildRequestBodyFromRequest(&Request{Messages: messages})
+}
+
+// buildRequestBodyFromRequest 构建 generateContent 请求体
+// 注意：Gemini 的模型名在 URL 中，Request.Model 不生效，使用客户端的 Model
+func (geminiClient *GeminiClient) buildRequestBodyFromRequest(req *Request) map[string]any {
+	system, conversation := splitSystemMessages(req.Messages)
+
+	contents := make([]map[string]any, 0, len(conversation))
+	for _, msg := range conversation {
+		role := msg.Role
+		if role == "assistant" {
+			role = "model"
+		}
+		contents = append(contents, map[string]any{
+			"role":  role,
+			"parts": []map[string]string{{"text": msg.Content}},
+		})
+	}
+
+	generationConfig := map[string]any{
+		"temperature":     geminiClient.config.Temperature,
+		"maxOutputTokens": geminiClient.MaxTokens,
+	}
+	if req.Temperature != nil {
+		generationConfig["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		generationConfig["maxOutputTokens"] = *req.MaxTokens
+	}
+	if req.TopP != nil {
+		generationConfig["topP"] = *req.TopP
+	}
+	if req.FrequencyPenalty != nil {
+		generationConfig["frequencyPenalty"] = *req.FrequencyPenalty
+	}
+	if req.PresencePenalty != nil {
+		generationConfig["presencePenalty"] = *req.PresencePenalty
+	}
+	if len(req.Stop) > 0 {
+		generationConfig["stopSequences"] = req.Stop
+	}
+
+	requestBody := map[string]any{
+		"contents":         contents,
+		"generationConfig": generationConfig,
+	}
+	if system != "" {
+		requestBody["systemInstruction"] = map[string]any{
+			"parts": []map[string]string{{"text": system}},
+		}
+	}
+	if len(req.Tools) > 0 {
+		declarations := make([]FunctionDef, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			declarations = append(declarations, tool.Function)
+		}
+		requestBody["tools"] = []map[string]any{{"functionDeclarations": declarations}}
+	}
+
+	return requestBody
+}
+
+func (geminiClient *GeminiClient) parseMCPResponse(body []byte) (string, error) {
+	var result struct {
+		ModelVersion string `json:"modelVersion"`
+		Candidates   []struct {
+			Content struct {
+				Parts []struct {
+					Text    string `json:"text"`
+					Thought bool   `json:"thought"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		PromptFeedback *struct {
+			BlockReason string `json:"blockReason"`
+		} `json:"promptFeedback"`
+		UsageMetadata *struct {
+			PromptTokenCount        int `json:"promptTokenCount"`
+			CandidatesTokenCount    int `json:"candidatesTokenCount"`
+			ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+			CachedContentTokenCount int `json:"cachedContentTokenCount"`
+			TotalTokenCount         int `json:"totalTokenCount"`
+		} `json:"usageMetadata"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(result.Candidates) == 0 {
+		if result.PromptFeedback != nil && result.PromptFeedback.BlockReason != "" {
+			return "", fmt.Errorf("Gemini拒绝了请求 (blockReason: %s)", result.PromptFeedback.BlockReason)
+		}
+		return "", fmt.Errorf("API返回空响应")
+	}
+
+	// 拼接正文部分，思考摘要作为思维链
+	candidate := result.Candidates[0]
+	var text, thinking strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if part.Thought {
+			thinking.WriteString(part.Text)
+		} else {
+			text.WriteString(part.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("API返回空响应 (finishReason: %s)", candidate.FinishReason)
+	}
+
+	// 记录 token 用量（candidatesTokenCount 不含思考 token，这里合并为输出 token）
+	var usage *Usage
+	if u := result.UsageMetadata; u != nil {
+		model := result.ModelVersion
+		if model == "" {
+			model = geminiClient.Model
+		}
+		usage = &Usage{
+			Model:            model,
+			PromptTokens:     u.PromptTokenCount,
+			CompletionTokens: u.CandidatesTokenCount + u.ThoughtsTokenCount,
+			ReasoningTokens:  u.ThoughtsTokenCount,
+			CachedTokens:     u.CachedContentTokenCount,
+			TotalTokens:      u.TotalTokenCount,
+		}
+		if usage.TotalTokens == 0 {
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		}
+	}
+	geminiClient.setLastResult(NewResult(text.String(), thinking.String(), candidate.FinishReason, usage))
+
+	return text.String(), nil
+}
+
+// callStream Gemini 的流式格式与 OpenAI 不兼容，完整调用后作为单个片段推送
+func (geminiClient *GeminiClient) callStream(req *Request, onChunk StreamHandler) (string, error) {
+	return geminiClient.callStreamFallback(req, onChunk)
+}
+
+// isRetryableError 限流、服务不可用和超时可重试，其余按通用规则判断
+func (geminiClient *GeminiClient) isRetryableError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return geminiRetryableStatus[apiErr.StatusCode]
+	}
+	return geminiClient.Client.isRetryableError(err)
+}
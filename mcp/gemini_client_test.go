@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ============================================================
+// 测试 GeminiClient（使用本地 httptest 模拟 generateContent）
+// ============================================================
+
+func newGeminiTestServer(t *testing.T, handler func(w http.ResponseWriter, body map[string]any)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-pro:generateContent" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "gemini-test-key" {
+			t.Errorf("x-goog-api-key header not set")
+		}
+		if r.URL.Query().Get("key") != "" {
+			t.Errorf("api key should not be in url")
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request body: %v", err)
+		}
+		handler(w, body)
+	}))
+}
+
+func newGeminiTestClient(url string, opts ...ClientOption) AIClient {
+	allOpts := append([]ClientOption{
+		WithBaseURL(url),
+		WithModel("gemini-2.5-pro"),
+		WithAPIKey("gemini-test-key"),
+		WithLogger(NewMockLogger()),
+	}, opts...)
+	return NewGeminiClientWithOptions(allOpts...)
+}
+
+func TestNewGeminiClient_Default(t *testing.T) {
+	client, ok := NewGeminiClient().(*GeminiClient)
+	if !ok {
+		t.Fatal("client should be *GeminiClient")
+	}
+	if client.Provider != ProviderGemini || client.BaseURL != DefaultGeminiBaseURL || client.Model != DefaultGeminiModel {
+		t.Errorf("unexpected defaults: %s %s %s", client.Provider, client.BaseURL, client.Model)
+	}
+	if !strings.HasSuffix(client.buildUrl(), "/models/"+DefaultGeminiModel+":generateContent") {
+		t.Errorf("unexpected url: %s", client.buildUrl())
+	}
+}
+
+func TestGeminiClient_CallWithMessages(t *testing.T) {
+	server := newGeminiTestServer(t, func(w http.ResponseWriter, body map[string]any) {
+		system := body["systemInstruction"].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"]
+		if system != "system prompt" {
+			t.Errorf("system prompt should be in systemInstruction, got %v", system)
+		}
+		contents := body["contents"].([]any)
+		if len(contents) != 1 || contents[0].(map[string]any)["role"] != "user" {
+			t.Errorf("contents should only contain user message, got %v", contents)
+		}
+		if body["generationConfig"].(map[string]any)["maxOutputTokens"] == nil {
+			t.Error("maxOutputTokens should be set")
+		}
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking...","thought":true},{"text":"hello"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":100,"candidatesTokenCount":20,"thoughtsTokenCount":30,"cachedContentTokenCount":40,"totalTokenCount":150},"modelVersion":"gemini-2.5-pro"}`))
+	})
+	defer server.Close()
+
+	client := newGeminiTestClient(server.URL)
+	result, err := client.CallWithMessages("system prompt", "user prompt")
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if result != "hello" {
+		t.Errorf("thought parts should be skipped, got %q", result)
+	}
+
+	usage := LastUsageOf(client)
+	want := Usage{Model: "gemini-2.5-pro", PromptTokens: 100, CompletionTokens: 50, ReasoningTokens: 30, CachedTokens: 40, TotalTokens: 150}
+	if usage == nil || *usage != want {
+		t.Errorf("got usage %+v, want %+v", usage, want)
+	}
+}
+
+func TestGeminiClient_CallWithRequest(t *testing.T) {
+	server := newGeminiTestServer(t, func(w http.ResponseWriter, body map[string]any) {
+		contents := body["contents"].([]any)
+		if len(contents) != 3 || contents[1].(map[string]any)["role"] != "model" {
+			t.Errorf("assistant role should be mapped to model, got %v", contents)
+		}
+		config := body["generationConfig"].(map[string]any)
+		if config["topP"] != 0.9 || config["stopSequences"] == nil {
+			t.Errorf("unexpected generationConfig: %v", config)
+		}
+		w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`))
+	})
+	defer server.Close()
+
+	req := NewRequestBuilder().
+		WithSystemPrompt("system").
+		AddUserMessage("q1").
+		AddAssistantMessage("a1").
+		AddUserMessage("q2").
+		WithTopP(0.9).
+		AddStopSequence("END").
+		MustBuild()
+
+	if result, err := newGeminiTestClient(server.URL).CallWithRequest(req); err != nil || result != "ok" {
+		t.Fatalf("unexpected result: %q, %v", result, err)
+	}
+}
+
+func TestGeminiClient_Blocked(t *testing.T) {
+	server := newGeminiTestServer(t, func(w http.ResponseWriter, body map[string]any) {
+		w.Write([]byte(`{"promptFeedback":{"blockReason":"SAFETY"}}`))
+	})
+	defer server.Close()
+
+	_, err := newGeminiTestClient(server.URL).CallWithMessages("", "user")
+	if err == nil || !strings.Contains(err.Error(), "SAFETY") {
+		t.Errorf("expected block reason error, got %v", err)
+	}
+}
+
+func TestGeminiClient_ErrorCodes(t *testing.T) {
+	var calls int32
+	status := int32(http.StatusServiceUnavailable)
+	server := newGeminiTestServer(t, func(w http.ResponseWriter, body map[string]any) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write([]byte(`{"error":{"code":503,"message":"overloaded","status":"UNAVAILABLE"}}`))
+	})
+	defer server.Close()
+
+	client := newGeminiTestClient(server.URL, WithMaxRetries(2), WithRetryWaitBase(time.Millisecond))
+
+	// 503 可重试
+	if _, err := client.CallWithMessages("", "user"); err == nil {
+		t.Fatal("should error")
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("503 should retry, got %d calls", calls)
+	}
+
+	// 403 PERMISSION_DENIED 不可重试
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&status, http.StatusForbidden)
+	if _, err := client.CallWithMessages("", "user"); err == nil {
+		t.Fatal("should error")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("403 should not retry, got %d calls", calls)
+	}
+}
+
+func TestNewProviderClient(t *testing.T) {
+	tests := map[string]string{
+		"deepseek":                "*mcp.DeepSeekClient",
+		"Qwen":                    "*mcp.QwenClient",
+		"anthropic":               "*mcp.AnthropicClient",
+		"gemini":                  "*mcp.GeminiClient",
+		"ollama":                  "*mcp.LocalClient",
+		"openai-compatible-local": "*mcp.LocalClient",
+		"custom":                  "*mcp.Client",
+	}
+	for provider, want := range tests {
+		client := NewProviderClient(provider, WithLogger(NewMockLogger()))
+		if got := fmt.Sprintf("%T", client); got != want {
+			t.Errorf("provider %s: got %s, want %s", provider, got, want)
+		}
+	}
+}
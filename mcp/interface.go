@@ -10,7 +10,12 @@ type AIClient interface {
 	SetAPIKey(apiKey string, customURL string, customModel string)
 	SetTimeout(timeout time.Duration)
 	CallWithMessages(systemPrompt, userPrompt string) (string, error)
-	CallWithRequest(req *Request) (string, error) // 构建器模式 API（支持高级功能）
+	CallWithRequest(req *Request) (string, error)                            // 构建器模式 API（支持高级功能）
+	CallWithMessagesResult(systemPrompt, userPrompt string) (*Result, error) // 结构化结果（正文、思维链、结束原因、用量）
+
+	// 流式 API：每收到一个片段（正文/思维链增量）回调一次，返回完整正文
+	CallWithMessagesStream(systemPrompt, userPrompt string, onChunk StreamHandler) (string, error)
+	CallWithRequestStream(req *Request, onChunk StreamHandler) (string, error)
 }
 
 // clientHooks 内部钩子接口（用于子类重写特定步骤）
@@ -19,8 +24,10 @@ type clientHooks interface {
 	// 可被子类重写的钩子方法
 
 	call(systemPrompt, userPrompt string) (string, error)
+	callStream(req *Request, onChunk StreamHandler) (string, error)
 
 	buildMCPRequestBody(systemPrompt, userPrompt string) map[string]any
+	buildRequestBodyFromRequest(req *Request) map[string]any
 	buildUrl() string
 	buildRequest(url string, jsonData []byte) (*http.Request, error)
 	setAuthHeader(reqHeaders http.Header)
@@ -0,0 +1,437 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderOllama       = "ollama"
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	DefaultOllamaModel   = "qwen2.5:7b"
+
+	// ProviderLocalOpenAI 本地 OpenAI 兼容服务（llama.cpp server、LM Studio、vLLM 等）
+	ProviderLocalOpenAI       = "openai-compatible-local"
+	DefaultLocalOpenAIBaseURL = "http://localhost:8080/v1"
+	DefaultLocalOpenAIModel   = "local-model"
+
+	// localMaxRetries 本地推理很慢，超时后不宜多次重试
+	localMaxRetries = 2
+)
+
+// DefaultLocalTimeout 本地模型的请求超时（CPU 推理可能需要数分钟，可通过 AI_LOCAL_TIMEOUT_SECONDS 调整）
+var DefaultLocalTimeout = time.Duration(getEnvInt("AI_LOCAL_TIMEOUT_SECONDS", 600)) * time.Second
+
+// IsLocalProvider 是否为无需 API Key 的本地模型提供商
+func IsLocalProvider(provider string) bool {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case ProviderOllama, ProviderLocalOpenAI:
+		return true
+	default:
+		return false
+	}
+}
+
+// LocalModel 本地模型服务上可用的模型
+type LocalModel struct {
+	Name              string    `json:"name"`
+	Size              int64     `json:"size,omitempty"`
+	Family            string    `json:"family,omitempty"`
+	ParameterSize     string    `json:"parameter_size,omitempty"`
+	QuantizationLevel string    `json:"quantization_level,omitempty"`
+	ModifiedAt        time.Time `json:"modified_at"`
+}
+
+// LocalClient 本地模型客户端（无需 API Key）
+// Ollama 默认使用原生 /api/chat 接口；BaseURL 以 /v1 结尾时使用 Ollama 的 OpenAI 兼容接口
+// openai-compatible-local 始终使用 OpenAI 兼容接口（/chat/completions）
+type LocalClient struct {
+	*Client
+}
+
+// NewOllamaClientWithOptions 创建 Ollama 客户端（支持选项模式）
+//
+// 使用示例：
+//   // 原生接口
+//   client := mcp.NewOllamaClientWithOptions(mcp.WithModel("llama3.1:8b"))
+//
+//   // OpenAI 兼容接口
+//   client := mcp.NewOllamaClientWithOptions(mcp.WithBaseURL("http://localhost:11434/v1"))
+func NewOllamaClientWithOptions(opts ...ClientOption) AIClient {
+	return newLocalClient([]ClientOption{
+		WithProvider(ProviderOllama),
+		WithModel(DefaultOllamaModel),
+		WithBaseURL(DefaultOllamaBaseURL),
+	}, opts)
+}
+
+// NewLocalOpenAIClientWithOptions 创建本地 OpenAI 兼容服务客户端（llama.cpp server 等）
+//
+// 使用示例：
+//   client := mcp.NewLocalOpenAIClientWithOptions(
+//       mcp.WithBaseURL("http://192.168.1.10:8080/v1"),
+//   )
+func NewLocalOpenAIClientWithOptions(opts ...ClientOption) AIClient {
+	return newLocalClient([]ClientOption{
+		WithProvider(ProviderLocalOpenAI),
+		WithModel(DefaultLocalOpenAIModel),
+		WithBaseURL(DefaultLocalOpenAIBaseURL),
+	}, opts)
+}
+
+func newLocalClient(presetOpts, opts []ClientOption) AIClient {
+	// 1. 本地模型预设：无需认证、长超时、少重试
+	localOpts := append(presetOpts,
+		WithNoAuth(),
+		WithTimeout(DefaultLocalTimeout),
+		WithMaxRetries(localMaxRetries),
+	)
+
+	// 2. 合并用户选项（用户选项优先级更高）
+	allOpts := append(localOpts, opts...)
+
+	// 3. 创建基础客户端
+	baseClient := NewClient(allOpts...).(*Client)
+	baseClient.BaseURL = strings.TrimSuffix(baseClient.BaseURL, "/")
+
+	// 4. 创建本地客户端并设置 hooks（实现动态分派）
+	localClient := &LocalClient{
+		Client: baseClient,
+	}
+	baseClient.hooks = localClient
+
+	return localClient
+}
+
+// SetAPIKey 设置地址和模型（apiKey 可以为空，反向代理要求认证时才需要）
+func (localClient *LocalClient) SetAPIKey(apiKey string, customURL string, customModel string) {
+	localClient.APIKey = apiKey
+
+	if customURL != "" {
+		localClient.BaseURL = strings.TrimSuffix(customURL, "/")
+		localClient.logger.Infof("🔧 [MCP] %s 使用自定义 BaseURL: %s", localClient.Provider, customURL)
+	} else {
+		localClient.logger.Infof("🔧 [MCP] %s 使用默认 BaseURL: %s", localClient.Provider, localClient.BaseURL)
+	}
+	if customModel != "" {
+		localClient.Model = customModel
+		localClient.logger.Infof("🔧 [MCP] %s 使用自定义 Model: %s", localClient.Provider, customModel)
+	} else {
+		localClient.logger.Infof("🔧 [MCP] %s 使用默认 Model: %s", localClient.Provider, localClient.Model)
+	}
+}
+
+// native 是否使用 Ollama 原生接口
+func (localClient *LocalClient) native() bool {
+	return localClient.Provider == ProviderOllama && !strings.HasSuffix(localClient.BaseURL, "/v1")
+}
+
+// setAuthHeader 未设置 API Key 时不发送认证头
+func (localClient *LocalClient) setAuthHeader(reqHeaders http.Header) {
+	if localClient.APIKey != "" {
+		localClient.Client.setAuthHeader(reqHeaders)
+	}
+}
+
+func (localClient *LocalClient) buildUrl() string {
+	if localClient.UseFullURL || !localClient.native() {
+		return localClient.Client.buildUrl()
+	}
+	return fmt.Sprintf("%s/api/chat", localClient.BaseURL)
+}
+
+func (localClient *LocalClient) buildMCPRequestBody(systemPrompt, userPrompt string) map[string]any {
+	if !localClient.native() {
+		return localClient.Client.buildMCPRequestBody(systemPrompt, userPrompt)
+	}
+
+	messages := []map[string]string{}
+	if systemPrompt != "" {
+		messages = append(messages, map[string]string{"role": "system", "content": systemPrompt})
+	}
+	messages = append(messages, map[string]string{"role": "user", "content": userPrompt})
+
+	return map[string]any{
+		"model":    localClient.Model,
+		"messages": messages,
+		"stream":   false,
+		"options": map[string]any{
+			"temperature": localClient.config.Temperature,
+			"num_predict": localClient.MaxTokens,
+		},
+	}
+}
+
+func (localClient *LocalClient) buildRequestBodyFromRequest(req *Request) map[string]any {
+	if !localClient.native() {
+		return localClient.Client.buildRequestBodyFromRequest(req)
+	}
+
+	messages := make([]map[string]string, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		messages = append(messages, map[string]string{
+			"role":    msg.Role,
+			"content": msg.Content,
+		})
+	}
+
+	// 采样参数放在 options 中
+	options := map[string]any{
+		"temperature": localClient.config.Temperature,
+		"num_predict": localClient.MaxTokens,
+	}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		options["num_predict"] = *req.MaxTokens
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if req.FrequencyPenalty != nil {
+		options["frequency_penalty"] = *req.FrequencyPenalty
+	}
+	if req.PresencePenalty != nil {
+		options["presence_penalty"] = *req.PresencePenalty
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+
+	requestBody := map[string]any{
+		"model":    req.Model,
+		"messages": messages,
+		"stream":   req.Stream,
+		"options":  options,
+	}
+	if len(req.Tools) > 0 {
+		requestBody["tools"] = req.Tools
+	}
+
+	return requestBody
+}
+
+// ollamaChatResponse Ollama /api/chat 的响应（流式时为每行一个对象）
+type ollamaChatResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// usage 转换 token 用量（仅在 done 时返回）
+func (r *ollamaChatResponse) usage(defaultModel string) *Usage {
+	if !r.Done || (r.PromptEvalCount == 0 && r.EvalCount == 0) {
+		return nil
+	}
+	model := r.Model
+	if model == "" {
+		model = defaultModel
+	}
+	return &Usage{
+		Model:            model,
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+func (localClient *LocalClient) parseMCPResponse(body []byte) (string, error) {
+	if !localClient.native() {
+		return localClient.Client.parseMCPResponse(body)
+	}
+
+	var result ollamaChatResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("Ollama返回错误: %s", result.Error)
+	}
+	if result.Message.Content == "" {
+		return "", fmt.Errorf("API返回空响应 (done_reason: %s)", result.DoneReason)
+	}
+
+	localClient.setLastResult(NewResult(result.Message.Content, result.Message.Thinking, result.DoneReason, result.usage(localClient.Model)))
+	return result.Message.Content, nil
+}
+
+// callStream Ollama 原生接口的流式响应为 NDJSON（每行一个 JSON 对象），OpenAI 兼容接口使用 SSE
+func (localClient *LocalClient) callStream(req *Request, onChunk StreamHandler) (string, error) {
+	if !localClient.native() {
+		return localClient.Client.callStream(req, onChunk)
+	}
+
+	localClient.logger.Infof("📡 [%s] Stream AI Server: BaseURL: %s", localClient.String(), localClient.BaseURL)
+
+	jsonData, err := localClient.marshalRequestBody(localClient.buildRequestBodyFromRequest(req))
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := localClient.buildRequest(localClient.buildUrl(), jsonData)
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	resp, err := localClient.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return localClient.readNDJSONStream(resp.Body, onChunk)
+}
+
+// readNDJSONStream 解析 Ollama 的 NDJSON 数据流（最后一行 done=true 并带有用量）
+func (localClient *LocalClient) readNDJSONStream(body io.Reader, onChunk StreamHandler) (string, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+	var content, reasoning strings.Builder
+	var finishReason string
+	var usage *Usage
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var event ollamaChatResponse
+		if err := json.Unmarshal(line, &event); err != nil {
+			return "", fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		if event.Error != "" {
+			return "", fmt.Errorf("流式响应返回错误: %s", event.Error)
+		}
+
+		chunk := StreamChunk{
+			Content:          event.Message.Content,
+			ReasoningContent: event.Message.Thinking,
+			FinishReason:     event.DoneReason,
+			Usage:            event.usage(localClient.Model),
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.FinishReason != "" {
+			finishReason = chunk.FinishReason
+		}
+		if chunk != (StreamChunk{}) {
+			content.WriteString(chunk.Content)
+			reasoning.WriteString(chunk.ReasoningContent)
+			onChunk(chunk)
+		}
+		if event.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("读取流式响应失败: %w", err)
+	}
+
+	if content.Len() == 0 {
+		return "", fmt.Errorf("API返回空响应 (思维链 %d 字符)", reasoning.Len())
+	}
+	localClient.setLastResult(NewResult(content.String(), reasoning.String(), finishReason, usage))
+	return content.String(), nil
+}
+
+// ListModels 列出本地模型服务上可用的模型
+// Ollama 原生接口使用 /api/tags，OpenAI 兼容接口使用 /models
+func (localClient *LocalClient) ListModels() ([]LocalModel, error) {
+	url := fmt.Sprintf("%s/models", localClient.BaseURL)
+	if localClient.Provider == ProviderOllama {
+		url = fmt.Sprintf("%s/api/tags", strings.TrimSuffix(localClient.BaseURL, "/v1"))
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	localClient.setAuthHeader(req.Header)
+
+	resp, err := localClient.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("连接本地模型服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	if localClient.Provider == ProviderOllama {
+		return parseOllamaTags(body)
+	}
+	return parseOpenAIModels(body)
+}
+
+func parseOllamaTags(body []byte) ([]LocalModel, error) {
+	var result struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			Size       int64     `json:"size"`
+			ModifiedAt time.Time `json:"modified_at"`
+			Details    struct {
+				Family            string `json:"family"`
+				ParameterSize     string `json:"parameter_size"`
+				QuantizationLevel string `json:"quantization_level"`
+			} `json:"details"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析模型列表失败: %w", err)
+	}
+
+	models := make([]LocalModel, 0, len(result.Models))
+	for _, m := range result.Models {
+		models = append(models, LocalModel{
+			Name:              m.Name,
+			Size:              m.Size,
+			Family:            m.Details.Family,
+			ParameterSize:     m.Details.ParameterSize,
+			QuantizationLevel: m.Details.QuantizationLevel,
+			ModifiedAt:        m.ModifiedAt,
+		})
+	}
+	return models, nil
+}
+
+func parseOpenAIModels(body []byte) ([]LocalModel, error) {
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析模型列表失败: %w", err)
+	}
+
+	models := make([]LocalModel, 0, len(result.Data))
+	for _, m := range result.Data {
+		models = append(models, LocalModel{Name: m.ID})
+	}
+	return models, nil
+}
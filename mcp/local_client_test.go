@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ============================================================
+// 测试 LocalClient（使用本地 httptest 模拟 Ollama / llama.cpp）
+// ============================================================
+
+func TestNewOllamaClient_Defaults(t *testing.T) {
+	client := NewOllamaClientWithOptions(WithLogger(NewMockLogger())).(*LocalClient)
+
+	if client.Provider != ProviderOllama || client.BaseURL != DefaultOllamaBaseURL || client.Model != DefaultOllamaModel {
+		t.Errorf("unexpected defaults: %s %s %s", client.Provider, client.BaseURL, client.Model)
+	}
+	if client.httpClient.Timeout != DefaultLocalTimeout {
+		t.Errorf("timeout = %v, want %v", client.httpClient.Timeout, DefaultLocalTimeout)
+	}
+	if client.buildUrl() != DefaultOllamaBaseURL+"/api/chat" {
+		t.Errorf("unexpected url: %s", client.buildUrl())
+	}
+
+	// BaseURL 以 /v1 结尾时使用 OpenAI 兼容接口
+	client.SetAPIKey("", "http://localhost:11434/v1/", "llama3.1:8b")
+	if client.buildUrl() != "http://localhost:11434/v1/chat/completions" {
+		t.Errorf("unexpected shim url: %s", client.buildUrl())
+	}
+}
+
+func TestOllamaClient_NativeChatWithoutAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("authorization header should not be sent without api key")
+		}
+
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != false {
+			t.Errorf("stream = %v, want false", body["stream"])
+		}
+		options, _ := body["options"].(map[string]any)
+		if options["num_predict"] == nil {
+			t.Errorf("num_predict should be set in options: %v", body)
+		}
+		messages, _ := body["messages"].([]any)
+		if len(messages) != 2 {
+			t.Errorf("messages = %v, want system + user", messages)
+		}
+
+		fmt.Fprint(w, `{"model":"qwen2.5:7b","message":{"role":"assistant","content":"<think>hmm</think>answer"},
+			"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":5}`)
+	}))
+	defer server.Close()
+
+	client := NewOllamaClientWithOptions(WithBaseURL(server.URL), WithLogger(NewMockLogger()))
+	result, err := client.CallWithMessagesResult("system", "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Content != "answer" || result.Reasoning != "hmm" || result.FinishReason != FinishReasonStop {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.Usage == nil || result.Usage.PromptTokens != 12 || result.Usage.CompletionTokens != 5 {
+		t.Errorf("unexpected usage: %+v", result.Usage)
+	}
+	if result.Provider != ProviderOllama {
+		t.Errorf("provider = %q, want ollama", result.Provider)
+	}
+}
+
+func TestOllamaClient_NativeStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"message":{"content":"","thinking":"let me think"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"content":"hel"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"content":"lo"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"content":""},"done":true,"done_reason":"stop","prompt_eval_count":3,"eval_count":2}`)
+	}))
+	defer server.Close()
+
+	client := NewOllamaClientWithOptions(WithBaseURL(server.URL), WithLogger(NewMockLogger()))
+
+	var content, reasoning strings.Builder
+	result, err := client.CallWithMessagesStream("system", "user", func(chunk StreamChunk) {
+		content.WriteString(chunk.Content)
+		reasoning.WriteString(chunk.ReasoningContent)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "hello" || content.String() != "hello" || reasoning.String() != "let me think" {
+		t.Errorf("unexpected stream output: result=%q content=%q reasoning=%q", result, content.String(), reasoning.String())
+	}
+	if usage := LastUsageOf(client); usage == nil || usage.TotalTokens != 5 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestOllamaClient_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"error":"model 'missing' not found"}`)
+	}))
+	defer server.Close()
+
+	client := NewOllamaClientWithOptions(WithBaseURL(server.URL), WithLogger(NewMockLogger()))
+	_, err := client.CallWithMessages("", "user")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("err = %v, want model not found", err)
+	}
+}
+
+func TestLocalOpenAIClient_ChatCompletions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	client := NewLocalOpenAIClientWithOptions(WithLogger(NewMockLogger()))
+	client.SetAPIKey("", server.URL+"/v1", "")
+	got, err := client.CallWithMessages("", "user")
+	if err != nil || got != "ok" {
+		t.Errorf("got (%q, %v), want ok", got, err)
+	}
+}
+
+func TestLocalClient_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			fmt.Fprint(w, `{"models":[{"name":"llama3.1:8b","size":4920753328,
+				"details":{"family":"llama","parameter_size":"8.0B","quantization_level":"Q4_K_M"}}]}`)
+		case "/v1/models":
+			fmt.Fprint(w, `{"object":"list","data":[{"id":"qwen2.5-7b-instruct-q4"}]}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Ollama 即使配置了 OpenAI 兼容地址也使用 /api/tags
+	ollama := NewOllamaClientWithOptions(WithBaseURL(server.URL+"/v1"), WithLogger(NewMockLogger())).(*LocalClient)
+	models, err := ollama.ListModels()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "llama3.1:8b" || models[0].QuantizationLevel != "Q4_K_M" {
+		t.Errorf("unexpected ollama models: %+v", models)
+	}
+
+	local := NewLocalOpenAIClientWithOptions(WithBaseURL(server.URL+"/v1"), WithLogger(NewMockLogger())).(*LocalClient)
+	models, err = local.ListModels()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "qwen2.5-7b-instruct-q4" {
+		t.Errorf("unexpected local models: %+v", models)
+	}
+}
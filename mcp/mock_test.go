@@ -261,6 +261,14 @@ func (m *MockClientHooks) buildMCPRequestBody(systemPrompt, userPrompt string) m
 	}
 }
 
+func (m *MockClientHooks) buildRequestBodyFromRequest(req *Request) map[string]any {
+	m.BuildRequestBodyCalled++
+	return map[string]any{
+		"model":    req.Model,
+		"messages": req.Messages,
+	}
+}
+
 func (m *MockClientHooks) buildUrl() string {
 	m.BuildUrlCalled++
 	if m.BuildUrlFunc != nil {
@@ -308,3 +316,8 @@ func (m *MockClientHooks) buildRequest(url string, jsonData []byte) (*http.Reque
 func (m *MockClientHooks) call(systemPrompt, userPrompt string) (string, error) {
 	return "mocked call result", nil
 }
+
+func (m *MockClientHooks) callStream(req *Request, onChunk StreamHandler) (string, error) {
+	onChunk(StreamChunk{Content: "mocked stream result"})
+	return "mocked stream result", nil
+}
@@ -124,6 +124,13 @@ func WithProvider(provider string) ClientOption {
 	}
 }
 
+// WithNoAuth 无需 API Key（Ollama、llama.cpp 等本地模型服务）
+func WithNoAuth() ClientOption {
+	return func(c *Config) {
+		c.NoAuth = true
+	}
+}
+
 // WithUseFullURL 设置是否使用完整 URL
 func WithUseFullURL(useFullURL bool) ClientOption {
 	return func(c *Config) {
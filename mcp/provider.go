@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewProviderClient 根据提供商（store.AIModel.Provider）创建对应的客户端
+// 未知提供商使用 OpenAI 兼容的通用客户端（需通过 SetAPIKey 设置 URL 和模型）
+//
+// 使用示例：
+//   client := mcp.NewProviderClient(model.Provider, mcp.WithTimeout(60*time.Second))
+//   client.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
+func NewProviderClient(provider string, opts ...ClientOption) AIClient {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case ProviderDeepSeek:
+		return NewDeepSeekClientWithOptions(opts...)
+	case ProviderQwen:
+		return NewQwenClientWithOptions(opts...)
+	case ProviderAnthropic, "claude":
+		return NewAnthropicClientWithOptions(opts...)
+	case ProviderGemini, "google":
+		return NewGeminiClientWithOptions(opts...)
+	case ProviderOllama:
+		return NewOllamaClientWithOptions(opts...)
+	case ProviderLocalOpenAI, "local", "llama.cpp", "llamacpp":
+		return NewLocalOpenAIClientWithOptions(opts...)
+	default:
+		return NewClient(opts...)
+	}
+}
+
+// CloneClient 复制客户端（保留提供商特有的请求格式和响应解析），副本可独立修改 API Key、地址和模型
+// 故障转移客户端等无法复制的类型返回错误
+func CloneClient(client AIClient) (AIClient, error) {
+	switch c := client.(type) {
+	case *Client:
+		return c.Clone(), nil
+	case *DeepSeekClient:
+		cp := &DeepSeekClient{Client: c.Client.Clone()}
+		cp.hooks = cp
+		return cp, nil
+	case *QwenClient:
+		cp := &QwenClient{Client: c.Client.Clone()}
+		cp.hooks = cp
+		return cp, nil
+	case *AnthropicClient:
+		cp := &AnthropicClient{Client: c.Client.Clone()}
+		cp.hooks = cp
+		return cp, nil
+	case *GeminiClient:
+		cp := &GeminiClient{Client: c.Client.Clone()}
+		cp.hooks = cp
+		return cp, nil
+	case *LocalClient:
+		cp := &LocalClient{Client: c.Client.Clone()}
+		cp.hooks = cp
+		return cp, nil
+	default:
+		return nil, fmt.Errorf("不支持复制的 AI 客户端类型: %T", client)
+	}
+}
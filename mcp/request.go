@@ -1,5 +1,7 @@
 package mcp
 
+import "strings"
+
 // Message 表示一条对话消息
 type Message struct {
 	Role    string `json:"role"`    // "system", "user", "assistant"
@@ -70,3 +72,18 @@ func NewAssistantMessage(content string) Message {
 		Content: content,
 	}
 }
+
+// splitSystemMessages 拆分系统消息和对话消息（Anthropic、Gemini 的系统提示词不放在消息列表中）
+// 多条系统消息按顺序以空行拼接
+func splitSystemMessages(messages []Message) (string, []Message) {
+	var system []string
+	rest := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			continue
+		}
+		rest = append(rest, msg)
+	}
+	return strings.Join(system, "\n\n"), rest
+}
@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 归一化后的结束原因
+const (
+	FinishReasonStop          = "stop"           // 正常结束
+	FinishReasonLength        = "length"         // 达到 max_tokens 被截断
+	FinishReasonToolCalls     = "tool_calls"     // 请求调用工具
+	FinishReasonContentFilter = "content_filter" // 被安全策略拦截
+)
+
+var (
+	// reThinkBlock 匹配 <think>...</think> / <thinking>...</thinking> 思考块
+	reThinkBlock = regexp.MustCompile(`(?is)<think(?:ing)?>(.*?)</think(?:ing)?>`)
+	// reThinkOpen 匹配未闭合的思考块开始标签（输出被截断时）
+	reThinkOpen = regexp.MustCompile(`(?i)<think(?:ing)?>`)
+	// reThinkClose 匹配思考块结束标签（部分模型省略开始标签）
+	reThinkClose = regexp.MustCompile(`(?i)</think(?:ing)?>`)
+)
+
+// Result 结构化的AI响应
+type Result struct {
+	Content      string `json:"content"`                 // 正文（已移除思考块）
+	Reasoning    string `json:"reasoning,omitempty"`     // 思维链（reasoning_content / thinking 块 / <think> 标签）
+	FinishReason string `json:"finish_reason,omitempty"` // 归一化的结束原因（stop / length / tool_calls / content_filter）
+	Usage        *Usage `json:"usage,omitempty"`
+	Provider     string `json:"provider,omitempty"` // 实际应答的提供商（故障转移时为备用提供商）
+	Target       string `json:"target,omitempty"`   // 故障转移链中实际应答的目标名称（FailoverTarget.Name，如 AI 模型 ID）
+}
+
+// ResultReporter 可获取最近一次成功调用结构化结果的客户端（Client 及内置子类均已实现）
+type ResultReporter interface {
+	LastResult() *Result
+}
+
+// ProviderOf 获取客户端的提供商（Client 及内置子类、故障转移客户端均已实现），不支持时返回空字符串
+func ProviderOf(client AIClient) string {
+	if named, ok := client.(interface{ ProviderName() string }); ok {
+		return named.ProviderName()
+	}
+	return ""
+}
+
+// LastResultOf 获取客户端最近一次调用的结构化结果，客户端不支持时返回 nil
+func LastResultOf(client AIClient) *Result {
+	if reporter, ok := client.(ResultReporter); ok {
+		return reporter.LastResult()
+	}
+	return nil
+}
+
+// NewResult 根据原始正文构建结构化结果
+// 正文中的思考块会被移除；提供商没有返回原生思维链时，使用思考块内容作为思维链
+func NewResult(content, reasoning, finishReason string, usage *Usage) *Result {
+	stripped, thinking := SplitThinking(content)
+	if strings.TrimSpace(reasoning) == "" {
+		reasoning = thinking
+	}
+	return &Result{
+		Content:      stripped,
+		Reasoning:    strings.TrimSpace(reasoning),
+		FinishReason: normalizeFinishReason(finishReason),
+		Usage:        usage,
+	}
+}
+
+// SplitThinking 拆分正文中的 <think>/<thinking> 思考块，返回移除思考块后的正文和思考内容
+// 兼容省略开始标签（只有 </think>）和输出被截断（只有 <think>）的情况
+func SplitThinking(text string) (content, thinking string) {
+	var thoughts []string
+	content = reThinkBlock.ReplaceAllStringFunc(text, func(block string) string {
+		if m := reThinkBlock.FindStringSubmatch(block); len(m) > 1 {
+			thoughts = append(thoughts, strings.TrimSpace(m[1]))
+		}
+		return ""
+	})
+
+	// 只有结束标签：之前的内容都是思考
+	if loc := reThinkClose.FindStringIndex(content); loc != nil {
+		thoughts = append(thoughts, strings.TrimSpace(content[:loc[0]]))
+		content = content[loc[1]:]
+	}
+	// 只有开始标签：之后的内容都是思考
+	if loc := reThinkOpen.FindStringIndex(content); loc != nil {
+		thoughts = append(thoughts, strings.TrimSpace(content[loc[1]:]))
+		content = content[:loc[0]]
+	}
+
+	return strings.TrimSpace(content), strings.TrimSpace(strings.Join(thoughts, "\n\n"))
+}
+
+// stripThinking 移除正文中的思考块（字符串 API 的返回值与结构化结果的 Content 保持一致）
+func stripThinking(text string) string {
+	content, _ := SplitThinking(text)
+	return content
+}
+
+// normalizeFinishReason 将各提供商的结束原因归一化为 OpenAI 风格
+func normalizeFinishReason(reason string) string {
+	switch r := strings.ToLower(strings.TrimSpace(reason)); r {
+	case "stop", "end_turn", "stop_sequence":
+		return FinishReasonStop
+	case "length", "max_tokens":
+		return FinishReasonLength
+	case "tool_calls", "tool_use", "function_call":
+		return FinishReasonToolCalls
+	case "content_filter", "refusal", "safety", "recitation", "blocklist", "prohibited_content", "spii":
+		return FinishReasonContentFilter
+	default:
+		return r
+	}
+}
+
+// CallWithMessagesResult 调用 AI API 并返回结构化结果（正文、思维链、结束原因和用量）
+func (client *Client) CallWithMessagesResult(systemPrompt, userPrompt string) (*Result, error) {
+	content, err := client.CallWithMessages(systemPrompt, userPrompt)
+	if err != nil {
+		return nil, err
+	}
+	if result := client.LastResult(); result != nil {
+		return result, nil
+	}
+	return NewResult(content, "", "", nil), nil
+}
+
+// LastResult 最近一次成功调用的结构化结果
+func (client *Client) LastResult() *Result {
+	client.resultMu.Lock()
+	defer client.resultMu.Unlock()
+	return client.lastResult
+}
+
+func (client *Client) setLastResult(result *Result) {
+	if result != nil && result.Provider == "" {
+		result.Provider = client.Provider
+	}
+	client.resultMu.Lock()
+	defer client.resultMu.Unlock()
+	client.lastResult = result
+}
@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"testing"
+)
+
+// ============================================================
+// 测试结构化结果和思考块拆分
+// ============================================================
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantContent  string
+		wantThinking string
+	}{
+		{"无思考块", "hello", "hello", ""},
+		{"完整思考块", "<think>想一想</think>\n结论", "结论", "想一想"},
+		{"thinking 标签且大小写混合", "<Thinking>a</Thinking>b<think>c</think>", "b", "a\n\nc"},
+		{"省略开始标签", "先分析</think>\n[{}]", "[{}]", "先分析"},
+		{"输出被截断", "结论<think>还没想完", "结论", "还没想完"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, thinking := SplitThinking(tt.text)
+			if content != tt.wantContent || thinking != tt.wantThinking {
+				t.Errorf("got (%q, %q), want (%q, %q)", content, thinking, tt.wantContent, tt.wantThinking)
+			}
+		})
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	// 原生思维链优先于 <think> 标签
+	result := NewResult("<think>tag</think>answer", "native", "end_turn", nil)
+	if result.Content != "answer" || result.Reasoning != "native" || result.FinishReason != FinishReasonStop {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	result = NewResult("<think>tag</think>answer", "", "MAX_TOKENS", nil)
+	if result.Reasoning != "tag" || result.FinishReason != FinishReasonLength {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	if got := NewResult("x", "", "SAFETY", nil).FinishReason; got != FinishReasonContentFilter {
+		t.Errorf("SAFETY should map to content_filter, got %s", got)
+	}
+}
+
+func TestClient_CallWithMessagesResult(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{"choices":[{"message":{"content":"[{\"symbol\":\"BTCUSDT\"}]","reasoning_content":"推理过程"},"finish_reason":"length"}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`
+
+	client := NewDeepSeekClientWithOptions(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("test-key"),
+	)
+
+	result, err := client.CallWithMessagesResult("system", "user")
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if result.Content != `[{"symbol":"BTCUSDT"}]` || result.Reasoning != "推理过程" || result.FinishReason != FinishReasonLength {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if result.Usage == nil || result.Usage.TotalTokens != 15 {
+		t.Errorf("usage should be included: %+v", result.Usage)
+	}
+	if LastResultOf(client) != result {
+		t.Error("LastResultOf should return the same result")
+	}
+}
@@ -0,0 +1,215 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxStreamLineSize 单行 SSE 数据的最大长度
+const maxStreamLineSize = 1024 * 1024
+
+// StreamChunk 流式响应的增量片段
+type StreamChunk struct {
+	Content          string `json:"content,omitempty"`           // 正文增量
+	ReasoningContent string `json:"reasoning_content,omitempty"` // 思维链增量（DeepSeek reasoner 等）
+	FinishReason     string `json:"finish_reason,omitempty"`
+	Usage            *Usage `json:"usage,omitempty"` // 仅在最后的片段中出现
+}
+
+// StreamHandler 流式片段回调（在调用方 goroutine 中同步执行，应尽快返回）
+type StreamHandler func(chunk StreamChunk)
+
+// CallWithMessagesStream 流式调用 AI API，每收到一个片段回调一次，返回完整正文
+func (client *Client) CallWithMessagesStream(systemPrompt, userPrompt string, onChunk StreamHandler) (string, error) {
+	req, err := NewRequestBuilder().
+		WithSystemPrompt(systemPrompt).
+		WithUserPrompt(userPrompt).
+		Build()
+	if err != nil {
+		return "", err
+	}
+	return client.CallWithRequestStream(req, onChunk)
+}
+
+// CallWithRequestStream 使用 Request 对象流式调用 AI API
+//
+// 重试规则与 CallWithRequest 相同，但一旦已经推送过片段就不再重试（避免订阅者收到重复内容）
+func (client *Client) CallWithRequestStream(req *Request, onChunk StreamHandler) (string, error) {
+	if err := client.checkAPIKey(); err != nil {
+		return "", err
+	}
+	client.setLastResult(nil)
+
+	streamReq := *req
+	streamReq.Stream = true
+	if streamReq.Model == "" {
+		streamReq.Model = client.Model
+	}
+
+	emitted := false
+	handler := func(chunk StreamChunk) {
+		emitted = true
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+	}
+
+	var lastErr error
+	maxRetries := client.config.MaxRetries
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if attempt > 1 {
+			client.logger.Warnf("⚠️  AI API流式调用失败，正在重试 (%d/%d)...", attempt, maxRetries)
+		}
+
+		result, err := client.hooks.callStream(&streamReq, handler)
+		if err == nil {
+			return stripThinking(result), nil
+		}
+
+		lastErr = err
+		if emitted || !client.hooks.isRetryableError(err) {
+			return "", err
+		}
+
+		if attempt < maxRetries {
+			waitTime := client.config.RetryWaitBase * time.Duration(attempt)
+			client.logger.Infof("⏳ 等待%v后重试...", waitTime)
+			time.Sleep(waitTime)
+		}
+	}
+
+	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+}
+
+// callStream 单次流式调用（OpenAI 兼容的 SSE 格式）
+func (client *Client) callStream(req *Request, onChunk StreamHandler) (string, error) {
+	client.logger.Infof("📡 [%s] Stream AI Server: BaseURL: %s", client.String(), client.BaseURL)
+
+	requestBody := client.hooks.buildRequestBodyFromRequest(req)
+	requestBody["stream"] = true
+	requestBody["stream_options"] = map[string]any{"include_usage": true}
+
+	jsonData, err := client.hooks.marshalRequestBody(requestBody)
+	if err != nil {
+		return "", err
+	}
+
+	httpReq, err := client.hooks.buildRequest(client.hooks.buildUrl(), jsonData)
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := client.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	// 服务端忽略了 stream 参数时按普通响应解析
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("读取响应失败: %w", err)
+		}
+		result, err := client.hooks.parseMCPResponse(body)
+		if err != nil {
+			return "", fmt.Errorf("fail to parse AI server response: %w", err)
+		}
+		onChunk(StreamChunk{Content: result, FinishReason: "stop", Usage: client.LastUsage()})
+		return result, nil
+	}
+
+	return client.readSSEStream(resp.Body, onChunk)
+}
+
+// readSSEStream 解析 OpenAI 兼容的 SSE 数据流（data: {...} / data: [DONE]）
+func (client *Client) readSSEStream(body io.Reader, onChunk StreamHandler) (string, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+	var content, reasoning strings.Builder
+	var finishReason string
+	var usage *Usage
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue // 空行、注释（: keep-alive）和 event: 行
+		}
+		data := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		if string(data) == "[DONE]" {
+			break
+		}
+
+		var event struct {
+			Choices []struct {
+				Delta struct {
+					Content          string `json:"content"`
+					ReasoningContent string `json:"reasoning_content"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return "", fmt.Errorf("解析流式响应失败: %w", err)
+		}
+		if event.Error != nil {
+			return "", fmt.Errorf("流式响应返回错误: %s", event.Error.Message)
+		}
+
+		chunk := StreamChunk{Usage: parseUsage(data, client.Model)}
+		if len(event.Choices) > 0 {
+			choice := event.Choices[0]
+			chunk.Content = choice.Delta.Content
+			chunk.ReasoningContent = choice.Delta.ReasoningContent
+			chunk.FinishReason = choice.FinishReason
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if chunk.FinishReason != "" {
+			finishReason = chunk.FinishReason
+		}
+		if chunk == (StreamChunk{}) {
+			continue
+		}
+
+		content.WriteString(chunk.Content)
+		reasoning.WriteString(chunk.ReasoningContent)
+		onChunk(chunk)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("读取流式响应失败: %w", err)
+	}
+
+	if content.Len() == 0 {
+		return "", fmt.Errorf("API返回空响应 (思维链 %d 字符)", reasoning.Len())
+	}
+	client.setLastResult(NewResult(content.String(), reasoning.String(), finishReason, usage))
+	return content.String(), nil
+}
+
+// callStreamFallback 不支持 OpenAI 兼容流式格式的提供商：完整调用后作为单个片段推送
+func (client *Client) callStreamFallback(req *Request, onChunk StreamHandler) (string, error) {
+	result, err := client.callWithRequest(req)
+	if err != nil {
+		return "", err
+	}
+	onChunk(StreamChunk{Content: result, FinishReason: "stop", Usage: client.LastUsage()})
+	return result, nil
+}
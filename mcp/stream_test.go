@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ============================================================
+// 测试 SSE 流式响应
+// ============================================================
+
+func writeSSE(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, event := range events {
+		fmt.Fprintf(w, "data: %s\n\n", event)
+		w.(http.Flusher).Flush()
+	}
+}
+
+func TestClient_CallWithMessagesStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true || body["stream_options"] == nil {
+			t.Errorf("stream should be enabled, got %v", body)
+		}
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Accept header should be text/event-stream")
+		}
+		messages := body["messages"].([]any)
+		if len(messages) != 2 || messages[0].(map[string]any)["role"] != "system" {
+			t.Errorf("unexpected messages: %v", messages)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		writeSSE(w,
+			`{"choices":[{"delta":{"role":"assistant","reasoning_content":"想一"}}],"usage":null}`,
+			`{"choices":[{"delta":{"reasoning_content":"想"}}]}`,
+			`{"choices":[{"delta":{"content":"hello "}}]}`,
+			`{"choices":[{"delta":{"content":"world"},"finish_reason":"stop"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15,"completion_tokens_details":{"reasoning_tokens":3}}}`,
+			`[DONE]`,
+		)
+	}))
+	defer server.Close()
+
+	client := NewDeepSeekClientWithOptions(
+		WithBaseURL(server.URL),
+		WithAPIKey("test-key"),
+		WithLogger(NewMockLogger()),
+	)
+
+	var reasoning, content strings.Builder
+	var chunks int
+	var lastUsage *Usage
+	result, err := client.CallWithMessagesStream("system", "user", func(chunk StreamChunk) {
+		chunks++
+		reasoning.WriteString(chunk.ReasoningContent)
+		content.WriteString(chunk.Content)
+		if chunk.Usage != nil {
+			lastUsage = chunk.Usage
+		}
+	})
+	if err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+	if result != "hello world" || content.String() != "hello world" {
+		t.Errorf("unexpected content: %q / %q", result, content.String())
+	}
+	if reasoning.String() != "想一想" {
+		t.Errorf("unexpected reasoning: %q", reasoning.String())
+	}
+	if chunks != 5 {
+		t.Errorf("expected 5 chunks, got %d", chunks)
+	}
+	if lastUsage == nil || lastUsage.ReasoningTokens != 3 {
+		t.Errorf("usage chunk missing: %+v", lastUsage)
+	}
+	if usage := LastUsageOf(client); usage == nil || usage.TotalTokens != 15 {
+		t.Errorf("last usage should be recorded, got %+v", usage)
+	}
+}
+
+func TestClient_CallWithMessagesStream_NonStreamingFallback(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.SetSuccessResponse("plain response")
+
+	client := NewClient(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("test-key"),
+	)
+
+	var chunks []StreamChunk
+	result, err := client.CallWithMessagesStream("", "user", func(chunk StreamChunk) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil || result != "plain response" {
+		t.Fatalf("unexpected result: %q, %v", result, err)
+	}
+	if len(chunks) != 1 || chunks[0].Content != "plain response" {
+		t.Errorf("non-SSE response should be delivered as one chunk, got %+v", chunks)
+	}
+}
+
+func TestClient_CallWithMessagesStream_NoRetryAfterChunks(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		// 推送一个片段后返回可重试的错误
+		writeSSE(w,
+			`{"choices":[{"delta":{"content":"partial"}}]}`,
+			`{"error":{"message":"connection reset by peer"}}`,
+		)
+	}))
+	defer server.Close()
+
+	client := NewDeepSeekClientWithOptions(
+		WithBaseURL(server.URL),
+		WithAPIKey("test-key"),
+		WithLogger(NewMockLogger()),
+		WithMaxRetries(3),
+		WithRetryWaitBase(time.Millisecond),
+	)
+
+	if _, err := client.CallWithMessagesStream("", "user", nil); err == nil {
+		t.Fatal("should error")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("should not retry after chunks were delivered, got %d calls", calls)
+	}
+}
+
+func TestAnthropicClient_StreamFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != nil {
+			t.Errorf("anthropic fallback should not request streaming")
+		}
+		w.Write([]byte(`{"type":"message","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	client := NewAnthropicClientWithOptions(
+		WithBaseURL(server.URL),
+		WithAPIKey("sk-ant-test-key"),
+		WithLogger(NewMockLogger()),
+	)
+
+	var chunks int
+	result, err := client.CallWithMessagesStream("system", "user", func(chunk StreamChunk) { chunks++ })
+	if err != nil || result != "ok" || chunks != 1 {
+		t.Errorf("unexpected result: %q, %v, %d chunks", result, err, chunks)
+	}
+}
@@ -0,0 +1,78 @@
+package mcp
+
+import "encoding/json"
+
+// Usage 单次AI调用的 token 用量（来自响应中的 usage 字段）
+type Usage struct {
+	Model            string `json:"model"`             // 实际调用的模型
+	PromptTokens     int    `json:"prompt_tokens"`     // 输入 token（含缓存命中）
+	CompletionTokens int    `json:"completion_tokens"` // 输出 token（含推理 token）
+	ReasoningTokens  int    `json:"reasoning_tokens"`  // 推理（思考）token
+	CachedTokens     int    `json:"cached_tokens"`     // 命中缓存的输入 token
+	TotalTokens      int    `json:"total_tokens"`
+}
+
+// UsageReporter 可获取最近一次成功调用用量的客户端（Client 及内置子类均已实现）
+type UsageReporter interface {
+	LastUsage() *Usage
+}
+
+// LastUsageOf 获取客户端最近一次调用的用量，客户端不支持或响应中没有 usage 时返回 nil
+func LastUsageOf(client AIClient) *Usage {
+	if reporter, ok := client.(UsageReporter); ok {
+		return reporter.LastUsage()
+	}
+	return nil
+}
+
+// parseUsage 解析 OpenAI 兼容响应中的 usage 字段
+// 兼容 DeepSeek 的 prompt_cache_hit_tokens 和 OpenAI 的 *_tokens_details
+func parseUsage(body []byte, model string) *Usage {
+	var result struct {
+		Model string `json:"model"`
+		Usage *struct {
+			PromptTokens         int `json:"prompt_tokens"`
+			CompletionTokens     int `json:"completion_tokens"`
+			TotalTokens          int `json:"total_tokens"`
+			PromptCacheHitTokens int `json:"prompt_cache_hit_tokens"`
+			PromptTokensDetails  *struct {
+				CachedTokens int `json:"cached_tokens"`
+			} `json:"prompt_tokens_details"`
+			CompletionTokensDetails *struct {
+				ReasoningTokens int `json:"reasoning_tokens"`
+			} `json:"completion_tokens_details"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil || result.Usage == nil {
+		return nil
+	}
+
+	usage := &Usage{
+		Model:            model,
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
+		TotalTokens:      result.Usage.TotalTokens,
+		CachedTokens:     result.Usage.PromptCacheHitTokens,
+	}
+	if result.Model != "" {
+		usage.Model = result.Model
+	}
+	if d := result.Usage.PromptTokensDetails; d != nil && d.CachedTokens > 0 {
+		usage.CachedTokens = d.CachedTokens
+	}
+	if d := result.Usage.CompletionTokensDetails; d != nil {
+		usage.ReasoningTokens = d.ReasoningTokens
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage
+}
+
+// LastUsage 最近一次成功调用的用量
+func (client *Client) LastUsage() *Usage {
+	if result := client.LastResult(); result != nil {
+		return result.Usage
+	}
+	return nil
+}
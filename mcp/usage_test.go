@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"testing"
+)
+
+// ============================================================
+// 测试 token 用量解析
+// ============================================================
+
+func TestParseUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *Usage
+	}{
+		{
+			name: "无 usage 字段",
+			body: `{"choices":[{"message":{"content":"ok"}}]}`,
+			want: nil,
+		},
+		{
+			name: "DeepSeek 缓存命中",
+			body: `{"model":"deepseek-reasoner","usage":{"prompt_tokens":1000,"completion_tokens":300,"total_tokens":1300,"prompt_cache_hit_tokens":600,"completion_tokens_details":{"reasoning_tokens":200}}}`,
+			want: &Usage{Model: "deepseek-reasoner", PromptTokens: 1000, CompletionTokens: 300, ReasoningTokens: 200, CachedTokens: 600, TotalTokens: 1300},
+		},
+		{
+			name: "OpenAI 格式，缺少 total_tokens 时自动计算",
+			body: `{"usage":{"prompt_tokens":100,"completion_tokens":50,"prompt_tokens_details":{"cached_tokens":20}}}`,
+			want: &Usage{Model: "fallback-model", PromptTokens: 100, CompletionTokens: 50, CachedTokens: 20, TotalTokens: 150},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseUsage([]byte(tt.body), "fallback-model")
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected nil, got %+v", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_LastUsage(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`
+
+	client := NewDeepSeekClientWithOptions(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewMockLogger()),
+		WithAPIKey("test-key"),
+	)
+
+	if _, err := client.CallWithMessages("system", "user"); err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+
+	usage := LastUsageOf(client)
+	if usage == nil {
+		t.Fatal("usage should be recorded")
+	}
+	if usage.Model != DefaultDeepSeekModel || usage.PromptTokens != 10 || usage.CompletionTokens != 5 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+
+	// 失败的调用不应保留上一次的用量
+	mockHTTP.SetErrorResponse(400, "bad request")
+	if _, err := client.CallWithMessages("system", "user"); err == nil {
+		t.Fatal("should error")
+	}
+	if LastUsageOf(client) != nil {
+		t.Error("usage should be cleared after failed call")
+	}
+}
@@ -74,6 +74,10 @@ func (s *AIModelStore) initDefaultData() error {
 	}{
 		{"deepseek", "DeepSeek", "deepseek"},
 		{"qwen", "Qwen", "qwen"},
+		{"anthropic", "Anthropic Claude", "anthropic"},
+		{"gemini", "Google Gemini", "gemini"},
+		{"ollama", "Ollama (本地)", "ollama"},
+		{"openai-compatible-local", "本地 OpenAI 兼容服务 (llama.cpp)", "openai-compatible-local"},
 	}
 
 	for _, model := range models {
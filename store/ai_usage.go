@@ -0,0 +1,346 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// systemPriceUserID 系统默认价格所属用户（用户可按模型覆盖）
+const systemPriceUserID = "system"
+
+// 成本汇总粒度
+const (
+	AICostPeriodDay   = "day"
+	AICostPeriodMonth = "month"
+)
+
+// AIUsageStore AI用量、价格表与预算存储
+type AIUsageStore struct {
+	db *sql.DB
+}
+
+// AIModelPrice 模型价格（美元 / 百万 token）
+type AIModelPrice struct {
+	UserID                string    `json:"user_id"`
+	Model                 string    `json:"model"` // 模型名称，按完全匹配或最长前缀匹配（如 "deepseek" 匹配 "deepseek-chat"）
+	InputPerMillion       float64   `json:"input_per_million"`
+	CachedInputPerMillion float64   `json:"cached_input_per_million"` // 缓存命中的输入价格（0 表示与输入价格相同）
+	OutputPerMillion      float64   `json:"output_per_million"`
+	IsSystem              bool      `json:"is_system"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// AIBudget 交易员月度AI预算
+type AIBudget struct {
+	TraderID         string    `json:"trader_id"`
+	UserID           string    `json:"user_id"`
+	MonthlyBudgetUSD float64   `json:"monthly_budget_usd"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// AICostSummary 按周期、交易员和模型汇总的AI成本
+type AICostSummary struct {
+	Period           string  `json:"period"` // 2006-01-02 或 2006-01
+	TraderID         string  `json:"trader_id"`
+	TraderName       string  `json:"trader_name"`
+	Model            string  `json:"model"`
+	Calls            int     `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	ReasoningTokens  int64   `json:"reasoning_tokens"`
+	CachedTokens     int64   `json:"cached_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// defaultAIModelPrices 默认价格表（美元 / 百万 token，可在 API 中按用户覆盖）
+var defaultAIModelPrices = []AIModelPrice{
+	{Model: "deepseek-chat", InputPerMillion: 0.28, CachedInputPerMillion: 0.028, OutputPerMillion: 0.42},
+	{Model: "deepseek-reasoner", InputPerMillion: 0.28, CachedInputPerMillion: 0.028, OutputPerMillion: 0.42},
+	{Model: "deepseek", InputPerMillion: 0.28, CachedInputPerMillion: 0.028, OutputPerMillion: 0.42},
+	{Model: "qwen3-max", InputPerMillion: 1.2, CachedInputPerMillion: 0, OutputPerMillion: 6},
+	{Model: "qwen-plus", InputPerMillion: 0.4, CachedInputPerMillion: 0, OutputPerMillion: 1.2},
+	{Model: "qwen", InputPerMillion: 1.2, CachedInputPerMillion: 0, OutputPerMillion: 6},
+	{Model: "gpt-4o", InputPerMillion: 2.5, CachedInputPerMillion: 1.25, OutputPerMillion: 10},
+	{Model: "gpt-4o-mini", InputPerMillion: 0.15, CachedInputPerMillion: 0.075, OutputPerMillion: 0.6},
+	{Model: "claude-sonnet-4-5", InputPerMillion: 3, CachedInputPerMillion: 0.3, OutputPerMillion: 15},
+	{Model: "claude-haiku-4-5", InputPerMillion: 1, CachedInputPerMillion: 0.1, OutputPerMillion: 5},
+	{Model: "claude-opus-4-1", InputPerMillion: 15, CachedInputPerMillion: 1.5, OutputPerMillion: 75},
+	{Model: "gemini-2.5-pro", InputPerMillion: 1.25, CachedInputPerMillion: 0.31, OutputPerMillion: 10},
+	{Model: "gemini-2.5-flash", InputPerMillion: 0.3, CachedInputPerMillion: 0.075, OutputPerMillion: 2.5},
+}
+
+func (s *AIUsageStore) initTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS ai_model_prices (
+			user_id TEXT NOT NULL,
+			model TEXT NOT NULL,
+			input_per_million REAL NOT NULL DEFAULT 0,
+			cached_input_per_million REAL NOT NULL DEFAULT 0,
+			output_per_million REAL NOT NULL DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, model)
+		)`,
+		`CREATE TABLE IF NOT EXISTS ai_budgets (
+			trader_id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			monthly_budget_usd REAL NOT NULL DEFAULT 0,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ai_budgets_user ON ai_budgets(user_id)`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("执行SQL失败: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *AIUsageStore) initDefaultData() error {
+	for _, p := range defaultAIModelPrices {
+		_, err := s.db.Exec(`
+			INSERT OR IGNORE INTO ai_model_prices (user_id, model, input_per_million, cached_input_per_million, output_per_million)
+			VALUES (?, ?, ?, ?, ?)
+		`, systemPriceUserID, p.Model, p.InputPerMillion, p.CachedInputPerMillion, p.OutputPerMillion)
+		if err != nil {
+			return fmt.Errorf("初始化模型价格失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListPrices 获取用户可见的价格表（用户自定义 + 未被覆盖的系统默认价格）
+func (s *AIUsageStore) ListPrices(userID string) ([]*AIModelPrice, error) {
+	rows, err := s.db.Query(`
+		SELECT user_id, model, input_per_million, cached_input_per_million, output_per_million, updated_at
+		FROM ai_model_prices
+		WHERE user_id = ?
+		   OR (user_id = ? AND model NOT IN (SELECT model FROM ai_model_prices WHERE user_id = ?))
+		ORDER BY model
+	`, userID, systemPriceUserID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询模型价格失败: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []*AIModelPrice
+	for rows.Next() {
+		var p AIModelPrice
+		var updatedAt string
+		if err := rows.Scan(&p.UserID, &p.Model, &p.InputPerMillion, &p.CachedInputPerMillion, &p.OutputPerMillion, &updatedAt); err != nil {
+			return nil, err
+		}
+		p.IsSystem = p.UserID == systemPriceUserID
+		p.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+		prices = append(prices, &p)
+	}
+	return prices, rows.Err()
+}
+
+// SetPrice 设置用户的模型价格（覆盖系统默认价格）
+func (s *AIUsageStore) SetPrice(userID string, price *AIModelPrice) error {
+	model := strings.ToLower(strings.TrimSpace(price.Model))
+	if model == "" {
+		return fmt.Errorf("模型名称不能为空")
+	}
+	if price.InputPerMillion < 0 || price.CachedInputPerMillion < 0 || price.OutputPerMillion < 0 {
+		return fmt.Errorf("价格不能为负数")
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO ai_model_prices (user_id, model, input_per_million, cached_input_per_million, output_per_million, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, model) DO UPDATE SET
+			input_per_million = excluded.input_per_million,
+			cached_input_per_million = excluded.cached_input_per_million,
+			output_per_million = excluded.output_per_million,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, model, price.InputPerMillion, price.CachedInputPerMillion, price.OutputPerMillion)
+	if err != nil {
+		return fmt.Errorf("保存模型价格失败: %w", err)
+	}
+	return nil
+}
+
+// DeletePrice 删除用户自定义价格（恢复系统默认价格）
+func (s *AIUsageStore) DeletePrice(userID, model string) error {
+	result, err := s.db.Exec(`DELETE FROM ai_model_prices WHERE user_id = ? AND model = ?`,
+		userID, strings.ToLower(model))
+	if err != nil {
+		return fmt.Errorf("删除模型价格失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("自定义价格不存在: %s", model)
+	}
+	return nil
+}
+
+// GetPrice 查找模型价格：完全匹配优先，其次最长前缀匹配；同等匹配时用户价格优先于系统价格
+func (s *AIUsageStore) GetPrice(userID, model string) (*AIModelPrice, error) {
+	prices, err := s.ListPrices(userID)
+	if err != nil {
+		return nil, err
+	}
+	model = strings.ToLower(model)
+	var best *AIModelPrice
+	for _, p := range prices {
+		if !strings.HasPrefix(model, p.Model) {
+			continue
+		}
+		if best == nil || len(p.Model) > len(best.Model) ||
+			(len(p.Model) == len(best.Model) && best.IsSystem && !p.IsSystem) {
+			best = p
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("未配置模型价格: %s", model)
+	}
+	return best, nil
+}
+
+// CalculateCost 按价格表计算一次调用的成本（美元），未配置价格时返回 0
+func (s *AIUsageStore) CalculateCost(userID, model string, promptTokens, cachedTokens, completionTokens int) float64 {
+	price, err := s.GetPrice(userID, model)
+	if err != nil {
+		return 0
+	}
+	return price.Cost(promptTokens, cachedTokens, completionTokens)
+}
+
+// Cost 计算成本（美元）。推理 token 已包含在 completionTokens 中，按输出价格计费
+func (p *AIModelPrice) Cost(promptTokens, cachedTokens, completionTokens int) float64 {
+	cachedPrice := p.CachedInputPerMillion
+	if cachedPrice <= 0 {
+		cachedPrice = p.InputPerMillion
+	}
+	cached := min(cachedTokens, promptTokens)
+	cost := float64(promptTokens-cached)*p.InputPerMillion +
+		float64(cached)*cachedPrice +
+		float64(completionTokens)*p.OutputPerMillion
+	return math.Round(cost) / 1e6
+}
+
+// SetBudget 设置交易员月度预算（budget <= 0 表示取消预算）
+func (s *AIUsageStore) SetBudget(userID, traderID string, budget float64) error {
+	if budget <= 0 {
+		_, err := s.db.Exec(`DELETE FROM ai_budgets WHERE trader_id = ? AND user_id = ?`, traderID, userID)
+		return err
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO ai_budgets (trader_id, user_id, monthly_budget_usd, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(trader_id) DO UPDATE SET
+			monthly_budget_usd = excluded.monthly_budget_usd,
+			updated_at = CURRENT_TIMESTAMP
+	`, traderID, userID, budget)
+	if err != nil {
+		return fmt.Errorf("保存AI预算失败: %w", err)
+	}
+	return nil
+}
+
+// GetBudget 获取交易员月度预算，未设置时返回 nil
+func (s *AIUsageStore) GetBudget(traderID string) (*AIBudget, error) {
+	var b AIBudget
+	var updatedAt string
+	err := s.db.QueryRow(`
+		SELECT trader_id, user_id, monthly_budget_usd, updated_at FROM ai_budgets WHERE trader_id = ?
+	`, traderID).Scan(&b.TraderID, &b.UserID, &b.MonthlyBudgetUSD, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询AI预算失败: %w", err)
+	}
+	b.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+	return &b, nil
+}
+
+// ListBudgets 获取用户所有交易员的月度预算
+func (s *AIUsageStore) ListBudgets(userID string) ([]*AIBudget, error) {
+	rows, err := s.db.Query(`
+		SELECT trader_id, user_id, monthly_budget_usd, updated_at FROM ai_budgets WHERE user_id = ? ORDER BY trader_id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询AI预算失败: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []*AIBudget
+	for rows.Next() {
+		var b AIBudget
+		var updatedAt string
+		if err := rows.Scan(&b.TraderID, &b.UserID, &b.MonthlyBudgetUSD, &updatedAt); err != nil {
+			return nil, err
+		}
+		b.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+		budgets = append(budgets, &b)
+	}
+	return budgets, rows.Err()
+}
+
+// GetMonthCost 获取交易员本月（UTC）累计AI成本
+func (s *AIUsageStore) GetMonthCost(traderID string, now time.Time) (float64, error) {
+	now = now.UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var cost float64
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(ai_cost_usd), 0) FROM decision_records
+		WHERE trader_id = ? AND timestamp >= ?
+	`, traderID, monthStart.Format(time.RFC3339)).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("查询月度AI成本失败: %w", err)
+	}
+	return cost, nil
+}
+
+// GetCostSummary 按天或按月汇总用户的AI成本（按交易员和模型分组）
+// traderID 为空表示全部交易员；from/to 为零值表示不限制
+func (s *AIUsageStore) GetCostSummary(userID, period, traderID string, from, to time.Time) ([]*AICostSummary, error) {
+	periodLen := 10
+	if period == AICostPeriodMonth {
+		periodLen = 7
+	}
+
+	query := `
+		SELECT substr(d.timestamp, 1, ?) AS period, d.trader_id, COALESCE(t.name, ''), COALESCE(d.ai_model, ''),
+			   COUNT(*), COALESCE(SUM(d.prompt_tokens), 0), COALESCE(SUM(d.completion_tokens), 0),
+			   COALESCE(SUM(d.reasoning_tokens), 0), COALESCE(SUM(d.cached_tokens), 0),
+			   COALESCE(SUM(d.ai_cost_usd), 0)
+		FROM decision_records d
+		JOIN traders t ON t.id = d.trader_id
+		WHERE t.user_id = ? AND d.total_tokens > 0`
+	args := []interface{}{periodLen, userID}
+	if traderID != "" {
+		query += ` AND d.trader_id = ?`
+		args = append(args, traderID)
+	}
+	if !from.IsZero() {
+		query += ` AND d.timestamp >= ?`
+		args = append(args, from.UTC().Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query += ` AND d.timestamp < ?`
+		args = append(args, to.UTC().Format(time.RFC3339))
+	}
+	query += ` GROUP BY period, d.trader_id, d.ai_model ORDER BY period DESC, d.trader_id, d.ai_model`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询AI成本失败: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []*AICostSummary
+	for rows.Next() {
+		var c AICostSummary
+		if err := rows.Scan(&c.Period, &c.TraderID, &c.TraderName, &c.Model, &c.Calls,
+			&c.PromptTokens, &c.CompletionTokens, &c.ReasoningTokens, &c.CachedTokens, &c.CostUSD); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &c)
+	}
+	return summaries, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -14,22 +15,39 @@ type DecisionStore struct {
 
 // DecisionRecord 决策记录
 type DecisionRecord struct {
-	ID                  int64              `json:"id"`
-	TraderID            string             `json:"trader_id"`
-	CycleNumber         int                `json:"cycle_number"`
-	Timestamp           time.Time          `json:"timestamp"`
-	SystemPrompt        string             `json:"system_prompt"`
-	InputPrompt         string             `json:"input_prompt"`
-	CoTTrace            string             `json:"cot_trace"`
-	DecisionJSON        string             `json:"decision_json"`
-	CandidateCoins      []string           `json:"candidate_coins"`
-	ExecutionLog        []string           `json:"execution_log"`
-	Success             bool               `json:"success"`
-	ErrorMessage        string             `json:"error_message"`
-	AIRequestDurationMs int64              `json:"ai_request_duration_ms"`
-	AccountState        AccountSnapshot    `json:"account_state"`
-	Positions           []PositionSnapshot `json:"positions"`
-	Decisions           []DecisionAction   `json:"decisions"`
+	ID                      int64              `json:"id"`
+	TraderID                string             `json:"trader_id"`
+	CycleNumber             int                `json:"cycle_number"`
+	Timestamp               time.Time          `json:"timestamp"`
+	SystemPrompt            string             `json:"system_prompt"`
+	InputPrompt             string             `json:"input_prompt"`
+	CoTTrace                string             `json:"cot_trace"`
+	DecisionJSON            string             `json:"decision_json"`
+	CandidateCoins          []string           `json:"candidate_coins"`
+	ExecutionLog            []string           `json:"execution_log"`
+	Success                 bool               `json:"success"`
+	ErrorMessage            string             `json:"error_message"`
+	AIRequestDurationMs     int64              `json:"ai_request_duration_ms"`
+	ExperimentID            string             `json:"experiment_id,omitempty"`              // 所属 Prompt A/B 实验
+	PromptVariant           string             `json:"prompt_variant"`                       // 本周期使用的 Prompt 变体
+	PromptTemplateVersionID int64              `json:"prompt_template_version_id,omitempty"` // 使用的提示词模板版本ID（0表示未使用模板）
+	SystemPromptTokens      int                `json:"system_prompt_tokens"`                 // System Prompt 估算 token 数
+	UserPromptTokens        int                `json:"user_prompt_tokens"`                   // User Prompt 估算 token 数（裁剪后）
+	PromptTokenLimit        int                `json:"prompt_token_limit,omitempty"`         // 本周期 Prompt 可用 token 上限（0表示未启用预算）
+	PromptTrimmed           string             `json:"prompt_trimmed,omitempty"`             // 为满足预算执行的裁剪操作
+	AIModel                 string             `json:"ai_model,omitempty"`                   // 实际调用的模型
+	AIProvider              string             `json:"ai_provider,omitempty"`                // 实际应答的提供商（故障转移时为备用提供商）
+	AITarget                string             `json:"ai_target,omitempty"`                  // 故障转移链中实际应答的目标（AI 模型），同一提供商的模型之间切换时可区分
+	PromptTokens            int                `json:"prompt_tokens"`                        // AI返回的输入 token 数
+	CompletionTokens        int                `json:"completion_tokens"`                    // AI返回的输出 token 数（含推理）
+	ReasoningTokens         int                `json:"reasoning_tokens"`                     // 推理 token 数
+	CachedTokens            int                `json:"cached_tokens"`                        // 缓存命中的输入 token 数
+	TotalTokens             int                `json:"total_tokens"`                         // 总 token 数
+	AICostUSD               float64            `json:"ai_cost_usd"`                          // 按价格表计算的本次调用成本（美元）
+	MarketRegime            string             `json:"market_regime,omitempty"`              // 决策时的市场状态（trending_up/trending_down/ranging/high_volatility）
+	AccountState            AccountSnapshot    `json:"account_state"`
+	Positions               []PositionSnapshot `json:"positions"`
+	Decisions               []DecisionAction   `json:"decisions"`
 }
 
 // AccountSnapshot 账户状态快照
@@ -74,6 +92,20 @@ type Statistics struct {
 	FailedCycles        int `json:"failed_cycles"`
 	TotalOpenPositions  int `json:"total_open_positions"`
 	TotalClosePositions int `json:"total_close_positions"`
+	// ByRegime 按决策时的市场状态分组的表现（未记录市场状态的周期不计入）
+	ByRegime []RegimeStatistics `json:"by_regime,omitempty"`
+}
+
+// RegimeStatistics 单个市场状态下的决策与交易表现
+// 交易按开仓订单ID关联到产生该订单的决策周期
+type RegimeStatistics struct {
+	Regime           string  `json:"regime"`
+	Cycles           int     `json:"cycles"`
+	SuccessfulCycles int     `json:"successful_cycles"`
+	Trades           int     `json:"trades"` // 已平仓交易数
+	WinTrades        int     `json:"win_trades"`
+	WinRate          float64 `json:"win_rate"` // 胜率（%）
+	TotalPnL         float64 `json:"total_pnl"`
 }
 
 // initTables 初始化决策相关表
@@ -156,9 +188,38 @@ func (s *DecisionStore) initTables() error {
 		}
 	}
 
+	// 迁移：为现有表添加新列（已存在时忽略错误）
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN experiment_id TEXT DEFAULT ''`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN prompt_variant TEXT DEFAULT ''`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN prompt_template_version_id INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN system_prompt_tokens INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN user_prompt_tokens INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN prompt_token_limit INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN prompt_trimmed TEXT DEFAULT ''`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN ai_model TEXT DEFAULT ''`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN prompt_tokens INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN completion_tokens INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN reasoning_tokens INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN cached_tokens INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN total_tokens INTEGER DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN ai_cost_usd REAL DEFAULT 0`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN ai_provider TEXT DEFAULT ''`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN market_regime TEXT DEFAULT ''`)
+	s.db.Exec(`ALTER TABLE decision_records ADD COLUMN ai_target TEXT DEFAULT ''`)
+	s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_decision_records_experiment ON decision_records(experiment_id, prompt_variant)`)
+
 	return nil
 }
 
+// decisionRecordColumns 决策记录查询列（与 scanDecisionRecord 保持一致）
+const decisionRecordColumns = `id, trader_id, cycle_number, timestamp, system_prompt, input_prompt,
+			   cot_trace, decision_json, candidate_coins, execution_log,
+			   success, error_message, ai_request_duration_ms,
+			   experiment_id, prompt_variant, prompt_template_version_id,
+			   system_prompt_tokens, user_prompt_tokens, prompt_token_limit, prompt_trimmed,
+			   ai_model, prompt_tokens, completion_tokens, reasoning_tokens, cached_tokens,
+			   total_tokens, ai_cost_usd, ai_provider, market_regime, ai_target`
+
 // LogDecision 记录决策
 func (s *DecisionStore) LogDecision(record *DecisionRecord) error {
 	if record.Timestamp.IsZero() {
@@ -183,13 +244,21 @@ func (s *DecisionStore) LogDecision(record *DecisionRecord) error {
 		INSERT INTO decision_records (
 			trader_id, cycle_number, timestamp, system_prompt, input_prompt,
 			cot_trace, decision_json, candidate_coins, execution_log,
-			success, error_message, ai_request_duration_ms
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			success, error_message, ai_request_duration_ms,
+			experiment_id, prompt_variant, prompt_template_version_id,
+			system_prompt_tokens, user_prompt_tokens, prompt_token_limit, prompt_trimmed,
+			ai_model, prompt_tokens, completion_tokens, reasoning_tokens, cached_tokens,
+			total_tokens, ai_cost_usd, ai_provider, market_regime, ai_target
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		record.TraderID, record.CycleNumber, record.Timestamp.Format(time.RFC3339),
 		record.SystemPrompt, record.InputPrompt, record.CoTTrace, record.DecisionJSON,
 		string(candidateCoinsJSON), string(executionLogJSON),
 		record.Success, record.ErrorMessage, record.AIRequestDurationMs,
+		record.ExperimentID, record.PromptVariant, record.PromptTemplateVersionID,
+		record.SystemPromptTokens, record.UserPromptTokens, record.PromptTokenLimit, record.PromptTrimmed,
+		record.AIModel, record.PromptTokens, record.CompletionTokens, record.ReasoningTokens, record.CachedTokens,
+		record.TotalTokens, record.AICostUSD, record.AIProvider, record.MarketRegime, record.AITarget,
 	)
 	if err != nil {
 		return fmt.Errorf("插入决策记录失败: %w", err)
@@ -234,8 +303,8 @@ func (s *DecisionStore) LogDecision(record *DecisionRecord) error {
 
 	// 插入决策动作（订单详情）
 	for _, action := range record.Decisions {
-		actionTimestamp := action.Timestamp
-		if actionTimestamp.IsZero() {
+		actionTimestamp := action.Timestamp.UTC()
+		if action.Timestamp.IsZero() {
 			actionTimestamp = record.Timestamp
 		}
 		_, err = tx.Exec(`
@@ -264,9 +333,7 @@ func (s *DecisionStore) LogDecision(record *DecisionRecord) error {
 // GetLatestRecords 获取指定交易员最近N条记录（按时间正序：从旧到新）
 func (s *DecisionStore) GetLatestRecords(traderID string, n int) ([]*DecisionRecord, error) {
 	rows, err := s.db.Query(`
-		SELECT id, trader_id, cycle_number, timestamp, system_prompt, input_prompt,
-			   cot_trace, decision_json, candidate_coins, execution_log,
-			   success, error_message, ai_request_duration_ms
+		SELECT `+decisionRecordColumns+`
 		FROM decision_records
 		WHERE trader_id = ?
 		ORDER BY timestamp DESC
@@ -302,9 +369,7 @@ func (s *DecisionStore) GetLatestRecords(traderID string, n int) ([]*DecisionRec
 // GetAllLatestRecords 获取所有交易员最近N条记录
 func (s *DecisionStore) GetAllLatestRecords(n int) ([]*DecisionRecord, error) {
 	rows, err := s.db.Query(`
-		SELECT id, trader_id, cycle_number, timestamp, system_prompt, input_prompt,
-			   cot_trace, decision_json, candidate_coins, execution_log,
-			   success, error_message, ai_request_duration_ms
+		SELECT `+decisionRecordColumns+`
 		FROM decision_records
 		ORDER BY timestamp DESC
 		LIMIT ?
@@ -336,9 +401,7 @@ func (s *DecisionStore) GetRecordsByDate(traderID string, date time.Time) ([]*De
 	dateStr := date.Format("2006-01-02")
 
 	rows, err := s.db.Query(`
-		SELECT id, trader_id, cycle_number, timestamp, system_prompt, input_prompt,
-			   cot_trace, decision_json, candidate_coins, execution_log,
-			   success, error_message, ai_request_duration_ms
+		SELECT `+decisionRecordColumns+`
 		FROM decision_records
 		WHERE trader_id = ? AND DATE(timestamp) = ?
 		ORDER BY timestamp ASC
@@ -360,9 +423,76 @@ func (s *DecisionStore) GetRecordsByDate(traderID string, date time.Time) ([]*De
 	return records, nil
 }
 
+// GetRecordsInRange 获取交易员在时间范围内可回放（包含 Prompt）的记录（按时间升序，用于回放评估）
+// from/to 为零值时不限制；limit > 0 时只返回范围内最近的 limit 条
+// 时间统一按 UTC 比较（兼容旧记录中带时区偏移的时间戳）
+func (s *DecisionStore) GetRecordsInRange(traderID string, from, to time.Time, limit int) ([]*DecisionRecord, error) {
+	query := `SELECT ` + decisionRecordColumns + ` FROM decision_records
+		WHERE trader_id = ? AND system_prompt != '' AND input_prompt != ''`
+	args := []any{traderID}
+	if !from.IsZero() {
+		query += ` AND datetime(timestamp) >= datetime(?)`
+		args = append(args, from.UTC().Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query += ` AND datetime(timestamp) < datetime(?)`
+		args = append(args, to.UTC().Format(time.RFC3339))
+	}
+	if limit > 0 {
+		query = `SELECT * FROM (` + query + ` ORDER BY datetime(timestamp) DESC LIMIT ?)`
+		args = append(args, limit)
+	}
+	query += ` ORDER BY datetime(timestamp) ASC`
+
+	return s.queryRecordsWithDetails(query, args...)
+}
+
+// GetRecordsByIDs 获取交易员的指定记录（按时间升序）
+func (s *DecisionStore) GetRecordsByIDs(traderID string, ids []int64) ([]*DecisionRecord, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := []any{traderID}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	return s.queryRecordsWithDetails(`
+		SELECT `+decisionRecordColumns+`
+		FROM decision_records
+		WHERE trader_id = ? AND id IN (`+placeholders+`)
+		ORDER BY timestamp ASC
+	`, args...)
+}
+
+// queryRecordsWithDetails 查询决策记录并填充关联数据
+func (s *DecisionStore) queryRecordsWithDetails(query string, args ...any) ([]*DecisionRecord, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*DecisionRecord
+	for rows.Next() {
+		record, err := s.scanDecisionRecord(rows)
+		if err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	rows.Close()
+
+	for _, record := range records {
+		s.fillRecordDetails(record)
+	}
+	return records, nil
+}
+
 // CleanOldRecords 清理N天前的旧记录
 func (s *DecisionStore) CleanOldRecords(traderID string, days int) (int64, error) {
-	cutoffTime := time.Now().AddDate(0, 0, -days).Format(time.RFC3339)
+	cutoffTime := time.Now().UTC().AddDate(0, 0, -days).Format(time.RFC3339)
 
 	result, err := s.db.Exec(`
 		DELETE FROM decision_records
@@ -410,9 +540,81 @@ func (s *DecisionStore) GetStatistics(traderID string) (*Statistics, error) {
 		return nil, fmt.Errorf("查询平仓次数失败: %w", err)
 	}
 
+	stats.ByRegime, err = s.getRegimeStatistics(traderID)
+	if err != nil {
+		return nil, err
+	}
+
 	return stats, nil
 }
 
+// getRegimeStatistics 按市场状态统计决策周期和已平仓交易
+func (s *DecisionStore) getRegimeStatistics(traderID string) ([]RegimeStatistics, error) {
+	var result []RegimeStatistics
+	index := make(map[string]int)
+
+	rows, err := s.db.Query(`
+		SELECT market_regime, COUNT(*), SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END)
+		FROM decision_records
+		WHERE trader_id = ? AND market_regime != ''
+		GROUP BY market_regime
+		ORDER BY market_regime
+	`, traderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询市场状态统计失败: %w", err)
+	}
+	for rows.Next() {
+		var st RegimeStatistics
+		if err := rows.Scan(&st.Regime, &st.Cycles, &st.SuccessfulCycles); err != nil {
+			continue
+		}
+		index[st.Regime] = len(result)
+		result = append(result, st)
+	}
+	rows.Close()
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	rows, err = s.db.Query(`
+		SELECT r.market_regime, p.realized_pnl
+		FROM trader_positions p
+		JOIN decision_actions a ON a.trader_id = p.trader_id
+			AND a.action IN ('open_long', 'open_short')
+			AND a.order_id != 0
+			AND CAST(a.order_id AS TEXT) = p.entry_order_id
+		JOIN decision_records r ON r.id = a.decision_id
+		WHERE r.trader_id = ? AND r.market_regime != '' AND p.status = 'CLOSED'
+	`, traderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询市场状态交易统计失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var regime string
+		var pnl float64
+		if err := rows.Scan(&regime, &pnl); err != nil {
+			continue
+		}
+		i, ok := index[regime]
+		if !ok {
+			continue
+		}
+		st := &result[i]
+		st.Trades++
+		st.TotalPnL += pnl
+		if pnl > 0 {
+			st.WinTrades++
+		}
+	}
+	for i := range result {
+		if result[i].Trades > 0 {
+			result[i].WinRate = float64(result[i].WinTrades) / float64(result[i].Trades) * 100
+		}
+	}
+	return result, nil
+}
+
 // GetAllStatistics 获取所有交易员的统计信息
 func (s *DecisionStore) GetAllStatistics() (*Statistics, error) {
 	stats := &Statistics{}
@@ -451,18 +653,44 @@ func (s *DecisionStore) scanDecisionRecord(rows *sql.Rows) (*DecisionRecord, err
 	var record DecisionRecord
 	var timestampStr string
 	var candidateCoinsJSON, executionLogJSON string
+	var experimentID, promptVariant sql.NullString
+	var templateVersionID, systemTokens, userTokens, tokenLimit sql.NullInt64
+	var promptTrimmed, aiModel, aiProvider, marketRegime, aiTarget sql.NullString
+	var promptTokens, completionTokens, reasoningTokens, cachedTokens, totalTokens sql.NullInt64
+	var aiCost sql.NullFloat64
 
 	err := rows.Scan(
 		&record.ID, &record.TraderID, &record.CycleNumber, &timestampStr,
 		&record.SystemPrompt, &record.InputPrompt, &record.CoTTrace,
 		&record.DecisionJSON, &candidateCoinsJSON, &executionLogJSON,
 		&record.Success, &record.ErrorMessage, &record.AIRequestDurationMs,
+		&experimentID, &promptVariant, &templateVersionID,
+		&systemTokens, &userTokens, &tokenLimit, &promptTrimmed,
+		&aiModel, &promptTokens, &completionTokens, &reasoningTokens, &cachedTokens,
+		&totalTokens, &aiCost, &aiProvider, &marketRegime, &aiTarget,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	record.Timestamp, _ = time.Parse(time.RFC3339, timestampStr)
+	record.ExperimentID = experimentID.String
+	record.PromptVariant = promptVariant.String
+	record.PromptTemplateVersionID = templateVersionID.Int64
+	record.SystemPromptTokens = int(systemTokens.Int64)
+	record.UserPromptTokens = int(userTokens.Int64)
+	record.PromptTokenLimit = int(tokenLimit.Int64)
+	record.PromptTrimmed = promptTrimmed.String
+	record.AIModel = aiModel.String
+	record.PromptTokens = int(promptTokens.Int64)
+	record.CompletionTokens = int(completionTokens.Int64)
+	record.ReasoningTokens = int(reasoningTokens.Int64)
+	record.CachedTokens = int(cachedTokens.Int64)
+	record.TotalTokens = int(totalTokens.Int64)
+	record.AICostUSD = aiCost.Float64
+	record.AIProvider = aiProvider.String
+	record.MarketRegime = marketRegime.String
+	record.AITarget = aiTarget.String
 	json.Unmarshal([]byte(candidateCoinsJSON), &record.CandidateCoins)
 	json.Unmarshal([]byte(executionLogJSON), &record.ExecutionLog)
 
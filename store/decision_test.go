@@ -0,0 +1,148 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestGetRecordsInRange 测试时间范围按 UTC 比较、只返回可回放记录，并按条数限制取最近的记录
+func TestGetRecordsInRange(t *testing.T) {
+	decisions := newTestStore(t).Decision()
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		record := &DecisionRecord{
+			TraderID:     "t1",
+			CycleNumber:  i + 1,
+			Timestamp:    base.Add(time.Duration(i) * time.Hour).In(shanghai), // 插入时统一转换为 UTC
+			SystemPrompt: "system",
+			InputPrompt:  "input",
+			AIProvider:   "deepseek",
+			AITarget:     "backup-model",
+			Decisions:    []DecisionAction{{Action: "hold", Symbol: "BTCUSDT", Timestamp: base.In(shanghai)}},
+		}
+		if i == 2 {
+			record.InputPrompt = "" // 无 Prompt 的记录不可回放
+		}
+		if err := decisions.LogDecision(record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Timestamp.Location() != time.UTC {
+			t.Errorf("timestamp not normalized to UTC: %v", record.Timestamp)
+		}
+	}
+	// 旧版本写入的带时区偏移的记录：2025-03-01 13:00+08:00 = 05:00 UTC
+	if _, err := decisions.db.Exec(`INSERT INTO decision_records (trader_id, cycle_number, timestamp, system_prompt, input_prompt)
+		VALUES ('t1', 6, '2025-03-01T13:00:00+08:00', 'system', 'input')`); err != nil {
+		t.Fatal(err)
+	}
+
+	cycles := func(records []*DecisionRecord) []int {
+		var result []int
+		for _, r := range records {
+			result = append(result, r.CycleNumber)
+		}
+		return result
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		limit    int
+		want     []int
+	}{
+		{"全部可回放记录", time.Time{}, time.Time{}, 0, []int{1, 2, 4, 5, 6}},
+		{"非 UTC 的查询范围", base.Add(time.Hour).In(shanghai), base.Add(4 * time.Hour).In(shanghai), 0, []int{2, 4}},
+		{"带时区偏移的旧记录", base.Add(5 * time.Hour), time.Time{}, 0, []int{6}},
+		{"限制条数时取最近的记录", time.Time{}, time.Time{}, 2, []int{5, 6}},
+	}
+	if records, _ := decisions.GetRecordsInRange("t1", base, base.Add(time.Minute), 0); len(records) != 1 ||
+		records[0].AIProvider != "deepseek" || records[0].AITarget != "backup-model" {
+		t.Errorf("ai provider/target: %+v", records)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := decisions.GetRecordsInRange("t1", tt.from, tt.to, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cycles(records); !equalInts(got, tt.want) {
+				t.Errorf("cycles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	records, _ := decisions.GetRecordsInRange("t1", time.Time{}, time.Time{}, 1)
+	if len(records) != 1 || len(records[0].Decisions) != 0 {
+		t.Fatalf("records: %+v", records)
+	}
+	records, _ = decisions.GetRecordsInRange("t1", time.Time{}, base.Add(time.Minute), 0)
+	if len(records) != 1 || !records[0].Decisions[0].Timestamp.Equal(base) {
+		t.Errorf("action timestamp: %+v", records)
+	}
+}
+
+// TestGetStatisticsByRegime 测试按决策时的市场状态统计周期和开仓订单关联的已平仓交易
+func TestGetStatisticsByRegime(t *testing.T) {
+	s := newTestStore(t)
+
+	orderID := int64(2000)
+	logCycle := func(traderID, regime string, success bool, pnl float64, closed bool) {
+		t.Helper()
+		orderID++
+		record := &DecisionRecord{
+			TraderID:     traderID,
+			MarketRegime: regime,
+			Success:      success,
+			Decisions:    []DecisionAction{{Action: "open_short", Symbol: "ETHUSDT", OrderID: orderID, Success: true}},
+		}
+		if err := s.Decision().LogDecision(record); err != nil {
+			t.Fatal(err)
+		}
+		pos := &TraderPosition{TraderID: traderID, Symbol: "ETHUSDT", Side: "SHORT", Quantity: 1, EntryPrice: 100, EntryOrderID: strconv.FormatInt(orderID, 10)}
+		if err := s.Position().Create(pos); err != nil {
+			t.Fatal(err)
+		}
+		if closed {
+			if err := s.Position().ClosePosition(pos.ID, 99, "", pnl, 0, "ai_decision"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	logCycle("t1", "trending_up", true, 4, true)
+	logCycle("t1", "trending_up", true, -1, true)
+	logCycle("t1", "trending_up", false, 0, false) // 未平仓不计入交易
+	logCycle("t1", "ranging", true, -2, true)
+	logCycle("t1", "", true, 10, true)       // 未记录市场状态
+	logCycle("t2", "ranging", true, 7, true) // 其他交易员
+
+	stats, err := s.Decision().GetStatistics("t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.ByRegime) != 2 {
+		t.Fatalf("by regime: %+v", stats.ByRegime)
+	}
+	ranging, trending := stats.ByRegime[0], stats.ByRegime[1]
+	if ranging.Regime != "ranging" || ranging.Cycles != 1 || ranging.Trades != 1 || ranging.WinTrades != 0 ||
+		ranging.WinRate != 0 || ranging.TotalPnL != -2 {
+		t.Errorf("ranging: %+v", ranging)
+	}
+	if trending.Regime != "trending_up" || trending.Cycles != 3 || trending.SuccessfulCycles != 2 ||
+		trending.Trades != 2 || trending.WinTrades != 1 || trending.WinRate != 50 || trending.TotalPnL != 3 {
+		t.Errorf("trending_up: %+v", trending)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
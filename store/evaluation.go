@@ -0,0 +1,520 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// 模型评估状态
+const (
+	EvaluationStatusPending   = "pending"
+	EvaluationStatusRunning   = "running"
+	EvaluationStatusCompleted = "completed"
+	EvaluationStatusFailed    = "failed"
+)
+
+// 综合得分权重（各指标均为 0-100）
+// 前瞻收益、耗时和成本没有绝对标准，按参与排名的模型之间的相对位置换算（最好 100，最差 0）
+const (
+	evaluationWeightValid     = 0.30  // 决策有效率
+	evaluationWeightAgreement = 0.15  // 与原决策一致率
+	evaluationWeightHit       = 0.25  // 前瞻方向命中率
+	evaluationWeightReturn    = 0.15  // 平均前瞻收益（越高越好）
+	evaluationWeightLatency   = 0.075 // 平均响应耗时（越低越好）
+	evaluationWeightCost      = 0.075 // 平均每次调用成本（越低越好）
+)
+
+// EvaluationStore 模型评估存储（历史决策回放到多个AI模型并打分）
+type EvaluationStore struct {
+	db *sql.DB
+}
+
+// ModelEvaluation 模型评估任务
+type ModelEvaluation struct {
+	ID             string     `json:"id"`
+	UserID         string     `json:"user_id"`
+	Name           string     `json:"name"`
+	TraderID       string     `json:"trader_id"`              // 回放该交易员的历史决策
+	ModelIDs       []string   `json:"model_ids"`              // 参与评估的AI模型
+	DecisionIDs    []int64    `json:"decision_ids,omitempty"` // 指定的决策记录（为空时按时间范围选取）
+	From           *time.Time `json:"from,omitempty"`
+	To             *time.Time `json:"to,omitempty"`
+	SampleLimit    int        `json:"sample_limit"`    // 最多回放的决策记录数
+	HorizonMinutes int        `json:"horizon_minutes"` // 前瞻价格变化的观察窗口（分钟）
+	Status         string     `json:"status"`
+	Progress       int        `json:"progress"` // 已完成的样本数
+	Total          int        `json:"total"`    // 样本总数（记录数 × 模型数）
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// EvaluationSample 单条决策记录在单个模型上的回放结果
+type EvaluationSample struct {
+	ID               int64     `json:"id"`
+	EvaluationID     string    `json:"evaluation_id"`
+	ModelID          string    `json:"model_id"`
+	DecisionID       int64     `json:"decision_id"` // 被回放的决策记录
+	Parsed           bool      `json:"parsed"`      // 成功提取出决策JSON
+	Valid            bool      `json:"valid"`       // 决策通过验证
+	LatencyMs        int64     `json:"latency_ms"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+	HasBaseline      bool      `json:"has_baseline"`       // 原决策可用于比较
+	Agreement        float64   `json:"agreement"`          // 与原决策的一致度 (0-1)
+	ActionableCount  int       `json:"actionable_count"`   // 有前瞻价格数据的开平仓决策数
+	HitCount         int       `json:"hit_count"`          // 方向正确的决策数
+	ForwardReturnPct float64   `json:"forward_return_pct"` // 按决策方向计算的平均前瞻收益 (%)
+	DecisionJSON     string    `json:"decision_json"`
+	Error            string    `json:"error,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// EvaluationScore 模型得分（排行榜的一行）
+type EvaluationScore struct {
+	ModelID             string  `json:"model_id"`
+	Samples             int     `json:"samples"`
+	ParseRate           float64 `json:"parse_rate"`             // 解析成功率 (%)
+	ValidRate           float64 `json:"valid_rate"`             // 验证通过率 (%)
+	AvgLatencyMs        float64 `json:"avg_latency_ms"`         // 平均响应耗时（仅统计解析成功的样本）
+	TotalCostUSD        float64 `json:"total_cost_usd"`         // 总成本
+	AvgCostUSD          float64 `json:"avg_cost_usd"`           // 平均每次调用成本
+	AgreementRate       float64 `json:"agreement_rate"`         // 与原决策的平均一致度 (%)
+	Actionable          int     `json:"actionable"`             // 参与前瞻统计的开平仓决策数
+	HitRate             float64 `json:"hit_rate"`               // 前瞻方向命中率 (%)
+	AvgForwardReturnPct float64 `json:"avg_forward_return_pct"` // 平均前瞻收益 (%)
+	Score               float64 `json:"score"`                  // 综合得分 (0-100)
+	Rank                int     `json:"rank"`
+}
+
+func (s *EvaluationStore) initTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS model_evaluations (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			trader_id TEXT NOT NULL,
+			model_ids TEXT NOT NULL DEFAULT '[]',
+			decision_ids TEXT NOT NULL DEFAULT '[]',
+			from_time TEXT DEFAULT '',
+			to_time TEXT DEFAULT '',
+			sample_limit INTEGER DEFAULT 0,
+			horizon_minutes INTEGER DEFAULT 60,
+			status TEXT NOT NULL DEFAULT 'pending',
+			progress INTEGER DEFAULT 0,
+			total INTEGER DEFAULT 0,
+			error TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS model_evaluation_samples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			evaluation_id TEXT NOT NULL,
+			model_id TEXT NOT NULL,
+			decision_id INTEGER NOT NULL,
+			parsed BOOLEAN DEFAULT 0,
+			valid BOOLEAN DEFAULT 0,
+			latency_ms INTEGER DEFAULT 0,
+			prompt_tokens INTEGER DEFAULT 0,
+			completion_tokens INTEGER DEFAULT 0,
+			cost_usd REAL DEFAULT 0,
+			has_baseline BOOLEAN DEFAULT 0,
+			agreement REAL DEFAULT 0,
+			actionable_count INTEGER DEFAULT 0,
+			hit_count INTEGER DEFAULT 0,
+			forward_return_pct REAL DEFAULT 0,
+			decision_json TEXT DEFAULT '',
+			error TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (evaluation_id) REFERENCES model_evaluations(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS model_evaluation_scores (
+			evaluation_id TEXT NOT NULL,
+			model_id TEXT NOT NULL,
+			samples INTEGER DEFAULT 0,
+			parse_rate REAL DEFAULT 0,
+			valid_rate REAL DEFAULT 0,
+			avg_latency_ms REAL DEFAULT 0,
+			total_cost_usd REAL DEFAULT 0,
+			avg_cost_usd REAL DEFAULT 0,
+			agreement_rate REAL DEFAULT 0,
+			actionable INTEGER DEFAULT 0,
+			hit_rate REAL DEFAULT 0,
+			avg_forward_return_pct REAL DEFAULT 0,
+			score REAL DEFAULT 0,
+			rank INTEGER DEFAULT 0,
+			PRIMARY KEY (evaluation_id, model_id),
+			FOREIGN KEY (evaluation_id) REFERENCES model_evaluations(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_model_evaluations_user ON model_evaluations(user_id, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_model_evaluation_samples_eval ON model_evaluation_samples(evaluation_id, model_id)`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create 创建评估任务
+func (s *EvaluationStore) Create(eval *ModelEvaluation) error {
+	modelIDsJSON, _ := json.Marshal(eval.ModelIDs)
+	decisionIDs := eval.DecisionIDs
+	if decisionIDs == nil {
+		decisionIDs = []int64{}
+	}
+	decisionIDsJSON, _ := json.Marshal(decisionIDs)
+	if eval.Status == "" {
+		eval.Status = EvaluationStatusPending
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO model_evaluations (id, user_id, name, trader_id, model_ids, decision_ids,
+			from_time, to_time, sample_limit, horizon_minutes, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, eval.ID, eval.UserID, eval.Name, eval.TraderID, string(modelIDsJSON), string(decisionIDsJSON),
+		formatOptionalTime(eval.From), formatOptionalTime(eval.To), eval.SampleLimit, eval.HorizonMinutes, eval.Status)
+	if err != nil {
+		return fmt.Errorf("创建评估任务失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateProgress 更新评估状态和进度
+func (s *EvaluationStore) UpdateProgress(id, status string, progress, total int, errMsg string) error {
+	_, err := s.db.Exec(`
+		UPDATE model_evaluations SET status = ?, progress = ?, total = ?, error = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, status, progress, total, errMsg, id)
+	return err
+}
+
+// MarkInterrupted 将进程退出前未完成的评估标记为失败（启动时调用）
+func (s *EvaluationStore) MarkInterrupted() error {
+	_, err := s.db.Exec(`
+		UPDATE model_evaluations SET status = ?, error = '服务重启，评估中断', updated_at = CURRENT_TIMESTAMP
+		WHERE status IN (?, ?)
+	`, EvaluationStatusFailed, EvaluationStatusPending, EvaluationStatusRunning)
+	return err
+}
+
+// Get 获取评估任务
+func (s *EvaluationStore) Get(userID, id string) (*ModelEvaluation, error) {
+	rows, err := s.db.Query(`SELECT `+evaluationColumns+` FROM model_evaluations WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+	return scanEvaluation(rows)
+}
+
+// List 获取用户的评估任务列表
+func (s *EvaluationStore) List(userID string) ([]*ModelEvaluation, error) {
+	rows, err := s.db.Query(`SELECT `+evaluationColumns+` FROM model_evaluations WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var evals []*ModelEvaluation
+	for rows.Next() {
+		eval, err := scanEvaluation(rows)
+		if err != nil {
+			return nil, err
+		}
+		evals = append(evals, eval)
+	}
+	return evals, nil
+}
+
+// Delete 删除评估任务及其样本和得分
+func (s *EvaluationStore) Delete(userID, id string) error {
+	result, err := s.db.Exec(`DELETE FROM model_evaluations WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("评估任务不存在")
+	}
+	s.db.Exec(`DELETE FROM model_evaluation_samples WHERE evaluation_id = ?`, id)
+	s.db.Exec(`DELETE FROM model_evaluation_scores WHERE evaluation_id = ?`, id)
+	return nil
+}
+
+// AddSample 保存回放样本
+func (s *EvaluationStore) AddSample(sample *EvaluationSample) error {
+	result, err := s.db.Exec(`
+		INSERT INTO model_evaluation_samples (evaluation_id, model_id, decision_id, parsed, valid, latency_ms,
+			prompt_tokens, completion_tokens, cost_usd, has_baseline, agreement,
+			actionable_count, hit_count, forward_return_pct, decision_json, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, sample.EvaluationID, sample.ModelID, sample.DecisionID, sample.Parsed, sample.Valid, sample.LatencyMs,
+		sample.PromptTokens, sample.CompletionTokens, sample.CostUSD, sample.HasBaseline, sample.Agreement,
+		sample.ActionableCount, sample.HitCount, sample.ForwardReturnPct, sample.DecisionJSON, sample.Error)
+	if err != nil {
+		return fmt.Errorf("保存评估样本失败: %w", err)
+	}
+	sample.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// ListSamples 获取评估样本（modelID 为空时返回全部模型）
+func (s *EvaluationStore) ListSamples(evaluationID, modelID string) ([]*EvaluationSample, error) {
+	query := `
+		SELECT id, evaluation_id, model_id, decision_id, parsed, valid, latency_ms,
+			prompt_tokens, completion_tokens, cost_usd, has_baseline, agreement,
+			actionable_count, hit_count, forward_return_pct, decision_json, error, created_at
+		FROM model_evaluation_samples WHERE evaluation_id = ?`
+	args := []any{evaluationID}
+	if modelID != "" {
+		query += ` AND model_id = ?`
+		args = append(args, modelID)
+	}
+	query += ` ORDER BY decision_id ASC, model_id ASC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []*EvaluationSample
+	for rows.Next() {
+		var sample EvaluationSample
+		var createdAt string
+		err := rows.Scan(
+			&sample.ID, &sample.EvaluationID, &sample.ModelID, &sample.DecisionID, &sample.Parsed, &sample.Valid,
+			&sample.LatencyMs, &sample.PromptTokens, &sample.CompletionTokens, &sample.CostUSD,
+			&sample.HasBaseline, &sample.Agreement, &sample.ActionableCount, &sample.HitCount,
+			&sample.ForwardReturnPct, &sample.DecisionJSON, &sample.Error, &createdAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sample.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+		samples = append(samples, &sample)
+	}
+	return samples, nil
+}
+
+// ComputeScores 根据样本计算评估中各模型的得分并持久化
+func (s *EvaluationStore) ComputeScores(evaluationID string) ([]*EvaluationScore, error) {
+	scores, err := s.aggregateScores(`WHERE evaluation_id = ?`, evaluationID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM model_evaluation_scores WHERE evaluation_id = ?`, evaluationID); err != nil {
+		return nil, err
+	}
+	for _, score := range scores {
+		_, err := tx.Exec(`
+			INSERT INTO model_evaluation_scores (evaluation_id, model_id, samples, parse_rate, valid_rate,
+				avg_latency_ms, total_cost_usd, avg_cost_usd, agreement_rate, actionable, hit_rate,
+				avg_forward_return_pct, score, rank)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, evaluationID, score.ModelID, score.Samples, score.ParseRate, score.ValidRate,
+			score.AvgLatencyMs, score.TotalCostUSD, score.AvgCostUSD, score.AgreementRate, score.Actionable,
+			score.HitRate, score.AvgForwardReturnPct, score.Score, score.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("保存评估得分失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return scores, nil
+}
+
+// GetScores 获取评估中各模型的得分（按排名）
+func (s *EvaluationStore) GetScores(evaluationID string) ([]*EvaluationScore, error) {
+	rows, err := s.db.Query(`
+		SELECT model_id, samples, parse_rate, valid_rate, avg_latency_ms, total_cost_usd, avg_cost_usd,
+			agreement_rate, actionable, hit_rate, avg_forward_return_pct, score, rank
+		FROM model_evaluation_scores WHERE evaluation_id = ? ORDER BY rank ASC
+	`, evaluationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []*EvaluationScore
+	for rows.Next() {
+		var score EvaluationScore
+		err := rows.Scan(&score.ModelID, &score.Samples, &score.ParseRate, &score.ValidRate, &score.AvgLatencyMs,
+			&score.TotalCostUSD, &score.AvgCostUSD, &score.AgreementRate, &score.Actionable, &score.HitRate,
+			&score.AvgForwardReturnPct, &score.Score, &score.Rank)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, &score)
+	}
+	return scores, nil
+}
+
+// GetLeaderboard 汇总用户所有已完成评估的样本，生成模型排行榜
+func (s *EvaluationStore) GetLeaderboard(userID string) ([]*EvaluationScore, error) {
+	return s.aggregateScores(`
+		WHERE evaluation_id IN (SELECT id FROM model_evaluations WHERE user_id = ? AND status = ?)`,
+		userID, EvaluationStatusCompleted)
+}
+
+// aggregateScores 按模型汇总样本并计算综合得分和排名
+func (s *EvaluationStore) aggregateScores(where string, args ...any) ([]*EvaluationScore, error) {
+	rows, err := s.db.Query(`
+		SELECT model_id, COUNT(*),
+			AVG(parsed) * 100, AVG(valid) * 100, COALESCE(AVG(CASE WHEN parsed THEN latency_ms END), 0), SUM(cost_usd),
+			COALESCE(SUM(CASE WHEN has_baseline THEN agreement END) * 100.0 / NULLIF(SUM(has_baseline), 0), 0),
+			SUM(actionable_count),
+			COALESCE(SUM(hit_count) * 100.0 / NULLIF(SUM(actionable_count), 0), 0),
+			COALESCE(SUM(forward_return_pct * actionable_count) / NULLIF(SUM(actionable_count), 0), 0)
+		FROM model_evaluation_samples
+		`+where+`
+		GROUP BY model_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("汇总评估样本失败: %w", err)
+	}
+	defer rows.Close()
+
+	var scores []*EvaluationScore
+	for rows.Next() {
+		var score EvaluationScore
+		err := rows.Scan(&score.ModelID, &score.Samples, &score.ParseRate, &score.ValidRate, &score.AvgLatencyMs,
+			&score.TotalCostUSD, &score.AgreementRate, &score.Actionable, &score.HitRate, &score.AvgForwardReturnPct)
+		if err != nil {
+			return nil, err
+		}
+		if score.Samples > 0 {
+			score.AvgCostUSD = score.TotalCostUSD / float64(score.Samples)
+		}
+		scores = append(scores, &score)
+	}
+	rankEvaluationScores(scores)
+	return scores, nil
+}
+
+// rankEvaluationScores 计算综合得分并排名（得分相同时成本低者优先）
+// 综合得分 = 30% 验证通过率 + 15% 与原决策一致率 + 25% 前瞻方向命中率
+// + 15% 前瞻收益 + 7.5% 响应耗时 + 7.5% 成本（后三项为模型间的相对得分）
+// 没有解析成功样本的模型没有有效耗时，耗时得分为 0 且不参与其他模型的耗时换算
+func rankEvaluationScores(scores []*EvaluationScore) {
+	returns := make([]float64, len(scores))
+	costs := make([]float64, len(scores))
+	var latencies []float64
+	for i, score := range scores {
+		returns[i] = score.AvgForwardReturnPct
+		costs[i] = score.AvgCostUSD
+		if score.ParseRate > 0 {
+			latencies = append(latencies, score.AvgLatencyMs)
+		}
+	}
+	returnScores := relativeScores(returns, true)
+	costScores := relativeScores(costs, false)
+	latencyScores := make([]float64, len(scores))
+	measured := relativeScores(latencies, false)
+	for i, score := range scores {
+		if score.ParseRate > 0 {
+			latencyScores[i], measured = measured[0], measured[1:]
+		}
+	}
+
+	for i, score := range scores {
+		score.Score = evaluationWeightValid*score.ValidRate +
+			evaluationWeightAgreement*score.AgreementRate +
+			evaluationWeightHit*score.HitRate +
+			evaluationWeightReturn*returnScores[i] +
+			evaluationWeightLatency*latencyScores[i] +
+			evaluationWeightCost*costScores[i]
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].AvgCostUSD < scores[j].AvgCostUSD
+	})
+	for i, score := range scores {
+		score.Rank = i + 1
+	}
+}
+
+// relativeScores 将指标按最小值-最大值线性换算为 0-100（最好为 100）；所有值相同时均为 100
+func relativeScores(values []float64, higherBetter bool) []float64 {
+	result := make([]float64, len(values))
+	if len(values) == 0 {
+		return result
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	for i, v := range values {
+		switch {
+		case hi == lo:
+			result[i] = 100
+		case higherBetter:
+			result[i] = (v - lo) / (hi - lo) * 100
+		default:
+			result[i] = (hi - v) / (hi - lo) * 100
+		}
+	}
+	return result
+}
+
+const evaluationColumns = `id, user_id, name, trader_id, model_ids, decision_ids, from_time, to_time,
+	sample_limit, horizon_minutes, status, progress, total, error, created_at, updated_at`
+
+func scanEvaluation(rows *sql.Rows) (*ModelEvaluation, error) {
+	var eval ModelEvaluation
+	var modelIDsJSON, decisionIDsJSON, fromTime, toTime, createdAt, updatedAt string
+	err := rows.Scan(
+		&eval.ID, &eval.UserID, &eval.Name, &eval.TraderID, &modelIDsJSON, &decisionIDsJSON, &fromTime, &toTime,
+		&eval.SampleLimit, &eval.HorizonMinutes, &eval.Status, &eval.Progress, &eval.Total, &eval.Error,
+		&createdAt, &updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(modelIDsJSON), &eval.ModelIDs)
+	json.Unmarshal([]byte(decisionIDsJSON), &eval.DecisionIDs)
+	eval.From = parseOptionalTime(fromTime)
+	eval.To = parseOptionalTime(toTime)
+	eval.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+	eval.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+	return &eval, nil
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseOptionalTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"math"
+	"testing"
+)
+
+// TestRankEvaluationScores 测试综合得分包含前瞻收益、耗时和成本
+func TestRankEvaluationScores(t *testing.T) {
+	// 三个模型的有效率、一致率和命中率相同，只有收益、耗时和成本不同
+	scores := []*EvaluationScore{
+		{ModelID: "slow", ParseRate: 100, ValidRate: 100, AgreementRate: 50, HitRate: 60, AvgForwardReturnPct: 0.5, AvgLatencyMs: 9000, AvgCostUSD: 0.01},
+		{ModelID: "fast", ParseRate: 100, ValidRate: 100, AgreementRate: 50, HitRate: 60, AvgForwardReturnPct: 0.5, AvgLatencyMs: 1000, AvgCostUSD: 0.01},
+		{ModelID: "loser", ParseRate: 100, ValidRate: 100, AgreementRate: 50, HitRate: 60, AvgForwardReturnPct: -1.0, AvgLatencyMs: 5000, AvgCostUSD: 0.002},
+	}
+	rankEvaluationScores(scores)
+
+	order := []string{scores[0].ModelID, scores[1].ModelID, scores[2].ModelID}
+	if order[0] != "fast" || order[1] != "slow" || order[2] != "loser" {
+		t.Fatalf("rank order = %v", order)
+	}
+	// fast: 30 + 7.5 + 15 + 15(收益最好) + 7.5(耗时最好) + 0(成本最高)
+	if math.Abs(scores[0].Score-75) > 1e-9 || scores[0].Rank != 1 {
+		t.Errorf("fast score = %.4f rank %d", scores[0].Score, scores[0].Rank)
+	}
+	// slow 只比 fast 少耗时得分
+	if math.Abs(scores[0].Score-scores[1].Score-evaluationWeightLatency*100) > 1e-9 {
+		t.Errorf("slow score = %.4f", scores[1].Score)
+	}
+
+	// 所有模型指标相同时相对得分均为满分
+	single := []*EvaluationScore{{ModelID: "only", ParseRate: 100, ValidRate: 100, AgreementRate: 100, HitRate: 100, AvgLatencyMs: 500}}
+	rankEvaluationScores(single)
+	if math.Abs(single[0].Score-100) > 1e-9 {
+		t.Errorf("single score = %.4f, want 100", single[0].Score)
+	}
+}
+
+// TestComputeScoresLatency 测试平均耗时只统计解析成功的样本，快速失败的模型不占耗时优势
+func TestComputeScoresLatency(t *testing.T) {
+	evaluations := newTestStore(t).Evaluation()
+	if err := evaluations.Create(&ModelEvaluation{ID: "eval-1", UserID: "u1", Name: "latency", TraderID: "t1",
+		ModelIDs: []string{"steady", "flaky", "broken"}}); err != nil {
+		t.Fatal(err)
+	}
+	samples := []*EvaluationSample{
+		{ModelID: "steady", Parsed: true, Valid: true, LatencyMs: 4000},
+		{ModelID: "steady", Parsed: true, Valid: true, LatencyMs: 6000},
+		{ModelID: "flaky", Parsed: true, Valid: true, LatencyMs: 8000},
+		{ModelID: "flaky", LatencyMs: 100, Error: "timeout"},
+		{ModelID: "broken", LatencyMs: 50, Error: "invalid json"},
+	}
+	for i, sample := range samples {
+		sample.EvaluationID = "eval-1"
+		sample.DecisionID = int64(i + 1)
+		if err := evaluations.AddSample(sample); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scores, err := evaluations.ComputeScores("eval-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byModel := map[string]*EvaluationScore{}
+	for _, score := range scores {
+		byModel[score.ModelID] = score
+	}
+	if byModel["steady"].AvgLatencyMs != 5000 || byModel["flaky"].AvgLatencyMs != 8000 || byModel["broken"].AvgLatencyMs != 0 {
+		t.Errorf("latency: steady=%v flaky=%v broken=%v",
+			byModel["steady"].AvgLatencyMs, byModel["flaky"].AvgLatencyMs, byModel["broken"].AvgLatencyMs)
+	}
+	// broken 没有成功样本，耗时得分为 0（只有三个模型相同的收益和成本得分）
+	if got, want := byModel["broken"].Score, (evaluationWeightReturn+evaluationWeightCost)*100; math.Abs(got-want) > 1e-9 {
+		t.Errorf("broken score = %.4f, want %.4f", got, want)
+	}
+	if scores[0].ModelID != "steady" || scores[len(scores)-1].ModelID != "broken" {
+		t.Errorf("rank order: %s ... %s", scores[0].ModelID, scores[len(scores)-1].ModelID)
+	}
+}
@@ -0,0 +1,567 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// 实验分配方式
+const (
+	ExperimentAssignRoundRobin = "round_robin" // 轮询
+	ExperimentAssignRandom     = "random"      // 带种子的随机（可复现）
+)
+
+// 实验分配粒度
+const (
+	ExperimentScopeCycle  = "cycle"  // 每个决策周期重新分配
+	ExperimentScopeTrader = "trader" // 每个交易员固定一个变体
+)
+
+// 实验状态
+const (
+	ExperimentStatusRunning = "running"
+	ExperimentStatusPaused  = "paused"
+	ExperimentStatusStopped = "stopped"
+)
+
+// PromptVariants 支持的交易模式变体（与策略引擎中的模式说明对应）
+var PromptVariants = []string{"balanced", "aggressive", "conservative", "scalping"}
+
+// ExperimentStore Prompt A/B 实验存储
+type ExperimentStore struct {
+	db *sql.DB
+}
+
+// Experiment Prompt A/B 实验
+type Experiment struct {
+	ID             string              `json:"id"`
+	UserID         string              `json:"user_id"`
+	Name           string              `json:"name"`
+	Description    string              `json:"description"`
+	Status         string              `json:"status"`          // running/paused/stopped
+	Scope          string              `json:"scope"`           // cycle/trader
+	AssignmentMode string              `json:"assignment_mode"` // round_robin/random
+	Seed           int64               `json:"seed"`            // 随机分配种子
+	TraderIDs      []string            `json:"trader_ids"`      // 参与的交易员（为空表示该用户全部交易员）
+	Variants       []ExperimentVariant `json:"variants"`        // 第一个变体为对照组
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// ExperimentVariant 实验变体
+type ExperimentVariant struct {
+	// 变体名称（写入 DecisionRecord.PromptVariant）
+	Name string `json:"name"`
+	// 交易模式变体: balanced/aggressive/conservative/scalping
+	PromptVariant string `json:"prompt_variant,omitempty"`
+	// 覆盖策略中的 System Prompt 可编辑部分（为空的字段沿用策略配置）
+	PromptSections *PromptSectionsConfig `json:"prompt_sections,omitempty"`
+	// 随机分配权重（默认1）
+	Weight int `json:"weight,omitempty"`
+}
+
+// ExperimentStats 实验统计
+type ExperimentStats struct {
+	ExperimentID   string                   `json:"experiment_id"`
+	ControlVariant string                   `json:"control_variant"`
+	Variants       []ExperimentVariantStats `json:"variants"`
+}
+
+// ExperimentVariantStats 单个变体的统计
+type ExperimentVariantStats struct {
+	Variant      string  `json:"variant"`
+	Cycles       int     `json:"cycles"`        // 决策周期数
+	ValidCycles  int     `json:"valid_cycles"`  // 决策有效（解析并校验通过）的周期数
+	ValidityRate float64 `json:"validity_rate"` // 决策有效率 (%)
+	Trades       int     `json:"trades"`        // 已平仓交易数
+	WinTrades    int     `json:"win_trades"`    // 盈利交易数
+	WinRate      float64 `json:"win_rate"`      // 胜率 (%)
+	TotalPnL     float64 `json:"total_pnl"`     // 总盈亏
+	PnLPerTrade  float64 `json:"pnl_per_trade"` // 平均每笔盈亏
+	PnLStdDev    float64 `json:"pnl_std_dev"`   // 每笔盈亏标准差
+	// 与对照组的显著性比较（对照组本身为 nil）
+	Significance *VariantSignificance `json:"significance,omitempty"`
+
+	pnls []float64
+}
+
+// VariantSignificance 与对照组相比的显著性估计（双侧检验）
+type VariantSignificance struct {
+	WinRateZ       float64 `json:"win_rate_z"`
+	WinRatePValue  float64 `json:"win_rate_p_value"`
+	ValidityZ      float64 `json:"validity_z"`
+	ValidityPValue float64 `json:"validity_p_value"`
+	PnLZ           float64 `json:"pnl_z"`
+	PnLPValue      float64 `json:"pnl_p_value"`
+	Significant    bool    `json:"significant"` // 任一指标 p < 0.05
+}
+
+func (s *ExperimentStore) initTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS prompt_experiments (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL,
+			description TEXT DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'running',
+			scope TEXT NOT NULL DEFAULT 'cycle',
+			assignment_mode TEXT NOT NULL DEFAULT 'round_robin',
+			seed INTEGER DEFAULT 0,
+			trader_ids TEXT NOT NULL DEFAULT '[]',
+			variants TEXT NOT NULL DEFAULT '[]',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, _ = s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_prompt_experiments_user ON prompt_experiments(user_id, status)`)
+
+	// 触发器：更新时自动更新 updated_at
+	_, err = s.db.Exec(`
+		CREATE TRIGGER IF NOT EXISTS update_prompt_experiments_updated_at
+		AFTER UPDATE ON prompt_experiments
+		BEGIN
+			UPDATE prompt_experiments SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+		END
+	`)
+	return err
+}
+
+// Validate 校验实验配置并补全默认值
+func (e *Experiment) Validate() error {
+	if strings.TrimSpace(e.Name) == "" {
+		return fmt.Errorf("实验名称不能为空")
+	}
+	if len(e.Variants) < 2 {
+		return fmt.Errorf("实验至少需要2个变体")
+	}
+	seen := make(map[string]bool, len(e.Variants))
+	for i := range e.Variants {
+		v := &e.Variants[i]
+		v.Name = strings.TrimSpace(v.Name)
+		if v.Name == "" {
+			return fmt.Errorf("第%d个变体名称不能为空", i+1)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("变体名称重复: %s", v.Name)
+		}
+		seen[v.Name] = true
+		v.PromptVariant = strings.ToLower(strings.TrimSpace(v.PromptVariant))
+		if v.PromptVariant != "" && !isKnownPromptVariant(v.PromptVariant) {
+			return fmt.Errorf("变体 %s 的交易模式不支持: %s（可选: %s）", v.Name, v.PromptVariant, strings.Join(PromptVariants, "/"))
+		}
+		if v.Weight < 0 {
+			return fmt.Errorf("变体 %s 权重不能为负数", v.Name)
+		}
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+	}
+
+	if e.Status == "" {
+		e.Status = ExperimentStatusRunning
+	}
+	switch e.Status {
+	case ExperimentStatusRunning, ExperimentStatusPaused, ExperimentStatusStopped:
+	default:
+		return fmt.Errorf("不支持的实验状态: %s", e.Status)
+	}
+
+	if e.Scope == "" {
+		e.Scope = ExperimentScopeCycle
+	}
+	if e.Scope != ExperimentScopeCycle && e.Scope != ExperimentScopeTrader {
+		return fmt.Errorf("不支持的分配粒度: %s", e.Scope)
+	}
+
+	if e.AssignmentMode == "" {
+		e.AssignmentMode = ExperimentAssignRoundRobin
+	}
+	if e.AssignmentMode != ExperimentAssignRoundRobin && e.AssignmentMode != ExperimentAssignRandom {
+		return fmt.Errorf("不支持的分配方式: %s", e.AssignmentMode)
+	}
+	return nil
+}
+
+func isKnownPromptVariant(variant string) bool {
+	for _, v := range PromptVariants {
+		if v == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// IncludesTrader 判断交易员是否参与该实验
+func (e *Experiment) IncludesTrader(traderID string) bool {
+	if len(e.TraderIDs) == 0 {
+		return true
+	}
+	return e.traderIndex(traderID) >= 0
+}
+
+// AssignVariant 为指定交易员的指定周期分配变体
+// 同样的实验配置、交易员和周期编号总是得到同样的结果，便于复现
+func (e *Experiment) AssignVariant(traderID string, cycleNumber int) *ExperimentVariant {
+	n := len(e.Variants)
+	if n == 0 {
+		return nil
+	}
+
+	// 交易员序号：优先使用在实验中的位置，否则使用哈希
+	traderIdx := e.traderIndex(traderID)
+	if traderIdx < 0 {
+		traderIdx = int(hashKey(traderID) % uint64(n))
+	}
+
+	if e.AssignmentMode == ExperimentAssignRandom {
+		key := fmt.Sprintf("%d|%s", e.Seed, traderID)
+		if e.Scope == ExperimentScopeCycle {
+			key = fmt.Sprintf("%s|%d", key, cycleNumber)
+		}
+		r := rand.New(rand.NewSource(int64(hashKey(key)))).Float64()
+		return e.pickWeighted(r)
+	}
+
+	// 轮询：按周期轮换时叠加交易员偏移，保证同一时刻不同交易员覆盖不同变体
+	idx := traderIdx
+	if e.Scope == ExperimentScopeCycle {
+		idx = cycleNumber + traderIdx
+	}
+	return &e.Variants[((idx%n)+n)%n]
+}
+
+func (e *Experiment) traderIndex(traderID string) int {
+	for i, id := range e.TraderIDs {
+		if id == traderID {
+			return i
+		}
+	}
+	return -1
+}
+
+// pickWeighted 按权重选择变体，r ∈ [0, 1)
+func (e *Experiment) pickWeighted(r float64) *ExperimentVariant {
+	total := 0
+	for _, v := range e.Variants {
+		total += max(v.Weight, 1)
+	}
+	target := r * float64(total)
+	acc := 0.0
+	for i := range e.Variants {
+		acc += float64(max(e.Variants[i].Weight, 1))
+		if target < acc {
+			return &e.Variants[i]
+		}
+	}
+	return &e.Variants[len(e.Variants)-1]
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// Create 创建实验
+func (s *ExperimentStore) Create(exp *Experiment) error {
+	traderIDsJSON, variantsJSON, err := marshalExperiment(exp)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO prompt_experiments (id, user_id, name, description, status, scope, assignment_mode, seed, trader_ids, variants)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, exp.ID, exp.UserID, exp.Name, exp.Description, exp.Status, exp.Scope, exp.AssignmentMode,
+		exp.Seed, traderIDsJSON, variantsJSON)
+	if err != nil {
+		return fmt.Errorf("创建实验失败: %w", err)
+	}
+	return nil
+}
+
+// Update 更新实验
+func (s *ExperimentStore) Update(exp *Experiment) error {
+	traderIDsJSON, variantsJSON, err := marshalExperiment(exp)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		UPDATE prompt_experiments SET
+			name = ?, description = ?, status = ?, scope = ?, assignment_mode = ?,
+			seed = ?, trader_ids = ?, variants = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?
+	`, exp.Name, exp.Description, exp.Status, exp.Scope, exp.AssignmentMode,
+		exp.Seed, traderIDsJSON, variantsJSON, exp.ID, exp.UserID)
+	if err != nil {
+		return fmt.Errorf("更新实验失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除实验（已记录的决策标签保留）
+func (s *ExperimentStore) Delete(userID, id string) error {
+	_, err := s.db.Exec(`DELETE FROM prompt_experiments WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+// List 获取用户的实验列表
+func (s *ExperimentStore) List(userID string) ([]*Experiment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, description, status, scope, assignment_mode, seed,
+			trader_ids, variants, created_at, updated_at
+		FROM prompt_experiments
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var experiments []*Experiment
+	for rows.Next() {
+		exp, err := scanExperiment(rows)
+		if err != nil {
+			return nil, err
+		}
+		experiments = append(experiments, exp)
+	}
+	return experiments, nil
+}
+
+// Get 获取单个实验
+func (s *ExperimentStore) Get(userID, id string) (*Experiment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, description, status, scope, assignment_mode, seed,
+			trader_ids, variants, created_at, updated_at
+		FROM prompt_experiments
+		WHERE id = ? AND user_id = ?
+	`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+	return scanExperiment(rows)
+}
+
+// GetRunningForTrader 获取交易员正在参与的实验（没有时返回 nil, nil）
+// 同时参与多个实验时取最早创建的一个，避免变体互相干扰
+func (s *ExperimentStore) GetRunningForTrader(userID, traderID string) (*Experiment, error) {
+	experiments, err := s.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	var found *Experiment
+	for _, exp := range experiments {
+		if exp.Status == ExperimentStatusRunning && exp.IncludesTrader(traderID) {
+			found = exp // List 按创建时间倒序，最后一个匹配即最早创建
+		}
+	}
+	return found, nil
+}
+
+// GetStats 统计实验中各变体的表现
+// 交易按开仓订单ID关联到产生该订单的决策周期，从而归属到对应变体
+func (s *ExperimentStore) GetStats(exp *Experiment) (*ExperimentStats, error) {
+	statsMap := make(map[string]*ExperimentVariantStats, len(exp.Variants))
+	result := &ExperimentStats{ExperimentID: exp.ID}
+	for _, v := range exp.Variants {
+		statsMap[v.Name] = &ExperimentVariantStats{Variant: v.Name}
+	}
+	if len(exp.Variants) > 0 {
+		result.ControlVariant = exp.Variants[0].Name
+	}
+
+	// 决策有效率
+	rows, err := s.db.Query(`
+		SELECT prompt_variant, COUNT(*), SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END)
+		FROM decision_records
+		WHERE experiment_id = ?
+		GROUP BY prompt_variant
+	`, exp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询实验决策记录失败: %w", err)
+	}
+	for rows.Next() {
+		var variant string
+		var cycles, valid int
+		if err := rows.Scan(&variant, &cycles, &valid); err != nil {
+			continue
+		}
+		st := getOrAddVariantStats(statsMap, variant)
+		st.Cycles = cycles
+		st.ValidCycles = valid
+	}
+	rows.Close()
+
+	// 已平仓交易
+	rows, err = s.db.Query(`
+		SELECT r.prompt_variant, p.realized_pnl
+		FROM trader_positions p
+		JOIN decision_actions a ON a.trader_id = p.trader_id
+			AND a.action IN ('open_long', 'open_short')
+			AND a.order_id != 0
+			AND CAST(a.order_id AS TEXT) = p.entry_order_id
+		JOIN decision_records r ON r.id = a.decision_id
+		WHERE r.experiment_id = ? AND p.status = 'CLOSED'
+	`, exp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询实验交易记录失败: %w", err)
+	}
+	for rows.Next() {
+		var variant string
+		var pnl float64
+		if err := rows.Scan(&variant, &pnl); err != nil {
+			continue
+		}
+		st := getOrAddVariantStats(statsMap, variant)
+		st.pnls = append(st.pnls, pnl)
+	}
+	rows.Close()
+
+	// 按实验定义顺序输出，其余（已删除的变体）追加在后
+	for _, v := range exp.Variants {
+		result.Variants = append(result.Variants, *statsMap[v.Name])
+		delete(statsMap, v.Name)
+	}
+	for _, st := range statsMap {
+		result.Variants = append(result.Variants, *st)
+	}
+
+	for i := range result.Variants {
+		result.Variants[i].finalize()
+	}
+	if len(result.Variants) > 0 {
+		control := &result.Variants[0]
+		for i := 1; i < len(result.Variants); i++ {
+			result.Variants[i].Significance = compareVariantStats(control, &result.Variants[i])
+		}
+	}
+
+	return result, nil
+}
+
+func getOrAddVariantStats(m map[string]*ExperimentVariantStats, variant string) *ExperimentVariantStats {
+	st, ok := m[variant]
+	if !ok {
+		st = &ExperimentVariantStats{Variant: variant}
+		m[variant] = st
+	}
+	return st
+}
+
+// finalize 根据原始数据计算比率和均值
+func (st *ExperimentVariantStats) finalize() {
+	if st.Cycles > 0 {
+		st.ValidityRate = float64(st.ValidCycles) / float64(st.Cycles) * 100
+	}
+	st.Trades = len(st.pnls)
+	if st.Trades == 0 {
+		return
+	}
+	for _, pnl := range st.pnls {
+		st.TotalPnL += pnl
+		if pnl > 0 {
+			st.WinTrades++
+		}
+	}
+	st.WinRate = float64(st.WinTrades) / float64(st.Trades) * 100
+	st.PnLPerTrade = st.TotalPnL / float64(st.Trades)
+	if st.Trades > 1 {
+		var sumSq float64
+		for _, pnl := range st.pnls {
+			sumSq += (pnl - st.PnLPerTrade) * (pnl - st.PnLPerTrade)
+		}
+		st.PnLStdDev = math.Sqrt(sumSq / float64(st.Trades-1))
+	}
+}
+
+// compareVariantStats 与对照组比较：比例使用双比例 z 检验，均值使用 Welch 检验（正态近似）
+func compareVariantStats(control, variant *ExperimentVariantStats) *VariantSignificance {
+	sig := &VariantSignificance{}
+	sig.WinRateZ, sig.WinRatePValue = twoProportionZTest(control.WinTrades, control.Trades, variant.WinTrades, variant.Trades)
+	sig.ValidityZ, sig.ValidityPValue = twoProportionZTest(control.ValidCycles, control.Cycles, variant.ValidCycles, variant.Cycles)
+	sig.PnLZ, sig.PnLPValue = welchZTest(control, variant)
+	sig.Significant = (sig.WinRatePValue < 0.05 || sig.ValidityPValue < 0.05 || sig.PnLPValue < 0.05)
+	return sig
+}
+
+// twoProportionZTest 双比例 z 检验，样本不足时返回 p=1
+func twoProportionZTest(x1, n1, x2, n2 int) (float64, float64) {
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+	p1 := float64(x1) / float64(n1)
+	p2 := float64(x2) / float64(n2)
+	pooled := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 0, 1
+	}
+	z := (p2 - p1) / se
+	return z, twoSidedPValue(z)
+}
+
+// welchZTest 比较每笔平均盈亏，样本不足时返回 p=1
+func welchZTest(a, b *ExperimentVariantStats) (float64, float64) {
+	if a.Trades < 2 || b.Trades < 2 {
+		return 0, 1
+	}
+	se := math.Sqrt(a.PnLStdDev*a.PnLStdDev/float64(a.Trades) + b.PnLStdDev*b.PnLStdDev/float64(b.Trades))
+	if se == 0 {
+		return 0, 1
+	}
+	z := (b.PnLPerTrade - a.PnLPerTrade) / se
+	return z, twoSidedPValue(z)
+}
+
+func twoSidedPValue(z float64) float64 {
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+func marshalExperiment(exp *Experiment) (string, string, error) {
+	traderIDs := exp.TraderIDs
+	if traderIDs == nil {
+		traderIDs = []string{}
+	}
+	traderIDsJSON, err := json.Marshal(traderIDs)
+	if err != nil {
+		return "", "", fmt.Errorf("序列化交易员列表失败: %w", err)
+	}
+	variantsJSON, err := json.Marshal(exp.Variants)
+	if err != nil {
+		return "", "", fmt.Errorf("序列化实验变体失败: %w", err)
+	}
+	return string(traderIDsJSON), string(variantsJSON), nil
+}
+
+func scanExperiment(rows *sql.Rows) (*Experiment, error) {
+	var exp Experiment
+	var traderIDsJSON, variantsJSON, createdAt, updatedAt string
+	err := rows.Scan(
+		&exp.ID, &exp.UserID, &exp.Name, &exp.Description, &exp.Status, &exp.Scope,
+		&exp.AssignmentMode, &exp.Seed, &traderIDsJSON, &variantsJSON, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(traderIDsJSON), &exp.TraderIDs)
+	json.Unmarshal([]byte(variantsJSON), &exp.Variants)
+	exp.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+	exp.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+	return &exp, nil
+}
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestExperimentValidate 测试实验配置校验（交易模式变体必须是已知变体）
+func TestExperimentValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant string
+		wantErr string
+	}{
+		{"未指定交易模式", "", ""},
+		{"已知交易模式", " Aggressive ", ""},
+		{"未知交易模式", "yolo", "交易模式不支持: yolo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := &Experiment{Name: "exp", Variants: []ExperimentVariant{
+				{Name: "control"},
+				{Name: "treatment", PromptVariant: tt.variant},
+			}}
+			err := exp.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := exp.Variants[1].PromptVariant; got != strings.ToLower(strings.TrimSpace(tt.variant)) {
+					t.Errorf("prompt variant = %q", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestAssignVariant 测试变体分配的确定性和权重分布
+func TestAssignVariant(t *testing.T) {
+	variants := []ExperimentVariant{{Name: "A", Weight: 3}, {Name: "B", Weight: 1}}
+
+	tests := []struct {
+		name string
+		exp  Experiment
+		// 校验函数：返回错误描述，空表示通过
+		check func(exp *Experiment) string
+	}{
+		{
+			name: "轮询按周期轮换并叠加交易员偏移",
+			exp:  Experiment{Scope: ExperimentScopeCycle, AssignmentMode: ExperimentAssignRoundRobin, TraderIDs: []string{"t1", "t2"}, Variants: variants},
+			check: func(exp *Experiment) string {
+				for cycle := 0; cycle < 4; cycle++ {
+					a, b := exp.AssignVariant("t1", cycle), exp.AssignVariant("t2", cycle)
+					if a.Name == b.Name {
+						return "同一周期的两个交易员应分配到不同变体"
+					}
+					if next := exp.AssignVariant("t1", cycle+1); next.Name == a.Name {
+						return "相邻周期应轮换变体"
+					}
+				}
+				return ""
+			},
+		},
+		{
+			name: "按交易员固定变体",
+			exp:  Experiment{Scope: ExperimentScopeTrader, AssignmentMode: ExperimentAssignRandom, Seed: 7, Variants: variants},
+			check: func(exp *Experiment) string {
+				first := exp.AssignVariant("trader-x", 1).Name
+				for cycle := 2; cycle < 50; cycle++ {
+					if exp.AssignVariant("trader-x", cycle).Name != first {
+						return "trader 粒度下同一交易员的变体不应变化"
+					}
+				}
+				return ""
+			},
+		},
+		{
+			name: "随机分配可复现且按权重分布",
+			exp:  Experiment{Scope: ExperimentScopeCycle, AssignmentMode: ExperimentAssignRandom, Seed: 42, Variants: variants},
+			check: func(exp *Experiment) string {
+				const n = 4000
+				countA := 0
+				for cycle := 0; cycle < n; cycle++ {
+					v := exp.AssignVariant("t1", cycle)
+					if again := exp.AssignVariant("t1", cycle); again.Name != v.Name {
+						return "相同输入应得到相同变体"
+					}
+					if v.Name == "A" {
+						countA++
+					}
+				}
+				if ratio := float64(countA) / n; math.Abs(ratio-0.75) > 0.03 {
+					return "A 的分配比例 " + strconv.FormatFloat(ratio, 'f', 3, 64) + " 偏离权重 0.75"
+				}
+				return ""
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if msg := tt.check(&tt.exp); msg != "" {
+				t.Error(msg)
+			}
+		})
+	}
+
+	if (&Experiment{}).AssignVariant("t1", 1) != nil {
+		t.Error("没有变体时应返回 nil")
+	}
+}
+
+// TestSignificanceTests 测试双比例 z 检验和 Welch 检验
+func TestSignificanceTests(t *testing.T) {
+	tests := []struct {
+		name           string
+		x1, n1, x2, n2 int
+		wantZ, wantP   float64
+	}{
+		{"30% vs 50%", 30, 100, 50, 100, 2.886751, 0.003892},
+		{"相同比例", 20, 40, 10, 20, 0, 1},
+		{"样本为空", 0, 0, 5, 10, 0, 1},
+		{"全部成功（标准误为0）", 10, 10, 10, 10, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z, p := twoProportionZTest(tt.x1, tt.n1, tt.x2, tt.n2)
+			if math.Abs(z-tt.wantZ) > 1e-4 || math.Abs(p-tt.wantP) > 1e-4 {
+				t.Errorf("z=%.6f p=%.6f, want z=%.6f p=%.6f", z, p, tt.wantZ, tt.wantP)
+			}
+		})
+	}
+
+	// 均值差 2，标准误 sqrt(4/10 + 4/10)
+	a := &ExperimentVariantStats{Trades: 10, PnLPerTrade: 1, PnLStdDev: 2}
+	b := &ExperimentVariantStats{Trades: 10, PnLPerTrade: 3, PnLStdDev: 2}
+	z, p := welchZTest(a, b)
+	if math.Abs(z-2/math.Sqrt(0.8)) > 1e-9 || math.Abs(p-0.025347) > 1e-4 {
+		t.Errorf("welch z=%.6f p=%.6f", z, p)
+	}
+	if _, p := welchZTest(&ExperimentVariantStats{Trades: 1}, b); p != 1 {
+		t.Errorf("样本不足时 p 应为 1, got %f", p)
+	}
+}
+
+// TestExperimentGetStats 测试按变体统计决策有效率和归属交易的盈亏
+func TestExperimentGetStats(t *testing.T) {
+	s := newTestStore(t)
+	exp := &Experiment{ID: "exp-1", UserID: "u1", Name: "exp", Variants: []ExperimentVariant{{Name: "control"}, {Name: "treatment"}}}
+	if err := exp.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Experiment().Create(exp); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每个变体 4 个周期；对照组 3 个有效、实验组 1 个有效，每个周期开一笔仓
+	orderID := int64(1000)
+	pnls := map[string][]float64{"control": {5, -1, 3, 1}, "treatment": {-2, -4, 1, -3}}
+	for _, variant := range []string{"control", "treatment"} {
+		for i, pnl := range pnls[variant] {
+			orderID++
+			record := &DecisionRecord{
+				TraderID:      "t1",
+				CycleNumber:   i + 1,
+				ExperimentID:  exp.ID,
+				PromptVariant: variant,
+				Success:       variant == "control" && i < 3 || variant == "treatment" && i == 0,
+				Decisions:     []DecisionAction{{Action: "open_long", Symbol: "BTCUSDT", OrderID: orderID, Success: true}},
+			}
+			if err := s.Decision().LogDecision(record); err != nil {
+				t.Fatal(err)
+			}
+			pos := &TraderPosition{TraderID: "t1", Symbol: "BTCUSDT", Side: "LONG", Quantity: 1, EntryPrice: 100, EntryOrderID: strconv.FormatInt(orderID, 10)}
+			if err := s.Position().Create(pos); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Position().ClosePosition(pos.ID, 101, "", pnl, 0, "ai_decision"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// 其他实验的记录不计入
+	if err := s.Decision().LogDecision(&DecisionRecord{TraderID: "t1", ExperimentID: "other", PromptVariant: "control", Success: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.Experiment().GetStats(exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.ControlVariant != "control" || len(stats.Variants) != 2 {
+		t.Fatalf("stats: %+v", stats)
+	}
+	control, treatment := stats.Variants[0], stats.Variants[1]
+	if control.Cycles != 4 || control.ValidCycles != 3 || control.ValidityRate != 75 {
+		t.Errorf("control cycles: %+v", control)
+	}
+	if control.Trades != 4 || control.WinTrades != 3 || control.TotalPnL != 8 || control.PnLPerTrade != 2 {
+		t.Errorf("control trades: %+v", control)
+	}
+	if treatment.Trades != 4 || treatment.WinTrades != 1 || treatment.TotalPnL != -8 || treatment.ValidityRate != 25 {
+		t.Errorf("treatment: %+v", treatment)
+	}
+	if control.Significance != nil || treatment.Significance == nil || treatment.Significance.PnLZ >= 0 {
+		t.Errorf("significance: control=%+v treatment=%+v", control.Significance, treatment.Significance)
+	}
+}
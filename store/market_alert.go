@@ -0,0 +1,106 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// MarketAlertStore 行情警报存储（全局行情数据，不区分用户）
+type MarketAlertStore struct {
+	db *sql.DB
+}
+
+// MarketAlert 行情警报引擎触发的警报
+type MarketAlert struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"` // volume_spike | price_change_15min | volume_trend | rsi_overbought | rsi_oversold
+	Symbol    string    `json:"symbol"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *MarketAlertStore) initTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS market_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			type TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			value REAL DEFAULT 0,
+			threshold REAL DEFAULT 0,
+			message TEXT DEFAULT '',
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_market_alerts_created ON market_alerts(created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_market_alerts_symbol ON market_alerts(symbol, created_at)`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create 保存警报
+func (s *MarketAlertStore) Create(alert *MarketAlert) error {
+	if alert.CreatedAt.IsZero() {
+		alert.CreatedAt = time.Now()
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO market_alerts (type, symbol, value, threshold, message, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		alert.Type, alert.Symbol, alert.Value, alert.Threshold, alert.Message,
+		alert.CreatedAt.UTC().Format(signalTimeLayout))
+	if err != nil {
+		return err
+	}
+	alert.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// List 获取 since 之后的警报，按时间倒序；symbols 为空时返回全部币种
+func (s *MarketAlertStore) List(symbols []string, since time.Time, limit int) ([]*MarketAlert, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	query := `SELECT id, type, symbol, value, threshold, message, created_at FROM market_alerts WHERE created_at >= ?`
+	args := []any{since.UTC().Format(signalTimeLayout)}
+	if len(symbols) > 0 {
+		query += ` AND symbol IN (?` + strings.Repeat(",?", len(symbols)-1) + `)`
+		for _, symbol := range symbols {
+			args = append(args, symbol)
+		}
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []*MarketAlert
+	for rows.Next() {
+		var alert MarketAlert
+		var createdAt string
+		if err := rows.Scan(&alert.ID, &alert.Type, &alert.Symbol, &alert.Value, &alert.Threshold, &alert.Message, &createdAt); err != nil {
+			return nil, err
+		}
+		alert.CreatedAt, _ = time.Parse(signalTimeLayout, createdAt)
+		alerts = append(alerts, &alert)
+	}
+	return alerts, nil
+}
+
+// DeleteBefore 删除 before 之前的警报，返回删除数量
+func (s *MarketAlertStore) DeleteBefore(before time.Time) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM market_alerts WHERE created_at < ?`, before.UTC().Format(signalTimeLayout))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
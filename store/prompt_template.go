@@ -0,0 +1,356 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SystemPromptTemplateUserID 系统模板（从 prompts/*.txt 导入）所属用户
+const SystemPromptTemplateUserID = "system"
+
+// PromptTemplateStore 提示词模板存储（按用户隔离，版本不可变）
+type PromptTemplateStore struct {
+	db *sql.DB
+}
+
+// PromptTemplate 提示词模板
+type PromptTemplate struct {
+	ID             int64     `json:"id"`
+	UserID         string    `json:"user_id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	CurrentVersion int       `json:"current_version"`
+	IsSystem       bool      `json:"is_system"` // 是否为系统模板（只读，用户修改时自动复制一份）
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PromptTemplateVersion 提示词模板版本（创建后不可修改）
+type PromptTemplateVersion struct {
+	ID          int64     `json:"id"` // 全局唯一，DecisionRecord 通过它引用具体版本
+	TemplateID  int64     `json:"template_id"`
+	Version     int       `json:"version"`
+	Content     string    `json:"content"`
+	ContentHash string    `json:"content_hash"`
+	Note        string    `json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PromptDiffLine 版本差异中的一行
+type PromptDiffLine struct {
+	Op   string `json:"op"` // equal/insert/delete
+	Text string `json:"text"`
+}
+
+func (s *PromptTemplateStore) initTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS prompt_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT DEFAULT '',
+			current_version INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(user_id, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS prompt_template_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			template_id INTEGER NOT NULL,
+			version INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			content_hash TEXT NOT NULL,
+			note TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(template_id, version),
+			FOREIGN KEY (template_id) REFERENCES prompt_templates(id)
+		)`,
+		// 版本不可变：禁止更新已有版本
+		`CREATE TRIGGER IF NOT EXISTS prevent_prompt_template_version_update
+		BEFORE UPDATE ON prompt_template_versions
+		BEGIN
+			SELECT RAISE(ABORT, 'prompt template versions are immutable');
+		END`,
+		`CREATE INDEX IF NOT EXISTS idx_prompt_templates_user ON prompt_templates(user_id)`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("执行SQL失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// SeedSystemTemplate 导入系统模板，内容与当前版本不同时追加新版本
+func (s *PromptTemplateStore) SeedSystemTemplate(name, content string) (*PromptTemplateVersion, error) {
+	return s.saveVersion(SystemPromptTemplateUserID, name, content, "从 prompts 目录导入")
+}
+
+// SaveVersion 保存用户模板的新版本
+// 模板不存在时自动创建；内容与当前版本相同时不产生新版本，直接返回当前版本
+func (s *PromptTemplateStore) SaveVersion(userID, name, content, note string) (*PromptTemplateVersion, error) {
+	if userID == SystemPromptTemplateUserID {
+		return nil, fmt.Errorf("不能修改系统模板")
+	}
+	return s.saveVersion(userID, name, content, note)
+}
+
+func (s *PromptTemplateStore) saveVersion(userID, name, content, note string) (*PromptTemplateVersion, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("模板名称不能为空")
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("模板内容不能为空")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var templateID int64
+	var currentVersion int
+	err = tx.QueryRow(`SELECT id, current_version FROM prompt_templates WHERE user_id = ? AND name = ?`,
+		userID, name).Scan(&templateID, &currentVersion)
+	if err == sql.ErrNoRows {
+		result, err := tx.Exec(`INSERT INTO prompt_templates (user_id, name) VALUES (?, ?)`, userID, name)
+		if err != nil {
+			return nil, fmt.Errorf("创建模板失败: %w", err)
+		}
+		templateID, _ = result.LastInsertId()
+	} else if err != nil {
+		return nil, fmt.Errorf("查询模板失败: %w", err)
+	}
+
+	hash := hashPromptContent(content)
+	if currentVersion > 0 {
+		current, err := scanPromptTemplateVersion(tx.QueryRow(`
+			SELECT id, template_id, version, content, content_hash, note, created_at
+			FROM prompt_template_versions WHERE template_id = ? AND version = ?
+		`, templateID, currentVersion))
+		if err == nil && current.ContentHash == hash {
+			return current, nil
+		}
+	}
+
+	version := currentVersion + 1
+	result, err := tx.Exec(`
+		INSERT INTO prompt_template_versions (template_id, version, content, content_hash, note)
+		VALUES (?, ?, ?, ?, ?)
+	`, templateID, version, content, hash, note)
+	if err != nil {
+		return nil, fmt.Errorf("插入模板版本失败: %w", err)
+	}
+	versionID, _ := result.LastInsertId()
+
+	if _, err := tx.Exec(`
+		UPDATE prompt_templates SET current_version = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, version, templateID); err != nil {
+		return nil, fmt.Errorf("更新模板当前版本失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	return &PromptTemplateVersion{
+		ID:          versionID,
+		TemplateID:  templateID,
+		Version:     version,
+		Content:     content,
+		ContentHash: hash,
+		Note:        note,
+		CreatedAt:   time.Now().UTC(),
+	}, nil
+}
+
+// UpdateDescription 更新模板描述（描述不属于版本内容）
+func (s *PromptTemplateStore) UpdateDescription(userID, name, description string) error {
+	_, err := s.db.Exec(`
+		UPDATE prompt_templates SET description = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND name = ?
+	`, description, userID, name)
+	return err
+}
+
+// List 获取用户可用的模板（用户模板 + 未被同名用户模板覆盖的系统模板）
+func (s *PromptTemplateStore) List(userID string) ([]*PromptTemplate, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, name, description, current_version, created_at, updated_at
+		FROM prompt_templates
+		WHERE user_id = ? OR (user_id = ? AND name NOT IN (SELECT name FROM prompt_templates WHERE user_id = ?))
+		ORDER BY user_id = ? DESC, name ASC
+	`, userID, SystemPromptTemplateUserID, userID, SystemPromptTemplateUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*PromptTemplate
+	for rows.Next() {
+		t, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// Get 获取模板（优先用户模板，其次系统模板）
+func (s *PromptTemplateStore) Get(userID, name string) (*PromptTemplate, error) {
+	return scanPromptTemplate(s.db.QueryRow(`
+		SELECT id, user_id, name, description, current_version, created_at, updated_at
+		FROM prompt_templates
+		WHERE name = ? AND user_id IN (?, ?)
+		ORDER BY user_id = ? ASC
+		LIMIT 1
+	`, name, userID, SystemPromptTemplateUserID, SystemPromptTemplateUserID))
+}
+
+// GetCurrentVersion 获取模板的当前版本（优先用户模板，其次系统模板）
+func (s *PromptTemplateStore) GetCurrentVersion(userID, name string) (*PromptTemplateVersion, error) {
+	t, err := s.Get(userID, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetVersion(t.ID, t.CurrentVersion)
+}
+
+// GetVersion 获取模板的指定版本
+func (s *PromptTemplateStore) GetVersion(templateID int64, version int) (*PromptTemplateVersion, error) {
+	return scanPromptTemplateVersion(s.db.QueryRow(`
+		SELECT id, template_id, version, content, content_hash, note, created_at
+		FROM prompt_template_versions WHERE template_id = ? AND version = ?
+	`, templateID, version))
+}
+
+// GetVersionByID 按版本ID获取（用于从 DecisionRecord 回溯）
+func (s *PromptTemplateStore) GetVersionByID(id int64) (*PromptTemplateVersion, error) {
+	return scanPromptTemplateVersion(s.db.QueryRow(`
+		SELECT id, template_id, version, content, content_hash, note, created_at
+		FROM prompt_template_versions WHERE id = ?
+	`, id))
+}
+
+// ListVersions 获取模板的全部版本（新版本在前）
+func (s *PromptTemplateStore) ListVersions(templateID int64) ([]*PromptTemplateVersion, error) {
+	rows, err := s.db.Query(`
+		SELECT id, template_id, version, content, content_hash, note, created_at
+		FROM prompt_template_versions WHERE template_id = ?
+		ORDER BY version DESC
+	`, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*PromptTemplateVersion
+	for rows.Next() {
+		v, err := scanPromptTemplateVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// Rollback 回滚到指定版本：以该版本内容创建新版本，历史保持不变
+// 回滚系统模板时会为用户创建一份副本
+func (s *PromptTemplateStore) Rollback(userID, name string, version int) (*PromptTemplateVersion, error) {
+	t, err := s.Get(userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("模板不存在: %s", name)
+	}
+	target, err := s.GetVersion(t.ID, version)
+	if err != nil {
+		return nil, fmt.Errorf("版本不存在: v%d", version)
+	}
+	return s.SaveVersion(userID, name, target.Content, fmt.Sprintf("回滚到 v%d", version))
+}
+
+// DiffPromptVersions 按行比较两个版本的内容
+func DiffPromptVersions(from, to *PromptTemplateVersion) []PromptDiffLine {
+	return diffLines(strings.Split(from.Content, "\n"), strings.Split(to.Content, "\n"))
+}
+
+// diffLines 基于最长公共子序列的逐行差异
+func diffLines(a, b []string) []PromptDiffLine {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	diff := make([]PromptDiffLine, 0, max(n, m))
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, PromptDiffLine{Op: "equal", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, PromptDiffLine{Op: "delete", Text: a[i]})
+			i++
+		default:
+			diff = append(diff, PromptDiffLine{Op: "insert", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		diff = append(diff, PromptDiffLine{Op: "delete", Text: a[i]})
+	}
+	for ; j < m; j++ {
+		diff = append(diff, PromptDiffLine{Op: "insert", Text: b[j]})
+	}
+	return diff
+}
+
+func hashPromptContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPromptTemplate(row rowScanner) (*PromptTemplate, error) {
+	var t PromptTemplate
+	var createdAt, updatedAt string
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Description, &t.CurrentVersion, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	t.IsSystem = t.UserID == SystemPromptTemplateUserID
+	t.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+	t.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+	return &t, nil
+}
+
+func scanPromptTemplateVersion(row rowScanner) (*PromptTemplateVersion, error) {
+	var v PromptTemplateVersion
+	var createdAt string
+	if err := row.Scan(&v.ID, &v.TemplateID, &v.Version, &v.Content, &v.ContentHash, &v.Note, &createdAt); err != nil {
+		return nil, err
+	}
+	v.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+	return &v, nil
+}
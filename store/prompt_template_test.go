@@ -0,0 +1,100 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestDiffLines 测试基于最长公共子序列的逐行差异
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []PromptDiffLine
+	}{
+		{
+			name: "相同内容",
+			a:    []string{"x", "y"},
+			b:    []string{"x", "y"},
+			want: []PromptDiffLine{{"equal", "x"}, {"equal", "y"}},
+		},
+		{
+			name: "修改中间一行",
+			a:    []string{"角色", "止损 2%", "输出 JSON"},
+			b:    []string{"角色", "止损 3%", "输出 JSON"},
+			want: []PromptDiffLine{{"equal", "角色"}, {"delete", "止损 2%"}, {"insert", "止损 3%"}, {"equal", "输出 JSON"}},
+		},
+		{
+			name: "追加和删除",
+			a:    []string{"a", "b"},
+			b:    []string{"b", "c"},
+			want: []PromptDiffLine{{"delete", "a"}, {"equal", "b"}, {"insert", "c"}},
+		},
+		{
+			name: "从空内容新增",
+			a:    nil,
+			b:    []string{"a"},
+			want: []PromptDiffLine{{"insert", "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPromptTemplateVersions 测试相同内容不产生新版本，回滚以旧内容创建新版本
+func TestPromptTemplateVersions(t *testing.T) {
+	templates := newTestStore(t).PromptTemplate()
+
+	v1, err := templates.SaveVersion("u1", "trend", "第一版", "初始")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := templates.SaveVersion("u1", "trend", "第二版", "修改")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.Version != 1 || v2.Version != 2 || v1.TemplateID != v2.TemplateID {
+		t.Fatalf("v1=%+v v2=%+v", v1, v2)
+	}
+
+	// 内容与当前版本相同时返回当前版本
+	same, err := templates.SaveVersion("u1", "trend", "第二版", "重复保存")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.ID != v2.ID || same.Version != 2 {
+		t.Errorf("重复内容不应产生新版本: %+v", same)
+	}
+
+	// 回滚到 v1：创建内容相同的 v3，历史版本保持不变
+	v3, err := templates.Rollback("u1", "trend", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v3.Version != 3 || v3.Content != "第一版" || v3.Note != "回滚到 v1" {
+		t.Errorf("rollback: %+v", v3)
+	}
+	versions, err := templates.ListVersions(v1.TemplateID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].Version != 3 || versions[2].Content != "第一版" || versions[1].Content != "第二版" {
+		t.Errorf("versions: %+v", versions)
+	}
+	current, err := templates.GetCurrentVersion("u1", "trend")
+	if err != nil || current.ID != v3.ID {
+		t.Errorf("current = %+v, err = %v", current, err)
+	}
+
+	if _, err := templates.Rollback("u1", "trend", 9); err == nil {
+		t.Error("回滚到不存在的版本应返回错误")
+	}
+	if _, err := templates.SaveVersion(SystemPromptTemplateUserID, "trend", "x", ""); err == nil {
+		t.Error("不能直接修改系统模板")
+	}
+}
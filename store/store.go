@@ -28,6 +28,13 @@ type Store struct {
 	order        *OrderStore
 	position     *PositionStore
 	strategy     *StrategyStore
+	experiment   *ExperimentStore
+	promptTmpl   *PromptTemplateStore
+	aiUsage      *AIUsageStore
+	evaluation   *EvaluationStore
+	webhook      *WebhookStore
+	marketAlert  *MarketAlertStore
+	symbolList   *SymbolListStore
 
 	// 加密函数
 	encryptFunc func(string) string
@@ -114,6 +121,10 @@ func (s *Store) SetCryptoFuncs(encrypt, decrypt func(string) string) {
 	if s.trader != nil {
 		s.trader.decryptFunc = decrypt
 	}
+	if s.webhook != nil {
+		s.webhook.encryptFunc = encrypt
+		s.webhook.decryptFunc = decrypt
+	}
 }
 
 // initTables 初始化所有数据库表
@@ -155,6 +166,27 @@ func (s *Store) initTables() error {
 	if err := s.Strategy().initTables(); err != nil {
 		return fmt.Errorf("初始化策略表失败: %w", err)
 	}
+	if err := s.Experiment().initTables(); err != nil {
+		return fmt.Errorf("初始化实验表失败: %w", err)
+	}
+	if err := s.PromptTemplate().initTables(); err != nil {
+		return fmt.Errorf("初始化提示词模板表失败: %w", err)
+	}
+	if err := s.AIUsage().initTables(); err != nil {
+		return fmt.Errorf("初始化AI用量表失败: %w", err)
+	}
+	if err := s.Evaluation().initTables(); err != nil {
+		return fmt.Errorf("初始化模型评估表失败: %w", err)
+	}
+	if err := s.Webhook().initTables(); err != nil {
+		return fmt.Errorf("初始化Webhook表失败: %w", err)
+	}
+	if err := s.MarketAlert().initTables(); err != nil {
+		return fmt.Errorf("初始化行情警报表失败: %w", err)
+	}
+	if err := s.SymbolList().initTables(); err != nil {
+		return fmt.Errorf("初始化币种列表表失败: %w", err)
+	}
 	return nil
 }
 
@@ -172,6 +204,9 @@ func (s *Store) initDefaultData() error {
 	if err := s.Strategy().initDefaultData(); err != nil {
 		return err
 	}
+	if err := s.AIUsage().initDefaultData(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -306,6 +341,80 @@ func (s *Store) Strategy() *StrategyStore {
 	return s.strategy
 }
 
+// Experiment 获取 Prompt A/B 实验存储
+func (s *Store) Experiment() *ExperimentStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.experiment == nil {
+		s.experiment = &ExperimentStore{db: s.db}
+	}
+	return s.experiment
+}
+
+// PromptTemplate 获取提示词模板存储
+func (s *Store) PromptTemplate() *PromptTemplateStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.promptTmpl == nil {
+		s.promptTmpl = &PromptTemplateStore{db: s.db}
+	}
+	return s.promptTmpl
+}
+
+// AIUsage 获取AI用量、价格表与预算存储
+func (s *Store) AIUsage() *AIUsageStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.aiUsage == nil {
+		s.aiUsage = &AIUsageStore{db: s.db}
+	}
+	return s.aiUsage
+}
+
+// Evaluation 获取模型评估存储
+func (s *Store) Evaluation() *EvaluationStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.evaluation == nil {
+		s.evaluation = &EvaluationStore{db: s.db}
+	}
+	return s.evaluation
+}
+
+// Webhook 获取入站 Webhook 存储
+func (s *Store) Webhook() *WebhookStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.webhook == nil {
+		s.webhook = &WebhookStore{
+			db:          s.db,
+			encryptFunc: s.encryptFunc,
+			decryptFunc: s.decryptFunc,
+		}
+	}
+	return s.webhook
+}
+
+// MarketAlert 获取行情警报存储
+func (s *Store) MarketAlert() *MarketAlertStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.marketAlert == nil {
+		s.marketAlert = &MarketAlertStore{db: s.db}
+	}
+	return s.marketAlert
+}
+
+// SymbolList 获取用户币种允许/禁止列表存储
+func (s *Store) SymbolList() *SymbolListStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.symbolList == nil {
+		s.symbolList = &SymbolListStore{db: s.db}
+	}
+	return s.symbolList
+}
+
 // Close 关闭数据库连接
 func (s *Store) Close() error {
 	return s.db.Close()
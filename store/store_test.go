@@ -0,0 +1,17 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newTestStore 创建使用临时数据库的 Store
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
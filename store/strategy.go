@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"nofx/market"
 	"time"
 )
 
@@ -37,6 +38,126 @@ type StrategyConfig struct {
 	RiskControl RiskControlConfig `json:"risk_control"`
 	// System Prompt 可编辑部分
 	PromptSections PromptSectionsConfig `json:"prompt_sections,omitempty"`
+	// 基础提示词模板名称（用户模板库，为空时不使用模板）
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	// Prompt Token 预算（超出模型上下文时按优先级裁剪）
+	TokenBudget TokenBudgetConfig `json:"token_budget,omitempty"`
+	// 决策提供者（默认使用 LLM，可切换为内置规则策略作为基准或兜底）
+	DecisionProvider DecisionProviderConfig `json:"decision_provider,omitempty"`
+	// 确定性规则（表达式）：AI决策前过滤候选币，决策后否决违规操作
+	Guards GuardConfig `json:"guards,omitempty"`
+	// 事件触发：行情异动时提前执行交易周期（定时周期作为兜底）
+	Triggers TriggerConfig `json:"triggers,omitempty"`
+	// 行情数据源（binance / bybit / hyperliquid），为空时使用交易员所在交易所的行情
+	MarketDataProvider string `json:"market_data_provider,omitempty"`
+}
+
+// TriggerConfig 事件触发配置
+// 基于实时K线监听上次周期的候选币和持仓，任一条件满足时提前执行交易周期；
+// 多个事件在防抖窗口内合并为一次，两次 AI 调用之间至少间隔 MinGapSecs
+type TriggerConfig struct {
+	Enabled bool `json:"enabled"`
+	// 监听的K线周期（默认使用主时间周期）
+	Timeframe string `json:"timeframe,omitempty"`
+	// 价格相对上次周期变动超过 N%（0 表示不启用）
+	PriceMovePct float64 `json:"price_move_pct,omitempty"`
+	// 当前K线振幅超过 ATR(14) 的倍数
+	ATRSpikeMultiplier float64 `json:"atr_spike_multiplier,omitempty"`
+	// 当前K线成交量超过近 20 根均量的倍数
+	VolumeSpikeMultiplier float64 `json:"volume_spike_multiplier,omitempty"`
+	// 持仓价格距止损价或强平价小于 N%
+	StopProximityPct float64 `json:"stop_proximity_pct,omitempty"`
+	// 收到新的 Webhook 信号
+	OnWebhookSignal bool `json:"on_webhook_signal,omitempty"`
+	// 监听周期K线收盘
+	OnCandleClose bool `json:"on_candle_close,omitempty"`
+	// 防抖窗口（秒，默认 5）
+	DebounceSecs int `json:"debounce_secs,omitempty"`
+	// 两次 AI 调用的最小间隔（秒，默认 60）
+	MinGapSecs int `json:"min_gap_secs,omitempty"`
+}
+
+// GuardConfig 规则过滤配置
+// 表达式语法见 decision.ParseGuardExpr，例如 "rsi14@4h > 80"、"quote_volume_24h > 50M"
+type GuardConfig struct {
+	// 候选币预过滤：候选币必须满足全部条件才会交给AI分析
+	CandidateFilters []GuardRule `json:"candidate_filters,omitempty"`
+	// 决策否决：条件成立时拒绝执行对应决策
+	Vetoes []GuardRule `json:"vetoes,omitempty"`
+}
+
+// GuardRule 单条规则
+type GuardRule struct {
+	Name string `json:"name,omitempty"`
+	Expr string `json:"expr"`
+	// 否决规则适用的动作（如 open_long），为空时适用于所有开仓动作
+	Actions []string `json:"actions,omitempty"`
+	// 为 true 时暂停该规则
+	Disabled bool `json:"disabled,omitempty"`
+	// 规则无法评估（如行情数据缺失）时的处理: "block" 否决决策/排除候选币 | "skip" 跳过该规则
+	// 为空时否决规则默认 block（失败即否决），候选币过滤默认 skip
+	OnError string `json:"on_error,omitempty"`
+}
+
+// 规则无法评估时的处理方式
+const (
+	GuardOnErrorBlock = "block"
+	GuardOnErrorSkip  = "skip"
+)
+
+// DecisionProviderConfig 决策提供者配置
+// 规则策略参数为 0 时使用默认值
+type DecisionProviderConfig struct {
+	// 提供者类型: "llm"（默认） | "ema_cross" | "rsi_reversion" | "atr_breakout"
+	Type string `json:"type,omitempty"`
+	// 信号计算使用的K线周期（默认使用主周期）
+	Timeframe string `json:"timeframe,omitempty"`
+	// 开仓杠杆（默认使用风控杠杆上限）
+	Leverage int `json:"leverage,omitempty"`
+	// 单笔保证金占账户净值的百分比，默认 20
+	PositionSizePct float64 `json:"position_size_pct,omitempty"`
+	// 止损/止盈距离（ATR 倍数），默认 2 / 6
+	StopLossATR   float64 `json:"stop_loss_atr,omitempty"`
+	TakeProfitATR float64 `json:"take_profit_atr,omitempty"`
+	// ATR 周期，默认 14
+	ATRPeriod int `json:"atr_period,omitempty"`
+
+	// EMA 交叉：快线/慢线周期，默认 9 / 21
+	EMAFast int `json:"ema_fast,omitempty"`
+	EMASlow int `json:"ema_slow,omitempty"`
+
+	// RSI 均值回归：周期默认 14，超卖/超买阈值默认 30 / 70，回到 RSIExit（默认 50）时平仓
+	RSIPeriod     int     `json:"rsi_period,omitempty"`
+	RSIOversold   float64 `json:"rsi_oversold,omitempty"`
+	RSIOverbought float64 `json:"rsi_overbought,omitempty"`
+	RSIExit       float64 `json:"rsi_exit,omitempty"`
+
+	// ATR 突破：收盘价突破前 N 根K线高/低点 ± BreakoutATRMult×ATR 时开仓，默认 20 / 0.5
+	// 反向突破前 N/2 根K线的低/高点时平仓
+	BreakoutLookback int     `json:"breakout_lookback,omitempty"`
+	BreakoutATRMult  float64 `json:"breakout_atr_mult,omitempty"`
+}
+
+// TokenBudgetConfig Prompt Token 预算配置
+// 裁剪顺序：先缩短K线序列，再移除候选币的外部数据，最后按排名从低到高移除候选币
+type TokenBudgetConfig struct {
+	// 是否启用预算裁剪（未启用时仍会记录 token 估算值）
+	Enabled bool `json:"enabled"`
+	// 用于选择分词近似参数的模型名称（如 deepseek、qwen、gpt-4o），为空时使用交易员的AI模型
+	Model string `json:"model,omitempty"`
+	// 模型上下文窗口（token），0 表示使用模型默认值
+	ContextWindow int `json:"context_window,omitempty"`
+	// 为AI输出预留的 token 数，0 表示使用默认值
+	ReserveOutputTokens int `json:"reserve_output_tokens,omitempty"`
+	// 分词近似：每个 token 对应的 ASCII 字符数，0 表示使用模型默认值
+	CharsPerToken float64 `json:"chars_per_token,omitempty"`
+	// 分词近似：每个 token 对应的非 ASCII（中文等）字符数，0 表示使用模型默认值
+	CJKCharsPerToken float64 `json:"cjk_chars_per_token,omitempty"`
+	// 各部分预算（token），0 表示不单独限制
+	PositionsTokens    int `json:"positions_tokens,omitempty"`
+	CandidatesTokens   int `json:"candidates_tokens,omitempty"`
+	KlinesTokens       int `json:"klines_tokens,omitempty"`
+	ExternalDataTokens int `json:"external_data_tokens,omitempty"`
 }
 
 // PromptSectionsConfig System Prompt 可编辑部分
@@ -53,7 +174,7 @@ type PromptSectionsConfig struct {
 
 // CoinSourceConfig 币种来源配置
 type CoinSourceConfig struct {
-	// 来源类型: "static" | "coinpool" | "oi_top" | "mixed"
+	// 来源类型: "static" | "coinpool" | "oi_top" | "local_rank" | "mixed"
 	SourceType string `json:"source_type"`
 	// 静态币种列表（当 source_type = "static" 时使用）
 	StaticCoins []string `json:"static_coins,omitempty"`
@@ -69,6 +190,14 @@ type CoinSourceConfig struct {
 	OITopLimit int `json:"oi_top_limit,omitempty"`
 	// OI Top API URL（策略级别配置）
 	OITopAPIURL string `json:"oi_top_api_url,omitempty"`
+	// 是否使用本地排名（mixed 模式）
+	UseLocalRank bool `json:"use_local_rank,omitempty"`
+	// 本地排名参数：数量、成交额下限和因子权重（source_type = "local_rank" 或启用 use_local_rank 时使用）
+	LocalRank market.RankingParams `json:"local_rank"`
+	// 允许交易的币种（非空时只保留列表内的候选币）
+	AllowSymbols []string `json:"allow_symbols,omitempty"`
+	// 禁止交易的币种（从所有来源的候选币中排除）
+	DenySymbols []string `json:"deny_symbols,omitempty"`
 }
 
 // IndicatorConfig 指标配置
@@ -89,6 +218,29 @@ type IndicatorConfig struct {
 	RSIPeriods []int `json:"rsi_periods,omitempty"` // 默认 [7, 14]
 	// ATR 周期配置
 	ATRPeriods []int `json:"atr_periods,omitempty"` // 默认 [14]
+	// 扩展技术指标开关
+	EnableBollinger  bool `json:"enable_bollinger,omitempty"`  // 布林带
+	EnableVWAP       bool `json:"enable_vwap,omitempty"`       // 成交量加权均价
+	EnableStochRSI   bool `json:"enable_stoch_rsi,omitempty"`  // 随机RSI
+	EnableADX        bool `json:"enable_adx,omitempty"`        // ADX/DMI
+	EnableOBV        bool `json:"enable_obv,omitempty"`        // 能量潮
+	EnableSupertrend bool `json:"enable_supertrend,omitempty"` // 超级趋势
+	EnableIchimoku   bool `json:"enable_ichimoku,omitempty"`   // 一目均衡表
+	EnableDonchian   bool `json:"enable_donchian,omitempty"`   // 唐奇安通道
+	EnableOrderBook  bool `json:"enable_order_book,omitempty"`  // 订单簿价差、深度和买卖盘失衡
+	EnableMarketAlerts bool `json:"enable_market_alerts,omitempty"` // 近期行情警报（放量、急涨急跌、RSI 超买超卖）
+	// 持仓情绪数据（Binance 合约）
+	EnableLongShortRatio bool                     `json:"enable_long_short_ratio,omitempty"` // 大户账户/持仓多空比、全市场多空比
+	EnableTakerFlow      bool                     `json:"enable_taker_flow,omitempty"`       // 主动买卖量
+	EnableLiquidations   bool                     `json:"enable_liquidations,omitempty"`     // 强平订单统计
+	Derivatives          market.DerivativesParams `json:"derivatives"`                       // 统计周期与聚合窗口
+	// 扩展技术指标参数（留空使用默认值）
+	Bollinger  market.BollingerParams  `json:"bollinger"`
+	StochRSI   market.StochRSIParams   `json:"stoch_rsi"`
+	ADX        market.ADXParams        `json:"adx"`
+	Supertrend market.SupertrendParams `json:"supertrend"`
+	Ichimoku   market.IchimokuParams   `json:"ichimoku"`
+	Donchian   market.DonchianParams   `json:"donchian"`
 	// 外部数据源
 	ExternalDataSources []ExternalDataSource `json:"external_data_sources,omitempty"`
 	// 量化数据源（资金流向、持仓变化、价格变化）
@@ -113,14 +265,25 @@ type KlineConfig struct {
 }
 
 // ExternalDataSource 外部数据源配置
+// "api" 类型由共享调度器按 RefreshSecs 定时拉取并缓存；"webhook" 类型的数据通过入站 Webhook 端点推送
 type ExternalDataSource struct {
-	Name        string            `json:"name"`         // 数据源名称
-	Type        string            `json:"type"`         // 类型: "api" | "webhook"
-	URL         string            `json:"url"`          // API URL
-	Method      string            `json:"method"`       // HTTP 方法
-	Headers     map[string]string `json:"headers,omitempty"`
-	DataPath    string            `json:"data_path,omitempty"`    // JSON 数据路径
-	RefreshSecs int               `json:"refresh_secs,omitempty"` // 刷新间隔（秒）
+	Name        string                 `json:"name"`   // 数据源名称
+	Type        string                 `json:"type"`   // 类型: "api" | "webhook"
+	URL         string                 `json:"url"`    // API URL
+	Method      string                 `json:"method"` // HTTP 方法: GET | POST
+	Headers     map[string]string      `json:"headers,omitempty"`
+	Body        string                 `json:"body,omitempty"`         // POST 请求体
+	DataPath    string                 `json:"data_path,omitempty"`    // JSONPath（如 $.data[0].value、$.items[?(@.symbol=='BTC')].rate）
+	RefreshSecs int                    `json:"refresh_secs,omitempty"` // 刷新间隔（秒，默认300）
+	TimeoutSecs int                    `json:"timeout_secs,omitempty"` // 请求超时（秒，默认10）
+	Transform   *ExternalDataTransform `json:"transform,omitempty"`    // 数值转换
+}
+
+// ExternalDataTransform 外部数据值转换（依次执行 scale → round → map，作用于提取结果中的每个标量）
+type ExternalDataTransform struct {
+	Scale float64           `json:"scale,omitempty"` // 数值乘数（0 表示不缩放）
+	Round *int              `json:"round,omitempty"` // 保留小数位
+	Map   map[string]string `json:"map,omitempty"`   // 值映射（如 {"1": "bullish", "-1": "bearish"}）
 }
 
 // RiskControlConfig 风险控制配置
@@ -141,6 +304,8 @@ type RiskControlConfig struct {
 	MinPositionSize float64 `json:"min_position_size"`
 	// 最小信心度
 	MinConfidence int `json:"min_confidence"`
+	// 最大市价冲击成本（基点），超过时按盘口深度缩减开仓金额；0 表示不检查
+	MaxMarketImpactBps float64 `json:"max_market_impact_bps,omitempty"`
 }
 
 func (s *StrategyStore) initTables() error {
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 用户币种列表类型
+const (
+	SymbolListAllow = "allow" // 允许列表：非空时只交易列表内的币种
+	SymbolListDeny  = "deny"  // 禁止列表：不交易列表内的币种
+)
+
+// SymbolListStore 用户级币种允许/禁止列表存储（作用于该用户的所有交易员）
+type SymbolListStore struct {
+	db *sql.DB
+}
+
+// SymbolListEntry 币种列表条目（同一币种只能属于一个列表）
+type SymbolListEntry struct {
+	UserID    string    `json:"-"`
+	Symbol    string    `json:"symbol"`
+	ListType  string    `json:"list_type"` // allow | deny
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *SymbolListStore) initTables() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS user_symbol_lists (
+			user_id TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			list_type TEXT NOT NULL,
+			reason TEXT DEFAULT '',
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (user_id, symbol)
+		)
+	`)
+	return err
+}
+
+// Set 添加或更新币种（已存在时覆盖列表类型和原因）
+func (s *SymbolListStore) Set(entry *SymbolListEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO user_symbol_lists (user_id, symbol, list_type, reason, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, symbol) DO UPDATE SET list_type = excluded.list_type, reason = excluded.reason`,
+		entry.UserID, entry.Symbol, entry.ListType, entry.Reason,
+		entry.CreatedAt.UTC().Format(signalTimeLayout))
+	return err
+}
+
+// Delete 从列表中移除币种
+func (s *SymbolListStore) Delete(userID, symbol string) error {
+	result, err := s.db.Exec(`DELETE FROM user_symbol_lists WHERE user_id = ? AND symbol = ?`, userID, symbol)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// List 获取用户的全部币种列表条目（按类型、币种排序）
+func (s *SymbolListStore) List(userID string) ([]*SymbolListEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT user_id, symbol, list_type, reason, created_at FROM user_symbol_lists
+		WHERE user_id = ? ORDER BY list_type, symbol`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*SymbolListEntry
+	for rows.Next() {
+		var entry SymbolListEntry
+		var createdAt string
+		if err := rows.Scan(&entry.UserID, &entry.Symbol, &entry.ListType, &entry.Reason, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.CreatedAt, _ = time.Parse(signalTimeLayout, createdAt)
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
@@ -103,6 +103,7 @@ func (s *TraderStore) initTables() error {
 		`ALTER TABLE traders ADD COLUMN use_oi_top BOOLEAN DEFAULT 0`,
 		`ALTER TABLE traders ADD COLUMN system_prompt_template TEXT DEFAULT 'default'`,
 		`ALTER TABLE traders ADD COLUMN strategy_id TEXT DEFAULT ''`,
+		`ALTER TABLE traders ADD COLUMN ai_failover_model_ids TEXT DEFAULT ''`,
 	}
 	for _, q := range alterQueries {
 		s.db.Exec(q)
@@ -202,6 +203,31 @@ func (s *TraderStore) UpdateCustomPrompt(userID, id string, customPrompt string,
 	return err
 }
 
+// GetFailoverModelIDs 获取AI故障转移链（主模型不可用时按顺序使用的备用模型ID）
+func (s *TraderStore) GetFailoverModelIDs(userID, id string) ([]string, error) {
+	var raw string
+	err := s.db.QueryRow(`SELECT COALESCE(ai_failover_model_ids, '') FROM traders WHERE id = ? AND user_id = ?`,
+		id, userID).Scan(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, modelID := range strings.Split(raw, ",") {
+		if modelID = strings.TrimSpace(modelID); modelID != "" {
+			ids = append(ids, modelID)
+		}
+	}
+	return ids, nil
+}
+
+// SetFailoverModelIDs 设置AI故障转移链
+func (s *TraderStore) SetFailoverModelIDs(userID, id string, modelIDs []string) error {
+	_, err := s.db.Exec(`UPDATE traders SET ai_failover_model_ids = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?`,
+		strings.Join(modelIDs, ","), id, userID)
+	return err
+}
+
 // Delete 删除交易员
 func (s *TraderStore) Delete(userID, id string) error {
 	_, err := s.db.Exec(`DELETE FROM traders WHERE id = ? AND user_id = ?`, id, userID)
@@ -0,0 +1,337 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Webhook 鉴权方式
+const (
+	WebhookAuthToken = "token" // 请求头/查询参数/payload 中携带密钥
+	WebhookAuthHMAC  = "hmac"  // 请求头携带 HMAC-SHA256(密钥, 请求体) 签名
+)
+
+// 信号动作（入站 payload 归一化后的取值）
+const (
+	SignalActionLong       = "long"
+	SignalActionShort      = "short"
+	SignalActionClose      = "close"
+	SignalActionCloseLong  = "close_long"
+	SignalActionCloseShort = "close_short"
+)
+
+// DefaultSignalTTLMinutes 信号默认有效期（分钟）
+const DefaultSignalTTLMinutes = 60
+
+// signalTimeLayout 信号时间的存储格式（UTC，可按字符串比较）
+const signalTimeLayout = "2006-01-02 15:04:05"
+
+// WebhookStore 入站 Webhook 端点与信号存储
+type WebhookStore struct {
+	db          *sql.DB
+	encryptFunc func(string) string
+	decryptFunc func(string) string
+}
+
+// WebhookEndpoint 入站 Webhook 端点（绑定交易员或策略）
+type WebhookEndpoint struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"user_id"`
+	Name         string     `json:"name"`
+	TraderID     string     `json:"trader_id,omitempty"`   // 绑定交易员（与 StrategyID 二选一）
+	StrategyID   string     `json:"strategy_id,omitempty"` // 绑定策略：使用该策略的所有交易员都能看到信号
+	AuthMode     string     `json:"auth_mode"`             // token | hmac
+	Secret       string     `json:"secret,omitempty"`      // 仅在创建/重置时返回给用户
+	TTLMinutes   int        `json:"ttl_minutes"`           // 信号有效期
+	TriggerCycle bool       `json:"trigger_cycle"`         // 收到信号后立即触发交易周期
+	Enabled      bool       `json:"enabled"`
+	LastSignalAt *time.Time `json:"last_signal_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// WebhookSignal 归一化后的入站信号
+type WebhookSignal struct {
+	ID         int64     `json:"id"`
+	EndpointID string    `json:"endpoint_id"`
+	UserID     string    `json:"user_id"`
+	TraderID   string    `json:"trader_id,omitempty"`
+	StrategyID string    `json:"strategy_id,omitempty"`
+	Symbol     string    `json:"symbol"` // 为空表示全市场信号
+	Action     string    `json:"action"` // long | short | close | close_long | close_short
+	Price      float64   `json:"price,omitempty"`
+	Confidence int       `json:"confidence,omitempty"` // 0-100
+	Timeframe  string    `json:"timeframe,omitempty"`
+	Source     string    `json:"source"` // 信号来源（payload 指定，默认端点名称）
+	Message    string    `json:"message,omitempty"`
+	Raw        string    `json:"raw,omitempty"` // 原始 payload
+	ReceivedAt time.Time `json:"received_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (s *WebhookStore) initTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS webhook_endpoints (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			trader_id TEXT NOT NULL DEFAULT '',
+			strategy_id TEXT NOT NULL DEFAULT '',
+			auth_mode TEXT NOT NULL DEFAULT 'token',
+			secret TEXT NOT NULL DEFAULT '',
+			ttl_minutes INTEGER DEFAULT 60,
+			trigger_cycle BOOLEAN DEFAULT 0,
+			enabled BOOLEAN DEFAULT 1,
+			last_signal_at TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhook_signals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			endpoint_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			trader_id TEXT NOT NULL DEFAULT '',
+			strategy_id TEXT NOT NULL DEFAULT '',
+			symbol TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			price REAL DEFAULT 0,
+			confidence INTEGER DEFAULT 0,
+			timeframe TEXT DEFAULT '',
+			source TEXT DEFAULT '',
+			message TEXT DEFAULT '',
+			raw TEXT DEFAULT '',
+			received_at TEXT NOT NULL,
+			expires_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_user ON webhook_endpoints(user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_signals_trader ON webhook_signals(trader_id, expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_signals_strategy ON webhook_signals(strategy_id, expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_signals_endpoint ON webhook_signals(endpoint_id, received_at)`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *WebhookStore) encrypt(plaintext string) string {
+	if s.encryptFunc != nil {
+		return s.encryptFunc(plaintext)
+	}
+	return plaintext
+}
+
+func (s *WebhookStore) decrypt(encrypted string) string {
+	if s.decryptFunc != nil {
+		return s.decryptFunc(encrypted)
+	}
+	return encrypted
+}
+
+// CreateEndpoint 创建 Webhook 端点（密钥加密存储）
+func (s *WebhookStore) CreateEndpoint(ep *WebhookEndpoint) error {
+	if ep.AuthMode == "" {
+		ep.AuthMode = WebhookAuthToken
+	}
+	if ep.TTLMinutes <= 0 {
+		ep.TTLMinutes = DefaultSignalTTLMinutes
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO webhook_endpoints (id, user_id, name, trader_id, strategy_id, auth_mode, secret,
+			ttl_minutes, trigger_cycle, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, ep.ID, ep.UserID, ep.Name, ep.TraderID, ep.StrategyID, ep.AuthMode, s.encrypt(ep.Secret),
+		ep.TTLMinutes, ep.TriggerCycle, ep.Enabled)
+	if err != nil {
+		return fmt.Errorf("创建Webhook端点失败: %w", err)
+	}
+	return nil
+}
+
+// UpdateEndpoint 更新端点设置（不修改密钥）
+func (s *WebhookStore) UpdateEndpoint(ep *WebhookEndpoint) error {
+	if ep.TTLMinutes <= 0 {
+		ep.TTLMinutes = DefaultSignalTTLMinutes
+	}
+	result, err := s.db.Exec(`
+		UPDATE webhook_endpoints SET name = ?, trader_id = ?, strategy_id = ?, auth_mode = ?,
+			ttl_minutes = ?, trigger_cycle = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?
+	`, ep.Name, ep.TraderID, ep.StrategyID, ep.AuthMode, ep.TTLMinutes, ep.TriggerCycle, ep.Enabled, ep.ID, ep.UserID)
+	if err != nil {
+		return fmt.Errorf("更新Webhook端点失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("Webhook端点不存在")
+	}
+	return nil
+}
+
+// RotateSecret 重置端点密钥
+func (s *WebhookStore) RotateSecret(userID, id, secret string) error {
+	result, err := s.db.Exec(`
+		UPDATE webhook_endpoints SET secret = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?
+	`, s.encrypt(secret), id, userID)
+	if err != nil {
+		return fmt.Errorf("重置Webhook密钥失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("Webhook端点不存在")
+	}
+	return nil
+}
+
+// DeleteEndpoint 删除端点及其信号
+func (s *WebhookStore) DeleteEndpoint(userID, id string) error {
+	result, err := s.db.Exec(`DELETE FROM webhook_endpoints WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("Webhook端点不存在")
+	}
+	s.db.Exec(`DELETE FROM webhook_signals WHERE endpoint_id = ?`, id)
+	return nil
+}
+
+const webhookEndpointColumns = `id, user_id, name, trader_id, strategy_id, auth_mode, secret,
+	ttl_minutes, trigger_cycle, enabled, last_signal_at, created_at, updated_at`
+
+// GetEndpoint 获取用户的端点（不含密钥）
+func (s *WebhookStore) GetEndpoint(userID, id string) (*WebhookEndpoint, error) {
+	ep, err := s.getEndpoint(`WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	ep.Secret = ""
+	return ep, nil
+}
+
+// GetEndpointWithSecret 按ID获取端点（含解密后的密钥，仅用于入站请求鉴权）
+func (s *WebhookStore) GetEndpointWithSecret(id string) (*WebhookEndpoint, error) {
+	ep, err := s.getEndpoint(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	ep.Secret = s.decrypt(ep.Secret)
+	return ep, nil
+}
+
+func (s *WebhookStore) getEndpoint(where string, args ...any) (*WebhookEndpoint, error) {
+	rows, err := s.db.Query(`SELECT `+webhookEndpointColumns+` FROM webhook_endpoints `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
+	}
+	return scanWebhookEndpoint(rows)
+}
+
+// ListEndpoints 获取用户的端点列表（不含密钥）
+func (s *WebhookStore) ListEndpoints(userID string) ([]*WebhookEndpoint, error) {
+	rows, err := s.db.Query(`SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []*WebhookEndpoint
+	for rows.Next() {
+		ep, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		ep.Secret = ""
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, nil
+}
+
+func scanWebhookEndpoint(rows *sql.Rows) (*WebhookEndpoint, error) {
+	var ep WebhookEndpoint
+	var lastSignalAt, createdAt, updatedAt string
+	err := rows.Scan(&ep.ID, &ep.UserID, &ep.Name, &ep.TraderID, &ep.StrategyID, &ep.AuthMode, &ep.Secret,
+		&ep.TTLMinutes, &ep.TriggerCycle, &ep.Enabled, &lastSignalAt, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if t, err := time.Parse(signalTimeLayout, lastSignalAt); err == nil {
+		ep.LastSignalAt = &t
+	}
+	ep.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAt)
+	ep.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAt)
+	return &ep, nil
+}
+
+// AddSignal 保存入站信号，并清理过期超过一天的旧信号
+func (s *WebhookStore) AddSignal(sig *WebhookSignal) error {
+	result, err := s.db.Exec(`
+		INSERT INTO webhook_signals (endpoint_id, user_id, trader_id, strategy_id, symbol, action, price,
+			confidence, timeframe, source, message, raw, received_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, sig.EndpointID, sig.UserID, sig.TraderID, sig.StrategyID, sig.Symbol, sig.Action, sig.Price,
+		sig.Confidence, sig.Timeframe, sig.Source, sig.Message, sig.Raw,
+		sig.ReceivedAt.UTC().Format(signalTimeLayout), sig.ExpiresAt.UTC().Format(signalTimeLayout))
+	if err != nil {
+		return fmt.Errorf("保存信号失败: %w", err)
+	}
+	sig.ID, _ = result.LastInsertId()
+
+	s.db.Exec(`UPDATE webhook_endpoints SET last_signal_at = ? WHERE id = ?`,
+		sig.ReceivedAt.UTC().Format(signalTimeLayout), sig.EndpointID)
+	s.db.Exec(`DELETE FROM webhook_signals WHERE expires_at < ?`,
+		sig.ReceivedAt.Add(-24*time.Hour).UTC().Format(signalTimeLayout))
+	return nil
+}
+
+const webhookSignalColumns = `id, endpoint_id, user_id, trader_id, strategy_id, symbol, action, price,
+	confidence, timeframe, source, message, raw, received_at, expires_at`
+
+// ListActiveSignals 获取交易员可见的未过期信号（交易员所属用户的端点，绑定该交易员或其策略），按接收时间倒序
+func (s *WebhookStore) ListActiveSignals(userID, traderID, strategyID string, now time.Time, limit int) ([]*WebhookSignal, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	return s.listSignals(`
+		WHERE user_id = ? AND (trader_id = ? OR (strategy_id != '' AND strategy_id = ?)) AND expires_at > ?
+		ORDER BY received_at DESC, id DESC LIMIT ?`,
+		userID, traderID, strategyID, now.UTC().Format(signalTimeLayout), limit)
+}
+
+// ListSignals 获取端点最近收到的信号（含已过期）
+func (s *WebhookStore) ListSignals(endpointID string, limit int) ([]*WebhookSignal, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return s.listSignals(`WHERE endpoint_id = ? ORDER BY received_at DESC, id DESC LIMIT ?`, endpointID, limit)
+}
+
+func (s *WebhookStore) listSignals(where string, args ...any) ([]*WebhookSignal, error) {
+	rows, err := s.db.Query(`SELECT `+webhookSignalColumns+` FROM webhook_signals `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var signals []*WebhookSignal
+	for rows.Next() {
+		var sig WebhookSignal
+		var receivedAt, expiresAt string
+		err := rows.Scan(&sig.ID, &sig.EndpointID, &sig.UserID, &sig.TraderID, &sig.StrategyID, &sig.Symbol,
+			&sig.Action, &sig.Price, &sig.Confidence, &sig.Timeframe, &sig.Source, &sig.Message, &sig.Raw,
+			&receivedAt, &expiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sig.ReceivedAt, _ = time.Parse(signalTimeLayout, receivedAt)
+		sig.ExpiresAt, _ = time.Parse(signalTimeLayout, expiresAt)
+		signals = append(signals, &sig)
+	}
+	return signals, nil
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// TestListActiveSignals 测试交易员只能看到本用户端点的未过期信号
+func TestListActiveSignals(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	add := func(userID, traderID, strategyID, symbol string, expiresIn time.Duration) {
+		t.Helper()
+		sig := &WebhookSignal{
+			EndpointID: "ep-" + userID, UserID: userID, TraderID: traderID, StrategyID: strategyID,
+			Symbol: symbol, Action: "long", Source: "test",
+			ReceivedAt: now.Add(-time.Minute), ExpiresAt: now.Add(expiresIn),
+		}
+		if err := s.Webhook().AddSignal(sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("alice", "trader-a", "", "BTCUSDT", time.Hour)
+	add("alice", "", "shared", "ETHUSDT", time.Hour)
+	add("alice", "trader-a", "", "SOLUSDT", -time.Minute) // 已过期
+	add("mallory", "", "shared", "DOGEUSDT", time.Hour)   // 其他用户绑定同一策略
+	add("mallory", "trader-a", "", "PEPEUSDT", time.Hour) // 其他用户伪造交易员ID
+
+	signals, err := s.Webhook().ListActiveSignals("alice", "trader-a", "shared", now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, sig := range signals {
+		got[sig.Symbol] = true
+	}
+	if len(signals) != 2 || !got["BTCUSDT"] || !got["ETHUSDT"] {
+		t.Errorf("signals: %+v", got)
+	}
+}
@@ -0,0 +1,32 @@
+package trader
+
+import (
+	"nofx/logger"
+	"nofx/mcp"
+)
+
+// newFailoverAIClient 将主模型和备用模型按顺序组成故障转移链
+func newFailoverAIClient(config AutoTraderConfig, primary mcp.AIClient) mcp.AIClient {
+	targets := []mcp.FailoverTarget{{Name: config.AIModel, Provider: mcp.ProviderOf(primary), Client: primary}}
+	for _, model := range config.FallbackAIModels {
+		client := mcp.NewProviderClient(model.Provider)
+		client.SetAPIKey(model.APIKey, model.CustomAPIURL, model.CustomModelName)
+		targets = append(targets, mcp.FailoverTarget{Name: model.ID, Provider: mcp.ProviderOf(client), Client: client})
+	}
+
+	names := make([]string, 0, len(targets))
+	for _, target := range targets {
+		names = append(names, target.Name)
+	}
+	logger.Infof("🔀 [%s] 启用AI故障转移链: %v", config.Name, names)
+
+	return mcp.NewFailoverClient(targets, mcp.FailoverConfig{})
+}
+
+// AIProviderStatus AI故障转移链中各提供商的熔断状态（未配置备用模型时返回 nil）
+func (at *AutoTrader) AIProviderStatus() []mcp.ProviderStatus {
+	if failover, ok := at.mcpClient.(*mcp.FailoverClient); ok {
+		return failover.Status()
+	}
+	return nil
+}
@@ -49,9 +49,14 @@ type AutoTraderConfig struct {
 	LighterTestnet          bool   // 是否使用testnet
 
 	// AI配置
-	UseQwen     bool
-	DeepSeekKey string
-	QwenKey     string
+	UseQwen      bool
+	DeepSeekKey  string
+	QwenKey      string
+	AnthropicKey string
+	GeminiKey    string
+
+	// AI故障转移链（主模型熔断时按顺序使用的备用模型）
+	FallbackAIModels []*store.AIModel
 
 	// 自定义AI API配置
 	CustomAPIURL    string
@@ -73,6 +78,7 @@ type AutoTraderConfig struct {
 	IsCrossMargin bool // true=全仓模式, false=逐仓模式
 
 	// 策略配置（使用完整策略配置）
+	StrategyID     string                // 策略ID（用于匹配绑定到策略的 Webhook 信号）
 	StrategyConfig *store.StrategyConfig // 策略配置（包含币种来源、指标、风控、Prompt等）
 }
 
@@ -80,7 +86,7 @@ type AutoTraderConfig struct {
 type AutoTrader struct {
 	id                    string // Trader唯一标识
 	name                  string // Trader显示名称
-	aiModel               string // AI模型名称
+	aiModel               string // AI模型名称（启用故障转移时为主模型的目标名称，与决策记录中实际应答的目标比较）
 	exchange              string // 交易平台名称
 	config                AutoTraderConfig
 	trader                Trader // 使用Trader接口（支持多平台）
@@ -95,15 +101,21 @@ type AutoTrader struct {
 	lastResetTime         time.Time
 	stopUntil             time.Time
 	isRunning             bool
-	startTime             time.Time          // 系统启动时间
-	callCount             int                // AI调用次数
-	positionFirstSeenTime map[string]int64   // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
-	stopMonitorCh         chan struct{}      // 用于停止监控goroutine
-	monitorWg             sync.WaitGroup     // 用于等待监控goroutine结束
-	peakPnLCache          map[string]float64 // 最高收益缓存 (symbol -> 峰值盈亏百分比)
-	peakPnLCacheMutex     sync.RWMutex       // 缓存读写锁
-	lastBalanceSyncTime   time.Time          // 上次余额同步时间
-	userID                string             // 用户ID
+	startTime             time.Time                  // 系统启动时间
+	callCount             int                        // AI调用次数
+	positionFirstSeenTime map[string]int64           // 持仓首次出现时间 (symbol_side -> timestamp毫秒)
+	stopMonitorCh         chan struct{}              // 用于停止监控goroutine
+	monitorWg             sync.WaitGroup             // 用于等待监控goroutine结束
+	peakPnLCache          map[string]float64         // 最高收益缓存 (symbol -> 峰值盈亏百分比)
+	peakPnLCacheMutex     sync.RWMutex               // 缓存读写锁
+	lastBalanceSyncTime   time.Time                  // 上次余额同步时间
+	userID                string                     // 用户ID
+	cycleStream           cycleStreamHub             // 决策周期实时事件（AI 流式输出）
+	triggerCh             chan string                // 外部触发立即执行周期（如 Webhook 信号、行情事件）
+	triggers              *decision.TriggerEvaluator // 事件触发条件（未启用时为 nil）
+	marketProvider        market.MarketDataProvider  // 行情数据源（策略指定或与交易所匹配）
+	venueSymbolSet        map[string]bool            // 执行交易所可交易的币种缓存（交易所支持查询时）
+	venueSymbolsAt        time.Time                  // 市场列表缓存时间
 }
 
 // NewAutoTrader 创建自动交易器
@@ -140,6 +152,20 @@ func NewAutoTrader(config AutoTraderConfig, st *store.Store, userID string) (*Au
 		} else {
 			logger.Infof("🤖 [%s] 使用阿里云Qwen AI", config.Name)
 		}
+	} else if config.AIModel == mcp.ProviderAnthropic || config.AIModel == mcp.ProviderGemini {
+		// 使用Anthropic / Gemini 原生API (支持自定义URL和Model)
+		apiKey := config.AnthropicKey
+		if config.AIModel == mcp.ProviderGemini {
+			apiKey = config.GeminiKey
+		}
+		mcpClient = mcp.NewProviderClient(config.AIModel)
+		mcpClient.SetAPIKey(apiKey, config.CustomAPIURL, config.CustomModelName)
+		logger.Infof("🤖 [%s] 使用%s AI (自定义URL: %s, 模型: %s)", config.Name, config.AIModel, config.CustomAPIURL, config.CustomModelName)
+	} else if mcp.IsLocalProvider(config.AIModel) {
+		// 使用本地模型 (Ollama / llama.cpp 等，无需API Key)
+		mcpClient = mcp.NewProviderClient(config.AIModel)
+		mcpClient.SetAPIKey(config.CustomAPIKey, config.CustomAPIURL, config.CustomModelName)
+		logger.Infof("🤖 [%s] 使用本地AI %s (URL: %s, 模型: %s)", config.Name, config.AIModel, config.CustomAPIURL, config.CustomModelName)
 	} else {
 		// 默认使用DeepSeek (支持自定义URL和Model)
 		mcpClient = mcp.NewDeepSeekClient()
@@ -151,6 +177,11 @@ func NewAutoTrader(config AutoTraderConfig, st *store.Store, userID string) (*Au
 		}
 	}
 
+	// 配置了备用模型时，使用故障转移客户端包装主模型
+	if len(config.FallbackAIModels) > 0 {
+		mcpClient = newFailoverAIClient(config, mcpClient)
+	}
+
 	// 设置默认交易平台
 	if config.Exchange == "" {
 		config.Exchange = "binance"
@@ -232,6 +263,14 @@ func NewAutoTrader(config AutoTraderConfig, st *store.Store, userID string) (*Au
 	strategyEngine := decision.NewStrategyEngine(config.StrategyConfig)
 	logger.Infof("✓ [%s] 使用策略引擎（策略配置已加载）", config.Name)
 
+	// 行情数据源：策略显式指定优先，否则使用与交易所匹配的数据源
+	marketProvider, err := market.ResolveProvider(config.StrategyConfig.MarketDataProvider, config.Exchange)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] %w", config.Name, err)
+	}
+	strategyEngine.SetMarketProvider(marketProvider)
+	logger.Infof("✓ [%s] 行情数据源: %s", config.Name, marketProvider.Name())
+
 	return &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
@@ -255,6 +294,8 @@ func NewAutoTrader(config AutoTraderConfig, st *store.Store, userID string) (*Au
 		peakPnLCacheMutex:     sync.RWMutex{},
 		lastBalanceSyncTime:   time.Now(),
 		userID:                userID,
+		triggerCh:             make(chan string, 16),
+		marketProvider:        marketProvider,
 	}, nil
 }
 
@@ -274,20 +315,53 @@ func (at *AutoTrader) Run() error {
 	// 启动回撤监控
 	at.startDrawdownMonitor()
 
+	// 启动事件触发监听（定时周期作为兜底）
+	stopTriggers := at.startEventTriggers()
+	defer stopTriggers()
+
 	ticker := time.NewTicker(at.config.ScanInterval)
 	defer ticker.Stop()
 
 	// 首次立即执行
+	lastCycle := time.Now()
 	if err := at.runCycle(); err != nil {
 		logger.Infof("❌ 执行失败: %v", err)
 	}
 
+	// 事件触发：防抖窗口内的触发合并为一次，并保证与上次周期的最小间隔
+	var triggerTimer *time.Timer
+	var triggerC <-chan time.Time
+	var reasons []string
+	cancelTrigger := func() {
+		if triggerTimer != nil {
+			triggerTimer.Stop()
+		}
+		triggerTimer, triggerC, reasons = nil, nil, nil
+	}
+	defer cancelTrigger()
+
 	for at.isRunning {
 		select {
 		case <-ticker.C:
+			cancelTrigger() // 定时周期已覆盖待执行的触发
+			lastCycle = time.Now()
+			if err := at.runCycle(); err != nil {
+				logger.Infof("❌ 执行失败: %v", err)
+			}
+		case reason := <-at.triggerCh:
+			reasons = append(reasons, reason)
+			if triggerC == nil {
+				triggerTimer = time.NewTimer(at.triggerDelay(lastCycle))
+				triggerC = triggerTimer.C
+			}
+		case <-triggerC:
+			logger.Infof("[%s] ⚡ 事件触发立即执行周期: %s", at.name, joinTriggerReasons(reasons))
+			cancelTrigger()
+			lastCycle = time.Now()
 			if err := at.runCycle(); err != nil {
 				logger.Infof("❌ 执行失败: %v", err)
 			}
+			ticker.Reset(at.config.ScanInterval)
 		case <-at.stopMonitorCh:
 			logger.Infof("[%s] ⏹ 收到停止信号，退出自动交易主循环", at.name)
 			return nil
@@ -332,6 +406,15 @@ func (at *AutoTrader) runCycle() error {
 		return nil
 	}
 
+	// 1.1 检查本月AI成本是否超出预算（超出后暂停至下月；规则策略不调用AI，不受预算限制）
+	if exceeded, spent, budget := at.aiBudgetExceeded(); exceeded {
+		logger.Warnf("⏸ [%s] 本月AI成本 $%.4f 已达到预算 $%.2f，暂停AI决策", at.name, spent, budget)
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("本月AI成本 $%.4f 已达到预算 $%.2f，暂停至下月或调整预算", spent, budget)
+		at.saveDecision(record)
+		return nil
+	}
+
 	// 2. 重置日盈亏（每天重置）
 	if time.Since(at.lastResetTime) > 24*time.Hour {
 		at.dailyPnL = 0
@@ -380,9 +463,28 @@ func (at *AutoTrader) runCycle() error {
 	logger.Infof("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
-	// 5. 使用策略引擎调用AI获取决策
-	logger.Infof("🤖 正在请求AI分析并决策... [策略引擎]")
-	aiDecision, err := decision.GetFullDecisionWithStrategy(ctx, at.mcpClient, at.strategyEngine, "balanced")
+	// 5. 使用策略引擎调用AI获取决策（参与 A/B 实验时使用分配到的变体）
+	engine, promptVariant := at.selectPromptVariant(record)
+	engine = at.applyPromptTemplate(engine, record)
+	ctx.PromptVariant = promptVariant
+	ctx.OnStreamChunk = at.cycleStreamHandler()
+	provider, err := decision.NewDecisionProvider(engine, at.mcpClient, promptVariant)
+	if err != nil {
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("创建决策提供者失败: %v", err)
+		at.saveDecision(record)
+		return fmt.Errorf("创建决策提供者失败: %w", err)
+	}
+	if provider.Name() != decision.DecisionProviderLLM {
+		logger.Infof("📐 使用规则策略决策: %s", provider.Name())
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("决策提供者: %s", provider.Name()))
+	} else {
+		logger.Infof("🤖 正在请求AI分析并决策... [策略引擎, 变体: %s]", record.PromptVariant)
+	}
+	at.publishCycleEvent(CycleEventStart, "", "", nil)
+	aiDecision, err := provider.Decide(ctx)
+	at.publishCycleEnd(aiDecision, err)
+	record.MarketRegime = ctx.MarketRegime()
 
 	if aiDecision != nil && aiDecision.AIRequestDurationMs > 0 {
 		record.AIRequestDurationMs = aiDecision.AIRequestDurationMs
@@ -396,6 +498,26 @@ func (at *AutoTrader) runCycle() error {
 		record.SystemPrompt = aiDecision.SystemPrompt // 保存系统提示词
 		record.InputPrompt = aiDecision.UserPrompt
 		record.CoTTrace = aiDecision.CoTTrace
+		record.AIProvider = aiDecision.AIProvider
+		record.AITarget = aiDecision.AITarget
+		// 按目标名称判断是否发生故障转移（同一提供商的不同模型之间切换时提供商相同）
+		if record.AITarget != "" && record.AITarget != at.aiModel {
+			record.ExecutionLog = append(record.ExecutionLog,
+				fmt.Sprintf("主AI模型不可用，由备用模型 %s（%s）应答", record.AITarget, record.AIProvider))
+		}
+		if usage := aiDecision.AIUsage; usage != nil {
+			at.recordAIUsage(record, usage)
+		}
+		if usage := aiDecision.TokenUsage; usage != nil {
+			record.SystemPromptTokens = usage.SystemTokens
+			record.UserPromptTokens = usage.UserTokens
+			record.PromptTokenLimit = usage.Limit
+			record.PromptTrimmed = strings.Join(usage.Trimmed, ", ")
+			if len(usage.Trimmed) > 0 {
+				record.ExecutionLog = append(record.ExecutionLog,
+					fmt.Sprintf("Prompt 超出 token 预算已裁剪: %s", record.PromptTrimmed))
+			}
+		}
 		if len(aiDecision.Decisions) > 0 {
 			decisionJSON, _ := json.MarshalIndent(aiDecision.Decisions, "", "  ")
 			record.DecisionJSON = string(decisionJSON)
@@ -455,8 +577,19 @@ func (at *AutoTrader) runCycle() error {
 	// 8. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
 	logger.Info(strings.Repeat("-", 70))
 
+	// 应用策略的否决规则（被否决的决策不执行，原因写入执行日志）
+	allowedDecisions, vetoed, vetoErr := engine.ApplyVetoes(ctx, aiDecision.Decisions)
+	if vetoErr != nil {
+		logger.Warnf("⚠️ [%s] 否决规则配置错误，已否决全部开仓决策: %v", at.name, vetoErr)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ 否决规则配置错误，已否决全部开仓决策: %v", vetoErr))
+	}
+	for _, v := range vetoed {
+		logger.Infof("🚫 %s", v.Reason)
+		record.ExecutionLog = append(record.ExecutionLog, "🚫 "+v.Reason)
+	}
+
 	// 8. 对决策排序：确保先平仓后开仓（防止仓位叠加超限）
-	sortedDecisions := sortDecisionsByPriority(aiDecision.Decisions)
+	sortedDecisions := sortDecisionsByPriority(allowedDecisions)
 
 	logger.Info("🔄 执行顺序（已优化）: 先平仓→后开仓")
 	for i, d := range sortedDecisions {
@@ -612,6 +745,14 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 	}
 	logger.Infof("📋 [%s] 策略引擎获取候选币种: %d个", at.name, len(candidateCoins))
 
+	// 按可交易币种范围过滤（用户列表、暂停交易或计划下架、执行交易所未上线），并检查持仓下架风险
+	universe := at.buildSymbolUniverse()
+	candidateCoins = universe.FilterCandidates(candidateCoins)
+	delistingWarnings := universe.DelistingWarnings(positionInfos)
+	for _, w := range delistingWarnings {
+		logger.Warnf("⚠️ [%s] 持仓 %s %s 需尽快平仓: %s", at.name, w.Symbol, w.Side, w.Reason)
+	}
+
 	// 4. 计算总盈亏
 	totalPnL := totalEquity - at.initialBalance
 	totalPnLPct := 0.0
@@ -637,6 +778,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		CallCount:       at.callCount,
 		BTCETHLeverage:  btcEthLeverage,
 		AltcoinLeverage: altcoinLeverage,
+		AIModel:         at.tokenizerModel(),
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -649,6 +791,9 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		},
 		Positions:      positionInfos,
 		CandidateCoins: candidateCoins,
+		DelistWarnings: delistingWarnings,
+		Market:         at.marketProvider,
+		Universe:       universe,
 	}
 
 	// 7. 添加交易统计和历史订单（如果store可用）
@@ -681,6 +826,27 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 				})
 			}
 		}
+
+		// 获取未过期的外部 Webhook 信号（本用户绑定本交易员或其策略的端点）
+		if signals, err := at.store.Webhook().ListActiveSignals(at.userID, at.id, at.config.StrategyID, time.Now(), 10); err == nil {
+			ctx.Signals = signals
+		}
+
+		// 获取候选币和持仓币种近 1 小时的行情警报（如果策略配置启用）
+		if strategyConfig.Indicators.EnableMarketAlerts {
+			var symbols []string
+			for _, coin := range candidateCoins {
+				symbols = append(symbols, coin.Symbol)
+			}
+			for _, pos := range positionInfos {
+				symbols = append(symbols, pos.Symbol)
+			}
+			if len(symbols) > 0 {
+				if alerts, err := at.store.MarketAlert().List(symbols, time.Now().Add(-time.Hour), 10); err == nil {
+					ctx.MarketAlerts = alerts
+				}
+			}
+		}
 	}
 
 	// 8. 获取量化数据（如果策略配置启用）
@@ -704,6 +870,14 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		logger.Infof("📊 [%s] 成功获取 %d 个币种的量化数据", at.name, len(ctx.QuantDataMap))
 	}
 
+	// 9. 获取外部数据源（共享调度器缓存，按各自刷新间隔拉取）
+	if len(strategyConfig.Indicators.ExternalDataSources) > 0 {
+		ctx.ExternalData = at.strategyEngine.FetchExternalData()
+	}
+
+	// 10. 更新事件触发关注的币种
+	at.resetEventTriggers(ctx)
+
 	return ctx, nil
 }
 
@@ -741,7 +915,13 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetFrom(at.marketProvider, decision.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// 按盘口深度估算冲击成本（超过上限时缩减开仓金额）
+	impactCost, err := at.applyMarketImpactLimit(decision, "buy")
 	if err != nil {
 		return err
 	}
@@ -764,7 +944,7 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	}
 
 	// 手续费估算（Taker费率 0.04%）
-	estimatedFee := decision.PositionSizeUSD * 0.0004
+	estimatedFee := decision.PositionSizeUSD*0.0004 + impactCost // 含预计冲击成本
 	totalRequired := requiredMargin + estimatedFee
 
 	if totalRequired > availableBalance {
@@ -801,6 +981,8 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	// 设置止损止盈
 	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
 		logger.Infof("  ⚠ 设置止损失败: %v", err)
+	} else if at.triggers != nil {
+		at.triggers.SetStopLoss(decision.Symbol, "LONG", decision.StopLoss)
 	}
 	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
 		logger.Infof("  ⚠ 设置止盈失败: %v", err)
@@ -824,7 +1006,13 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetFrom(at.marketProvider, decision.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// 按盘口深度估算冲击成本（超过上限时缩减开仓金额）
+	impactCost, err := at.applyMarketImpactLimit(decision, "sell")
 	if err != nil {
 		return err
 	}
@@ -847,7 +1035,7 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	}
 
 	// 手续费估算（Taker费率 0.04%）
-	estimatedFee := decision.PositionSizeUSD * 0.0004
+	estimatedFee := decision.PositionSizeUSD*0.0004 + impactCost // 含预计冲击成本
 	totalRequired := requiredMargin + estimatedFee
 
 	if totalRequired > availableBalance {
@@ -884,6 +1072,8 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	// 设置止损止盈
 	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
 		logger.Infof("  ⚠ 设置止损失败: %v", err)
+	} else if at.triggers != nil {
+		at.triggers.SetStopLoss(decision.Symbol, "SHORT", decision.StopLoss)
 	}
 	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
 		logger.Infof("  ⚠ 设置止盈失败: %v", err)
@@ -897,7 +1087,7 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 	logger.Infof("  🔄 平多仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetFrom(at.marketProvider, decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -936,7 +1126,7 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	logger.Infof("  🔄 平空仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := market.GetFrom(at.marketProvider, decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -985,6 +1175,57 @@ func (at *AutoTrader) GetAIModel() string {
 	return at.aiModel
 }
 
+// recordAIUsage 记录AI用量并按价格表计算成本
+func (at *AutoTrader) recordAIUsage(record *store.DecisionRecord, usage *mcp.Usage) {
+	record.AIModel = usage.Model
+	record.PromptTokens = usage.PromptTokens
+	record.CompletionTokens = usage.CompletionTokens
+	record.ReasoningTokens = usage.ReasoningTokens
+	record.CachedTokens = usage.CachedTokens
+	record.TotalTokens = usage.TotalTokens
+	if at.store != nil {
+		record.AICostUSD = at.store.AIUsage().CalculateCost(at.userID, usage.Model,
+			usage.PromptTokens, usage.CachedTokens, usage.CompletionTokens)
+	}
+	record.ExecutionLog = append(record.ExecutionLog,
+		fmt.Sprintf("AI用量: %s 输入%d(缓存%d) 输出%d(推理%d) tokens, 成本 $%.6f",
+			usage.Model, usage.PromptTokens, usage.CachedTokens,
+			usage.CompletionTokens, usage.ReasoningTokens, record.AICostUSD))
+}
+
+// aiBudgetExceeded 检查本月AI成本是否达到交易员的月度预算（规则策略不调用AI，始终返回 false）
+func (at *AutoTrader) aiBudgetExceeded() (bool, float64, float64) {
+	if at.store == nil || !at.usesAIDecisions() {
+		return false, 0, 0
+	}
+	budget, err := at.store.AIUsage().GetBudget(at.id)
+	if err != nil || budget == nil {
+		return false, 0, 0
+	}
+	spent, err := at.store.AIUsage().GetMonthCost(at.id, time.Now())
+	if err != nil {
+		logger.Warnf("⚠️ 查询AI成本失败: %v", err)
+		return false, 0, budget.MonthlyBudgetUSD
+	}
+	return spent >= budget.MonthlyBudgetUSD, spent, budget.MonthlyBudgetUSD
+}
+
+// usesAIDecisions 策略是否使用 LLM 决策（规则策略返回 false）
+func (at *AutoTrader) usesAIDecisions() bool {
+	if at.strategyEngine == nil || at.strategyEngine.GetConfig() == nil {
+		return true
+	}
+	return !decision.IsRuleProvider(at.strategyEngine.GetConfig().DecisionProvider.Type)
+}
+
+// tokenizerModel 用于 token 估算的模型名称（优先使用自定义模型名）
+func (at *AutoTrader) tokenizerModel() string {
+	if at.config.CustomModelName != "" {
+		return at.config.CustomModelName
+	}
+	return at.aiModel
+}
+
 // GetExchange 获取交易所
 func (at *AutoTrader) GetExchange() string {
 	return at.exchange
@@ -1011,6 +1252,63 @@ func (at *AutoTrader) GetSystemPromptTemplate() string {
 	return "strategy"
 }
 
+// selectPromptVariant 选择本周期使用的策略引擎和交易模式变体，并在决策记录上打标签
+// 未参与任何运行中的实验时使用默认的 balanced 模式
+func (at *AutoTrader) selectPromptVariant(record *store.DecisionRecord) (*decision.StrategyEngine, string) {
+	const defaultVariant = "balanced"
+	record.PromptVariant = defaultVariant
+
+	if at.store == nil {
+		return at.strategyEngine, defaultVariant
+	}
+	exp, err := at.store.Experiment().GetRunningForTrader(at.userID, at.id)
+	if err != nil {
+		logger.Infof("⚠️ [%s] 查询Prompt实验失败，使用默认变体: %v", at.name, err)
+		return at.strategyEngine, defaultVariant
+	}
+	if exp == nil {
+		return at.strategyEngine, defaultVariant
+	}
+
+	// 周期编号在保存记录时才递增，这里使用即将写入的编号
+	variant := exp.AssignVariant(at.id, at.cycleNumber+1)
+	if variant == nil {
+		return at.strategyEngine, defaultVariant
+	}
+
+	record.ExperimentID = exp.ID
+	record.PromptVariant = variant.Name
+	record.ExecutionLog = append(record.ExecutionLog,
+		fmt.Sprintf("Prompt实验: %s, 变体: %s", exp.Name, variant.Name))
+
+	engine := at.strategyEngine
+	if variant.PromptSections != nil {
+		engine = engine.WithPromptSections(*variant.PromptSections)
+	}
+	mode := variant.PromptVariant
+	if mode == "" {
+		mode = defaultVariant
+	}
+	return engine, mode
+}
+
+// applyPromptTemplate 加载策略引用的提示词模板当前版本，并在决策记录中记录版本ID
+func (at *AutoTrader) applyPromptTemplate(engine *decision.StrategyEngine, record *store.DecisionRecord) *decision.StrategyEngine {
+	name := engine.GetConfig().PromptTemplate
+	if name == "" || at.store == nil {
+		return engine
+	}
+
+	version, err := at.store.PromptTemplate().GetCurrentVersion(at.userID, name)
+	if err != nil {
+		logger.Infof("⚠️ [%s] 加载提示词模板 %s 失败，本周期不使用模板: %v", at.name, name, err)
+		return engine
+	}
+
+	record.PromptTemplateVersionID = version.ID
+	return engine.WithBaseTemplate(version.Content)
+}
+
 // saveDecision 保存决策记录到数据库
 func (at *AutoTrader) saveDecision(record *store.DecisionRecord) error {
 	if at.store == nil {
@@ -1034,6 +1332,30 @@ func (at *AutoTrader) saveDecision(record *store.DecisionRecord) error {
 	return nil
 }
 
+// GetUserID 获取trader所属用户ID
+func (at *AutoTrader) GetUserID() string {
+	return at.userID
+}
+
+// GetStrategyID 获取trader使用的策略ID
+func (at *AutoTrader) GetStrategyID() string {
+	return at.config.StrategyID
+}
+
+// TriggerCycle 请求提前执行一个交易周期（不阻塞）
+// 触发会按策略的防抖窗口和最小间隔合并执行（未启用事件触发时使用默认值）；未运行或触发队列已满时返回 false；执行后重新计时扫描间隔
+func (at *AutoTrader) TriggerCycle(reason string) bool {
+	if !at.isRunning {
+		return false
+	}
+	select {
+	case at.triggerCh <- reason:
+		return true
+	default:
+		return false
+	}
+}
+
 // GetStore 获取数据存储（用于外部访问决策记录等）
 func (at *AutoTrader) GetStore() *store.Store {
 	return at.store
@@ -1046,7 +1368,7 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		aiProvider = "Qwen"
 	}
 
-	return map[string]interface{}{
+	status := map[string]interface{}{
 		"trader_id":       at.id,
 		"trader_name":     at.name,
 		"ai_model":        at.aiModel,
@@ -1061,6 +1383,10 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
 		"ai_provider":     aiProvider,
 	}
+	if providers := at.AIProviderStatus(); providers != nil {
+		status["ai_failover"] = providers
+	}
+	return status
 }
 
 // GetAccountInfo 获取账户信息（用于API）
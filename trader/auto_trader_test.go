@@ -377,8 +377,8 @@ func (s *AutoTraderTestSuite) TestGetCandidateCoins() {
 // ============================================================
 
 func (s *AutoTraderTestSuite) TestBuildTradingContext() {
-	// Mock market.Get
-	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+	// Mock market.GetFrom
+	s.patches.ApplyFunc(market.GetFrom, func(_ market.MarketDataProvider, symbol string) (*market.Data, error) {
 		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
 	})
 
@@ -470,7 +470,7 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 	for _, tt := range tests {
 		time.Sleep(time.Millisecond)
 		s.Run(tt.name, func() {
-			s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+			s.patches.ApplyFunc(market.GetFrom, func(_ market.MarketDataProvider, symbol string) (*market.Data, error) {
 				return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
 			})
 
@@ -535,7 +535,7 @@ func (s *AutoTraderTestSuite) TestExecuteClosePosition() {
 	for _, tt := range tests {
 		time.Sleep(time.Millisecond)
 		s.Run(tt.name, func() {
-			s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+			s.patches.ApplyFunc(market.GetFrom, func(_ market.MarketDataProvider, symbol string) (*market.Data, error) {
 				return &market.Data{Symbol: symbol, CurrentPrice: tt.currentPrice}, nil
 			})
 
@@ -556,8 +556,8 @@ func (s *AutoTraderTestSuite) TestExecuteClosePosition() {
 // ============================================================
 
 func (s *AutoTraderTestSuite) TestExecuteDecisionWithRecord() {
-	// Mock market.Get
-	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+	// Mock market.GetFrom
+	s.patches.ApplyFunc(market.GetFrom, func(_ market.MarketDataProvider, symbol string) (*market.Data, error) {
 		return &market.Data{
 			Symbol:       symbol,
 			CurrentPrice: 50000.0,
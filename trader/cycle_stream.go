@@ -0,0 +1,121 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/mcp"
+	"sync"
+	"time"
+)
+
+// 决策周期实时事件类型
+const (
+	CycleEventStart     = "cycle_start" // 开始请求AI
+	CycleEventReasoning = "reasoning"   // 思维链增量（reasoning_content）
+	CycleEventContent   = "content"     // 正文增量
+	CycleEventEnd       = "cycle_end"   // AI调用结束（Message 为错误信息，成功时为空）
+)
+
+// cycleStreamBuffer 每个订阅者的事件缓冲，消费过慢时丢弃新事件
+const cycleStreamBuffer = 256
+
+// CycleStreamEvent AI决策周期的实时事件（推送给 API 订阅者）
+type CycleStreamEvent struct {
+	Type      string     `json:"type"`
+	TraderID  string     `json:"trader_id"`
+	Cycle     int        `json:"cycle"`
+	Delta     string     `json:"delta,omitempty"`
+	Message   string     `json:"message,omitempty"`
+	Usage     *mcp.Usage `json:"usage,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+}
+
+// cycleStreamHub 决策周期事件的发布/订阅（零值可用）
+type cycleStreamHub struct {
+	mu          sync.Mutex
+	subscribers map[chan CycleStreamEvent]struct{}
+}
+
+func (h *cycleStreamHub) subscribe() (<-chan CycleStreamEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers == nil {
+		h.subscribers = make(map[chan CycleStreamEvent]struct{})
+	}
+	ch := make(chan CycleStreamEvent, cycleStreamBuffer)
+	h.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+func (h *cycleStreamHub) hasSubscribers() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers) > 0
+}
+
+// publish 非阻塞推送，不会拖慢决策周期
+func (h *cycleStreamHub) publish(event CycleStreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SubscribeCycleStream 订阅AI决策周期的实时输出（思维链和正文增量）
+// 仅在周期开始时已有订阅者的情况下以流式方式调用AI；返回的函数用于取消订阅
+func (at *AutoTrader) SubscribeCycleStream() (<-chan CycleStreamEvent, func()) {
+	return at.cycleStream.subscribe()
+}
+
+// publishCycleEvent 推送当前周期的事件
+func (at *AutoTrader) publishCycleEvent(eventType, delta, message string, usage *mcp.Usage) {
+	at.cycleStream.publish(CycleStreamEvent{
+		Type:      eventType,
+		TraderID:  at.id,
+		Cycle:     at.callCount,
+		Delta:     delta,
+		Message:   message,
+		Usage:     usage,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// cycleStreamHandler 有订阅者时返回流式回调，将AI片段转发给订阅者；无订阅者时返回 nil（使用普通调用）
+func (at *AutoTrader) cycleStreamHandler() mcp.StreamHandler {
+	if !at.cycleStream.hasSubscribers() {
+		return nil
+	}
+	return func(chunk mcp.StreamChunk) {
+		if chunk.ReasoningContent != "" {
+			at.publishCycleEvent(CycleEventReasoning, chunk.ReasoningContent, "", nil)
+		}
+		if chunk.Content != "" {
+			at.publishCycleEvent(CycleEventContent, chunk.Content, "", nil)
+		}
+	}
+}
+
+// publishCycleEnd 推送AI调用结束事件
+func (at *AutoTrader) publishCycleEnd(aiDecision *decision.FullDecision, err error) {
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+	var usage *mcp.Usage
+	if aiDecision != nil {
+		usage = aiDecision.AIUsage
+	}
+	at.publishCycleEvent(CycleEventEnd, "", message, usage)
+}
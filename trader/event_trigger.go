@@ -0,0 +1,84 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/store"
+	"strings"
+	"time"
+)
+
+// startEventTriggers 启动事件触发监听：实时K线满足策略配置的条件时请求提前执行交易周期
+// 使用交易员的行情数据源的实时K线流；策略未启用事件触发时不启动；返回停止函数
+func (at *AutoTrader) startEventTriggers() func() {
+	at.triggers = nil
+	if at.config.StrategyConfig == nil || !at.config.StrategyConfig.Triggers.Enabled {
+		return func() {}
+	}
+	stream := market.OrDefault(at.marketProvider).Stream()
+
+	strategyConfig := at.config.StrategyConfig
+	triggers := decision.NewTriggerEvaluator(strategyConfig.Triggers,
+		strategyConfig.Indicators.Klines.PrimaryTimeframe, stream.GetCurrentKlines)
+	at.triggers = triggers
+
+	updates, cancel := stream.SubscribeKlineUpdates(1024)
+	go func() {
+		for update := range updates {
+			for _, reason := range triggers.Evaluate(update) {
+				at.TriggerCycle(reason)
+			}
+		}
+	}()
+
+	debounce, minGap := decision.TriggerTiming(strategyConfig.Triggers)
+	logger.Infof("[%s] ⚡ 事件触发已启用: 监听 %s K线，防抖 %v，最小间隔 %v", at.name, triggers.Timeframe(), debounce, minGap)
+	return cancel
+}
+
+// resetEventTriggers 周期开始时更新关注的币种（候选币和持仓），并预先订阅新币种的监听周期K线
+func (at *AutoTrader) resetEventTriggers(ctx *decision.Context) {
+	if at.triggers == nil {
+		return
+	}
+	added := at.triggers.Reset(ctx)
+	if len(added) == 0 {
+		return
+	}
+	stream, timeframe := market.OrDefault(at.marketProvider).Stream(), at.triggers.Timeframe()
+	go func() {
+		for _, symbol := range added {
+			stream.GetCurrentKlines(symbol, timeframe)
+		}
+	}()
+}
+
+// TriggersOnWebhookSignal 策略是否配置了收到 Webhook 信号时触发周期
+func (at *AutoTrader) TriggersOnWebhookSignal() bool {
+	cfg := at.config.StrategyConfig
+	return cfg != nil && cfg.Triggers.Enabled && cfg.Triggers.OnWebhookSignal
+}
+
+// triggerDelay 事件触发后到执行周期的等待时间：至少一个防抖窗口，且距上次周期不少于最小间隔
+func (at *AutoTrader) triggerDelay(lastCycle time.Time) time.Duration {
+	var config store.TriggerConfig
+	if at.config.StrategyConfig != nil {
+		config = at.config.StrategyConfig.Triggers
+	}
+	debounce, minGap := decision.TriggerTiming(config)
+	return max(debounce, time.Until(lastCycle.Add(minGap)))
+}
+
+// joinTriggerReasons 合并防抖窗口内的触发原因（去重）
+func joinTriggerReasons(reasons []string) string {
+	seen := make(map[string]bool, len(reasons))
+	unique := reasons[:0:0]
+	for _, reason := range reasons {
+		if !seen[reason] {
+			seen[reason] = true
+			unique = append(unique, reason)
+		}
+	}
+	return strings.Join(unique, "; ")
+}
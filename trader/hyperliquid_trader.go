@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"nofx/logger"
+	"nofx/market"
 	"strconv"
 	"strings"
 	"sync"
@@ -887,6 +888,32 @@ func (t *HyperliquidTrader) roundPriceToSigfigs(price float64) float64 {
 	return rounded
 }
 
+// GetTradableSymbols 获取 Hyperliquid 当前上线的币种（刷新 Meta，排除已下架资产）
+func (t *HyperliquidTrader) GetTradableSymbols() (map[string]bool, error) {
+	meta, err := t.exchange.Info().Meta(t.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取 Meta 信息失败: %w", err)
+	}
+
+	t.metaMutex.Lock()
+	t.meta = meta
+	t.metaMutex.Unlock()
+
+	return tradableSymbolsFromMeta(meta), nil
+}
+
+// tradableSymbolsFromMeta 未下架资产的标准币种集合
+// 键与 market.Normalize 一致（全大写），kPEPE 等小写前缀资产记为 KPEPEUSDT
+func tradableSymbolsFromMeta(meta *hyperliquid.Meta) map[string]bool {
+	symbols := make(map[string]bool, len(meta.Universe))
+	for _, asset := range meta.Universe {
+		if !asset.IsDelisted {
+			symbols[market.Normalize(asset.Name)] = true
+		}
+	}
+	return symbols
+}
+
 // convertSymbolToHyperliquid 将标准symbol转换为Hyperliquid格式
 // 例如: "BTCUSDT" -> "BTC"
 func convertSymbolToHyperliquid(symbol string) string {
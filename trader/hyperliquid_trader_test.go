@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"nofx/market"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -413,6 +414,20 @@ func TestConvertSymbolToHyperliquid(t *testing.T) {
 	}
 }
 
+// TestTradableSymbolsFromMeta 测试市场列表键与标准币种格式一致（含小写前缀资产）
+func TestTradableSymbolsFromMeta(t *testing.T) {
+	symbols := tradableSymbolsFromMeta(&hyperliquid.Meta{
+		Universe: []hyperliquid.AssetInfo{
+			{Name: "BTC"},
+			{Name: "kPEPE"},
+			{Name: "OLD", IsDelisted: true},
+		},
+	})
+
+	assert.Equal(t, map[string]bool{"BTCUSDT": true, "KPEPEUSDT": true}, symbols)
+	assert.True(t, symbols[market.Normalize("kPEPEUSDT")])
+}
+
 // TestAbsFloat 测试绝对值函数
 func TestAbsFloat(t *testing.T) {
 	tests := []struct {
@@ -55,3 +55,9 @@ type Trader interface {
 	// 返回: status(FILLED/NEW/CANCELED), avgPrice, executedQty, commission
 	GetOrderStatus(symbol string, orderID string) (map[string]interface{}, error)
 }
+
+// MarketLister 可查询执行交易所市场列表的交易器（用于排除未上线或已下架的币种）
+type MarketLister interface {
+	// GetTradableSymbols 获取当前可交易的币种（标准格式，如 BTCUSDT）
+	GetTradableSymbols() (map[string]bool, error)
+}
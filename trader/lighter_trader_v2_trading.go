@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"nofx/logger"
+	"nofx/market"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/elliottech/lighter-go/types"
@@ -416,6 +418,23 @@ func (t *LighterTraderV2) fetchMarketList() ([]MarketInfo, error) {
 	return markets, nil
 }
 
+// GetTradableSymbols 獲取 Lighter 當前上線的市場（轉換為標準格式，如 BTC-PERP → BTCUSDT）
+func (t *LighterTraderV2) GetTradableSymbols() (map[string]bool, error) {
+	markets, err := t.fetchMarketList()
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make(map[string]bool, len(markets))
+	t.marketMutex.Lock()
+	for _, m := range markets {
+		t.marketIndexMap[m.Symbol] = m.MarketID
+		symbols[market.Normalize(strings.TrimSuffix(m.Symbol, "-PERP"))] = true
+	}
+	t.marketMutex.Unlock()
+	return symbols, nil
+}
+
 // getFallbackMarketIndex 硬編碼的回退映射
 func (t *LighterTraderV2) getFallbackMarketIndex(symbol string) (uint8, error) {
 	fallbackMap := map[string]uint8{
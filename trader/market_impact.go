@@ -0,0 +1,60 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+)
+
+// applyMarketImpactLimit 按盘口深度估算开仓的市价冲击成本（USDT）
+// 策略设置了 MaxMarketImpactBps 且冲击超过上限时，将开仓金额缩减到上限内可成交的最大值；
+// 缩减后低于最小开仓金额则拒绝开仓。未设置上限时不请求订单簿，返回 0
+// 订单簿取自 Binance 合约行情，其他交易所作为流动性参考
+func (at *AutoTrader) applyMarketImpactLimit(d *decision.Decision, side string) (float64, error) {
+	if at.strategyEngine == nil {
+		return 0, nil
+	}
+	riskControl := at.strategyEngine.GetRiskControlConfig()
+	maxImpact := riskControl.MaxMarketImpactBps
+	if maxImpact <= 0 {
+		return 0, nil
+	}
+
+	book, err := market.GetOrderBook(d.Symbol)
+	if err != nil {
+		logger.Infof("  ⚠️ 获取 %s 订单簿失败，跳过冲击成本检查: %v", d.Symbol, err)
+		return 0, nil
+	}
+	impact, err := book.EstimateImpact(side, d.PositionSizeUSD)
+	if err != nil {
+		logger.Infof("  ⚠️ 估算 %s 冲击成本失败: %v", d.Symbol, err)
+		return 0, nil
+	}
+	if impact.Complete && impact.ImpactBps <= maxImpact {
+		logger.Infof("  💧 预计冲击成本: %.2f bps (%.4f USDT)", impact.ImpactBps, impact.CostUSD)
+		return impact.CostUSD, nil
+	}
+
+	allowed, err := book.MaxNotionalWithinImpact(side, maxImpact)
+	if err != nil {
+		return 0, err
+	}
+	minSize := riskControl.MinPositionSize
+	if minSize <= 0 {
+		minSize = 12
+	}
+	if allowed < minSize {
+		return 0, fmt.Errorf("❌ %s 盘口深度不足: 冲击成本上限 %.1f bps 内仅可成交 %.2f USDT，低于最小开仓金额 %.2f USDT",
+			d.Symbol, maxImpact, allowed, minSize)
+	}
+
+	logger.Infof("  💧 %s 冲击成本 %.2f bps 超过上限 %.1f bps，开仓金额 %.2f → %.2f USDT",
+		d.Symbol, impact.ImpactBps, maxImpact, d.PositionSizeUSD, allowed)
+	d.PositionSizeUSD = allowed
+	impact, err = book.EstimateImpact(side, allowed)
+	if err != nil {
+		return 0, err
+	}
+	return impact.CostUSD, nil
+}
@@ -0,0 +1,69 @@
+package trader
+
+import (
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/market"
+	"nofx/store"
+	"time"
+)
+
+// venueSymbolsTTL 执行交易所市场列表的缓存时长
+const venueSymbolsTTL = 10 * time.Minute
+
+// buildSymbolUniverse 组合策略和用户的允许/禁止列表、行情交易所的交易对状态和执行交易所的市场列表
+// 任一数据获取失败时跳过对应检查，不阻塞交易周期
+func (at *AutoTrader) buildSymbolUniverse() *decision.SymbolUniverse {
+	universe := at.strategyEngine.SymbolUniverse()
+
+	if at.store != nil {
+		entries, err := at.store.SymbolList().List(at.userID)
+		if err != nil {
+			logger.Warnf("⚠️ [%s] 读取用户币种列表失败: %v", at.name, err)
+		}
+		var allow []string
+		for _, entry := range entries {
+			switch entry.ListType {
+			case store.SymbolListAllow:
+				allow = append(allow, entry.Symbol)
+			case store.SymbolListDeny:
+				universe.Deny(entry.Symbol, entry.Reason)
+			}
+		}
+		universe.Allow("用户", allow)
+	}
+
+	if provider, ok := at.marketProvider.(market.SymbolStatusProvider); ok {
+		statuses, err := provider.GetSymbolStatuses()
+		if err != nil {
+			logger.Warnf("⚠️ [%s] 获取交易对状态失败，跳过下架检查: %v", at.name, err)
+		} else {
+			universe.SetStatuses(statuses)
+		}
+	}
+
+	if symbols := at.venueSymbols(); symbols != nil {
+		universe.SetVenue(at.exchange, symbols)
+	}
+	return universe
+}
+
+// venueSymbols 执行交易所当前可交易的币种（缓存 venueSymbolsTTL；交易所不支持查询时返回 nil）
+// 获取失败时沿用上次结果
+func (at *AutoTrader) venueSymbols() map[string]bool {
+	lister, ok := at.trader.(MarketLister)
+	if !ok {
+		return nil
+	}
+	if at.venueSymbolSet != nil && time.Since(at.venueSymbolsAt) < venueSymbolsTTL {
+		return at.venueSymbolSet
+	}
+	symbols, err := lister.GetTradableSymbols()
+	if err != nil || len(symbols) == 0 {
+		logger.Warnf("⚠️ [%s] 获取 %s 市场列表失败，沿用上次结果: %v", at.name, at.exchange, err)
+		return at.venueSymbolSet
+	}
+	at.venueSymbolSet = symbols
+	at.venueSymbolsAt = time.Now()
+	return symbols
+}